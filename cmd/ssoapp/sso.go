@@ -26,9 +26,15 @@ func main() {
 	log := setupLogger(cfg.Env)
 
 	// Application load
-	application := app.NewApp(log, cfg.GRPC.Port, cfg.StoragePath, cfg.TokenTTL)
+	application := app.NewApp(log, cfg)
 
 	go application.GRPCServer.MustStart()
+	go application.HTTPServer.MustStart()
+	go application.Gateway.MustStart()
+	go application.Sweeper.MustStart()
+	go application.Logout.MustStart()
+	go application.Webhooks.MustStart()
+	go application.Relay.MustStart()
 
 	stop := make(chan os.Signal, 1)
 	signal.Notify(stop, syscall.SIGINT, syscall.SIGTERM, syscall.SIGKILL)
@@ -36,6 +42,12 @@ func main() {
 	sign := <-stop
 
 	application.GRPCServer.Stop()
+	application.HTTPServer.Stop()
+	application.Gateway.Stop()
+	application.Sweeper.Stop()
+	application.Logout.Stop()
+	application.Relay.Stop()
+	application.Webhooks.Stop()
 	// Stop every service and components (databases for ex) separately
 
 	log.Info("Application stopped", slog.String("sign", sign.String()))
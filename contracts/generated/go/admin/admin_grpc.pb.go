@@ -0,0 +1,431 @@
+// Code generated by protoc-gen-go-grpc. DO NOT EDIT.
+// versions:
+// - protoc-gen-go-grpc v1.5.1
+// - protoc             v3.21.12
+// source: admin/admin.proto
+
+package adminv1
+
+import (
+	context "context"
+	grpc "google.golang.org/grpc"
+	codes "google.golang.org/grpc/codes"
+	status "google.golang.org/grpc/status"
+)
+
+// This is a compile-time assertion to ensure that this generated file
+// is compatible with the grpc package it is being compiled against.
+// Requires gRPC-Go v1.64.0 or later.
+const _ = grpc.SupportPackageIsVersion9
+
+const (
+	Admin_IsAppAdmin_FullMethodName            = "/admin.Admin/IsAppAdmin"
+	Admin_GrantAdmin_FullMethodName            = "/admin.Admin/GrantAdmin"
+	Admin_RevokeAdmin_FullMethodName           = "/admin.Admin/RevokeAdmin"
+	Admin_ListAdmins_FullMethodName            = "/admin.Admin/ListAdmins"
+	Admin_ListAuditEvents_FullMethodName       = "/admin.Admin/ListAuditEvents"
+	Admin_RequestElevation_FullMethodName      = "/admin.Admin/RequestElevation"
+	Admin_ApproveElevation_FullMethodName      = "/admin.Admin/ApproveElevation"
+	Admin_DenyElevation_FullMethodName         = "/admin.Admin/DenyElevation"
+	Admin_ListElevationRequests_FullMethodName = "/admin.Admin/ListElevationRequests"
+)
+
+// AdminClient is the client API for Admin service.
+//
+// For semantics around ctx use and closing/ending streaming RPCs, please refer to https://pkg.go.dev/google.golang.org/grpc/?tab=doc#ClientConn.NewStream.
+//
+// Every RPC except IsAppAdmin requires bearer token of an admin in the authorization metadata,
+// RequestElevation requires bearer token of any user
+type AdminClient interface {
+	IsAppAdmin(ctx context.Context, in *IsAppAdminRequest, opts ...grpc.CallOption) (*IsAppAdminResponse, error)
+	GrantAdmin(ctx context.Context, in *GrantAdminRequest, opts ...grpc.CallOption) (*GrantAdminResponse, error)
+	RevokeAdmin(ctx context.Context, in *RevokeAdminRequest, opts ...grpc.CallOption) (*RevokeAdminResponse, error)
+	ListAdmins(ctx context.Context, in *ListAdminsRequest, opts ...grpc.CallOption) (*ListAdminsResponse, error)
+	ListAuditEvents(ctx context.Context, in *ListAuditEventsRequest, opts ...grpc.CallOption) (*ListAuditEventsResponse, error)
+	RequestElevation(ctx context.Context, in *RequestElevationRequest, opts ...grpc.CallOption) (*RequestElevationResponse, error)
+	ApproveElevation(ctx context.Context, in *ApproveElevationRequest, opts ...grpc.CallOption) (*ApproveElevationResponse, error)
+	DenyElevation(ctx context.Context, in *DenyElevationRequest, opts ...grpc.CallOption) (*DenyElevationResponse, error)
+	ListElevationRequests(ctx context.Context, in *ListElevationRequestsRequest, opts ...grpc.CallOption) (*ListElevationRequestsResponse, error)
+}
+
+type adminClient struct {
+	cc grpc.ClientConnInterface
+}
+
+func NewAdminClient(cc grpc.ClientConnInterface) AdminClient {
+	return &adminClient{cc}
+}
+
+func (c *adminClient) IsAppAdmin(ctx context.Context, in *IsAppAdminRequest, opts ...grpc.CallOption) (*IsAppAdminResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(IsAppAdminResponse)
+	err := c.cc.Invoke(ctx, Admin_IsAppAdmin_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *adminClient) GrantAdmin(ctx context.Context, in *GrantAdminRequest, opts ...grpc.CallOption) (*GrantAdminResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(GrantAdminResponse)
+	err := c.cc.Invoke(ctx, Admin_GrantAdmin_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *adminClient) RevokeAdmin(ctx context.Context, in *RevokeAdminRequest, opts ...grpc.CallOption) (*RevokeAdminResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(RevokeAdminResponse)
+	err := c.cc.Invoke(ctx, Admin_RevokeAdmin_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *adminClient) ListAdmins(ctx context.Context, in *ListAdminsRequest, opts ...grpc.CallOption) (*ListAdminsResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(ListAdminsResponse)
+	err := c.cc.Invoke(ctx, Admin_ListAdmins_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *adminClient) ListAuditEvents(ctx context.Context, in *ListAuditEventsRequest, opts ...grpc.CallOption) (*ListAuditEventsResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(ListAuditEventsResponse)
+	err := c.cc.Invoke(ctx, Admin_ListAuditEvents_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *adminClient) RequestElevation(ctx context.Context, in *RequestElevationRequest, opts ...grpc.CallOption) (*RequestElevationResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(RequestElevationResponse)
+	err := c.cc.Invoke(ctx, Admin_RequestElevation_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *adminClient) ApproveElevation(ctx context.Context, in *ApproveElevationRequest, opts ...grpc.CallOption) (*ApproveElevationResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(ApproveElevationResponse)
+	err := c.cc.Invoke(ctx, Admin_ApproveElevation_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *adminClient) DenyElevation(ctx context.Context, in *DenyElevationRequest, opts ...grpc.CallOption) (*DenyElevationResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(DenyElevationResponse)
+	err := c.cc.Invoke(ctx, Admin_DenyElevation_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *adminClient) ListElevationRequests(ctx context.Context, in *ListElevationRequestsRequest, opts ...grpc.CallOption) (*ListElevationRequestsResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(ListElevationRequestsResponse)
+	err := c.cc.Invoke(ctx, Admin_ListElevationRequests_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// AdminServer is the server API for Admin service.
+// All implementations must embed UnimplementedAdminServer
+// for forward compatibility.
+//
+// Every RPC except IsAppAdmin requires bearer token of an admin in the authorization metadata,
+// RequestElevation requires bearer token of any user
+type AdminServer interface {
+	IsAppAdmin(context.Context, *IsAppAdminRequest) (*IsAppAdminResponse, error)
+	GrantAdmin(context.Context, *GrantAdminRequest) (*GrantAdminResponse, error)
+	RevokeAdmin(context.Context, *RevokeAdminRequest) (*RevokeAdminResponse, error)
+	ListAdmins(context.Context, *ListAdminsRequest) (*ListAdminsResponse, error)
+	ListAuditEvents(context.Context, *ListAuditEventsRequest) (*ListAuditEventsResponse, error)
+	RequestElevation(context.Context, *RequestElevationRequest) (*RequestElevationResponse, error)
+	ApproveElevation(context.Context, *ApproveElevationRequest) (*ApproveElevationResponse, error)
+	DenyElevation(context.Context, *DenyElevationRequest) (*DenyElevationResponse, error)
+	ListElevationRequests(context.Context, *ListElevationRequestsRequest) (*ListElevationRequestsResponse, error)
+	mustEmbedUnimplementedAdminServer()
+}
+
+// UnimplementedAdminServer must be embedded to have
+// forward compatible implementations.
+//
+// NOTE: this should be embedded by value instead of pointer to avoid a nil
+// pointer dereference when methods are called.
+type UnimplementedAdminServer struct{}
+
+func (UnimplementedAdminServer) IsAppAdmin(context.Context, *IsAppAdminRequest) (*IsAppAdminResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method IsAppAdmin not implemented")
+}
+func (UnimplementedAdminServer) GrantAdmin(context.Context, *GrantAdminRequest) (*GrantAdminResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GrantAdmin not implemented")
+}
+func (UnimplementedAdminServer) RevokeAdmin(context.Context, *RevokeAdminRequest) (*RevokeAdminResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method RevokeAdmin not implemented")
+}
+func (UnimplementedAdminServer) ListAdmins(context.Context, *ListAdminsRequest) (*ListAdminsResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method ListAdmins not implemented")
+}
+func (UnimplementedAdminServer) ListAuditEvents(context.Context, *ListAuditEventsRequest) (*ListAuditEventsResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method ListAuditEvents not implemented")
+}
+func (UnimplementedAdminServer) RequestElevation(context.Context, *RequestElevationRequest) (*RequestElevationResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method RequestElevation not implemented")
+}
+func (UnimplementedAdminServer) ApproveElevation(context.Context, *ApproveElevationRequest) (*ApproveElevationResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method ApproveElevation not implemented")
+}
+func (UnimplementedAdminServer) DenyElevation(context.Context, *DenyElevationRequest) (*DenyElevationResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method DenyElevation not implemented")
+}
+func (UnimplementedAdminServer) ListElevationRequests(context.Context, *ListElevationRequestsRequest) (*ListElevationRequestsResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method ListElevationRequests not implemented")
+}
+func (UnimplementedAdminServer) mustEmbedUnimplementedAdminServer() {}
+func (UnimplementedAdminServer) testEmbeddedByValue()               {}
+
+// UnsafeAdminServer may be embedded to opt out of forward compatibility for this service.
+// Use of this interface is not recommended, as added methods to AdminServer will
+// result in compilation errors.
+type UnsafeAdminServer interface {
+	mustEmbedUnimplementedAdminServer()
+}
+
+func RegisterAdminServer(s grpc.ServiceRegistrar, srv AdminServer) {
+	// If the following call pancis, it indicates UnimplementedAdminServer was
+	// embedded by pointer and is nil.  This will cause panics if an
+	// unimplemented method is ever invoked, so we test this at initialization
+	// time to prevent it from happening at runtime later due to I/O.
+	if t, ok := srv.(interface{ testEmbeddedByValue() }); ok {
+		t.testEmbeddedByValue()
+	}
+	s.RegisterService(&Admin_ServiceDesc, srv)
+}
+
+func _Admin_IsAppAdmin_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(IsAppAdminRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(AdminServer).IsAppAdmin(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Admin_IsAppAdmin_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(AdminServer).IsAppAdmin(ctx, req.(*IsAppAdminRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Admin_GrantAdmin_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GrantAdminRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(AdminServer).GrantAdmin(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Admin_GrantAdmin_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(AdminServer).GrantAdmin(ctx, req.(*GrantAdminRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Admin_RevokeAdmin_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(RevokeAdminRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(AdminServer).RevokeAdmin(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Admin_RevokeAdmin_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(AdminServer).RevokeAdmin(ctx, req.(*RevokeAdminRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Admin_ListAdmins_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ListAdminsRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(AdminServer).ListAdmins(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Admin_ListAdmins_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(AdminServer).ListAdmins(ctx, req.(*ListAdminsRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Admin_ListAuditEvents_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ListAuditEventsRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(AdminServer).ListAuditEvents(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Admin_ListAuditEvents_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(AdminServer).ListAuditEvents(ctx, req.(*ListAuditEventsRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Admin_RequestElevation_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(RequestElevationRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(AdminServer).RequestElevation(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Admin_RequestElevation_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(AdminServer).RequestElevation(ctx, req.(*RequestElevationRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Admin_ApproveElevation_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ApproveElevationRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(AdminServer).ApproveElevation(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Admin_ApproveElevation_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(AdminServer).ApproveElevation(ctx, req.(*ApproveElevationRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Admin_DenyElevation_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(DenyElevationRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(AdminServer).DenyElevation(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Admin_DenyElevation_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(AdminServer).DenyElevation(ctx, req.(*DenyElevationRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Admin_ListElevationRequests_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ListElevationRequestsRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(AdminServer).ListElevationRequests(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Admin_ListElevationRequests_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(AdminServer).ListElevationRequests(ctx, req.(*ListElevationRequestsRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+// Admin_ServiceDesc is the grpc.ServiceDesc for Admin service.
+// It's only intended for direct use with grpc.RegisterService,
+// and not to be introspected or modified (even as a copy)
+var Admin_ServiceDesc = grpc.ServiceDesc{
+	ServiceName: "admin.Admin",
+	HandlerType: (*AdminServer)(nil),
+	Methods: []grpc.MethodDesc{
+		{
+			MethodName: "IsAppAdmin",
+			Handler:    _Admin_IsAppAdmin_Handler,
+		},
+		{
+			MethodName: "GrantAdmin",
+			Handler:    _Admin_GrantAdmin_Handler,
+		},
+		{
+			MethodName: "RevokeAdmin",
+			Handler:    _Admin_RevokeAdmin_Handler,
+		},
+		{
+			MethodName: "ListAdmins",
+			Handler:    _Admin_ListAdmins_Handler,
+		},
+		{
+			MethodName: "ListAuditEvents",
+			Handler:    _Admin_ListAuditEvents_Handler,
+		},
+		{
+			MethodName: "RequestElevation",
+			Handler:    _Admin_RequestElevation_Handler,
+		},
+		{
+			MethodName: "ApproveElevation",
+			Handler:    _Admin_ApproveElevation_Handler,
+		},
+		{
+			MethodName: "DenyElevation",
+			Handler:    _Admin_DenyElevation_Handler,
+		},
+		{
+			MethodName: "ListElevationRequests",
+			Handler:    _Admin_ListElevationRequests_Handler,
+		},
+	},
+	Streams:  []grpc.StreamDesc{},
+	Metadata: "admin/admin.proto",
+}
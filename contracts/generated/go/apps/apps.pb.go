@@ -0,0 +1,1124 @@
+// Code generated by protoc-gen-go. DO NOT EDIT.
+// versions:
+// 	protoc-gen-go v1.36.9
+// 	protoc        v3.21.12
+// source: apps/apps.proto
+
+package appsv1
+
+import (
+	protoreflect "google.golang.org/protobuf/reflect/protoreflect"
+	protoimpl "google.golang.org/protobuf/runtime/protoimpl"
+	fieldmaskpb "google.golang.org/protobuf/types/known/fieldmaskpb"
+	reflect "reflect"
+	sync "sync"
+	unsafe "unsafe"
+)
+
+const (
+	// Verify that this generated code is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(20 - protoimpl.MinVersion)
+	// Verify that runtime/protoimpl is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(protoimpl.MaxVersion - 20)
+)
+
+type AppSettings struct {
+	state                  protoimpl.MessageState `protogen:"open.v1"`
+	EmbedRoles             bool                   `protobuf:"varint,1,opt,name=embed_roles,json=embedRoles,proto3" json:"embed_roles,omitempty"`                                         // Put roles of the user into the token
+	EmbedScope             bool                   `protobuf:"varint,2,opt,name=embed_scope,json=embedScope,proto3" json:"embed_scope,omitempty"`                                         // Put scopes of the user roles into the token
+	MaxClaimsSize          int32                  `protobuf:"varint,3,opt,name=max_claims_size,json=maxClaimsSize,proto3" json:"max_claims_size,omitempty"`                              // Max size in bytes of the roles and scope claims, 2048 by default
+	RedirectUris           []string               `protobuf:"bytes,4,rep,name=redirect_uris,json=redirectUris,proto3" json:"redirect_uris,omitempty"`                                    // Absolute URIs the OAuth authorization codes may be sent to
+	ClientScopes           []string               `protobuf:"bytes,5,rep,name=client_scopes,json=clientScopes,proto3" json:"client_scopes,omitempty"`                                    // Scopes the application gets by the client credentials grant
+	GrantTypes             []string               `protobuf:"bytes,6,rep,name=grant_types,json=grantTypes,proto3" json:"grant_types,omitempty"`                                          // OAuth grant types the client may use, empty allows every grant type
+	AllowedScopes          []string               `protobuf:"bytes,7,rep,name=allowed_scopes,json=allowedScopes,proto3" json:"allowed_scopes,omitempty"`                                 // Scopes the client may ask the user for, empty allows any scope
+	AccessTokenTtlSeconds  int64                  `protobuf:"varint,8,opt,name=access_token_ttl_seconds,json=accessTokenTtlSeconds,proto3" json:"access_token_ttl_seconds,omitempty"`    // Lifetime of the access tokens, zero for the default token TTL
+	RefreshTokenTtlSeconds int64                  `protobuf:"varint,9,opt,name=refresh_token_ttl_seconds,json=refreshTokenTtlSeconds,proto3" json:"refresh_token_ttl_seconds,omitempty"` // Lifetime of the refresh tokens, zero disables refresh tokens
+	PublicClient           bool                   `protobuf:"varint,10,opt,name=public_client,json=publicClient,proto3" json:"public_client,omitempty"`                                  // Client without secret may exchange codes and refresh tokens
+	ThirdParty             bool                   `protobuf:"varint,11,opt,name=third_party,json=thirdParty,proto3" json:"third_party,omitempty"`                                        // Client gets scopes of the user only with consent of the user
+	BackchannelLogoutUri   string                 `protobuf:"bytes,12,opt,name=backchannel_logout_uri,json=backchannelLogoutUri,proto3" json:"backchannel_logout_uri,omitempty"`         // Absolute URI logout tokens are posted to when the user logs out, empty disables them
+	Theme                  *AppTheme              `protobuf:"bytes,13,opt,name=theme,proto3" json:"theme,omitempty"`                                                                     // Look of the hosted login pages
+	unknownFields          protoimpl.UnknownFields
+	sizeCache              protoimpl.SizeCache
+}
+
+func (x *AppSettings) Reset() {
+	*x = AppSettings{}
+	mi := &file_apps_apps_proto_msgTypes[0]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *AppSettings) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*AppSettings) ProtoMessage() {}
+
+func (x *AppSettings) ProtoReflect() protoreflect.Message {
+	mi := &file_apps_apps_proto_msgTypes[0]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use AppSettings.ProtoReflect.Descriptor instead.
+func (*AppSettings) Descriptor() ([]byte, []int) {
+	return file_apps_apps_proto_rawDescGZIP(), []int{0}
+}
+
+func (x *AppSettings) GetEmbedRoles() bool {
+	if x != nil {
+		return x.EmbedRoles
+	}
+	return false
+}
+
+func (x *AppSettings) GetEmbedScope() bool {
+	if x != nil {
+		return x.EmbedScope
+	}
+	return false
+}
+
+func (x *AppSettings) GetMaxClaimsSize() int32 {
+	if x != nil {
+		return x.MaxClaimsSize
+	}
+	return 0
+}
+
+func (x *AppSettings) GetRedirectUris() []string {
+	if x != nil {
+		return x.RedirectUris
+	}
+	return nil
+}
+
+func (x *AppSettings) GetClientScopes() []string {
+	if x != nil {
+		return x.ClientScopes
+	}
+	return nil
+}
+
+func (x *AppSettings) GetGrantTypes() []string {
+	if x != nil {
+		return x.GrantTypes
+	}
+	return nil
+}
+
+func (x *AppSettings) GetAllowedScopes() []string {
+	if x != nil {
+		return x.AllowedScopes
+	}
+	return nil
+}
+
+func (x *AppSettings) GetAccessTokenTtlSeconds() int64 {
+	if x != nil {
+		return x.AccessTokenTtlSeconds
+	}
+	return 0
+}
+
+func (x *AppSettings) GetRefreshTokenTtlSeconds() int64 {
+	if x != nil {
+		return x.RefreshTokenTtlSeconds
+	}
+	return 0
+}
+
+func (x *AppSettings) GetPublicClient() bool {
+	if x != nil {
+		return x.PublicClient
+	}
+	return false
+}
+
+func (x *AppSettings) GetThirdParty() bool {
+	if x != nil {
+		return x.ThirdParty
+	}
+	return false
+}
+
+func (x *AppSettings) GetBackchannelLogoutUri() string {
+	if x != nil {
+		return x.BackchannelLogoutUri
+	}
+	return ""
+}
+
+func (x *AppSettings) GetTheme() *AppTheme {
+	if x != nil {
+		return x.Theme
+	}
+	return nil
+}
+
+// Empty fields keep the default look of the hosted login pages
+type AppTheme struct {
+	state           protoimpl.MessageState `protogen:"open.v1"`
+	LogoUri         string                 `protobuf:"bytes,1,opt,name=logo_uri,json=logoUri,proto3" json:"logo_uri,omitempty"`                         // Absolute HTTP URI of the logo
+	PrimaryColor    string                 `protobuf:"bytes,2,opt,name=primary_color,json=primaryColor,proto3" json:"primary_color,omitempty"`          // Color of the buttons and links, #rrggbb
+	BackgroundColor string                 `protobuf:"bytes,3,opt,name=background_color,json=backgroundColor,proto3" json:"background_color,omitempty"` // Color of the page background, #rrggbb
+	Title           string                 `protobuf:"bytes,4,opt,name=title,proto3" json:"title,omitempty"`                                            // Heading of the pages, name of the application by default
+	Text            string                 `protobuf:"bytes,5,opt,name=text,proto3" json:"text,omitempty"`                                              // Text shown under the heading
+	unknownFields   protoimpl.UnknownFields
+	sizeCache       protoimpl.SizeCache
+}
+
+func (x *AppTheme) Reset() {
+	*x = AppTheme{}
+	mi := &file_apps_apps_proto_msgTypes[1]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *AppTheme) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*AppTheme) ProtoMessage() {}
+
+func (x *AppTheme) ProtoReflect() protoreflect.Message {
+	mi := &file_apps_apps_proto_msgTypes[1]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use AppTheme.ProtoReflect.Descriptor instead.
+func (*AppTheme) Descriptor() ([]byte, []int) {
+	return file_apps_apps_proto_rawDescGZIP(), []int{1}
+}
+
+func (x *AppTheme) GetLogoUri() string {
+	if x != nil {
+		return x.LogoUri
+	}
+	return ""
+}
+
+func (x *AppTheme) GetPrimaryColor() string {
+	if x != nil {
+		return x.PrimaryColor
+	}
+	return ""
+}
+
+func (x *AppTheme) GetBackgroundColor() string {
+	if x != nil {
+		return x.BackgroundColor
+	}
+	return ""
+}
+
+func (x *AppTheme) GetTitle() string {
+	if x != nil {
+		return x.Title
+	}
+	return ""
+}
+
+func (x *AppTheme) GetText() string {
+	if x != nil {
+		return x.Text
+	}
+	return ""
+}
+
+type AppInfo struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Id            int32                  `protobuf:"varint,1,opt,name=id,proto3" json:"id,omitempty"`             // ID of the application
+	Name          string                 `protobuf:"bytes,2,opt,name=name,proto3" json:"name,omitempty"`          // Unique name of the application
+	Disabled      bool                   `protobuf:"varint,3,opt,name=disabled,proto3" json:"disabled,omitempty"` // Disabled application can't issue and accept tokens
+	Settings      *AppSettings           `protobuf:"bytes,4,opt,name=settings,proto3" json:"settings,omitempty"`  // Settings of the application
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *AppInfo) Reset() {
+	*x = AppInfo{}
+	mi := &file_apps_apps_proto_msgTypes[2]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *AppInfo) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*AppInfo) ProtoMessage() {}
+
+func (x *AppInfo) ProtoReflect() protoreflect.Message {
+	mi := &file_apps_apps_proto_msgTypes[2]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use AppInfo.ProtoReflect.Descriptor instead.
+func (*AppInfo) Descriptor() ([]byte, []int) {
+	return file_apps_apps_proto_rawDescGZIP(), []int{2}
+}
+
+func (x *AppInfo) GetId() int32 {
+	if x != nil {
+		return x.Id
+	}
+	return 0
+}
+
+func (x *AppInfo) GetName() string {
+	if x != nil {
+		return x.Name
+	}
+	return ""
+}
+
+func (x *AppInfo) GetDisabled() bool {
+	if x != nil {
+		return x.Disabled
+	}
+	return false
+}
+
+func (x *AppInfo) GetSettings() *AppSettings {
+	if x != nil {
+		return x.Settings
+	}
+	return nil
+}
+
+type CreateAppRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Name          string                 `protobuf:"bytes,1,opt,name=name,proto3" json:"name,omitempty"`         // Unique name of the application
+	Settings      *AppSettings           `protobuf:"bytes,2,opt,name=settings,proto3" json:"settings,omitempty"` // Settings of the application
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *CreateAppRequest) Reset() {
+	*x = CreateAppRequest{}
+	mi := &file_apps_apps_proto_msgTypes[3]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *CreateAppRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*CreateAppRequest) ProtoMessage() {}
+
+func (x *CreateAppRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_apps_apps_proto_msgTypes[3]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use CreateAppRequest.ProtoReflect.Descriptor instead.
+func (*CreateAppRequest) Descriptor() ([]byte, []int) {
+	return file_apps_apps_proto_rawDescGZIP(), []int{3}
+}
+
+func (x *CreateAppRequest) GetName() string {
+	if x != nil {
+		return x.Name
+	}
+	return ""
+}
+
+func (x *CreateAppRequest) GetSettings() *AppSettings {
+	if x != nil {
+		return x.Settings
+	}
+	return nil
+}
+
+type CreateAppResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	App           *AppInfo               `protobuf:"bytes,1,opt,name=app,proto3" json:"app,omitempty"`       // Created application
+	Secret        string                 `protobuf:"bytes,2,opt,name=secret,proto3" json:"secret,omitempty"` // Secret of the application, it is shown only once
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *CreateAppResponse) Reset() {
+	*x = CreateAppResponse{}
+	mi := &file_apps_apps_proto_msgTypes[4]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *CreateAppResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*CreateAppResponse) ProtoMessage() {}
+
+func (x *CreateAppResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_apps_apps_proto_msgTypes[4]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use CreateAppResponse.ProtoReflect.Descriptor instead.
+func (*CreateAppResponse) Descriptor() ([]byte, []int) {
+	return file_apps_apps_proto_rawDescGZIP(), []int{4}
+}
+
+func (x *CreateAppResponse) GetApp() *AppInfo {
+	if x != nil {
+		return x.App
+	}
+	return nil
+}
+
+func (x *CreateAppResponse) GetSecret() string {
+	if x != nil {
+		return x.Secret
+	}
+	return ""
+}
+
+type UpdateAppRequest struct {
+	state    protoimpl.MessageState `protogen:"open.v1"`
+	AppId    int32                  `protobuf:"varint,1,opt,name=app_id,json=appId,proto3" json:"app_id,omitempty"` // ID of the application to update
+	Name     string                 `protobuf:"bytes,2,opt,name=name,proto3" json:"name,omitempty"`                 // New name of the application, empty keeps the current one
+	Settings *AppSettings           `protobuf:"bytes,3,opt,name=settings,proto3" json:"settings,omitempty"`         // New settings of the application, unset keeps the current ones
+	// Settings fields to update, e.g. "redirect_uris" or "theme", empty mask replaces all settings
+	UpdateMask    *fieldmaskpb.FieldMask `protobuf:"bytes,4,opt,name=update_mask,json=updateMask,proto3" json:"update_mask,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *UpdateAppRequest) Reset() {
+	*x = UpdateAppRequest{}
+	mi := &file_apps_apps_proto_msgTypes[5]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *UpdateAppRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*UpdateAppRequest) ProtoMessage() {}
+
+func (x *UpdateAppRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_apps_apps_proto_msgTypes[5]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use UpdateAppRequest.ProtoReflect.Descriptor instead.
+func (*UpdateAppRequest) Descriptor() ([]byte, []int) {
+	return file_apps_apps_proto_rawDescGZIP(), []int{5}
+}
+
+func (x *UpdateAppRequest) GetAppId() int32 {
+	if x != nil {
+		return x.AppId
+	}
+	return 0
+}
+
+func (x *UpdateAppRequest) GetName() string {
+	if x != nil {
+		return x.Name
+	}
+	return ""
+}
+
+func (x *UpdateAppRequest) GetSettings() *AppSettings {
+	if x != nil {
+		return x.Settings
+	}
+	return nil
+}
+
+func (x *UpdateAppRequest) GetUpdateMask() *fieldmaskpb.FieldMask {
+	if x != nil {
+		return x.UpdateMask
+	}
+	return nil
+}
+
+type UpdateAppResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	App           *AppInfo               `protobuf:"bytes,1,opt,name=app,proto3" json:"app,omitempty"` // Updated application
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *UpdateAppResponse) Reset() {
+	*x = UpdateAppResponse{}
+	mi := &file_apps_apps_proto_msgTypes[6]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *UpdateAppResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*UpdateAppResponse) ProtoMessage() {}
+
+func (x *UpdateAppResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_apps_apps_proto_msgTypes[6]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use UpdateAppResponse.ProtoReflect.Descriptor instead.
+func (*UpdateAppResponse) Descriptor() ([]byte, []int) {
+	return file_apps_apps_proto_rawDescGZIP(), []int{6}
+}
+
+func (x *UpdateAppResponse) GetApp() *AppInfo {
+	if x != nil {
+		return x.App
+	}
+	return nil
+}
+
+type ListAppsRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	PageSize      int32                  `protobuf:"varint,1,opt,name=page_size,json=pageSize,proto3" json:"page_size,omitempty"`   // Max count of the applications in the page, 50 by default
+	PageToken     string                 `protobuf:"bytes,2,opt,name=page_token,json=pageToken,proto3" json:"page_token,omitempty"` // Token of the page from the previous response, empty for the first page
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ListAppsRequest) Reset() {
+	*x = ListAppsRequest{}
+	mi := &file_apps_apps_proto_msgTypes[7]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ListAppsRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ListAppsRequest) ProtoMessage() {}
+
+func (x *ListAppsRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_apps_apps_proto_msgTypes[7]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ListAppsRequest.ProtoReflect.Descriptor instead.
+func (*ListAppsRequest) Descriptor() ([]byte, []int) {
+	return file_apps_apps_proto_rawDescGZIP(), []int{7}
+}
+
+func (x *ListAppsRequest) GetPageSize() int32 {
+	if x != nil {
+		return x.PageSize
+	}
+	return 0
+}
+
+func (x *ListAppsRequest) GetPageToken() string {
+	if x != nil {
+		return x.PageToken
+	}
+	return ""
+}
+
+type ListAppsResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Apps          []*AppInfo             `protobuf:"bytes,1,rep,name=apps,proto3" json:"apps,omitempty"`                                          // Applications ordered by ID
+	NextPageToken string                 `protobuf:"bytes,2,opt,name=next_page_token,json=nextPageToken,proto3" json:"next_page_token,omitempty"` // Token of the next page, empty if there are no more pages
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ListAppsResponse) Reset() {
+	*x = ListAppsResponse{}
+	mi := &file_apps_apps_proto_msgTypes[8]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ListAppsResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ListAppsResponse) ProtoMessage() {}
+
+func (x *ListAppsResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_apps_apps_proto_msgTypes[8]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ListAppsResponse.ProtoReflect.Descriptor instead.
+func (*ListAppsResponse) Descriptor() ([]byte, []int) {
+	return file_apps_apps_proto_rawDescGZIP(), []int{8}
+}
+
+func (x *ListAppsResponse) GetApps() []*AppInfo {
+	if x != nil {
+		return x.Apps
+	}
+	return nil
+}
+
+func (x *ListAppsResponse) GetNextPageToken() string {
+	if x != nil {
+		return x.NextPageToken
+	}
+	return ""
+}
+
+type DisableAppRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	AppId         int32                  `protobuf:"varint,1,opt,name=app_id,json=appId,proto3" json:"app_id,omitempty"` // ID of the application to disable
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *DisableAppRequest) Reset() {
+	*x = DisableAppRequest{}
+	mi := &file_apps_apps_proto_msgTypes[9]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *DisableAppRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*DisableAppRequest) ProtoMessage() {}
+
+func (x *DisableAppRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_apps_apps_proto_msgTypes[9]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use DisableAppRequest.ProtoReflect.Descriptor instead.
+func (*DisableAppRequest) Descriptor() ([]byte, []int) {
+	return file_apps_apps_proto_rawDescGZIP(), []int{9}
+}
+
+func (x *DisableAppRequest) GetAppId() int32 {
+	if x != nil {
+		return x.AppId
+	}
+	return 0
+}
+
+type DisableAppResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *DisableAppResponse) Reset() {
+	*x = DisableAppResponse{}
+	mi := &file_apps_apps_proto_msgTypes[10]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *DisableAppResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*DisableAppResponse) ProtoMessage() {}
+
+func (x *DisableAppResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_apps_apps_proto_msgTypes[10]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use DisableAppResponse.ProtoReflect.Descriptor instead.
+func (*DisableAppResponse) Descriptor() ([]byte, []int) {
+	return file_apps_apps_proto_rawDescGZIP(), []int{10}
+}
+
+type EnableAppRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	AppId         int32                  `protobuf:"varint,1,opt,name=app_id,json=appId,proto3" json:"app_id,omitempty"` // ID of the application to enable
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *EnableAppRequest) Reset() {
+	*x = EnableAppRequest{}
+	mi := &file_apps_apps_proto_msgTypes[11]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *EnableAppRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*EnableAppRequest) ProtoMessage() {}
+
+func (x *EnableAppRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_apps_apps_proto_msgTypes[11]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use EnableAppRequest.ProtoReflect.Descriptor instead.
+func (*EnableAppRequest) Descriptor() ([]byte, []int) {
+	return file_apps_apps_proto_rawDescGZIP(), []int{11}
+}
+
+func (x *EnableAppRequest) GetAppId() int32 {
+	if x != nil {
+		return x.AppId
+	}
+	return 0
+}
+
+type EnableAppResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *EnableAppResponse) Reset() {
+	*x = EnableAppResponse{}
+	mi := &file_apps_apps_proto_msgTypes[12]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *EnableAppResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*EnableAppResponse) ProtoMessage() {}
+
+func (x *EnableAppResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_apps_apps_proto_msgTypes[12]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use EnableAppResponse.ProtoReflect.Descriptor instead.
+func (*EnableAppResponse) Descriptor() ([]byte, []int) {
+	return file_apps_apps_proto_rawDescGZIP(), []int{12}
+}
+
+type DeleteAppRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	AppId         int32                  `protobuf:"varint,1,opt,name=app_id,json=appId,proto3" json:"app_id,omitempty"` // ID of the application to delete
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *DeleteAppRequest) Reset() {
+	*x = DeleteAppRequest{}
+	mi := &file_apps_apps_proto_msgTypes[13]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *DeleteAppRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*DeleteAppRequest) ProtoMessage() {}
+
+func (x *DeleteAppRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_apps_apps_proto_msgTypes[13]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use DeleteAppRequest.ProtoReflect.Descriptor instead.
+func (*DeleteAppRequest) Descriptor() ([]byte, []int) {
+	return file_apps_apps_proto_rawDescGZIP(), []int{13}
+}
+
+func (x *DeleteAppRequest) GetAppId() int32 {
+	if x != nil {
+		return x.AppId
+	}
+	return 0
+}
+
+type DeleteAppResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *DeleteAppResponse) Reset() {
+	*x = DeleteAppResponse{}
+	mi := &file_apps_apps_proto_msgTypes[14]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *DeleteAppResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*DeleteAppResponse) ProtoMessage() {}
+
+func (x *DeleteAppResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_apps_apps_proto_msgTypes[14]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use DeleteAppResponse.ProtoReflect.Descriptor instead.
+func (*DeleteAppResponse) Descriptor() ([]byte, []int) {
+	return file_apps_apps_proto_rawDescGZIP(), []int{14}
+}
+
+type RotateClientSecretRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	AppId         int32                  `protobuf:"varint,1,opt,name=app_id,json=appId,proto3" json:"app_id,omitempty"` // ID of the application, it is also client ID of the OAuth client
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *RotateClientSecretRequest) Reset() {
+	*x = RotateClientSecretRequest{}
+	mi := &file_apps_apps_proto_msgTypes[15]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *RotateClientSecretRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*RotateClientSecretRequest) ProtoMessage() {}
+
+func (x *RotateClientSecretRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_apps_apps_proto_msgTypes[15]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use RotateClientSecretRequest.ProtoReflect.Descriptor instead.
+func (*RotateClientSecretRequest) Descriptor() ([]byte, []int) {
+	return file_apps_apps_proto_rawDescGZIP(), []int{15}
+}
+
+func (x *RotateClientSecretRequest) GetAppId() int32 {
+	if x != nil {
+		return x.AppId
+	}
+	return 0
+}
+
+type RotateClientSecretResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	ClientSecret  string                 `protobuf:"bytes,1,opt,name=client_secret,json=clientSecret,proto3" json:"client_secret,omitempty"` // New OAuth client secret, it is shown only once and replaces the previous one
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *RotateClientSecretResponse) Reset() {
+	*x = RotateClientSecretResponse{}
+	mi := &file_apps_apps_proto_msgTypes[16]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *RotateClientSecretResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*RotateClientSecretResponse) ProtoMessage() {}
+
+func (x *RotateClientSecretResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_apps_apps_proto_msgTypes[16]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use RotateClientSecretResponse.ProtoReflect.Descriptor instead.
+func (*RotateClientSecretResponse) Descriptor() ([]byte, []int) {
+	return file_apps_apps_proto_rawDescGZIP(), []int{16}
+}
+
+func (x *RotateClientSecretResponse) GetClientSecret() string {
+	if x != nil {
+		return x.ClientSecret
+	}
+	return ""
+}
+
+var File_apps_apps_proto protoreflect.FileDescriptor
+
+const file_apps_apps_proto_rawDesc = "" +
+	"\n" +
+	"\x0fapps/apps.proto\x12\x04apps\x1a google/protobuf/field_mask.proto\"\x9f\x04\n" +
+	"\vAppSettings\x12\x1f\n" +
+	"\vembed_roles\x18\x01 \x01(\bR\n" +
+	"embedRoles\x12\x1f\n" +
+	"\vembed_scope\x18\x02 \x01(\bR\n" +
+	"embedScope\x12&\n" +
+	"\x0fmax_claims_size\x18\x03 \x01(\x05R\rmaxClaimsSize\x12#\n" +
+	"\rredirect_uris\x18\x04 \x03(\tR\fredirectUris\x12#\n" +
+	"\rclient_scopes\x18\x05 \x03(\tR\fclientScopes\x12\x1f\n" +
+	"\vgrant_types\x18\x06 \x03(\tR\n" +
+	"grantTypes\x12%\n" +
+	"\x0eallowed_scopes\x18\a \x03(\tR\rallowedScopes\x127\n" +
+	"\x18access_token_ttl_seconds\x18\b \x01(\x03R\x15accessTokenTtlSeconds\x129\n" +
+	"\x19refresh_token_ttl_seconds\x18\t \x01(\x03R\x16refreshTokenTtlSeconds\x12#\n" +
+	"\rpublic_client\x18\n" +
+	" \x01(\bR\fpublicClient\x12\x1f\n" +
+	"\vthird_party\x18\v \x01(\bR\n" +
+	"thirdParty\x124\n" +
+	"\x16backchannel_logout_uri\x18\f \x01(\tR\x14backchannelLogoutUri\x12$\n" +
+	"\x05theme\x18\r \x01(\v2\x0e.apps.AppThemeR\x05theme\"\x9f\x01\n" +
+	"\bAppTheme\x12\x19\n" +
+	"\blogo_uri\x18\x01 \x01(\tR\alogoUri\x12#\n" +
+	"\rprimary_color\x18\x02 \x01(\tR\fprimaryColor\x12)\n" +
+	"\x10background_color\x18\x03 \x01(\tR\x0fbackgroundColor\x12\x14\n" +
+	"\x05title\x18\x04 \x01(\tR\x05title\x12\x12\n" +
+	"\x04text\x18\x05 \x01(\tR\x04text\"x\n" +
+	"\aAppInfo\x12\x0e\n" +
+	"\x02id\x18\x01 \x01(\x05R\x02id\x12\x12\n" +
+	"\x04name\x18\x02 \x01(\tR\x04name\x12\x1a\n" +
+	"\bdisabled\x18\x03 \x01(\bR\bdisabled\x12-\n" +
+	"\bsettings\x18\x04 \x01(\v2\x11.apps.AppSettingsR\bsettings\"U\n" +
+	"\x10CreateAppRequest\x12\x12\n" +
+	"\x04name\x18\x01 \x01(\tR\x04name\x12-\n" +
+	"\bsettings\x18\x02 \x01(\v2\x11.apps.AppSettingsR\bsettings\"L\n" +
+	"\x11CreateAppResponse\x12\x1f\n" +
+	"\x03app\x18\x01 \x01(\v2\r.apps.AppInfoR\x03app\x12\x16\n" +
+	"\x06secret\x18\x02 \x01(\tR\x06secret\"\xa9\x01\n" +
+	"\x10UpdateAppRequest\x12\x15\n" +
+	"\x06app_id\x18\x01 \x01(\x05R\x05appId\x12\x12\n" +
+	"\x04name\x18\x02 \x01(\tR\x04name\x12-\n" +
+	"\bsettings\x18\x03 \x01(\v2\x11.apps.AppSettingsR\bsettings\x12;\n" +
+	"\vupdate_mask\x18\x04 \x01(\v2\x1a.google.protobuf.FieldMaskR\n" +
+	"updateMask\"4\n" +
+	"\x11UpdateAppResponse\x12\x1f\n" +
+	"\x03app\x18\x01 \x01(\v2\r.apps.AppInfoR\x03app\"M\n" +
+	"\x0fListAppsRequest\x12\x1b\n" +
+	"\tpage_size\x18\x01 \x01(\x05R\bpageSize\x12\x1d\n" +
+	"\n" +
+	"page_token\x18\x02 \x01(\tR\tpageToken\"]\n" +
+	"\x10ListAppsResponse\x12!\n" +
+	"\x04apps\x18\x01 \x03(\v2\r.apps.AppInfoR\x04apps\x12&\n" +
+	"\x0fnext_page_token\x18\x02 \x01(\tR\rnextPageToken\"*\n" +
+	"\x11DisableAppRequest\x12\x15\n" +
+	"\x06app_id\x18\x01 \x01(\x05R\x05appId\"\x14\n" +
+	"\x12DisableAppResponse\")\n" +
+	"\x10EnableAppRequest\x12\x15\n" +
+	"\x06app_id\x18\x01 \x01(\x05R\x05appId\"\x13\n" +
+	"\x11EnableAppResponse\")\n" +
+	"\x10DeleteAppRequest\x12\x15\n" +
+	"\x06app_id\x18\x01 \x01(\x05R\x05appId\"\x13\n" +
+	"\x11DeleteAppResponse\"2\n" +
+	"\x19RotateClientSecretRequest\x12\x15\n" +
+	"\x06app_id\x18\x01 \x01(\x05R\x05appId\"A\n" +
+	"\x1aRotateClientSecretResponse\x12#\n" +
+	"\rclient_secret\x18\x01 \x01(\tR\fclientSecret2\xd9\x03\n" +
+	"\n" +
+	"AppService\x12<\n" +
+	"\tCreateApp\x12\x16.apps.CreateAppRequest\x1a\x17.apps.CreateAppResponse\x12<\n" +
+	"\tUpdateApp\x12\x16.apps.UpdateAppRequest\x1a\x17.apps.UpdateAppResponse\x129\n" +
+	"\bListApps\x12\x15.apps.ListAppsRequest\x1a\x16.apps.ListAppsResponse\x12?\n" +
+	"\n" +
+	"DisableApp\x12\x17.apps.DisableAppRequest\x1a\x18.apps.DisableAppResponse\x12<\n" +
+	"\tEnableApp\x12\x16.apps.EnableAppRequest\x1a\x17.apps.EnableAppResponse\x12<\n" +
+	"\tDeleteApp\x12\x16.apps.DeleteAppRequest\x1a\x17.apps.DeleteAppResponse\x12W\n" +
+	"\x12RotateClientSecret\x12\x1f.apps.RotateClientSecretRequest\x1a .apps.RotateClientSecretResponseB?Z=github.com/nhassl3/sso-app/contracts/generated/go/apps;appsv1b\x06proto3"
+
+var (
+	file_apps_apps_proto_rawDescOnce sync.Once
+	file_apps_apps_proto_rawDescData []byte
+)
+
+func file_apps_apps_proto_rawDescGZIP() []byte {
+	file_apps_apps_proto_rawDescOnce.Do(func() {
+		file_apps_apps_proto_rawDescData = protoimpl.X.CompressGZIP(unsafe.Slice(unsafe.StringData(file_apps_apps_proto_rawDesc), len(file_apps_apps_proto_rawDesc)))
+	})
+	return file_apps_apps_proto_rawDescData
+}
+
+var file_apps_apps_proto_msgTypes = make([]protoimpl.MessageInfo, 17)
+var file_apps_apps_proto_goTypes = []any{
+	(*AppSettings)(nil),                // 0: apps.AppSettings
+	(*AppTheme)(nil),                   // 1: apps.AppTheme
+	(*AppInfo)(nil),                    // 2: apps.AppInfo
+	(*CreateAppRequest)(nil),           // 3: apps.CreateAppRequest
+	(*CreateAppResponse)(nil),          // 4: apps.CreateAppResponse
+	(*UpdateAppRequest)(nil),           // 5: apps.UpdateAppRequest
+	(*UpdateAppResponse)(nil),          // 6: apps.UpdateAppResponse
+	(*ListAppsRequest)(nil),            // 7: apps.ListAppsRequest
+	(*ListAppsResponse)(nil),           // 8: apps.ListAppsResponse
+	(*DisableAppRequest)(nil),          // 9: apps.DisableAppRequest
+	(*DisableAppResponse)(nil),         // 10: apps.DisableAppResponse
+	(*EnableAppRequest)(nil),           // 11: apps.EnableAppRequest
+	(*EnableAppResponse)(nil),          // 12: apps.EnableAppResponse
+	(*DeleteAppRequest)(nil),           // 13: apps.DeleteAppRequest
+	(*DeleteAppResponse)(nil),          // 14: apps.DeleteAppResponse
+	(*RotateClientSecretRequest)(nil),  // 15: apps.RotateClientSecretRequest
+	(*RotateClientSecretResponse)(nil), // 16: apps.RotateClientSecretResponse
+	(*fieldmaskpb.FieldMask)(nil),      // 17: google.protobuf.FieldMask
+}
+var file_apps_apps_proto_depIdxs = []int32{
+	1,  // 0: apps.AppSettings.theme:type_name -> apps.AppTheme
+	0,  // 1: apps.AppInfo.settings:type_name -> apps.AppSettings
+	0,  // 2: apps.CreateAppRequest.settings:type_name -> apps.AppSettings
+	2,  // 3: apps.CreateAppResponse.app:type_name -> apps.AppInfo
+	0,  // 4: apps.UpdateAppRequest.settings:type_name -> apps.AppSettings
+	17, // 5: apps.UpdateAppRequest.update_mask:type_name -> google.protobuf.FieldMask
+	2,  // 6: apps.UpdateAppResponse.app:type_name -> apps.AppInfo
+	2,  // 7: apps.ListAppsResponse.apps:type_name -> apps.AppInfo
+	3,  // 8: apps.AppService.CreateApp:input_type -> apps.CreateAppRequest
+	5,  // 9: apps.AppService.UpdateApp:input_type -> apps.UpdateAppRequest
+	7,  // 10: apps.AppService.ListApps:input_type -> apps.ListAppsRequest
+	9,  // 11: apps.AppService.DisableApp:input_type -> apps.DisableAppRequest
+	11, // 12: apps.AppService.EnableApp:input_type -> apps.EnableAppRequest
+	13, // 13: apps.AppService.DeleteApp:input_type -> apps.DeleteAppRequest
+	15, // 14: apps.AppService.RotateClientSecret:input_type -> apps.RotateClientSecretRequest
+	4,  // 15: apps.AppService.CreateApp:output_type -> apps.CreateAppResponse
+	6,  // 16: apps.AppService.UpdateApp:output_type -> apps.UpdateAppResponse
+	8,  // 17: apps.AppService.ListApps:output_type -> apps.ListAppsResponse
+	10, // 18: apps.AppService.DisableApp:output_type -> apps.DisableAppResponse
+	12, // 19: apps.AppService.EnableApp:output_type -> apps.EnableAppResponse
+	14, // 20: apps.AppService.DeleteApp:output_type -> apps.DeleteAppResponse
+	16, // 21: apps.AppService.RotateClientSecret:output_type -> apps.RotateClientSecretResponse
+	15, // [15:22] is the sub-list for method output_type
+	8,  // [8:15] is the sub-list for method input_type
+	8,  // [8:8] is the sub-list for extension type_name
+	8,  // [8:8] is the sub-list for extension extendee
+	0,  // [0:8] is the sub-list for field type_name
+}
+
+func init() { file_apps_apps_proto_init() }
+func file_apps_apps_proto_init() {
+	if File_apps_apps_proto != nil {
+		return
+	}
+	type x struct{}
+	out := protoimpl.TypeBuilder{
+		File: protoimpl.DescBuilder{
+			GoPackagePath: reflect.TypeOf(x{}).PkgPath(),
+			RawDescriptor: unsafe.Slice(unsafe.StringData(file_apps_apps_proto_rawDesc), len(file_apps_apps_proto_rawDesc)),
+			NumEnums:      0,
+			NumMessages:   17,
+			NumExtensions: 0,
+			NumServices:   1,
+		},
+		GoTypes:           file_apps_apps_proto_goTypes,
+		DependencyIndexes: file_apps_apps_proto_depIdxs,
+		MessageInfos:      file_apps_apps_proto_msgTypes,
+	}.Build()
+	File_apps_apps_proto = out.File
+	file_apps_apps_proto_goTypes = nil
+	file_apps_apps_proto_depIdxs = nil
+}
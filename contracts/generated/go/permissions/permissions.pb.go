@@ -0,0 +1,1919 @@
+// Code generated by protoc-gen-go. DO NOT EDIT.
+// versions:
+// 	protoc-gen-go v1.36.9
+// 	protoc        v3.21.12
+// source: permissions/permissions.proto
+
+package permissionsv1
+
+import (
+	protoreflect "google.golang.org/protobuf/reflect/protoreflect"
+	protoimpl "google.golang.org/protobuf/runtime/protoimpl"
+	reflect "reflect"
+	sync "sync"
+	unsafe "unsafe"
+)
+
+const (
+	// Verify that this generated code is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(20 - protoimpl.MinVersion)
+	// Verify that runtime/protoimpl is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(protoimpl.MaxVersion - 20)
+)
+
+type WriteNamespaceConfigRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	AppId         int32                  `protobuf:"varint,1,opt,name=app_id,json=appId,proto3" json:"app_id,omitempty"` // ID of the application
+	Config        string                 `protobuf:"bytes,2,opt,name=config,proto3" json:"config,omitempty"`             // Namespace config in JSON, until it is written any relation consists of the tuples only
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *WriteNamespaceConfigRequest) Reset() {
+	*x = WriteNamespaceConfigRequest{}
+	mi := &file_permissions_permissions_proto_msgTypes[0]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *WriteNamespaceConfigRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*WriteNamespaceConfigRequest) ProtoMessage() {}
+
+func (x *WriteNamespaceConfigRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_permissions_permissions_proto_msgTypes[0]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use WriteNamespaceConfigRequest.ProtoReflect.Descriptor instead.
+func (*WriteNamespaceConfigRequest) Descriptor() ([]byte, []int) {
+	return file_permissions_permissions_proto_rawDescGZIP(), []int{0}
+}
+
+func (x *WriteNamespaceConfigRequest) GetAppId() int32 {
+	if x != nil {
+		return x.AppId
+	}
+	return 0
+}
+
+func (x *WriteNamespaceConfigRequest) GetConfig() string {
+	if x != nil {
+		return x.Config
+	}
+	return ""
+}
+
+type WriteNamespaceConfigResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *WriteNamespaceConfigResponse) Reset() {
+	*x = WriteNamespaceConfigResponse{}
+	mi := &file_permissions_permissions_proto_msgTypes[1]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *WriteNamespaceConfigResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*WriteNamespaceConfigResponse) ProtoMessage() {}
+
+func (x *WriteNamespaceConfigResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_permissions_permissions_proto_msgTypes[1]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use WriteNamespaceConfigResponse.ProtoReflect.Descriptor instead.
+func (*WriteNamespaceConfigResponse) Descriptor() ([]byte, []int) {
+	return file_permissions_permissions_proto_rawDescGZIP(), []int{1}
+}
+
+type ReadNamespaceConfigRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	AppId         int32                  `protobuf:"varint,1,opt,name=app_id,json=appId,proto3" json:"app_id,omitempty"` // ID of the application
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ReadNamespaceConfigRequest) Reset() {
+	*x = ReadNamespaceConfigRequest{}
+	mi := &file_permissions_permissions_proto_msgTypes[2]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ReadNamespaceConfigRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ReadNamespaceConfigRequest) ProtoMessage() {}
+
+func (x *ReadNamespaceConfigRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_permissions_permissions_proto_msgTypes[2]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ReadNamespaceConfigRequest.ProtoReflect.Descriptor instead.
+func (*ReadNamespaceConfigRequest) Descriptor() ([]byte, []int) {
+	return file_permissions_permissions_proto_rawDescGZIP(), []int{2}
+}
+
+func (x *ReadNamespaceConfigRequest) GetAppId() int32 {
+	if x != nil {
+		return x.AppId
+	}
+	return 0
+}
+
+type ReadNamespaceConfigResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Config        string                 `protobuf:"bytes,1,opt,name=config,proto3" json:"config,omitempty"` // Namespace config in JSON
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ReadNamespaceConfigResponse) Reset() {
+	*x = ReadNamespaceConfigResponse{}
+	mi := &file_permissions_permissions_proto_msgTypes[3]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ReadNamespaceConfigResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ReadNamespaceConfigResponse) ProtoMessage() {}
+
+func (x *ReadNamespaceConfigResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_permissions_permissions_proto_msgTypes[3]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ReadNamespaceConfigResponse.ProtoReflect.Descriptor instead.
+func (*ReadNamespaceConfigResponse) Descriptor() ([]byte, []int) {
+	return file_permissions_permissions_proto_rawDescGZIP(), []int{3}
+}
+
+func (x *ReadNamespaceConfigResponse) GetConfig() string {
+	if x != nil {
+		return x.Config
+	}
+	return ""
+}
+
+type WriteTuplesRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	AppId         int32                  `protobuf:"varint,1,opt,name=app_id,json=appId,proto3" json:"app_id,omitempty"` // ID of the application
+	Writes        []string               `protobuf:"bytes,2,rep,name=writes,proto3" json:"writes,omitempty"`             // Tuples to write
+	Deletes       []string               `protobuf:"bytes,3,rep,name=deletes,proto3" json:"deletes,omitempty"`           // Tuples to delete
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *WriteTuplesRequest) Reset() {
+	*x = WriteTuplesRequest{}
+	mi := &file_permissions_permissions_proto_msgTypes[4]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *WriteTuplesRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*WriteTuplesRequest) ProtoMessage() {}
+
+func (x *WriteTuplesRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_permissions_permissions_proto_msgTypes[4]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use WriteTuplesRequest.ProtoReflect.Descriptor instead.
+func (*WriteTuplesRequest) Descriptor() ([]byte, []int) {
+	return file_permissions_permissions_proto_rawDescGZIP(), []int{4}
+}
+
+func (x *WriteTuplesRequest) GetAppId() int32 {
+	if x != nil {
+		return x.AppId
+	}
+	return 0
+}
+
+func (x *WriteTuplesRequest) GetWrites() []string {
+	if x != nil {
+		return x.Writes
+	}
+	return nil
+}
+
+func (x *WriteTuplesRequest) GetDeletes() []string {
+	if x != nil {
+		return x.Deletes
+	}
+	return nil
+}
+
+type WriteTuplesResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Revision      int64                  `protobuf:"varint,1,opt,name=revision,proto3" json:"revision,omitempty"` // Revision of the tuples after the write
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *WriteTuplesResponse) Reset() {
+	*x = WriteTuplesResponse{}
+	mi := &file_permissions_permissions_proto_msgTypes[5]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *WriteTuplesResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*WriteTuplesResponse) ProtoMessage() {}
+
+func (x *WriteTuplesResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_permissions_permissions_proto_msgTypes[5]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use WriteTuplesResponse.ProtoReflect.Descriptor instead.
+func (*WriteTuplesResponse) Descriptor() ([]byte, []int) {
+	return file_permissions_permissions_proto_rawDescGZIP(), []int{5}
+}
+
+func (x *WriteTuplesResponse) GetRevision() int64 {
+	if x != nil {
+		return x.Revision
+	}
+	return 0
+}
+
+type CheckRequest struct {
+	state           protoimpl.MessageState `protogen:"open.v1"`
+	AppId           int32                  `protobuf:"varint,1,opt,name=app_id,json=appId,proto3" json:"app_id,omitempty"`                                 // ID of the application
+	Object          string                 `protobuf:"bytes,2,opt,name=object,proto3" json:"object,omitempty"`                                             // Object written as type:id
+	Relation        string                 `protobuf:"bytes,3,opt,name=relation,proto3" json:"relation,omitempty"`                                         // Relation of the object
+	Subject         string                 `protobuf:"bytes,4,opt,name=subject,proto3" json:"subject,omitempty"`                                           // Subject written as type:id or type:id#relation
+	AtLeastRevision int64                  `protobuf:"varint,5,opt,name=at_least_revision,json=atLeastRevision,proto3" json:"at_least_revision,omitempty"` // Min revision of the tuples to read
+	unknownFields   protoimpl.UnknownFields
+	sizeCache       protoimpl.SizeCache
+}
+
+func (x *CheckRequest) Reset() {
+	*x = CheckRequest{}
+	mi := &file_permissions_permissions_proto_msgTypes[6]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *CheckRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*CheckRequest) ProtoMessage() {}
+
+func (x *CheckRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_permissions_permissions_proto_msgTypes[6]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use CheckRequest.ProtoReflect.Descriptor instead.
+func (*CheckRequest) Descriptor() ([]byte, []int) {
+	return file_permissions_permissions_proto_rawDescGZIP(), []int{6}
+}
+
+func (x *CheckRequest) GetAppId() int32 {
+	if x != nil {
+		return x.AppId
+	}
+	return 0
+}
+
+func (x *CheckRequest) GetObject() string {
+	if x != nil {
+		return x.Object
+	}
+	return ""
+}
+
+func (x *CheckRequest) GetRelation() string {
+	if x != nil {
+		return x.Relation
+	}
+	return ""
+}
+
+func (x *CheckRequest) GetSubject() string {
+	if x != nil {
+		return x.Subject
+	}
+	return ""
+}
+
+func (x *CheckRequest) GetAtLeastRevision() int64 {
+	if x != nil {
+		return x.AtLeastRevision
+	}
+	return 0
+}
+
+type CheckResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Allowed       bool                   `protobuf:"varint,1,opt,name=allowed,proto3" json:"allowed,omitempty"`   // Subject has the relation with the object
+	Revision      int64                  `protobuf:"varint,2,opt,name=revision,proto3" json:"revision,omitempty"` // Revision of the tuples which were read
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *CheckResponse) Reset() {
+	*x = CheckResponse{}
+	mi := &file_permissions_permissions_proto_msgTypes[7]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *CheckResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*CheckResponse) ProtoMessage() {}
+
+func (x *CheckResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_permissions_permissions_proto_msgTypes[7]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use CheckResponse.ProtoReflect.Descriptor instead.
+func (*CheckResponse) Descriptor() ([]byte, []int) {
+	return file_permissions_permissions_proto_rawDescGZIP(), []int{7}
+}
+
+func (x *CheckResponse) GetAllowed() bool {
+	if x != nil {
+		return x.Allowed
+	}
+	return false
+}
+
+func (x *CheckResponse) GetRevision() int64 {
+	if x != nil {
+		return x.Revision
+	}
+	return 0
+}
+
+type CheckItem struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Object        string                 `protobuf:"bytes,1,opt,name=object,proto3" json:"object,omitempty"`     // Object written as type:id
+	Relation      string                 `protobuf:"bytes,2,opt,name=relation,proto3" json:"relation,omitempty"` // Relation of the object
+	Subject       string                 `protobuf:"bytes,3,opt,name=subject,proto3" json:"subject,omitempty"`   // Subject written as type:id or type:id#relation
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *CheckItem) Reset() {
+	*x = CheckItem{}
+	mi := &file_permissions_permissions_proto_msgTypes[8]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *CheckItem) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*CheckItem) ProtoMessage() {}
+
+func (x *CheckItem) ProtoReflect() protoreflect.Message {
+	mi := &file_permissions_permissions_proto_msgTypes[8]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use CheckItem.ProtoReflect.Descriptor instead.
+func (*CheckItem) Descriptor() ([]byte, []int) {
+	return file_permissions_permissions_proto_rawDescGZIP(), []int{8}
+}
+
+func (x *CheckItem) GetObject() string {
+	if x != nil {
+		return x.Object
+	}
+	return ""
+}
+
+func (x *CheckItem) GetRelation() string {
+	if x != nil {
+		return x.Relation
+	}
+	return ""
+}
+
+func (x *CheckItem) GetSubject() string {
+	if x != nil {
+		return x.Subject
+	}
+	return ""
+}
+
+type BatchCheckRequest struct {
+	state           protoimpl.MessageState `protogen:"open.v1"`
+	AppId           int32                  `protobuf:"varint,1,opt,name=app_id,json=appId,proto3" json:"app_id,omitempty"`                                 // ID of the application
+	Items           []*CheckItem           `protobuf:"bytes,2,rep,name=items,proto3" json:"items,omitempty"`                                               // Checks to answer, 100 at most
+	AtLeastRevision int64                  `protobuf:"varint,3,opt,name=at_least_revision,json=atLeastRevision,proto3" json:"at_least_revision,omitempty"` // Min revision of the tuples to read
+	unknownFields   protoimpl.UnknownFields
+	sizeCache       protoimpl.SizeCache
+}
+
+func (x *BatchCheckRequest) Reset() {
+	*x = BatchCheckRequest{}
+	mi := &file_permissions_permissions_proto_msgTypes[9]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *BatchCheckRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*BatchCheckRequest) ProtoMessage() {}
+
+func (x *BatchCheckRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_permissions_permissions_proto_msgTypes[9]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use BatchCheckRequest.ProtoReflect.Descriptor instead.
+func (*BatchCheckRequest) Descriptor() ([]byte, []int) {
+	return file_permissions_permissions_proto_rawDescGZIP(), []int{9}
+}
+
+func (x *BatchCheckRequest) GetAppId() int32 {
+	if x != nil {
+		return x.AppId
+	}
+	return 0
+}
+
+func (x *BatchCheckRequest) GetItems() []*CheckItem {
+	if x != nil {
+		return x.Items
+	}
+	return nil
+}
+
+func (x *BatchCheckRequest) GetAtLeastRevision() int64 {
+	if x != nil {
+		return x.AtLeastRevision
+	}
+	return 0
+}
+
+type BatchCheckResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Allowed       []bool                 `protobuf:"varint,1,rep,packed,name=allowed,proto3" json:"allowed,omitempty"` // Results in order of the items
+	Revision      int64                  `protobuf:"varint,2,opt,name=revision,proto3" json:"revision,omitempty"`      // The latest revision of the tuples which the results are based on
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *BatchCheckResponse) Reset() {
+	*x = BatchCheckResponse{}
+	mi := &file_permissions_permissions_proto_msgTypes[10]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *BatchCheckResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*BatchCheckResponse) ProtoMessage() {}
+
+func (x *BatchCheckResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_permissions_permissions_proto_msgTypes[10]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use BatchCheckResponse.ProtoReflect.Descriptor instead.
+func (*BatchCheckResponse) Descriptor() ([]byte, []int) {
+	return file_permissions_permissions_proto_rawDescGZIP(), []int{10}
+}
+
+func (x *BatchCheckResponse) GetAllowed() []bool {
+	if x != nil {
+		return x.Allowed
+	}
+	return nil
+}
+
+func (x *BatchCheckResponse) GetRevision() int64 {
+	if x != nil {
+		return x.Revision
+	}
+	return 0
+}
+
+type CacheStatsRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	AppId         int32                  `protobuf:"varint,1,opt,name=app_id,json=appId,proto3" json:"app_id,omitempty"` // ID of the application
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *CacheStatsRequest) Reset() {
+	*x = CacheStatsRequest{}
+	mi := &file_permissions_permissions_proto_msgTypes[11]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *CacheStatsRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*CacheStatsRequest) ProtoMessage() {}
+
+func (x *CacheStatsRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_permissions_permissions_proto_msgTypes[11]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use CacheStatsRequest.ProtoReflect.Descriptor instead.
+func (*CacheStatsRequest) Descriptor() ([]byte, []int) {
+	return file_permissions_permissions_proto_rawDescGZIP(), []int{11}
+}
+
+func (x *CacheStatsRequest) GetAppId() int32 {
+	if x != nil {
+		return x.AppId
+	}
+	return 0
+}
+
+// Checks are answered from the in-process decision cache until tuples or namespace config of the app change
+type CacheStatsResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Hits          uint64                 `protobuf:"varint,1,opt,name=hits,proto3" json:"hits,omitempty"`                   // Checks answered from the cache
+	Misses        uint64                 `protobuf:"varint,2,opt,name=misses,proto3" json:"misses,omitempty"`               // Checks computed on the tuples
+	Invalidations uint64                 `protobuf:"varint,3,opt,name=invalidations,proto3" json:"invalidations,omitempty"` // Times the cached decisions of the app were dropped
+	Size          int32                  `protobuf:"varint,4,opt,name=size,proto3" json:"size,omitempty"`                   // Count of the cached decisions of the app
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *CacheStatsResponse) Reset() {
+	*x = CacheStatsResponse{}
+	mi := &file_permissions_permissions_proto_msgTypes[12]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *CacheStatsResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*CacheStatsResponse) ProtoMessage() {}
+
+func (x *CacheStatsResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_permissions_permissions_proto_msgTypes[12]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use CacheStatsResponse.ProtoReflect.Descriptor instead.
+func (*CacheStatsResponse) Descriptor() ([]byte, []int) {
+	return file_permissions_permissions_proto_rawDescGZIP(), []int{12}
+}
+
+func (x *CacheStatsResponse) GetHits() uint64 {
+	if x != nil {
+		return x.Hits
+	}
+	return 0
+}
+
+func (x *CacheStatsResponse) GetMisses() uint64 {
+	if x != nil {
+		return x.Misses
+	}
+	return 0
+}
+
+func (x *CacheStatsResponse) GetInvalidations() uint64 {
+	if x != nil {
+		return x.Invalidations
+	}
+	return 0
+}
+
+func (x *CacheStatsResponse) GetSize() int32 {
+	if x != nil {
+		return x.Size
+	}
+	return 0
+}
+
+type ExpandRequest struct {
+	state           protoimpl.MessageState `protogen:"open.v1"`
+	AppId           int32                  `protobuf:"varint,1,opt,name=app_id,json=appId,proto3" json:"app_id,omitempty"`                                 // ID of the application
+	Object          string                 `protobuf:"bytes,2,opt,name=object,proto3" json:"object,omitempty"`                                             // Object written as type:id
+	Relation        string                 `protobuf:"bytes,3,opt,name=relation,proto3" json:"relation,omitempty"`                                         // Relation of the object
+	AtLeastRevision int64                  `protobuf:"varint,4,opt,name=at_least_revision,json=atLeastRevision,proto3" json:"at_least_revision,omitempty"` // Min revision of the tuples to read
+	unknownFields   protoimpl.UnknownFields
+	sizeCache       protoimpl.SizeCache
+}
+
+func (x *ExpandRequest) Reset() {
+	*x = ExpandRequest{}
+	mi := &file_permissions_permissions_proto_msgTypes[13]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ExpandRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ExpandRequest) ProtoMessage() {}
+
+func (x *ExpandRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_permissions_permissions_proto_msgTypes[13]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ExpandRequest.ProtoReflect.Descriptor instead.
+func (*ExpandRequest) Descriptor() ([]byte, []int) {
+	return file_permissions_permissions_proto_rawDescGZIP(), []int{13}
+}
+
+func (x *ExpandRequest) GetAppId() int32 {
+	if x != nil {
+		return x.AppId
+	}
+	return 0
+}
+
+func (x *ExpandRequest) GetObject() string {
+	if x != nil {
+		return x.Object
+	}
+	return ""
+}
+
+func (x *ExpandRequest) GetRelation() string {
+	if x != nil {
+		return x.Relation
+	}
+	return ""
+}
+
+func (x *ExpandRequest) GetAtLeastRevision() int64 {
+	if x != nil {
+		return x.AtLeastRevision
+	}
+	return 0
+}
+
+type UsersetTree struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Operation     string                 `protobuf:"bytes,1,opt,name=operation,proto3" json:"operation,omitempty"` // leaf, union, intersection or exclusion
+	Object        string                 `protobuf:"bytes,2,opt,name=object,proto3" json:"object,omitempty"`       // Object of the userset
+	Relation      string                 `protobuf:"bytes,3,opt,name=relation,proto3" json:"relation,omitempty"`   // Relation of the userset
+	Subjects      []string               `protobuf:"bytes,4,rep,name=subjects,proto3" json:"subjects,omitempty"`   // Subjects written directly, for leaf only
+	Children      []*UsersetTree         `protobuf:"bytes,5,rep,name=children,proto3" json:"children,omitempty"`   // Operands of the operation, for exclusion the second one is subtracted
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *UsersetTree) Reset() {
+	*x = UsersetTree{}
+	mi := &file_permissions_permissions_proto_msgTypes[14]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *UsersetTree) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*UsersetTree) ProtoMessage() {}
+
+func (x *UsersetTree) ProtoReflect() protoreflect.Message {
+	mi := &file_permissions_permissions_proto_msgTypes[14]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use UsersetTree.ProtoReflect.Descriptor instead.
+func (*UsersetTree) Descriptor() ([]byte, []int) {
+	return file_permissions_permissions_proto_rawDescGZIP(), []int{14}
+}
+
+func (x *UsersetTree) GetOperation() string {
+	if x != nil {
+		return x.Operation
+	}
+	return ""
+}
+
+func (x *UsersetTree) GetObject() string {
+	if x != nil {
+		return x.Object
+	}
+	return ""
+}
+
+func (x *UsersetTree) GetRelation() string {
+	if x != nil {
+		return x.Relation
+	}
+	return ""
+}
+
+func (x *UsersetTree) GetSubjects() []string {
+	if x != nil {
+		return x.Subjects
+	}
+	return nil
+}
+
+func (x *UsersetTree) GetChildren() []*UsersetTree {
+	if x != nil {
+		return x.Children
+	}
+	return nil
+}
+
+type ExpandResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Tree          *UsersetTree           `protobuf:"bytes,1,opt,name=tree,proto3" json:"tree,omitempty"`          // Tree of the subjects which have the relation with the object
+	Revision      int64                  `protobuf:"varint,2,opt,name=revision,proto3" json:"revision,omitempty"` // Revision of the tuples which were read
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ExpandResponse) Reset() {
+	*x = ExpandResponse{}
+	mi := &file_permissions_permissions_proto_msgTypes[15]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ExpandResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ExpandResponse) ProtoMessage() {}
+
+func (x *ExpandResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_permissions_permissions_proto_msgTypes[15]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ExpandResponse.ProtoReflect.Descriptor instead.
+func (*ExpandResponse) Descriptor() ([]byte, []int) {
+	return file_permissions_permissions_proto_rawDescGZIP(), []int{15}
+}
+
+func (x *ExpandResponse) GetTree() *UsersetTree {
+	if x != nil {
+		return x.Tree
+	}
+	return nil
+}
+
+func (x *ExpandResponse) GetRevision() int64 {
+	if x != nil {
+		return x.Revision
+	}
+	return 0
+}
+
+type ListObjectsRequest struct {
+	state           protoimpl.MessageState `protogen:"open.v1"`
+	AppId           int32                  `protobuf:"varint,1,opt,name=app_id,json=appId,proto3" json:"app_id,omitempty"`                                 // ID of the application
+	ObjectType      string                 `protobuf:"bytes,2,opt,name=object_type,json=objectType,proto3" json:"object_type,omitempty"`                   // Type of the objects
+	Relation        string                 `protobuf:"bytes,3,opt,name=relation,proto3" json:"relation,omitempty"`                                         // Relation of the objects
+	Subject         string                 `protobuf:"bytes,4,opt,name=subject,proto3" json:"subject,omitempty"`                                           // Subject written as type:id or type:id#relation
+	AtLeastRevision int64                  `protobuf:"varint,5,opt,name=at_least_revision,json=atLeastRevision,proto3" json:"at_least_revision,omitempty"` // Min revision of the tuples to read
+	unknownFields   protoimpl.UnknownFields
+	sizeCache       protoimpl.SizeCache
+}
+
+func (x *ListObjectsRequest) Reset() {
+	*x = ListObjectsRequest{}
+	mi := &file_permissions_permissions_proto_msgTypes[16]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ListObjectsRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ListObjectsRequest) ProtoMessage() {}
+
+func (x *ListObjectsRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_permissions_permissions_proto_msgTypes[16]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ListObjectsRequest.ProtoReflect.Descriptor instead.
+func (*ListObjectsRequest) Descriptor() ([]byte, []int) {
+	return file_permissions_permissions_proto_rawDescGZIP(), []int{16}
+}
+
+func (x *ListObjectsRequest) GetAppId() int32 {
+	if x != nil {
+		return x.AppId
+	}
+	return 0
+}
+
+func (x *ListObjectsRequest) GetObjectType() string {
+	if x != nil {
+		return x.ObjectType
+	}
+	return ""
+}
+
+func (x *ListObjectsRequest) GetRelation() string {
+	if x != nil {
+		return x.Relation
+	}
+	return ""
+}
+
+func (x *ListObjectsRequest) GetSubject() string {
+	if x != nil {
+		return x.Subject
+	}
+	return ""
+}
+
+func (x *ListObjectsRequest) GetAtLeastRevision() int64 {
+	if x != nil {
+		return x.AtLeastRevision
+	}
+	return 0
+}
+
+type ListObjectsResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	ObjectIds     []string               `protobuf:"bytes,1,rep,name=object_ids,json=objectIds,proto3" json:"object_ids,omitempty"` // IDs of the objects with which the subject has the relation
+	Revision      int64                  `protobuf:"varint,2,opt,name=revision,proto3" json:"revision,omitempty"`                   // Revision of the tuples which were read
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ListObjectsResponse) Reset() {
+	*x = ListObjectsResponse{}
+	mi := &file_permissions_permissions_proto_msgTypes[17]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ListObjectsResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ListObjectsResponse) ProtoMessage() {}
+
+func (x *ListObjectsResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_permissions_permissions_proto_msgTypes[17]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ListObjectsResponse.ProtoReflect.Descriptor instead.
+func (*ListObjectsResponse) Descriptor() ([]byte, []int) {
+	return file_permissions_permissions_proto_rawDescGZIP(), []int{17}
+}
+
+func (x *ListObjectsResponse) GetObjectIds() []string {
+	if x != nil {
+		return x.ObjectIds
+	}
+	return nil
+}
+
+func (x *ListObjectsResponse) GetRevision() int64 {
+	if x != nil {
+		return x.Revision
+	}
+	return 0
+}
+
+type WritePolicyRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	AppId         int32                  `protobuf:"varint,1,opt,name=app_id,json=appId,proto3" json:"app_id,omitempty"` // ID of the application
+	Policy        string                 `protobuf:"bytes,2,opt,name=policy,proto3" json:"policy,omitempty"`             // Policy in JSON: {"rules": [{"name", "effect": "allow|deny", "condition"}], "default": "deny"}
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *WritePolicyRequest) Reset() {
+	*x = WritePolicyRequest{}
+	mi := &file_permissions_permissions_proto_msgTypes[18]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *WritePolicyRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*WritePolicyRequest) ProtoMessage() {}
+
+func (x *WritePolicyRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_permissions_permissions_proto_msgTypes[18]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use WritePolicyRequest.ProtoReflect.Descriptor instead.
+func (*WritePolicyRequest) Descriptor() ([]byte, []int) {
+	return file_permissions_permissions_proto_rawDescGZIP(), []int{18}
+}
+
+func (x *WritePolicyRequest) GetAppId() int32 {
+	if x != nil {
+		return x.AppId
+	}
+	return 0
+}
+
+func (x *WritePolicyRequest) GetPolicy() string {
+	if x != nil {
+		return x.Policy
+	}
+	return ""
+}
+
+type WritePolicyResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Version       int64                  `protobuf:"varint,1,opt,name=version,proto3" json:"version,omitempty"` // Version of the written policy
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *WritePolicyResponse) Reset() {
+	*x = WritePolicyResponse{}
+	mi := &file_permissions_permissions_proto_msgTypes[19]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *WritePolicyResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*WritePolicyResponse) ProtoMessage() {}
+
+func (x *WritePolicyResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_permissions_permissions_proto_msgTypes[19]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use WritePolicyResponse.ProtoReflect.Descriptor instead.
+func (*WritePolicyResponse) Descriptor() ([]byte, []int) {
+	return file_permissions_permissions_proto_rawDescGZIP(), []int{19}
+}
+
+func (x *WritePolicyResponse) GetVersion() int64 {
+	if x != nil {
+		return x.Version
+	}
+	return 0
+}
+
+type ReadPolicyRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	AppId         int32                  `protobuf:"varint,1,opt,name=app_id,json=appId,proto3" json:"app_id,omitempty"` // ID of the application
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ReadPolicyRequest) Reset() {
+	*x = ReadPolicyRequest{}
+	mi := &file_permissions_permissions_proto_msgTypes[20]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ReadPolicyRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ReadPolicyRequest) ProtoMessage() {}
+
+func (x *ReadPolicyRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_permissions_permissions_proto_msgTypes[20]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ReadPolicyRequest.ProtoReflect.Descriptor instead.
+func (*ReadPolicyRequest) Descriptor() ([]byte, []int) {
+	return file_permissions_permissions_proto_rawDescGZIP(), []int{20}
+}
+
+func (x *ReadPolicyRequest) GetAppId() int32 {
+	if x != nil {
+		return x.AppId
+	}
+	return 0
+}
+
+type ReadPolicyResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Policy        string                 `protobuf:"bytes,1,opt,name=policy,proto3" json:"policy,omitempty"`    // Policy in JSON
+	Version       int64                  `protobuf:"varint,2,opt,name=version,proto3" json:"version,omitempty"` // Version of the policy, zero if it is not written yet
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ReadPolicyResponse) Reset() {
+	*x = ReadPolicyResponse{}
+	mi := &file_permissions_permissions_proto_msgTypes[21]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ReadPolicyResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ReadPolicyResponse) ProtoMessage() {}
+
+func (x *ReadPolicyResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_permissions_permissions_proto_msgTypes[21]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ReadPolicyResponse.ProtoReflect.Descriptor instead.
+func (*ReadPolicyResponse) Descriptor() ([]byte, []int) {
+	return file_permissions_permissions_proto_rawDescGZIP(), []int{21}
+}
+
+func (x *ReadPolicyResponse) GetPolicy() string {
+	if x != nil {
+		return x.Policy
+	}
+	return ""
+}
+
+func (x *ReadPolicyResponse) GetVersion() int64 {
+	if x != nil {
+		return x.Version
+	}
+	return 0
+}
+
+type EvaluateRequest struct {
+	state             protoimpl.MessageState `protogen:"open.v1"`
+	AppId             int32                  `protobuf:"varint,1,opt,name=app_id,json=appId,proto3" json:"app_id,omitempty"`                                    // ID of the application
+	UserId            int64                  `protobuf:"varint,2,opt,name=user_id,json=userId,proto3" json:"user_id,omitempty"`                                 // ID of the user, email and roles of the user in the app become user.email and user.roles
+	UserAttributes    string                 `protobuf:"bytes,3,opt,name=user_attributes,json=userAttributes,proto3" json:"user_attributes,omitempty"`          // JSON object of user.attributes
+	Resource          string                 `protobuf:"bytes,4,opt,name=resource,proto3" json:"resource,omitempty"`                                            // JSON object of resource
+	Ip                string                 `protobuf:"bytes,5,opt,name=ip,proto3" json:"ip,omitempty"`                                                        // IP address of the request as request.ip
+	Time              string                 `protobuf:"bytes,6,opt,name=time,proto3" json:"time,omitempty"`                                                    // Time of the request in RFC 3339 as request.time, now by default
+	RequestAttributes string                 `protobuf:"bytes,7,opt,name=request_attributes,json=requestAttributes,proto3" json:"request_attributes,omitempty"` // JSON object of request.attributes
+	unknownFields     protoimpl.UnknownFields
+	sizeCache         protoimpl.SizeCache
+}
+
+func (x *EvaluateRequest) Reset() {
+	*x = EvaluateRequest{}
+	mi := &file_permissions_permissions_proto_msgTypes[22]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *EvaluateRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*EvaluateRequest) ProtoMessage() {}
+
+func (x *EvaluateRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_permissions_permissions_proto_msgTypes[22]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use EvaluateRequest.ProtoReflect.Descriptor instead.
+func (*EvaluateRequest) Descriptor() ([]byte, []int) {
+	return file_permissions_permissions_proto_rawDescGZIP(), []int{22}
+}
+
+func (x *EvaluateRequest) GetAppId() int32 {
+	if x != nil {
+		return x.AppId
+	}
+	return 0
+}
+
+func (x *EvaluateRequest) GetUserId() int64 {
+	if x != nil {
+		return x.UserId
+	}
+	return 0
+}
+
+func (x *EvaluateRequest) GetUserAttributes() string {
+	if x != nil {
+		return x.UserAttributes
+	}
+	return ""
+}
+
+func (x *EvaluateRequest) GetResource() string {
+	if x != nil {
+		return x.Resource
+	}
+	return ""
+}
+
+func (x *EvaluateRequest) GetIp() string {
+	if x != nil {
+		return x.Ip
+	}
+	return ""
+}
+
+func (x *EvaluateRequest) GetTime() string {
+	if x != nil {
+		return x.Time
+	}
+	return ""
+}
+
+func (x *EvaluateRequest) GetRequestAttributes() string {
+	if x != nil {
+		return x.RequestAttributes
+	}
+	return ""
+}
+
+type RuleResult struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Name          string                 `protobuf:"bytes,1,opt,name=name,proto3" json:"name,omitempty"`        // Name of the rule
+	Effect        string                 `protobuf:"bytes,2,opt,name=effect,proto3" json:"effect,omitempty"`    // Effect of the rule
+	Matched       bool                   `protobuf:"varint,3,opt,name=matched,proto3" json:"matched,omitempty"` // Condition of the rule is true
+	Error         string                 `protobuf:"bytes,4,opt,name=error,proto3" json:"error,omitempty"`      // Error of the condition evaluation, the rule doesn't match then
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *RuleResult) Reset() {
+	*x = RuleResult{}
+	mi := &file_permissions_permissions_proto_msgTypes[23]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *RuleResult) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*RuleResult) ProtoMessage() {}
+
+func (x *RuleResult) ProtoReflect() protoreflect.Message {
+	mi := &file_permissions_permissions_proto_msgTypes[23]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use RuleResult.ProtoReflect.Descriptor instead.
+func (*RuleResult) Descriptor() ([]byte, []int) {
+	return file_permissions_permissions_proto_rawDescGZIP(), []int{23}
+}
+
+func (x *RuleResult) GetName() string {
+	if x != nil {
+		return x.Name
+	}
+	return ""
+}
+
+func (x *RuleResult) GetEffect() string {
+	if x != nil {
+		return x.Effect
+	}
+	return ""
+}
+
+func (x *RuleResult) GetMatched() bool {
+	if x != nil {
+		return x.Matched
+	}
+	return false
+}
+
+func (x *RuleResult) GetError() string {
+	if x != nil {
+		return x.Error
+	}
+	return ""
+}
+
+type EvaluateResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Allowed       bool                   `protobuf:"varint,1,opt,name=allowed,proto3" json:"allowed,omitempty"`                                  // Effect is allow
+	Effect        string                 `protobuf:"bytes,2,opt,name=effect,proto3" json:"effect,omitempty"`                                     // Effect of the matched rule or default effect of the policy
+	MatchedRule   string                 `protobuf:"bytes,3,opt,name=matched_rule,json=matchedRule,proto3" json:"matched_rule,omitempty"`        // Name of the matched rule, empty if default effect is used
+	Rules         []*RuleResult          `protobuf:"bytes,4,rep,name=rules,proto3" json:"rules,omitempty"`                                       // Evaluated rules in order up to the matched one
+	DecisionId    int64                  `protobuf:"varint,5,opt,name=decision_id,json=decisionId,proto3" json:"decision_id,omitempty"`          // ID of the recorded decision
+	PolicyVersion int64                  `protobuf:"varint,6,opt,name=policy_version,json=policyVersion,proto3" json:"policy_version,omitempty"` // Version of the evaluated policy
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *EvaluateResponse) Reset() {
+	*x = EvaluateResponse{}
+	mi := &file_permissions_permissions_proto_msgTypes[24]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *EvaluateResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*EvaluateResponse) ProtoMessage() {}
+
+func (x *EvaluateResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_permissions_permissions_proto_msgTypes[24]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use EvaluateResponse.ProtoReflect.Descriptor instead.
+func (*EvaluateResponse) Descriptor() ([]byte, []int) {
+	return file_permissions_permissions_proto_rawDescGZIP(), []int{24}
+}
+
+func (x *EvaluateResponse) GetAllowed() bool {
+	if x != nil {
+		return x.Allowed
+	}
+	return false
+}
+
+func (x *EvaluateResponse) GetEffect() string {
+	if x != nil {
+		return x.Effect
+	}
+	return ""
+}
+
+func (x *EvaluateResponse) GetMatchedRule() string {
+	if x != nil {
+		return x.MatchedRule
+	}
+	return ""
+}
+
+func (x *EvaluateResponse) GetRules() []*RuleResult {
+	if x != nil {
+		return x.Rules
+	}
+	return nil
+}
+
+func (x *EvaluateResponse) GetDecisionId() int64 {
+	if x != nil {
+		return x.DecisionId
+	}
+	return 0
+}
+
+func (x *EvaluateResponse) GetPolicyVersion() int64 {
+	if x != nil {
+		return x.PolicyVersion
+	}
+	return 0
+}
+
+type DryRunPolicyRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	AppId         int32                  `protobuf:"varint,1,opt,name=app_id,json=appId,proto3" json:"app_id,omitempty"` // ID of the application
+	Policy        string                 `protobuf:"bytes,2,opt,name=policy,proto3" json:"policy,omitempty"`             // Policy in JSON to test, it is not saved
+	Limit         int32                  `protobuf:"varint,3,opt,name=limit,proto3" json:"limit,omitempty"`              // Count of the latest recorded decisions to replay, 100 by default and 1000 at most
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *DryRunPolicyRequest) Reset() {
+	*x = DryRunPolicyRequest{}
+	mi := &file_permissions_permissions_proto_msgTypes[25]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *DryRunPolicyRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*DryRunPolicyRequest) ProtoMessage() {}
+
+func (x *DryRunPolicyRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_permissions_permissions_proto_msgTypes[25]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use DryRunPolicyRequest.ProtoReflect.Descriptor instead.
+func (*DryRunPolicyRequest) Descriptor() ([]byte, []int) {
+	return file_permissions_permissions_proto_rawDescGZIP(), []int{25}
+}
+
+func (x *DryRunPolicyRequest) GetAppId() int32 {
+	if x != nil {
+		return x.AppId
+	}
+	return 0
+}
+
+func (x *DryRunPolicyRequest) GetPolicy() string {
+	if x != nil {
+		return x.Policy
+	}
+	return ""
+}
+
+func (x *DryRunPolicyRequest) GetLimit() int32 {
+	if x != nil {
+		return x.Limit
+	}
+	return 0
+}
+
+type ReplayResult struct {
+	state          protoimpl.MessageState `protogen:"open.v1"`
+	DecisionId     int64                  `protobuf:"varint,1,opt,name=decision_id,json=decisionId,proto3" json:"decision_id,omitempty"`            // ID of the recorded decision
+	PolicyVersion  int64                  `protobuf:"varint,2,opt,name=policy_version,json=policyVersion,proto3" json:"policy_version,omitempty"`   // Version of the policy which made the decision
+	RecordedEffect string                 `protobuf:"bytes,3,opt,name=recorded_effect,json=recordedEffect,proto3" json:"recorded_effect,omitempty"` // Effect of the recorded decision
+	RecordedRule   string                 `protobuf:"bytes,4,opt,name=recorded_rule,json=recordedRule,proto3" json:"recorded_rule,omitempty"`       // Matched rule of the recorded decision
+	Effect         string                 `protobuf:"bytes,5,opt,name=effect,proto3" json:"effect,omitempty"`                                       // Effect of the tested policy
+	Rule           string                 `protobuf:"bytes,6,opt,name=rule,proto3" json:"rule,omitempty"`                                           // Matched rule of the tested policy
+	Changed        bool                   `protobuf:"varint,7,opt,name=changed,proto3" json:"changed,omitempty"`                                    // Effects differ
+	unknownFields  protoimpl.UnknownFields
+	sizeCache      protoimpl.SizeCache
+}
+
+func (x *ReplayResult) Reset() {
+	*x = ReplayResult{}
+	mi := &file_permissions_permissions_proto_msgTypes[26]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ReplayResult) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ReplayResult) ProtoMessage() {}
+
+func (x *ReplayResult) ProtoReflect() protoreflect.Message {
+	mi := &file_permissions_permissions_proto_msgTypes[26]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ReplayResult.ProtoReflect.Descriptor instead.
+func (*ReplayResult) Descriptor() ([]byte, []int) {
+	return file_permissions_permissions_proto_rawDescGZIP(), []int{26}
+}
+
+func (x *ReplayResult) GetDecisionId() int64 {
+	if x != nil {
+		return x.DecisionId
+	}
+	return 0
+}
+
+func (x *ReplayResult) GetPolicyVersion() int64 {
+	if x != nil {
+		return x.PolicyVersion
+	}
+	return 0
+}
+
+func (x *ReplayResult) GetRecordedEffect() string {
+	if x != nil {
+		return x.RecordedEffect
+	}
+	return ""
+}
+
+func (x *ReplayResult) GetRecordedRule() string {
+	if x != nil {
+		return x.RecordedRule
+	}
+	return ""
+}
+
+func (x *ReplayResult) GetEffect() string {
+	if x != nil {
+		return x.Effect
+	}
+	return ""
+}
+
+func (x *ReplayResult) GetRule() string {
+	if x != nil {
+		return x.Rule
+	}
+	return ""
+}
+
+func (x *ReplayResult) GetChanged() bool {
+	if x != nil {
+		return x.Changed
+	}
+	return false
+}
+
+type DryRunPolicyResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Results       []*ReplayResult        `protobuf:"bytes,1,rep,name=results,proto3" json:"results,omitempty"`  // Replayed decisions, the latest first
+	Changed       int32                  `protobuf:"varint,2,opt,name=changed,proto3" json:"changed,omitempty"` // Count of the decisions which effect is changed
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *DryRunPolicyResponse) Reset() {
+	*x = DryRunPolicyResponse{}
+	mi := &file_permissions_permissions_proto_msgTypes[27]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *DryRunPolicyResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*DryRunPolicyResponse) ProtoMessage() {}
+
+func (x *DryRunPolicyResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_permissions_permissions_proto_msgTypes[27]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use DryRunPolicyResponse.ProtoReflect.Descriptor instead.
+func (*DryRunPolicyResponse) Descriptor() ([]byte, []int) {
+	return file_permissions_permissions_proto_rawDescGZIP(), []int{27}
+}
+
+func (x *DryRunPolicyResponse) GetResults() []*ReplayResult {
+	if x != nil {
+		return x.Results
+	}
+	return nil
+}
+
+func (x *DryRunPolicyResponse) GetChanged() int32 {
+	if x != nil {
+		return x.Changed
+	}
+	return 0
+}
+
+var File_permissions_permissions_proto protoreflect.FileDescriptor
+
+const file_permissions_permissions_proto_rawDesc = "" +
+	"\n" +
+	"\x1dpermissions/permissions.proto\x12\vpermissions\"L\n" +
+	"\x1bWriteNamespaceConfigRequest\x12\x15\n" +
+	"\x06app_id\x18\x01 \x01(\x05R\x05appId\x12\x16\n" +
+	"\x06config\x18\x02 \x01(\tR\x06config\"\x1e\n" +
+	"\x1cWriteNamespaceConfigResponse\"3\n" +
+	"\x1aReadNamespaceConfigRequest\x12\x15\n" +
+	"\x06app_id\x18\x01 \x01(\x05R\x05appId\"5\n" +
+	"\x1bReadNamespaceConfigResponse\x12\x16\n" +
+	"\x06config\x18\x01 \x01(\tR\x06config\"]\n" +
+	"\x12WriteTuplesRequest\x12\x15\n" +
+	"\x06app_id\x18\x01 \x01(\x05R\x05appId\x12\x16\n" +
+	"\x06writes\x18\x02 \x03(\tR\x06writes\x12\x18\n" +
+	"\adeletes\x18\x03 \x03(\tR\adeletes\"1\n" +
+	"\x13WriteTuplesResponse\x12\x1a\n" +
+	"\brevision\x18\x01 \x01(\x03R\brevision\"\x9f\x01\n" +
+	"\fCheckRequest\x12\x15\n" +
+	"\x06app_id\x18\x01 \x01(\x05R\x05appId\x12\x16\n" +
+	"\x06object\x18\x02 \x01(\tR\x06object\x12\x1a\n" +
+	"\brelation\x18\x03 \x01(\tR\brelation\x12\x18\n" +
+	"\asubject\x18\x04 \x01(\tR\asubject\x12*\n" +
+	"\x11at_least_revision\x18\x05 \x01(\x03R\x0fatLeastRevision\"E\n" +
+	"\rCheckResponse\x12\x18\n" +
+	"\aallowed\x18\x01 \x01(\bR\aallowed\x12\x1a\n" +
+	"\brevision\x18\x02 \x01(\x03R\brevision\"Y\n" +
+	"\tCheckItem\x12\x16\n" +
+	"\x06object\x18\x01 \x01(\tR\x06object\x12\x1a\n" +
+	"\brelation\x18\x02 \x01(\tR\brelation\x12\x18\n" +
+	"\asubject\x18\x03 \x01(\tR\asubject\"\x84\x01\n" +
+	"\x11BatchCheckRequest\x12\x15\n" +
+	"\x06app_id\x18\x01 \x01(\x05R\x05appId\x12,\n" +
+	"\x05items\x18\x02 \x03(\v2\x16.permissions.CheckItemR\x05items\x12*\n" +
+	"\x11at_least_revision\x18\x03 \x01(\x03R\x0fatLeastRevision\"J\n" +
+	"\x12BatchCheckResponse\x12\x18\n" +
+	"\aallowed\x18\x01 \x03(\bR\aallowed\x12\x1a\n" +
+	"\brevision\x18\x02 \x01(\x03R\brevision\"*\n" +
+	"\x11CacheStatsRequest\x12\x15\n" +
+	"\x06app_id\x18\x01 \x01(\x05R\x05appId\"z\n" +
+	"\x12CacheStatsResponse\x12\x12\n" +
+	"\x04hits\x18\x01 \x01(\x04R\x04hits\x12\x16\n" +
+	"\x06misses\x18\x02 \x01(\x04R\x06misses\x12$\n" +
+	"\rinvalidations\x18\x03 \x01(\x04R\rinvalidations\x12\x12\n" +
+	"\x04size\x18\x04 \x01(\x05R\x04size\"\x86\x01\n" +
+	"\rExpandRequest\x12\x15\n" +
+	"\x06app_id\x18\x01 \x01(\x05R\x05appId\x12\x16\n" +
+	"\x06object\x18\x02 \x01(\tR\x06object\x12\x1a\n" +
+	"\brelation\x18\x03 \x01(\tR\brelation\x12*\n" +
+	"\x11at_least_revision\x18\x04 \x01(\x03R\x0fatLeastRevision\"\xb1\x01\n" +
+	"\vUsersetTree\x12\x1c\n" +
+	"\toperation\x18\x01 \x01(\tR\toperation\x12\x16\n" +
+	"\x06object\x18\x02 \x01(\tR\x06object\x12\x1a\n" +
+	"\brelation\x18\x03 \x01(\tR\brelation\x12\x1a\n" +
+	"\bsubjects\x18\x04 \x03(\tR\bsubjects\x124\n" +
+	"\bchildren\x18\x05 \x03(\v2\x18.permissions.UsersetTreeR\bchildren\"Z\n" +
+	"\x0eExpandResponse\x12,\n" +
+	"\x04tree\x18\x01 \x01(\v2\x18.permissions.UsersetTreeR\x04tree\x12\x1a\n" +
+	"\brevision\x18\x02 \x01(\x03R\brevision\"\xae\x01\n" +
+	"\x12ListObjectsRequest\x12\x15\n" +
+	"\x06app_id\x18\x01 \x01(\x05R\x05appId\x12\x1f\n" +
+	"\vobject_type\x18\x02 \x01(\tR\n" +
+	"objectType\x12\x1a\n" +
+	"\brelation\x18\x03 \x01(\tR\brelation\x12\x18\n" +
+	"\asubject\x18\x04 \x01(\tR\asubject\x12*\n" +
+	"\x11at_least_revision\x18\x05 \x01(\x03R\x0fatLeastRevision\"P\n" +
+	"\x13ListObjectsResponse\x12\x1d\n" +
+	"\n" +
+	"object_ids\x18\x01 \x03(\tR\tobjectIds\x12\x1a\n" +
+	"\brevision\x18\x02 \x01(\x03R\brevision\"C\n" +
+	"\x12WritePolicyRequest\x12\x15\n" +
+	"\x06app_id\x18\x01 \x01(\x05R\x05appId\x12\x16\n" +
+	"\x06policy\x18\x02 \x01(\tR\x06policy\"/\n" +
+	"\x13WritePolicyResponse\x12\x18\n" +
+	"\aversion\x18\x01 \x01(\x03R\aversion\"*\n" +
+	"\x11ReadPolicyRequest\x12\x15\n" +
+	"\x06app_id\x18\x01 \x01(\x05R\x05appId\"F\n" +
+	"\x12ReadPolicyResponse\x12\x16\n" +
+	"\x06policy\x18\x01 \x01(\tR\x06policy\x12\x18\n" +
+	"\aversion\x18\x02 \x01(\x03R\aversion\"\xd9\x01\n" +
+	"\x0fEvaluateRequest\x12\x15\n" +
+	"\x06app_id\x18\x01 \x01(\x05R\x05appId\x12\x17\n" +
+	"\auser_id\x18\x02 \x01(\x03R\x06userId\x12'\n" +
+	"\x0fuser_attributes\x18\x03 \x01(\tR\x0euserAttributes\x12\x1a\n" +
+	"\bresource\x18\x04 \x01(\tR\bresource\x12\x0e\n" +
+	"\x02ip\x18\x05 \x01(\tR\x02ip\x12\x12\n" +
+	"\x04time\x18\x06 \x01(\tR\x04time\x12-\n" +
+	"\x12request_attributes\x18\a \x01(\tR\x11requestAttributes\"h\n" +
+	"\n" +
+	"RuleResult\x12\x12\n" +
+	"\x04name\x18\x01 \x01(\tR\x04name\x12\x16\n" +
+	"\x06effect\x18\x02 \x01(\tR\x06effect\x12\x18\n" +
+	"\amatched\x18\x03 \x01(\bR\amatched\x12\x14\n" +
+	"\x05error\x18\x04 \x01(\tR\x05error\"\xde\x01\n" +
+	"\x10EvaluateResponse\x12\x18\n" +
+	"\aallowed\x18\x01 \x01(\bR\aallowed\x12\x16\n" +
+	"\x06effect\x18\x02 \x01(\tR\x06effect\x12!\n" +
+	"\fmatched_rule\x18\x03 \x01(\tR\vmatchedRule\x12-\n" +
+	"\x05rules\x18\x04 \x03(\v2\x17.permissions.RuleResultR\x05rules\x12\x1f\n" +
+	"\vdecision_id\x18\x05 \x01(\x03R\n" +
+	"decisionId\x12%\n" +
+	"\x0epolicy_version\x18\x06 \x01(\x03R\rpolicyVersion\"Z\n" +
+	"\x13DryRunPolicyRequest\x12\x15\n" +
+	"\x06app_id\x18\x01 \x01(\x05R\x05appId\x12\x16\n" +
+	"\x06policy\x18\x02 \x01(\tR\x06policy\x12\x14\n" +
+	"\x05limit\x18\x03 \x01(\x05R\x05limit\"\xea\x01\n" +
+	"\fReplayResult\x12\x1f\n" +
+	"\vdecision_id\x18\x01 \x01(\x03R\n" +
+	"decisionId\x12%\n" +
+	"\x0epolicy_version\x18\x02 \x01(\x03R\rpolicyVersion\x12'\n" +
+	"\x0frecorded_effect\x18\x03 \x01(\tR\x0erecordedEffect\x12#\n" +
+	"\rrecorded_rule\x18\x04 \x01(\tR\frecordedRule\x12\x16\n" +
+	"\x06effect\x18\x05 \x01(\tR\x06effect\x12\x12\n" +
+	"\x04rule\x18\x06 \x01(\tR\x04rule\x12\x18\n" +
+	"\achanged\x18\a \x01(\bR\achanged\"e\n" +
+	"\x14DryRunPolicyResponse\x123\n" +
+	"\aresults\x18\x01 \x03(\v2\x19.permissions.ReplayResultR\aresults\x12\x18\n" +
+	"\achanged\x18\x02 \x01(\x05R\achanged2\xe8\a\n" +
+	"\vPermissions\x12k\n" +
+	"\x14WriteNamespaceConfig\x12(.permissions.WriteNamespaceConfigRequest\x1a).permissions.WriteNamespaceConfigResponse\x12h\n" +
+	"\x13ReadNamespaceConfig\x12'.permissions.ReadNamespaceConfigRequest\x1a(.permissions.ReadNamespaceConfigResponse\x12P\n" +
+	"\vWriteTuples\x12\x1f.permissions.WriteTuplesRequest\x1a .permissions.WriteTuplesResponse\x12>\n" +
+	"\x05Check\x12\x19.permissions.CheckRequest\x1a\x1a.permissions.CheckResponse\x12M\n" +
+	"\n" +
+	"BatchCheck\x12\x1e.permissions.BatchCheckRequest\x1a\x1f.permissions.BatchCheckResponse\x12M\n" +
+	"\n" +
+	"CacheStats\x12\x1e.permissions.CacheStatsRequest\x1a\x1f.permissions.CacheStatsResponse\x12A\n" +
+	"\x06Expand\x12\x1a.permissions.ExpandRequest\x1a\x1b.permissions.ExpandResponse\x12P\n" +
+	"\vListObjects\x12\x1f.permissions.ListObjectsRequest\x1a .permissions.ListObjectsResponse\x12P\n" +
+	"\vWritePolicy\x12\x1f.permissions.WritePolicyRequest\x1a .permissions.WritePolicyResponse\x12M\n" +
+	"\n" +
+	"ReadPolicy\x12\x1e.permissions.ReadPolicyRequest\x1a\x1f.permissions.ReadPolicyResponse\x12G\n" +
+	"\bEvaluate\x12\x1c.permissions.EvaluateRequest\x1a\x1d.permissions.EvaluateResponse\x12S\n" +
+	"\fDryRunPolicy\x12 .permissions.DryRunPolicyRequest\x1a!.permissions.DryRunPolicyResponseBMZKgithub.com/nhassl3/sso-app/contracts/generated/go/permissions;permissionsv1b\x06proto3"
+
+var (
+	file_permissions_permissions_proto_rawDescOnce sync.Once
+	file_permissions_permissions_proto_rawDescData []byte
+)
+
+func file_permissions_permissions_proto_rawDescGZIP() []byte {
+	file_permissions_permissions_proto_rawDescOnce.Do(func() {
+		file_permissions_permissions_proto_rawDescData = protoimpl.X.CompressGZIP(unsafe.Slice(unsafe.StringData(file_permissions_permissions_proto_rawDesc), len(file_permissions_permissions_proto_rawDesc)))
+	})
+	return file_permissions_permissions_proto_rawDescData
+}
+
+var file_permissions_permissions_proto_msgTypes = make([]protoimpl.MessageInfo, 28)
+var file_permissions_permissions_proto_goTypes = []any{
+	(*WriteNamespaceConfigRequest)(nil),  // 0: permissions.WriteNamespaceConfigRequest
+	(*WriteNamespaceConfigResponse)(nil), // 1: permissions.WriteNamespaceConfigResponse
+	(*ReadNamespaceConfigRequest)(nil),   // 2: permissions.ReadNamespaceConfigRequest
+	(*ReadNamespaceConfigResponse)(nil),  // 3: permissions.ReadNamespaceConfigResponse
+	(*WriteTuplesRequest)(nil),           // 4: permissions.WriteTuplesRequest
+	(*WriteTuplesResponse)(nil),          // 5: permissions.WriteTuplesResponse
+	(*CheckRequest)(nil),                 // 6: permissions.CheckRequest
+	(*CheckResponse)(nil),                // 7: permissions.CheckResponse
+	(*CheckItem)(nil),                    // 8: permissions.CheckItem
+	(*BatchCheckRequest)(nil),            // 9: permissions.BatchCheckRequest
+	(*BatchCheckResponse)(nil),           // 10: permissions.BatchCheckResponse
+	(*CacheStatsRequest)(nil),            // 11: permissions.CacheStatsRequest
+	(*CacheStatsResponse)(nil),           // 12: permissions.CacheStatsResponse
+	(*ExpandRequest)(nil),                // 13: permissions.ExpandRequest
+	(*UsersetTree)(nil),                  // 14: permissions.UsersetTree
+	(*ExpandResponse)(nil),               // 15: permissions.ExpandResponse
+	(*ListObjectsRequest)(nil),           // 16: permissions.ListObjectsRequest
+	(*ListObjectsResponse)(nil),          // 17: permissions.ListObjectsResponse
+	(*WritePolicyRequest)(nil),           // 18: permissions.WritePolicyRequest
+	(*WritePolicyResponse)(nil),          // 19: permissions.WritePolicyResponse
+	(*ReadPolicyRequest)(nil),            // 20: permissions.ReadPolicyRequest
+	(*ReadPolicyResponse)(nil),           // 21: permissions.ReadPolicyResponse
+	(*EvaluateRequest)(nil),              // 22: permissions.EvaluateRequest
+	(*RuleResult)(nil),                   // 23: permissions.RuleResult
+	(*EvaluateResponse)(nil),             // 24: permissions.EvaluateResponse
+	(*DryRunPolicyRequest)(nil),          // 25: permissions.DryRunPolicyRequest
+	(*ReplayResult)(nil),                 // 26: permissions.ReplayResult
+	(*DryRunPolicyResponse)(nil),         // 27: permissions.DryRunPolicyResponse
+}
+var file_permissions_permissions_proto_depIdxs = []int32{
+	8,  // 0: permissions.BatchCheckRequest.items:type_name -> permissions.CheckItem
+	14, // 1: permissions.UsersetTree.children:type_name -> permissions.UsersetTree
+	14, // 2: permissions.ExpandResponse.tree:type_name -> permissions.UsersetTree
+	23, // 3: permissions.EvaluateResponse.rules:type_name -> permissions.RuleResult
+	26, // 4: permissions.DryRunPolicyResponse.results:type_name -> permissions.ReplayResult
+	0,  // 5: permissions.Permissions.WriteNamespaceConfig:input_type -> permissions.WriteNamespaceConfigRequest
+	2,  // 6: permissions.Permissions.ReadNamespaceConfig:input_type -> permissions.ReadNamespaceConfigRequest
+	4,  // 7: permissions.Permissions.WriteTuples:input_type -> permissions.WriteTuplesRequest
+	6,  // 8: permissions.Permissions.Check:input_type -> permissions.CheckRequest
+	9,  // 9: permissions.Permissions.BatchCheck:input_type -> permissions.BatchCheckRequest
+	11, // 10: permissions.Permissions.CacheStats:input_type -> permissions.CacheStatsRequest
+	13, // 11: permissions.Permissions.Expand:input_type -> permissions.ExpandRequest
+	16, // 12: permissions.Permissions.ListObjects:input_type -> permissions.ListObjectsRequest
+	18, // 13: permissions.Permissions.WritePolicy:input_type -> permissions.WritePolicyRequest
+	20, // 14: permissions.Permissions.ReadPolicy:input_type -> permissions.ReadPolicyRequest
+	22, // 15: permissions.Permissions.Evaluate:input_type -> permissions.EvaluateRequest
+	25, // 16: permissions.Permissions.DryRunPolicy:input_type -> permissions.DryRunPolicyRequest
+	1,  // 17: permissions.Permissions.WriteNamespaceConfig:output_type -> permissions.WriteNamespaceConfigResponse
+	3,  // 18: permissions.Permissions.ReadNamespaceConfig:output_type -> permissions.ReadNamespaceConfigResponse
+	5,  // 19: permissions.Permissions.WriteTuples:output_type -> permissions.WriteTuplesResponse
+	7,  // 20: permissions.Permissions.Check:output_type -> permissions.CheckResponse
+	10, // 21: permissions.Permissions.BatchCheck:output_type -> permissions.BatchCheckResponse
+	12, // 22: permissions.Permissions.CacheStats:output_type -> permissions.CacheStatsResponse
+	15, // 23: permissions.Permissions.Expand:output_type -> permissions.ExpandResponse
+	17, // 24: permissions.Permissions.ListObjects:output_type -> permissions.ListObjectsResponse
+	19, // 25: permissions.Permissions.WritePolicy:output_type -> permissions.WritePolicyResponse
+	21, // 26: permissions.Permissions.ReadPolicy:output_type -> permissions.ReadPolicyResponse
+	24, // 27: permissions.Permissions.Evaluate:output_type -> permissions.EvaluateResponse
+	27, // 28: permissions.Permissions.DryRunPolicy:output_type -> permissions.DryRunPolicyResponse
+	17, // [17:29] is the sub-list for method output_type
+	5,  // [5:17] is the sub-list for method input_type
+	5,  // [5:5] is the sub-list for extension type_name
+	5,  // [5:5] is the sub-list for extension extendee
+	0,  // [0:5] is the sub-list for field type_name
+}
+
+func init() { file_permissions_permissions_proto_init() }
+func file_permissions_permissions_proto_init() {
+	if File_permissions_permissions_proto != nil {
+		return
+	}
+	type x struct{}
+	out := protoimpl.TypeBuilder{
+		File: protoimpl.DescBuilder{
+			GoPackagePath: reflect.TypeOf(x{}).PkgPath(),
+			RawDescriptor: unsafe.Slice(unsafe.StringData(file_permissions_permissions_proto_rawDesc), len(file_permissions_permissions_proto_rawDesc)),
+			NumEnums:      0,
+			NumMessages:   28,
+			NumExtensions: 0,
+			NumServices:   1,
+		},
+		GoTypes:           file_permissions_permissions_proto_goTypes,
+		DependencyIndexes: file_permissions_permissions_proto_depIdxs,
+		MessageInfos:      file_permissions_permissions_proto_msgTypes,
+	}.Build()
+	File_permissions_permissions_proto = out.File
+	file_permissions_permissions_proto_goTypes = nil
+	file_permissions_permissions_proto_depIdxs = nil
+}
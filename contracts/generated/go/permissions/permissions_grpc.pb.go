@@ -0,0 +1,557 @@
+// Code generated by protoc-gen-go-grpc. DO NOT EDIT.
+// versions:
+// - protoc-gen-go-grpc v1.5.1
+// - protoc             v3.21.12
+// source: permissions/permissions.proto
+
+package permissionsv1
+
+import (
+	context "context"
+	grpc "google.golang.org/grpc"
+	codes "google.golang.org/grpc/codes"
+	status "google.golang.org/grpc/status"
+)
+
+// This is a compile-time assertion to ensure that this generated file
+// is compatible with the grpc package it is being compiled against.
+// Requires gRPC-Go v1.64.0 or later.
+const _ = grpc.SupportPackageIsVersion9
+
+const (
+	Permissions_WriteNamespaceConfig_FullMethodName = "/permissions.Permissions/WriteNamespaceConfig"
+	Permissions_ReadNamespaceConfig_FullMethodName  = "/permissions.Permissions/ReadNamespaceConfig"
+	Permissions_WriteTuples_FullMethodName          = "/permissions.Permissions/WriteTuples"
+	Permissions_Check_FullMethodName                = "/permissions.Permissions/Check"
+	Permissions_BatchCheck_FullMethodName           = "/permissions.Permissions/BatchCheck"
+	Permissions_CacheStats_FullMethodName           = "/permissions.Permissions/CacheStats"
+	Permissions_Expand_FullMethodName               = "/permissions.Permissions/Expand"
+	Permissions_ListObjects_FullMethodName          = "/permissions.Permissions/ListObjects"
+	Permissions_WritePolicy_FullMethodName          = "/permissions.Permissions/WritePolicy"
+	Permissions_ReadPolicy_FullMethodName           = "/permissions.Permissions/ReadPolicy"
+	Permissions_Evaluate_FullMethodName             = "/permissions.Permissions/Evaluate"
+	Permissions_DryRunPolicy_FullMethodName         = "/permissions.Permissions/DryRunPolicy"
+)
+
+// PermissionsClient is the client API for Permissions service.
+//
+// For semantics around ctx use and closing/ending streaming RPCs, please refer to https://pkg.go.dev/google.golang.org/grpc/?tab=doc#ClientConn.NewStream.
+//
+// Relationship-based authorization over relation tuples written as object#relation@subject,
+// for example doc:readme#viewer@user:42 or doc:readme#viewer@group:eng#member.
+// Every RPC requires bearer token in the authorization metadata. Writes are allowed to admins
+// of the application, reads are allowed to admins and to tokens issued for the application.
+// Reads see one snapshot of the tuples and return its revision, at_least_revision makes
+// the read fail with FAILED_PRECONDITION if the tuples are older than the given revision.
+// Attribute-based policies are ordered rules with conditions in CEL-like expression language
+// over user, resource, app and request variables, the first matched rule decides the effect
+type PermissionsClient interface {
+	WriteNamespaceConfig(ctx context.Context, in *WriteNamespaceConfigRequest, opts ...grpc.CallOption) (*WriteNamespaceConfigResponse, error)
+	ReadNamespaceConfig(ctx context.Context, in *ReadNamespaceConfigRequest, opts ...grpc.CallOption) (*ReadNamespaceConfigResponse, error)
+	WriteTuples(ctx context.Context, in *WriteTuplesRequest, opts ...grpc.CallOption) (*WriteTuplesResponse, error)
+	Check(ctx context.Context, in *CheckRequest, opts ...grpc.CallOption) (*CheckResponse, error)
+	BatchCheck(ctx context.Context, in *BatchCheckRequest, opts ...grpc.CallOption) (*BatchCheckResponse, error)
+	CacheStats(ctx context.Context, in *CacheStatsRequest, opts ...grpc.CallOption) (*CacheStatsResponse, error)
+	Expand(ctx context.Context, in *ExpandRequest, opts ...grpc.CallOption) (*ExpandResponse, error)
+	ListObjects(ctx context.Context, in *ListObjectsRequest, opts ...grpc.CallOption) (*ListObjectsResponse, error)
+	WritePolicy(ctx context.Context, in *WritePolicyRequest, opts ...grpc.CallOption) (*WritePolicyResponse, error)
+	ReadPolicy(ctx context.Context, in *ReadPolicyRequest, opts ...grpc.CallOption) (*ReadPolicyResponse, error)
+	Evaluate(ctx context.Context, in *EvaluateRequest, opts ...grpc.CallOption) (*EvaluateResponse, error)
+	DryRunPolicy(ctx context.Context, in *DryRunPolicyRequest, opts ...grpc.CallOption) (*DryRunPolicyResponse, error)
+}
+
+type permissionsClient struct {
+	cc grpc.ClientConnInterface
+}
+
+func NewPermissionsClient(cc grpc.ClientConnInterface) PermissionsClient {
+	return &permissionsClient{cc}
+}
+
+func (c *permissionsClient) WriteNamespaceConfig(ctx context.Context, in *WriteNamespaceConfigRequest, opts ...grpc.CallOption) (*WriteNamespaceConfigResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(WriteNamespaceConfigResponse)
+	err := c.cc.Invoke(ctx, Permissions_WriteNamespaceConfig_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *permissionsClient) ReadNamespaceConfig(ctx context.Context, in *ReadNamespaceConfigRequest, opts ...grpc.CallOption) (*ReadNamespaceConfigResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(ReadNamespaceConfigResponse)
+	err := c.cc.Invoke(ctx, Permissions_ReadNamespaceConfig_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *permissionsClient) WriteTuples(ctx context.Context, in *WriteTuplesRequest, opts ...grpc.CallOption) (*WriteTuplesResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(WriteTuplesResponse)
+	err := c.cc.Invoke(ctx, Permissions_WriteTuples_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *permissionsClient) Check(ctx context.Context, in *CheckRequest, opts ...grpc.CallOption) (*CheckResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(CheckResponse)
+	err := c.cc.Invoke(ctx, Permissions_Check_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *permissionsClient) BatchCheck(ctx context.Context, in *BatchCheckRequest, opts ...grpc.CallOption) (*BatchCheckResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(BatchCheckResponse)
+	err := c.cc.Invoke(ctx, Permissions_BatchCheck_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *permissionsClient) CacheStats(ctx context.Context, in *CacheStatsRequest, opts ...grpc.CallOption) (*CacheStatsResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(CacheStatsResponse)
+	err := c.cc.Invoke(ctx, Permissions_CacheStats_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *permissionsClient) Expand(ctx context.Context, in *ExpandRequest, opts ...grpc.CallOption) (*ExpandResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(ExpandResponse)
+	err := c.cc.Invoke(ctx, Permissions_Expand_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *permissionsClient) ListObjects(ctx context.Context, in *ListObjectsRequest, opts ...grpc.CallOption) (*ListObjectsResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(ListObjectsResponse)
+	err := c.cc.Invoke(ctx, Permissions_ListObjects_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *permissionsClient) WritePolicy(ctx context.Context, in *WritePolicyRequest, opts ...grpc.CallOption) (*WritePolicyResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(WritePolicyResponse)
+	err := c.cc.Invoke(ctx, Permissions_WritePolicy_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *permissionsClient) ReadPolicy(ctx context.Context, in *ReadPolicyRequest, opts ...grpc.CallOption) (*ReadPolicyResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(ReadPolicyResponse)
+	err := c.cc.Invoke(ctx, Permissions_ReadPolicy_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *permissionsClient) Evaluate(ctx context.Context, in *EvaluateRequest, opts ...grpc.CallOption) (*EvaluateResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(EvaluateResponse)
+	err := c.cc.Invoke(ctx, Permissions_Evaluate_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *permissionsClient) DryRunPolicy(ctx context.Context, in *DryRunPolicyRequest, opts ...grpc.CallOption) (*DryRunPolicyResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(DryRunPolicyResponse)
+	err := c.cc.Invoke(ctx, Permissions_DryRunPolicy_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// PermissionsServer is the server API for Permissions service.
+// All implementations must embed UnimplementedPermissionsServer
+// for forward compatibility.
+//
+// Relationship-based authorization over relation tuples written as object#relation@subject,
+// for example doc:readme#viewer@user:42 or doc:readme#viewer@group:eng#member.
+// Every RPC requires bearer token in the authorization metadata. Writes are allowed to admins
+// of the application, reads are allowed to admins and to tokens issued for the application.
+// Reads see one snapshot of the tuples and return its revision, at_least_revision makes
+// the read fail with FAILED_PRECONDITION if the tuples are older than the given revision.
+// Attribute-based policies are ordered rules with conditions in CEL-like expression language
+// over user, resource, app and request variables, the first matched rule decides the effect
+type PermissionsServer interface {
+	WriteNamespaceConfig(context.Context, *WriteNamespaceConfigRequest) (*WriteNamespaceConfigResponse, error)
+	ReadNamespaceConfig(context.Context, *ReadNamespaceConfigRequest) (*ReadNamespaceConfigResponse, error)
+	WriteTuples(context.Context, *WriteTuplesRequest) (*WriteTuplesResponse, error)
+	Check(context.Context, *CheckRequest) (*CheckResponse, error)
+	BatchCheck(context.Context, *BatchCheckRequest) (*BatchCheckResponse, error)
+	CacheStats(context.Context, *CacheStatsRequest) (*CacheStatsResponse, error)
+	Expand(context.Context, *ExpandRequest) (*ExpandResponse, error)
+	ListObjects(context.Context, *ListObjectsRequest) (*ListObjectsResponse, error)
+	WritePolicy(context.Context, *WritePolicyRequest) (*WritePolicyResponse, error)
+	ReadPolicy(context.Context, *ReadPolicyRequest) (*ReadPolicyResponse, error)
+	Evaluate(context.Context, *EvaluateRequest) (*EvaluateResponse, error)
+	DryRunPolicy(context.Context, *DryRunPolicyRequest) (*DryRunPolicyResponse, error)
+	mustEmbedUnimplementedPermissionsServer()
+}
+
+// UnimplementedPermissionsServer must be embedded to have
+// forward compatible implementations.
+//
+// NOTE: this should be embedded by value instead of pointer to avoid a nil
+// pointer dereference when methods are called.
+type UnimplementedPermissionsServer struct{}
+
+func (UnimplementedPermissionsServer) WriteNamespaceConfig(context.Context, *WriteNamespaceConfigRequest) (*WriteNamespaceConfigResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method WriteNamespaceConfig not implemented")
+}
+func (UnimplementedPermissionsServer) ReadNamespaceConfig(context.Context, *ReadNamespaceConfigRequest) (*ReadNamespaceConfigResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method ReadNamespaceConfig not implemented")
+}
+func (UnimplementedPermissionsServer) WriteTuples(context.Context, *WriteTuplesRequest) (*WriteTuplesResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method WriteTuples not implemented")
+}
+func (UnimplementedPermissionsServer) Check(context.Context, *CheckRequest) (*CheckResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method Check not implemented")
+}
+func (UnimplementedPermissionsServer) BatchCheck(context.Context, *BatchCheckRequest) (*BatchCheckResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method BatchCheck not implemented")
+}
+func (UnimplementedPermissionsServer) CacheStats(context.Context, *CacheStatsRequest) (*CacheStatsResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method CacheStats not implemented")
+}
+func (UnimplementedPermissionsServer) Expand(context.Context, *ExpandRequest) (*ExpandResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method Expand not implemented")
+}
+func (UnimplementedPermissionsServer) ListObjects(context.Context, *ListObjectsRequest) (*ListObjectsResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method ListObjects not implemented")
+}
+func (UnimplementedPermissionsServer) WritePolicy(context.Context, *WritePolicyRequest) (*WritePolicyResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method WritePolicy not implemented")
+}
+func (UnimplementedPermissionsServer) ReadPolicy(context.Context, *ReadPolicyRequest) (*ReadPolicyResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method ReadPolicy not implemented")
+}
+func (UnimplementedPermissionsServer) Evaluate(context.Context, *EvaluateRequest) (*EvaluateResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method Evaluate not implemented")
+}
+func (UnimplementedPermissionsServer) DryRunPolicy(context.Context, *DryRunPolicyRequest) (*DryRunPolicyResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method DryRunPolicy not implemented")
+}
+func (UnimplementedPermissionsServer) mustEmbedUnimplementedPermissionsServer() {}
+func (UnimplementedPermissionsServer) testEmbeddedByValue()                     {}
+
+// UnsafePermissionsServer may be embedded to opt out of forward compatibility for this service.
+// Use of this interface is not recommended, as added methods to PermissionsServer will
+// result in compilation errors.
+type UnsafePermissionsServer interface {
+	mustEmbedUnimplementedPermissionsServer()
+}
+
+func RegisterPermissionsServer(s grpc.ServiceRegistrar, srv PermissionsServer) {
+	// If the following call pancis, it indicates UnimplementedPermissionsServer was
+	// embedded by pointer and is nil.  This will cause panics if an
+	// unimplemented method is ever invoked, so we test this at initialization
+	// time to prevent it from happening at runtime later due to I/O.
+	if t, ok := srv.(interface{ testEmbeddedByValue() }); ok {
+		t.testEmbeddedByValue()
+	}
+	s.RegisterService(&Permissions_ServiceDesc, srv)
+}
+
+func _Permissions_WriteNamespaceConfig_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(WriteNamespaceConfigRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(PermissionsServer).WriteNamespaceConfig(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Permissions_WriteNamespaceConfig_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(PermissionsServer).WriteNamespaceConfig(ctx, req.(*WriteNamespaceConfigRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Permissions_ReadNamespaceConfig_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ReadNamespaceConfigRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(PermissionsServer).ReadNamespaceConfig(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Permissions_ReadNamespaceConfig_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(PermissionsServer).ReadNamespaceConfig(ctx, req.(*ReadNamespaceConfigRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Permissions_WriteTuples_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(WriteTuplesRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(PermissionsServer).WriteTuples(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Permissions_WriteTuples_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(PermissionsServer).WriteTuples(ctx, req.(*WriteTuplesRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Permissions_Check_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(CheckRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(PermissionsServer).Check(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Permissions_Check_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(PermissionsServer).Check(ctx, req.(*CheckRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Permissions_BatchCheck_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(BatchCheckRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(PermissionsServer).BatchCheck(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Permissions_BatchCheck_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(PermissionsServer).BatchCheck(ctx, req.(*BatchCheckRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Permissions_CacheStats_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(CacheStatsRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(PermissionsServer).CacheStats(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Permissions_CacheStats_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(PermissionsServer).CacheStats(ctx, req.(*CacheStatsRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Permissions_Expand_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ExpandRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(PermissionsServer).Expand(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Permissions_Expand_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(PermissionsServer).Expand(ctx, req.(*ExpandRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Permissions_ListObjects_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ListObjectsRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(PermissionsServer).ListObjects(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Permissions_ListObjects_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(PermissionsServer).ListObjects(ctx, req.(*ListObjectsRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Permissions_WritePolicy_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(WritePolicyRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(PermissionsServer).WritePolicy(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Permissions_WritePolicy_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(PermissionsServer).WritePolicy(ctx, req.(*WritePolicyRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Permissions_ReadPolicy_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ReadPolicyRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(PermissionsServer).ReadPolicy(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Permissions_ReadPolicy_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(PermissionsServer).ReadPolicy(ctx, req.(*ReadPolicyRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Permissions_Evaluate_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(EvaluateRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(PermissionsServer).Evaluate(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Permissions_Evaluate_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(PermissionsServer).Evaluate(ctx, req.(*EvaluateRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Permissions_DryRunPolicy_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(DryRunPolicyRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(PermissionsServer).DryRunPolicy(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Permissions_DryRunPolicy_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(PermissionsServer).DryRunPolicy(ctx, req.(*DryRunPolicyRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+// Permissions_ServiceDesc is the grpc.ServiceDesc for Permissions service.
+// It's only intended for direct use with grpc.RegisterService,
+// and not to be introspected or modified (even as a copy)
+var Permissions_ServiceDesc = grpc.ServiceDesc{
+	ServiceName: "permissions.Permissions",
+	HandlerType: (*PermissionsServer)(nil),
+	Methods: []grpc.MethodDesc{
+		{
+			MethodName: "WriteNamespaceConfig",
+			Handler:    _Permissions_WriteNamespaceConfig_Handler,
+		},
+		{
+			MethodName: "ReadNamespaceConfig",
+			Handler:    _Permissions_ReadNamespaceConfig_Handler,
+		},
+		{
+			MethodName: "WriteTuples",
+			Handler:    _Permissions_WriteTuples_Handler,
+		},
+		{
+			MethodName: "Check",
+			Handler:    _Permissions_Check_Handler,
+		},
+		{
+			MethodName: "BatchCheck",
+			Handler:    _Permissions_BatchCheck_Handler,
+		},
+		{
+			MethodName: "CacheStats",
+			Handler:    _Permissions_CacheStats_Handler,
+		},
+		{
+			MethodName: "Expand",
+			Handler:    _Permissions_Expand_Handler,
+		},
+		{
+			MethodName: "ListObjects",
+			Handler:    _Permissions_ListObjects_Handler,
+		},
+		{
+			MethodName: "WritePolicy",
+			Handler:    _Permissions_WritePolicy_Handler,
+		},
+		{
+			MethodName: "ReadPolicy",
+			Handler:    _Permissions_ReadPolicy_Handler,
+		},
+		{
+			MethodName: "Evaluate",
+			Handler:    _Permissions_Evaluate_Handler,
+		},
+		{
+			MethodName: "DryRunPolicy",
+			Handler:    _Permissions_DryRunPolicy_Handler,
+		},
+	},
+	Streams:  []grpc.StreamDesc{},
+	Metadata: "permissions/permissions.proto",
+}
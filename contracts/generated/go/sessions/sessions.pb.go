@@ -0,0 +1,491 @@
+// Code generated by protoc-gen-go. DO NOT EDIT.
+// versions:
+// 	protoc-gen-go v1.36.9
+// 	protoc        v3.21.12
+// source: sessions/sessions.proto
+
+package sessionsv1
+
+import (
+	protoreflect "google.golang.org/protobuf/reflect/protoreflect"
+	protoimpl "google.golang.org/protobuf/runtime/protoimpl"
+	reflect "reflect"
+	sync "sync"
+	unsafe "unsafe"
+)
+
+const (
+	// Verify that this generated code is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(20 - protoimpl.MinVersion)
+	// Verify that runtime/protoimpl is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(protoimpl.MaxVersion - 20)
+)
+
+type LogoutRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *LogoutRequest) Reset() {
+	*x = LogoutRequest{}
+	mi := &file_sessions_sessions_proto_msgTypes[0]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *LogoutRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*LogoutRequest) ProtoMessage() {}
+
+func (x *LogoutRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_sessions_sessions_proto_msgTypes[0]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use LogoutRequest.ProtoReflect.Descriptor instead.
+func (*LogoutRequest) Descriptor() ([]byte, []int) {
+	return file_sessions_sessions_proto_rawDescGZIP(), []int{0}
+}
+
+type LogoutResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	NotifiedApps  int32                  `protobuf:"varint,1,opt,name=notified_apps,json=notifiedApps,proto3" json:"notified_apps,omitempty"` // Count of the applications which get logout tokens
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *LogoutResponse) Reset() {
+	*x = LogoutResponse{}
+	mi := &file_sessions_sessions_proto_msgTypes[1]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *LogoutResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*LogoutResponse) ProtoMessage() {}
+
+func (x *LogoutResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_sessions_sessions_proto_msgTypes[1]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use LogoutResponse.ProtoReflect.Descriptor instead.
+func (*LogoutResponse) Descriptor() ([]byte, []int) {
+	return file_sessions_sessions_proto_rawDescGZIP(), []int{1}
+}
+
+func (x *LogoutResponse) GetNotifiedApps() int32 {
+	if x != nil {
+		return x.NotifiedApps
+	}
+	return 0
+}
+
+type RevokeSessionsRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	UserId        int64                  `protobuf:"varint,1,opt,name=user_id,json=userId,proto3" json:"user_id,omitempty"` // User ID of the user whose sessions are ended
+	AppId         int32                  `protobuf:"varint,2,opt,name=app_id,json=appId,proto3" json:"app_id,omitempty"`    // ID of the application, zero ends sessions in all applications and requires super-admin rights
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *RevokeSessionsRequest) Reset() {
+	*x = RevokeSessionsRequest{}
+	mi := &file_sessions_sessions_proto_msgTypes[2]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *RevokeSessionsRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*RevokeSessionsRequest) ProtoMessage() {}
+
+func (x *RevokeSessionsRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_sessions_sessions_proto_msgTypes[2]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use RevokeSessionsRequest.ProtoReflect.Descriptor instead.
+func (*RevokeSessionsRequest) Descriptor() ([]byte, []int) {
+	return file_sessions_sessions_proto_rawDescGZIP(), []int{2}
+}
+
+func (x *RevokeSessionsRequest) GetUserId() int64 {
+	if x != nil {
+		return x.UserId
+	}
+	return 0
+}
+
+func (x *RevokeSessionsRequest) GetAppId() int32 {
+	if x != nil {
+		return x.AppId
+	}
+	return 0
+}
+
+type RevokeSessionsResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	NotifiedApps  int32                  `protobuf:"varint,1,opt,name=notified_apps,json=notifiedApps,proto3" json:"notified_apps,omitempty"` // Count of the applications which get logout tokens
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *RevokeSessionsResponse) Reset() {
+	*x = RevokeSessionsResponse{}
+	mi := &file_sessions_sessions_proto_msgTypes[3]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *RevokeSessionsResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*RevokeSessionsResponse) ProtoMessage() {}
+
+func (x *RevokeSessionsResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_sessions_sessions_proto_msgTypes[3]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use RevokeSessionsResponse.ProtoReflect.Descriptor instead.
+func (*RevokeSessionsResponse) Descriptor() ([]byte, []int) {
+	return file_sessions_sessions_proto_rawDescGZIP(), []int{3}
+}
+
+func (x *RevokeSessionsResponse) GetNotifiedApps() int32 {
+	if x != nil {
+		return x.NotifiedApps
+	}
+	return 0
+}
+
+type LogoutDelivery struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Id            int64                  `protobuf:"varint,1,opt,name=id,proto3" json:"id,omitempty"`                                      // ID of the delivery
+	AppId         int32                  `protobuf:"varint,2,opt,name=app_id,json=appId,proto3" json:"app_id,omitempty"`                   // ID of the notified application
+	UserId        int64                  `protobuf:"varint,3,opt,name=user_id,json=userId,proto3" json:"user_id,omitempty"`                // User ID of the logged out user
+	Status        string                 `protobuf:"bytes,4,opt,name=status,proto3" json:"status,omitempty"`                               // pending, delivered or failed
+	Attempts      int32                  `protobuf:"varint,5,opt,name=attempts,proto3" json:"attempts,omitempty"`                          // Count of the made attempts
+	LastError     string                 `protobuf:"bytes,6,opt,name=last_error,json=lastError,proto3" json:"last_error,omitempty"`        // Error of the last failed attempt
+	CreatedAt     int64                  `protobuf:"varint,7,opt,name=created_at,json=createdAt,proto3" json:"created_at,omitempty"`       // Time of the logout (unix seconds)
+	DeliveredAt   int64                  `protobuf:"varint,8,opt,name=delivered_at,json=deliveredAt,proto3" json:"delivered_at,omitempty"` // Time of the successful attempt (unix seconds), zero until it is made
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *LogoutDelivery) Reset() {
+	*x = LogoutDelivery{}
+	mi := &file_sessions_sessions_proto_msgTypes[4]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *LogoutDelivery) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*LogoutDelivery) ProtoMessage() {}
+
+func (x *LogoutDelivery) ProtoReflect() protoreflect.Message {
+	mi := &file_sessions_sessions_proto_msgTypes[4]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use LogoutDelivery.ProtoReflect.Descriptor instead.
+func (*LogoutDelivery) Descriptor() ([]byte, []int) {
+	return file_sessions_sessions_proto_rawDescGZIP(), []int{4}
+}
+
+func (x *LogoutDelivery) GetId() int64 {
+	if x != nil {
+		return x.Id
+	}
+	return 0
+}
+
+func (x *LogoutDelivery) GetAppId() int32 {
+	if x != nil {
+		return x.AppId
+	}
+	return 0
+}
+
+func (x *LogoutDelivery) GetUserId() int64 {
+	if x != nil {
+		return x.UserId
+	}
+	return 0
+}
+
+func (x *LogoutDelivery) GetStatus() string {
+	if x != nil {
+		return x.Status
+	}
+	return ""
+}
+
+func (x *LogoutDelivery) GetAttempts() int32 {
+	if x != nil {
+		return x.Attempts
+	}
+	return 0
+}
+
+func (x *LogoutDelivery) GetLastError() string {
+	if x != nil {
+		return x.LastError
+	}
+	return ""
+}
+
+func (x *LogoutDelivery) GetCreatedAt() int64 {
+	if x != nil {
+		return x.CreatedAt
+	}
+	return 0
+}
+
+func (x *LogoutDelivery) GetDeliveredAt() int64 {
+	if x != nil {
+		return x.DeliveredAt
+	}
+	return 0
+}
+
+type ListLogoutDeliveriesRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	AppId         int32                  `protobuf:"varint,1,opt,name=app_id,json=appId,proto3" json:"app_id,omitempty"` // ID of the application, zero lists deliveries to all applications
+	Limit         int32                  `protobuf:"varint,2,opt,name=limit,proto3" json:"limit,omitempty"`              // Max count of the latest deliveries, 100 by default
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ListLogoutDeliveriesRequest) Reset() {
+	*x = ListLogoutDeliveriesRequest{}
+	mi := &file_sessions_sessions_proto_msgTypes[5]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ListLogoutDeliveriesRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ListLogoutDeliveriesRequest) ProtoMessage() {}
+
+func (x *ListLogoutDeliveriesRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_sessions_sessions_proto_msgTypes[5]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ListLogoutDeliveriesRequest.ProtoReflect.Descriptor instead.
+func (*ListLogoutDeliveriesRequest) Descriptor() ([]byte, []int) {
+	return file_sessions_sessions_proto_rawDescGZIP(), []int{5}
+}
+
+func (x *ListLogoutDeliveriesRequest) GetAppId() int32 {
+	if x != nil {
+		return x.AppId
+	}
+	return 0
+}
+
+func (x *ListLogoutDeliveriesRequest) GetLimit() int32 {
+	if x != nil {
+		return x.Limit
+	}
+	return 0
+}
+
+type ListLogoutDeliveriesResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Deliveries    []*LogoutDelivery      `protobuf:"bytes,1,rep,name=deliveries,proto3" json:"deliveries,omitempty"` // Deliveries from the latest to the oldest
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ListLogoutDeliveriesResponse) Reset() {
+	*x = ListLogoutDeliveriesResponse{}
+	mi := &file_sessions_sessions_proto_msgTypes[6]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ListLogoutDeliveriesResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ListLogoutDeliveriesResponse) ProtoMessage() {}
+
+func (x *ListLogoutDeliveriesResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_sessions_sessions_proto_msgTypes[6]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ListLogoutDeliveriesResponse.ProtoReflect.Descriptor instead.
+func (*ListLogoutDeliveriesResponse) Descriptor() ([]byte, []int) {
+	return file_sessions_sessions_proto_rawDescGZIP(), []int{6}
+}
+
+func (x *ListLogoutDeliveriesResponse) GetDeliveries() []*LogoutDelivery {
+	if x != nil {
+		return x.Deliveries
+	}
+	return nil
+}
+
+var File_sessions_sessions_proto protoreflect.FileDescriptor
+
+const file_sessions_sessions_proto_rawDesc = "" +
+	"\n" +
+	"\x17sessions/sessions.proto\x12\bsessions\"\x0f\n" +
+	"\rLogoutRequest\"5\n" +
+	"\x0eLogoutResponse\x12#\n" +
+	"\rnotified_apps\x18\x01 \x01(\x05R\fnotifiedApps\"G\n" +
+	"\x15RevokeSessionsRequest\x12\x17\n" +
+	"\auser_id\x18\x01 \x01(\x03R\x06userId\x12\x15\n" +
+	"\x06app_id\x18\x02 \x01(\x05R\x05appId\"=\n" +
+	"\x16RevokeSessionsResponse\x12#\n" +
+	"\rnotified_apps\x18\x01 \x01(\x05R\fnotifiedApps\"\xe5\x01\n" +
+	"\x0eLogoutDelivery\x12\x0e\n" +
+	"\x02id\x18\x01 \x01(\x03R\x02id\x12\x15\n" +
+	"\x06app_id\x18\x02 \x01(\x05R\x05appId\x12\x17\n" +
+	"\auser_id\x18\x03 \x01(\x03R\x06userId\x12\x16\n" +
+	"\x06status\x18\x04 \x01(\tR\x06status\x12\x1a\n" +
+	"\battempts\x18\x05 \x01(\x05R\battempts\x12\x1d\n" +
+	"\n" +
+	"last_error\x18\x06 \x01(\tR\tlastError\x12\x1d\n" +
+	"\n" +
+	"created_at\x18\a \x01(\x03R\tcreatedAt\x12!\n" +
+	"\fdelivered_at\x18\b \x01(\x03R\vdeliveredAt\"J\n" +
+	"\x1bListLogoutDeliveriesRequest\x12\x15\n" +
+	"\x06app_id\x18\x01 \x01(\x05R\x05appId\x12\x14\n" +
+	"\x05limit\x18\x02 \x01(\x05R\x05limit\"X\n" +
+	"\x1cListLogoutDeliveriesResponse\x128\n" +
+	"\n" +
+	"deliveries\x18\x01 \x03(\v2\x18.sessions.LogoutDeliveryR\n" +
+	"deliveries2\x83\x02\n" +
+	"\bSessions\x12;\n" +
+	"\x06Logout\x12\x17.sessions.LogoutRequest\x1a\x18.sessions.LogoutResponse\x12S\n" +
+	"\x0eRevokeSessions\x12\x1f.sessions.RevokeSessionsRequest\x1a .sessions.RevokeSessionsResponse\x12e\n" +
+	"\x14ListLogoutDeliveries\x12%.sessions.ListLogoutDeliveriesRequest\x1a&.sessions.ListLogoutDeliveriesResponseBGZEgithub.com/nhassl3/sso-app/contracts/generated/go/sessions;sessionsv1b\x06proto3"
+
+var (
+	file_sessions_sessions_proto_rawDescOnce sync.Once
+	file_sessions_sessions_proto_rawDescData []byte
+)
+
+func file_sessions_sessions_proto_rawDescGZIP() []byte {
+	file_sessions_sessions_proto_rawDescOnce.Do(func() {
+		file_sessions_sessions_proto_rawDescData = protoimpl.X.CompressGZIP(unsafe.Slice(unsafe.StringData(file_sessions_sessions_proto_rawDesc), len(file_sessions_sessions_proto_rawDesc)))
+	})
+	return file_sessions_sessions_proto_rawDescData
+}
+
+var file_sessions_sessions_proto_msgTypes = make([]protoimpl.MessageInfo, 7)
+var file_sessions_sessions_proto_goTypes = []any{
+	(*LogoutRequest)(nil),                // 0: sessions.LogoutRequest
+	(*LogoutResponse)(nil),               // 1: sessions.LogoutResponse
+	(*RevokeSessionsRequest)(nil),        // 2: sessions.RevokeSessionsRequest
+	(*RevokeSessionsResponse)(nil),       // 3: sessions.RevokeSessionsResponse
+	(*LogoutDelivery)(nil),               // 4: sessions.LogoutDelivery
+	(*ListLogoutDeliveriesRequest)(nil),  // 5: sessions.ListLogoutDeliveriesRequest
+	(*ListLogoutDeliveriesResponse)(nil), // 6: sessions.ListLogoutDeliveriesResponse
+}
+var file_sessions_sessions_proto_depIdxs = []int32{
+	4, // 0: sessions.ListLogoutDeliveriesResponse.deliveries:type_name -> sessions.LogoutDelivery
+	0, // 1: sessions.Sessions.Logout:input_type -> sessions.LogoutRequest
+	2, // 2: sessions.Sessions.RevokeSessions:input_type -> sessions.RevokeSessionsRequest
+	5, // 3: sessions.Sessions.ListLogoutDeliveries:input_type -> sessions.ListLogoutDeliveriesRequest
+	1, // 4: sessions.Sessions.Logout:output_type -> sessions.LogoutResponse
+	3, // 5: sessions.Sessions.RevokeSessions:output_type -> sessions.RevokeSessionsResponse
+	6, // 6: sessions.Sessions.ListLogoutDeliveries:output_type -> sessions.ListLogoutDeliveriesResponse
+	4, // [4:7] is the sub-list for method output_type
+	1, // [1:4] is the sub-list for method input_type
+	1, // [1:1] is the sub-list for extension type_name
+	1, // [1:1] is the sub-list for extension extendee
+	0, // [0:1] is the sub-list for field type_name
+}
+
+func init() { file_sessions_sessions_proto_init() }
+func file_sessions_sessions_proto_init() {
+	if File_sessions_sessions_proto != nil {
+		return
+	}
+	type x struct{}
+	out := protoimpl.TypeBuilder{
+		File: protoimpl.DescBuilder{
+			GoPackagePath: reflect.TypeOf(x{}).PkgPath(),
+			RawDescriptor: unsafe.Slice(unsafe.StringData(file_sessions_sessions_proto_rawDesc), len(file_sessions_sessions_proto_rawDesc)),
+			NumEnums:      0,
+			NumMessages:   7,
+			NumExtensions: 0,
+			NumServices:   1,
+		},
+		GoTypes:           file_sessions_sessions_proto_goTypes,
+		DependencyIndexes: file_sessions_sessions_proto_depIdxs,
+		MessageInfos:      file_sessions_sessions_proto_msgTypes,
+	}.Build()
+	File_sessions_sessions_proto = out.File
+	file_sessions_sessions_proto_goTypes = nil
+	file_sessions_sessions_proto_depIdxs = nil
+}
@@ -0,0 +1,319 @@
+// Code generated by protoc-gen-go-grpc. DO NOT EDIT.
+// versions:
+// - protoc-gen-go-grpc v1.5.1
+// - protoc             v3.21.12
+// source: token/token.proto
+
+package tokenv1
+
+import (
+	context "context"
+	grpc "google.golang.org/grpc"
+	codes "google.golang.org/grpc/codes"
+	status "google.golang.org/grpc/status"
+)
+
+// This is a compile-time assertion to ensure that this generated file
+// is compatible with the grpc package it is being compiled against.
+// Requires gRPC-Go v1.64.0 or later.
+const _ = grpc.SupportPackageIsVersion9
+
+const (
+	Token_Introspect_FullMethodName    = "/token.Token/Introspect"
+	Token_ApproveDevice_FullMethodName = "/token.Token/ApproveDevice"
+	Token_DenyDevice_FullMethodName    = "/token.Token/DenyDevice"
+	Token_ListConsents_FullMethodName  = "/token.Token/ListConsents"
+	Token_RevokeConsent_FullMethodName = "/token.Token/RevokeConsent"
+	Token_DiscoverRealm_FullMethodName = "/token.Token/DiscoverRealm"
+)
+
+// TokenClient is the client API for Token service.
+//
+// For semantics around ctx use and closing/ending streaming RPCs, please refer to https://pkg.go.dev/google.golang.org/grpc/?tab=doc#ClientConn.NewStream.
+//
+// Introspect requires bearer token of the application the token is issued for,
+// ApproveDevice, DenyDevice, ListConsents and RevokeConsent require bearer token of the user
+// issued for the admin console
+type TokenClient interface {
+	Introspect(ctx context.Context, in *IntrospectRequest, opts ...grpc.CallOption) (*IntrospectResponse, error)
+	ApproveDevice(ctx context.Context, in *ApproveDeviceRequest, opts ...grpc.CallOption) (*ApproveDeviceResponse, error)
+	DenyDevice(ctx context.Context, in *DenyDeviceRequest, opts ...grpc.CallOption) (*DenyDeviceResponse, error)
+	ListConsents(ctx context.Context, in *ListConsentsRequest, opts ...grpc.CallOption) (*ListConsentsResponse, error)
+	RevokeConsent(ctx context.Context, in *RevokeConsentRequest, opts ...grpc.CallOption) (*RevokeConsentResponse, error)
+	DiscoverRealm(ctx context.Context, in *DiscoverRealmRequest, opts ...grpc.CallOption) (*DiscoverRealmResponse, error)
+}
+
+type tokenClient struct {
+	cc grpc.ClientConnInterface
+}
+
+func NewTokenClient(cc grpc.ClientConnInterface) TokenClient {
+	return &tokenClient{cc}
+}
+
+func (c *tokenClient) Introspect(ctx context.Context, in *IntrospectRequest, opts ...grpc.CallOption) (*IntrospectResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(IntrospectResponse)
+	err := c.cc.Invoke(ctx, Token_Introspect_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *tokenClient) ApproveDevice(ctx context.Context, in *ApproveDeviceRequest, opts ...grpc.CallOption) (*ApproveDeviceResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(ApproveDeviceResponse)
+	err := c.cc.Invoke(ctx, Token_ApproveDevice_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *tokenClient) DenyDevice(ctx context.Context, in *DenyDeviceRequest, opts ...grpc.CallOption) (*DenyDeviceResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(DenyDeviceResponse)
+	err := c.cc.Invoke(ctx, Token_DenyDevice_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *tokenClient) ListConsents(ctx context.Context, in *ListConsentsRequest, opts ...grpc.CallOption) (*ListConsentsResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(ListConsentsResponse)
+	err := c.cc.Invoke(ctx, Token_ListConsents_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *tokenClient) RevokeConsent(ctx context.Context, in *RevokeConsentRequest, opts ...grpc.CallOption) (*RevokeConsentResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(RevokeConsentResponse)
+	err := c.cc.Invoke(ctx, Token_RevokeConsent_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *tokenClient) DiscoverRealm(ctx context.Context, in *DiscoverRealmRequest, opts ...grpc.CallOption) (*DiscoverRealmResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(DiscoverRealmResponse)
+	err := c.cc.Invoke(ctx, Token_DiscoverRealm_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// TokenServer is the server API for Token service.
+// All implementations must embed UnimplementedTokenServer
+// for forward compatibility.
+//
+// Introspect requires bearer token of the application the token is issued for,
+// ApproveDevice, DenyDevice, ListConsents and RevokeConsent require bearer token of the user
+// issued for the admin console
+type TokenServer interface {
+	Introspect(context.Context, *IntrospectRequest) (*IntrospectResponse, error)
+	ApproveDevice(context.Context, *ApproveDeviceRequest) (*ApproveDeviceResponse, error)
+	DenyDevice(context.Context, *DenyDeviceRequest) (*DenyDeviceResponse, error)
+	ListConsents(context.Context, *ListConsentsRequest) (*ListConsentsResponse, error)
+	RevokeConsent(context.Context, *RevokeConsentRequest) (*RevokeConsentResponse, error)
+	DiscoverRealm(context.Context, *DiscoverRealmRequest) (*DiscoverRealmResponse, error)
+	mustEmbedUnimplementedTokenServer()
+}
+
+// UnimplementedTokenServer must be embedded to have
+// forward compatible implementations.
+//
+// NOTE: this should be embedded by value instead of pointer to avoid a nil
+// pointer dereference when methods are called.
+type UnimplementedTokenServer struct{}
+
+func (UnimplementedTokenServer) Introspect(context.Context, *IntrospectRequest) (*IntrospectResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method Introspect not implemented")
+}
+func (UnimplementedTokenServer) ApproveDevice(context.Context, *ApproveDeviceRequest) (*ApproveDeviceResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method ApproveDevice not implemented")
+}
+func (UnimplementedTokenServer) DenyDevice(context.Context, *DenyDeviceRequest) (*DenyDeviceResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method DenyDevice not implemented")
+}
+func (UnimplementedTokenServer) ListConsents(context.Context, *ListConsentsRequest) (*ListConsentsResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method ListConsents not implemented")
+}
+func (UnimplementedTokenServer) RevokeConsent(context.Context, *RevokeConsentRequest) (*RevokeConsentResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method RevokeConsent not implemented")
+}
+func (UnimplementedTokenServer) DiscoverRealm(context.Context, *DiscoverRealmRequest) (*DiscoverRealmResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method DiscoverRealm not implemented")
+}
+func (UnimplementedTokenServer) mustEmbedUnimplementedTokenServer() {}
+func (UnimplementedTokenServer) testEmbeddedByValue()               {}
+
+// UnsafeTokenServer may be embedded to opt out of forward compatibility for this service.
+// Use of this interface is not recommended, as added methods to TokenServer will
+// result in compilation errors.
+type UnsafeTokenServer interface {
+	mustEmbedUnimplementedTokenServer()
+}
+
+func RegisterTokenServer(s grpc.ServiceRegistrar, srv TokenServer) {
+	// If the following call pancis, it indicates UnimplementedTokenServer was
+	// embedded by pointer and is nil.  This will cause panics if an
+	// unimplemented method is ever invoked, so we test this at initialization
+	// time to prevent it from happening at runtime later due to I/O.
+	if t, ok := srv.(interface{ testEmbeddedByValue() }); ok {
+		t.testEmbeddedByValue()
+	}
+	s.RegisterService(&Token_ServiceDesc, srv)
+}
+
+func _Token_Introspect_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(IntrospectRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(TokenServer).Introspect(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Token_Introspect_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(TokenServer).Introspect(ctx, req.(*IntrospectRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Token_ApproveDevice_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ApproveDeviceRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(TokenServer).ApproveDevice(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Token_ApproveDevice_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(TokenServer).ApproveDevice(ctx, req.(*ApproveDeviceRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Token_DenyDevice_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(DenyDeviceRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(TokenServer).DenyDevice(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Token_DenyDevice_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(TokenServer).DenyDevice(ctx, req.(*DenyDeviceRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Token_ListConsents_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ListConsentsRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(TokenServer).ListConsents(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Token_ListConsents_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(TokenServer).ListConsents(ctx, req.(*ListConsentsRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Token_RevokeConsent_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(RevokeConsentRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(TokenServer).RevokeConsent(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Token_RevokeConsent_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(TokenServer).RevokeConsent(ctx, req.(*RevokeConsentRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Token_DiscoverRealm_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(DiscoverRealmRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(TokenServer).DiscoverRealm(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Token_DiscoverRealm_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(TokenServer).DiscoverRealm(ctx, req.(*DiscoverRealmRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+// Token_ServiceDesc is the grpc.ServiceDesc for Token service.
+// It's only intended for direct use with grpc.RegisterService,
+// and not to be introspected or modified (even as a copy)
+var Token_ServiceDesc = grpc.ServiceDesc{
+	ServiceName: "token.Token",
+	HandlerType: (*TokenServer)(nil),
+	Methods: []grpc.MethodDesc{
+		{
+			MethodName: "Introspect",
+			Handler:    _Token_Introspect_Handler,
+		},
+		{
+			MethodName: "ApproveDevice",
+			Handler:    _Token_ApproveDevice_Handler,
+		},
+		{
+			MethodName: "DenyDevice",
+			Handler:    _Token_DenyDevice_Handler,
+		},
+		{
+			MethodName: "ListConsents",
+			Handler:    _Token_ListConsents_Handler,
+		},
+		{
+			MethodName: "RevokeConsent",
+			Handler:    _Token_RevokeConsent_Handler,
+		},
+		{
+			MethodName: "DiscoverRealm",
+			Handler:    _Token_DiscoverRealm_Handler,
+		},
+	},
+	Streams:  []grpc.StreamDesc{},
+	Metadata: "token/token.proto",
+}
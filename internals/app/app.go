@@ -1,29 +1,398 @@
 package app
 
 import (
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
 	"log/slog"
-	"time"
+	"net/http"
+	"regexp"
+	"slices"
+	"strings"
 
+	"github.com/nhassl3/sso-app/internals/app/gatewayapp"
 	"github.com/nhassl3/sso-app/internals/app/grpcapp"
+	"github.com/nhassl3/sso-app/internals/app/httpapp"
+	"github.com/nhassl3/sso-app/internals/app/logoutapp"
+	"github.com/nhassl3/sso-app/internals/app/relayapp"
+	"github.com/nhassl3/sso-app/internals/app/sweeperapp"
+	"github.com/nhassl3/sso-app/internals/app/webhookapp"
+	"github.com/nhassl3/sso-app/internals/config"
+	"github.com/nhassl3/sso-app/internals/domain/services/admin"
+	"github.com/nhassl3/sso-app/internals/domain/services/apps"
 	"github.com/nhassl3/sso-app/internals/domain/services/auth"
+	"github.com/nhassl3/sso-app/internals/domain/services/events"
+	"github.com/nhassl3/sso-app/internals/domain/services/permissions"
+	"github.com/nhassl3/sso-app/internals/domain/services/scim"
+	"github.com/nhassl3/sso-app/internals/domain/services/sessions"
+	"github.com/nhassl3/sso-app/internals/domain/services/webhooks"
+	njwt "github.com/nhassl3/sso-app/internals/lib/jwt"
+	"github.com/nhassl3/sso-app/internals/lib/ldap"
+	"github.com/nhassl3/sso-app/internals/lib/oidc"
 	"github.com/nhassl3/sso-app/internals/storage/sqlite"
 )
 
+// cookieKeySize is size of the generated AES-256 key of the cookies
+const cookieKeySize = 32
+
+var providerName = regexp.MustCompile(`^[a-zA-Z0-9_-]+$`)
+
 type App struct {
 	GRPCServer *grpcapp.App
+	HTTPServer *httpapp.App
+	Gateway    *gatewayapp.App
+	Sweeper    *sweeperapp.App
+	Logout     *logoutapp.App
+	Webhooks   *webhookapp.App
+	Relay      *relayapp.App
+
+	// Events passes published events of the outbox to the handlers of the process if bus publisher is configured
+	Events *events.Bus
 }
 
-func NewApp(log *slog.Logger, gRPCPort int, storagePath string, tokenTTL time.Duration) *App {
-	storage, err := sqlite.NewStorage(storagePath)
+// NewApp returns the application of the config with its servers and background workers
+func NewApp(log *slog.Logger, cfg *config.Config) *App {
+	storage, err := sqlite.NewStorage(cfg.StoragePath)
 	if err != nil {
 		panic(err)
 	}
 
-	authObj := auth.NewAuth(log, storage, storage, storage, tokenTTL)
+	signingKey := mustSigningKey(log, cfg.OAuth.SigningKeyPath)
+	consoleApp := consoleAppID(log, cfg.Admin.ConsoleAppID)
+
+	webhooksObj := webhooks.NewWebhooks(
+		log, storage, storage, storage, &http.Client{Timeout: cfg.Webhooks.Timeout},
+		cfg.Webhooks.MaxAttempts, cfg.Webhooks.RetryBackoff,
+	)
+
+	authObj := auth.NewAuth(log, auth.Deps{
+		UserSaver:    storage,
+		UserProvider: storage,
+		AppProvider:  storage,
+		RoleProvider: storage,
+		CodeSaver:    storage,
+
+		TokenTTL:   cfg.TokenTTL,
+		CodeTTL:    cfg.OAuth.CodeTTL,
+		Issuer:     cfg.OAuth.Issuer,
+		SigningKey: signingKey,
+
+		DeviceCodeSaver:       storage,
+		DeviceCodeTTL:         cfg.OAuth.DeviceCodeTTL,
+		DeviceInterval:        cfg.OAuth.DeviceInterval,
+		DeviceVerificationURI: cfg.OAuth.DeviceVerificationURI,
+
+		AuditSaver:       storage,
+		ImpersonationTTL: cfg.OAuth.ImpersonationTTL,
+		ConsoleAppID:     consoleApp,
+
+		RefreshTokenSaver: storage,
+		ConsentSaver:      storage,
+		SessionSaver:      storage,
+
+		FederationSaver:    storage,
+		Providers:          mustProviders(cfg.Federation),
+		FederationStateTTL: cfg.Federation.StateTTL,
+
+		Directory:  directory(cfg.LDAP),
+		RoleSyncer: storage,
+		GroupRoles: groupRoles(cfg.LDAP),
+
+		IdentityChain: mustIdentityChain(cfg.IdentityChain, cfg.Federation, cfg.LDAP),
+		Realms:        mustRealms(cfg.Federation),
+	})
+
+	adminObj := admin.NewAdmin(log, storage, storage, storage, storage, storage, cfg.Admin.MaxElevationTTL)
+
+	permissionsObj := permissions.NewPermissions(log, permissions.Deps{
+		TupleSaver:        storage,
+		TupleProvider:     storage,
+		NamespaceSaver:    storage,
+		NamespaceProvider: storage,
+		AdminProvider:     storage,
+		PolicySaver:       storage,
+		PolicyProvider:    storage,
+		UserProvider:      storage,
+
+		CacheTTL:      cfg.Permissions.CacheTTL,
+		CacheSize:     cfg.Permissions.CacheSize,
+		DecisionsKept: cfg.Permissions.DecisionsKept,
+	})
+
+	appsObj := apps.NewApps(log, storage, storage, storage, permissionsObj)
+
+	sessionsObj := sessions.NewSessions(
+		log, storage, storage, storage, storage,
+		signingKey, cfg.OAuth.Issuer, &http.Client{Timeout: cfg.Logout.Timeout},
+		cfg.Logout.MaxAttempts, cfg.Logout.RetryBackoff,
+	)
+
+	scimObj := scim.NewScim(log, storage, storage, storage, storage, permissionsObj)
+
+	gRPCApp := grpcapp.NewApp(
+		log, cfg.GRPC.Port, consoleApp,
+		authObj, adminObj, appsObj, permissionsObj, sessionsObj, webhooksObj,
+	)
+
+	httpApp := httpapp.NewApp(
+		log, cfg.HTTP.Port, cfg.HTTP.Timeout, authObj, scimObj,
+		mustCookieKey(log, cfg.Pages.CookieKey), cfg.Pages.SecureCookies, cfg.Pages.LoginTTL,
+	)
+
+	gatewayApp := gatewayapp.NewApp(
+		log, cfg.Gateway.Address, cfg.Gateway.ReadTimeout, cfg.Gateway.WriteTimeout, cfg.Gateway.IdleTimeout,
+		fmt.Sprintf("localhost:%d", cfg.GRPC.Port),
+	)
+
+	sweeperApp := sweeperapp.NewApp(log, adminObj, cfg.Admin.SweepInterval)
+
+	logoutApp := logoutapp.NewApp(log, sessionsObj, cfg.Logout.DeliveryInterval)
+
+	webhookApp := webhookapp.NewApp(log, webhooksObj, cfg.Webhooks.DeliveryInterval)
 
-	gRPCApp := grpcapp.NewApp(log, gRPCPort, authObj)
+	bus := events.NewBus()
+
+	relayObj := events.NewRelay(
+		log, storage, mustPublishers(log, cfg.Outbox.Publishers, webhooksObj, bus),
+		cfg.Outbox.RetryBackoff, cfg.Outbox.Retention,
+	)
+
+	relayApp := relayapp.NewApp(log, relayObj, cfg.Outbox.RelayInterval)
 
 	return &App{
 		GRPCServer: gRPCApp,
+		HTTPServer: httpApp,
+		Gateway:    gatewayApp,
+		Sweeper:    sweeperApp,
+		Logout:     logoutApp,
+		Webhooks:   webhookApp,
+		Relay:      relayApp,
+		Events:     bus,
+	}
+}
+
+// mustProviders returns clients of the upstream providers, names of the providers must be unique
+// and safe to put into URI path as is
+func mustProviders(cfg config.FederationConfig) []auth.FederatedProvider {
+	client := &http.Client{Timeout: cfg.Timeout}
+
+	providers := make([]auth.FederatedProvider, 0, len(cfg.Providers))
+	names := make(map[string]bool, len(cfg.Providers))
+
+	for _, provider := range cfg.Providers {
+		if !providerName.MatchString(provider.Name) || names[provider.Name] {
+			panic(fmt.Errorf("provider name %q must be unique and made of letters, digits, '-' and '_'", provider.Name))
+		}
+		names[provider.Name] = true
+
+		if provider.Issuer == "" || provider.ClientID == "" {
+			panic(fmt.Errorf("issuer and client id of provider %q are required", provider.Name))
+		}
+
+		providers = append(providers, auth.FederatedProvider{
+			Provider: oidc.NewProvider(
+				provider.Name, provider.Issuer, provider.ClientID, provider.ClientSecret, provider.Scopes,
+				oidc.ClaimMapping{
+					Subject:       provider.Claims.Subject,
+					Email:         provider.Claims.Email,
+					EmailVerified: provider.Claims.EmailVerified,
+				},
+				client,
+			),
+			TrustEmail: provider.TrustEmail,
+		})
 	}
+
+	return providers
+}
+
+// directory returns the LDAP directory of the config, nil if it is disabled
+func directory(cfg config.LDAPConfig) auth.Directory {
+	if cfg.URL == "" {
+		return nil
+	}
+
+	return ldap.NewDirectory(ldap.Config{
+		URL:                cfg.URL,
+		StartTLS:           cfg.StartTLS,
+		InsecureSkipVerify: cfg.InsecureSkipVerify,
+		Timeout:            cfg.Timeout,
+		BindDN:             cfg.BindDN,
+		BindPassword:       cfg.BindPassword,
+		BaseDN:             cfg.BaseDN,
+		UserFilter:         cfg.UserFilter,
+		SubjectAttribute:   cfg.Attributes.Subject,
+		EmailAttribute:     cfg.Attributes.Email,
+		GroupsAttribute:    cfg.Attributes.Groups,
+	})
+}
+
+func groupRoles(cfg config.LDAPConfig) []auth.GroupRole {
+	roles := make([]auth.GroupRole, 0, len(cfg.GroupRoles))
+	for _, groupRole := range cfg.GroupRoles {
+		roles = append(roles, auth.GroupRole{Group: groupRole.Group, AppID: groupRole.AppID, Role: groupRole.Role})
+	}
+
+	return roles
+}
+
+// mustRealms returns rules of the realms, every domain has one rule for all applications and one rule per application
+func mustRealms(cfg config.FederationConfig) []auth.RealmRule {
+	type realmKey struct {
+		domain string
+		appID  int32
+	}
+
+	rules := make([]auth.RealmRule, 0, len(cfg.Realms))
+	seen := make(map[realmKey]bool, len(cfg.Realms))
+
+	for _, realm := range cfg.Realms {
+		domain := strings.ToLower(strings.TrimPrefix(realm.Domain, "@"))
+		if domain == "" || strings.ContainsAny(domain, "@ ") || realm.AppID < 0 {
+			panic(fmt.Errorf("realm domain %q of app %d is invalid", realm.Domain, realm.AppID))
+		}
+
+		key := realmKey{domain: domain, appID: realm.AppID}
+		if seen[key] {
+			panic(fmt.Errorf("realm domain %q of app %d is routed twice", domain, realm.AppID))
+		}
+		seen[key] = true
+
+		if !hasProvider(cfg, realm.Provider) {
+			panic(fmt.Errorf("realm domain %q is routed to unknown provider %q", domain, realm.Provider))
+		}
+
+		rules = append(rules, auth.RealmRule{Domain: domain, AppID: realm.AppID, Provider: realm.Provider})
+	}
+
+	return rules
+}
+
+// mustIdentityChain returns steps of the identity chain, providers of the steps must be configured.
+// Empty chain authenticates the users by the directory if it is configured and then by the database
+func mustIdentityChain(
+	chain []config.IdentityStepConfig,
+	federationCfg config.FederationConfig,
+	ldapCfg config.LDAPConfig,
+) []auth.IdentityStep {
+	if len(chain) == 0 {
+		if ldapCfg.URL != "" {
+			chain = append(chain, config.IdentityStepConfig{Type: auth.IdentityLDAP})
+		}
+
+		chain = append(chain, config.IdentityStepConfig{Type: auth.IdentityLocal})
+	}
+
+	steps := make([]auth.IdentityStep, 0, len(chain))
+
+	for _, step := range chain {
+		switch step.Type {
+		case auth.IdentityLocal:
+		case auth.IdentityLDAP:
+			if ldapCfg.URL == "" {
+				panic("ldap step of the identity chain requires ldap.url")
+			}
+		case auth.IdentityOIDC:
+			if !hasProvider(federationCfg, step.Provider) {
+				panic(fmt.Errorf("oidc step of the identity chain requires federation provider %q", step.Provider))
+			}
+		default:
+			panic(fmt.Errorf("unknown type %q of the identity chain step", step.Type))
+		}
+
+		steps = append(steps, auth.IdentityStep{Kind: step.Type, Provider: step.Provider, Fallthrough: step.Fallthrough})
+	}
+
+	return steps
+}
+
+// mustPublishers returns publishers of the outbox events by their names, every publisher may be used once
+func mustPublishers(
+	log *slog.Logger,
+	names []string,
+	webhooksObj *webhooks.Webhooks,
+	bus *events.Bus,
+) []events.EventPublisher {
+	publishers := make([]events.EventPublisher, 0, len(names))
+	seen := make(map[string]bool, len(names))
+
+	for _, name := range names {
+		if seen[name] {
+			panic(fmt.Errorf("outbox publisher %q is configured twice", name))
+		}
+		seen[name] = true
+
+		switch name {
+		case "log":
+			publishers = append(publishers, events.NewLogPublisher(log))
+		case "webhook":
+			publishers = append(publishers, webhooksObj)
+		case "bus":
+			publishers = append(publishers, bus)
+		default:
+			panic(fmt.Errorf("unknown outbox publisher %q", name))
+		}
+	}
+
+	return publishers
+}
+
+// hasProvider reports whether the upstream provider is configured
+func hasProvider(cfg config.FederationConfig, name string) bool {
+	return slices.ContainsFunc(cfg.Providers, func(provider config.ProviderConfig) bool {
+		return provider.Name == name
+	})
+}
+
+// consoleAppID returns ID of the admin console application, admin requests are denied if it is not set
+func consoleAppID(log *slog.Logger, appID int32) int32 {
+	if appID <= 0 {
+		log.Warn("admin.console_app_id is not set, admin requests will be denied")
+
+		return 0
+	}
+
+	return appID
+}
+
+// mustCookieKey decodes key of the cookies of the hosted pages or generates it if key is empty
+func mustCookieKey(log *slog.Logger, key string) []byte {
+	if key == "" {
+		log.Warn("cookie key is not set, cookies of the hosted pages will be sealed by generated key")
+
+		generated := make([]byte, cookieKeySize)
+		if _, err := rand.Read(generated); err != nil {
+			panic(err)
+		}
+
+		return generated
+	}
+
+	decoded, err := hex.DecodeString(key)
+	if err != nil {
+		panic(fmt.Errorf("cookie key must be hex: %w", err))
+	}
+
+	return decoded
+}
+
+// mustSigningKey loads signing key of ID tokens or generates it if path is empty
+func mustSigningKey(log *slog.Logger, path string) *njwt.SigningKey {
+	if path == "" {
+		log.Warn("signing key path is not set, ID tokens will be signed by generated key")
+
+		key, err := njwt.GenerateSigningKey()
+		if err != nil {
+			panic(err)
+		}
+
+		return key
+	}
+
+	key, err := njwt.LoadSigningKey(path)
+	if err != nil {
+		panic(err)
+	}
+
+	return key
 }
@@ -0,0 +1,86 @@
+package gatewayapp
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net"
+	"net/http"
+	"time"
+
+	"github.com/nhassl3/sso-app/internals/http/gateway"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials/insecure"
+)
+
+const (
+	opNew   = "gatewayapp.NewApp"
+	opStart = "gatewayapp.MustStart"
+)
+
+type App struct {
+	log        *slog.Logger
+	httpServer *http.Server
+	conn       *grpc.ClientConn
+	address    string
+	timeout    time.Duration
+}
+
+// NewApp returns REST gateway which calls the gRPC server listening on gRPCAddress
+func NewApp(
+	log *slog.Logger,
+	address string,
+	readTimeout time.Duration,
+	writeTimeout time.Duration,
+	idleTimeout time.Duration,
+	gRPCAddress string,
+) *App {
+	// Connection is established by the first call, so the gRPC server may start later
+	conn, err := grpc.NewClient(gRPCAddress, grpc.WithTransportCredentials(insecure.NewCredentials()))
+	if err != nil {
+		panic(fmt.Errorf("%s: %w", opNew, err))
+	}
+
+	mux := http.NewServeMux()
+
+	gateway.Register(mux, conn, writeTimeout)
+
+	return &App{
+		log: log,
+		httpServer: &http.Server{
+			Handler:      mux,
+			ReadTimeout:  readTimeout,
+			WriteTimeout: writeTimeout,
+			IdleTimeout:  idleTimeout,
+		},
+		conn:    conn,
+		address: address,
+		timeout: writeTimeout,
+	}
+}
+
+// MustStart launching HTTP server of the REST gateway
+func (s *App) MustStart() {
+	log := s.log.With(slog.String("op", opStart), slog.String("address", s.address))
+
+	l, err := net.Listen("tcp", s.address)
+	if err != nil {
+		panic(fmt.Errorf("%s: %w", opStart, err))
+	}
+
+	log.Info("server started", slog.String("address", l.Addr().String()))
+
+	if err := s.httpServer.Serve(l); err != nil && !errors.Is(err, http.ErrServerClosed) {
+		panic(fmt.Errorf("%s: %w", opStart, err))
+	}
+}
+
+// Stop graceful stops HTTP server waiting for the active requests at most write timeout, then closes connection to the gRPC server
+func (s *App) Stop() {
+	ctx, cancel := context.WithTimeout(context.Background(), s.timeout)
+	defer cancel()
+
+	_ = s.httpServer.Shutdown(ctx)
+	_ = s.conn.Close()
+}
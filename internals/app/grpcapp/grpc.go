@@ -5,8 +5,20 @@ import (
 	"log/slog"
 	"net"
 
+	"github.com/nhassl3/sso-app/internals/domain/services/admin"
+	"github.com/nhassl3/sso-app/internals/domain/services/apps"
 	"github.com/nhassl3/sso-app/internals/domain/services/auth"
+	"github.com/nhassl3/sso-app/internals/domain/services/permissions"
+	"github.com/nhassl3/sso-app/internals/domain/services/sessions"
+	"github.com/nhassl3/sso-app/internals/domain/services/webhooks"
+	admingrpc "github.com/nhassl3/sso-app/internals/grpc/admin"
+	appsgrpc "github.com/nhassl3/sso-app/internals/grpc/apps"
 	authgrpc "github.com/nhassl3/sso-app/internals/grpc/auth"
+	"github.com/nhassl3/sso-app/internals/grpc/interceptors"
+	permissionsgrpc "github.com/nhassl3/sso-app/internals/grpc/permissions"
+	sessionsgrpc "github.com/nhassl3/sso-app/internals/grpc/sessions"
+	tokengrpc "github.com/nhassl3/sso-app/internals/grpc/token"
+	webhooksgrpc "github.com/nhassl3/sso-app/internals/grpc/webhooks"
 	"google.golang.org/grpc"
 )
 
@@ -20,10 +32,28 @@ type App struct {
 	port       int
 }
 
-func NewApp(log *slog.Logger, port int, authObj *auth.Auth) *App {
-	gRPCServer := grpc.NewServer()
+func NewApp(
+	log *slog.Logger,
+	port int,
+	consoleAppID int32,
+	authObj *auth.Auth,
+	adminObj *admin.Admin,
+	appsObj *apps.Apps,
+	permissionsObj *permissions.Permissions,
+	sessionsObj *sessions.Sessions,
+	webhooksObj *webhooks.Webhooks,
+) *App {
+	gRPCServer := grpc.NewServer(
+		grpc.ChainUnaryInterceptor(interceptors.Auth(authObj, consoleAppID)),
+	)
 
 	authgrpc.Register(gRPCServer, authObj)
+	tokengrpc.Register(gRPCServer, authObj)
+	admingrpc.Register(gRPCServer, adminObj)
+	appsgrpc.Register(gRPCServer, appsObj)
+	permissionsgrpc.Register(gRPCServer, permissionsObj)
+	sessionsgrpc.Register(gRPCServer, sessionsObj)
+	webhooksgrpc.Register(gRPCServer, webhooksObj)
 
 	return &App{
 		log:        log,
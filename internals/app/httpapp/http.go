@@ -0,0 +1,84 @@
+package httpapp
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net"
+	"net/http"
+	"time"
+
+	"github.com/nhassl3/sso-app/internals/domain/services/auth"
+	"github.com/nhassl3/sso-app/internals/domain/services/scim"
+	oauthhttp "github.com/nhassl3/sso-app/internals/http/oauth"
+	scimhttp "github.com/nhassl3/sso-app/internals/http/scim"
+)
+
+const (
+	opNew   = "httpapp.NewApp"
+	opStart = "httpapp.MustStart"
+)
+
+type App struct {
+	log        *slog.Logger
+	httpServer *http.Server
+	port       int
+	timeout    time.Duration
+}
+
+func NewApp(
+	log *slog.Logger,
+	port int,
+	timeout time.Duration,
+	authObj *auth.Auth,
+	scimObj *scim.Scim,
+	cookieKey []byte,
+	secureCookies bool,
+	loginTTL time.Duration,
+) *App {
+	mux := http.NewServeMux()
+
+	oauthhttp.Register(mux, authObj)
+
+	if err := oauthhttp.RegisterPages(mux, authObj, cookieKey, secureCookies, loginTTL); err != nil {
+		panic(fmt.Errorf("%s: %w", opNew, err))
+	}
+
+	scimhttp.Register(mux, scimObj, authObj)
+
+	return &App{
+		log: log,
+		httpServer: &http.Server{
+			Handler:      mux,
+			ReadTimeout:  timeout,
+			WriteTimeout: timeout,
+		},
+		port:    port,
+		timeout: timeout,
+	}
+}
+
+// MustStart launching HTTP server of the OAuth endpoints, hosted pages and SCIM provisioning
+func (s *App) MustStart() {
+	log := s.log.With(slog.String("op", opStart), slog.Int("port", s.port))
+
+	l, err := net.Listen("tcp", fmt.Sprintf(":%d", s.port))
+	if err != nil {
+		panic(fmt.Errorf("%s: %w", opStart, err))
+	}
+
+	log.Info("server started", slog.String("address", l.Addr().String()))
+
+	if err := s.httpServer.Serve(l); err != nil && !errors.Is(err, http.ErrServerClosed) {
+		panic(fmt.Errorf("%s: %w", opStart, err))
+	}
+}
+
+// Stop graceful stops HTTP server waiting for the active requests at most timeout
+func (s *App) Stop() {
+	ctx, cancel := context.WithTimeout(context.Background(), s.timeout)
+	defer cancel()
+
+	_ = s.httpServer.Shutdown(ctx)
+}
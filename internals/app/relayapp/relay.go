@@ -0,0 +1,69 @@
+package relayapp
+
+import (
+	"context"
+	"log/slog"
+	"time"
+
+	"github.com/nhassl3/sso-app/internals/lib/logger/sl"
+)
+
+const (
+	opStart = "relayapp.MustStart"
+)
+
+type Relayer interface {
+	RelayEvents(ctx context.Context) (published int, err error)
+}
+
+// App periodically publishes pending events of the outbox
+type App struct {
+	log      *slog.Logger
+	relayer  Relayer
+	interval time.Duration
+	stop     chan struct{}
+	done     chan struct{}
+}
+
+func NewApp(log *slog.Logger, relayer Relayer, interval time.Duration) *App {
+	return &App{
+		log:      log,
+		relayer:  relayer,
+		interval: interval,
+		stop:     make(chan struct{}),
+		done:     make(chan struct{}),
+	}
+}
+
+// MustStart publishes due events every interval until Stop is called
+func (l *App) MustStart() {
+	defer close(l.done)
+
+	log := l.log.With(slog.String("op", opStart), slog.Duration("interval", l.interval))
+
+	if l.interval <= 0 {
+		panic(opStart + ": relay interval must be positive")
+	}
+
+	log.Info("event relay started")
+
+	ticker := time.NewTicker(l.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-l.stop:
+			return
+		case <-ticker.C:
+			if _, err := l.relayer.RelayEvents(context.Background()); err != nil {
+				log.Error("failed to relay events", sl.Err(err))
+			}
+		}
+	}
+}
+
+// Stop stops relaying and waits for the current events
+func (l *App) Stop() {
+	close(l.stop)
+	<-l.done
+}
@@ -0,0 +1,69 @@
+package sweeperapp
+
+import (
+	"context"
+	"log/slog"
+	"time"
+
+	"github.com/nhassl3/sso-app/internals/lib/logger/sl"
+)
+
+const (
+	opStart = "sweeperapp.MustStart"
+)
+
+type Sweeper interface {
+	SweepExpired(ctx context.Context) (expired int, err error)
+}
+
+// App periodically takes away expired temporary admin rights
+type App struct {
+	log      *slog.Logger
+	sweeper  Sweeper
+	interval time.Duration
+	stop     chan struct{}
+	done     chan struct{}
+}
+
+func NewApp(log *slog.Logger, sweeper Sweeper, interval time.Duration) *App {
+	return &App{
+		log:      log,
+		sweeper:  sweeper,
+		interval: interval,
+		stop:     make(chan struct{}),
+		done:     make(chan struct{}),
+	}
+}
+
+// MustStart sweeps expired rights every interval until Stop is called
+func (s *App) MustStart() {
+	defer close(s.done)
+
+	log := s.log.With(slog.String("op", opStart), slog.Duration("interval", s.interval))
+
+	if s.interval <= 0 {
+		panic(opStart + ": sweep interval must be positive")
+	}
+
+	log.Info("sweeper started")
+
+	ticker := time.NewTicker(s.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-s.stop:
+			return
+		case <-ticker.C:
+			if _, err := s.sweeper.SweepExpired(context.Background()); err != nil {
+				log.Error("failed to sweep expired admin rights", sl.Err(err))
+			}
+		}
+	}
+}
+
+// Stop stops sweeping and waits for the current sweep
+func (s *App) Stop() {
+	close(s.stop)
+	<-s.done
+}
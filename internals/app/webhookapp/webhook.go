@@ -0,0 +1,69 @@
+package webhookapp
+
+import (
+	"context"
+	"log/slog"
+	"time"
+
+	"github.com/nhassl3/sso-app/internals/lib/logger/sl"
+)
+
+const (
+	opStart = "webhookapp.MustStart"
+)
+
+type Deliverer interface {
+	DeliverWebhooks(ctx context.Context) (delivered int, err error)
+}
+
+// App periodically delivers pending webhook payloads to the subscriptions of the applications
+type App struct {
+	log       *slog.Logger
+	deliverer Deliverer
+	interval  time.Duration
+	stop      chan struct{}
+	done      chan struct{}
+}
+
+func NewApp(log *slog.Logger, deliverer Deliverer, interval time.Duration) *App {
+	return &App{
+		log:       log,
+		deliverer: deliverer,
+		interval:  interval,
+		stop:      make(chan struct{}),
+		done:      make(chan struct{}),
+	}
+}
+
+// MustStart delivers due webhook payloads every interval until Stop is called
+func (l *App) MustStart() {
+	defer close(l.done)
+
+	log := l.log.With(slog.String("op", opStart), slog.Duration("interval", l.interval))
+
+	if l.interval <= 0 {
+		panic(opStart + ": delivery interval must be positive")
+	}
+
+	log.Info("webhook deliverer started")
+
+	ticker := time.NewTicker(l.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-l.stop:
+			return
+		case <-ticker.C:
+			if _, err := l.deliverer.DeliverWebhooks(context.Background()); err != nil {
+				log.Error("failed to deliver webhooks", sl.Err(err))
+			}
+		}
+	}
+}
+
+// Stop stops delivering and waits for the current deliveries
+func (l *App) Stop() {
+	close(l.stop)
+	<-l.done
+}
@@ -10,10 +10,25 @@ import (
 )
 
 type Config struct {
-	Env         uint8         `yaml:"env" env-default:"1"`
-	StoragePath string        `yaml:"storage_path" env-required:"true"`
-	TokenTTL    time.Duration `yaml:"token_ttl" env-required:"true"`
-	GRPC        GRPCConfig    `yaml:"grpc"`
+	Env         uint8             `yaml:"env" env-default:"1"`
+	StoragePath string            `yaml:"storage_path" env-required:"true"`
+	TokenTTL    time.Duration     `yaml:"token_ttl" env-required:"true"`
+	GRPC        GRPCConfig        `yaml:"grpc"`
+	HTTP        HTTPConfig        `yaml:"http"`
+	Gateway     GatewayConfig     `yaml:"gateway"`
+	Pages       PagesConfig       `yaml:"pages"`
+	OAuth       OAuthConfig       `yaml:"oauth"`
+	Permissions PermissionsConfig `yaml:"permissions"`
+	Admin       AdminConfig       `yaml:"admin"`
+	Logout      LogoutConfig      `yaml:"logout"`
+	Webhooks    WebhooksConfig    `yaml:"webhooks"`
+	Outbox      OutboxConfig      `yaml:"outbox"`
+	Federation  FederationConfig  `yaml:"federation"`
+	LDAP        LDAPConfig        `yaml:"ldap"`
+
+	// IdentityChain authenticates the users by the password in its order,
+	// empty chain is ldap step if the directory is configured and then local step
+	IdentityChain []IdentityStepConfig `yaml:"identity_chain"`
 }
 
 type GRPCConfig struct {
@@ -21,6 +36,142 @@ type GRPCConfig struct {
 	Timeout time.Duration `yaml:"timeout" env-default:"5s"`
 }
 
+type HTTPConfig struct {
+	Port    int           `yaml:"port" env-default:"8081"`
+	Timeout time.Duration `yaml:"timeout" env-default:"5s"`
+}
+
+type GatewayConfig struct {
+	Address      string        `yaml:"address" env-default:":8082"`
+	ReadTimeout  time.Duration `yaml:"read_timeout" env-default:"5s"`
+	WriteTimeout time.Duration `yaml:"write_timeout" env-default:"5s"` // also limits the call of the RPC
+	IdleTimeout  time.Duration `yaml:"idle_timeout" env-default:"1m"`
+}
+
+type PagesConfig struct {
+	CookieKey     string        `yaml:"cookie_key"`                        // hex of 32-byte key sealing cookies of the hosted pages, empty generates key on start
+	SecureCookies bool          `yaml:"secure_cookies" env-default:"true"` // cookies are sent only by HTTPS
+	LoginTTL      time.Duration `yaml:"login_ttl" env-default:"10m"`       // how long the signed in user may decide on consent
+}
+
+type OAuthConfig struct {
+	CodeTTL        time.Duration `yaml:"code_ttl" env-default:"1m"` // lifetime of the authorization codes
+	Issuer         string        `yaml:"issuer" env-default:"http://localhost:8081"`
+	SigningKeyPath string        `yaml:"signing_key_path"` // PEM file of RSA key of ID tokens, empty generates key on start
+
+	DeviceCodeTTL         time.Duration `yaml:"device_code_ttl" env-default:"10m"`
+	DeviceInterval        time.Duration `yaml:"device_interval" env-default:"5s"` // minimal interval between polls of the device
+	DeviceVerificationURI string        `yaml:"device_verification_uri" env-default:"http://localhost:8081/device"`
+
+	ImpersonationTTL time.Duration `yaml:"impersonation_ttl" env-default:"15m"` // max lifetime of the impersonation tokens
+}
+
+type PermissionsConfig struct {
+	CacheTTL  time.Duration `yaml:"cache_ttl" env-default:"1m"`
+	CacheSize int           `yaml:"cache_size" env-default:"10000"` // 0 disables the decision cache
+
+	DecisionsKept int `yaml:"decisions_kept" env-default:"1000"` // latest policy decisions of the application kept for dry runs
+}
+
+type AdminConfig struct {
+	SweepInterval   time.Duration `yaml:"sweep_interval" env-default:"1m"` // how often expired admin rights are taken away
+	MaxElevationTTL time.Duration `yaml:"max_elevation_ttl" env-default:"8h"`
+	ConsoleAppID    int32         `yaml:"console_app_id"` // only user tokens of this application act as admin, zero denies admin requests
+}
+
+type LogoutConfig struct {
+	DeliveryInterval time.Duration `yaml:"delivery_interval" env-default:"5s"` // how often pending logout notifications are sent
+	Timeout          time.Duration `yaml:"timeout" env-default:"5s"`           // timeout of the request to the logout URI
+	MaxAttempts      int           `yaml:"max_attempts" env-default:"5"`
+	RetryBackoff     time.Duration `yaml:"retry_backoff" env-default:"10s"` // delay before the second attempt, doubled for every next one
+}
+
+type WebhooksConfig struct {
+	DeliveryInterval time.Duration `yaml:"delivery_interval" env-default:"5s"` // how often pending webhook payloads are sent
+	Timeout          time.Duration `yaml:"timeout" env-default:"5s"`           // timeout of the request to the webhook
+	MaxAttempts      int           `yaml:"max_attempts" env-default:"8"`       // failed deliveries go to the dead-letter list after them
+	RetryBackoff     time.Duration `yaml:"retry_backoff" env-default:"10s"`    // delay before the second attempt, doubled for every next one
+}
+
+type OutboxConfig struct {
+	RelayInterval time.Duration `yaml:"relay_interval" env-default:"1s"` // how often pending events are published
+	RetryBackoff  time.Duration `yaml:"retry_backoff" env-default:"5s"`  // delay before the second attempt, doubled for every next one
+	Retention     time.Duration `yaml:"retention" env-default:"168h"`    // how long published events are kept
+
+	// Publishers receive every event, known ones are log, webhook and bus
+	Publishers []string `yaml:"publishers" env-default:"webhook"`
+}
+
+type FederationConfig struct {
+	StateTTL  time.Duration    `yaml:"state_ttl" env-default:"10m"` // how long the user may sign in at the provider
+	Timeout   time.Duration    `yaml:"timeout" env-default:"5s"`    // timeout of the requests to the providers
+	Providers []ProviderConfig `yaml:"providers"`
+	Realms    []RealmConfig    `yaml:"realms"`
+}
+
+// ProviderConfig is the upstream OpenID Connect provider, its callback URI is {issuer of the server}/federation/{name}/callback
+type ProviderConfig struct {
+	Name         string       `yaml:"name"` // shown on the login page and used in the URIs of the provider
+	Issuer       string       `yaml:"issuer"`
+	ClientID     string       `yaml:"client_id"`
+	ClientSecret string       `yaml:"client_secret"`
+	Scopes       []string     `yaml:"scopes"` // openid is always requested
+	Claims       ClaimsConfig `yaml:"claims"`
+	TrustEmail   bool         `yaml:"trust_email"` // verified email of the identity links it to the existing user
+}
+
+// RealmConfig routes the users of the email domain to the provider, they can't sign in by password.
+// Rule of the application takes precedence over the rule of all applications
+type RealmConfig struct {
+	Domain   string `yaml:"domain"`   // e.g. partner.com, subdomains need their own rules
+	AppID    int32  `yaml:"app_id"`   // zero for all applications
+	Provider string `yaml:"provider"` // name of the provider
+}
+
+// ClaimsConfig names claims of ID token of the provider, empty names are standard claims
+type ClaimsConfig struct {
+	Subject       string `yaml:"subject"`
+	Email         string `yaml:"email"`
+	EmailVerified string `yaml:"email_verified"`
+}
+
+// LDAPConfig is the directory of the ldap step of the identity chain, empty URL disables it
+type LDAPConfig struct {
+	URL                string            `yaml:"url"` // ldap:// or ldaps:// URL of the server
+	StartTLS           bool              `yaml:"start_tls"`
+	InsecureSkipVerify bool              `yaml:"insecure_skip_verify"` // certificate of the server isn't verified, only for tests
+	Timeout            time.Duration     `yaml:"timeout" env-default:"5s"`
+	BindDN             string            `yaml:"bind_dn"` // service account searching the users
+	BindPassword       string            `yaml:"bind_password"`
+	BaseDN             string            `yaml:"base_dn"`
+	UserFilter         string            `yaml:"user_filter" env-default:"(mail={login})"` // {login} is replaced by the escaped login
+	Attributes         LDAPAttributes    `yaml:"attributes"`
+	GroupRoles         []GroupRoleConfig `yaml:"group_roles"`
+}
+
+// LDAPAttributes names attributes of the directory entries mapped to the fields of the users
+type LDAPAttributes struct {
+	Subject string `yaml:"subject" env-default:"entryUUID"` // stable ID of the entry, DN if empty
+	Email   string `yaml:"email" env-default:"mail"`
+	Groups  string `yaml:"groups" env-default:"memberOf"`
+}
+
+// GroupRoleConfig grants the role in the application to the members of the group,
+// these roles are synced with the groups by every sign in of the user
+type GroupRoleConfig struct {
+	Group string `yaml:"group"` // DN of the group
+	AppID int32  `yaml:"app_id"`
+	Role  string `yaml:"role"`
+}
+
+// IdentityStepConfig is the step of the identity chain. The step authenticates the user, rejects
+// or passes the user it doesn't know to the next step
+type IdentityStepConfig struct {
+	Type        string `yaml:"type"`        // local, ldap or oidc
+	Provider    string `yaml:"provider"`    // name of the federation provider of oidc step, checked by its password grant
+	Fallthrough bool   `yaml:"fallthrough"` // invalid password passes the user to the next step instead of rejecting
+}
+
 // MustLoad loading configuration of the project
 // and return object in better case else
 // panic and kill all program
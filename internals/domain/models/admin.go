@@ -0,0 +1,33 @@
+package models
+
+import "time"
+
+const (
+	ElevationPending  = "pending"
+	ElevationApproved = "approved"
+	ElevationDenied   = "denied"
+)
+
+type Admin struct {
+	UserID    int64
+	Email     string
+	AppID     int32     // zero for super-admin of the whole system
+	ExpiresAt time.Time // zero for permanent rights
+}
+
+// ElevationRequest is a request of the user for temporary admin rights in the application
+type ElevationRequest struct {
+	ID        int64
+	UserID    int64
+	AppID     int32 // zero for super-admin rights
+	TTL       time.Duration
+	Reason    string
+	Status    string
+	DecidedBy int64 // zero while request is pending
+	CreatedAt time.Time
+}
+
+// IsSuperAdmin reports whether the admin has rights in every application
+func (a Admin) IsSuperAdmin() bool {
+	return a.AppID == 0
+}
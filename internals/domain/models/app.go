@@ -1,7 +1,63 @@
 package models
 
+import (
+	"slices"
+	"time"
+)
+
 type App struct {
-	ID     int
-	Name   string
-	Secret string
+	ID               int
+	Name             string
+	Secret           string
+	ClientSecretHash []byte // bcrypt hash of the OAuth client secret, nil if the client has no secret
+	Disabled         bool   // disabled application can't issue and accept tokens
+	AppSettings
+}
+
+type AppSettings struct {
+	EmbedRoles    bool     // put roles of the user into the token
+	EmbedScope    bool     // put scopes of the user roles into the token
+	MaxClaimsSize int      // max size in bytes of the roles and scope claims
+	RedirectURIs  []string // absolute URIs the OAuth authorization codes may be sent to
+	ClientScopes  []string // scopes the application gets by the client credentials grant
+
+	GrantTypes      []string      // OAuth grant types the client may use, empty allows every grant type
+	AllowedScopes   []string      // scopes the client may ask the user for, empty allows any scope
+	AccessTokenTTL  time.Duration // lifetime of the access tokens, zero for the global token TTL
+	RefreshTokenTTL time.Duration // lifetime of the refresh tokens, zero disables refresh tokens
+	PublicClient    bool          // client without secret may exchange codes and refresh tokens
+	ThirdParty      bool          // client gets scopes of the user only with consent of the user
+
+	BackchannelLogoutURI string // URI logout tokens of OpenID Connect Back-Channel Logout are sent to, empty disables them
+
+	Theme AppTheme // look of the hosted login pages
+}
+
+// AppTheme is the look of the hosted login pages of the application, empty fields keep the default look
+type AppTheme struct {
+	LogoURI         string `json:"logo_uri,omitempty"`
+	PrimaryColor    string `json:"primary_color,omitempty"` // #rrggbb
+	BackgroundColor string `json:"background_color,omitempty"`
+	Title           string `json:"title,omitempty"` // heading of the pages, name of the application by default
+	Text            string `json:"text,omitempty"`  // text shown under the heading
+}
+
+// AllowsGrant reports whether the OAuth client of the application may use the grant type
+func (s AppSettings) AllowsGrant(grantType string) bool {
+	return len(s.GrantTypes) == 0 || slices.Contains(s.GrantTypes, grantType)
+}
+
+// AllowsScopes reports whether the OAuth client of the application may ask the user for the scopes
+func (s AppSettings) AllowsScopes(scopes []string) bool {
+	if len(s.AllowedScopes) == 0 {
+		return true
+	}
+
+	for _, scope := range scopes {
+		if !slices.Contains(s.AllowedScopes, scope) {
+			return false
+		}
+	}
+
+	return true
 }
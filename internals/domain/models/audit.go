@@ -0,0 +1,43 @@
+package models
+
+import "time"
+
+const (
+	AuditActionGrantAdmin  = "admin.grant"
+	AuditActionRevokeAdmin = "admin.revoke"
+	AuditActionExpireAdmin = "admin.expire"
+	AuditActionCreateApp   = "app.create"
+	AuditActionUpdateApp   = "app.update"
+	AuditActionDisableApp  = "app.disable"
+	AuditActionEnableApp   = "app.enable"
+	AuditActionDeleteApp   = "app.delete"
+
+	AuditActionRotateClientSecret = "app.rotate_client_secret"
+
+	AuditActionRequestElevation = "elevation.request"
+	AuditActionApproveElevation = "elevation.approve"
+	AuditActionDenyElevation    = "elevation.deny"
+
+	AuditActionImpersonate = "token.impersonate"
+
+	AuditActionRevokeSessions = "sessions.revoke"
+
+	AuditActionProvisionUser   = "scim.provision"
+	AuditActionDeactivateUser  = "scim.deactivate"
+	AuditActionReactivateUser  = "scim.reactivate"
+	AuditActionDeprovisionUser = "scim.deprovision"
+
+	AuditActionCreateWebhook    = "webhook.create"
+	AuditActionDeleteWebhook    = "webhook.delete"
+	AuditActionRedeliverWebhook = "webhook.redeliver"
+)
+
+type AuditEvent struct {
+	ID           int64
+	ActorID      int64 // zero for changes made by the system itself
+	Action       string
+	TargetUserID int64
+	AppID        int32 // zero if event is not related to some application
+	Details      string
+	CreatedAt    time.Time
+}
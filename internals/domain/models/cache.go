@@ -0,0 +1,9 @@
+package models
+
+// DecisionCacheStats describes usage of the decision cache of one application
+type DecisionCacheStats struct {
+	Hits          uint64
+	Misses        uint64
+	Invalidations uint64
+	Size          int // count of the cached decisions
+}
@@ -0,0 +1,15 @@
+package models
+
+// DirectoryEntry is the user authenticated by the LDAP directory
+type DirectoryEntry struct {
+	DN      string
+	Subject string // stable ID of the entry, links it to the local user
+	Email   string
+	Groups  []string // DNs of the groups the entry is a member of
+}
+
+// AppRole is the role of the user in the application
+type AppRole struct {
+	AppID int32
+	Role  string
+}
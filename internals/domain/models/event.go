@@ -0,0 +1,40 @@
+package models
+
+import "time"
+
+// Types of the domain events
+const (
+	EventUserRegistered      = "user.registered"
+	EventUserPasswordChanged = "user.password_changed"
+	EventUserDeleted         = "user.deleted"
+	EventUserAdminGranted    = "user.admin_granted"
+	EventUserAdminRevoked    = "user.admin_revoked"
+	EventUserRolesChanged    = "user.roles_changed"
+	EventAppCreated          = "app.created"
+	EventAppUpdated          = "app.updated"
+	EventAppDisabled         = "app.disabled"
+	EventAppEnabled          = "app.enabled"
+	EventAppDeleted          = "app.deleted"
+)
+
+// Event is the change of the user or application saved in the outbox in the transaction of the change.
+// Event of the application concerns only it, event with zero app ID concerns all applications
+type Event struct {
+	ID        string
+	Type      string
+	ActorID   int64 // user made the change, zero for the user itself or the system
+	UserID    int64
+	Email     string
+	AppID     int32
+	CreatedAt time.Time
+}
+
+// OutboxEvent is the event waiting in the outbox until all publishers accept it
+type OutboxEvent struct {
+	Seq           int64 // position in the outbox, events are published in its order
+	Event         Event
+	Attempts      int
+	NextAttemptAt time.Time
+	LastError     string    // error of the last failed attempt
+	PublishedAt   time.Time // zero until all publishers accept the event
+}
@@ -0,0 +1,27 @@
+package models
+
+import "time"
+
+// ExternalIdentity is the identity of the user asserted by the upstream OpenID Connect provider
+type ExternalIdentity struct {
+	Provider      string // name of the provider in the config
+	Subject       string // ID of the user at the provider, unique within the provider
+	Email         string
+	EmailVerified bool
+}
+
+// FederationState is the authorization request waiting for the callback of the upstream provider
+type FederationState struct {
+	Provider     string
+	Nonce        string // nonce of the ID token of the provider
+	CodeVerifier string // PKCE verifier of the code of the provider
+	Request      AuthorizeRequest
+	ClientState  string // state of the client, returned to it with the authorization code
+	ExpiresAt    time.Time
+}
+
+// Realm is the upstream provider the users of the email domain sign in with
+type Realm struct {
+	Provider string // empty if the users sign in by password
+	LoginURI string // URI of the federated login at the provider
+}
@@ -0,0 +1,23 @@
+package models
+
+import "time"
+
+const (
+	LogoutPending   = "pending"
+	LogoutDelivered = "delivered"
+	LogoutFailed    = "failed"
+)
+
+// LogoutDelivery is a notification of the application about logout of the user by OpenID Connect Back-Channel Logout
+type LogoutDelivery struct {
+	ID            int64
+	AppID         int32
+	UserID        int64
+	LogoutURI     string // back-channel logout URI of the application
+	Status        string
+	Attempts      int
+	NextAttemptAt time.Time
+	LastError     string // error of the last failed attempt
+	CreatedAt     time.Time
+	DeliveredAt   time.Time // zero until the delivery succeeds
+}
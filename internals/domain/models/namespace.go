@@ -0,0 +1,155 @@
+package models
+
+import (
+	"errors"
+	"fmt"
+)
+
+var ErrInvalidNamespaceConfig = errors.New("invalid namespace config")
+
+// NamespaceConfig describes object types of the application and rewrites of their relations, for example
+//
+//	{"namespaces": {"doc": {"relations": {
+//	    "owner": {},
+//	    "viewer": {"union": [{"this": true}, {"computed": "owner"},
+//	        {"tuple_to_userset": {"tupleset": "parent", "computed": "viewer"}}]}
+//	}}}}
+type NamespaceConfig struct {
+	Namespaces map[string]Namespace `json:"namespaces"`
+}
+
+type Namespace struct {
+	Relations map[string]*Rewrite `json:"relations"`
+}
+
+// Rewrite defines how the set of subjects of a relation is computed.
+// Exactly one field must be set, empty rewrite means This
+type Rewrite struct {
+	This           bool            `json:"this,omitempty"`             // subjects written directly to the relation
+	Computed       string          `json:"computed,omitempty"`         // subjects of other relation of the same object
+	TupleToUserset *TupleToUserset `json:"tuple_to_userset,omitempty"` // subjects of relation of the objects related by tupleset
+	Union          []*Rewrite      `json:"union,omitempty"`
+	Intersection   []*Rewrite      `json:"intersection,omitempty"`
+	Exclusion      *Exclusion      `json:"exclusion,omitempty"`
+}
+
+// TupleToUserset takes objects which are subjects of the tupleset relation
+// and computes subjects of their computed relation, it describes inheritance like doc#parent@folder:x
+type TupleToUserset struct {
+	Tupleset string `json:"tupleset"`
+	Computed string `json:"computed"`
+}
+
+type Exclusion struct {
+	Base     *Rewrite `json:"base"`
+	Subtract *Rewrite `json:"subtract"`
+}
+
+// Rewrite returns rewrite of the relation of the object type.
+// Relations of the types without configuration are written directly only
+func (c NamespaceConfig) Rewrite(objectType, relation string) (rewrite *Rewrite, ok bool) {
+	namespace, ok := c.Namespaces[objectType]
+	if !ok {
+		return &Rewrite{This: true}, len(c.Namespaces) == 0
+	}
+
+	rewrite, ok = namespace.Relations[relation]
+	if !ok {
+		return nil, false
+	}
+
+	if rewrite == nil || rewrite.IsEmpty() {
+		return &Rewrite{This: true}, true
+	}
+
+	return rewrite, true
+}
+
+// IsEmpty reports whether no field of the rewrite is set
+func (r *Rewrite) IsEmpty() bool {
+	return !r.This && r.Computed == "" && r.TupleToUserset == nil &&
+		len(r.Union) == 0 && len(r.Intersection) == 0 && r.Exclusion == nil
+}
+
+// Validate checks names of the types and relations and that rewrites reference existing relations
+func (c NamespaceConfig) Validate() error {
+	for typeName, namespace := range c.Namespaces {
+		if !validName(typeName) {
+			return fmt.Errorf("%w: invalid type name %q", ErrInvalidNamespaceConfig, typeName)
+		}
+
+		for relation, rewrite := range namespace.Relations {
+			if !validName(relation) {
+				return fmt.Errorf("%w: invalid relation name %q of type %s", ErrInvalidNamespaceConfig, relation, typeName)
+			}
+
+			if rewrite == nil {
+				continue
+			}
+
+			if err := rewrite.validate(namespace); err != nil {
+				return fmt.Errorf("%w: relation %s#%s: %w", ErrInvalidNamespaceConfig, typeName, relation, err)
+			}
+		}
+	}
+
+	return nil
+}
+
+// validate checks that exactly one field of the rewrite is set and relations of the namespace exist
+func (r *Rewrite) validate(namespace Namespace) error {
+	set := 0
+	for _, isSet := range []bool{
+		r.This, r.Computed != "", r.TupleToUserset != nil,
+		len(r.Union) != 0, len(r.Intersection) != 0, r.Exclusion != nil,
+	} {
+		if isSet {
+			set++
+		}
+	}
+
+	if set > 1 {
+		return errors.New("rewrite must have exactly one operation")
+	}
+
+	switch {
+	case r.Computed != "":
+		if _, ok := namespace.Relations[r.Computed]; !ok {
+			return fmt.Errorf("computed relation %q doesn't exist", r.Computed)
+		}
+	case r.TupleToUserset != nil:
+		if _, ok := namespace.Relations[r.TupleToUserset.Tupleset]; !ok {
+			return fmt.Errorf("tupleset relation %q doesn't exist", r.TupleToUserset.Tupleset)
+		}
+
+		if !validName(r.TupleToUserset.Computed) {
+			return fmt.Errorf("invalid computed relation %q of tupleset", r.TupleToUserset.Computed)
+		}
+	case r.Exclusion != nil:
+		if r.Exclusion.Base == nil || r.Exclusion.Subtract == nil {
+			return errors.New("exclusion must have base and subtract")
+		}
+
+		return validateAll(namespace, r.Exclusion.Base, r.Exclusion.Subtract)
+	case len(r.Union) != 0:
+		return validateAll(namespace, r.Union...)
+	case len(r.Intersection) != 0:
+		return validateAll(namespace, r.Intersection...)
+	}
+
+	return nil
+}
+
+func validateAll(namespace Namespace, rewrites ...*Rewrite) error {
+	for _, rewrite := range rewrites {
+		if rewrite == nil {
+			return errors.New("rewrite can't be null")
+		}
+
+		if err := rewrite.validate(namespace); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,130 @@
+package models
+
+import (
+	"slices"
+	"time"
+)
+
+const (
+	ResponseTypeCode  = "code"
+	PKCEMethodS256    = "S256"
+	GrantTypeAuthCode = "authorization_code"
+	GrantTypeClient   = "client_credentials"
+	GrantTypeDevice   = "urn:ietf:params:oauth:grant-type:device_code"
+	GrantTypeExchange = "urn:ietf:params:oauth:grant-type:token-exchange"
+	GrantTypeRefresh  = "refresh_token"
+	TokenTypeBearer   = "Bearer"
+
+	// ScopeSCIM is the client scope of the SCIM provisioning client, it is given to the applications by super-admin only
+	ScopeSCIM = "scim"
+
+	// TokenTypeAccessToken is a type of the tokens the token exchange accepts and issues
+	TokenTypeAccessToken = "urn:ietf:params:oauth:token-type:access_token"
+
+	DevicePending  = "pending"
+	DeviceApproved = "approved"
+	DeviceDenied   = "denied"
+
+	ConsentApprove = "approve"
+	ConsentDeny    = "deny"
+
+	ScopeOpenID  = "openid"
+	ScopeProfile = "profile"
+	ScopeEmail   = "email"
+)
+
+// AuthorizeRequest is a request of the OAuth client for authorization code of the user
+type AuthorizeRequest struct {
+	AppID               int32
+	RedirectURI         string // may be empty if the application has the only redirect URI
+	ResponseType        string
+	Scope               string
+	CodeChallenge       string
+	CodeChallengeMethod string
+	Nonce               string // put into ID token as is
+	Consent             string // decision of the user on the scopes of the third-party client, empty if not asked yet
+}
+
+// AuthCode is an issued authorization code, the code itself is kept only by the client
+type AuthCode struct {
+	AppID         int32
+	UserID        int64
+	RedirectURI   string
+	Scope         string
+	CodeChallenge string
+	Nonce         string
+	AuthTime      time.Time // time the user was authenticated
+	ExpiresAt     time.Time
+}
+
+// OAuthToken is a response of the token endpoint
+type OAuthToken struct {
+	AccessToken  string
+	TokenType    string
+	ExpiresIn    time.Duration
+	Scope        string
+	IDToken      string // ID token of OpenID Connect, only if openid scope is granted
+	RefreshToken string // only if the application allows refresh tokens
+
+	IssuedTokenType string // type of the token issued by the token exchange
+}
+
+// TokenExchangeRequest is a request of the token exchange of RFC 8693
+type TokenExchangeRequest struct {
+	SubjectToken     string
+	RequestedSubject int64  // user to impersonate, zero exchanges token of the subject itself
+	Audience         int32  // application of the new token, zero keeps application of the subject token
+	Scope            string // scopes the new token is limited to, empty keeps scopes of the user
+	ClientID         int32  // client which exchanges token of its user, impersonation doesn't need it
+	ClientSecret     string
+}
+
+// DeviceAuthorization is a response of the device authorization endpoint of RFC 8628
+type DeviceAuthorization struct {
+	DeviceCode              string
+	UserCode                string
+	VerificationURI         string
+	VerificationURIComplete string // verification URI with the user code
+	ExpiresIn               time.Duration
+	Interval                time.Duration
+}
+
+// DeviceCode is an issued device code, the code itself is kept only by the device
+type DeviceCode struct {
+	AppID        int32
+	UserCode     string
+	Scope        string
+	Status       string
+	UserID       int64         // user who decided, zero while code is pending
+	Interval     time.Duration // minimal interval between polls of the token endpoint
+	LastPolledAt time.Time     // zero if the device hasn't polled yet
+	ExpiresAt    time.Time
+}
+
+// RefreshToken is an issued refresh token, the token itself is kept only by the client
+type RefreshToken struct {
+	AppID     int32
+	UserID    int64
+	Scope     string
+	ExpiresAt time.Time
+}
+
+// Consent is a set of scopes the user granted to the third-party OAuth client
+type Consent struct {
+	UserID    int64
+	AppID     int32
+	AppName   string
+	Scopes    []string
+	GrantedAt time.Time // time of the last consent, scopes of the previous consents are kept
+}
+
+// Covers reports whether the consent grants all the scopes
+func (c Consent) Covers(scopes []string) bool {
+	for _, scope := range scopes {
+		if !slices.Contains(c.Scopes, scope) {
+			return false
+		}
+	}
+
+	return true
+}
@@ -0,0 +1,134 @@
+package models
+
+import (
+	"errors"
+	"fmt"
+	"time"
+)
+
+const (
+	PolicyEffectAllow = "allow"
+	PolicyEffectDeny  = "deny"
+)
+
+var ErrInvalidPolicy = errors.New("invalid policy")
+
+// Policy is an ordered list of the ABAC rules of the application.
+// The first rule which condition is true decides the effect, Default effect is used when no rule matches
+type Policy struct {
+	Rules   []PolicyRule `json:"rules"`
+	Default string       `json:"default,omitempty"` // deny if empty
+}
+
+// PolicyRule is a named rule with condition written in the expression language, for example
+//
+//	{"name": "owner", "effect": "allow", "condition": "resource.owner == user.id"}
+type PolicyRule struct {
+	Name      string `json:"name"`
+	Effect    string `json:"effect"`
+	Condition string `json:"condition"`
+}
+
+// PolicyInput contains attributes which conditions of the rules can reference
+// as user, resource, app and request variables
+type PolicyInput struct {
+	User     PolicyUser     `json:"user"`
+	Resource map[string]any `json:"resource"`
+	App      PolicyApp      `json:"app"`
+	Request  PolicyRequest  `json:"request"`
+}
+
+type PolicyUser struct {
+	ID         int64          `json:"id"`
+	Email      string         `json:"email"`
+	Roles      []string       `json:"roles"`
+	Attributes map[string]any `json:"attributes"`
+}
+
+type PolicyApp struct {
+	ID int32 `json:"id"`
+}
+
+type PolicyRequest struct {
+	Time       time.Time      `json:"time"`
+	IP         string         `json:"ip"`
+	Attributes map[string]any `json:"attributes"`
+}
+
+// RuleResult explains evaluation of one rule, Error is set when the condition can't be evaluated
+type RuleResult struct {
+	Name    string
+	Effect  string
+	Matched bool
+	Error   string
+}
+
+// PolicyDecision is a recorded result of the policy evaluation, Input is PolicyInput in JSON
+type PolicyDecision struct {
+	ID            int64
+	AppID         int32
+	PolicyVersion int64
+	Input         []byte
+	Effect        string
+	Rule          string // name of the matched rule, empty if default effect is used
+	CreatedAt     time.Time
+}
+
+// DefaultEffect returns effect of the policy when no rule matches
+func (p Policy) DefaultEffect() string {
+	if p.Default == "" {
+		return PolicyEffectDeny
+	}
+
+	return p.Default
+}
+
+// Validate checks names and effects of the rules, conditions are checked when they are compiled
+func (p Policy) Validate() error {
+	if !validEffect(p.DefaultEffect()) {
+		return fmt.Errorf("%w: invalid default effect %q", ErrInvalidPolicy, p.Default)
+	}
+
+	names := make(map[string]bool, len(p.Rules))
+	for i, rule := range p.Rules {
+		if rule.Name == "" || names[rule.Name] {
+			return fmt.Errorf("%w: rule %d must have unique name", ErrInvalidPolicy, i)
+		}
+		names[rule.Name] = true
+
+		if !validEffect(rule.Effect) {
+			return fmt.Errorf("%w: invalid effect %q of rule %s", ErrInvalidPolicy, rule.Effect, rule.Name)
+		}
+
+		if rule.Condition == "" {
+			return fmt.Errorf("%w: rule %s has no condition", ErrInvalidPolicy, rule.Name)
+		}
+	}
+
+	return nil
+}
+
+func validEffect(effect string) bool {
+	return effect == PolicyEffectAllow || effect == PolicyEffectDeny
+}
+
+// PolicyEvaluation is a result of the policy evaluation with explanation of the evaluated rules
+type PolicyEvaluation struct {
+	DecisionID    int64
+	PolicyVersion int64
+	Effect        string
+	Rule          string // name of the matched rule, empty if default effect is used
+	Rules         []RuleResult
+}
+
+// PolicyReplay compares recorded decision with result of other policy for the same input
+type PolicyReplay struct {
+	Decision PolicyDecision
+	Effect   string
+	Rule     string
+}
+
+// Changed reports whether the other policy gives another effect than the recorded one
+func (r PolicyReplay) Changed() bool {
+	return r.Effect != r.Decision.Effect
+}
@@ -0,0 +1,115 @@
+package models
+
+import (
+	"errors"
+	"strings"
+)
+
+var ErrInvalidRelationTuple = errors.New("invalid relation tuple")
+
+// Object is an object of the relation, for example doc:readme
+type Object struct {
+	Type string
+	ID   string
+}
+
+// Subject is a user (user:42) or a set of users which have relation with an object (group:eng#member)
+type Subject struct {
+	Type     string
+	ID       string
+	Relation string // not empty for userset
+}
+
+// RelationTuple is a relation between object and subject written as object#relation@subject
+type RelationTuple struct {
+	Object   Object
+	Relation string
+	Subject  Subject
+}
+
+func (o Object) String() string {
+	return o.Type + ":" + o.ID
+}
+
+func (s Subject) String() string {
+	if s.Relation == "" {
+		return s.Type + ":" + s.ID
+	}
+
+	return s.Type + ":" + s.ID + "#" + s.Relation
+}
+
+// Object returns object of the userset subject
+func (s Subject) Object() Object {
+	return Object{Type: s.Type, ID: s.ID}
+}
+
+func (t RelationTuple) String() string {
+	return t.Object.String() + "#" + t.Relation + "@" + t.Subject.String()
+}
+
+// ParseObject parses object written as type:id
+func ParseObject(s string) (Object, error) {
+	objectType, id, ok := strings.Cut(s, ":")
+	if !ok || !validName(objectType) || id == "" || strings.ContainsAny(id, "#@") {
+		return Object{}, ErrInvalidRelationTuple
+	}
+
+	return Object{Type: objectType, ID: id}, nil
+}
+
+// ParseSubject parses subject written as type:id or type:id#relation
+func ParseSubject(s string) (Subject, error) {
+	object, relation, isUserset := strings.Cut(s, "#")
+
+	parsed, err := ParseObject(object)
+	if err != nil {
+		return Subject{}, err
+	}
+
+	if isUserset && !validName(relation) {
+		return Subject{}, ErrInvalidRelationTuple
+	}
+
+	return Subject{Type: parsed.Type, ID: parsed.ID, Relation: relation}, nil
+}
+
+// ParseRelationTuple parses relation tuple written as type:id#relation@subject
+func ParseRelationTuple(s string) (RelationTuple, error) {
+	objectRelation, subject, ok := strings.Cut(s, "@")
+	if !ok {
+		return RelationTuple{}, ErrInvalidRelationTuple
+	}
+
+	object, relation, ok := strings.Cut(objectRelation, "#")
+	if !ok || !validName(relation) {
+		return RelationTuple{}, ErrInvalidRelationTuple
+	}
+
+	parsedObject, err := ParseObject(object)
+	if err != nil {
+		return RelationTuple{}, err
+	}
+
+	parsedSubject, err := ParseSubject(subject)
+	if err != nil {
+		return RelationTuple{}, err
+	}
+
+	return RelationTuple{Object: parsedObject, Relation: relation, Subject: parsedSubject}, nil
+}
+
+// validName reports whether s can be a name of a type or a relation
+func validName(s string) bool {
+	if s == "" {
+		return false
+	}
+
+	for _, r := range s {
+		if !(r >= 'a' && r <= 'z' || r >= '0' && r <= '9' || r == '_' || r == '-') {
+			return false
+		}
+	}
+
+	return true
+}
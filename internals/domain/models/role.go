@@ -0,0 +1,6 @@
+package models
+
+type Role struct {
+	Name   string
+	Scopes []string
+}
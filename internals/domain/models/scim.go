@@ -0,0 +1,50 @@
+package models
+
+import "time"
+
+// Operations of SCIM PATCH request
+const (
+	ScimPatchAdd     = "add"
+	ScimPatchReplace = "replace"
+	ScimPatchRemove  = "remove"
+)
+
+// ScimUser is the user as SCIM provisioning client sees it, user name is the email of the user
+type ScimUser struct {
+	ID           int64
+	UserName     string
+	ExternalID   string // ID of the user in the provisioning client
+	DisplayName  string
+	GivenName    string
+	FamilyName   string
+	Active       bool   // inactive users can't sign in
+	Password     string // write-only, empty keeps the password
+	Version      int64  // grows on every change, sent as ETag
+	Created      time.Time
+	LastModified time.Time
+}
+
+// ScimGroup is the group of the users in the application of SCIM client.
+// Members are relation tuples group:<id>#member@user:<user id> of the application
+type ScimGroup struct {
+	ID           int64
+	AppID        int32
+	DisplayName  string
+	ExternalID   string
+	Members      []ScimMember
+	Version      int64
+	Created      time.Time
+	LastModified time.Time
+}
+
+type ScimMember struct {
+	UserID   int64
+	UserName string // read-only, filled when the group is read
+}
+
+// ScimPatchOp is the operation of SCIM PATCH request, value is decoded from JSON
+type ScimPatchOp struct {
+	Op    string
+	Path  string // empty path patches attributes of the value object
+	Value any
+}
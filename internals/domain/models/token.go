@@ -0,0 +1,24 @@
+package models
+
+import "time"
+
+type TokenInfo struct {
+	Active    bool
+	UserID    int64 // zero for tokens of the application itself
+	Email     string
+	AppID     int32
+	ExpiresAt time.Time
+	Roles     []string
+	Scopes    []string
+	Granted   []string // scopes granted by the user to the OAuth client
+	ActorID   int64    // admin who impersonates the user, zero if the user acts
+
+	ScopeLimit []string // scopes the token is limited to by the token exchange, nil if it isn't limited
+}
+
+// IsConsole reports whether the token is issued to the user for the admin console application.
+// Only these tokens act on behalf of the user in other applications, impersonation and downscoped
+// tokens of the token exchange never do
+func (i TokenInfo) IsConsole(consoleAppID int32) bool {
+	return i.UserID != 0 && consoleAppID != 0 && i.AppID == consoleAppID && i.ActorID == 0 && len(i.ScopeLimit) == 0
+}
@@ -4,4 +4,11 @@ type User struct {
 	ID           int64
 	Email        string
 	HashPassword []byte
+	Disabled     bool // deactivated by SCIM client, the user can't sign in
+}
+
+// Authentication is the user authenticated by the identity chain
+type Authentication struct {
+	User             User
+	IdentityProvider string // name of the step of the chain which authenticated the user
 }
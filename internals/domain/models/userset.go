@@ -0,0 +1,18 @@
+package models
+
+const (
+	UsersetLeaf         = "leaf"
+	UsersetUnion        = "union"
+	UsersetIntersection = "intersection"
+	UsersetExclusion    = "exclusion"
+)
+
+// UsersetTree is an expanded set of subjects which have the relation with the object.
+// Leaf contains subjects written directly, usersets among them are not expanded further
+type UsersetTree struct {
+	Operation string
+	Object    Object
+	Relation  string
+	Subjects  []Subject
+	Children  []UsersetTree
+}
@@ -0,0 +1,47 @@
+package models
+
+import "time"
+
+// WebhookEventTypes are the user lifecycle events the webhooks may subscribe to, other events are not sent to them
+var WebhookEventTypes = []string{
+	EventUserRegistered,
+	EventUserPasswordChanged,
+	EventUserDeleted,
+	EventUserAdminGranted,
+}
+
+const (
+	WebhookPending   = "pending"
+	WebhookDelivered = "delivered"
+	WebhookDead      = "dead" // ran out of attempts, waits for redelivery in the dead-letter list
+)
+
+// WebhookSubscription is the endpoint of the application receiving the events of the types,
+// empty types subscribe to all events. Payloads are signed by HMAC-SHA256 with the secret
+type WebhookSubscription struct {
+	ID        int64
+	AppID     int32
+	URL       string
+	Secret    string
+	Events    []string
+	AllUsers  bool // gets events which are not bound to an application, only super-admin subscribes to them
+	CreatedAt time.Time
+}
+
+// WebhookDelivery is the event sent to the subscription, payload is JSON body of the request
+type WebhookDelivery struct {
+	ID             int64
+	AppID          int32
+	SubscriptionID int64
+	URL            string // URL of the subscription
+	Secret         string // secret of the subscription
+	EventID        string
+	EventType      string
+	Payload        []byte
+	Status         string
+	Attempts       int
+	NextAttemptAt  time.Time
+	LastError      string // error of the last failed attempt
+	CreatedAt      time.Time
+	DeliveredAt    time.Time // zero until the delivery succeeds
+}
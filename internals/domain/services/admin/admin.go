@@ -0,0 +1,269 @@
+package admin
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"time"
+
+	"github.com/nhassl3/sso-app/internals/domain/models"
+	"github.com/nhassl3/sso-app/internals/lib/logger/sl"
+	"github.com/nhassl3/sso-app/internals/storage"
+)
+
+const (
+	opIsAppAdmin  = "admin.IsAppAdmin"
+	opGrantAdmin  = "admin.GrantAdmin"
+	opRevokeAdmin = "admin.RevokeAdmin"
+	opAdmins      = "admin.Admins"
+	opAuditEvents = "admin.AuditEvents"
+
+	defaultAuditLimit = 100
+)
+
+var (
+	ErrInvalidAppID     = errors.New("invalid application ID")
+	ErrInvalidUserID    = errors.New("invalid user ID")
+	ErrPermissionDenied = errors.New("permission denied")
+	ErrLastSuperAdmin   = errors.New("last super-admin can't be revoked")
+	ErrInvalidTTL       = errors.New("invalid duration of admin rights")
+)
+
+type Admin struct {
+	log               *slog.Logger
+	adminSaver        AdminSaver
+	adminProvider     AdminProvider
+	auditProvider     AuditProvider
+	elevationSaver    ElevationSaver
+	elevationProvider ElevationProvider
+	maxElevationTTL   time.Duration
+}
+
+// NewAdmin returns a new instance of the Admin service
+func NewAdmin(
+	log *slog.Logger,
+	adminSaver AdminSaver,
+	adminProvider AdminProvider,
+	auditProvider AuditProvider,
+	elevationSaver ElevationSaver,
+	elevationProvider ElevationProvider,
+	maxElevationTTL time.Duration,
+) *Admin {
+	return &Admin{
+		log:               log,
+		adminSaver:        adminSaver,
+		adminProvider:     adminProvider,
+		auditProvider:     auditProvider,
+		elevationSaver:    elevationSaver,
+		elevationProvider: elevationProvider,
+		maxElevationTTL:   maxElevationTTL,
+	}
+}
+
+type AdminSaver interface {
+	SaveAdmin(ctx context.Context, actorID, userID int64, appID int32, ttl time.Duration) (saved bool, err error)
+	DeleteAdmin(ctx context.Context, actorID, userID int64, appID int32) (deleted bool, err error)
+	DeleteExpiredAdmins(ctx context.Context) (expired []models.Admin, err error)
+}
+
+type AdminProvider interface {
+	IsAppAdmin(ctx context.Context, userID int64, appID int32) (isAdmin bool, isSuperAdmin bool, err error)
+	IsAdmin(ctx context.Context, userID int64) (isAdmin bool, err error)
+	Admins(ctx context.Context, appID int32) (admins []models.Admin, err error)
+}
+
+type AuditProvider interface {
+	AuditEvents(ctx context.Context, appID int32, limit int) (events []models.AuditEvent, err error)
+}
+
+// IsAppAdmin checks if the user has administrator rights in the application.
+// Super-admin has administrator rights in every application.
+// If application doesn't exist, returns error
+func (a *Admin) IsAppAdmin(ctx context.Context, userID int64, appID int32) (isAdmin bool, isSuperAdmin bool, err error) {
+	log := a.log.With(slog.String("op", opIsAppAdmin))
+
+	isAdmin, isSuperAdmin, err = a.adminProvider.IsAppAdmin(ctx, userID, appID)
+	if err != nil {
+		if errors.Is(err, storage.ErrAppNotFound) {
+			log.Warn("failed to found app in the system", sl.Err(err))
+
+			return false, false, sl.ErrUpLevel(opIsAppAdmin, ErrInvalidAppID)
+		}
+
+		log.Error("failed to check admin rights", sl.Err(err))
+
+		return false, false, sl.ErrUpLevel(opIsAppAdmin, err)
+	}
+
+	return
+}
+
+// GrantAdmin gives administrator rights in the application to the user, zero app ID gives super-admin rights.
+// Rights expire after ttl, zero ttl gives permanent rights.
+// Actor must be admin of the application, super-admin rights can be given by super-admin only.
+// Returns false if user already has these rights
+func (a *Admin) GrantAdmin(ctx context.Context, caller models.TokenInfo, userID int64, appID int32, ttl time.Duration) (granted bool, err error) {
+	log := a.log.With(slog.String("op", opGrantAdmin), slog.Int64("actor_id", caller.UserID))
+
+	if err := a.authorize(ctx, caller, appID); err != nil {
+		log.Warn("failed to authorize actor", sl.Err(err))
+
+		return false, sl.ErrUpLevel(opGrantAdmin, err)
+	}
+
+	if ttl < 0 {
+		log.Warn("invalid ttl", slog.Duration("ttl", ttl))
+
+		return false, sl.ErrUpLevel(opGrantAdmin, ErrInvalidTTL)
+	}
+
+	granted, err = a.adminSaver.SaveAdmin(ctx, caller.UserID, userID, appID, ttl)
+	if err != nil {
+		return false, sl.ErrUpLevel(opGrantAdmin, a.storageErr(log, err))
+	}
+
+	if granted {
+		log.Info(
+			"admin rights granted",
+			slog.Int64("user_id", userID), slog.Int("app_id", int(appID)), slog.Duration("ttl", ttl),
+		)
+	}
+
+	return
+}
+
+// RevokeAdmin takes away administrator rights in the application from the user, zero app ID takes super-admin rights.
+// Actor must be admin of the application, super-admin rights can be taken by super-admin only.
+// Returns false if user doesn't have these rights, last super-admin can't be revoked
+func (a *Admin) RevokeAdmin(ctx context.Context, caller models.TokenInfo, userID int64, appID int32) (revoked bool, err error) {
+	log := a.log.With(slog.String("op", opRevokeAdmin), slog.Int64("actor_id", caller.UserID))
+
+	if err := a.authorize(ctx, caller, appID); err != nil {
+		log.Warn("failed to authorize actor", sl.Err(err))
+
+		return false, sl.ErrUpLevel(opRevokeAdmin, err)
+	}
+
+	revoked, err = a.adminSaver.DeleteAdmin(ctx, caller.UserID, userID, appID)
+	if err != nil {
+		return false, sl.ErrUpLevel(opRevokeAdmin, a.storageErr(log, err))
+	}
+
+	if revoked {
+		log.Info("admin rights revoked", slog.Int64("user_id", userID), slog.Int("app_id", int(appID)))
+	}
+
+	return
+}
+
+// Admins returns admins which have rights in the application, zero app ID returns admins of all applications.
+// Actor must be admin of the application or super-admin for all applications
+func (a *Admin) Admins(ctx context.Context, caller models.TokenInfo, appID int32) (admins []models.Admin, err error) {
+	log := a.log.With(slog.String("op", opAdmins), slog.Int64("actor_id", caller.UserID))
+
+	if err := a.authorize(ctx, caller, appID); err != nil {
+		log.Warn("failed to authorize actor", sl.Err(err))
+
+		return nil, sl.ErrUpLevel(opAdmins, err)
+	}
+
+	admins, err = a.adminProvider.Admins(ctx, appID)
+	if err != nil {
+		log.Error("failed to get admins", sl.Err(err))
+
+		return nil, sl.ErrUpLevel(opAdmins, err)
+	}
+
+	return
+}
+
+// AuditEvents returns the latest audit events of the application, zero app ID returns events of all applications.
+// Actor must be admin of the application or super-admin for all applications
+func (a *Admin) AuditEvents(ctx context.Context, caller models.TokenInfo, appID int32, limit int) (events []models.AuditEvent, err error) {
+	log := a.log.With(slog.String("op", opAuditEvents), slog.Int64("actor_id", caller.UserID))
+
+	if err := a.authorize(ctx, caller, appID); err != nil {
+		log.Warn("failed to authorize actor", sl.Err(err))
+
+		return nil, sl.ErrUpLevel(opAuditEvents, err)
+	}
+
+	if limit <= 0 {
+		limit = defaultAuditLimit
+	}
+
+	events, err = a.auditProvider.AuditEvents(ctx, appID, limit)
+	if err != nil {
+		log.Error("failed to get audit events", sl.Err(err))
+
+		return nil, sl.ErrUpLevel(opAuditEvents, err)
+	}
+
+	return
+}
+
+// authorize checks that actor is admin of the application, zero app ID requires super-admin rights.
+// Impersonated users never act as admin
+func (a *Admin) authorize(ctx context.Context, caller models.TokenInfo, appID int32) error {
+	if caller.ActorID != 0 {
+		return ErrPermissionDenied
+	}
+
+	if appID == 0 {
+		isSuperAdmin, err := a.adminProvider.IsAdmin(ctx, caller.UserID)
+		if err != nil {
+			return err
+		}
+
+		if !isSuperAdmin {
+			return ErrPermissionDenied
+		}
+
+		return nil
+	}
+
+	isAdmin, _, err := a.adminProvider.IsAppAdmin(ctx, caller.UserID, appID)
+	if err != nil {
+		if errors.Is(err, storage.ErrAppNotFound) {
+			return ErrInvalidAppID
+		}
+
+		return err
+	}
+
+	if !isAdmin {
+		return ErrPermissionDenied
+	}
+
+	return nil
+}
+
+// storageErr converts errors of the admins storage to errors of the service
+func (a *Admin) storageErr(log *slog.Logger, err error) error {
+	switch {
+	case errors.Is(err, storage.ErrUserNotFound):
+		log.Warn("failed to found user in the system", sl.Err(err))
+
+		return ErrInvalidUserID
+	case errors.Is(err, storage.ErrAppNotFound):
+		log.Warn("failed to found app in the system", sl.Err(err))
+
+		return ErrInvalidAppID
+	case errors.Is(err, storage.ErrLastSuperAdmin):
+		log.Warn("attempt to revoke last super-admin", sl.Err(err))
+
+		return ErrLastSuperAdmin
+	case errors.Is(err, storage.ErrElevationNotFound):
+		log.Warn("failed to found elevation request", sl.Err(err))
+
+		return ErrElevationNotFound
+	case errors.Is(err, storage.ErrElevationNotPending):
+		log.Warn("elevation request is already decided", sl.Err(err))
+
+		return ErrElevationNotPending
+	}
+
+	log.Error("failed to change admin rights", sl.Err(err))
+
+	return err
+}
@@ -0,0 +1,172 @@
+package admin
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"time"
+
+	"github.com/nhassl3/sso-app/internals/domain/models"
+	"github.com/nhassl3/sso-app/internals/lib/logger/sl"
+)
+
+const (
+	opRequestElevation  = "admin.RequestElevation"
+	opDecideElevation   = "admin.DecideElevation"
+	opElevationRequests = "admin.ElevationRequests"
+	opSweepExpired      = "admin.SweepExpired"
+
+	defaultElevationTTL = time.Hour
+)
+
+var (
+	ErrElevationNotFound   = errors.New("elevation request not found")
+	ErrElevationNotPending = errors.New("elevation request is already decided")
+	ErrSelfApproval        = errors.New("elevation request can't be decided by its author")
+)
+
+type ElevationSaver interface {
+	SaveElevationRequest(ctx context.Context, request models.ElevationRequest) (requestID int64, err error)
+	DecideElevation(ctx context.Context, actorID, requestID int64, approve bool) (request models.ElevationRequest, err error)
+}
+
+type ElevationProvider interface {
+	ElevationRequest(ctx context.Context, requestID int64) (request models.ElevationRequest, err error)
+	ElevationRequests(ctx context.Context, appID int32, pendingOnly bool) (requests []models.ElevationRequest, err error)
+}
+
+// RequestElevation saves request of the actor for temporary admin rights in the application,
+// zero app ID requests super-admin rights. Zero ttl requests rights for an hour.
+// Rights are given only after approval of another admin
+func (a *Admin) RequestElevation(
+	ctx context.Context,
+	caller models.TokenInfo,
+	appID int32,
+	ttl time.Duration,
+	reason string,
+) (requestID int64, err error) {
+	log := a.log.With(slog.String("op", opRequestElevation), slog.Int64("actor_id", caller.UserID))
+
+	if caller.ActorID != 0 {
+		log.Warn("impersonated user can't request elevation", slog.Int64("impersonator_id", caller.ActorID))
+
+		return 0, sl.ErrUpLevel(opRequestElevation, ErrPermissionDenied)
+	}
+
+	if ttl == 0 {
+		ttl = min(defaultElevationTTL, a.maxElevationTTL)
+	}
+
+	if ttl < time.Second || ttl > a.maxElevationTTL {
+		log.Warn("invalid ttl", slog.Duration("ttl", ttl))
+
+		return 0, sl.ErrUpLevel(opRequestElevation, ErrInvalidTTL)
+	}
+
+	requestID, err = a.elevationSaver.SaveElevationRequest(ctx, models.ElevationRequest{
+		UserID: caller.UserID,
+		AppID:  appID,
+		TTL:    ttl,
+		Reason: reason,
+	})
+	if err != nil {
+		return 0, sl.ErrUpLevel(opRequestElevation, a.storageErr(log, err))
+	}
+
+	log.Info(
+		"elevation requested",
+		slog.Int64("request_id", requestID), slog.Int("app_id", int(appID)), slog.Duration("ttl", ttl),
+	)
+
+	return
+}
+
+// ApproveElevation approves pending request and gives requested admin rights to its author.
+// Actor must be admin of the application of the request and can't approve own requests
+func (a *Admin) ApproveElevation(ctx context.Context, caller models.TokenInfo, requestID int64) error {
+	return a.decideElevation(ctx, caller, requestID, true)
+}
+
+// DenyElevation denies pending request for admin rights.
+// Actor must be admin of the application of the request and can't deny own requests
+func (a *Admin) DenyElevation(ctx context.Context, caller models.TokenInfo, requestID int64) error {
+	return a.decideElevation(ctx, caller, requestID, false)
+}
+
+// ElevationRequests returns requests for admin rights in the application, zero app ID returns requests of all applications.
+// Actor must be admin of the application or super-admin for all applications
+func (a *Admin) ElevationRequests(
+	ctx context.Context,
+	caller models.TokenInfo,
+	appID int32,
+	pendingOnly bool,
+) (requests []models.ElevationRequest, err error) {
+	log := a.log.With(slog.String("op", opElevationRequests), slog.Int64("actor_id", caller.UserID))
+
+	if err := a.authorize(ctx, caller, appID); err != nil {
+		log.Warn("failed to authorize actor", sl.Err(err))
+
+		return nil, sl.ErrUpLevel(opElevationRequests, err)
+	}
+
+	requests, err = a.elevationProvider.ElevationRequests(ctx, appID, pendingOnly)
+	if err != nil {
+		log.Error("failed to get elevation requests", sl.Err(err))
+
+		return nil, sl.ErrUpLevel(opElevationRequests, err)
+	}
+
+	return
+}
+
+// SweepExpired takes away expired admin rights and returns count of them
+func (a *Admin) SweepExpired(ctx context.Context) (expired int, err error) {
+	log := a.log.With(slog.String("op", opSweepExpired))
+
+	admins, err := a.adminSaver.DeleteExpiredAdmins(ctx)
+	if err != nil {
+		log.Error("failed to delete expired admin rights", sl.Err(err))
+
+		return 0, sl.ErrUpLevel(opSweepExpired, err)
+	}
+
+	for _, admin := range admins {
+		log.Info("admin rights expired", slog.Int64("user_id", admin.UserID), slog.Int("app_id", int(admin.AppID)))
+	}
+
+	return len(admins), nil
+}
+
+func (a *Admin) decideElevation(ctx context.Context, caller models.TokenInfo, requestID int64, approve bool) error {
+	log := a.log.With(
+		slog.String("op", opDecideElevation),
+		slog.Int64("actor_id", caller.UserID),
+		slog.Int64("request_id", requestID),
+		slog.Bool("approve", approve),
+	)
+
+	request, err := a.elevationProvider.ElevationRequest(ctx, requestID)
+	if err != nil {
+		return sl.ErrUpLevel(opDecideElevation, a.storageErr(log, err))
+	}
+
+	if err := a.authorize(ctx, caller, request.AppID); err != nil {
+		log.Warn("failed to authorize actor", sl.Err(err))
+
+		return sl.ErrUpLevel(opDecideElevation, err)
+	}
+
+	if request.UserID == caller.UserID {
+		log.Warn("attempt to decide own elevation request")
+
+		return sl.ErrUpLevel(opDecideElevation, ErrSelfApproval)
+	}
+
+	if _, err := a.elevationSaver.DecideElevation(ctx, caller.UserID, requestID, approve); err != nil {
+		return sl.ErrUpLevel(opDecideElevation, a.storageErr(log, err))
+	}
+
+	log.Info("elevation request decided", slog.Int64("user_id", request.UserID), slog.Int("app_id", int(request.AppID)))
+
+	return nil
+}
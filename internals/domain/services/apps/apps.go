@@ -0,0 +1,522 @@
+package apps
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/url"
+	"regexp"
+	"slices"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/nhassl3/sso-app/internals/domain/models"
+	"github.com/nhassl3/sso-app/internals/lib/logger/sl"
+	"github.com/nhassl3/sso-app/internals/lib/random"
+	"github.com/nhassl3/sso-app/internals/storage"
+	"golang.org/x/crypto/bcrypt"
+)
+
+const (
+	opCreateApp  = "apps.CreateApp"
+	opUpdateApp  = "apps.UpdateApp"
+	opDisableApp = "apps.SetAppDisabled"
+	opDeleteApp  = "apps.DeleteApp"
+	opApps       = "apps.Apps"
+
+	opRotateClientSecret = "apps.RotateClientSecret"
+
+	secretSize           = 32
+	defaultMaxClaimsSize = 2048
+	defaultPageSize      = 50
+	maxThemeTitleLen     = 100
+	maxThemeTextLen      = 500
+	maxPageSize          = 500
+)
+
+// grantTypes are grant types the OAuth clients may be restricted to
+var grantTypes = []string{
+	models.GrantTypeAuthCode,
+	models.GrantTypeClient,
+	models.GrantTypeDevice,
+	models.GrantTypeExchange,
+	models.GrantTypeRefresh,
+}
+
+// reservedScopes are client scopes which give the application access to the users of all applications
+var reservedScopes = []string{models.ScopeSCIM}
+
+var themeColor = regexp.MustCompile(`^#[0-9a-fA-F]{6}$`)
+
+var (
+	ErrInvalidAppID     = errors.New("invalid application ID")
+	ErrAppExists        = errors.New("application already exists")
+	ErrPermissionDenied = errors.New("permission denied")
+	ErrInvalidSettings  = errors.New("invalid application settings")
+)
+
+type Apps struct {
+	log           *slog.Logger
+	appSaver      AppSaver
+	appProvider   AppProvider
+	adminProvider AdminProvider
+	cacheDropper  CacheDropper
+}
+
+// NewApps returns a new instance of the Apps service
+func NewApps(
+	log *slog.Logger,
+	appSaver AppSaver,
+	appProvider AppProvider,
+	adminProvider AdminProvider,
+	cacheDropper CacheDropper,
+) *Apps {
+	return &Apps{
+		log:           log,
+		appSaver:      appSaver,
+		appProvider:   appProvider,
+		adminProvider: adminProvider,
+		cacheDropper:  cacheDropper,
+	}
+}
+
+type AppSaver interface {
+	SaveApp(ctx context.Context, actorID int64, app models.App) (appID int32, err error)
+	UpdateApp(ctx context.Context, actorID int64, app models.App) error
+	SetAppDisabled(ctx context.Context, actorID int64, appID int32, disabled bool) error
+	DeleteApp(ctx context.Context, actorID int64, appID int32) error
+	SetClientSecret(ctx context.Context, actorID int64, appID int32, secretHash []byte) error
+}
+
+type AppProvider interface {
+	App(ctx context.Context, appID int32) (app models.App, err error)
+	Apps(ctx context.Context, afterID int32, limit int) (apps []models.App, err error)
+}
+
+type AdminProvider interface {
+	IsAdmin(ctx context.Context, userID int64) (isAdmin bool, err error)
+	IsAppAdmin(ctx context.Context, userID int64, appID int32) (isAdmin bool, isSuperAdmin bool, err error)
+}
+
+// CacheDropper forgets permission decisions of the deleted application
+type CacheDropper interface {
+	DropCache(appID int32)
+}
+
+// CreateApp registers new application in the system with generated secret.
+// Secret is returned only here and can't be got later. Actor must be super-admin
+func (a *Apps) CreateApp(ctx context.Context, caller models.TokenInfo, name string, settings models.AppSettings) (app models.App, err error) {
+	log := a.log.With(slog.String("op", opCreateApp), slog.Int64("actor_id", caller.UserID))
+
+	if err := a.authorize(ctx, caller, 0); err != nil {
+		log.Warn("failed to authorize actor", sl.Err(err))
+
+		return models.App{}, sl.ErrUpLevel(opCreateApp, err)
+	}
+
+	if err := validateSettings(settings); err != nil {
+		log.Warn("invalid settings", sl.Err(err))
+
+		return models.App{}, sl.ErrUpLevel(opCreateApp, err)
+	}
+
+	if err := a.authorizeSettings(ctx, caller, models.AppSettings{}, settings); err != nil {
+		log.Warn("failed to authorize actor for the settings", sl.Err(err))
+
+		return models.App{}, sl.ErrUpLevel(opCreateApp, err)
+	}
+
+	secret, err := random.String(secretSize)
+	if err != nil {
+		log.Error("failed to generate secret", sl.Err(err))
+
+		return models.App{}, sl.ErrUpLevel(opCreateApp, err)
+	}
+
+	if settings.MaxClaimsSize <= 0 {
+		settings.MaxClaimsSize = defaultMaxClaimsSize
+	}
+
+	app = models.App{Name: name, Secret: secret, AppSettings: settings}
+
+	appID, err := a.appSaver.SaveApp(ctx, caller.UserID, app)
+	if err != nil {
+		return models.App{}, sl.ErrUpLevel(opCreateApp, a.storageErr(log, err))
+	}
+	app.ID = int(appID)
+
+	log.Info("app created", slog.Int("app_id", app.ID))
+
+	return
+}
+
+// UpdateApp updates name and settings of the application, empty name and nil settings are kept as is.
+// Only the settings fields are updated, empty fields replace all settings.
+// Actor must be admin of the application, the OAuth client kind and reserved scopes are changed by super-admin only
+func (a *Apps) UpdateApp(
+	ctx context.Context,
+	caller models.TokenInfo,
+	appID int32,
+	name string,
+	settings *models.AppSettings,
+	fields []string,
+) (app models.App, err error) {
+	log := a.log.With(slog.String("op", opUpdateApp), slog.Int64("actor_id", caller.UserID))
+
+	if err := a.authorize(ctx, caller, appID); err != nil {
+		log.Warn("failed to authorize actor", sl.Err(err))
+
+		return models.App{}, sl.ErrUpLevel(opUpdateApp, err)
+	}
+
+	app, err = a.appProvider.App(ctx, appID)
+	if err != nil {
+		return models.App{}, sl.ErrUpLevel(opUpdateApp, a.storageErr(log, err))
+	}
+
+	if name != "" {
+		app.Name = name
+	}
+
+	if settings != nil {
+		updated := *settings
+		if len(fields) > 0 {
+			updated = app.AppSettings
+			if err := mergeSettings(&updated, *settings, fields); err != nil {
+				log.Warn("invalid settings fields", sl.Err(err))
+
+				return models.App{}, sl.ErrUpLevel(opUpdateApp, err)
+			}
+		}
+
+		if err := validateSettings(updated); err != nil {
+			log.Warn("invalid settings", sl.Err(err))
+
+			return models.App{}, sl.ErrUpLevel(opUpdateApp, err)
+		}
+
+		if err := a.authorizeSettings(ctx, caller, app.AppSettings, updated); err != nil {
+			log.Warn("failed to authorize actor for the settings", sl.Err(err))
+
+			return models.App{}, sl.ErrUpLevel(opUpdateApp, err)
+		}
+
+		app.AppSettings = updated
+		if app.MaxClaimsSize <= 0 {
+			app.MaxClaimsSize = defaultMaxClaimsSize
+		}
+	}
+
+	if err := a.appSaver.UpdateApp(ctx, caller.UserID, app); err != nil {
+		return models.App{}, sl.ErrUpLevel(opUpdateApp, a.storageErr(log, err))
+	}
+
+	return
+}
+
+// SetAppDisabled disables or enables the application, disabled application can't issue and accept tokens.
+// Actor must be admin of the application
+func (a *Apps) SetAppDisabled(ctx context.Context, caller models.TokenInfo, appID int32, disabled bool) error {
+	log := a.log.With(slog.String("op", opDisableApp), slog.Int64("actor_id", caller.UserID))
+
+	if err := a.authorize(ctx, caller, appID); err != nil {
+		log.Warn("failed to authorize actor", sl.Err(err))
+
+		return sl.ErrUpLevel(opDisableApp, err)
+	}
+
+	if err := a.appSaver.SetAppDisabled(ctx, caller.UserID, appID, disabled); err != nil {
+		return sl.ErrUpLevel(opDisableApp, a.storageErr(log, err))
+	}
+
+	log.Info("app disabled state changed", slog.Int("app_id", int(appID)), slog.Bool("disabled", disabled))
+
+	return nil
+}
+
+// DeleteApp deletes the application with roles, admins and other data which belong to it.
+// Actor must be super-admin
+func (a *Apps) DeleteApp(ctx context.Context, caller models.TokenInfo, appID int32) error {
+	log := a.log.With(slog.String("op", opDeleteApp), slog.Int64("actor_id", caller.UserID))
+
+	if err := a.authorize(ctx, caller, 0); err != nil {
+		log.Warn("failed to authorize actor", sl.Err(err))
+
+		return sl.ErrUpLevel(opDeleteApp, err)
+	}
+
+	if err := a.appSaver.DeleteApp(ctx, caller.UserID, appID); err != nil {
+		return sl.ErrUpLevel(opDeleteApp, a.storageErr(log, err))
+	}
+
+	a.cacheDropper.DropCache(appID)
+
+	log.Info("app deleted", slog.Int("app_id", int(appID)))
+
+	return nil
+}
+
+// RotateClientSecret generates new OAuth client secret of the application replacing the previous one.
+// Secret is returned only here, the system keeps only its hash. Actor must be admin of the application
+func (a *Apps) RotateClientSecret(ctx context.Context, caller models.TokenInfo, appID int32) (secret string, err error) {
+	log := a.log.With(slog.String("op", opRotateClientSecret), slog.Int64("actor_id", caller.UserID))
+
+	if err := a.authorize(ctx, caller, appID); err != nil {
+		log.Warn("failed to authorize actor", sl.Err(err))
+
+		return "", sl.ErrUpLevel(opRotateClientSecret, err)
+	}
+
+	secret, err = random.String(secretSize)
+	if err != nil {
+		log.Error("failed to generate client secret", sl.Err(err))
+
+		return "", sl.ErrUpLevel(opRotateClientSecret, err)
+	}
+
+	secretHash, err := bcrypt.GenerateFromPassword([]byte(secret), bcrypt.DefaultCost)
+	if err != nil {
+		log.Error("failed to generate client secret hash", sl.Err(err))
+
+		return "", sl.ErrUpLevel(opRotateClientSecret, err)
+	}
+
+	if err := a.appSaver.SetClientSecret(ctx, caller.UserID, appID, secretHash); err != nil {
+		return "", sl.ErrUpLevel(opRotateClientSecret, a.storageErr(log, err))
+	}
+
+	log.Info("client secret rotated", slog.Int("app_id", int(appID)))
+
+	return
+}
+
+// Apps returns page of applications which go after the given application ID and ID to request the next page with.
+// Zero next ID means that there are no more pages. Actor must be super-admin
+func (a *Apps) Apps(ctx context.Context, caller models.TokenInfo, afterID int32, pageSize int) (apps []models.App, nextID int32, err error) {
+	log := a.log.With(slog.String("op", opApps), slog.Int64("actor_id", caller.UserID))
+
+	if err := a.authorize(ctx, caller, 0); err != nil {
+		log.Warn("failed to authorize actor", sl.Err(err))
+
+		return nil, 0, sl.ErrUpLevel(opApps, err)
+	}
+
+	if pageSize <= 0 {
+		pageSize = defaultPageSize
+	}
+	pageSize = min(pageSize, maxPageSize)
+
+	// One more application shows whether the next page exists
+	apps, err = a.appProvider.Apps(ctx, afterID, pageSize+1)
+	if err != nil {
+		log.Error("failed to get apps", sl.Err(err))
+
+		return nil, 0, sl.ErrUpLevel(opApps, err)
+	}
+
+	if len(apps) > pageSize {
+		apps = apps[:pageSize]
+		nextID = int32(apps[pageSize-1].ID)
+	}
+
+	return
+}
+
+// validateSettings checks that redirect URIs are absolute URIs without fragment as RFC 6749 requires
+// and client scopes are scope tokens without spaces
+func validateSettings(settings models.AppSettings) error {
+	for _, redirectURI := range settings.RedirectURIs {
+		u, err := url.Parse(redirectURI)
+		if err != nil {
+			return fmt.Errorf("%w: redirect uri %q: %w", ErrInvalidSettings, redirectURI, err)
+		}
+
+		if !u.IsAbs() || u.Host == "" || u.Fragment != "" {
+			return fmt.Errorf("%w: redirect uri %q must be absolute and without fragment", ErrInvalidSettings, redirectURI)
+		}
+	}
+
+	for _, scope := range settings.ClientScopes {
+		if !validScope(scope) {
+			return fmt.Errorf("%w: invalid client scope %q", ErrInvalidSettings, scope)
+		}
+	}
+
+	for _, scope := range settings.AllowedScopes {
+		if !validScope(scope) {
+			return fmt.Errorf("%w: invalid allowed scope %q", ErrInvalidSettings, scope)
+		}
+	}
+
+	for _, grantType := range settings.GrantTypes {
+		if !slices.Contains(grantTypes, grantType) {
+			return fmt.Errorf("%w: unknown grant type %q", ErrInvalidSettings, grantType)
+		}
+	}
+
+	if logoutURI := settings.BackchannelLogoutURI; logoutURI != "" {
+		u, err := url.Parse(logoutURI)
+		if err != nil {
+			return fmt.Errorf("%w: back-channel logout uri %q: %w", ErrInvalidSettings, logoutURI, err)
+		}
+
+		if (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" || u.Fragment != "" {
+			return fmt.Errorf("%w: back-channel logout uri %q must be absolute HTTP URI without fragment", ErrInvalidSettings, logoutURI)
+		}
+	}
+
+	if settings.AccessTokenTTL < 0 || settings.RefreshTokenTTL < 0 {
+		return fmt.Errorf("%w: token TTL can't be negative", ErrInvalidSettings)
+	}
+
+	return validateTheme(settings.Theme)
+}
+
+// mergeSettings copies the fields of the update into the settings, fields are named as in the API
+func mergeSettings(settings *models.AppSettings, update models.AppSettings, fields []string) error {
+	for _, field := range fields {
+		switch field {
+		case "embed_roles":
+			settings.EmbedRoles = update.EmbedRoles
+		case "embed_scope":
+			settings.EmbedScope = update.EmbedScope
+		case "max_claims_size":
+			settings.MaxClaimsSize = update.MaxClaimsSize
+		case "redirect_uris":
+			settings.RedirectURIs = update.RedirectURIs
+		case "client_scopes":
+			settings.ClientScopes = update.ClientScopes
+		case "grant_types":
+			settings.GrantTypes = update.GrantTypes
+		case "allowed_scopes":
+			settings.AllowedScopes = update.AllowedScopes
+		case "access_token_ttl_seconds":
+			settings.AccessTokenTTL = update.AccessTokenTTL
+		case "refresh_token_ttl_seconds":
+			settings.RefreshTokenTTL = update.RefreshTokenTTL
+		case "public_client":
+			settings.PublicClient = update.PublicClient
+		case "third_party":
+			settings.ThirdParty = update.ThirdParty
+		case "backchannel_logout_uri":
+			settings.BackchannelLogoutURI = update.BackchannelLogoutURI
+		case "theme":
+			settings.Theme = update.Theme
+		default:
+			return fmt.Errorf("%w: unknown settings field %q", ErrInvalidSettings, field)
+		}
+	}
+
+	return nil
+}
+
+// clientKindChanged reports whether the update changes the kind of the OAuth client: its grant types,
+// public or third-party client flags. App admin could take tokens of the users by them, so they are for super-admins
+func clientKindChanged(settings, updated models.AppSettings) bool {
+	return settings.PublicClient != updated.PublicClient ||
+		settings.ThirdParty != updated.ThirdParty ||
+		!slices.Equal(settings.GrantTypes, updated.GrantTypes)
+}
+
+// reservedScopesAdded reports whether the update gives the application reserved client scopes it doesn't have
+func reservedScopesAdded(scopes, updated []string) bool {
+	for _, scope := range reservedScopes {
+		if slices.Contains(updated, scope) && !slices.Contains(scopes, scope) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// validateTheme checks the theme, colors are strict, so they are safe to put into styles of the pages
+func validateTheme(theme models.AppTheme) error {
+	if theme.LogoURI != "" {
+		u, err := url.Parse(theme.LogoURI)
+		if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			return fmt.Errorf("%w: logo uri %q must be absolute HTTP URI", ErrInvalidSettings, theme.LogoURI)
+		}
+	}
+
+	for _, color := range []string{theme.PrimaryColor, theme.BackgroundColor} {
+		if color != "" && !themeColor.MatchString(color) {
+			return fmt.Errorf("%w: color %q must be #rrggbb", ErrInvalidSettings, color)
+		}
+	}
+
+	if utf8.RuneCountInString(theme.Title) > maxThemeTitleLen || utf8.RuneCountInString(theme.Text) > maxThemeTextLen {
+		return fmt.Errorf("%w: theme title or text is too long", ErrInvalidSettings)
+	}
+
+	return nil
+}
+
+// validScope reports whether the scope is a scope token of RFC 6749
+func validScope(scope string) bool {
+	return scope != "" && !strings.ContainsAny(scope, " \t\n\"\\")
+}
+
+// authorizeSettings checks that actor is super-admin if the update changes the kind of the OAuth client
+// or gives it reserved scopes
+func (a *Apps) authorizeSettings(ctx context.Context, caller models.TokenInfo, settings, updated models.AppSettings) error {
+	if !clientKindChanged(settings, updated) && !reservedScopesAdded(settings.ClientScopes, updated.ClientScopes) {
+		return nil
+	}
+
+	return a.authorize(ctx, caller, 0)
+}
+
+// authorize checks that actor is admin of the application, zero app ID requires super-admin rights.
+// Impersonated users never act as admin
+func (a *Apps) authorize(ctx context.Context, caller models.TokenInfo, appID int32) error {
+	if caller.ActorID != 0 {
+		return ErrPermissionDenied
+	}
+
+	if appID == 0 {
+		isSuperAdmin, err := a.adminProvider.IsAdmin(ctx, caller.UserID)
+		if err != nil {
+			return err
+		}
+
+		if !isSuperAdmin {
+			return ErrPermissionDenied
+		}
+
+		return nil
+	}
+
+	isAdmin, _, err := a.adminProvider.IsAppAdmin(ctx, caller.UserID, appID)
+	if err != nil {
+		if errors.Is(err, storage.ErrAppNotFound) {
+			return ErrInvalidAppID
+		}
+
+		return err
+	}
+
+	if !isAdmin {
+		return ErrPermissionDenied
+	}
+
+	return nil
+}
+
+// storageErr converts errors of the apps storage to errors of the service
+func (a *Apps) storageErr(log *slog.Logger, err error) error {
+	switch {
+	case errors.Is(err, storage.ErrAppNotFound):
+		log.Warn("failed to found app in the system", sl.Err(err))
+
+		return ErrInvalidAppID
+	case errors.Is(err, storage.ErrAppExists):
+		log.Warn("app already exists", sl.Err(err))
+
+		return ErrAppExists
+	}
+
+	log.Error("failed to change app", sl.Err(err))
+
+	return err
+}
@@ -3,7 +3,10 @@ package auth
 import (
 	"context"
 	"errors"
+	"fmt"
 	"log/slog"
+	"slices"
+	"strings"
 	"time"
 
 	"github.com/nhassl3/sso-app/internals/domain/models"
@@ -17,6 +20,7 @@ const (
 	opLogin           = "auth.Login"
 	opRegisterNewUser = "auth.RegisterNewUser"
 	opIsAdmin         = "auth.IsAdmin"
+	opIntrospect      = "auth.Introspect"
 )
 
 var (
@@ -24,6 +28,7 @@ var (
 	ErrInvalidAppID       = errors.New("invalid application ID")
 	ErrInvalidUserID      = errors.New("invalid user ID")
 	ErrUserExists         = errors.New("user already exists")
+	ErrAppDisabled        = errors.New("application is disabled")
 )
 
 type Auth struct {
@@ -31,24 +36,118 @@ type Auth struct {
 	userSaver    UserSaver
 	userProvider UserProvider
 	appProvider  AppProvider
+	roleProvider RoleProvider
+	codeSaver    CodeSaver
 	tokenTTL     time.Duration
+	codeTTL      time.Duration
+	issuer       string
+	signingKey   *njwt.SigningKey
+
+	deviceCodeSaver       DeviceCodeSaver
+	deviceCodeTTL         time.Duration
+	deviceInterval        time.Duration
+	deviceVerificationURI string
+
+	auditSaver       AuditSaver
+	impersonationTTL time.Duration
+	consoleAppID     int32
+
+	refreshTokenSaver RefreshTokenSaver
+	consentSaver      ConsentSaver
+	sessionSaver      SessionSaver
+
+	federationSaver    FederationSaver
+	providers          []FederatedProvider
+	federationStateTTL time.Duration
+
+	directory  Directory
+	roleSyncer RoleSyncer
+	groupRoles []GroupRole
+
+	chain  []identityStep
+	realms []RealmRule
+}
+
+// Deps are storages, upstream providers and settings of the Auth service
+type Deps struct {
+	UserSaver    UserSaver
+	UserProvider UserProvider
+	AppProvider  AppProvider
+	RoleProvider RoleProvider
+	CodeSaver    CodeSaver
+
+	TokenTTL   time.Duration
+	CodeTTL    time.Duration // lifetime of the authorization codes
+	Issuer     string
+	SigningKey *njwt.SigningKey // key of ID tokens
+
+	DeviceCodeSaver       DeviceCodeSaver
+	DeviceCodeTTL         time.Duration
+	DeviceInterval        time.Duration // minimal interval between polls of the device
+	DeviceVerificationURI string
+
+	AuditSaver       AuditSaver
+	ImpersonationTTL time.Duration // max lifetime of the impersonation tokens
+	ConsoleAppID     int32         // only user tokens of the admin console application impersonate the users
+
+	RefreshTokenSaver RefreshTokenSaver
+	ConsentSaver      ConsentSaver
+	SessionSaver      SessionSaver
+
+	FederationSaver    FederationSaver
+	Providers          []FederatedProvider
+	FederationStateTTL time.Duration // how long the user may sign in at the provider
+
+	Directory  Directory // nil if LDAP directory is disabled
+	RoleSyncer RoleSyncer
+	GroupRoles []GroupRole
+
+	IdentityChain []IdentityStep
+	Realms        []RealmRule
 }
 
 // NewAuth returns a new instance of the Auth service
-func NewAuth(
-	log *slog.Logger,
-	userSaver UserSaver,
-	userProvider UserProvider,
-	appProvider AppProvider,
-	tokenTTL time.Duration,
-) *Auth {
-	return &Auth{
+func NewAuth(log *slog.Logger, deps Deps) *Auth {
+	a := &Auth{
 		log:          log,
-		userSaver:    userSaver,
-		userProvider: userProvider,
-		appProvider:  appProvider,
-		tokenTTL:     tokenTTL,
+		userSaver:    deps.UserSaver,
+		userProvider: deps.UserProvider,
+		appProvider:  deps.AppProvider,
+		roleProvider: deps.RoleProvider,
+		codeSaver:    deps.CodeSaver,
+		tokenTTL:     deps.TokenTTL,
+		codeTTL:      deps.CodeTTL,
+		issuer:       deps.Issuer,
+		signingKey:   deps.SigningKey,
+
+		deviceCodeSaver:       deps.DeviceCodeSaver,
+		deviceCodeTTL:         deps.DeviceCodeTTL,
+		deviceInterval:        deps.DeviceInterval,
+		deviceVerificationURI: deps.DeviceVerificationURI,
+
+		auditSaver:       deps.AuditSaver,
+		impersonationTTL: deps.ImpersonationTTL,
+		consoleAppID:     deps.ConsoleAppID,
+
+		refreshTokenSaver: deps.RefreshTokenSaver,
+		consentSaver:      deps.ConsentSaver,
+		sessionSaver:      deps.SessionSaver,
+
+		federationSaver:    deps.FederationSaver,
+		providers:          deps.Providers,
+		federationStateTTL: deps.FederationStateTTL,
+
+		directory:  deps.Directory,
+		roleSyncer: deps.RoleSyncer,
+		groupRoles: deps.GroupRoles,
+
+		realms: deps.Realms,
 	}
+
+	// Steps are resolved when upstream providers and the directory are set
+	a.chain = a.identityChain(deps.IdentityChain)
+
+	return a
 }
 
 type UserSaver interface {
@@ -57,57 +156,55 @@ type UserSaver interface {
 
 type UserProvider interface {
 	User(ctx context.Context, email string) (user models.User, err error)
+	UserByID(ctx context.Context, userID int64) (user models.User, err error)
 	IsAdmin(ctx context.Context, userID int64) (isAdmin bool, err error)
+	IsAppAdmin(ctx context.Context, userID int64, appID int32) (isAdmin bool, isSuperAdmin bool, err error)
+	HasAdminRights(ctx context.Context, userID int64) (hasRights bool, err error)
+}
+
+type AuditSaver interface {
+	SaveAuditEvent(ctx context.Context, event models.AuditEvent) error
+}
+
+// SessionSaver keeps applications the users got tokens for, so they are notified about logout of the user
+type SessionSaver interface {
+	SaveSession(ctx context.Context, userID int64, appID int32, expiresAt time.Time) error
 }
 
 type AppProvider interface {
 	App(ctx context.Context, appID int32) (app models.App, err error)
 }
 
+type RoleProvider interface {
+	UserRoles(ctx context.Context, userID int64, appID int32) (roles []models.Role, err error)
+}
+
 // Login checks if user with given credentials exists in the system.
+// The user is authenticated by the identity chain, idp claim of the token has the provider authenticated the user.
+// Users of the email domains routed to the upstream providers can't sign in by password.
 //
 // If user exists, but password is incorrect, returns error.
 // If user doesn't exist, returns error
 func (a *Auth) Login(ctx context.Context, email string, password string, appID int32) (token string, err error) {
 	log := a.log.With(slog.String("op", opLogin))
 
-	user, err := a.userProvider.User(ctx, email)
-	if err != nil {
-		if errors.Is(err, storage.ErrUserNotFound) {
-			log.Warn("failed to found user in the system", sl.Err(err))
-
-			return "", sl.ErrUpLevel(opLogin, ErrInvalidCredentials.Error())
-		}
-
-		log.Error("failed to get user", sl.Err(err))
-
-		return "", sl.ErrUpLevel(opLogin, err.Error())
+	if err := a.checkRealm(log, email, appID); err != nil {
+		return "", sl.ErrUpLevel(opLogin, err)
 	}
 
-	if err := bcrypt.CompareHashAndPassword(user.HashPassword, []byte(password)); err != nil {
-		log.Info(ErrInvalidCredentials.Error(), sl.Err(err))
-
-		return "", sl.ErrUpLevel(opLogin, ErrInvalidCredentials.Error())
+	authn, err := a.authenticate(ctx, log, email, password)
+	if err != nil {
+		return "", sl.ErrUpLevel(opLogin, err)
 	}
 
-	app, err := a.appProvider.App(ctx, appID)
+	app, err := a.enabledApp(ctx, log, appID)
 	if err != nil {
-		if errors.Is(err, storage.ErrAppNotFound) {
-			log.Warn("failed to found app in the system", sl.Err(err))
-
-			return "", sl.ErrUpLevel(opLogin, ErrInvalidAppID.Error())
-		}
-
-		log.Error("failed to get app", sl.Err(err))
-
-		return "", sl.ErrUpLevel(opLogin, err.Error())
+		return "", sl.ErrUpLevel(opLogin, err)
 	}
 
-	token, err = njwt.NewToken(user, app, a.tokenTTL)
+	token, err = a.issueToken(ctx, log, authn.User, app, "", authn.IdentityProvider)
 	if err != nil {
-		log.Error("failed to generate token", sl.Err(err))
-
-		return "", sl.ErrUpLevel(opLogin, err.Error())
+		return "", sl.ErrUpLevel(opLogin, err)
 	}
 
 	return
@@ -121,7 +218,7 @@ func (a *Auth) RegisterNewUser(ctx context.Context, email string, password strin
 	passHash, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
 	if err != nil {
 		log.Error("failed to generate password hash", sl.Err(err))
-		return 0, sl.ErrUpLevel(opRegisterNewUser, err.Error())
+		return 0, sl.ErrUpLevel(opRegisterNewUser, err)
 	}
 
 	userID, err = a.userSaver.SaveUser(ctx, email, passHash)
@@ -129,40 +226,212 @@ func (a *Auth) RegisterNewUser(ctx context.Context, email string, password strin
 		if errors.Is(err, storage.ErrUserExists) {
 			log.Warn("user already exists", sl.Err(err))
 
-			return 0, sl.ErrUpLevel(opRegisterNewUser, ErrUserExists.Error())
+			return 0, sl.ErrUpLevel(opRegisterNewUser, ErrUserExists)
 		}
 
 		log.Error("failed to save user in database", sl.Err(err))
 
-		return 0, sl.ErrUpLevel(opRegisterNewUser, err.Error())
+		return 0, sl.ErrUpLevel(opRegisterNewUser, err)
 	}
 
 	return
 }
 
-// IsAdmin checks if the user has administrator rights on the system.
+// IsAdmin checks if the user has super-admin rights on the whole system.
 // If user doesn't have, returns false else true.
+// Admin rights in some application are checked by admin.IsAppAdmin
 func (a *Auth) IsAdmin(ctx context.Context, userID int64) (isAdmin bool, err error) {
 	log := a.log.With(slog.String("op", opIsAdmin))
 
 	isAdmin, err = a.userProvider.IsAdmin(ctx, userID)
+	if err != nil {
+		if errors.Is(err, storage.ErrUserNotFound) {
+			log.Warn("failed to found user in the system", sl.Err(err))
+
+			return false, sl.ErrUpLevel(opIsAdmin, ErrInvalidUserID)
+		}
+
+		log.Error("failed to get user", sl.Err(err))
+
+		return false, sl.ErrUpLevel(opIsAdmin, err)
+	}
+
+	return
+}
+
+// issueToken issues token of the user for the application with roles and scope the application asks for.
+// Granted is scope granted by the user to the OAuth client, empty for tokens issued by Login.
+// Identity is the identity provider authenticated the user by Login, empty for tokens of other grants
+func (a *Auth) issueToken(
+	ctx context.Context,
+	log *slog.Logger,
+	user models.User,
+	app models.App,
+	granted string,
+	identity string,
+) (string, error) {
+	var (
+		roles []models.Role
+		err   error
+	)
+	if app.EmbedRoles || app.EmbedScope {
+		roles, err = a.roleProvider.UserRoles(ctx, user.ID, int32(app.ID))
+		if err != nil {
+			log.Error("failed to get user roles", sl.Err(err))
+
+			return "", err
+		}
+	}
+
+	ttl := a.accessTokenTTL(app)
+
+	token, err := njwt.NewGrantedToken(user, app, roles, ttl, granted, identity)
+	if err != nil {
+		log.Error("failed to generate token", sl.Err(err))
+
+		return "", err
+	}
+
+	if err := a.saveSession(ctx, log, user.ID, app, time.Now().Add(ttl)); err != nil {
+		return "", err
+	}
+
+	return token, nil
+}
+
+// saveSession saves session of the user in the application which lasts at least until the expiry
+func (a *Auth) saveSession(ctx context.Context, log *slog.Logger, userID int64, app models.App, expiresAt time.Time) error {
+	if err := a.sessionSaver.SaveSession(ctx, userID, int32(app.ID), expiresAt); err != nil {
+		log.Error("failed to save session", sl.Err(err))
+
+		return err
+	}
+
+	return nil
+}
+
+// accessTokenTTL returns lifetime of the access tokens of the application
+func (a *Auth) accessTokenTTL(app models.App) time.Duration {
+	if app.AccessTokenTTL > 0 {
+		return app.AccessTokenTTL
+	}
+
+	return a.tokenTTL
+}
+
+// enabledApp returns the application if it exists and is not disabled
+func (a *Auth) enabledApp(ctx context.Context, log *slog.Logger, appID int32) (models.App, error) {
+	app, err := a.appProvider.App(ctx, appID)
 	if err != nil {
 		if errors.Is(err, storage.ErrAppNotFound) {
 			log.Warn("failed to found app in the system", sl.Err(err))
 
-			return false, sl.ErrUpLevel(opIsAdmin, ErrInvalidAppID.Error())
+			return models.App{}, ErrInvalidAppID
 		}
 
-		if errors.Is(err, storage.ErrUserNotFound) {
-			log.Warn("failed to found user in the system", sl.Err(err))
+		log.Error("failed to get app", sl.Err(err))
+
+		return models.App{}, err
+	}
+
+	if app.Disabled {
+		log.Warn("attempt to use disabled app", slog.Int("app_id", app.ID))
+
+		return models.App{}, ErrAppDisabled
+	}
+
+	return app, nil
+}
 
-			return false, sl.ErrUpLevel(opIsAdmin, ErrInvalidUserID.Error())
+// activeUser returns the user by ID, users deactivated by SCIM client are not found
+func (a *Auth) activeUser(ctx context.Context, userID int64) (models.User, error) {
+	user, err := a.userProvider.UserByID(ctx, userID)
+	if err != nil {
+		return models.User{}, err
+	}
+
+	if user.Disabled {
+		return models.User{}, fmt.Errorf("%w: user is deactivated", storage.ErrUserNotFound)
+	}
+
+	return user, nil
+}
+
+// Introspect validates the token and returns information about it with actual roles and scopes of the user,
+// token of the application itself has scopes granted by the client credentials grant.
+// If token is invalid or expired, returns inactive token information without error
+func (a *Auth) Introspect(ctx context.Context, token string) (info models.TokenInfo, err error) {
+	log := a.log.With(slog.String("op", opIntrospect))
+
+	var app models.App
+
+	claims, err := njwt.Parse(token, func(appID int32) (string, error) {
+		var err error
+
+		app, err = a.appProvider.App(ctx, appID)
+		if err != nil {
+			return "", err
 		}
 
-		log.Error("failed to get user", sl.Err(err))
+		if app.Disabled {
+			return "", ErrAppDisabled
+		}
+
+		return app.Secret, nil
+	})
+	if err != nil {
+		if errors.Is(err, njwt.ErrInvalidToken) || errors.Is(err, storage.ErrAppNotFound) || errors.Is(err, ErrAppDisabled) {
+			log.Debug("token is not active", sl.Err(err))
 
-		return false, sl.ErrUpLevel(opIsAdmin, err.Error())
+			return models.TokenInfo{Active: false}, nil
+		}
+
+		log.Error("failed to get app of the token", sl.Err(err))
+
+		return models.TokenInfo{}, sl.ErrUpLevel(opIntrospect, err)
 	}
 
-	return
+	// Application has only scopes granted to it by the client credentials grant. Owner of the application
+	// has its secret and may sign any scope, so scopes of the token are limited to the actual client scopes
+	if claims.UID == 0 {
+		scopes := make([]string, 0, len(app.ClientScopes))
+		for _, scope := range strings.Fields(claims.Scope) {
+			if slices.Contains(app.ClientScopes, scope) {
+				scopes = append(scopes, scope)
+			}
+		}
+
+		return models.TokenInfo{
+			Active:    true,
+			AppID:     claims.AppID,
+			ExpiresAt: claims.ExpiresAt,
+			Scopes:    scopes,
+		}, nil
+	}
+
+	roles, err := a.roleProvider.UserRoles(ctx, claims.UID, claims.AppID)
+	if err != nil {
+		log.Error("failed to get user roles", sl.Err(err))
+
+		return models.TokenInfo{}, sl.ErrUpLevel(opIntrospect, err)
+	}
+
+	scopeLimit := strings.Fields(claims.ScopeLimit)
+	if len(scopeLimit) > 0 {
+		roles = limitRoles(roles, scopeLimit)
+	}
+
+	return models.TokenInfo{
+		Active:    true,
+		UserID:    claims.UID,
+		Email:     claims.Email,
+		AppID:     claims.AppID,
+		ExpiresAt: claims.ExpiresAt,
+		Roles:     njwt.RoleNames(roles),
+		Scopes:    njwt.RoleScopes(roles),
+		Granted:   strings.Fields(claims.Granted),
+		ActorID:   claims.ActorID,
+
+		ScopeLimit: scopeLimit,
+	}, nil
 }
@@ -0,0 +1,141 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"slices"
+	"time"
+
+	"github.com/nhassl3/sso-app/internals/domain/models"
+	"github.com/nhassl3/sso-app/internals/lib/logger/sl"
+	"github.com/nhassl3/sso-app/internals/storage"
+)
+
+const (
+	opConsents      = "auth.Consents"
+	opRevokeConsent = "auth.RevokeConsent"
+)
+
+var (
+	ErrConsentRequired = errors.New("consent of the user to the scope is required")
+	ErrConsentDenied   = errors.New("user denied consent to the scope")
+	ErrConsentNotFound = errors.New("consent not found")
+)
+
+type ConsentSaver interface {
+	Consent(ctx context.Context, userID int64, appID int32) (consent models.Consent, err error)
+	SaveConsent(ctx context.Context, consent models.Consent) error
+	Consents(ctx context.Context, userID int64) (consents []models.Consent, err error)
+	DeleteConsent(ctx context.Context, userID int64, appID int32) error
+}
+
+// Consents returns consents the user gave to the third-party applications
+func (a *Auth) Consents(ctx context.Context, userID int64) (consents []models.Consent, err error) {
+	log := a.log.With(slog.String("op", opConsents), slog.Int64("user_id", userID))
+
+	consents, err = a.consentSaver.Consents(ctx, userID)
+	if err != nil {
+		log.Error("failed to get consents", sl.Err(err))
+
+		return nil, sl.ErrUpLevel(opConsents, err)
+	}
+
+	return
+}
+
+// RevokeConsent revokes consent of the user to the application together with its refresh tokens of the user,
+// the application asks for the consent again by the next authorization
+func (a *Auth) RevokeConsent(ctx context.Context, userID int64, appID int32) error {
+	log := a.log.With(slog.String("op", opRevokeConsent), slog.Int64("user_id", userID), slog.Int("app_id", int(appID)))
+
+	if err := a.consentSaver.DeleteConsent(ctx, userID, appID); err != nil {
+		if errors.Is(err, storage.ErrConsentNotFound) {
+			log.Warn("consent not found", sl.Err(err))
+
+			return sl.ErrUpLevel(opRevokeConsent, ErrConsentNotFound)
+		}
+
+		log.Error("failed to delete consent", sl.Err(err))
+
+		return sl.ErrUpLevel(opRevokeConsent, err)
+	}
+
+	log.Info("consent revoked")
+
+	return nil
+}
+
+// checkConsent checks that the user consented to the scopes of the third-party application.
+// Decision of the user is saved if the consent is missing or doesn't cover the scopes, empty decision asks for it
+func (a *Auth) checkConsent(
+	ctx context.Context,
+	log *slog.Logger,
+	app models.App,
+	userID int64,
+	scopes []string,
+	decision string,
+) error {
+	if !app.ThirdParty {
+		return nil
+	}
+
+	consent, err := a.consentSaver.Consent(ctx, userID, int32(app.ID))
+	if err != nil && !errors.Is(err, storage.ErrConsentNotFound) {
+		log.Error("failed to get consent", sl.Err(err))
+
+		return err
+	}
+
+	// Token without scopes still tells the application who the user is, so it needs consent too
+	if err == nil && consent.Covers(scopes) {
+		return nil
+	}
+
+	switch decision {
+	case models.ConsentApprove:
+		return a.grantConsent(ctx, log, consent, app, userID, scopes)
+	case models.ConsentDeny:
+		log.Info("user denied consent", slog.Int64("user_id", userID))
+
+		return ErrConsentDenied
+	}
+
+	log.Info("consent is required", slog.Int64("user_id", userID))
+
+	return ErrConsentRequired
+}
+
+// grantConsent adds the scopes to the consent of the user to the application
+func (a *Auth) grantConsent(
+	ctx context.Context,
+	log *slog.Logger,
+	consent models.Consent,
+	app models.App,
+	userID int64,
+	scopes []string,
+) error {
+	granted := slices.Clone(consent.Scopes)
+	for _, scope := range scopes {
+		if !slices.Contains(granted, scope) {
+			granted = append(granted, scope)
+		}
+	}
+	slices.Sort(granted)
+
+	err := a.consentSaver.SaveConsent(ctx, models.Consent{
+		UserID:    userID,
+		AppID:     int32(app.ID),
+		Scopes:    granted,
+		GrantedAt: time.Now(),
+	})
+	if err != nil {
+		log.Error("failed to save consent", sl.Err(err))
+
+		return err
+	}
+
+	log.Info("consent granted", slog.Int64("user_id", userID), slog.Any("scopes", granted))
+
+	return nil
+}
@@ -0,0 +1,289 @@
+package auth
+
+import (
+	"context"
+	"crypto/rand"
+	"errors"
+	"log/slog"
+	"math/big"
+	"strings"
+	"time"
+
+	"github.com/nhassl3/sso-app/internals/domain/models"
+	"github.com/nhassl3/sso-app/internals/lib/logger/sl"
+	"github.com/nhassl3/sso-app/internals/lib/random"
+	"github.com/nhassl3/sso-app/internals/storage"
+)
+
+const (
+	opAuthorizeDevice    = "auth.AuthorizeDevice"
+	opApproveDevice      = "auth.ApproveDevice"
+	opDenyDevice         = "auth.DenyDevice"
+	opExchangeDeviceCode = "auth.ExchangeDeviceCode"
+
+	// User codes are typed by people, so they have no vowels and similar looking characters as RFC 8628 advises
+	userCodeAlphabet = "BCDFGHJKLMNPQRSTVWXZ"
+	userCodeLen      = 8
+	userCodeAttempts = 3
+
+	// slowDownStep is added to the interval of the device which polls too often, as RFC 8628 requires
+	slowDownStep = 5 * time.Second
+)
+
+var (
+	ErrInvalidUserCode      = errors.New("user code is invalid, expired or already decided")
+	ErrAuthorizationPending = errors.New("user hasn't decided on the device code yet")
+	ErrSlowDown             = errors.New("device polls too often")
+	ErrExpiredToken         = errors.New("device code is expired")
+	ErrAccessDenied         = errors.New("user denied the device code")
+)
+
+type DeviceCodeSaver interface {
+	SaveDeviceCode(ctx context.Context, codeHash string, code models.DeviceCode) error
+	DecideDeviceCode(ctx context.Context, userCode string, userID int64, status string) (code models.DeviceCode, err error)
+	PollDeviceCode(ctx context.Context, appID int32, codeHash string, polledAt time.Time) (code models.DeviceCode, err error)
+	SlowDownDeviceCode(ctx context.Context, codeHash string, interval time.Duration) error
+}
+
+// AuthorizeDevice issues device code and user code for the OAuth client on the device without browser.
+// The user enters the user code at the verification URI and approves it by ApproveDevice,
+// meanwhile the device polls the token endpoint by ExchangeDeviceCode. Confidential client authenticates by the client secret
+func (a *Auth) AuthorizeDevice(
+	ctx context.Context,
+	appID int32,
+	clientSecret string,
+	scope string,
+) (authorization models.DeviceAuthorization, err error) {
+	log := a.log.With(slog.String("op", opAuthorizeDevice), slog.Int("app_id", int(appID)))
+
+	app, err := a.oauthClient(ctx, log, appID, clientSecret, models.GrantTypeDevice)
+	if err != nil {
+		return models.DeviceAuthorization{}, sl.ErrUpLevel(opAuthorizeDevice, err)
+	}
+
+	if !app.AllowsScopes(strings.Fields(scope)) {
+		log.Warn("scope is not allowed to the client", slog.String("scope", scope))
+
+		return models.DeviceAuthorization{}, sl.ErrUpLevel(opAuthorizeDevice, ErrInvalidScope)
+	}
+
+	deviceCode, err := random.String(codeSize)
+	if err != nil {
+		log.Error("failed to generate device code", sl.Err(err))
+
+		return models.DeviceAuthorization{}, sl.ErrUpLevel(opAuthorizeDevice, err)
+	}
+
+	// Space of the user codes is small enough to collide with pending codes, so the code is generated again
+	var userCode string
+	for range userCodeAttempts {
+		userCode, err = newUserCode()
+		if err != nil {
+			log.Error("failed to generate user code", sl.Err(err))
+
+			return models.DeviceAuthorization{}, sl.ErrUpLevel(opAuthorizeDevice, err)
+		}
+
+		err = a.deviceCodeSaver.SaveDeviceCode(ctx, hashCode(deviceCode), models.DeviceCode{
+			AppID:     appID,
+			UserCode:  userCode,
+			Scope:     scope,
+			Interval:  a.deviceInterval,
+			ExpiresAt: time.Now().Add(a.deviceCodeTTL),
+		})
+		if !errors.Is(err, storage.ErrUserCodeExists) {
+			break
+		}
+	}
+	if err != nil {
+		log.Error("failed to save device code", sl.Err(err))
+
+		return models.DeviceAuthorization{}, sl.ErrUpLevel(opAuthorizeDevice, err)
+	}
+
+	log.Info("device code issued")
+
+	displayed := userCode[:userCodeLen/2] + "-" + userCode[userCodeLen/2:]
+
+	return models.DeviceAuthorization{
+		DeviceCode:              deviceCode,
+		UserCode:                displayed,
+		VerificationURI:         a.deviceVerificationURI,
+		VerificationURIComplete: a.deviceVerificationURI + "?user_code=" + displayed,
+		ExpiresIn:               a.deviceCodeTTL,
+		Interval:                a.deviceInterval,
+	}, nil
+}
+
+// ApproveDevice approves pending device code by its user code, the device gets token of the user by the next poll.
+// Approval is consent of the user to the scope of the third-party application
+func (a *Auth) ApproveDevice(ctx context.Context, userID int64, userCode string) (code models.DeviceCode, err error) {
+	code, err = a.decideDevice(ctx, opApproveDevice, userID, userCode, models.DeviceApproved)
+	if err != nil {
+		return models.DeviceCode{}, sl.ErrUpLevel(opApproveDevice, err)
+	}
+
+	log := a.log.With(slog.String("op", opApproveDevice), slog.Int("app_id", int(code.AppID)))
+
+	app, err := a.appProvider.App(ctx, code.AppID)
+	if err != nil {
+		log.Error("failed to get app", sl.Err(err))
+
+		return models.DeviceCode{}, sl.ErrUpLevel(opApproveDevice, err)
+	}
+
+	err = a.checkConsent(ctx, log, app, userID, strings.Fields(code.Scope), models.ConsentApprove)
+	if err != nil {
+		return models.DeviceCode{}, sl.ErrUpLevel(opApproveDevice, err)
+	}
+
+	return
+}
+
+// DenyDevice denies pending device code by its user code, the device gets access_denied error by the next poll
+func (a *Auth) DenyDevice(ctx context.Context, userID int64, userCode string) (code models.DeviceCode, err error) {
+	code, err = a.decideDevice(ctx, opDenyDevice, userID, userCode, models.DeviceDenied)
+	if err != nil {
+		return models.DeviceCode{}, sl.ErrUpLevel(opDenyDevice, err)
+	}
+
+	return
+}
+
+// ExchangeDeviceCode exchanges approved device code for the token of the user who approved it.
+// Until the decision it returns ErrAuthorizationPending, or ErrSlowDown if the device polls more often
+// than the interval, which is increased then. Decided and expired codes are used once.
+// Refresh token is issued too if the application allows them
+func (a *Auth) ExchangeDeviceCode(
+	ctx context.Context,
+	appID int32,
+	clientSecret string,
+	deviceCode string,
+) (token models.OAuthToken, err error) {
+	log := a.log.With(slog.String("op", opExchangeDeviceCode), slog.Int("app_id", int(appID)))
+
+	app, err := a.oauthClient(ctx, log, appID, clientSecret, models.GrantTypeDevice)
+	if err != nil {
+		return models.OAuthToken{}, sl.ErrUpLevel(opExchangeDeviceCode, err)
+	}
+
+	now := time.Now()
+	codeHash := hashCode(deviceCode)
+
+	code, err := a.deviceCodeSaver.PollDeviceCode(ctx, appID, codeHash, now)
+	if err != nil {
+		if errors.Is(err, storage.ErrDeviceCodeNotFound) {
+			log.Warn("unknown, used or issued to another client device code", sl.Err(err))
+
+			return models.OAuthToken{}, sl.ErrUpLevel(opExchangeDeviceCode, ErrInvalidGrant)
+		}
+
+		log.Error("failed to poll device code", sl.Err(err))
+
+		return models.OAuthToken{}, sl.ErrUpLevel(opExchangeDeviceCode, err)
+	}
+
+	switch {
+	case !now.Before(code.ExpiresAt):
+		return models.OAuthToken{}, sl.ErrUpLevel(opExchangeDeviceCode, ErrExpiredToken)
+	case code.Status == models.DeviceDenied:
+		return models.OAuthToken{}, sl.ErrUpLevel(opExchangeDeviceCode, ErrAccessDenied)
+	case code.Status == models.DevicePending:
+		if code.LastPolledAt.IsZero() || now.Sub(code.LastPolledAt) >= code.Interval {
+			return models.OAuthToken{}, sl.ErrUpLevel(opExchangeDeviceCode, ErrAuthorizationPending)
+		}
+
+		if err := a.deviceCodeSaver.SlowDownDeviceCode(ctx, codeHash, code.Interval+slowDownStep); err != nil {
+			log.Error("failed to slow down device", sl.Err(err))
+
+			return models.OAuthToken{}, sl.ErrUpLevel(opExchangeDeviceCode, err)
+		}
+
+		log.Info("device polls too often", slog.Duration("interval", code.Interval+slowDownStep))
+
+		return models.OAuthToken{}, sl.ErrUpLevel(opExchangeDeviceCode, ErrSlowDown)
+	}
+
+	user, err := a.activeUser(ctx, code.UserID)
+	if err != nil {
+		if errors.Is(err, storage.ErrUserNotFound) {
+			log.Warn("user of the device code not found", sl.Err(err))
+
+			return models.OAuthToken{}, sl.ErrUpLevel(opExchangeDeviceCode, ErrInvalidGrant)
+		}
+
+		log.Error("failed to get user", sl.Err(err))
+
+		return models.OAuthToken{}, sl.ErrUpLevel(opExchangeDeviceCode, err)
+	}
+
+	token = models.OAuthToken{
+		TokenType: models.TokenTypeBearer,
+		ExpiresIn: a.accessTokenTTL(app),
+		Scope:     code.Scope,
+	}
+
+	token.AccessToken, err = a.issueToken(ctx, log, user, app, code.Scope, "")
+	if err != nil {
+		return models.OAuthToken{}, sl.ErrUpLevel(opExchangeDeviceCode, err)
+	}
+
+	token.RefreshToken, err = a.issueRefreshToken(ctx, log, app, user.ID, code.Scope)
+	if err != nil {
+		return models.OAuthToken{}, sl.ErrUpLevel(opExchangeDeviceCode, err)
+	}
+
+	log.Info("device token issued", slog.Int64("user_id", user.ID))
+
+	return
+}
+
+// decideDevice saves decision of the user on the pending device code
+func (a *Auth) decideDevice(
+	ctx context.Context,
+	op string,
+	userID int64,
+	userCode string,
+	status string,
+) (models.DeviceCode, error) {
+	log := a.log.With(slog.String("op", op), slog.Int64("user_id", userID))
+
+	code, err := a.deviceCodeSaver.DecideDeviceCode(ctx, normalizeUserCode(userCode), userID, status)
+	if err != nil {
+		if errors.Is(err, storage.ErrDeviceCodeNotFound) {
+			log.Warn("unknown, expired or decided user code", sl.Err(err))
+
+			return models.DeviceCode{}, ErrInvalidUserCode
+		}
+
+		log.Error("failed to decide on device code", sl.Err(err))
+
+		return models.DeviceCode{}, err
+	}
+
+	log.Info("device code decided", slog.Int("app_id", int(code.AppID)), slog.String("status", status))
+
+	return code, nil
+}
+
+// newUserCode returns random user code in normalized form
+func newUserCode() (string, error) {
+	size := big.NewInt(int64(len(userCodeAlphabet)))
+
+	code := make([]byte, userCodeLen)
+	for i := range code {
+		n, err := rand.Int(rand.Reader, size)
+		if err != nil {
+			return "", err
+		}
+
+		code[i] = userCodeAlphabet[n.Int64()]
+	}
+
+	return string(code), nil
+}
+
+// normalizeUserCode drops dashes and spaces the user may type and converts the code to upper case
+func normalizeUserCode(userCode string) string {
+	return strings.ToUpper(strings.NewReplacer("-", "", " ", "").Replace(userCode))
+}
@@ -0,0 +1,113 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"strings"
+
+	"github.com/nhassl3/sso-app/internals/domain/models"
+	"github.com/nhassl3/sso-app/internals/lib/ldap"
+	"github.com/nhassl3/sso-app/internals/lib/logger/sl"
+)
+
+// directoryProvider is the provider of the identities of the directory users
+const directoryProvider = "ldap"
+
+// Directory authenticates the users by the LDAP directory
+type Directory interface {
+	Authenticate(ctx context.Context, login, password string) (entry models.DirectoryEntry, err error)
+}
+
+type RoleSyncer interface {
+	SyncUserRoles(ctx context.Context, userID int64, managed []models.AppRole, granted []models.AppRole) error
+}
+
+// GroupRole grants the role in the application to the members of the directory group
+type GroupRole struct {
+	Group string // DN of the group
+	AppID int32
+	Role  string
+}
+
+// authenticateDirectory authenticates the user by the directory. The directory user signs in as the local user
+// linked to the entry, the user is created by the first sign in or linked to the local user with the email
+// of the entry. Roles of the groups of the entry replace the roles the groups grant.
+//
+// Returns ErrUnknownIdentity if the directory has no such user
+func (a *Auth) authenticateDirectory(ctx context.Context, log *slog.Logger, login, password string) (models.User, error) {
+	entry, err := a.directory.Authenticate(ctx, login, password)
+	if err != nil {
+		switch {
+		case errors.Is(err, ldap.ErrUserNotFound):
+			return models.User{}, ErrUnknownIdentity
+		case errors.Is(err, ldap.ErrInvalidCredentials):
+			log.Info("invalid credentials of the directory user", sl.Err(err))
+
+			return models.User{}, ErrInvalidCredentials
+		}
+
+		// Directory users must not fall back to stale passwords of the database while the directory is unavailable
+		log.Error("failed to authenticate by the directory", sl.Err(err))
+
+		return models.User{}, err
+	}
+
+	log = log.With(slog.String("dn", entry.DN))
+
+	// Directory is the authority on its users, so their emails are trusted
+	userID, err := a.federatedUser(ctx, log, models.ExternalIdentity{
+		Provider:      directoryProvider,
+		Subject:       entry.Subject,
+		Email:         entry.Email,
+		EmailVerified: true,
+	}, true)
+	if err != nil {
+		if errors.Is(err, ErrIdentityWithoutEmail) || errors.Is(err, ErrIdentityConflict) {
+			return models.User{}, ErrInvalidCredentials
+		}
+
+		return models.User{}, err
+	}
+
+	if err := a.roleSyncer.SyncUserRoles(ctx, userID, a.managedRoles(), a.grantedRoles(entry.Groups)); err != nil {
+		log.Error("failed to sync roles of the directory groups", sl.Err(err))
+
+		return models.User{}, err
+	}
+
+	user, err := a.userProvider.UserByID(ctx, userID)
+	if err != nil {
+		log.Error("failed to get user", sl.Err(err))
+
+		return models.User{}, err
+	}
+
+	return user, nil
+}
+
+// managedRoles returns roles granted by the directory groups
+func (a *Auth) managedRoles() []models.AppRole {
+	roles := make([]models.AppRole, 0, len(a.groupRoles))
+	for _, groupRole := range a.groupRoles {
+		roles = append(roles, models.AppRole{AppID: groupRole.AppID, Role: groupRole.Role})
+	}
+
+	return roles
+}
+
+// grantedRoles returns roles granted by the groups, DNs are compared case-insensitively
+func (a *Auth) grantedRoles(groups []string) []models.AppRole {
+	var roles []models.AppRole
+
+	for _, groupRole := range a.groupRoles {
+		for _, group := range groups {
+			if strings.EqualFold(group, groupRole.Group) {
+				roles = append(roles, models.AppRole{AppID: groupRole.AppID, Role: groupRole.Role})
+				break
+			}
+		}
+	}
+
+	return roles
+}
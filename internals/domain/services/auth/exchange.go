@@ -0,0 +1,284 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/nhassl3/sso-app/internals/domain/models"
+	njwt "github.com/nhassl3/sso-app/internals/lib/jwt"
+	"github.com/nhassl3/sso-app/internals/lib/logger/sl"
+	"github.com/nhassl3/sso-app/internals/storage"
+)
+
+const (
+	opExchangeToken = "auth.ExchangeToken"
+)
+
+var (
+	ErrInvalidSubjectToken = errors.New("subject token is invalid, expired or can't be exchanged")
+	ErrInvalidTarget       = errors.New("audience of the token exchange is unknown or disabled")
+	ErrImpersonationDenied = errors.New("admin rights are required to impersonate the user")
+)
+
+// ExchangeToken exchanges token of the user for the token of RFC 8693.
+//
+// With requested subject it is impersonation: subject token must be token of the admin of the audience
+// issued for the admin console, new token is token of the requested user with act claim of the admin, its lifetime is capped
+// by the impersonation TTL and it is recorded in the audit log. Only super-admin can impersonate super-admin.
+//
+// Without requested subject the client which token of the user was issued to gets token of the same user
+// for the audience, possibly limited to the scope. New token doesn't outlive the subject token
+func (a *Auth) ExchangeToken(ctx context.Context, req models.TokenExchangeRequest) (token models.OAuthToken, err error) {
+	log := a.log.With(
+		slog.String("op", opExchangeToken),
+		slog.Int("audience", int(req.Audience)),
+		slog.Int64("requested_subject", req.RequestedSubject),
+	)
+
+	subject, err := a.Introspect(ctx, req.SubjectToken)
+	if err != nil {
+		return models.OAuthToken{}, sl.ErrUpLevel(opExchangeToken, err)
+	}
+
+	// Tokens of the applications have no user to exchange
+	if !subject.Active || subject.UserID == 0 {
+		log.Warn("subject token is not active or has no user")
+
+		return models.OAuthToken{}, sl.ErrUpLevel(opExchangeToken, ErrInvalidSubjectToken)
+	}
+
+	audience := req.Audience
+	if audience == 0 {
+		audience = subject.AppID
+	}
+
+	app, err := a.enabledApp(ctx, log, audience)
+	if err != nil {
+		if errors.Is(err, ErrInvalidAppID) || errors.Is(err, ErrAppDisabled) {
+			return models.OAuthToken{}, sl.ErrUpLevel(opExchangeToken, ErrInvalidTarget)
+		}
+
+		return models.OAuthToken{}, sl.ErrUpLevel(opExchangeToken, err)
+	}
+
+	var (
+		user    models.User
+		actorID int64
+		ttl     time.Duration
+	)
+
+	if req.RequestedSubject != 0 {
+		// Impersonation has no client, so the audience decides if its tokens are exchanged
+		if !app.AllowsGrant(models.GrantTypeExchange) {
+			log.Warn("token exchange is not allowed to the audience")
+
+			return models.OAuthToken{}, sl.ErrUpLevel(opExchangeToken, ErrUnauthorizedClient)
+		}
+
+		user, err = a.impersonatedUser(ctx, log, subject, req.RequestedSubject, audience)
+		if err != nil {
+			return models.OAuthToken{}, sl.ErrUpLevel(opExchangeToken, err)
+		}
+
+		actorID = subject.UserID
+		ttl = min(a.accessTokenTTL(app), a.impersonationTTL)
+	} else {
+		client, err := a.authenticateClient(ctx, log, req.ClientID, req.ClientSecret)
+		if err != nil {
+			return models.OAuthToken{}, sl.ErrUpLevel(opExchangeToken, err)
+		}
+
+		if !client.AllowsGrant(models.GrantTypeExchange) {
+			log.Warn("token exchange is not allowed to the client", slog.Int("client_id", int(req.ClientID)))
+
+			return models.OAuthToken{}, sl.ErrUpLevel(opExchangeToken, ErrUnauthorizedClient)
+		}
+
+		if req.ClientID != subject.AppID {
+			log.Warn("subject token is issued to another client", slog.Int("client_id", int(req.ClientID)))
+
+			return models.OAuthToken{}, sl.ErrUpLevel(opExchangeToken, ErrInvalidSubjectToken)
+		}
+
+		user, err = a.activeUser(ctx, subject.UserID)
+		if err != nil {
+			if errors.Is(err, storage.ErrUserNotFound) {
+				log.Warn("user of the subject token not found", sl.Err(err))
+
+				return models.OAuthToken{}, sl.ErrUpLevel(opExchangeToken, ErrInvalidSubjectToken)
+			}
+
+			log.Error("failed to get user", sl.Err(err))
+
+			return models.OAuthToken{}, sl.ErrUpLevel(opExchangeToken, err)
+		}
+
+		// Impersonation goes on with the same actor
+		actorID = subject.ActorID
+		ttl = min(a.accessTokenTTL(app), time.Until(subject.ExpiresAt))
+	}
+
+	scopes := strings.Fields(req.Scope)
+
+	// Limited token can only be limited further
+	if len(subject.ScopeLimit) > 0 && req.RequestedSubject == 0 {
+		if len(scopes) == 0 {
+			scopes = subject.ScopeLimit
+		}
+
+		for _, s := range scopes {
+			if !slices.Contains(subject.ScopeLimit, s) {
+				log.Warn("scope is out of the limit of the subject token", slog.String("scope", s))
+
+				return models.OAuthToken{}, sl.ErrUpLevel(opExchangeToken, ErrInvalidScope)
+			}
+		}
+	}
+
+	roles, err := a.roleProvider.UserRoles(ctx, user.ID, audience)
+	if err != nil {
+		log.Error("failed to get user roles", sl.Err(err))
+
+		return models.OAuthToken{}, sl.ErrUpLevel(opExchangeToken, err)
+	}
+
+	if len(scopes) > 0 {
+		granted := njwt.RoleScopes(roles)
+		for _, s := range scopes {
+			if !slices.Contains(granted, s) {
+				log.Warn("scope is not granted to the user", slog.String("scope", s))
+
+				return models.OAuthToken{}, sl.ErrUpLevel(opExchangeToken, ErrInvalidScope)
+			}
+		}
+
+		roles = limitRoles(roles, scopes)
+	}
+
+	accessToken, err := njwt.NewExchangedToken(user, app, roles, ttl, actorID, strings.Join(scopes, " "))
+	if err != nil {
+		log.Error("failed to generate token", sl.Err(err))
+
+		return models.OAuthToken{}, sl.ErrUpLevel(opExchangeToken, err)
+	}
+
+	if err := a.saveSession(ctx, log, user.ID, app, time.Now().Add(ttl)); err != nil {
+		return models.OAuthToken{}, sl.ErrUpLevel(opExchangeToken, err)
+	}
+
+	// Impersonation token is given out only if it is recorded
+	if req.RequestedSubject != 0 {
+		err := a.auditSaver.SaveAuditEvent(ctx, models.AuditEvent{
+			ActorID:      actorID,
+			Action:       models.AuditActionImpersonate,
+			TargetUserID: user.ID,
+			AppID:        audience,
+			Details:      fmt.Sprintf("expires in %s", ttl),
+		})
+		if err != nil {
+			log.Error("failed to save audit event", sl.Err(err))
+
+			return models.OAuthToken{}, sl.ErrUpLevel(opExchangeToken, err)
+		}
+
+		log.Info("user impersonated", slog.Int64("actor_id", actorID))
+	}
+
+	return models.OAuthToken{
+		AccessToken:     accessToken,
+		TokenType:       models.TokenTypeBearer,
+		ExpiresIn:       ttl,
+		Scope:           strings.Join(scopes, " "),
+		IssuedTokenType: models.TokenTypeAccessToken,
+	}, nil
+}
+
+// impersonatedUser returns the user the admin of the subject token may impersonate in the application
+func (a *Auth) impersonatedUser(
+	ctx context.Context,
+	log *slog.Logger,
+	subject models.TokenInfo,
+	userID int64,
+	appID int32,
+) (models.User, error) {
+	// Chains of the impersonations would hide the admin who started them
+	if subject.ActorID != 0 {
+		log.Warn("impersonation token can't impersonate")
+
+		return models.User{}, ErrInvalidSubjectToken
+	}
+
+	// Owners of the applications sign tokens of their applications, so only the console token proves the admin
+	if !subject.IsConsole(a.consoleAppID) {
+		log.Warn("subject token isn't issued for the admin console", slog.Int("subject_app_id", int(subject.AppID)))
+
+		return models.User{}, ErrImpersonationDenied
+	}
+
+	isAdmin, isSuperAdmin, err := a.userProvider.IsAppAdmin(ctx, subject.UserID, appID)
+	if err != nil {
+		log.Error("failed to check admin rights", sl.Err(err))
+
+		return models.User{}, err
+	}
+
+	if !isAdmin {
+		log.Warn("caller is not admin of the application", slog.Int64("actor_id", subject.UserID))
+
+		return models.User{}, ErrImpersonationDenied
+	}
+
+	user, err := a.activeUser(ctx, userID)
+	if err != nil {
+		if errors.Is(err, storage.ErrUserNotFound) {
+			log.Warn("impersonated user not found", sl.Err(err))
+
+			return models.User{}, ErrInvalidUserID
+		}
+
+		log.Error("failed to get user", sl.Err(err))
+
+		return models.User{}, err
+	}
+
+	// Admin rights of the impersonated user would be used with the token, so app admin can't impersonate other admins
+	if !isSuperAdmin {
+		isTargetAdmin, err := a.userProvider.HasAdminRights(ctx, user.ID)
+		if err != nil {
+			log.Error("failed to check admin rights", sl.Err(err))
+
+			return models.User{}, err
+		}
+
+		if isTargetAdmin {
+			log.Warn("app admin can't impersonate admin", slog.Int64("actor_id", subject.UserID))
+
+			return models.User{}, ErrImpersonationDenied
+		}
+	}
+
+	return user, nil
+}
+
+// limitRoles returns roles with only scopes of the limit
+func limitRoles(roles []models.Role, limit []string) []models.Role {
+	limited := make([]models.Role, 0, len(roles))
+	for _, role := range roles {
+		scopes := make([]string, 0, len(role.Scopes))
+		for _, scope := range role.Scopes {
+			if slices.Contains(limit, scope) {
+				scopes = append(scopes, scope)
+			}
+		}
+
+		role.Scopes = scopes
+		limited = append(limited, role)
+	}
+
+	return limited
+}
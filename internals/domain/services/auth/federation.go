@@ -0,0 +1,343 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"time"
+
+	"github.com/nhassl3/sso-app/internals/domain/models"
+	"github.com/nhassl3/sso-app/internals/lib/logger/sl"
+	"github.com/nhassl3/sso-app/internals/lib/random"
+	"github.com/nhassl3/sso-app/internals/storage"
+	"golang.org/x/crypto/bcrypt"
+)
+
+const (
+	opStartFederatedLogin  = "auth.StartFederatedLogin"
+	opFinishFederatedLogin = "auth.FinishFederatedLogin"
+
+	federationStateSize = 32
+	nonceSize           = 32
+	verifierSize        = 32 // 43 characters of the verifier as RFC 7636 requires at least
+	unusablePasswordLen = 32
+
+	federationPath = "/federation/" // path of the federated login pages of the providers
+)
+
+var (
+	ErrUnknownProvider        = errors.New("identity provider is unknown")
+	ErrInvalidFederationState = errors.New("state of the federated login is invalid, used or expired")
+	ErrUpstreamLogin          = errors.New("sign in with the identity provider failed")
+	ErrIdentityConflict       = errors.New("user with the email of the identity already exists")
+	ErrIdentityWithoutEmail   = errors.New("identity provider didn't assert email of the user")
+)
+
+type FederationSaver interface {
+	SaveFederationState(ctx context.Context, stateHash string, state models.FederationState) error
+	UseFederationState(ctx context.Context, stateHash string) (state models.FederationState, err error)
+	FederatedIdentity(ctx context.Context, provider, subject string) (userID int64, err error)
+	LinkIdentity(ctx context.Context, identity models.ExternalIdentity, userID int64) error
+	SaveFederatedUser(ctx context.Context, identity models.ExternalIdentity, hashPassword []byte) (userID int64, err error)
+}
+
+// UpstreamProvider is the OpenID Connect provider the users sign in with instead of the password
+type UpstreamProvider interface {
+	Name() string
+	AuthCodeURL(ctx context.Context, redirectURI, state, nonce, codeVerifier string) (string, error)
+	Exchange(
+		ctx context.Context,
+		code string,
+		redirectURI string,
+		codeVerifier string,
+		nonce string,
+	) (identity models.ExternalIdentity, err error)
+}
+
+// FederatedProvider is the upstream provider with the policy of linking its identities to the users
+type FederatedProvider struct {
+	Provider UpstreamProvider
+	// TrustEmail links new identity with verified email to the existing user with the email,
+	// otherwise such identity is rejected, so the provider can't take over accounts of the users
+	TrustEmail bool
+}
+
+// FederationProviders returns names of the upstream providers in the order of the config
+func (a *Auth) FederationProviders() []string {
+	names := make([]string, 0, len(a.providers))
+	for _, provider := range a.providers {
+		names = append(names, provider.Provider.Name())
+	}
+
+	return names
+}
+
+// StartFederatedLogin checks the authorization request of the client and returns URL of the provider
+// the user signs in at. The request waits for the callback of the provider under the random state,
+// which is sent to the provider together with nonce of its ID token and PKCE challenge of its code.
+//
+// Redirect URI is returned as Authorize does, errors returned with it must be sent to the client
+func (a *Auth) StartFederatedLogin(
+	ctx context.Context,
+	providerName string,
+	req models.AuthorizeRequest,
+	clientState string,
+) (authURL string, state string, redirectURI string, err error) {
+	log := a.log.With(
+		slog.String("op", opStartFederatedLogin),
+		slog.String("provider", providerName),
+		slog.Int("app_id", int(req.AppID)),
+	)
+
+	provider, ok := a.provider(providerName)
+	if !ok {
+		log.Warn("unknown identity provider")
+
+		return "", "", "", sl.ErrUpLevel(opStartFederatedLogin, ErrUnknownProvider)
+	}
+
+	_, redirectURI, err = a.checkAuthorizeRequest(ctx, log, req)
+	if err != nil {
+		return "", "", redirectURI, sl.ErrUpLevel(opStartFederatedLogin, err)
+	}
+
+	// Consent is asked by the hosted pages, federated login never carries the decision
+	req.Consent = ""
+
+	federation := models.FederationState{
+		Provider:    providerName,
+		Request:     req,
+		ClientState: clientState,
+		ExpiresAt:   time.Now().Add(a.federationStateTTL),
+	}
+
+	state, err = random.String(federationStateSize)
+	if err == nil {
+		federation.Nonce, err = random.String(nonceSize)
+	}
+	if err == nil {
+		federation.CodeVerifier, err = random.String(verifierSize)
+	}
+	if err != nil {
+		log.Error("failed to generate federation state", sl.Err(err))
+
+		return "", "", redirectURI, sl.ErrUpLevel(opStartFederatedLogin, err)
+	}
+
+	authURL, err = provider.Provider.AuthCodeURL(
+		ctx, a.federationCallbackURI(providerName), state, federation.Nonce, federation.CodeVerifier,
+	)
+	if err != nil {
+		log.Error("failed to build authorization url of the provider", sl.Err(err))
+
+		return "", "", redirectURI, sl.ErrUpLevel(opStartFederatedLogin, ErrUpstreamLogin)
+	}
+
+	if err := a.federationSaver.SaveFederationState(ctx, hashCode(state), federation); err != nil {
+		log.Error("failed to save federation state", sl.Err(err))
+
+		return "", "", redirectURI, sl.ErrUpLevel(opStartFederatedLogin, err)
+	}
+
+	log.Info("federated login started")
+
+	return
+}
+
+// FinishFederatedLogin handles the callback of the provider. Code of the provider is exchanged for the identity
+// of the user, which is linked to the local user, and authorization code of the original request is issued.
+// State is used once. New identity creates the user with its email, or is linked to the existing user
+// with the email if the provider is trusted and the email is verified.
+//
+// Redirect URI and state of the client are returned as soon as the state is checked,
+// errors returned with them must be sent to the client
+func (a *Auth) FinishFederatedLogin(
+	ctx context.Context,
+	providerName string,
+	state string,
+	code string,
+	upstreamError string,
+) (authCode string, redirectURI string, clientState string, err error) {
+	log := a.log.With(slog.String("op", opFinishFederatedLogin), slog.String("provider", providerName))
+
+	provider, ok := a.provider(providerName)
+	if !ok {
+		log.Warn("unknown identity provider")
+
+		return "", "", "", sl.ErrUpLevel(opFinishFederatedLogin, ErrUnknownProvider)
+	}
+
+	federation, err := a.federationSaver.UseFederationState(ctx, hashCode(state))
+	if err != nil {
+		if errors.Is(err, storage.ErrFederationStateNotFound) {
+			log.Warn("unknown or used federation state")
+
+			return "", "", "", sl.ErrUpLevel(opFinishFederatedLogin, ErrInvalidFederationState)
+		}
+
+		log.Error("failed to use federation state", sl.Err(err))
+
+		return "", "", "", sl.ErrUpLevel(opFinishFederatedLogin, err)
+	}
+
+	if federation.Provider != providerName || time.Now().After(federation.ExpiresAt) {
+		log.Warn("federation state is expired or issued to another provider", slog.String("state_provider", federation.Provider))
+
+		return "", "", "", sl.ErrUpLevel(opFinishFederatedLogin, ErrInvalidFederationState)
+	}
+
+	req := federation.Request
+	log = log.With(slog.Int("app_id", int(req.AppID)))
+
+	// Client may be changed while the user signs in at the provider
+	app, redirectURI, err := a.checkAuthorizeRequest(ctx, log, req)
+	if err != nil {
+		return "", redirectURI, federation.ClientState, sl.ErrUpLevel(opFinishFederatedLogin, err)
+	}
+	clientState = federation.ClientState
+
+	if upstreamError != "" || code == "" {
+		log.Warn("provider returned no code", slog.String("error", upstreamError))
+
+		return "", redirectURI, clientState, sl.ErrUpLevel(opFinishFederatedLogin, ErrUpstreamLogin)
+	}
+
+	identity, err := provider.Provider.Exchange(
+		ctx, code, a.federationCallbackURI(providerName), federation.CodeVerifier, federation.Nonce,
+	)
+	if err != nil {
+		log.Warn("failed to exchange code of the provider", sl.Err(err))
+
+		return "", redirectURI, clientState, sl.ErrUpLevel(opFinishFederatedLogin, ErrUpstreamLogin)
+	}
+
+	userID, err := a.federatedUser(ctx, log, identity, provider.TrustEmail)
+	if err != nil {
+		return "", redirectURI, clientState, sl.ErrUpLevel(opFinishFederatedLogin, err)
+	}
+
+	authCode, err = a.issueAuthCode(ctx, log, app, req, userID)
+	if err != nil {
+		return "", redirectURI, clientState, sl.ErrUpLevel(opFinishFederatedLogin, err)
+	}
+
+	return
+}
+
+// federatedUser returns ID of the user linked to the identity, new identity is linked to the new user
+// or to the existing user with its email if the email is trusted
+func (a *Auth) federatedUser(
+	ctx context.Context,
+	log *slog.Logger,
+	identity models.ExternalIdentity,
+	trustEmail bool,
+) (int64, error) {
+	log = log.With(slog.String("subject", identity.Subject))
+
+	userID, err := a.federationSaver.FederatedIdentity(ctx, identity.Provider, identity.Subject)
+	if err == nil {
+		return userID, nil
+	}
+	if !errors.Is(err, storage.ErrIdentityNotFound) {
+		log.Error("failed to get federated identity", sl.Err(err))
+
+		return 0, err
+	}
+
+	if identity.Email == "" {
+		log.Warn("identity has no email")
+
+		return 0, ErrIdentityWithoutEmail
+	}
+
+	user, err := a.userProvider.User(ctx, identity.Email)
+	switch {
+	case err == nil:
+		if !trustEmail || !identity.EmailVerified {
+			log.Warn("identity conflicts with existing user", slog.Bool("email_verified", identity.EmailVerified))
+
+			return 0, ErrIdentityConflict
+		}
+
+		if err := a.federationSaver.LinkIdentity(ctx, identity, user.ID); err != nil {
+			return a.linkedConcurrently(ctx, log, identity, err)
+		}
+
+		log.Info("identity linked to existing user", slog.Int64("user_id", user.ID))
+
+		return user.ID, nil
+	case !errors.Is(err, storage.ErrUserNotFound):
+		log.Error("failed to get user", sl.Err(err))
+
+		return 0, err
+	}
+
+	// User of the identity signs in only by the provider, the password is unknown to anybody
+	password, err := random.String(unusablePasswordLen)
+	if err != nil {
+		log.Error("failed to generate password", sl.Err(err))
+
+		return 0, err
+	}
+
+	passHash, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+	if err != nil {
+		log.Error("failed to generate password hash", sl.Err(err))
+
+		return 0, err
+	}
+
+	userID, err = a.federationSaver.SaveFederatedUser(ctx, identity, passHash)
+	if err != nil {
+		if errors.Is(err, storage.ErrUserExists) {
+			log.Warn("user with the email is created concurrently", sl.Err(err))
+
+			return 0, ErrIdentityConflict
+		}
+
+		return a.linkedConcurrently(ctx, log, identity, err)
+	}
+
+	log.Info("user of the identity created", slog.Int64("user_id", userID))
+
+	return userID, nil
+}
+
+// linkedConcurrently returns the user the identity is linked to by the concurrent callback if linking failed because of it
+func (a *Auth) linkedConcurrently(
+	ctx context.Context,
+	log *slog.Logger,
+	identity models.ExternalIdentity,
+	linkErr error,
+) (int64, error) {
+	if !errors.Is(linkErr, storage.ErrIdentityExists) {
+		log.Error("failed to link identity", sl.Err(linkErr))
+
+		return 0, linkErr
+	}
+
+	userID, err := a.federationSaver.FederatedIdentity(ctx, identity.Provider, identity.Subject)
+	if err != nil {
+		log.Error("failed to get federated identity", sl.Err(err))
+
+		return 0, err
+	}
+
+	return userID, nil
+}
+
+// provider returns the upstream provider by its name
+func (a *Auth) provider(name string) (FederatedProvider, bool) {
+	for _, provider := range a.providers {
+		if provider.Provider.Name() == name {
+			return provider, true
+		}
+	}
+
+	return FederatedProvider{}, false
+}
+
+// federationCallbackURI returns URI the provider redirects the user back to, it is registered at the provider
+func (a *Auth) federationCallbackURI(providerName string) string {
+	return a.issuer + federationPath + providerName + "/callback"
+}
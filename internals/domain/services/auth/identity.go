@@ -0,0 +1,206 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+
+	"github.com/nhassl3/sso-app/internals/domain/models"
+	"github.com/nhassl3/sso-app/internals/lib/logger/sl"
+	"github.com/nhassl3/sso-app/internals/lib/oidc"
+	"github.com/nhassl3/sso-app/internals/storage"
+	"golang.org/x/crypto/bcrypt"
+)
+
+// Kinds of the steps of the identity chain
+const (
+	IdentityLocal = "local" // password saved in the database
+	IdentityLDAP  = "ldap"  // bind to the directory
+	IdentityOIDC  = "oidc"  // password grant of the upstream provider
+)
+
+// ErrUnknownIdentity is returned by the identity provider which doesn't know the user, so the next step is asked
+var ErrUnknownIdentity = errors.New("user is unknown to the identity provider")
+
+// IdentityProvider authenticates the users by email and password as the step of the identity chain.
+// The provider returns ErrUnknownIdentity to pass the user to the next step, other errors reject the user
+type IdentityProvider interface {
+	Authenticate(ctx context.Context, log *slog.Logger, email, password string) (user models.User, err error)
+}
+
+// PasswordProvider is the upstream provider which checks passwords of its users itself
+type PasswordProvider interface {
+	PasswordGrant(ctx context.Context, username, password string) (identity models.ExternalIdentity, err error)
+}
+
+// IdentityStep configures the step of the identity chain
+type IdentityStep struct {
+	Kind     string // IdentityLocal, IdentityLDAP or IdentityOIDC
+	Provider string // name of the upstream provider of IdentityOIDC step
+	// Fallthrough passes the user with invalid credentials to the next step instead of rejecting,
+	// so the user may have another password in the next provider
+	Fallthrough bool
+}
+
+// identityStep is the step of the chain with its provider
+type identityStep struct {
+	name         string // recorded as the provider authenticated the user
+	provider     IdentityProvider
+	passRejected bool
+}
+
+// identityFunc is the function used as IdentityProvider
+type identityFunc func(ctx context.Context, log *slog.Logger, email, password string) (models.User, error)
+
+func (f identityFunc) Authenticate(ctx context.Context, log *slog.Logger, email, password string) (models.User, error) {
+	return f(ctx, log, email, password)
+}
+
+// identityChain returns the steps of the chain with their providers. Step of the missing provider
+// rejects every user, so misconfigured chain never lets the user in past it
+func (a *Auth) identityChain(steps []IdentityStep) []identityStep {
+	chain := make([]identityStep, 0, len(steps))
+
+	for _, step := range steps {
+		resolved := identityStep{name: step.Kind, passRejected: step.Fallthrough}
+
+		switch step.Kind {
+		case IdentityLocal:
+			resolved.provider = identityFunc(a.authenticateLocal)
+		case IdentityLDAP:
+			if a.directory == nil {
+				resolved.provider = unavailableIdentity(errors.New("directory isn't configured"))
+				break
+			}
+
+			resolved.provider = identityFunc(a.authenticateDirectory)
+		case IdentityOIDC:
+			resolved.name = step.Provider
+			resolved.provider = a.upstreamIdentity(step.Provider)
+		default:
+			resolved.provider = unavailableIdentity(fmt.Errorf("unknown kind %q of the identity provider", step.Kind))
+		}
+
+		chain = append(chain, resolved)
+	}
+
+	return chain
+}
+
+// authenticate passes the credentials through the identity chain in its order and returns the user
+// with the name of the step which authenticated the user
+func (a *Auth) authenticate(
+	ctx context.Context,
+	log *slog.Logger,
+	email string,
+	password string,
+) (models.Authentication, error) {
+	for _, step := range a.chain {
+		stepLog := log.With(slog.String("identity_provider", step.name))
+
+		user, err := step.provider.Authenticate(ctx, stepLog, email, password)
+		switch {
+		case err == nil && user.Disabled:
+			stepLog.Warn("deactivated user rejected", slog.Int64("user_id", user.ID))
+
+			return models.Authentication{}, ErrInvalidCredentials
+		case err == nil:
+			stepLog.Info("user authenticated", slog.Int64("user_id", user.ID))
+
+			return models.Authentication{User: user, IdentityProvider: step.name}, nil
+		case errors.Is(err, ErrUnknownIdentity):
+			continue
+		case errors.Is(err, ErrInvalidCredentials) && step.passRejected:
+			stepLog.Debug("invalid credentials passed to the next identity provider")
+
+			continue
+		}
+
+		return models.Authentication{}, err
+	}
+
+	log.Warn("no identity provider authenticated the user")
+
+	return models.Authentication{}, ErrInvalidCredentials
+}
+
+// authenticateLocal authenticates the user by the password saved in the database
+func (a *Auth) authenticateLocal(ctx context.Context, log *slog.Logger, email, password string) (models.User, error) {
+	user, err := a.userProvider.User(ctx, email)
+	if err != nil {
+		if errors.Is(err, storage.ErrUserNotFound) {
+			log.Warn("failed to found user in the system", sl.Err(err))
+
+			return models.User{}, ErrUnknownIdentity
+		}
+
+		log.Error("failed to get user", sl.Err(err))
+
+		return models.User{}, err
+	}
+
+	if err := bcrypt.CompareHashAndPassword(user.HashPassword, []byte(password)); err != nil {
+		log.Info(ErrInvalidCredentials.Error(), sl.Err(err))
+
+		return models.User{}, ErrInvalidCredentials
+	}
+
+	return user, nil
+}
+
+// upstreamIdentity returns provider authenticating the users by the password grant of the upstream provider.
+// The identity signs in as the local user linked to it, as by the federated login
+func (a *Auth) upstreamIdentity(name string) IdentityProvider {
+	provider, ok := a.provider(name)
+	if !ok {
+		return unavailableIdentity(fmt.Errorf("%w: %s", ErrUnknownProvider, name))
+	}
+
+	passwords, ok := provider.Provider.(PasswordProvider)
+	if !ok {
+		return unavailableIdentity(fmt.Errorf("provider %s doesn't support password grant", name))
+	}
+
+	return identityFunc(func(ctx context.Context, log *slog.Logger, email, password string) (models.User, error) {
+		identity, err := passwords.PasswordGrant(ctx, email, password)
+		if err != nil {
+			if errors.Is(err, oidc.ErrInvalidGrant) {
+				log.Info("provider rejected credentials of the user", sl.Err(err))
+
+				return models.User{}, ErrInvalidCredentials
+			}
+
+			log.Error("failed to authenticate by the provider", sl.Err(err))
+
+			return models.User{}, err
+		}
+
+		userID, err := a.federatedUser(ctx, log, identity, provider.TrustEmail)
+		if err != nil {
+			if errors.Is(err, ErrIdentityWithoutEmail) || errors.Is(err, ErrIdentityConflict) {
+				return models.User{}, ErrInvalidCredentials
+			}
+
+			return models.User{}, err
+		}
+
+		user, err := a.userProvider.UserByID(ctx, userID)
+		if err != nil {
+			log.Error("failed to get user", sl.Err(err))
+
+			return models.User{}, err
+		}
+
+		return user, nil
+	})
+}
+
+// unavailableIdentity returns provider failing with the error
+func unavailableIdentity(err error) IdentityProvider {
+	return identityFunc(func(_ context.Context, log *slog.Logger, _, _ string) (models.User, error) {
+		log.Error("identity provider is unavailable", sl.Err(err))
+
+		return models.User{}, err
+	})
+}
@@ -0,0 +1,482 @@
+package auth
+
+import (
+	"context"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"log/slog"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/nhassl3/sso-app/internals/domain/models"
+	njwt "github.com/nhassl3/sso-app/internals/lib/jwt"
+	"github.com/nhassl3/sso-app/internals/lib/logger/sl"
+	"github.com/nhassl3/sso-app/internals/lib/random"
+	"github.com/nhassl3/sso-app/internals/storage"
+	"golang.org/x/crypto/bcrypt"
+)
+
+const (
+	opAuthorize         = "auth.Authorize"
+	opAuthorizeUser     = "auth.AuthorizeUser"
+	opValidateAuthorize = "auth.ValidateAuthorizeRequest"
+	opExchangeCode      = "auth.ExchangeCode"
+	opClientToken       = "auth.ClientCredentials"
+	opClientApp         = "auth.ClientApp"
+
+	codeSize = 32
+
+	// Verifier of RFC 7636 is 43-128 characters, S256 challenge is always 43 characters
+	minVerifierLen = 43
+	maxVerifierLen = 128
+	challengeLen   = 43
+)
+
+var (
+	ErrInvalidRedirectURI      = errors.New("redirect URI is not registered by the application")
+	ErrUnsupportedResponseType = errors.New("unsupported response type")
+	ErrInvalidPKCE             = errors.New("PKCE code challenge with S256 method is required")
+	ErrInvalidGrant            = errors.New("authorization code is invalid, expired or issued to another client")
+	ErrInvalidClient           = errors.New("invalid client credentials")
+	ErrInvalidScope            = errors.New("scope is not granted to the client")
+	ErrUnauthorizedClient      = errors.New("grant type is not allowed to the client")
+)
+
+type CodeSaver interface {
+	SaveAuthCode(ctx context.Context, codeHash string, code models.AuthCode) error
+	UseAuthCode(ctx context.Context, codeHash string) (code models.AuthCode, err error)
+}
+
+// Authorize authenticates the user and issues authorization code of the user for the OAuth client.
+//
+// Third-party application gets the code only if consent of the user covers the scope, the decision
+// of the request is saved if it doesn't.
+//
+// Redirect URI is returned as soon as it is checked against URIs registered by the application,
+// errors returned with it must be sent to the client by the redirect. Errors without it
+// (unknown or disabled application, unregistered redirect URI) must be shown to the user.
+// ID of the user is returned as soon as the user is authenticated, so the user asked for consent
+// is authorized by AuthorizeUser without the password
+func (a *Auth) Authorize(
+	ctx context.Context,
+	req models.AuthorizeRequest,
+	email string,
+	password string,
+) (code string, userID int64, redirectURI string, err error) {
+	log := a.log.With(slog.String("op", opAuthorize), slog.Int("app_id", int(req.AppID)))
+
+	app, redirectURI, err := a.checkAuthorizeRequest(ctx, log, req)
+	if err != nil {
+		return "", 0, redirectURI, sl.ErrUpLevel(opAuthorize, err)
+	}
+
+	if err := a.checkRealm(log, email, req.AppID); err != nil {
+		return "", 0, redirectURI, sl.ErrUpLevel(opAuthorize, err)
+	}
+
+	authn, err := a.authenticate(ctx, log, email, password)
+	if err != nil {
+		return "", 0, redirectURI, sl.ErrUpLevel(opAuthorize, err)
+	}
+	userID = authn.User.ID
+
+	code, err = a.issueAuthCode(ctx, log, app, req, userID)
+	if err != nil {
+		return "", userID, redirectURI, sl.ErrUpLevel(opAuthorize, err)
+	}
+
+	return
+}
+
+// AuthorizeUser issues authorization code of the user authenticated by Authorize before,
+// the consent page authorizes the request with the decision of the user by it.
+// Deactivated user is rejected as invalid credentials, errors are returned as by Authorize
+func (a *Auth) AuthorizeUser(
+	ctx context.Context,
+	req models.AuthorizeRequest,
+	userID int64,
+) (code string, redirectURI string, err error) {
+	log := a.log.With(
+		slog.String("op", opAuthorizeUser),
+		slog.Int("app_id", int(req.AppID)),
+		slog.Int64("user_id", userID),
+	)
+
+	app, redirectURI, err := a.checkAuthorizeRequest(ctx, log, req)
+	if err != nil {
+		return "", redirectURI, sl.ErrUpLevel(opAuthorizeUser, err)
+	}
+
+	if _, err := a.activeUser(ctx, userID); err != nil {
+		if errors.Is(err, storage.ErrUserNotFound) {
+			log.Warn("user not found or deactivated")
+
+			return "", redirectURI, sl.ErrUpLevel(opAuthorizeUser, ErrInvalidCredentials)
+		}
+
+		log.Error("failed to get user", sl.Err(err))
+
+		return "", redirectURI, sl.ErrUpLevel(opAuthorizeUser, err)
+	}
+
+	code, err = a.issueAuthCode(ctx, log, app, req, userID)
+	if err != nil {
+		return "", redirectURI, sl.ErrUpLevel(opAuthorizeUser, err)
+	}
+
+	return
+}
+
+// ValidateAuthorizeRequest checks the authorization request before the user signs in on the login page.
+// Redirect URI and errors are returned as by Authorize
+func (a *Auth) ValidateAuthorizeRequest(ctx context.Context, req models.AuthorizeRequest) (redirectURI string, err error) {
+	log := a.log.With(slog.String("op", opValidateAuthorize), slog.Int("app_id", int(req.AppID)))
+
+	_, redirectURI, err = a.checkAuthorizeRequest(ctx, log, req)
+	if err != nil {
+		return redirectURI, sl.ErrUpLevel(opValidateAuthorize, err)
+	}
+
+	return redirectURI, nil
+}
+
+// checkAuthorizeRequest checks the authorization request against settings of the client and returns
+// the client with the redirect URI, which is empty if the error can't be sent to the client
+func (a *Auth) checkAuthorizeRequest(
+	ctx context.Context,
+	log *slog.Logger,
+	req models.AuthorizeRequest,
+) (models.App, string, error) {
+	app, err := a.enabledApp(ctx, log, req.AppID)
+	if err != nil {
+		return models.App{}, "", err
+	}
+
+	redirectURI, err := resolveRedirectURI(app, req.RedirectURI)
+	if err != nil {
+		log.Warn("invalid redirect uri", slog.String("redirect_uri", req.RedirectURI))
+
+		return models.App{}, "", err
+	}
+
+	if req.ResponseType != models.ResponseTypeCode {
+		log.Warn("unsupported response type", slog.String("response_type", req.ResponseType))
+
+		return models.App{}, redirectURI, ErrUnsupportedResponseType
+	}
+
+	if !app.AllowsGrant(models.GrantTypeAuthCode) {
+		log.Warn("authorization code grant is not allowed to the client")
+
+		return models.App{}, redirectURI, ErrUnauthorizedClient
+	}
+
+	if !app.AllowsScopes(strings.Fields(req.Scope)) {
+		log.Warn("scope is not allowed to the client", slog.String("scope", req.Scope))
+
+		return models.App{}, redirectURI, ErrInvalidScope
+	}
+
+	// Plain method gives nothing against interception of the code, so only S256 is allowed
+	if req.CodeChallengeMethod != models.PKCEMethodS256 || len(req.CodeChallenge) != challengeLen {
+		log.Warn("invalid code challenge", slog.String("method", req.CodeChallengeMethod))
+
+		return models.App{}, redirectURI, ErrInvalidPKCE
+	}
+
+	return app, redirectURI, nil
+}
+
+// issueAuthCode issues authorization code of the authenticated user if consent of the user covers the scope
+func (a *Auth) issueAuthCode(
+	ctx context.Context,
+	log *slog.Logger,
+	app models.App,
+	req models.AuthorizeRequest,
+	userID int64,
+) (string, error) {
+	authTime := time.Now()
+
+	if err := a.checkConsent(ctx, log, app, userID, strings.Fields(req.Scope), req.Consent); err != nil {
+		return "", err
+	}
+
+	code, err := random.String(codeSize)
+	if err != nil {
+		log.Error("failed to generate code", sl.Err(err))
+
+		return "", err
+	}
+
+	err = a.codeSaver.SaveAuthCode(ctx, hashCode(code), models.AuthCode{
+		AppID:         req.AppID,
+		UserID:        userID,
+		RedirectURI:   req.RedirectURI,
+		Scope:         req.Scope,
+		CodeChallenge: req.CodeChallenge,
+		Nonce:         req.Nonce,
+		AuthTime:      authTime,
+		ExpiresAt:     authTime.Add(a.codeTTL),
+	})
+	if err != nil {
+		log.Error("failed to save code", sl.Err(err))
+
+		return "", err
+	}
+
+	log.Info("authorization code issued", slog.Int64("user_id", userID))
+
+	return code, nil
+}
+
+// ClientApp returns the application of the OAuth client if it exists and is not disabled,
+// so the hosted pages show its name and theme
+func (a *Auth) ClientApp(ctx context.Context, appID int32) (models.App, error) {
+	log := a.log.With(slog.String("op", opClientApp), slog.Int("app_id", int(appID)))
+
+	app, err := a.enabledApp(ctx, log, appID)
+	if err != nil {
+		return models.App{}, sl.ErrUpLevel(opClientApp, err)
+	}
+
+	return app, nil
+}
+
+// ExchangeCode exchanges authorization code for the token of the user issued as by Login,
+// ID token is issued too if openid scope is granted and refresh token if the application allows them.
+// Redirect URI must be the same as in the authorization request and code verifier must match its code challenge.
+// Confidential client authenticates by the client secret. Code is used once even if the exchange fails
+func (a *Auth) ExchangeCode(
+	ctx context.Context,
+	appID int32,
+	clientSecret string,
+	code string,
+	redirectURI string,
+	codeVerifier string,
+) (token models.OAuthToken, err error) {
+	log := a.log.With(slog.String("op", opExchangeCode), slog.Int("app_id", int(appID)))
+
+	app, err := a.oauthClient(ctx, log, appID, clientSecret, models.GrantTypeAuthCode)
+	if err != nil {
+		return models.OAuthToken{}, sl.ErrUpLevel(opExchangeCode, err)
+	}
+
+	authCode, err := a.codeSaver.UseAuthCode(ctx, hashCode(code))
+	if err != nil {
+		if errors.Is(err, storage.ErrAuthCodeNotFound) {
+			log.Warn("unknown or used code", sl.Err(err))
+
+			return models.OAuthToken{}, sl.ErrUpLevel(opExchangeCode, ErrInvalidGrant)
+		}
+
+		log.Error("failed to use code", sl.Err(err))
+
+		return models.OAuthToken{}, sl.ErrUpLevel(opExchangeCode, err)
+	}
+
+	switch {
+	case time.Now().After(authCode.ExpiresAt):
+		log.Warn("code is expired")
+
+		return models.OAuthToken{}, sl.ErrUpLevel(opExchangeCode, ErrInvalidGrant)
+	case authCode.AppID != appID || authCode.RedirectURI != redirectURI:
+		log.Warn("code is issued to another client or redirect uri", slog.Int("code_app_id", int(authCode.AppID)))
+
+		return models.OAuthToken{}, sl.ErrUpLevel(opExchangeCode, ErrInvalidGrant)
+	case !verifyPKCE(authCode.CodeChallenge, codeVerifier):
+		log.Warn("code verifier doesn't match code challenge")
+
+		return models.OAuthToken{}, sl.ErrUpLevel(opExchangeCode, ErrInvalidGrant)
+	}
+
+	user, err := a.activeUser(ctx, authCode.UserID)
+	if err != nil {
+		if errors.Is(err, storage.ErrUserNotFound) {
+			log.Warn("user of the code not found", sl.Err(err))
+
+			return models.OAuthToken{}, sl.ErrUpLevel(opExchangeCode, ErrInvalidGrant)
+		}
+
+		log.Error("failed to get user", sl.Err(err))
+
+		return models.OAuthToken{}, sl.ErrUpLevel(opExchangeCode, err)
+	}
+
+	token = models.OAuthToken{
+		TokenType: models.TokenTypeBearer,
+		ExpiresIn: a.accessTokenTTL(app),
+		Scope:     authCode.Scope,
+	}
+
+	token.AccessToken, err = a.issueToken(ctx, log, user, app, authCode.Scope, "")
+	if err != nil {
+		return models.OAuthToken{}, sl.ErrUpLevel(opExchangeCode, err)
+	}
+
+	if slices.Contains(strings.Fields(authCode.Scope), models.ScopeOpenID) {
+		token.IDToken, err = a.issueIDToken(user, authCode, token.ExpiresIn)
+		if err != nil {
+			log.Error("failed to generate id token", sl.Err(err))
+
+			return models.OAuthToken{}, sl.ErrUpLevel(opExchangeCode, err)
+		}
+	}
+
+	token.RefreshToken, err = a.issueRefreshToken(ctx, log, app, user.ID, authCode.Scope)
+	if err != nil {
+		return models.OAuthToken{}, sl.ErrUpLevel(opExchangeCode, err)
+	}
+
+	return
+}
+
+// ClientCredentials authenticates the application by its OAuth client secret and issues token of the application
+// without user. Requested scope must be a subset of the scopes granted to the client, empty scope requests all of them
+func (a *Auth) ClientCredentials(
+	ctx context.Context,
+	appID int32,
+	clientSecret string,
+	scope string,
+) (token models.OAuthToken, err error) {
+	log := a.log.With(slog.String("op", opClientToken), slog.Int("app_id", int(appID)))
+
+	app, err := a.authenticateClient(ctx, log, appID, clientSecret)
+	if err != nil {
+		return models.OAuthToken{}, sl.ErrUpLevel(opClientToken, err)
+	}
+
+	if !app.AllowsGrant(models.GrantTypeClient) {
+		log.Warn("client credentials grant is not allowed to the client")
+
+		return models.OAuthToken{}, sl.ErrUpLevel(opClientToken, ErrUnauthorizedClient)
+	}
+
+	scopes := strings.Fields(scope)
+	if len(scopes) == 0 {
+		scopes = app.ClientScopes
+	}
+
+	for _, s := range scopes {
+		if !slices.Contains(app.ClientScopes, s) {
+			log.Warn("scope is not granted to the client", slog.String("scope", s))
+
+			return models.OAuthToken{}, sl.ErrUpLevel(opClientToken, ErrInvalidScope)
+		}
+	}
+
+	accessToken, err := njwt.NewClientToken(app, scopes, a.accessTokenTTL(app))
+	if err != nil {
+		log.Error("failed to generate token", sl.Err(err))
+
+		return models.OAuthToken{}, sl.ErrUpLevel(opClientToken, err)
+	}
+
+	log.Info("client token issued", slog.Any("scopes", scopes))
+
+	return models.OAuthToken{
+		AccessToken: accessToken,
+		TokenType:   models.TokenTypeBearer,
+		ExpiresIn:   a.accessTokenTTL(app),
+		Scope:       strings.Join(scopes, " "),
+	}, nil
+}
+
+// authenticateClient returns enabled application with the OAuth client secret
+func (a *Auth) authenticateClient(ctx context.Context, log *slog.Logger, appID int32, clientSecret string) (models.App, error) {
+	app, err := a.enabledApp(ctx, log, appID)
+	if err != nil {
+		return models.App{}, err
+	}
+
+	if err := verifyClientSecret(log, app, clientSecret); err != nil {
+		return models.App{}, err
+	}
+
+	return app, nil
+}
+
+// oauthClient returns enabled application of the OAuth client which may use the grant type.
+// Public client may omit the client secret, but the given secret is always verified
+func (a *Auth) oauthClient(
+	ctx context.Context,
+	log *slog.Logger,
+	appID int32,
+	clientSecret string,
+	grantType string,
+) (models.App, error) {
+	app, err := a.enabledApp(ctx, log, appID)
+	if err != nil {
+		return models.App{}, err
+	}
+
+	if clientSecret != "" || !app.PublicClient {
+		if err := verifyClientSecret(log, app, clientSecret); err != nil {
+			return models.App{}, err
+		}
+	}
+
+	if !app.AllowsGrant(grantType) {
+		log.Warn("grant type is not allowed to the client", slog.String("grant_type", grantType))
+
+		return models.App{}, ErrUnauthorizedClient
+	}
+
+	return app, nil
+}
+
+// verifyClientSecret checks the OAuth client secret of the application
+func verifyClientSecret(log *slog.Logger, app models.App, clientSecret string) error {
+	if app.ClientSecretHash == nil {
+		log.Warn("client has no secret")
+
+		return ErrInvalidClient
+	}
+
+	if err := bcrypt.CompareHashAndPassword(app.ClientSecretHash, []byte(clientSecret)); err != nil {
+		log.Info("invalid client secret", sl.Err(err))
+
+		return ErrInvalidClient
+	}
+
+	return nil
+}
+
+// resolveRedirectURI returns redirect URI of the request if the application registered it.
+// Empty URI of the request is resolved to the only registered URI
+func resolveRedirectURI(app models.App, redirectURI string) (string, error) {
+	if redirectURI == "" {
+		if len(app.RedirectURIs) == 1 {
+			return app.RedirectURIs[0], nil
+		}
+
+		return "", ErrInvalidRedirectURI
+	}
+
+	if !slices.Contains(app.RedirectURIs, redirectURI) {
+		return "", ErrInvalidRedirectURI
+	}
+
+	return redirectURI, nil
+}
+
+// verifyPKCE checks that S256 code challenge is made of the code verifier
+func verifyPKCE(challenge, verifier string) bool {
+	if len(verifier) < minVerifierLen || len(verifier) > maxVerifierLen {
+		return false
+	}
+
+	sum := sha256.Sum256([]byte(verifier))
+
+	return subtle.ConstantTimeCompare([]byte(base64.RawURLEncoding.EncodeToString(sum[:])), []byte(challenge)) == 1
+}
+
+// hashCode returns hash of the code to keep in the storage, leaked storage doesn't give usable codes
+func hashCode(code string) string {
+	sum := sha256.Sum256([]byte(code))
+
+	return hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,107 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/nhassl3/sso-app/internals/domain/models"
+	njwt "github.com/nhassl3/sso-app/internals/lib/jwt"
+	"github.com/nhassl3/sso-app/internals/lib/logger/sl"
+	"github.com/nhassl3/sso-app/internals/storage"
+)
+
+const (
+	opUserInfo = "auth.UserInfo"
+)
+
+var (
+	ErrInvalidToken      = errors.New("token is invalid or expired")
+	ErrInsufficientScope = errors.New("openid scope is not granted")
+)
+
+// Issuer returns issuer identifier of the OpenID Connect provider
+func (a *Auth) Issuer() string {
+	return a.issuer
+}
+
+// PublicKeys returns public keys which verify ID tokens
+func (a *Auth) PublicKeys() []njwt.JWK {
+	return []njwt.JWK{a.signingKey.JWK()}
+}
+
+// UserInfo returns claims about the user of the access token filtered by the scopes granted to the OAuth client.
+// Token must be issued by the authorization code flow with openid scope
+func (a *Auth) UserInfo(ctx context.Context, accessToken string) (claims map[string]any, err error) {
+	log := a.log.With(slog.String("op", opUserInfo))
+
+	info, err := a.Introspect(ctx, accessToken)
+	if err != nil {
+		return nil, sl.ErrUpLevel(opUserInfo, err)
+	}
+
+	// Tokens of the applications have no user
+	if !info.Active || info.UserID == 0 {
+		log.Debug("token is not active or has no user")
+
+		return nil, sl.ErrUpLevel(opUserInfo, ErrInvalidToken)
+	}
+
+	if !slices.Contains(info.Granted, models.ScopeOpenID) {
+		log.Warn("openid scope is not granted", slog.Int64("user_id", info.UserID))
+
+		return nil, sl.ErrUpLevel(opUserInfo, ErrInsufficientScope)
+	}
+
+	user, err := a.activeUser(ctx, info.UserID)
+	if err != nil {
+		if errors.Is(err, storage.ErrUserNotFound) {
+			log.Warn("user of the token not found", sl.Err(err))
+
+			return nil, sl.ErrUpLevel(opUserInfo, ErrInvalidToken)
+		}
+
+		log.Error("failed to get user", sl.Err(err))
+
+		return nil, sl.ErrUpLevel(opUserInfo, err)
+	}
+
+	return userClaims(user, info.Granted), nil
+}
+
+// issueIDToken issues ID token of the user for the client of the authorization code
+func (a *Auth) issueIDToken(user models.User, code models.AuthCode, ttl time.Duration) (string, error) {
+	claims := userClaims(user, strings.Fields(code.Scope))
+	claims["iss"] = a.issuer
+	claims["aud"] = strconv.Itoa(int(code.AppID))
+	claims["auth_time"] = code.AuthTime.Unix()
+
+	if code.Nonce != "" {
+		claims["nonce"] = code.Nonce
+	}
+
+	return a.signingKey.NewIDToken(claims, ttl)
+}
+
+// userClaims returns standard claims about the user which are allowed by the scopes.
+// Users have only email, so profile scope gives it as preferred username
+func userClaims(user models.User, scopes []string) map[string]any {
+	claims := map[string]any{
+		"sub": strconv.FormatInt(user.ID, 10),
+	}
+
+	if slices.Contains(scopes, models.ScopeEmail) {
+		claims["email"] = user.Email
+		claims["email_verified"] = false
+	}
+
+	if slices.Contains(scopes, models.ScopeProfile) {
+		claims["preferred_username"] = user.Email
+	}
+
+	return claims
+}
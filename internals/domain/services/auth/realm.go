@@ -0,0 +1,79 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"strings"
+
+	"github.com/nhassl3/sso-app/internals/domain/models"
+)
+
+const opDiscoverRealm = "auth.DiscoverRealm"
+
+var ErrFederatedRealm = errors.New("users of the email domain must sign in with their identity provider")
+
+// RealmRule sends the users of the email domain to the upstream provider, they can't sign in by password
+type RealmRule struct {
+	Domain   string // lowercase domain of the emails
+	AppID    int32  // application the rule applies to, zero for all applications
+	Provider string // name of the upstream provider
+}
+
+// DiscoverRealm returns the upstream provider the user with the email must sign in with to the application
+// and URI of the federated login at it. Rule of the application takes precedence over the rule of all applications.
+// Empty provider means the user signs in by password
+func (a *Auth) DiscoverRealm(_ context.Context, email string, appID int32) (realm models.Realm, err error) {
+	log := a.log.With(slog.String("op", opDiscoverRealm), slog.Int("app_id", int(appID)))
+
+	provider := a.realmProvider(email, appID)
+	if provider == "" {
+		return models.Realm{}, nil
+	}
+
+	log.Debug("email domain is routed to the provider", slog.String("provider", provider))
+
+	return models.Realm{
+		Provider: provider,
+		LoginURI: a.issuer + federationPath + provider + "/login",
+	}, nil
+}
+
+// checkRealm rejects password of the user whose email domain is routed to the upstream provider
+func (a *Auth) checkRealm(log *slog.Logger, email string, appID int32) error {
+	if provider := a.realmProvider(email, appID); provider != "" {
+		log.Warn("password sign in of the federated realm", slog.String("provider", provider))
+
+		return ErrFederatedRealm
+	}
+
+	return nil
+}
+
+// realmProvider returns the provider of the rule matching domain of the email, empty if there is no rule.
+// Domains are compared case-insensitively
+func (a *Auth) realmProvider(email string, appID int32) string {
+	at := strings.LastIndexByte(email, '@')
+	if at < 0 {
+		return ""
+	}
+
+	domain := strings.ToLower(email[at+1:])
+
+	var global string
+	for _, rule := range a.realms {
+		if rule.Domain != domain {
+			continue
+		}
+
+		if rule.AppID != 0 && rule.AppID == appID {
+			return rule.Provider
+		}
+
+		if rule.AppID == 0 {
+			global = rule.Provider
+		}
+	}
+
+	return global
+}
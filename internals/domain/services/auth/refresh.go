@@ -0,0 +1,161 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/nhassl3/sso-app/internals/domain/models"
+	"github.com/nhassl3/sso-app/internals/lib/logger/sl"
+	"github.com/nhassl3/sso-app/internals/lib/random"
+	"github.com/nhassl3/sso-app/internals/storage"
+)
+
+const (
+	opRefreshToken = "auth.RefreshToken"
+)
+
+type RefreshTokenSaver interface {
+	SaveRefreshToken(ctx context.Context, tokenHash string, token models.RefreshToken) error
+	UseRefreshToken(ctx context.Context, tokenHash string) (token models.RefreshToken, err error)
+}
+
+// RefreshToken exchanges refresh token for the new access token of the user and rotates the refresh token:
+// used token is deleted and the new one with the same scope is issued. Requested scope must be a subset
+// of the scope of the refresh token, empty scope requests all of it. Confidential client authenticates by the client secret
+func (a *Auth) RefreshToken(
+	ctx context.Context,
+	appID int32,
+	clientSecret string,
+	refreshToken string,
+	scope string,
+) (token models.OAuthToken, err error) {
+	log := a.log.With(slog.String("op", opRefreshToken), slog.Int("app_id", int(appID)))
+
+	app, err := a.oauthClient(ctx, log, appID, clientSecret, models.GrantTypeRefresh)
+	if err != nil {
+		return models.OAuthToken{}, sl.ErrUpLevel(opRefreshToken, err)
+	}
+
+	stored, err := a.refreshTokenSaver.UseRefreshToken(ctx, hashCode(refreshToken))
+	if err != nil {
+		if errors.Is(err, storage.ErrRefreshTokenNotFound) {
+			log.Warn("unknown or used refresh token", sl.Err(err))
+
+			return models.OAuthToken{}, sl.ErrUpLevel(opRefreshToken, ErrInvalidGrant)
+		}
+
+		log.Error("failed to use refresh token", sl.Err(err))
+
+		return models.OAuthToken{}, sl.ErrUpLevel(opRefreshToken, err)
+	}
+
+	switch {
+	case !time.Now().Before(stored.ExpiresAt):
+		log.Warn("refresh token is expired")
+
+		return models.OAuthToken{}, sl.ErrUpLevel(opRefreshToken, ErrInvalidGrant)
+	case stored.AppID != appID:
+		log.Warn("refresh token is issued to another client", slog.Int("token_app_id", int(stored.AppID)))
+
+		return models.OAuthToken{}, sl.ErrUpLevel(opRefreshToken, ErrInvalidGrant)
+	case app.RefreshTokenTTL <= 0:
+		log.Warn("refresh tokens are disabled for the client")
+
+		return models.OAuthToken{}, sl.ErrUpLevel(opRefreshToken, ErrUnauthorizedClient)
+	}
+
+	original := strings.Fields(stored.Scope)
+
+	scopes := strings.Fields(scope)
+	if len(scopes) == 0 {
+		scopes = original
+	}
+
+	for _, s := range scopes {
+		if !slices.Contains(original, s) {
+			log.Warn("scope is out of the refresh token", slog.String("scope", s))
+
+			return models.OAuthToken{}, sl.ErrUpLevel(opRefreshToken, ErrInvalidScope)
+		}
+	}
+
+	user, err := a.activeUser(ctx, stored.UserID)
+	if err != nil {
+		if errors.Is(err, storage.ErrUserNotFound) {
+			log.Warn("user of the refresh token not found", sl.Err(err))
+
+			return models.OAuthToken{}, sl.ErrUpLevel(opRefreshToken, ErrInvalidGrant)
+		}
+
+		log.Error("failed to get user", sl.Err(err))
+
+		return models.OAuthToken{}, sl.ErrUpLevel(opRefreshToken, err)
+	}
+
+	token = models.OAuthToken{
+		TokenType: models.TokenTypeBearer,
+		ExpiresIn: a.accessTokenTTL(app),
+		Scope:     strings.Join(scopes, " "),
+	}
+
+	token.AccessToken, err = a.issueToken(ctx, log, user, app, token.Scope, "")
+	if err != nil {
+		return models.OAuthToken{}, sl.ErrUpLevel(opRefreshToken, err)
+	}
+
+	// Rotated token keeps the original scope, so narrowed access token doesn't narrow the next refresh
+	token.RefreshToken, err = a.issueRefreshToken(ctx, log, app, user.ID, stored.Scope)
+	if err != nil {
+		return models.OAuthToken{}, sl.ErrUpLevel(opRefreshToken, err)
+	}
+
+	log.Info("token refreshed", slog.Int64("user_id", user.ID))
+
+	return
+}
+
+// issueRefreshToken issues refresh token of the user for the application with the scope.
+// Returns empty token if the application doesn't allow refresh tokens
+func (a *Auth) issueRefreshToken(
+	ctx context.Context,
+	log *slog.Logger,
+	app models.App,
+	userID int64,
+	scope string,
+) (string, error) {
+	if app.RefreshTokenTTL <= 0 || !app.AllowsGrant(models.GrantTypeRefresh) {
+		return "", nil
+	}
+
+	refreshToken, err := random.String(codeSize)
+	if err != nil {
+		log.Error("failed to generate refresh token", sl.Err(err))
+
+		return "", err
+	}
+
+	expiresAt := time.Now().Add(app.RefreshTokenTTL)
+
+	err = a.refreshTokenSaver.SaveRefreshToken(ctx, hashCode(refreshToken), models.RefreshToken{
+		AppID:     int32(app.ID),
+		UserID:    userID,
+		Scope:     scope,
+		ExpiresAt: expiresAt,
+	})
+	if err != nil {
+		log.Error("failed to save refresh token", sl.Err(err))
+
+		return "", err
+	}
+
+	// Application may get new access tokens until the refresh token expires
+	if err := a.saveSession(ctx, log, userID, app, expiresAt); err != nil {
+		return "", err
+	}
+
+	return refreshToken, nil
+}
@@ -0,0 +1,121 @@
+package events
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"time"
+
+	"github.com/nhassl3/sso-app/internals/domain/models"
+	"github.com/nhassl3/sso-app/internals/lib/logger/sl"
+)
+
+const (
+	opRelayEvents = "events.RelayEvents"
+
+	relayBatchSize          = 100
+	maxPublishErrorLen      = 200
+	maxRetryBackoffDoubling = 16
+)
+
+// Relay publishes the events of the outbox saved with the changes they describe.
+// Event is published until every publisher accepts it, so publishers may get the same event more than once
+// and events published after a failure may go out of order
+type Relay struct {
+	log          *slog.Logger
+	outbox       Outbox
+	publishers   []EventPublisher
+	retryBackoff time.Duration
+	retention    time.Duration
+}
+
+// NewRelay returns a new instance of the Relay service
+func NewRelay(
+	log *slog.Logger,
+	outbox Outbox,
+	publishers []EventPublisher,
+	retryBackoff time.Duration,
+	retention time.Duration,
+) *Relay {
+	return &Relay{
+		log:          log,
+		outbox:       outbox,
+		publishers:   publishers,
+		retryBackoff: retryBackoff,
+		retention:    retention,
+	}
+}
+
+type Outbox interface {
+	PendingEvents(ctx context.Context, now time.Time, limit int) (events []models.OutboxEvent, err error)
+	UpdateOutboxEvent(ctx context.Context, event models.OutboxEvent) error
+	DeletePublishedEvents(ctx context.Context, before time.Time) (deleted int, err error)
+}
+
+// EventPublisher sends the event to the consumers, the event may be sent again if any publisher fails
+type EventPublisher interface {
+	Publish(ctx context.Context, event models.Event) error
+}
+
+// RelayEvents publishes due events of the outbox to all publishers, failed events are retried with
+// exponential backoff. Published events are deleted after the retention. Returns count of the published events
+func (r *Relay) RelayEvents(ctx context.Context) (published int, err error) {
+	log := r.log.With(slog.String("op", opRelayEvents))
+
+	now := time.Now()
+
+	events, err := r.outbox.PendingEvents(ctx, now, relayBatchSize)
+	if err != nil {
+		log.Error("failed to get pending events", sl.Err(err))
+
+		return 0, sl.ErrUpLevel(opRelayEvents, err)
+	}
+
+	for _, event := range events {
+		event.Attempts++
+
+		if err := r.publish(ctx, event.Event); err != nil {
+			log.Warn(
+				"failed to publish event",
+				slog.String("event_id", event.Event.ID), slog.String("type", event.Event.Type),
+				slog.Int("attempts", event.Attempts), sl.Err(err),
+			)
+
+			event.LastError = err.Error()
+			if len(event.LastError) > maxPublishErrorLen {
+				event.LastError = event.LastError[:maxPublishErrorLen]
+			}
+			event.NextAttemptAt = now.Add(r.retryBackoff << min(event.Attempts-1, maxRetryBackoffDoubling))
+		} else {
+			event.LastError = ""
+			event.PublishedAt = now
+			published++
+		}
+
+		if err := r.outbox.UpdateOutboxEvent(ctx, event); err != nil {
+			log.Error("failed to save event", slog.String("event_id", event.Event.ID), sl.Err(err))
+
+			return published, sl.ErrUpLevel(opRelayEvents, err)
+		}
+	}
+
+	if _, err := r.outbox.DeletePublishedEvents(ctx, now.Add(-r.retention)); err != nil {
+		log.Error("failed to delete published events", sl.Err(err))
+
+		return published, sl.ErrUpLevel(opRelayEvents, err)
+	}
+
+	return published, nil
+}
+
+// publish sends the event to every publisher, failure of one publisher doesn't stop the others
+func (r *Relay) publish(ctx context.Context, event models.Event) error {
+	var errs []error
+	for _, publisher := range r.publishers {
+		if err := publisher.Publish(ctx, event); err != nil {
+			errs = append(errs, err)
+		}
+	}
+
+	return errors.Join(errs...)
+}
@@ -0,0 +1,73 @@
+package events
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"sync"
+
+	"github.com/nhassl3/sso-app/internals/domain/models"
+)
+
+// LogPublisher writes the events to the log
+type LogPublisher struct {
+	log *slog.Logger
+}
+
+// NewLogPublisher returns a new instance of the LogPublisher
+func NewLogPublisher(log *slog.Logger) *LogPublisher {
+	return &LogPublisher{log: log}
+}
+
+// Publish logs the event
+func (p *LogPublisher) Publish(ctx context.Context, event models.Event) error {
+	p.log.InfoContext(
+		ctx,
+		"domain event",
+		slog.String("event_id", event.ID),
+		slog.String("type", event.Type),
+		slog.Int64("actor_id", event.ActorID),
+		slog.Int64("user_id", event.UserID),
+		slog.Int("app_id", int(event.AppID)),
+	)
+
+	return nil
+}
+
+// Handler consumes the event of the bus, returned error makes the relay publish the event again
+type Handler func(ctx context.Context, event models.Event) error
+
+// Bus passes the events to the handlers of the same process
+type Bus struct {
+	mu       sync.RWMutex
+	handlers []Handler
+}
+
+// NewBus returns a new instance of the Bus without handlers
+func NewBus() *Bus {
+	return &Bus{}
+}
+
+// Subscribe adds the handler of all events of the bus
+func (b *Bus) Subscribe(handler Handler) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.handlers = append(b.handlers, handler)
+}
+
+// Publish passes the event to every handler in the order of subscription
+func (b *Bus) Publish(ctx context.Context, event models.Event) error {
+	b.mu.RLock()
+	handlers := b.handlers
+	b.mu.RUnlock()
+
+	var errs []error
+	for _, handler := range handlers {
+		if err := handler(ctx, event); err != nil {
+			errs = append(errs, err)
+		}
+	}
+
+	return errors.Join(errs...)
+}
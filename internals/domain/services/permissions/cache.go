@@ -0,0 +1,164 @@
+package permissions
+
+import (
+	"sync"
+	"time"
+
+	"github.com/nhassl3/sso-app/internals/domain/models"
+)
+
+// decisionCache remembers results of the checks of every application.
+// Results are valid for one revision of the tuples and one generation of the application,
+// generation is increased by every invalidation, so results computed before it are not saved
+type decisionCache struct {
+	mu      sync.Mutex
+	ttl     time.Duration
+	maxSize int
+	size    int
+	apps    map[int32]*appDecisions
+}
+
+type appDecisions struct {
+	generation uint64
+	revision   int64
+	entries    map[models.RelationTuple]cachedDecision
+	stats      models.DecisionCacheStats
+}
+
+type cachedDecision struct {
+	allowed   bool
+	expiresAt time.Time
+}
+
+// newDecisionCache returns cache of maxSize decisions at most which live for ttl, zero maxSize disables caching
+func newDecisionCache(ttl time.Duration, maxSize int) *decisionCache {
+	return &decisionCache{
+		ttl:     ttl,
+		maxSize: maxSize,
+		apps:    make(map[int32]*appDecisions),
+	}
+}
+
+// get returns cached result of the check made at least at minRevision.
+// Generation returned on miss must be passed to put with computed result
+func (c *decisionCache) get(appID int32, check models.RelationTuple, minRevision int64) (
+	allowed bool,
+	revision int64,
+	generation uint64,
+	ok bool,
+) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	app := c.app(appID)
+
+	entry, found := app.entries[check]
+	if found && time.Now().Before(entry.expiresAt) && app.revision >= minRevision {
+		app.stats.Hits++
+
+		return entry.allowed, app.revision, app.generation, true
+	}
+
+	if found {
+		delete(app.entries, check)
+		c.size--
+	}
+
+	app.stats.Misses++
+
+	return false, 0, app.generation, false
+}
+
+// put saves result of the check computed at the revision unless the application was invalidated since get
+func (c *decisionCache) put(appID int32, generation uint64, revision int64, check models.RelationTuple, allowed bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.maxSize <= 0 {
+		return
+	}
+
+	app := c.app(appID)
+	if app.generation != generation || revision < app.revision {
+		return
+	}
+
+	if revision > app.revision {
+		c.clear(app)
+		app.revision = revision
+	}
+
+	if _, found := app.entries[check]; !found {
+		if c.size >= c.maxSize {
+			c.evict()
+		}
+		c.size++
+	}
+
+	app.entries[check] = cachedDecision{allowed: allowed, expiresAt: time.Now().Add(c.ttl)}
+}
+
+// invalidate drops cached results of the application, revision is the revision of the tuples after the change
+func (c *decisionCache) invalidate(appID int32, revision int64) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	app := c.app(appID)
+	app.generation++
+	app.stats.Invalidations++
+	c.clear(app)
+
+	if revision > app.revision {
+		app.revision = revision
+	}
+}
+
+// drop forgets the application with its revision and stats, IDs of the deleted applications are reused
+func (c *decisionCache) drop(appID int32) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if app, ok := c.apps[appID]; ok {
+		c.clear(app)
+		delete(c.apps, appID)
+	}
+}
+
+func (c *decisionCache) stats(appID int32) models.DecisionCacheStats {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	app := c.app(appID)
+
+	stats := app.stats
+	stats.Size = len(app.entries)
+
+	return stats
+}
+
+func (c *decisionCache) app(appID int32) *appDecisions {
+	app, ok := c.apps[appID]
+	if !ok {
+		app = &appDecisions{entries: make(map[models.RelationTuple]cachedDecision)}
+		c.apps[appID] = app
+	}
+
+	return app
+}
+
+func (c *decisionCache) clear(app *appDecisions) {
+	c.size -= len(app.entries)
+	app.entries = make(map[models.RelationTuple]cachedDecision)
+}
+
+// evict drops one arbitrary decision, order of the maps iteration is random
+func (c *decisionCache) evict() {
+	for _, app := range c.apps {
+		for check := range app.entries {
+			delete(app.entries, check)
+			c.size--
+
+			return
+		}
+	}
+}
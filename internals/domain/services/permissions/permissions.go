@@ -0,0 +1,524 @@
+package permissions
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"time"
+
+	"github.com/nhassl3/sso-app/internals/domain/models"
+	"github.com/nhassl3/sso-app/internals/lib/logger/sl"
+	"github.com/nhassl3/sso-app/internals/storage"
+)
+
+const (
+	opWriteNamespaceConfig = "permissions.WriteNamespaceConfig"
+	opNamespaceConfig      = "permissions.NamespaceConfig"
+	opWriteTuples          = "permissions.WriteTuples"
+	opCheck                = "permissions.Check"
+	opBatchCheck           = "permissions.BatchCheck"
+	opCacheStats           = "permissions.CacheStats"
+	opExpand               = "permissions.Expand"
+	opListObjects          = "permissions.ListObjects"
+)
+
+var (
+	ErrInvalidAppID     = errors.New("invalid application ID")
+	ErrInvalidUserID    = errors.New("invalid user ID")
+	ErrPermissionDenied = errors.New("permission denied")
+	ErrUnknownRelation  = errors.New("relation is not defined in namespace config")
+	ErrStaleRevision    = errors.New("requested revision is not reached yet")
+	ErrDepthExceeded    = errors.New("max depth of relations exceeded")
+)
+
+type Permissions struct {
+	log               *slog.Logger
+	tupleSaver        TupleSaver
+	tupleProvider     TupleProvider
+	namespaceSaver    NamespaceSaver
+	namespaceProvider NamespaceProvider
+	adminProvider     AdminProvider
+	policySaver       PolicySaver
+	policyProvider    PolicyProvider
+	userProvider      UserProvider
+	cache             *decisionCache
+	decisionsKept     int // latest policy decisions of the application kept for dry runs, older ones are deleted
+}
+
+// Deps are storages and settings of the Permissions service
+type Deps struct {
+	TupleSaver        TupleSaver
+	TupleProvider     TupleProvider
+	NamespaceSaver    NamespaceSaver
+	NamespaceProvider NamespaceProvider
+	AdminProvider     AdminProvider
+	PolicySaver       PolicySaver
+	PolicyProvider    PolicyProvider
+	UserProvider      UserProvider
+
+	CacheTTL      time.Duration
+	CacheSize     int // 0 disables the decision cache
+	DecisionsKept int // latest policy decisions of the application kept for dry runs
+}
+
+// NewPermissions returns a new instance of the Permissions service
+func NewPermissions(log *slog.Logger, deps Deps) *Permissions {
+	return &Permissions{
+		log:               log,
+		tupleSaver:        deps.TupleSaver,
+		tupleProvider:     deps.TupleProvider,
+		namespaceSaver:    deps.NamespaceSaver,
+		namespaceProvider: deps.NamespaceProvider,
+		adminProvider:     deps.AdminProvider,
+		policySaver:       deps.PolicySaver,
+		policyProvider:    deps.PolicyProvider,
+		userProvider:      deps.UserProvider,
+		cache:             newDecisionCache(deps.CacheTTL, deps.CacheSize),
+		decisionsKept:     deps.DecisionsKept,
+	}
+}
+
+type TupleSaver interface {
+	WriteTuples(ctx context.Context, appID int32, writes, deletes []models.RelationTuple) (revision int64, err error)
+}
+
+type TupleProvider interface {
+	ReadTuples(ctx context.Context, appID int32, read func(reader storage.TupleReader) error) (revision int64, err error)
+}
+
+type NamespaceSaver interface {
+	SaveNamespaceConfig(ctx context.Context, appID int32, config []byte) error
+}
+
+type NamespaceProvider interface {
+	NamespaceConfig(ctx context.Context, appID int32) (config []byte, err error)
+}
+
+type AdminProvider interface {
+	IsAppAdmin(ctx context.Context, userID int64, appID int32) (isAdmin bool, isSuperAdmin bool, err error)
+}
+
+// WriteNamespaceConfig replaces namespace configuration of the application.
+// Caller must be admin of the application
+func (p *Permissions) WriteNamespaceConfig(ctx context.Context, caller models.TokenInfo, appID int32, config models.NamespaceConfig) error {
+	log := p.log.With(slog.String("op", opWriteNamespaceConfig), slog.Int64("actor_id", caller.UserID))
+
+	if err := p.authorizeWrite(ctx, caller, appID); err != nil {
+		log.Warn("failed to authorize caller", sl.Err(err))
+
+		return sl.ErrUpLevel(opWriteNamespaceConfig, err)
+	}
+
+	if err := config.Validate(); err != nil {
+		log.Warn("invalid namespace config", sl.Err(err))
+
+		return sl.ErrUpLevel(opWriteNamespaceConfig, err)
+	}
+
+	raw, err := json.Marshal(config)
+	if err != nil {
+		log.Error("failed to marshal namespace config", sl.Err(err))
+
+		return sl.ErrUpLevel(opWriteNamespaceConfig, err)
+	}
+
+	if err := p.namespaceSaver.SaveNamespaceConfig(ctx, appID, raw); err != nil {
+		return sl.ErrUpLevel(opWriteNamespaceConfig, p.storageErr(log, err))
+	}
+
+	p.cache.invalidate(appID, 0)
+
+	log.Info("namespace config changed", slog.Int("app_id", int(appID)))
+
+	return nil
+}
+
+// NamespaceConfig returns namespace configuration of the application, empty if it is not written yet.
+// Caller must have token of the application or be its admin
+func (p *Permissions) NamespaceConfig(ctx context.Context, caller models.TokenInfo, appID int32) (config models.NamespaceConfig, err error) {
+	log := p.log.With(slog.String("op", opNamespaceConfig))
+
+	if err := p.authorizeRead(ctx, caller, appID); err != nil {
+		log.Warn("failed to authorize caller", sl.Err(err))
+
+		return models.NamespaceConfig{}, sl.ErrUpLevel(opNamespaceConfig, err)
+	}
+
+	config, err = p.namespaceConfig(ctx, appID)
+	if err != nil {
+		log.Error("failed to get namespace config", sl.Err(err))
+
+		return models.NamespaceConfig{}, sl.ErrUpLevel(opNamespaceConfig, err)
+	}
+
+	return
+}
+
+// WriteTuples writes and deletes relation tuples of the application atomically and returns revision after the change.
+// Relations of the tuples must be defined by namespace config if it is written. Caller must be admin of the application
+func (p *Permissions) WriteTuples(ctx context.Context, caller models.TokenInfo, appID int32, writes, deletes []models.RelationTuple) (revision int64, err error) {
+	log := p.log.With(slog.String("op", opWriteTuples), slog.Int64("actor_id", caller.UserID))
+
+	if err := p.authorizeWrite(ctx, caller, appID); err != nil {
+		log.Warn("failed to authorize caller", sl.Err(err))
+
+		return 0, sl.ErrUpLevel(opWriteTuples, err)
+	}
+
+	config, err := p.namespaceConfig(ctx, appID)
+	if err != nil {
+		log.Error("failed to get namespace config", sl.Err(err))
+
+		return 0, sl.ErrUpLevel(opWriteTuples, err)
+	}
+
+	for _, tuple := range writes {
+		if _, ok := config.Rewrite(tuple.Object.Type, tuple.Relation); !ok {
+			log.Warn("attempt to write tuple of unknown relation", slog.String("tuple", tuple.String()))
+
+			return 0, sl.ErrUpLevel(opWriteTuples, ErrUnknownRelation)
+		}
+	}
+
+	revision, err = p.tupleSaver.WriteTuples(ctx, appID, writes, deletes)
+	if err != nil {
+		return 0, sl.ErrUpLevel(opWriteTuples, p.storageErr(log, err))
+	}
+
+	p.cache.invalidate(appID, revision)
+
+	return
+}
+
+// Check reports whether the subject has the relation with the object following rewrites of the namespace config.
+// Tuples are read from one snapshot which revision is at least minRevision and is returned.
+// Caller must have token of the application or be its admin
+func (p *Permissions) Check(
+	ctx context.Context,
+	caller models.TokenInfo,
+	appID int32,
+	object models.Object,
+	relation string,
+	subject models.Subject,
+	minRevision int64,
+) (allowed bool, revision int64, err error) {
+	log := p.log.With(slog.String("op", opCheck))
+
+	if err := p.authorizeRead(ctx, caller, appID); err != nil {
+		return false, 0, sl.ErrUpLevel(opCheck, p.readErr(log, err))
+	}
+
+	check := models.RelationTuple{Object: object, Relation: relation, Subject: subject}
+
+	results, revision, err := p.check(ctx, appID, []models.RelationTuple{check}, minRevision)
+	if err != nil {
+		return false, 0, sl.ErrUpLevel(opCheck, p.readErr(log, err))
+	}
+
+	return results[0], revision, nil
+}
+
+// BatchCheck reports for every check whether its subject has its relation with its object.
+// Results are taken from the decision cache or computed on one snapshot of the tuples,
+// returned revision is the latest revision of the results which is at least minRevision.
+// Caller must have token of the application or be its admin
+func (p *Permissions) BatchCheck(
+	ctx context.Context,
+	caller models.TokenInfo,
+	appID int32,
+	checks []models.RelationTuple,
+	minRevision int64,
+) (results []bool, revision int64, err error) {
+	log := p.log.With(slog.String("op", opBatchCheck))
+
+	if err := p.authorizeRead(ctx, caller, appID); err != nil {
+		return nil, 0, sl.ErrUpLevel(opBatchCheck, p.readErr(log, err))
+	}
+
+	results, revision, err = p.check(ctx, appID, checks, minRevision)
+	if err != nil {
+		return nil, 0, sl.ErrUpLevel(opBatchCheck, p.readErr(log, err))
+	}
+
+	return
+}
+
+// CacheStats returns usage of the decision cache of the application.
+// Caller must have token of the application or be its admin
+func (p *Permissions) CacheStats(ctx context.Context, caller models.TokenInfo, appID int32) (models.DecisionCacheStats, error) {
+	log := p.log.With(slog.String("op", opCacheStats))
+
+	if err := p.authorizeRead(ctx, caller, appID); err != nil {
+		return models.DecisionCacheStats{}, sl.ErrUpLevel(opCacheStats, p.readErr(log, err))
+	}
+
+	return p.cache.stats(appID), nil
+}
+
+// InvalidateCache drops cached decisions of the application,
+// it must be called when data which the checks depend on is changed outside of the service
+func (p *Permissions) InvalidateCache(appID int32) {
+	p.cache.invalidate(appID, 0)
+}
+
+// DropCache forgets cached decisions and stats of the deleted application
+func (p *Permissions) DropCache(appID int32) {
+	p.cache.drop(appID)
+}
+
+// check answers the checks using the decision cache, missed ones are computed on one snapshot of the tuples
+func (p *Permissions) check(
+	ctx context.Context,
+	appID int32,
+	checks []models.RelationTuple,
+	minRevision int64,
+) (results []bool, revision int64, err error) {
+	results = make([]bool, len(checks))
+
+	var (
+		missed     []int
+		generation uint64
+	)
+
+	for i, check := range checks {
+		allowed, cachedRevision, checkGeneration, ok := p.cache.get(appID, check, minRevision)
+		if ok {
+			results[i] = allowed
+			revision = max(revision, cachedRevision)
+
+			continue
+		}
+
+		// Generation of the first miss is the oldest one, results are not cached if it is changed since
+		if len(missed) == 0 {
+			generation = checkGeneration
+		}
+		missed = append(missed, i)
+	}
+
+	if len(missed) == 0 {
+		return results, revision, nil
+	}
+
+	config, err := p.namespaceConfig(ctx, appID)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	readRevision, err := p.tupleProvider.ReadTuples(ctx, appID, func(reader storage.TupleReader) error {
+		// Checks of one subject share answers about common parents and groups
+		checkers := make(map[models.Subject]*checker)
+
+		for _, i := range missed {
+			check := checks[i]
+
+			c, ok := checkers[check.Subject]
+			if !ok {
+				c = newChecker(config, reader, check.Subject)
+				checkers[check.Subject] = c
+			}
+
+			allowed, err := c.check(check.Object, check.Relation, 0)
+			if err != nil {
+				return err
+			}
+
+			results[i] = allowed
+		}
+
+		return nil
+	})
+	if err == nil && readRevision < minRevision {
+		err = ErrStaleRevision
+	}
+
+	if err != nil {
+		return nil, 0, err
+	}
+
+	for _, i := range missed {
+		p.cache.put(appID, generation, readRevision, checks[i], results[i])
+	}
+
+	return results, max(revision, readRevision), nil
+}
+
+// Expand returns tree of the subjects which have the relation with the object.
+// Tuples are read from one snapshot which revision is at least minRevision and is returned.
+// Caller must have token of the application or be its admin
+func (p *Permissions) Expand(
+	ctx context.Context,
+	caller models.TokenInfo,
+	appID int32,
+	object models.Object,
+	relation string,
+	minRevision int64,
+) (tree models.UsersetTree, revision int64, err error) {
+	log := p.log.With(slog.String("op", opExpand))
+
+	config, err := p.readConfig(ctx, caller, appID)
+	if err != nil {
+		return models.UsersetTree{}, 0, sl.ErrUpLevel(opExpand, p.readErr(log, err))
+	}
+
+	revision, err = p.tupleProvider.ReadTuples(ctx, appID, func(reader storage.TupleReader) error {
+		tree, err = (&expander{config: config, reader: reader}).expand(object, relation, 0)
+		return err
+	})
+	if err == nil && revision < minRevision {
+		err = ErrStaleRevision
+	}
+
+	if err != nil {
+		return models.UsersetTree{}, 0, sl.ErrUpLevel(opExpand, p.readErr(log, err))
+	}
+
+	return
+}
+
+// ListObjects returns IDs of the objects of the type with which the subject has the relation.
+// Tuples are read from one snapshot which revision is at least minRevision and is returned.
+// Caller must have token of the application or be its admin
+func (p *Permissions) ListObjects(
+	ctx context.Context,
+	caller models.TokenInfo,
+	appID int32,
+	objectType string,
+	relation string,
+	subject models.Subject,
+	minRevision int64,
+) (objectIDs []string, revision int64, err error) {
+	log := p.log.With(slog.String("op", opListObjects))
+
+	config, err := p.readConfig(ctx, caller, appID)
+	if err != nil {
+		return nil, 0, sl.ErrUpLevel(opListObjects, p.readErr(log, err))
+	}
+
+	if _, ok := config.Rewrite(objectType, relation); !ok {
+		return nil, 0, sl.ErrUpLevel(opListObjects, p.readErr(log, ErrUnknownRelation))
+	}
+
+	revision, err = p.tupleProvider.ReadTuples(ctx, appID, func(reader storage.TupleReader) error {
+		candidates, err := reader.Objects(objectType)
+		if err != nil {
+			return err
+		}
+
+		// One checker shares answers about common parents and groups between candidates
+		c := newChecker(config, reader, subject)
+		for _, objectID := range candidates {
+			ok, err := c.check(models.Object{Type: objectType, ID: objectID}, relation, 0)
+			if err != nil {
+				return err
+			}
+
+			if ok {
+				objectIDs = append(objectIDs, objectID)
+			}
+		}
+
+		return nil
+	})
+	if err == nil && revision < minRevision {
+		err = ErrStaleRevision
+	}
+
+	if err != nil {
+		return nil, 0, sl.ErrUpLevel(opListObjects, p.readErr(log, err))
+	}
+
+	return
+}
+
+// readConfig authorizes reading caller and returns namespace config of the application
+func (p *Permissions) readConfig(ctx context.Context, caller models.TokenInfo, appID int32) (models.NamespaceConfig, error) {
+	if err := p.authorizeRead(ctx, caller, appID); err != nil {
+		return models.NamespaceConfig{}, err
+	}
+
+	return p.namespaceConfig(ctx, appID)
+}
+
+// namespaceConfig returns namespace config of the application, empty config allows any relation written directly
+func (p *Permissions) namespaceConfig(ctx context.Context, appID int32) (config models.NamespaceConfig, err error) {
+	raw, err := p.namespaceProvider.NamespaceConfig(ctx, appID)
+	if err != nil {
+		if errors.Is(err, storage.ErrNamespaceConfigNotFound) {
+			return models.NamespaceConfig{}, nil
+		}
+
+		return models.NamespaceConfig{}, err
+	}
+
+	if err := json.Unmarshal(raw, &config); err != nil {
+		return models.NamespaceConfig{}, err
+	}
+
+	return
+}
+
+// authorizeRead checks that caller has token of the application or is its admin.
+// Impersonated users can't read relations
+func (p *Permissions) authorizeRead(ctx context.Context, caller models.TokenInfo, appID int32) error {
+	if caller.ActorID != 0 {
+		return ErrPermissionDenied
+	}
+
+	if caller.AppID == appID {
+		return nil
+	}
+
+	return p.authorizeWrite(ctx, caller, appID)
+}
+
+// authorizeWrite checks that caller is admin of the application, impersonated users never act as admin
+func (p *Permissions) authorizeWrite(ctx context.Context, caller models.TokenInfo, appID int32) error {
+	if caller.ActorID != 0 {
+		return ErrPermissionDenied
+	}
+
+	isAdmin, _, err := p.adminProvider.IsAppAdmin(ctx, caller.UserID, appID)
+	if err != nil {
+		if errors.Is(err, storage.ErrAppNotFound) {
+			return ErrInvalidAppID
+		}
+
+		return err
+	}
+
+	if !isAdmin {
+		return ErrPermissionDenied
+	}
+
+	return nil
+}
+
+// readErr logs error of the reading request on the suitable level and converts it to error of the service
+func (p *Permissions) readErr(log *slog.Logger, err error) error {
+	switch {
+	case errors.Is(err, ErrPermissionDenied), errors.Is(err, ErrInvalidAppID),
+		errors.Is(err, ErrUnknownRelation), errors.Is(err, ErrStaleRevision), errors.Is(err, ErrDepthExceeded):
+		log.Warn("failed to read relations", sl.Err(err))
+
+		return err
+	}
+
+	log.Error("failed to read relations", sl.Err(err))
+
+	return err
+}
+
+// storageErr converts errors of the permissions storage to errors of the service
+func (p *Permissions) storageErr(log *slog.Logger, err error) error {
+	if errors.Is(err, storage.ErrAppNotFound) {
+		log.Warn("failed to found app in the system", sl.Err(err))
+
+		return ErrInvalidAppID
+	}
+
+	log.Error("failed to change relations", sl.Err(err))
+
+	return err
+}
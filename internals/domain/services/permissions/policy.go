@@ -0,0 +1,341 @@
+package permissions
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/nhassl3/sso-app/internals/domain/models"
+	"github.com/nhassl3/sso-app/internals/lib/expr"
+	"github.com/nhassl3/sso-app/internals/lib/logger/sl"
+	"github.com/nhassl3/sso-app/internals/storage"
+)
+
+const (
+	opWritePolicy  = "permissions.WritePolicy"
+	opPolicy       = "permissions.Policy"
+	opEvaluate     = "permissions.Evaluate"
+	opDryRunPolicy = "permissions.DryRunPolicy"
+
+	defaultDryRunLimit = 100
+	maxDryRunLimit     = 1000
+)
+
+type PolicySaver interface {
+	SavePolicy(ctx context.Context, appID int32, policy []byte) (version int64, err error)
+	SaveDecision(ctx context.Context, decision models.PolicyDecision, keep int) (decisionID int64, err error)
+}
+
+type PolicyProvider interface {
+	Policy(ctx context.Context, appID int32) (policy []byte, version int64, err error)
+	Decisions(ctx context.Context, appID int32, limit int) (decisions []models.PolicyDecision, err error)
+}
+
+type UserProvider interface {
+	UserByID(ctx context.Context, userID int64) (user models.User, err error)
+	UserRoles(ctx context.Context, userID int64, appID int32) (roles []models.Role, err error)
+}
+
+// WritePolicy replaces ABAC policy of the application and returns its new version.
+// Caller must be admin of the application
+func (p *Permissions) WritePolicy(ctx context.Context, caller models.TokenInfo, appID int32, policy models.Policy) (version int64, err error) {
+	log := p.log.With(slog.String("op", opWritePolicy), slog.Int64("actor_id", caller.UserID))
+
+	if err := p.authorizeWrite(ctx, caller, appID); err != nil {
+		log.Warn("failed to authorize caller", sl.Err(err))
+
+		return 0, sl.ErrUpLevel(opWritePolicy, err)
+	}
+
+	if _, err := compilePolicy(policy); err != nil {
+		log.Warn("invalid policy", sl.Err(err))
+
+		return 0, sl.ErrUpLevel(opWritePolicy, err)
+	}
+
+	raw, err := json.Marshal(policy)
+	if err != nil {
+		log.Error("failed to marshal policy", sl.Err(err))
+
+		return 0, sl.ErrUpLevel(opWritePolicy, err)
+	}
+
+	version, err = p.policySaver.SavePolicy(ctx, appID, raw)
+	if err != nil {
+		return 0, sl.ErrUpLevel(opWritePolicy, p.storageErr(log, err))
+	}
+
+	log.Info("policy changed", slog.Int("app_id", int(appID)), slog.Int64("version", version))
+
+	return
+}
+
+// Policy returns ABAC policy of the application with its version, empty policy has zero version.
+// Caller must have token of the application or be its admin
+func (p *Permissions) Policy(ctx context.Context, caller models.TokenInfo, appID int32) (policy models.Policy, version int64, err error) {
+	log := p.log.With(slog.String("op", opPolicy))
+
+	if err := p.authorizeRead(ctx, caller, appID); err != nil {
+		log.Warn("failed to authorize caller", sl.Err(err))
+
+		return models.Policy{}, 0, sl.ErrUpLevel(opPolicy, err)
+	}
+
+	policy, version, err = p.policy(ctx, appID)
+	if err != nil {
+		log.Error("failed to get policy", sl.Err(err))
+
+		return models.Policy{}, 0, sl.ErrUpLevel(opPolicy, err)
+	}
+
+	return
+}
+
+// Evaluate evaluates ABAC policy of the application and records the decision.
+// Email and roles of the user are taken from the system, time of the request is now if it is not given.
+// Caller must have token of the application or be its admin
+func (p *Permissions) Evaluate(
+	ctx context.Context,
+	caller models.TokenInfo,
+	appID int32,
+	input models.PolicyInput,
+) (evaluation models.PolicyEvaluation, err error) {
+	log := p.log.With(slog.String("op", opEvaluate))
+
+	if err := p.authorizeRead(ctx, caller, appID); err != nil {
+		log.Warn("failed to authorize caller", sl.Err(err))
+
+		return models.PolicyEvaluation{}, sl.ErrUpLevel(opEvaluate, err)
+	}
+
+	if err := p.fillInput(ctx, appID, &input); err != nil {
+		if errors.Is(err, storage.ErrUserNotFound) {
+			log.Warn("user not found", sl.Err(err))
+
+			return models.PolicyEvaluation{}, sl.ErrUpLevel(opEvaluate, ErrInvalidUserID)
+		}
+
+		log.Error("failed to get user attributes", sl.Err(err))
+
+		return models.PolicyEvaluation{}, sl.ErrUpLevel(opEvaluate, err)
+	}
+
+	policy, version, err := p.policy(ctx, appID)
+	if err != nil {
+		log.Error("failed to get policy", sl.Err(err))
+
+		return models.PolicyEvaluation{}, sl.ErrUpLevel(opEvaluate, err)
+	}
+
+	compiled, err := compilePolicy(policy)
+	if err != nil {
+		log.Error("stored policy is invalid", sl.Err(err))
+
+		return models.PolicyEvaluation{}, sl.ErrUpLevel(opEvaluate, err)
+	}
+
+	raw, err := json.Marshal(input)
+	if err != nil {
+		log.Error("failed to marshal policy input", sl.Err(err))
+
+		return models.PolicyEvaluation{}, sl.ErrUpLevel(opEvaluate, err)
+	}
+
+	evaluation = compiled.evaluate(raw)
+	evaluation.PolicyVersion = version
+
+	evaluation.DecisionID, err = p.policySaver.SaveDecision(ctx, models.PolicyDecision{
+		AppID:         appID,
+		PolicyVersion: version,
+		Input:         raw,
+		Effect:        evaluation.Effect,
+		Rule:          evaluation.Rule,
+	}, p.decisionsKept)
+	if err != nil {
+		log.Error("failed to record decision", sl.Err(err))
+
+		return models.PolicyEvaluation{}, sl.ErrUpLevel(opEvaluate, err)
+	}
+
+	return
+}
+
+// DryRunPolicy evaluates the policy against inputs of the latest recorded decisions of the application
+// without saving it. Caller must be admin of the application
+func (p *Permissions) DryRunPolicy(
+	ctx context.Context,
+	caller models.TokenInfo,
+	appID int32,
+	policy models.Policy,
+	limit int,
+) (replays []models.PolicyReplay, err error) {
+	log := p.log.With(slog.String("op", opDryRunPolicy), slog.Int64("actor_id", caller.UserID))
+
+	if err := p.authorizeWrite(ctx, caller, appID); err != nil {
+		log.Warn("failed to authorize caller", sl.Err(err))
+
+		return nil, sl.ErrUpLevel(opDryRunPolicy, err)
+	}
+
+	compiled, err := compilePolicy(policy)
+	if err != nil {
+		log.Warn("invalid policy", sl.Err(err))
+
+		return nil, sl.ErrUpLevel(opDryRunPolicy, err)
+	}
+
+	if limit <= 0 || limit > maxDryRunLimit {
+		limit = defaultDryRunLimit
+	}
+
+	decisions, err := p.policyProvider.Decisions(ctx, appID, limit)
+	if err != nil {
+		log.Error("failed to get recorded decisions", sl.Err(err))
+
+		return nil, sl.ErrUpLevel(opDryRunPolicy, err)
+	}
+
+	replays = make([]models.PolicyReplay, 0, len(decisions))
+	for _, decision := range decisions {
+		evaluation := compiled.evaluate(decision.Input)
+
+		replays = append(replays, models.PolicyReplay{
+			Decision: decision,
+			Effect:   evaluation.Effect,
+			Rule:     evaluation.Rule,
+		})
+	}
+
+	return
+}
+
+// fillInput puts attributes known by the system into the input
+func (p *Permissions) fillInput(ctx context.Context, appID int32, input *models.PolicyInput) error {
+	input.App.ID = appID
+
+	if input.Request.Time.IsZero() {
+		input.Request.Time = time.Now().UTC()
+	}
+
+	if input.User.ID == 0 {
+		return nil
+	}
+
+	user, err := p.userProvider.UserByID(ctx, input.User.ID)
+	if err != nil {
+		return err
+	}
+
+	roles, err := p.userProvider.UserRoles(ctx, input.User.ID, appID)
+	if err != nil {
+		return err
+	}
+
+	input.User.Email = user.Email
+	input.User.Roles = make([]string, 0, len(roles))
+	for _, role := range roles {
+		input.User.Roles = append(input.User.Roles, role.Name)
+	}
+
+	return nil
+}
+
+// policy returns ABAC policy of the application, empty policy denies everything
+func (p *Permissions) policy(ctx context.Context, appID int32) (policy models.Policy, version int64, err error) {
+	raw, version, err := p.policyProvider.Policy(ctx, appID)
+	if err != nil {
+		if errors.Is(err, storage.ErrPolicyNotFound) {
+			return models.Policy{}, 0, nil
+		}
+
+		return models.Policy{}, 0, err
+	}
+
+	if err := json.Unmarshal(raw, &policy); err != nil {
+		return models.Policy{}, 0, err
+	}
+
+	return
+}
+
+type compiledPolicy struct {
+	policy     models.Policy
+	conditions []*expr.Expr
+}
+
+// compilePolicy validates the policy and compiles conditions of its rules
+func compilePolicy(policy models.Policy) (compiledPolicy, error) {
+	if err := policy.Validate(); err != nil {
+		return compiledPolicy{}, err
+	}
+
+	compiled := compiledPolicy{policy: policy, conditions: make([]*expr.Expr, 0, len(policy.Rules))}
+	for _, rule := range policy.Rules {
+		condition, err := expr.Compile(rule.Condition)
+		if err != nil {
+			return compiledPolicy{}, fmt.Errorf("%w: rule %s: %w", models.ErrInvalidPolicy, rule.Name, err)
+		}
+
+		compiled.conditions = append(compiled.conditions, condition)
+	}
+
+	return compiled, nil
+}
+
+// evaluate applies rules to the input in JSON until the first matched one.
+// Rule which condition can't be evaluated doesn't match, the error is put into explanation
+func (c compiledPolicy) evaluate(input []byte) models.PolicyEvaluation {
+	evaluation := models.PolicyEvaluation{Effect: c.policy.DefaultEffect()}
+
+	vars, err := inputVars(input)
+	if err != nil {
+		// Input is made by Evaluate, so it can be broken only in the storage
+		evaluation.Rules = append(evaluation.Rules, models.RuleResult{Error: err.Error()})
+		return evaluation
+	}
+
+	for i, rule := range c.policy.Rules {
+		result := models.RuleResult{Name: rule.Name, Effect: rule.Effect}
+
+		matched, err := c.conditions[i].EvalBool(vars)
+		if err != nil {
+			result.Error = err.Error()
+		}
+		result.Matched = matched
+
+		evaluation.Rules = append(evaluation.Rules, result)
+
+		if matched {
+			evaluation.Effect = rule.Effect
+			evaluation.Rule = rule.Name
+
+			break
+		}
+	}
+
+	return evaluation
+}
+
+// inputVars converts policy input in JSON to variables of the expressions
+func inputVars(input []byte) (map[string]any, error) {
+	var vars map[string]any
+	if err := json.Unmarshal(input, &vars); err != nil {
+		return nil, err
+	}
+
+	if request, ok := vars["request"].(map[string]any); ok {
+		if s, ok := request["time"].(string); ok {
+			t, err := time.Parse(time.RFC3339Nano, s)
+			if err != nil {
+				return nil, err
+			}
+
+			request["time"] = t
+		}
+	}
+
+	return vars, nil
+}
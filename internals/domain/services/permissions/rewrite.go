@@ -0,0 +1,241 @@
+package permissions
+
+import (
+	"errors"
+
+	"github.com/nhassl3/sso-app/internals/domain/models"
+	"github.com/nhassl3/sso-app/internals/storage"
+)
+
+// maxDepth limits nesting of the relations which are followed by one check
+const maxDepth = 32
+
+type relationKey struct {
+	object   models.Object
+	relation string
+}
+
+// checker answers whether one subject has relations with objects.
+// It reads tuples from one snapshot and remembers answers, so it lives during one request only
+type checker struct {
+	config   models.NamespaceConfig
+	reader   storage.TupleReader
+	subject  models.Subject
+	results  map[relationKey]bool
+	visiting map[relationKey]bool
+	cycles   int // number of cycles cut so far, answers computed while cutting them aren't remembered
+}
+
+func newChecker(config models.NamespaceConfig, reader storage.TupleReader, subject models.Subject) *checker {
+	return &checker{
+		config:   config,
+		reader:   reader,
+		subject:  subject,
+		results:  make(map[relationKey]bool),
+		visiting: make(map[relationKey]bool),
+	}
+}
+
+// check reports whether the subject has the relation with the object
+func (c *checker) check(object models.Object, relation string, depth int) (bool, error) {
+	if depth > maxDepth {
+		return false, ErrDepthExceeded
+	}
+
+	key := relationKey{object: object, relation: relation}
+	if result, ok := c.results[key]; ok {
+		return result, nil
+	}
+
+	// Relation which is already being checked doesn't give anything new, it breaks cycles
+	if c.visiting[key] {
+		c.cycles++
+		return false, nil
+	}
+
+	rewrite, ok := c.config.Rewrite(object.Type, relation)
+	if !ok {
+		return false, nil
+	}
+
+	cycles := c.cycles
+
+	c.visiting[key] = true
+	result, err := c.rewrite(rewrite, object, relation, depth)
+	delete(c.visiting, key)
+
+	if err != nil {
+		return false, err
+	}
+
+	// Answer which depends on a cut cycle may differ when the relation is reached by other path
+	if c.cycles == cycles {
+		c.results[key] = result
+	}
+
+	return result, nil
+}
+
+func (c *checker) rewrite(rewrite *models.Rewrite, object models.Object, relation string, depth int) (bool, error) {
+	switch {
+	case rewrite.Computed != "":
+		return c.check(object, rewrite.Computed, depth+1)
+	case rewrite.TupleToUserset != nil:
+		parents, err := c.reader.Subjects(object, rewrite.TupleToUserset.Tupleset)
+		if err != nil {
+			return false, err
+		}
+
+		for _, parent := range parents {
+			ok, err := c.check(parent.Object(), rewrite.TupleToUserset.Computed, depth+1)
+			if err != nil || ok {
+				return ok, err
+			}
+		}
+
+		return false, nil
+	case len(rewrite.Union) != 0:
+		for _, child := range rewrite.Union {
+			ok, err := c.rewrite(child, object, relation, depth)
+			if err != nil || ok {
+				return ok, err
+			}
+		}
+
+		return false, nil
+	case len(rewrite.Intersection) != 0:
+		for _, child := range rewrite.Intersection {
+			ok, err := c.rewrite(child, object, relation, depth)
+			if err != nil || !ok {
+				return false, err
+			}
+		}
+
+		return true, nil
+	case rewrite.Exclusion != nil:
+		ok, err := c.rewrite(rewrite.Exclusion.Base, object, relation, depth)
+		if err != nil || !ok {
+			return false, err
+		}
+
+		cycles := c.cycles
+
+		excluded, err := c.rewrite(rewrite.Exclusion.Subtract, object, relation, depth)
+		if err != nil {
+			return false, err
+		}
+
+		// Subtracted relation which can't be resolved because of a cycle denies access
+		if c.cycles != cycles {
+			return false, nil
+		}
+
+		return !excluded, nil
+	}
+
+	return c.direct(object, relation, depth)
+}
+
+// direct checks subjects written directly to the relation of the object and members of usersets among them
+func (c *checker) direct(object models.Object, relation string, depth int) (bool, error) {
+	subjects, err := c.reader.Subjects(object, relation)
+	if err != nil {
+		return false, err
+	}
+
+	for _, subject := range subjects {
+		if subject == c.subject {
+			return true, nil
+		}
+
+		if subject.Relation == "" {
+			continue
+		}
+
+		ok, err := c.check(subject.Object(), subject.Relation, depth+1)
+		if err != nil || ok {
+			return ok, err
+		}
+	}
+
+	return false, nil
+}
+
+// expander builds tree of the subjects which have a relation with an object
+type expander struct {
+	config models.NamespaceConfig
+	reader storage.TupleReader
+}
+
+func (e *expander) expand(object models.Object, relation string, depth int) (models.UsersetTree, error) {
+	if depth > maxDepth {
+		return models.UsersetTree{}, ErrDepthExceeded
+	}
+
+	rewrite, ok := e.config.Rewrite(object.Type, relation)
+	if !ok {
+		return models.UsersetTree{}, ErrUnknownRelation
+	}
+
+	return e.rewrite(rewrite, object, relation, depth)
+}
+
+func (e *expander) rewrite(rewrite *models.Rewrite, object models.Object, relation string, depth int) (models.UsersetTree, error) {
+	tree := models.UsersetTree{Object: object, Relation: relation}
+
+	switch {
+	case rewrite.Computed != "":
+		return e.expand(object, rewrite.Computed, depth+1)
+	case rewrite.TupleToUserset != nil:
+		parents, err := e.reader.Subjects(object, rewrite.TupleToUserset.Tupleset)
+		if err != nil {
+			return models.UsersetTree{}, err
+		}
+
+		tree.Operation = models.UsersetUnion
+		for _, parent := range parents {
+			child, err := e.expand(parent.Object(), rewrite.TupleToUserset.Computed, depth+1)
+			if errors.Is(err, ErrUnknownRelation) {
+				continue
+			}
+
+			if err != nil {
+				return models.UsersetTree{}, err
+			}
+
+			tree.Children = append(tree.Children, child)
+		}
+	case len(rewrite.Union) != 0:
+		tree.Operation = models.UsersetUnion
+		return e.children(tree, rewrite.Union, depth)
+	case len(rewrite.Intersection) != 0:
+		tree.Operation = models.UsersetIntersection
+		return e.children(tree, rewrite.Intersection, depth)
+	case rewrite.Exclusion != nil:
+		tree.Operation = models.UsersetExclusion
+		return e.children(tree, []*models.Rewrite{rewrite.Exclusion.Base, rewrite.Exclusion.Subtract}, depth)
+	default:
+		subjects, err := e.reader.Subjects(object, relation)
+		if err != nil {
+			return models.UsersetTree{}, err
+		}
+
+		tree.Operation = models.UsersetLeaf
+		tree.Subjects = subjects
+	}
+
+	return tree, nil
+}
+
+func (e *expander) children(tree models.UsersetTree, rewrites []*models.Rewrite, depth int) (models.UsersetTree, error) {
+	for _, rewrite := range rewrites {
+		child, err := e.rewrite(rewrite, tree.Object, tree.Relation, depth)
+		if err != nil {
+			return models.UsersetTree{}, err
+		}
+
+		tree.Children = append(tree.Children, child)
+	}
+
+	return tree, nil
+}
@@ -0,0 +1,300 @@
+package scim
+
+import (
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/nhassl3/sso-app/internals/domain/models"
+	scimexpr "github.com/nhassl3/sso-app/internals/lib/scim"
+)
+
+// patchUser applies PATCH operations to the user. Operation without path applies the attributes of its value
+// as operations with their paths. Email of the user is its user name, so emails patch user name
+func patchUser(user *models.ScimUser, ops []models.ScimPatchOp) error {
+	return applyPatch(ops, func(op string, path scimexpr.Path, value any) error {
+		if op == models.ScimPatchRemove {
+			return removeUserAttr(user, path)
+		}
+
+		return setUserAttr(user, path, value)
+	})
+}
+
+// patchGroup applies PATCH operations to the group. Members are added to the group by add operation
+// and replaced by replace operation, remove operation removes the members matching the filter of the path
+// or listed in the value, all members are removed without both
+func patchGroup(group *models.ScimGroup, ops []models.ScimPatchOp) error {
+	return applyPatch(ops, func(op string, path scimexpr.Path, value any) error {
+		switch path.String() {
+		case "displayname":
+			if op == models.ScimPatchRemove {
+				return fmt.Errorf("%w: displayName is required", ErrInvalidValue)
+			}
+
+			return setString(&group.DisplayName, value)
+		case "externalid":
+			if op == models.ScimPatchRemove {
+				group.ExternalID = ""
+				return nil
+			}
+
+			return setString(&group.ExternalID, value)
+		case "members":
+			return patchMembers(group, op, path.Filter, value)
+		}
+
+		return fmt.Errorf("%w: %s", ErrInvalidPath, path)
+	})
+}
+
+// applyPatch calls apply for every attribute patched by the operations with lower case operation
+func applyPatch(ops []models.ScimPatchOp, apply func(op string, path scimexpr.Path, value any) error) error {
+	for _, patch := range ops {
+		op := strings.ToLower(patch.Op)
+		if op != models.ScimPatchAdd && op != models.ScimPatchReplace && op != models.ScimPatchRemove {
+			return fmt.Errorf("%w: unknown operation %q", ErrInvalidValue, patch.Op)
+		}
+
+		if patch.Path != "" {
+			path, err := scimexpr.ParsePath(patch.Path)
+			if err != nil {
+				return fmt.Errorf("%w: %w", ErrInvalidPath, err)
+			}
+
+			if err := apply(op, path, patch.Value); err != nil {
+				return err
+			}
+
+			continue
+		}
+
+		attrs, ok := patch.Value.(map[string]any)
+		if !ok || op == models.ScimPatchRemove {
+			return fmt.Errorf("%w: operation without path requires object value", ErrInvalidPath)
+		}
+
+		for name, value := range attrs {
+			path, err := scimexpr.ParsePath(name)
+			if err != nil {
+				return fmt.Errorf("%w: %w", ErrInvalidPath, err)
+			}
+
+			if path.Attr == "schemas" {
+				continue
+			}
+
+			if err := apply(op, path, value); err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+func setUserAttr(user *models.ScimUser, path scimexpr.Path, value any) error {
+	switch path.String() {
+	case "username", "emails.value":
+		return setString(&user.UserName, value)
+	case "emails":
+		email, err := primaryEmail(value)
+		if err != nil {
+			return err
+		}
+
+		user.UserName = email
+
+		return nil
+	case "externalid":
+		return setString(&user.ExternalID, value)
+	case "displayname":
+		return setString(&user.DisplayName, value)
+	case "name.givenname":
+		return setString(&user.GivenName, value)
+	case "name.familyname":
+		return setString(&user.FamilyName, value)
+	case "name":
+		name, ok := value.(map[string]any)
+		if !ok {
+			return fmt.Errorf("%w: name must be object", ErrInvalidValue)
+		}
+
+		for attr, value := range name {
+			if err := setUserAttr(user, scimexpr.Path{Attr: "name." + strings.ToLower(attr)}, value); err != nil {
+				return err
+			}
+		}
+
+		return nil
+	case "active":
+		active, err := boolValue(value)
+		if err != nil {
+			return err
+		}
+
+		user.Active = active
+
+		return nil
+	case "password":
+		return setString(&user.Password, value)
+	}
+
+	return fmt.Errorf("%w: %s", ErrInvalidPath, path)
+}
+
+func removeUserAttr(user *models.ScimUser, path scimexpr.Path) error {
+	switch path.String() {
+	case "externalid":
+		user.ExternalID = ""
+	case "displayname":
+		user.DisplayName = ""
+	case "name.givenname":
+		user.GivenName = ""
+	case "name.familyname":
+		user.FamilyName = ""
+	case "name":
+		user.GivenName, user.FamilyName = "", ""
+	case "username", "emails", "emails.value", "active", "password":
+		return fmt.Errorf("%w: %s can't be removed", ErrInvalidValue, path)
+	default:
+		return fmt.Errorf("%w: %s", ErrInvalidPath, path)
+	}
+
+	return nil
+}
+
+func patchMembers(group *models.ScimGroup, op string, filter scimexpr.Expr, value any) error {
+	if op != models.ScimPatchRemove {
+		members, err := memberValues(value)
+		if err != nil {
+			return err
+		}
+
+		if op == models.ScimPatchReplace {
+			group.Members = nil
+		}
+
+		group.Members = append(group.Members, members...)
+
+		return nil
+	}
+
+	var listed []models.ScimMember
+	if filter == nil && value != nil {
+		members, err := memberValues(value)
+		if err != nil {
+			return err
+		}
+
+		listed = members
+	}
+
+	group.Members = slices.DeleteFunc(group.Members, func(member models.ScimMember) bool {
+		switch {
+		case filter != nil:
+			return scimexpr.Match(filter, map[string]any{
+				"members.value":   strconv.FormatInt(member.UserID, 10),
+				"members.display": member.UserName,
+			})
+		case listed != nil:
+			return slices.ContainsFunc(listed, func(listed models.ScimMember) bool {
+				return listed.UserID == member.UserID
+			})
+		}
+
+		return true
+	})
+
+	return nil
+}
+
+// memberValues returns members of the group from SCIM value which is the list of objects
+// with user ID in value attribute or one such object
+func memberValues(value any) ([]models.ScimMember, error) {
+	list, ok := value.([]any)
+	if !ok {
+		list = []any{value}
+	}
+
+	members := make([]models.ScimMember, 0, len(list))
+
+	for _, item := range list {
+		object, ok := item.(map[string]any)
+		if !ok {
+			return nil, fmt.Errorf("%w: member must be object", ErrInvalidValue)
+		}
+
+		id, ok := object["value"].(string)
+		if !ok {
+			return nil, fmt.Errorf("%w: member must have value", ErrInvalidValue)
+		}
+
+		userID, err := strconv.ParseInt(id, 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("%w: member %q is unknown", ErrInvalidValue, id)
+		}
+
+		members = append(members, models.ScimMember{UserID: userID})
+	}
+
+	return members, nil
+}
+
+// primaryEmail returns the primary or the first email of SCIM emails value
+func primaryEmail(value any) (string, error) {
+	list, ok := value.([]any)
+	if !ok {
+		list = []any{value}
+	}
+
+	var email string
+
+	for _, item := range list {
+		object, ok := item.(map[string]any)
+		if !ok {
+			return "", fmt.Errorf("%w: email must be object", ErrInvalidValue)
+		}
+
+		address, ok := object["value"].(string)
+		if !ok {
+			return "", fmt.Errorf("%w: email must have value", ErrInvalidValue)
+		}
+
+		if primary, _ := object["primary"].(bool); primary || email == "" {
+			email = address
+		}
+	}
+
+	if email == "" {
+		return "", fmt.Errorf("%w: emails are empty", ErrInvalidValue)
+	}
+
+	return email, nil
+}
+
+func setString(target *string, value any) error {
+	s, ok := value.(string)
+	if !ok {
+		return fmt.Errorf("%w: string is expected", ErrInvalidValue)
+	}
+
+	*target = s
+
+	return nil
+}
+
+// boolValue returns boolean of the value, some clients send booleans as "True" and "False" strings
+func boolValue(value any) (bool, error) {
+	switch value := value.(type) {
+	case bool:
+		return value, nil
+	case string:
+		if b, err := strconv.ParseBool(strings.ToLower(value)); err == nil {
+			return b, nil
+		}
+	}
+
+	return false, fmt.Errorf("%w: boolean is expected", ErrInvalidValue)
+}
@@ -0,0 +1,759 @@
+package scim
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"slices"
+	"strings"
+
+	"github.com/nhassl3/sso-app/internals/domain/models"
+	"github.com/nhassl3/sso-app/internals/lib/logger/sl"
+	"github.com/nhassl3/sso-app/internals/lib/random"
+	scimexpr "github.com/nhassl3/sso-app/internals/lib/scim"
+	"github.com/nhassl3/sso-app/internals/storage"
+	"golang.org/x/crypto/bcrypt"
+)
+
+const (
+	opCreateUser   = "scim.CreateUser"
+	opUser         = "scim.User"
+	opUsers        = "scim.Users"
+	opReplaceUser  = "scim.ReplaceUser"
+	opPatchUser    = "scim.PatchUser"
+	opDeleteUser   = "scim.DeleteUser"
+	opCreateGroup  = "scim.CreateGroup"
+	opGroup        = "scim.Group"
+	opGroups       = "scim.Groups"
+	opReplaceGroup = "scim.ReplaceGroup"
+	opPatchGroup   = "scim.PatchGroup"
+	opDeleteGroup  = "scim.DeleteGroup"
+
+	// Scope is the scope of the client credentials token of the provisioning client
+	Scope = models.ScopeSCIM
+
+	DefaultCount = 100
+	MaxCount     = 200
+
+	unusablePasswordLen = 32
+)
+
+var (
+	ErrPermissionDenied = errors.New("permission denied")
+	ErrUserNotFound     = errors.New("user not found")
+	ErrGroupNotFound    = errors.New("group not found")
+	ErrUserExists       = errors.New("user already exists")
+	ErrGroupExists      = errors.New("group already exists")
+	ErrInvalidFilter    = errors.New("invalid filter")
+	ErrInvalidPath      = errors.New("invalid attribute path")
+	ErrInvalidValue     = errors.New("invalid attribute value")
+	ErrVersionMismatch  = errors.New("resource version doesn't match")
+	ErrLastSuperAdmin   = errors.New("last super-admin can't be deleted")
+)
+
+// Scim is the SCIM 2.0 provisioning service. Users are the users of the system,
+// groups belong to the application of the provisioning client
+type Scim struct {
+	log              *slog.Logger
+	userSaver        UserSaver
+	groupSaver       GroupSaver
+	sessionSaver     SessionSaver
+	auditSaver       AuditSaver
+	cacheInvalidator CacheInvalidator
+}
+
+// NewScim returns a new instance of the Scim service
+func NewScim(
+	log *slog.Logger,
+	userSaver UserSaver,
+	groupSaver GroupSaver,
+	sessionSaver SessionSaver,
+	auditSaver AuditSaver,
+	cacheInvalidator CacheInvalidator,
+) *Scim {
+	return &Scim{
+		log:              log,
+		userSaver:        userSaver,
+		groupSaver:       groupSaver,
+		sessionSaver:     sessionSaver,
+		auditSaver:       auditSaver,
+		cacheInvalidator: cacheInvalidator,
+	}
+}
+
+type UserSaver interface {
+	SaveScimUser(ctx context.Context, user models.ScimUser, hashPassword []byte) (userID int64, err error)
+	ScimUser(ctx context.Context, userID int64) (user models.ScimUser, err error)
+	ScimUsers(ctx context.Context, filter scimexpr.Expr, offset int, limit int) (users []models.ScimUser, total int, err error)
+	UpdateScimUser(ctx context.Context, user models.ScimUser, hashPassword []byte) error
+	DeleteUser(ctx context.Context, userID int64) (appIDs []int32, err error)
+}
+
+type GroupSaver interface {
+	SaveScimGroup(ctx context.Context, group models.ScimGroup) (groupID int64, err error)
+	ScimGroup(ctx context.Context, appID int32, groupID int64) (group models.ScimGroup, err error)
+	ScimGroups(
+		ctx context.Context,
+		appID int32,
+		filter scimexpr.Expr,
+		offset int,
+		limit int,
+	) (groups []models.ScimGroup, total int, err error)
+	UpdateScimGroup(ctx context.Context, group models.ScimGroup) error
+	DeleteScimGroup(ctx context.Context, appID int32, groupID int64) error
+}
+
+type SessionSaver interface {
+	EndSessions(ctx context.Context, userID int64, appID int32) (notified int, err error)
+}
+
+type AuditSaver interface {
+	SaveAuditEvent(ctx context.Context, event models.AuditEvent) error
+}
+
+// CacheInvalidator drops cached permission checks of the application when its groups change
+type CacheInvalidator interface {
+	InvalidateCache(appID int32)
+}
+
+// CreateUser provisions the user, user without password signs in only by the directory or upstream providers
+func (s *Scim) CreateUser(ctx context.Context, caller models.TokenInfo, user models.ScimUser) (models.ScimUser, error) {
+	log := s.log.With(slog.String("op", opCreateUser), slog.Int("app_id", int(caller.AppID)))
+
+	if err := authorize(caller); err != nil {
+		log.Warn("failed to authorize caller", sl.Err(err))
+
+		return models.ScimUser{}, sl.ErrUpLevel(opCreateUser, err)
+	}
+
+	if err := validateUser(user); err != nil {
+		log.Warn("invalid user", sl.Err(err))
+
+		return models.ScimUser{}, sl.ErrUpLevel(opCreateUser, err)
+	}
+
+	password := user.Password
+	if password == "" {
+		generated, err := random.String(unusablePasswordLen)
+		if err != nil {
+			log.Error("failed to generate password", sl.Err(err))
+
+			return models.ScimUser{}, sl.ErrUpLevel(opCreateUser, err)
+		}
+
+		password = generated
+	}
+
+	passHash, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+	if err != nil {
+		log.Error("failed to generate password hash", sl.Err(err))
+
+		return models.ScimUser{}, sl.ErrUpLevel(opCreateUser, err)
+	}
+
+	userID, err := s.userSaver.SaveScimUser(ctx, user, passHash)
+	if err != nil {
+		return models.ScimUser{}, sl.ErrUpLevel(opCreateUser, s.storageErr(log, err))
+	}
+
+	log.Info("user provisioned", slog.Int64("user_id", userID))
+
+	if err := s.audit(ctx, caller, models.AuditActionProvisionUser, userID); err != nil {
+		log.Error("failed to save audit event", sl.Err(err))
+
+		return models.ScimUser{}, sl.ErrUpLevel(opCreateUser, err)
+	}
+
+	created, err := s.userSaver.ScimUser(ctx, userID)
+	if err != nil {
+		return models.ScimUser{}, sl.ErrUpLevel(opCreateUser, s.storageErr(log, err))
+	}
+
+	return created, nil
+}
+
+// User returns the user
+func (s *Scim) User(ctx context.Context, caller models.TokenInfo, userID int64) (models.ScimUser, error) {
+	log := s.log.With(slog.String("op", opUser), slog.Int64("user_id", userID))
+
+	if err := authorize(caller); err != nil {
+		log.Warn("failed to authorize caller", sl.Err(err))
+
+		return models.ScimUser{}, sl.ErrUpLevel(opUser, err)
+	}
+
+	user, err := s.userSaver.ScimUser(ctx, userID)
+	if err != nil {
+		return models.ScimUser{}, sl.ErrUpLevel(opUser, s.storageErr(log, err))
+	}
+
+	return user, nil
+}
+
+// Users returns page of the users matching the filter and count of all matching users.
+// Start index is 1-based, count is limited by MaxCount
+func (s *Scim) Users(
+	ctx context.Context,
+	caller models.TokenInfo,
+	filter string,
+	startIndex int,
+	count int,
+) (users []models.ScimUser, total int, err error) {
+	log := s.log.With(slog.String("op", opUsers))
+
+	if err := authorize(caller); err != nil {
+		log.Warn("failed to authorize caller", sl.Err(err))
+
+		return nil, 0, sl.ErrUpLevel(opUsers, err)
+	}
+
+	expr, err := parseFilter(log, filter)
+	if err != nil {
+		return nil, 0, sl.ErrUpLevel(opUsers, err)
+	}
+
+	offset, limit := page(startIndex, count)
+
+	users, total, err = s.userSaver.ScimUsers(ctx, expr, offset, limit)
+	if err != nil {
+		return nil, 0, sl.ErrUpLevel(opUsers, s.storageErr(log, err))
+	}
+
+	return
+}
+
+// ReplaceUser replaces attributes of the user, empty password keeps the password.
+// Non-zero version must be the current version of the user
+func (s *Scim) ReplaceUser(
+	ctx context.Context,
+	caller models.TokenInfo,
+	user models.ScimUser,
+	version int64,
+) (models.ScimUser, error) {
+	log := s.log.With(slog.String("op", opReplaceUser), slog.Int64("user_id", user.ID))
+
+	if err := authorize(caller); err != nil {
+		log.Warn("failed to authorize caller", sl.Err(err))
+
+		return models.ScimUser{}, sl.ErrUpLevel(opReplaceUser, err)
+	}
+
+	current, err := s.currentUser(ctx, log, user.ID, version)
+	if err != nil {
+		return models.ScimUser{}, sl.ErrUpLevel(opReplaceUser, err)
+	}
+
+	user.Version = current.Version
+
+	updated, err := s.updateUser(ctx, log, caller, current, user)
+	if err != nil {
+		return models.ScimUser{}, sl.ErrUpLevel(opReplaceUser, err)
+	}
+
+	return updated, nil
+}
+
+// PatchUser applies the operations to attributes of the user. Non-zero version must be the current version of the user
+func (s *Scim) PatchUser(
+	ctx context.Context,
+	caller models.TokenInfo,
+	userID int64,
+	ops []models.ScimPatchOp,
+	version int64,
+) (models.ScimUser, error) {
+	log := s.log.With(slog.String("op", opPatchUser), slog.Int64("user_id", userID))
+
+	if err := authorize(caller); err != nil {
+		log.Warn("failed to authorize caller", sl.Err(err))
+
+		return models.ScimUser{}, sl.ErrUpLevel(opPatchUser, err)
+	}
+
+	current, err := s.currentUser(ctx, log, userID, version)
+	if err != nil {
+		return models.ScimUser{}, sl.ErrUpLevel(opPatchUser, err)
+	}
+
+	user := current
+	if err := patchUser(&user, ops); err != nil {
+		log.Warn("failed to apply patch", sl.Err(err))
+
+		return models.ScimUser{}, sl.ErrUpLevel(opPatchUser, err)
+	}
+
+	updated, err := s.updateUser(ctx, log, caller, current, user)
+	if err != nil {
+		return models.ScimUser{}, sl.ErrUpLevel(opPatchUser, err)
+	}
+
+	return updated, nil
+}
+
+// DeleteUser deprovisions the user, the applications are notified about the end of the sessions of the user.
+// Non-zero version must be the current version of the user
+func (s *Scim) DeleteUser(ctx context.Context, caller models.TokenInfo, userID int64, version int64) error {
+	log := s.log.With(slog.String("op", opDeleteUser), slog.Int64("user_id", userID))
+
+	if err := authorize(caller); err != nil {
+		log.Warn("failed to authorize caller", sl.Err(err))
+
+		return sl.ErrUpLevel(opDeleteUser, err)
+	}
+
+	if _, err := s.currentUser(ctx, log, userID, version); err != nil {
+		return sl.ErrUpLevel(opDeleteUser, err)
+	}
+
+	appIDs, err := s.userSaver.DeleteUser(ctx, userID)
+	if err != nil {
+		return sl.ErrUpLevel(opDeleteUser, s.storageErr(log, err))
+	}
+
+	for _, appID := range appIDs {
+		s.cacheInvalidator.InvalidateCache(appID)
+	}
+
+	log.Info("user deprovisioned", slog.Any("groups_app_ids", appIDs))
+
+	if err := s.audit(ctx, caller, models.AuditActionDeprovisionUser, userID); err != nil {
+		log.Error("failed to save audit event", sl.Err(err))
+
+		return sl.ErrUpLevel(opDeleteUser, err)
+	}
+
+	return nil
+}
+
+// CreateGroup creates the group in the application of the caller, members must exist
+func (s *Scim) CreateGroup(ctx context.Context, caller models.TokenInfo, group models.ScimGroup) (models.ScimGroup, error) {
+	log := s.log.With(slog.String("op", opCreateGroup), slog.Int("app_id", int(caller.AppID)))
+
+	if err := authorize(caller); err != nil {
+		log.Warn("failed to authorize caller", sl.Err(err))
+
+		return models.ScimGroup{}, sl.ErrUpLevel(opCreateGroup, err)
+	}
+
+	if err := validateGroup(group); err != nil {
+		log.Warn("invalid group", sl.Err(err))
+
+		return models.ScimGroup{}, sl.ErrUpLevel(opCreateGroup, err)
+	}
+
+	group.AppID = caller.AppID
+	group.Members = uniqueMembers(group.Members)
+
+	groupID, err := s.groupSaver.SaveScimGroup(ctx, group)
+	if err != nil {
+		return models.ScimGroup{}, sl.ErrUpLevel(opCreateGroup, s.groupErr(log, err))
+	}
+
+	s.cacheInvalidator.InvalidateCache(caller.AppID)
+
+	log.Info("group created", slog.Int64("group_id", groupID), slog.Int("members", len(group.Members)))
+
+	created, err := s.groupSaver.ScimGroup(ctx, caller.AppID, groupID)
+	if err != nil {
+		return models.ScimGroup{}, sl.ErrUpLevel(opCreateGroup, s.groupErr(log, err))
+	}
+
+	return created, nil
+}
+
+// Group returns the group of the application of the caller
+func (s *Scim) Group(ctx context.Context, caller models.TokenInfo, groupID int64) (models.ScimGroup, error) {
+	log := s.log.With(slog.String("op", opGroup), slog.Int("app_id", int(caller.AppID)), slog.Int64("group_id", groupID))
+
+	if err := authorize(caller); err != nil {
+		log.Warn("failed to authorize caller", sl.Err(err))
+
+		return models.ScimGroup{}, sl.ErrUpLevel(opGroup, err)
+	}
+
+	group, err := s.groupSaver.ScimGroup(ctx, caller.AppID, groupID)
+	if err != nil {
+		return models.ScimGroup{}, sl.ErrUpLevel(opGroup, s.groupErr(log, err))
+	}
+
+	return group, nil
+}
+
+// Groups returns page of the groups of the application of the caller matching the filter
+// and count of all matching groups. Start index is 1-based, count is limited by MaxCount
+func (s *Scim) Groups(
+	ctx context.Context,
+	caller models.TokenInfo,
+	filter string,
+	startIndex int,
+	count int,
+) (groups []models.ScimGroup, total int, err error) {
+	log := s.log.With(slog.String("op", opGroups), slog.Int("app_id", int(caller.AppID)))
+
+	if err := authorize(caller); err != nil {
+		log.Warn("failed to authorize caller", sl.Err(err))
+
+		return nil, 0, sl.ErrUpLevel(opGroups, err)
+	}
+
+	expr, err := parseFilter(log, filter)
+	if err != nil {
+		return nil, 0, sl.ErrUpLevel(opGroups, err)
+	}
+
+	offset, limit := page(startIndex, count)
+
+	groups, total, err = s.groupSaver.ScimGroups(ctx, caller.AppID, expr, offset, limit)
+	if err != nil {
+		return nil, 0, sl.ErrUpLevel(opGroups, s.groupErr(log, err))
+	}
+
+	return
+}
+
+// ReplaceGroup replaces attributes and members of the group. Non-zero version must be the current version of the group
+func (s *Scim) ReplaceGroup(
+	ctx context.Context,
+	caller models.TokenInfo,
+	group models.ScimGroup,
+	version int64,
+) (models.ScimGroup, error) {
+	log := s.log.With(slog.String("op", opReplaceGroup), slog.Int("app_id", int(caller.AppID)), slog.Int64("group_id", group.ID))
+
+	if err := authorize(caller); err != nil {
+		log.Warn("failed to authorize caller", sl.Err(err))
+
+		return models.ScimGroup{}, sl.ErrUpLevel(opReplaceGroup, err)
+	}
+
+	current, err := s.currentGroup(ctx, log, caller.AppID, group.ID, version)
+	if err != nil {
+		return models.ScimGroup{}, sl.ErrUpLevel(opReplaceGroup, err)
+	}
+
+	group.AppID, group.Version = current.AppID, current.Version
+
+	updated, err := s.updateGroup(ctx, log, group)
+	if err != nil {
+		return models.ScimGroup{}, sl.ErrUpLevel(opReplaceGroup, err)
+	}
+
+	return updated, nil
+}
+
+// PatchGroup applies the operations to attributes and members of the group.
+// Non-zero version must be the current version of the group
+func (s *Scim) PatchGroup(
+	ctx context.Context,
+	caller models.TokenInfo,
+	groupID int64,
+	ops []models.ScimPatchOp,
+	version int64,
+) (models.ScimGroup, error) {
+	log := s.log.With(slog.String("op", opPatchGroup), slog.Int("app_id", int(caller.AppID)), slog.Int64("group_id", groupID))
+
+	if err := authorize(caller); err != nil {
+		log.Warn("failed to authorize caller", sl.Err(err))
+
+		return models.ScimGroup{}, sl.ErrUpLevel(opPatchGroup, err)
+	}
+
+	group, err := s.currentGroup(ctx, log, caller.AppID, groupID, version)
+	if err != nil {
+		return models.ScimGroup{}, sl.ErrUpLevel(opPatchGroup, err)
+	}
+
+	if err := patchGroup(&group, ops); err != nil {
+		log.Warn("failed to apply patch", sl.Err(err))
+
+		return models.ScimGroup{}, sl.ErrUpLevel(opPatchGroup, err)
+	}
+
+	updated, err := s.updateGroup(ctx, log, group)
+	if err != nil {
+		return models.ScimGroup{}, sl.ErrUpLevel(opPatchGroup, err)
+	}
+
+	return updated, nil
+}
+
+// DeleteGroup deletes the group with relation tuples of the group. Non-zero version must be the current version of the group
+func (s *Scim) DeleteGroup(ctx context.Context, caller models.TokenInfo, groupID int64, version int64) error {
+	log := s.log.With(slog.String("op", opDeleteGroup), slog.Int("app_id", int(caller.AppID)), slog.Int64("group_id", groupID))
+
+	if err := authorize(caller); err != nil {
+		log.Warn("failed to authorize caller", sl.Err(err))
+
+		return sl.ErrUpLevel(opDeleteGroup, err)
+	}
+
+	if _, err := s.currentGroup(ctx, log, caller.AppID, groupID, version); err != nil {
+		return sl.ErrUpLevel(opDeleteGroup, err)
+	}
+
+	if err := s.groupSaver.DeleteScimGroup(ctx, caller.AppID, groupID); err != nil {
+		return sl.ErrUpLevel(opDeleteGroup, s.groupErr(log, err))
+	}
+
+	s.cacheInvalidator.InvalidateCache(caller.AppID)
+
+	log.Info("group deleted")
+
+	return nil
+}
+
+// currentUser returns the user which must have the version unless it is zero
+func (s *Scim) currentUser(ctx context.Context, log *slog.Logger, userID int64, version int64) (models.ScimUser, error) {
+	user, err := s.userSaver.ScimUser(ctx, userID)
+	if err != nil {
+		return models.ScimUser{}, s.storageErr(log, err)
+	}
+
+	if version != 0 && user.Version != version {
+		log.Warn("user version doesn't match", slog.Int64("version", user.Version), slog.Int64("expected", version))
+
+		return models.ScimUser{}, ErrVersionMismatch
+	}
+
+	return user, nil
+}
+
+// updateUser saves the user read as current, deactivated user loses the sessions
+func (s *Scim) updateUser(
+	ctx context.Context,
+	log *slog.Logger,
+	caller models.TokenInfo,
+	current models.ScimUser,
+	user models.ScimUser,
+) (models.ScimUser, error) {
+	if err := validateUser(user); err != nil {
+		log.Warn("invalid user", sl.Err(err))
+
+		return models.ScimUser{}, err
+	}
+
+	var passHash []byte
+	if user.Password != "" {
+		hash, err := bcrypt.GenerateFromPassword([]byte(user.Password), bcrypt.DefaultCost)
+		if err != nil {
+			log.Error("failed to generate password hash", sl.Err(err))
+
+			return models.ScimUser{}, err
+		}
+
+		passHash = hash
+	}
+
+	if err := s.userSaver.UpdateScimUser(ctx, user, passHash); err != nil {
+		return models.ScimUser{}, s.storageErr(log, err)
+	}
+
+	switch {
+	case current.Active && !user.Active:
+		notified, err := s.sessionSaver.EndSessions(ctx, user.ID, 0)
+		if err != nil {
+			log.Error("failed to end sessions", sl.Err(err))
+
+			return models.ScimUser{}, err
+		}
+
+		log.Info("user deactivated", slog.Int("notified", notified))
+
+		if err := s.audit(ctx, caller, models.AuditActionDeactivateUser, user.ID); err != nil {
+			log.Error("failed to save audit event", sl.Err(err))
+
+			return models.ScimUser{}, err
+		}
+	case !current.Active && user.Active:
+		log.Info("user reactivated")
+
+		if err := s.audit(ctx, caller, models.AuditActionReactivateUser, user.ID); err != nil {
+			log.Error("failed to save audit event", sl.Err(err))
+
+			return models.ScimUser{}, err
+		}
+	}
+
+	updated, err := s.userSaver.ScimUser(ctx, user.ID)
+	if err != nil {
+		return models.ScimUser{}, s.storageErr(log, err)
+	}
+
+	return updated, nil
+}
+
+// currentGroup returns the group which must have the version unless it is zero
+func (s *Scim) currentGroup(
+	ctx context.Context,
+	log *slog.Logger,
+	appID int32,
+	groupID int64,
+	version int64,
+) (models.ScimGroup, error) {
+	group, err := s.groupSaver.ScimGroup(ctx, appID, groupID)
+	if err != nil {
+		return models.ScimGroup{}, s.groupErr(log, err)
+	}
+
+	if version != 0 && group.Version != version {
+		log.Warn("group version doesn't match", slog.Int64("version", group.Version), slog.Int64("expected", version))
+
+		return models.ScimGroup{}, ErrVersionMismatch
+	}
+
+	return group, nil
+}
+
+func (s *Scim) updateGroup(ctx context.Context, log *slog.Logger, group models.ScimGroup) (models.ScimGroup, error) {
+	if err := validateGroup(group); err != nil {
+		log.Warn("invalid group", sl.Err(err))
+
+		return models.ScimGroup{}, err
+	}
+
+	group.Members = uniqueMembers(group.Members)
+
+	if err := s.groupSaver.UpdateScimGroup(ctx, group); err != nil {
+		return models.ScimGroup{}, s.groupErr(log, err)
+	}
+
+	s.cacheInvalidator.InvalidateCache(group.AppID)
+
+	log.Info("group updated", slog.Int("members", len(group.Members)))
+
+	updated, err := s.groupSaver.ScimGroup(ctx, group.AppID, group.ID)
+	if err != nil {
+		return models.ScimGroup{}, s.groupErr(log, err)
+	}
+
+	return updated, nil
+}
+
+// audit saves audit event of the change made by the provisioning client of the application
+func (s *Scim) audit(ctx context.Context, caller models.TokenInfo, action string, userID int64) error {
+	return s.auditSaver.SaveAuditEvent(ctx, models.AuditEvent{
+		Action:       action,
+		TargetUserID: userID,
+		AppID:        caller.AppID,
+	})
+}
+
+// storageErr converts errors of the users storage to errors of the service
+func (s *Scim) storageErr(log *slog.Logger, err error) error {
+	switch {
+	case errors.Is(err, storage.ErrUserNotFound):
+		log.Warn("user not found", sl.Err(err))
+
+		return ErrUserNotFound
+	case errors.Is(err, storage.ErrUserExists):
+		log.Warn("user already exists", sl.Err(err))
+
+		return ErrUserExists
+	case errors.Is(err, storage.ErrVersionConflict):
+		log.Warn("user is changed concurrently", sl.Err(err))
+
+		return ErrVersionMismatch
+	case errors.Is(err, storage.ErrLastSuperAdmin):
+		log.Warn("attempt to delete last super-admin", sl.Err(err))
+
+		return ErrLastSuperAdmin
+	case errors.Is(err, storage.ErrInvalidFilter):
+		log.Warn("filter can't be applied", sl.Err(err))
+
+		return ErrInvalidFilter
+	}
+
+	log.Error("failed to access users", sl.Err(err))
+
+	return err
+}
+
+// groupErr converts errors of the groups storage to errors of the service
+func (s *Scim) groupErr(log *slog.Logger, err error) error {
+	switch {
+	case errors.Is(err, storage.ErrGroupNotFound):
+		log.Warn("group not found", sl.Err(err))
+
+		return ErrGroupNotFound
+	case errors.Is(err, storage.ErrGroupExists):
+		log.Warn("group already exists", sl.Err(err))
+
+		return ErrGroupExists
+	case errors.Is(err, storage.ErrUserNotFound):
+		log.Warn("member of the group not found", sl.Err(err))
+
+		return ErrInvalidValue
+	case errors.Is(err, storage.ErrVersionConflict):
+		log.Warn("group is changed concurrently", sl.Err(err))
+
+		return ErrVersionMismatch
+	case errors.Is(err, storage.ErrInvalidFilter):
+		log.Warn("filter can't be applied", sl.Err(err))
+
+		return ErrInvalidFilter
+	}
+
+	log.Error("failed to access groups", sl.Err(err))
+
+	return err
+}
+
+// authorize checks that the caller is the application with the scope of the provisioning client,
+// tokens of the users are not accepted
+func authorize(caller models.TokenInfo) error {
+	if !caller.Active || caller.UserID != 0 || !slices.Contains(caller.Scopes, Scope) {
+		return ErrPermissionDenied
+	}
+
+	return nil
+}
+
+func parseFilter(log *slog.Logger, filter string) (scimexpr.Expr, error) {
+	if strings.TrimSpace(filter) == "" {
+		return nil, nil
+	}
+
+	expr, err := scimexpr.Parse(filter)
+	if err != nil {
+		log.Warn("invalid filter", sl.Err(err))
+
+		return nil, ErrInvalidFilter
+	}
+
+	return expr, nil
+}
+
+// page converts 1-based start index and count of SCIM to offset and limit
+func page(startIndex int, count int) (offset int, limit int) {
+	return max(startIndex, 1) - 1, min(max(count, 0), MaxCount)
+}
+
+func validateUser(user models.ScimUser) error {
+	if strings.TrimSpace(user.UserName) == "" || !strings.Contains(user.UserName, "@") {
+		return ErrInvalidValue
+	}
+
+	return nil
+}
+
+func validateGroup(group models.ScimGroup) error {
+	if strings.TrimSpace(group.DisplayName) == "" {
+		return ErrInvalidValue
+	}
+
+	return nil
+}
+
+// uniqueMembers returns the members without duplicates in their order
+func uniqueMembers(members []models.ScimMember) []models.ScimMember {
+	unique := make([]models.ScimMember, 0, len(members))
+	seen := make(map[int64]bool, len(members))
+
+	for _, member := range members {
+		if !seen[member.UserID] {
+			seen[member.UserID] = true
+			unique = append(unique, member)
+		}
+	}
+
+	return unique
+}
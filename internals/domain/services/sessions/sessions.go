@@ -0,0 +1,322 @@
+package sessions
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/nhassl3/sso-app/internals/domain/models"
+	njwt "github.com/nhassl3/sso-app/internals/lib/jwt"
+	"github.com/nhassl3/sso-app/internals/lib/logger/sl"
+	"github.com/nhassl3/sso-app/internals/lib/random"
+	"github.com/nhassl3/sso-app/internals/storage"
+)
+
+const (
+	opLogout           = "sessions.Logout"
+	opRevokeSessions   = "sessions.RevokeSessions"
+	opLogoutDeliveries = "sessions.LogoutDeliveries"
+	opDeliverLogouts   = "sessions.DeliverLogouts"
+
+	// Logout tokens are signed for every attempt, so they may live as short as OpenID Connect Back-Channel Logout advises
+	logoutTokenTTL = 2 * time.Minute
+	logoutEvent    = "http://schemas.openid.net/event/backchannel-logout"
+	jtiSize        = 16
+
+	deliveryBatchSize       = 100
+	defaultDeliveriesLimit  = 100
+	maxDeliveryErrorLen     = 200
+	maxRetryBackoffDoubling = 16
+)
+
+var (
+	ErrInvalidAppID     = errors.New("invalid application ID")
+	ErrPermissionDenied = errors.New("permission denied")
+
+	errNoLogoutURI = errors.New("application has no back-channel logout URI")
+)
+
+type Sessions struct {
+	log           *slog.Logger
+	sessionSaver  SessionSaver
+	deliverySaver DeliverySaver
+	adminProvider AdminProvider
+	auditSaver    AuditSaver
+	signingKey    *njwt.SigningKey
+	issuer        string
+	client        *http.Client
+	maxAttempts   int
+	retryBackoff  time.Duration
+}
+
+// NewSessions returns a new instance of the Sessions service
+func NewSessions(
+	log *slog.Logger,
+	sessionSaver SessionSaver,
+	deliverySaver DeliverySaver,
+	adminProvider AdminProvider,
+	auditSaver AuditSaver,
+	signingKey *njwt.SigningKey,
+	issuer string,
+	client *http.Client,
+	maxAttempts int,
+	retryBackoff time.Duration,
+) *Sessions {
+	return &Sessions{
+		log:           log,
+		sessionSaver:  sessionSaver,
+		deliverySaver: deliverySaver,
+		adminProvider: adminProvider,
+		auditSaver:    auditSaver,
+		signingKey:    signingKey,
+		issuer:        issuer,
+		client:        client,
+		maxAttempts:   maxAttempts,
+		retryBackoff:  retryBackoff,
+	}
+}
+
+type SessionSaver interface {
+	EndSessions(ctx context.Context, userID int64, appID int32) (notified int, err error)
+}
+
+type DeliverySaver interface {
+	DueLogoutDeliveries(ctx context.Context, now time.Time, limit int) (deliveries []models.LogoutDelivery, err error)
+	UpdateLogoutDelivery(ctx context.Context, delivery models.LogoutDelivery) error
+	LogoutDeliveries(ctx context.Context, appID int32, limit int) (deliveries []models.LogoutDelivery, err error)
+}
+
+type AdminProvider interface {
+	IsAdmin(ctx context.Context, userID int64) (isAdmin bool, err error)
+	IsAppAdmin(ctx context.Context, userID int64, appID int32) (isAdmin bool, isSuperAdmin bool, err error)
+}
+
+type AuditSaver interface {
+	SaveAuditEvent(ctx context.Context, event models.AuditEvent) error
+}
+
+// Logout ends sessions of the user in the application, zero app ID ends sessions in all applications.
+// Applications are notified by back-channel logout. Returns count of the notified applications
+func (s *Sessions) Logout(ctx context.Context, userID int64, appID int32) (notified int, err error) {
+	log := s.log.With(slog.String("op", opLogout), slog.Int64("user_id", userID), slog.Int("app_id", int(appID)))
+
+	notified, err = s.sessionSaver.EndSessions(ctx, userID, appID)
+	if err != nil {
+		log.Error("failed to end sessions", sl.Err(err))
+
+		return 0, sl.ErrUpLevel(opLogout, err)
+	}
+
+	log.Info("user logged out", slog.Int("notified", notified))
+
+	return
+}
+
+// RevokeSessions ends sessions of the user in the application on behalf of the admin of the application,
+// zero app ID ends sessions in all applications and requires super-admin rights. Returns count of the notified applications
+func (s *Sessions) RevokeSessions(ctx context.Context, actorID int64, userID int64, appID int32) (notified int, err error) {
+	log := s.log.With(
+		slog.String("op", opRevokeSessions),
+		slog.Int64("actor_id", actorID),
+		slog.Int64("user_id", userID),
+		slog.Int("app_id", int(appID)),
+	)
+
+	if err := s.authorize(ctx, actorID, appID); err != nil {
+		log.Warn("failed to authorize actor", sl.Err(err))
+
+		return 0, sl.ErrUpLevel(opRevokeSessions, err)
+	}
+
+	notified, err = s.sessionSaver.EndSessions(ctx, userID, appID)
+	if err != nil {
+		log.Error("failed to end sessions", sl.Err(err))
+
+		return 0, sl.ErrUpLevel(opRevokeSessions, err)
+	}
+
+	err = s.auditSaver.SaveAuditEvent(ctx, models.AuditEvent{
+		ActorID:      actorID,
+		Action:       models.AuditActionRevokeSessions,
+		TargetUserID: userID,
+		AppID:        appID,
+		Details:      fmt.Sprintf("%d applications notified", notified),
+	})
+	if err != nil {
+		log.Error("failed to save audit event", sl.Err(err))
+
+		return 0, sl.ErrUpLevel(opRevokeSessions, err)
+	}
+
+	log.Info("sessions revoked", slog.Int("notified", notified))
+
+	return
+}
+
+// LogoutDeliveries returns the latest logout deliveries to the application, zero app ID returns deliveries
+// to all applications and requires super-admin rights
+func (s *Sessions) LogoutDeliveries(
+	ctx context.Context,
+	actorID int64,
+	appID int32,
+	limit int,
+) (deliveries []models.LogoutDelivery, err error) {
+	log := s.log.With(slog.String("op", opLogoutDeliveries), slog.Int64("actor_id", actorID))
+
+	if err := s.authorize(ctx, actorID, appID); err != nil {
+		log.Warn("failed to authorize actor", sl.Err(err))
+
+		return nil, sl.ErrUpLevel(opLogoutDeliveries, err)
+	}
+
+	if limit <= 0 {
+		limit = defaultDeliveriesLimit
+	}
+
+	deliveries, err = s.deliverySaver.LogoutDeliveries(ctx, appID, limit)
+	if err != nil {
+		log.Error("failed to get logout deliveries", sl.Err(err))
+
+		return nil, sl.ErrUpLevel(opLogoutDeliveries, err)
+	}
+
+	return
+}
+
+// DeliverLogouts sends logout tokens of the due deliveries to back-channel logout URIs of the applications.
+// Failed delivery is retried with doubling backoff until it runs out of attempts. Returns count of the delivered tokens
+func (s *Sessions) DeliverLogouts(ctx context.Context) (delivered int, err error) {
+	log := s.log.With(slog.String("op", opDeliverLogouts))
+
+	deliveries, err := s.deliverySaver.DueLogoutDeliveries(ctx, time.Now(), deliveryBatchSize)
+	if err != nil {
+		log.Error("failed to get due logout deliveries", sl.Err(err))
+
+		return 0, sl.ErrUpLevel(opDeliverLogouts, err)
+	}
+
+	for _, delivery := range deliveries {
+		log := log.With(slog.Int64("delivery_id", delivery.ID), slog.Int("app_id", int(delivery.AppID)))
+
+		delivery.Attempts++
+
+		sendErr := s.send(ctx, delivery)
+		switch {
+		case sendErr == nil:
+			delivery.Status = models.LogoutDelivered
+			delivery.DeliveredAt = time.Now()
+			delivery.LastError = ""
+			delivered++
+		case delivery.Attempts >= s.maxAttempts || errors.Is(sendErr, errNoLogoutURI):
+			log.Warn("logout delivery failed", sl.Err(sendErr), slog.Int("attempts", delivery.Attempts))
+
+			delivery.Status = models.LogoutFailed
+			delivery.LastError = truncate(sendErr.Error(), maxDeliveryErrorLen)
+		default:
+			log.Info("logout delivery will be retried", sl.Err(sendErr), slog.Int("attempts", delivery.Attempts))
+
+			delivery.NextAttemptAt = time.Now().Add(s.retryBackoff << min(delivery.Attempts-1, maxRetryBackoffDoubling))
+			delivery.LastError = truncate(sendErr.Error(), maxDeliveryErrorLen)
+		}
+
+		if err := s.deliverySaver.UpdateLogoutDelivery(ctx, delivery); err != nil {
+			log.Error("failed to update logout delivery", sl.Err(err))
+
+			return delivered, sl.ErrUpLevel(opDeliverLogouts, err)
+		}
+	}
+
+	return
+}
+
+// send posts logout token of the delivery to back-channel logout URI of the application
+func (s *Sessions) send(ctx context.Context, delivery models.LogoutDelivery) error {
+	if delivery.LogoutURI == "" {
+		return errNoLogoutURI
+	}
+
+	jti, err := random.String(jtiSize)
+	if err != nil {
+		return err
+	}
+
+	logoutToken, err := s.signingKey.NewLogoutToken(map[string]any{
+		"iss":    s.issuer,
+		"aud":    strconv.Itoa(int(delivery.AppID)),
+		"sub":    strconv.FormatInt(delivery.UserID, 10),
+		"jti":    jti,
+		"events": map[string]any{logoutEvent: map[string]any{}},
+	}, logoutTokenTTL)
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequestWithContext(
+		ctx,
+		http.MethodPost,
+		delivery.LogoutURI,
+		strings.NewReader(url.Values{"logout_token": {logoutToken}}.Encode()),
+	)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < http.StatusOK || resp.StatusCode >= http.StatusMultipleChoices {
+		return fmt.Errorf("logout uri responded with status %d", resp.StatusCode)
+	}
+
+	return nil
+}
+
+// authorize checks that actor is admin of the application, zero app ID requires super-admin rights
+func (s *Sessions) authorize(ctx context.Context, actorID int64, appID int32) error {
+	if appID == 0 {
+		isSuperAdmin, err := s.adminProvider.IsAdmin(ctx, actorID)
+		if err != nil {
+			return err
+		}
+
+		if !isSuperAdmin {
+			return ErrPermissionDenied
+		}
+
+		return nil
+	}
+
+	isAdmin, _, err := s.adminProvider.IsAppAdmin(ctx, actorID, appID)
+	if err != nil {
+		if errors.Is(err, storage.ErrAppNotFound) {
+			return ErrInvalidAppID
+		}
+
+		return err
+	}
+
+	if !isAdmin {
+		return ErrPermissionDenied
+	}
+
+	return nil
+}
+
+// truncate cuts the message to the size, so errors of the receivers don't bloat the storage
+func truncate(message string, size int) string {
+	if len(message) <= size {
+		return message
+	}
+
+	return message[:size]
+}
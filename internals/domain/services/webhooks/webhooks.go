@@ -0,0 +1,483 @@
+package webhooks
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"slices"
+	"strconv"
+	"time"
+
+	"github.com/nhassl3/sso-app/internals/domain/models"
+	"github.com/nhassl3/sso-app/internals/lib/logger/sl"
+	"github.com/nhassl3/sso-app/internals/lib/random"
+	"github.com/nhassl3/sso-app/internals/storage"
+)
+
+const (
+	opCreateSubscription = "webhooks.CreateSubscription"
+	opSubscriptions      = "webhooks.Subscriptions"
+	opDeleteSubscription = "webhooks.DeleteSubscription"
+	opDeliveries         = "webhooks.Deliveries"
+	opRedeliver          = "webhooks.Redeliver"
+	opPublish            = "webhooks.Publish"
+	opDeliverWebhooks    = "webhooks.DeliverWebhooks"
+
+	secretSize = 32
+
+	deliveryBatchSize       = 100
+	defaultDeliveriesLimit  = 100
+	maxDeliveryErrorLen     = 200
+	maxRetryBackoffDoubling = 16
+
+	// Headers of the requests follow Standard Webhooks, so receivers may verify them by its libraries
+	headerID        = "Webhook-Id"
+	headerTimestamp = "Webhook-Timestamp"
+	headerSignature = "Webhook-Signature"
+)
+
+var (
+	ErrInvalidAppID         = errors.New("invalid application ID")
+	ErrPermissionDenied     = errors.New("permission denied")
+	ErrInvalidURL           = errors.New("invalid webhook URL")
+	ErrUnknownEvent         = errors.New("unknown event type")
+	ErrInvalidStatus        = errors.New("invalid delivery status")
+	ErrSubscriptionNotFound = errors.New("webhook subscription not found")
+	ErrDeliveryNotFound     = errors.New("webhook delivery not found")
+)
+
+type Webhooks struct {
+	log               *slog.Logger
+	subscriptionSaver SubscriptionSaver
+	deliverySaver     DeliverySaver
+	adminProvider     AdminProvider
+	client            *http.Client
+	maxAttempts       int
+	retryBackoff      time.Duration
+}
+
+// NewWebhooks returns a new instance of the Webhooks service
+func NewWebhooks(
+	log *slog.Logger,
+	subscriptionSaver SubscriptionSaver,
+	deliverySaver DeliverySaver,
+	adminProvider AdminProvider,
+	client *http.Client,
+	maxAttempts int,
+	retryBackoff time.Duration,
+) *Webhooks {
+	return &Webhooks{
+		log:               log,
+		subscriptionSaver: subscriptionSaver,
+		deliverySaver:     deliverySaver,
+		adminProvider:     adminProvider,
+		client:            client,
+		maxAttempts:       maxAttempts,
+		retryBackoff:      retryBackoff,
+	}
+}
+
+type SubscriptionSaver interface {
+	SaveWebhookSubscription(
+		ctx context.Context,
+		actorID int64,
+		subscription models.WebhookSubscription,
+	) (subscriptionID int64, err error)
+	WebhookSubscriptions(ctx context.Context, appID int32) (subscriptions []models.WebhookSubscription, err error)
+	DeleteWebhookSubscription(ctx context.Context, actorID int64, appID int32, subscriptionID int64) error
+}
+
+type DeliverySaver interface {
+	SaveWebhookEvent(ctx context.Context, event models.Event, payload []byte) (queued int, err error)
+	DueWebhookDeliveries(ctx context.Context, now time.Time, limit int) (deliveries []models.WebhookDelivery, err error)
+	UpdateWebhookDelivery(ctx context.Context, delivery models.WebhookDelivery) error
+	WebhookDeliveries(
+		ctx context.Context,
+		appID int32,
+		status string,
+		limit int,
+	) (deliveries []models.WebhookDelivery, err error)
+	RedeliverWebhook(ctx context.Context, actorID int64, appID int32, deliveryID int64, now time.Time) error
+}
+
+type AdminProvider interface {
+	IsAppAdmin(ctx context.Context, userID int64, appID int32) (isAdmin bool, isSuperAdmin bool, err error)
+}
+
+// payload is JSON body of the webhook request
+type payload struct {
+	ID        string      `json:"id"`
+	Type      string      `json:"type"`
+	CreatedAt time.Time   `json:"created_at"`
+	AppID     int32       `json:"app_id,omitempty"`
+	Data      payloadData `json:"data"`
+}
+
+type payloadData struct {
+	UserID int64  `json:"user_id"`
+	Email  string `json:"email,omitempty"`
+}
+
+// CreateSubscription subscribes the URL to the events of the application, empty events subscribe to all events.
+// Returns the subscription with generated secret signing the payloads, it is returned only here.
+// Actor must be admin of the application, subscription of all users gets events which aren't bound
+// to an application and is created by super-admin only
+func (w *Webhooks) CreateSubscription(
+	ctx context.Context,
+	actorID int64,
+	appID int32,
+	webhookURL string,
+	events []string,
+	allUsers bool,
+) (subscription models.WebhookSubscription, err error) {
+	log := w.log.With(slog.String("op", opCreateSubscription), slog.Int64("actor_id", actorID), slog.Int("app_id", int(appID)))
+
+	isSuperAdmin, err := w.authorizeSuperAdmin(ctx, actorID, appID)
+	if err != nil {
+		log.Warn("failed to authorize actor", sl.Err(err))
+
+		return models.WebhookSubscription{}, sl.ErrUpLevel(opCreateSubscription, err)
+	}
+
+	if allUsers && !isSuperAdmin {
+		log.Warn("subscription of all users requires super-admin rights")
+
+		return models.WebhookSubscription{}, sl.ErrUpLevel(opCreateSubscription, ErrPermissionDenied)
+	}
+
+	if err := validateSubscription(webhookURL, events); err != nil {
+		log.Warn("invalid subscription", sl.Err(err))
+
+		return models.WebhookSubscription{}, sl.ErrUpLevel(opCreateSubscription, err)
+	}
+
+	secret, err := random.String(secretSize)
+	if err != nil {
+		log.Error("failed to generate secret", sl.Err(err))
+
+		return models.WebhookSubscription{}, sl.ErrUpLevel(opCreateSubscription, err)
+	}
+
+	subscription = models.WebhookSubscription{
+		AppID:     appID,
+		URL:       webhookURL,
+		Secret:    secret,
+		Events:    events,
+		AllUsers:  allUsers,
+		CreatedAt: time.Now(),
+	}
+
+	subscription.ID, err = w.subscriptionSaver.SaveWebhookSubscription(ctx, actorID, subscription)
+	if err != nil {
+		return models.WebhookSubscription{}, sl.ErrUpLevel(opCreateSubscription, w.storageErr(log, err))
+	}
+
+	log.Info("webhook subscription created", slog.Int64("subscription_id", subscription.ID))
+
+	return
+}
+
+// Subscriptions returns subscriptions of the application, actor must be admin of the application
+func (w *Webhooks) Subscriptions(
+	ctx context.Context,
+	actorID int64,
+	appID int32,
+) (subscriptions []models.WebhookSubscription, err error) {
+	log := w.log.With(slog.String("op", opSubscriptions), slog.Int64("actor_id", actorID))
+
+	if err := w.authorize(ctx, actorID, appID); err != nil {
+		log.Warn("failed to authorize actor", sl.Err(err))
+
+		return nil, sl.ErrUpLevel(opSubscriptions, err)
+	}
+
+	subscriptions, err = w.subscriptionSaver.WebhookSubscriptions(ctx, appID)
+	if err != nil {
+		log.Error("failed to get webhook subscriptions", sl.Err(err))
+
+		return nil, sl.ErrUpLevel(opSubscriptions, err)
+	}
+
+	return
+}
+
+// DeleteSubscription deletes the subscription of the application with its deliveries,
+// actor must be admin of the application
+func (w *Webhooks) DeleteSubscription(ctx context.Context, actorID int64, appID int32, subscriptionID int64) error {
+	log := w.log.With(
+		slog.String("op", opDeleteSubscription),
+		slog.Int64("actor_id", actorID),
+		slog.Int64("subscription_id", subscriptionID),
+	)
+
+	if err := w.authorize(ctx, actorID, appID); err != nil {
+		log.Warn("failed to authorize actor", sl.Err(err))
+
+		return sl.ErrUpLevel(opDeleteSubscription, err)
+	}
+
+	if err := w.subscriptionSaver.DeleteWebhookSubscription(ctx, actorID, appID, subscriptionID); err != nil {
+		return sl.ErrUpLevel(opDeleteSubscription, w.storageErr(log, err))
+	}
+
+	log.Info("webhook subscription deleted")
+
+	return nil
+}
+
+// Deliveries returns the latest deliveries of the application with the status, empty status returns all of them.
+// Dead deliveries are the dead-letter list. Actor must be admin of the application
+func (w *Webhooks) Deliveries(
+	ctx context.Context,
+	actorID int64,
+	appID int32,
+	status string,
+	limit int,
+) (deliveries []models.WebhookDelivery, err error) {
+	log := w.log.With(slog.String("op", opDeliveries), slog.Int64("actor_id", actorID))
+
+	if err := w.authorize(ctx, actorID, appID); err != nil {
+		log.Warn("failed to authorize actor", sl.Err(err))
+
+		return nil, sl.ErrUpLevel(opDeliveries, err)
+	}
+
+	if status != "" && status != models.WebhookPending && status != models.WebhookDelivered && status != models.WebhookDead {
+		log.Warn("invalid status", slog.String("status", status))
+
+		return nil, sl.ErrUpLevel(opDeliveries, ErrInvalidStatus)
+	}
+
+	if limit <= 0 {
+		limit = defaultDeliveriesLimit
+	}
+
+	deliveries, err = w.deliverySaver.WebhookDeliveries(ctx, appID, status, limit)
+	if err != nil {
+		log.Error("failed to get webhook deliveries", sl.Err(err))
+
+		return nil, sl.ErrUpLevel(opDeliveries, err)
+	}
+
+	return
+}
+
+// Redeliver queues the delivered or dead delivery of the application again with fresh attempts,
+// receiver gets the same payload and event ID. Actor must be admin of the application
+func (w *Webhooks) Redeliver(ctx context.Context, actorID int64, appID int32, deliveryID int64) error {
+	log := w.log.With(slog.String("op", opRedeliver), slog.Int64("actor_id", actorID), slog.Int64("delivery_id", deliveryID))
+
+	if err := w.authorize(ctx, actorID, appID); err != nil {
+		log.Warn("failed to authorize actor", sl.Err(err))
+
+		return sl.ErrUpLevel(opRedeliver, err)
+	}
+
+	if err := w.deliverySaver.RedeliverWebhook(ctx, actorID, appID, deliveryID, time.Now()); err != nil {
+		return sl.ErrUpLevel(opRedeliver, w.storageErr(log, err))
+	}
+
+	log.Info("webhook delivery queued again")
+
+	return nil
+}
+
+// Publish queues the event for the subscribed webhooks, the same payload is sent to all of them.
+// Events the webhooks can't subscribe to are skipped, the event published again is not queued twice
+func (w *Webhooks) Publish(ctx context.Context, event models.Event) error {
+	log := w.log.With(slog.String("op", opPublish), slog.String("type", event.Type), slog.Int64("user_id", event.UserID))
+
+	if !slices.Contains(models.WebhookEventTypes, event.Type) {
+		return nil
+	}
+
+	body, err := json.Marshal(payload{
+		ID:        event.ID,
+		Type:      event.Type,
+		CreatedAt: event.CreatedAt.UTC(),
+		AppID:     event.AppID,
+		Data:      payloadData{UserID: event.UserID, Email: event.Email},
+	})
+	if err != nil {
+		log.Error("failed to encode payload", sl.Err(err))
+
+		return sl.ErrUpLevel(opPublish, err)
+	}
+
+	queued, err := w.deliverySaver.SaveWebhookEvent(ctx, event, body)
+	if err != nil {
+		log.Error("failed to queue webhook deliveries", sl.Err(err))
+
+		return sl.ErrUpLevel(opPublish, err)
+	}
+
+	log.Debug("webhook deliveries queued", slog.String("event_id", event.ID), slog.Int("queued", queued))
+
+	return nil
+}
+
+// DeliverWebhooks posts payloads of the due deliveries to URLs of the subscriptions. Failed delivery is retried
+// with doubling backoff until it runs out of attempts and goes to the dead-letter list. Returns count of the delivered payloads
+func (w *Webhooks) DeliverWebhooks(ctx context.Context) (delivered int, err error) {
+	log := w.log.With(slog.String("op", opDeliverWebhooks))
+
+	deliveries, err := w.deliverySaver.DueWebhookDeliveries(ctx, time.Now(), deliveryBatchSize)
+	if err != nil {
+		log.Error("failed to get due webhook deliveries", sl.Err(err))
+
+		return 0, sl.ErrUpLevel(opDeliverWebhooks, err)
+	}
+
+	for _, delivery := range deliveries {
+		log := log.With(slog.Int64("delivery_id", delivery.ID), slog.Int("app_id", int(delivery.AppID)))
+
+		delivery.Attempts++
+
+		sendErr := w.send(ctx, delivery)
+		switch {
+		case sendErr == nil:
+			delivery.Status = models.WebhookDelivered
+			delivery.DeliveredAt = time.Now()
+			delivery.LastError = ""
+			delivered++
+		case delivery.Attempts >= w.maxAttempts:
+			log.Warn("webhook delivery is dead", sl.Err(sendErr), slog.Int("attempts", delivery.Attempts))
+
+			delivery.Status = models.WebhookDead
+			delivery.LastError = truncate(sendErr.Error(), maxDeliveryErrorLen)
+		default:
+			log.Info("webhook delivery will be retried", sl.Err(sendErr), slog.Int("attempts", delivery.Attempts))
+
+			delivery.NextAttemptAt = time.Now().Add(w.retryBackoff << min(delivery.Attempts-1, maxRetryBackoffDoubling))
+			delivery.LastError = truncate(sendErr.Error(), maxDeliveryErrorLen)
+		}
+
+		if err := w.deliverySaver.UpdateWebhookDelivery(ctx, delivery); err != nil {
+			log.Error("failed to update webhook delivery", sl.Err(err))
+
+			return delivered, sl.ErrUpLevel(opDeliverWebhooks, err)
+		}
+	}
+
+	return
+}
+
+// send posts payload of the delivery signed by the secret of the subscription
+func (w *Webhooks) send(ctx context.Context, delivery models.WebhookDelivery) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, delivery.URL, bytes.NewReader(delivery.Payload))
+	if err != nil {
+		return err
+	}
+
+	timestamp := strconv.FormatInt(time.Now().Unix(), 10)
+
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(headerID, delivery.EventID)
+	req.Header.Set(headerTimestamp, timestamp)
+	req.Header.Set(headerSignature, "v1,"+sign(delivery.Secret, delivery.EventID, timestamp, delivery.Payload))
+
+	resp, err := w.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < http.StatusOK || resp.StatusCode >= http.StatusMultipleChoices {
+		return fmt.Errorf("webhook responded with status %d", resp.StatusCode)
+	}
+
+	return nil
+}
+
+// sign returns base64 of HMAC-SHA256 of the event ID, the timestamp and the payload joined by dots
+func sign(secret string, eventID string, timestamp string, payload []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(eventID + "." + timestamp + "."))
+	mac.Write(payload)
+
+	return base64.StdEncoding.EncodeToString(mac.Sum(nil))
+}
+
+// validateSubscription checks that URL is absolute HTTP URL without fragment and events are known
+func validateSubscription(webhookURL string, events []string) error {
+	u, err := url.Parse(webhookURL)
+	if err != nil {
+		return fmt.Errorf("%w: %w", ErrInvalidURL, err)
+	}
+
+	if (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" || u.Fragment != "" {
+		return fmt.Errorf("%w: %q must be absolute HTTP URL without fragment", ErrInvalidURL, webhookURL)
+	}
+
+	for _, event := range events {
+		if !slices.Contains(models.WebhookEventTypes, event) {
+			return fmt.Errorf("%w: %q", ErrUnknownEvent, event)
+		}
+	}
+
+	return nil
+}
+
+// authorize checks that actor is admin of the application
+func (w *Webhooks) authorize(ctx context.Context, actorID int64, appID int32) error {
+	_, err := w.authorizeSuperAdmin(ctx, actorID, appID)
+
+	return err
+}
+
+// authorizeSuperAdmin checks that actor is admin of the application and reports whether actor is super-admin
+func (w *Webhooks) authorizeSuperAdmin(ctx context.Context, actorID int64, appID int32) (isSuperAdmin bool, err error) {
+	isAdmin, isSuperAdmin, err := w.adminProvider.IsAppAdmin(ctx, actorID, appID)
+	if err != nil {
+		if errors.Is(err, storage.ErrAppNotFound) {
+			return false, ErrInvalidAppID
+		}
+
+		return false, err
+	}
+
+	if !isAdmin {
+		return false, ErrPermissionDenied
+	}
+
+	return isSuperAdmin, nil
+}
+
+// storageErr converts storage errors to errors of the service, unexpected errors are logged
+func (w *Webhooks) storageErr(log *slog.Logger, err error) error {
+	switch {
+	case errors.Is(err, storage.ErrAppNotFound):
+		log.Warn("failed to found app in the system", sl.Err(err))
+
+		return ErrInvalidAppID
+	case errors.Is(err, storage.ErrWebhookNotFound):
+		log.Warn("webhook subscription not found", sl.Err(err))
+
+		return ErrSubscriptionNotFound
+	case errors.Is(err, storage.ErrDeliveryNotFound):
+		log.Warn("webhook delivery not found or pending", sl.Err(err))
+
+		return ErrDeliveryNotFound
+	}
+
+	log.Error("storage failed", sl.Err(err))
+
+	return err
+}
+
+// truncate cuts the message to the size, so errors of the receivers don't bloat the storage
+func truncate(message string, size int) string {
+	if len(message) <= size {
+		return message
+	}
+
+	return message[:size]
+}
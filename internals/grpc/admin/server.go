@@ -0,0 +1,349 @@
+package admin
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	adminv1 "github.com/nhassl3/sso-app/contracts/generated/go/admin"
+	"github.com/nhassl3/sso-app/internals/domain/models"
+	"github.com/nhassl3/sso-app/internals/domain/services/admin"
+	"github.com/nhassl3/sso-app/internals/grpc/interceptors"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+type Admin interface {
+	IsAppAdmin(
+		ctx context.Context,
+		userID int64,
+		appID int32,
+	) (isAdmin bool, isSuperAdmin bool, err error)
+	GrantAdmin(
+		ctx context.Context,
+		caller models.TokenInfo,
+		userID int64,
+		appID int32,
+		ttl time.Duration,
+	) (granted bool, err error)
+	RevokeAdmin(
+		ctx context.Context,
+		caller models.TokenInfo,
+		userID int64,
+		appID int32,
+	) (revoked bool, err error)
+	Admins(
+		ctx context.Context,
+		caller models.TokenInfo,
+		appID int32,
+	) (admins []models.Admin, err error)
+	AuditEvents(
+		ctx context.Context,
+		caller models.TokenInfo,
+		appID int32,
+		limit int,
+	) (events []models.AuditEvent, err error)
+	RequestElevation(
+		ctx context.Context,
+		caller models.TokenInfo,
+		appID int32,
+		ttl time.Duration,
+		reason string,
+	) (requestID int64, err error)
+	ApproveElevation(ctx context.Context, caller models.TokenInfo, requestID int64) error
+	DenyElevation(ctx context.Context, caller models.TokenInfo, requestID int64) error
+	ElevationRequests(
+		ctx context.Context,
+		caller models.TokenInfo,
+		appID int32,
+		pendingOnly bool,
+	) (requests []models.ElevationRequest, err error)
+}
+
+type ServerAPI struct {
+	adminv1.UnimplementedAdminServer
+	admin Admin
+}
+
+func Register(gRPC *grpc.Server, admin Admin) {
+	adminv1.RegisterAdminServer(gRPC, &ServerAPI{admin: admin})
+}
+
+// IsAppAdmin handler. Return boolean value if user have admin rights in the application
+func (s *ServerAPI) IsAppAdmin(ctx context.Context, in *adminv1.IsAppAdminRequest) (*adminv1.IsAppAdminResponse, error) {
+	if in.GetUserId() <= 0 {
+		return nil, status.Error(codes.InvalidArgument, "invalid user id")
+	}
+
+	if in.GetAppId() <= 0 {
+		return nil, status.Error(codes.InvalidArgument, "invalid app id")
+	}
+
+	isAdmin, isSuperAdmin, err := s.admin.IsAppAdmin(ctx, in.GetUserId(), in.GetAppId())
+	if err != nil {
+		return nil, adminError(err)
+	}
+
+	return &adminv1.IsAppAdminResponse{
+		IsAdmin:      isAdmin,
+		IsSuperAdmin: isSuperAdmin,
+	}, nil
+}
+
+// GrantAdmin handler. Gives admin rights in the application to the user, does nothing if user already has them
+func (s *ServerAPI) GrantAdmin(ctx context.Context, in *adminv1.GrantAdminRequest) (*adminv1.GrantAdminResponse, error) {
+	caller, err := interceptors.MustAdmin(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	if in.GetUserId() <= 0 {
+		return nil, status.Error(codes.InvalidArgument, "invalid user id")
+	}
+
+	if in.GetAppId() < 0 {
+		return nil, status.Error(codes.InvalidArgument, "invalid app id")
+	}
+
+	if in.GetTtlSeconds() < 0 {
+		return nil, status.Error(codes.InvalidArgument, "invalid ttl")
+	}
+
+	granted, err := s.admin.GrantAdmin(
+		ctx, caller, in.GetUserId(), in.GetAppId(), time.Duration(in.GetTtlSeconds())*time.Second,
+	)
+	if err != nil {
+		return nil, adminError(err)
+	}
+
+	return &adminv1.GrantAdminResponse{
+		Granted: granted,
+	}, nil
+}
+
+// RevokeAdmin handler. Takes away admin rights in the application from the user, does nothing if user doesn't have them
+func (s *ServerAPI) RevokeAdmin(ctx context.Context, in *adminv1.RevokeAdminRequest) (*adminv1.RevokeAdminResponse, error) {
+	caller, err := interceptors.MustAdmin(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	if in.GetUserId() <= 0 {
+		return nil, status.Error(codes.InvalidArgument, "invalid user id")
+	}
+
+	if in.GetAppId() < 0 {
+		return nil, status.Error(codes.InvalidArgument, "invalid app id")
+	}
+
+	revoked, err := s.admin.RevokeAdmin(ctx, caller, in.GetUserId(), in.GetAppId())
+	if err != nil {
+		return nil, adminError(err)
+	}
+
+	return &adminv1.RevokeAdminResponse{
+		Revoked: revoked,
+	}, nil
+}
+
+// ListAdmins handler. Returns admins which have rights in the application
+func (s *ServerAPI) ListAdmins(ctx context.Context, in *adminv1.ListAdminsRequest) (*adminv1.ListAdminsResponse, error) {
+	caller, err := interceptors.MustAdmin(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	if in.GetAppId() < 0 {
+		return nil, status.Error(codes.InvalidArgument, "invalid app id")
+	}
+
+	admins, err := s.admin.Admins(ctx, caller, in.GetAppId())
+	if err != nil {
+		return nil, adminError(err)
+	}
+
+	resp := &adminv1.ListAdminsResponse{
+		Admins: make([]*adminv1.AdminInfo, 0, len(admins)),
+	}
+	for _, a := range admins {
+		info := &adminv1.AdminInfo{
+			UserId:       a.UserID,
+			Email:        a.Email,
+			AppId:        a.AppID,
+			IsSuperAdmin: a.IsSuperAdmin(),
+		}
+		if !a.ExpiresAt.IsZero() {
+			info.ExpiresAt = a.ExpiresAt.Unix()
+		}
+
+		resp.Admins = append(resp.Admins, info)
+	}
+
+	return resp, nil
+}
+
+// ListAuditEvents handler. Returns the latest audit events of the application
+func (s *ServerAPI) ListAuditEvents(ctx context.Context, in *adminv1.ListAuditEventsRequest) (*adminv1.ListAuditEventsResponse, error) {
+	caller, err := interceptors.MustAdmin(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	if in.GetAppId() < 0 {
+		return nil, status.Error(codes.InvalidArgument, "invalid app id")
+	}
+
+	events, err := s.admin.AuditEvents(ctx, caller, in.GetAppId(), int(in.GetLimit()))
+	if err != nil {
+		return nil, adminError(err)
+	}
+
+	resp := &adminv1.ListAuditEventsResponse{
+		Events: make([]*adminv1.AuditEvent, 0, len(events)),
+	}
+	for _, e := range events {
+		resp.Events = append(resp.Events, &adminv1.AuditEvent{
+			Id:           e.ID,
+			ActorId:      e.ActorID,
+			Action:       e.Action,
+			TargetUserId: e.TargetUserID,
+			AppId:        e.AppID,
+			Details:      e.Details,
+			CreatedAt:    e.CreatedAt.Unix(),
+		})
+	}
+
+	return resp, nil
+}
+
+// RequestElevation handler. Saves request of the caller for temporary admin rights, another admin has to approve it
+func (s *ServerAPI) RequestElevation(
+	ctx context.Context,
+	in *adminv1.RequestElevationRequest,
+) (*adminv1.RequestElevationResponse, error) {
+	caller, err := interceptors.MustAdmin(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	if in.GetAppId() < 0 {
+		return nil, status.Error(codes.InvalidArgument, "invalid app id")
+	}
+
+	if in.GetTtlSeconds() < 0 {
+		return nil, status.Error(codes.InvalidArgument, "invalid ttl")
+	}
+
+	requestID, err := s.admin.RequestElevation(
+		ctx, caller, in.GetAppId(), time.Duration(in.GetTtlSeconds())*time.Second, in.GetReason(),
+	)
+	if err != nil {
+		return nil, adminError(err)
+	}
+
+	return &adminv1.RequestElevationResponse{
+		RequestId: requestID,
+	}, nil
+}
+
+// ApproveElevation handler. Approves pending request and gives requested admin rights to its author
+func (s *ServerAPI) ApproveElevation(
+	ctx context.Context,
+	in *adminv1.ApproveElevationRequest,
+) (*adminv1.ApproveElevationResponse, error) {
+	caller, err := interceptors.MustAdmin(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	if in.GetRequestId() <= 0 {
+		return nil, status.Error(codes.InvalidArgument, "invalid request id")
+	}
+
+	if err := s.admin.ApproveElevation(ctx, caller, in.GetRequestId()); err != nil {
+		return nil, adminError(err)
+	}
+
+	return &adminv1.ApproveElevationResponse{}, nil
+}
+
+// DenyElevation handler. Denies pending request for admin rights
+func (s *ServerAPI) DenyElevation(ctx context.Context, in *adminv1.DenyElevationRequest) (*adminv1.DenyElevationResponse, error) {
+	caller, err := interceptors.MustAdmin(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	if in.GetRequestId() <= 0 {
+		return nil, status.Error(codes.InvalidArgument, "invalid request id")
+	}
+
+	if err := s.admin.DenyElevation(ctx, caller, in.GetRequestId()); err != nil {
+		return nil, adminError(err)
+	}
+
+	return &adminv1.DenyElevationResponse{}, nil
+}
+
+// ListElevationRequests handler. Returns requests for admin rights in the application
+func (s *ServerAPI) ListElevationRequests(
+	ctx context.Context,
+	in *adminv1.ListElevationRequestsRequest,
+) (*adminv1.ListElevationRequestsResponse, error) {
+	caller, err := interceptors.MustAdmin(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	if in.GetAppId() < 0 {
+		return nil, status.Error(codes.InvalidArgument, "invalid app id")
+	}
+
+	requests, err := s.admin.ElevationRequests(ctx, caller, in.GetAppId(), in.GetPendingOnly())
+	if err != nil {
+		return nil, adminError(err)
+	}
+
+	resp := &adminv1.ListElevationRequestsResponse{
+		Requests: make([]*adminv1.ElevationRequest, 0, len(requests)),
+	}
+	for _, r := range requests {
+		resp.Requests = append(resp.Requests, &adminv1.ElevationRequest{
+			Id:         r.ID,
+			UserId:     r.UserID,
+			AppId:      r.AppID,
+			TtlSeconds: int64(r.TTL.Seconds()),
+			Reason:     r.Reason,
+			Status:     r.Status,
+			DecidedBy:  r.DecidedBy,
+			CreatedAt:  r.CreatedAt.Unix(),
+		})
+	}
+
+	return resp, nil
+}
+
+// adminError converts errors of the Admin service to gRPC status errors
+func adminError(err error) error {
+	switch {
+	case errors.Is(err, admin.ErrPermissionDenied):
+		return status.Error(codes.PermissionDenied, "admin rights are required")
+	case errors.Is(err, admin.ErrInvalidAppID):
+		return status.Error(codes.NotFound, "app not found")
+	case errors.Is(err, admin.ErrInvalidUserID):
+		return status.Error(codes.NotFound, "user not found")
+	case errors.Is(err, admin.ErrLastSuperAdmin):
+		return status.Error(codes.FailedPrecondition, "last super-admin can't be revoked")
+	case errors.Is(err, admin.ErrInvalidTTL):
+		return status.Error(codes.InvalidArgument, "invalid ttl")
+	case errors.Is(err, admin.ErrElevationNotFound):
+		return status.Error(codes.NotFound, "elevation request not found")
+	case errors.Is(err, admin.ErrElevationNotPending):
+		return status.Error(codes.FailedPrecondition, "elevation request is already decided")
+	case errors.Is(err, admin.ErrSelfApproval):
+		return status.Error(codes.PermissionDenied, "elevation request can't be decided by its author")
+	}
+
+	return status.Error(codes.Internal, err.Error())
+}
@@ -0,0 +1,302 @@
+package apps
+
+import (
+	"context"
+	"errors"
+	"strconv"
+	"time"
+
+	appsv1 "github.com/nhassl3/sso-app/contracts/generated/go/apps"
+	"github.com/nhassl3/sso-app/internals/domain/models"
+	"github.com/nhassl3/sso-app/internals/domain/services/apps"
+	"github.com/nhassl3/sso-app/internals/grpc/interceptors"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+type Apps interface {
+	CreateApp(
+		ctx context.Context,
+		caller models.TokenInfo,
+		name string,
+		settings models.AppSettings,
+	) (app models.App, err error)
+	UpdateApp(
+		ctx context.Context,
+		caller models.TokenInfo,
+		appID int32,
+		name string,
+		settings *models.AppSettings,
+		fields []string,
+	) (app models.App, err error)
+	SetAppDisabled(
+		ctx context.Context,
+		caller models.TokenInfo,
+		appID int32,
+		disabled bool,
+	) error
+	DeleteApp(
+		ctx context.Context,
+		caller models.TokenInfo,
+		appID int32,
+	) error
+	Apps(
+		ctx context.Context,
+		caller models.TokenInfo,
+		afterID int32,
+		pageSize int,
+	) (apps []models.App, nextID int32, err error)
+	RotateClientSecret(
+		ctx context.Context,
+		caller models.TokenInfo,
+		appID int32,
+	) (secret string, err error)
+}
+
+type ServerAPI struct {
+	appsv1.UnimplementedAppServiceServer
+	apps Apps
+}
+
+func Register(gRPC *grpc.Server, apps Apps) {
+	appsv1.RegisterAppServiceServer(gRPC, &ServerAPI{apps: apps})
+}
+
+// CreateApp handler. Registers new application and returns its secret which is shown only once
+func (s *ServerAPI) CreateApp(ctx context.Context, in *appsv1.CreateAppRequest) (*appsv1.CreateAppResponse, error) {
+	caller, err := interceptors.MustAdmin(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	if in.GetName() == "" {
+		return nil, status.Error(codes.InvalidArgument, "name is required")
+	}
+
+	app, err := s.apps.CreateApp(ctx, caller, in.GetName(), settingsFromProto(in.GetSettings()))
+	if err != nil {
+		return nil, appsError(err)
+	}
+
+	return &appsv1.CreateAppResponse{
+		App:    appToProto(app),
+		Secret: app.Secret,
+	}, nil
+}
+
+// UpdateApp handler. Updates name and settings of the application, update mask limits the settings fields to update
+func (s *ServerAPI) UpdateApp(ctx context.Context, in *appsv1.UpdateAppRequest) (*appsv1.UpdateAppResponse, error) {
+	caller, err := interceptors.MustAdmin(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	if in.GetAppId() <= 0 {
+		return nil, status.Error(codes.InvalidArgument, "invalid app id")
+	}
+
+	// Fields of the mask without settings are reset
+	fields := in.GetUpdateMask().GetPaths()
+
+	var settings *models.AppSettings
+	if in.GetSettings() != nil || len(fields) > 0 {
+		converted := settingsFromProto(in.GetSettings())
+		settings = &converted
+	}
+
+	app, err := s.apps.UpdateApp(ctx, caller, in.GetAppId(), in.GetName(), settings, fields)
+	if err != nil {
+		return nil, appsError(err)
+	}
+
+	return &appsv1.UpdateAppResponse{
+		App: appToProto(app),
+	}, nil
+}
+
+// ListApps handler. Returns page of the applications ordered by ID
+func (s *ServerAPI) ListApps(ctx context.Context, in *appsv1.ListAppsRequest) (*appsv1.ListAppsResponse, error) {
+	caller, err := interceptors.MustAdmin(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	var afterID int64
+	if in.GetPageToken() != "" {
+		afterID, err = strconv.ParseInt(in.GetPageToken(), 10, 32)
+		if err != nil || afterID < 0 {
+			return nil, status.Error(codes.InvalidArgument, "invalid page token")
+		}
+	}
+
+	list, nextID, err := s.apps.Apps(ctx, caller, int32(afterID), int(in.GetPageSize()))
+	if err != nil {
+		return nil, appsError(err)
+	}
+
+	resp := &appsv1.ListAppsResponse{
+		Apps: make([]*appsv1.AppInfo, 0, len(list)),
+	}
+	for _, app := range list {
+		resp.Apps = append(resp.Apps, appToProto(app))
+	}
+
+	if nextID != 0 {
+		resp.NextPageToken = strconv.Itoa(int(nextID))
+	}
+
+	return resp, nil
+}
+
+// DisableApp handler. Disabled application can't issue and accept tokens
+func (s *ServerAPI) DisableApp(ctx context.Context, in *appsv1.DisableAppRequest) (*appsv1.DisableAppResponse, error) {
+	if err := s.setDisabled(ctx, in.GetAppId(), true); err != nil {
+		return nil, err
+	}
+
+	return &appsv1.DisableAppResponse{}, nil
+}
+
+// EnableApp handler. Enables disabled application back
+func (s *ServerAPI) EnableApp(ctx context.Context, in *appsv1.EnableAppRequest) (*appsv1.EnableAppResponse, error) {
+	if err := s.setDisabled(ctx, in.GetAppId(), false); err != nil {
+		return nil, err
+	}
+
+	return &appsv1.EnableAppResponse{}, nil
+}
+
+// DeleteApp handler. Deletes the application with all data which belong to it
+func (s *ServerAPI) DeleteApp(ctx context.Context, in *appsv1.DeleteAppRequest) (*appsv1.DeleteAppResponse, error) {
+	caller, err := interceptors.MustAdmin(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	if in.GetAppId() <= 0 {
+		return nil, status.Error(codes.InvalidArgument, "invalid app id")
+	}
+
+	if err := s.apps.DeleteApp(ctx, caller, in.GetAppId()); err != nil {
+		return nil, appsError(err)
+	}
+
+	return &appsv1.DeleteAppResponse{}, nil
+}
+
+// RotateClientSecret handler. Generates new OAuth client secret of the application which is shown only once
+func (s *ServerAPI) RotateClientSecret(
+	ctx context.Context,
+	in *appsv1.RotateClientSecretRequest,
+) (*appsv1.RotateClientSecretResponse, error) {
+	caller, err := interceptors.MustAdmin(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	if in.GetAppId() <= 0 {
+		return nil, status.Error(codes.InvalidArgument, "invalid app id")
+	}
+
+	secret, err := s.apps.RotateClientSecret(ctx, caller, in.GetAppId())
+	if err != nil {
+		return nil, appsError(err)
+	}
+
+	return &appsv1.RotateClientSecretResponse{
+		ClientSecret: secret,
+	}, nil
+}
+
+func (s *ServerAPI) setDisabled(ctx context.Context, appID int32, disabled bool) error {
+	caller, err := interceptors.MustAdmin(ctx)
+	if err != nil {
+		return err
+	}
+
+	if appID <= 0 {
+		return status.Error(codes.InvalidArgument, "invalid app id")
+	}
+
+	if err := s.apps.SetAppDisabled(ctx, caller, appID, disabled); err != nil {
+		return appsError(err)
+	}
+
+	return nil
+}
+
+func settingsFromProto(settings *appsv1.AppSettings) models.AppSettings {
+	return models.AppSettings{
+		EmbedRoles:    settings.GetEmbedRoles(),
+		EmbedScope:    settings.GetEmbedScope(),
+		MaxClaimsSize: int(settings.GetMaxClaimsSize()),
+		RedirectURIs:  settings.GetRedirectUris(),
+		ClientScopes:  settings.GetClientScopes(),
+
+		GrantTypes:      settings.GetGrantTypes(),
+		AllowedScopes:   settings.GetAllowedScopes(),
+		AccessTokenTTL:  time.Duration(settings.GetAccessTokenTtlSeconds()) * time.Second,
+		RefreshTokenTTL: time.Duration(settings.GetRefreshTokenTtlSeconds()) * time.Second,
+		PublicClient:    settings.GetPublicClient(),
+		ThirdParty:      settings.GetThirdParty(),
+
+		BackchannelLogoutURI: settings.GetBackchannelLogoutUri(),
+
+		Theme: models.AppTheme{
+			LogoURI:         settings.GetTheme().GetLogoUri(),
+			PrimaryColor:    settings.GetTheme().GetPrimaryColor(),
+			BackgroundColor: settings.GetTheme().GetBackgroundColor(),
+			Title:           settings.GetTheme().GetTitle(),
+			Text:            settings.GetTheme().GetText(),
+		},
+	}
+}
+
+func appToProto(app models.App) *appsv1.AppInfo {
+	return &appsv1.AppInfo{
+		Id:       int32(app.ID),
+		Name:     app.Name,
+		Disabled: app.Disabled,
+		Settings: &appsv1.AppSettings{
+			EmbedRoles:    app.EmbedRoles,
+			EmbedScope:    app.EmbedScope,
+			MaxClaimsSize: int32(app.MaxClaimsSize),
+			RedirectUris:  app.RedirectURIs,
+			ClientScopes:  app.ClientScopes,
+
+			GrantTypes:             app.GrantTypes,
+			AllowedScopes:          app.AllowedScopes,
+			AccessTokenTtlSeconds:  int64(app.AccessTokenTTL.Seconds()),
+			RefreshTokenTtlSeconds: int64(app.RefreshTokenTTL.Seconds()),
+			PublicClient:           app.PublicClient,
+			ThirdParty:             app.ThirdParty,
+
+			BackchannelLogoutUri: app.BackchannelLogoutURI,
+
+			Theme: &appsv1.AppTheme{
+				LogoUri:         app.Theme.LogoURI,
+				PrimaryColor:    app.Theme.PrimaryColor,
+				BackgroundColor: app.Theme.BackgroundColor,
+				Title:           app.Theme.Title,
+				Text:            app.Theme.Text,
+			},
+		},
+	}
+}
+
+// appsError converts errors of the Apps service to gRPC status errors
+func appsError(err error) error {
+	switch {
+	case errors.Is(err, apps.ErrPermissionDenied):
+		return status.Error(codes.PermissionDenied, "admin rights are required")
+	case errors.Is(err, apps.ErrInvalidAppID):
+		return status.Error(codes.NotFound, "app not found")
+	case errors.Is(err, apps.ErrAppExists):
+		return status.Error(codes.AlreadyExists, "app already exists")
+	case errors.Is(err, apps.ErrInvalidSettings):
+		return status.Error(codes.InvalidArgument, err.Error())
+	}
+
+	return status.Error(codes.Internal, err.Error())
+}
@@ -56,6 +56,14 @@ func (s *ServerAPI) Login(ctx context.Context, in *ssov1.LoginRequest) (*ssov1.L
 			return nil, status.Error(codes.InvalidArgument, "email or password is invalid")
 		}
 
+		if errors.Is(err, auth.ErrAppDisabled) {
+			return nil, status.Error(codes.PermissionDenied, "app is disabled")
+		}
+
+		if errors.Is(err, auth.ErrFederatedRealm) {
+			return nil, status.Error(codes.FailedPrecondition, "user must sign in with the identity provider of the email domain")
+		}
+
 		return nil, status.Error(codes.Internal, err.Error())
 	}
 
@@ -92,7 +100,7 @@ func (s *ServerAPI) IsAdmin(ctx context.Context, in *ssov1.IsAdminRequest) (*sso
 
 	isAdmin, err := s.auth.IsAdmin(ctx, in.GetUserId())
 	if err != nil {
-		if errors.Is(err, auth.ErrInvalidCredentials) {
+		if errors.Is(err, auth.ErrInvalidUserID) {
 			return nil, status.Error(codes.NotFound, "user not found")
 		}
 
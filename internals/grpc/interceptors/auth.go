@@ -0,0 +1,104 @@
+package interceptors
+
+import (
+	"context"
+	"strings"
+
+	"github.com/nhassl3/sso-app/internals/domain/models"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+const (
+	authorizationHeader = "authorization"
+	bearerPrefix        = "bearer "
+)
+
+type callerKey struct{}
+
+// caller is information about the token of the request with the flag of the admin console
+type caller struct {
+	info    models.TokenInfo
+	console bool
+}
+
+type Introspector interface {
+	Introspect(ctx context.Context, token string) (info models.TokenInfo, err error)
+}
+
+// Auth returns interceptor which validates bearer token of the request if it is given
+// and puts information about the caller into the context.
+// Requests without token pass as is, handlers which need caller check it by Caller.
+// Only tokens of the users issued for the console application can be used in admin requests, see MustAdmin
+func Auth(introspector Introspector, consoleAppID int32) grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req any, _ *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+		md, ok := metadata.FromIncomingContext(ctx)
+		if !ok || len(md.Get(authorizationHeader)) == 0 {
+			return handler(ctx, req)
+		}
+
+		header := md.Get(authorizationHeader)[0]
+		if len(header) <= len(bearerPrefix) || !strings.EqualFold(header[:len(bearerPrefix)], bearerPrefix) {
+			return nil, status.Error(codes.Unauthenticated, "authorization header must be a bearer token")
+		}
+
+		info, err := introspector.Introspect(ctx, header[len(bearerPrefix):])
+		if err != nil {
+			return nil, status.Error(codes.Internal, "failed to check token")
+		}
+
+		if !info.Active {
+			return nil, status.Error(codes.Unauthenticated, "token is invalid or expired")
+		}
+
+		return handler(context.WithValue(ctx, callerKey{}, caller{info: info, console: info.IsConsole(consoleAppID)}), req)
+	}
+}
+
+// Caller returns information about the token of the caller put by Auth interceptor
+func Caller(ctx context.Context) (info models.TokenInfo, ok bool) {
+	c, ok := ctx.Value(callerKey{}).(caller)
+	return c.info, ok
+}
+
+// MustCaller returns information about the caller or Unauthenticated status error if request has no token
+func MustCaller(ctx context.Context) (models.TokenInfo, error) {
+	info, ok := Caller(ctx)
+	if !ok {
+		return models.TokenInfo{}, status.Error(codes.Unauthenticated, "authorization token is required")
+	}
+
+	return info, nil
+}
+
+// MustConsole returns information about the user of the token issued for the admin console.
+// Owners of the applications sign tokens of their applications for any user, so the user acts
+// on own account only by the console token.
+// Returns Unauthenticated status error if request has no token and PermissionDenied if token isn't of the console
+func MustConsole(ctx context.Context) (models.TokenInfo, error) {
+	c, ok := ctx.Value(callerKey{}).(caller)
+	if !ok {
+		return models.TokenInfo{}, status.Error(codes.Unauthenticated, "authorization token is required")
+	}
+
+	if !c.console {
+		return models.TokenInfo{}, status.Error(codes.PermissionDenied, "token of the user issued for the admin console is required")
+	}
+
+	return c.info, nil
+}
+
+// IsConsole reports whether the request is made by the token of the user issued for the admin console
+func IsConsole(ctx context.Context) bool {
+	c, ok := ctx.Value(callerKey{}).(caller)
+
+	return ok && c.console
+}
+
+// MustAdmin returns information about the caller whose token may be used in admin requests.
+// Only console tokens are, errors are returned as by MustConsole
+func MustAdmin(ctx context.Context) (models.TokenInfo, error) {
+	return MustConsole(ctx)
+}
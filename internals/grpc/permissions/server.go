@@ -0,0 +1,631 @@
+package permissions
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"time"
+
+	permissionsv1 "github.com/nhassl3/sso-app/contracts/generated/go/permissions"
+	"github.com/nhassl3/sso-app/internals/domain/models"
+	"github.com/nhassl3/sso-app/internals/domain/services/permissions"
+	"github.com/nhassl3/sso-app/internals/grpc/interceptors"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+type Permissions interface {
+	WriteNamespaceConfig(
+		ctx context.Context,
+		caller models.TokenInfo,
+		appID int32,
+		config models.NamespaceConfig,
+	) error
+	NamespaceConfig(
+		ctx context.Context,
+		caller models.TokenInfo,
+		appID int32,
+	) (config models.NamespaceConfig, err error)
+	WriteTuples(
+		ctx context.Context,
+		caller models.TokenInfo,
+		appID int32,
+		writes, deletes []models.RelationTuple,
+	) (revision int64, err error)
+	Check(
+		ctx context.Context,
+		caller models.TokenInfo,
+		appID int32,
+		object models.Object,
+		relation string,
+		subject models.Subject,
+		minRevision int64,
+	) (allowed bool, revision int64, err error)
+	BatchCheck(
+		ctx context.Context,
+		caller models.TokenInfo,
+		appID int32,
+		checks []models.RelationTuple,
+		minRevision int64,
+	) (results []bool, revision int64, err error)
+	CacheStats(
+		ctx context.Context,
+		caller models.TokenInfo,
+		appID int32,
+	) (stats models.DecisionCacheStats, err error)
+	Expand(
+		ctx context.Context,
+		caller models.TokenInfo,
+		appID int32,
+		object models.Object,
+		relation string,
+		minRevision int64,
+	) (tree models.UsersetTree, revision int64, err error)
+	ListObjects(
+		ctx context.Context,
+		caller models.TokenInfo,
+		appID int32,
+		objectType string,
+		relation string,
+		subject models.Subject,
+		minRevision int64,
+	) (objectIDs []string, revision int64, err error)
+	WritePolicy(
+		ctx context.Context,
+		caller models.TokenInfo,
+		appID int32,
+		policy models.Policy,
+	) (version int64, err error)
+	Policy(
+		ctx context.Context,
+		caller models.TokenInfo,
+		appID int32,
+	) (policy models.Policy, version int64, err error)
+	Evaluate(
+		ctx context.Context,
+		caller models.TokenInfo,
+		appID int32,
+		input models.PolicyInput,
+	) (evaluation models.PolicyEvaluation, err error)
+	DryRunPolicy(
+		ctx context.Context,
+		caller models.TokenInfo,
+		appID int32,
+		policy models.Policy,
+		limit int,
+	) (replays []models.PolicyReplay, err error)
+}
+
+// maxBatchChecks limits count of the checks of one BatchCheck request
+const maxBatchChecks = 100
+
+type ServerAPI struct {
+	permissionsv1.UnimplementedPermissionsServer
+	permissions Permissions
+}
+
+func Register(gRPC *grpc.Server, permissions Permissions) {
+	permissionsv1.RegisterPermissionsServer(gRPC, &ServerAPI{permissions: permissions})
+}
+
+// WriteNamespaceConfig handler. Replaces namespace config of the application
+func (s *ServerAPI) WriteNamespaceConfig(
+	ctx context.Context,
+	in *permissionsv1.WriteNamespaceConfigRequest,
+) (*permissionsv1.WriteNamespaceConfigResponse, error) {
+	caller, err := interceptors.MustAdmin(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	if in.GetAppId() <= 0 {
+		return nil, status.Error(codes.InvalidArgument, "invalid app id")
+	}
+
+	var config models.NamespaceConfig
+	if err := json.Unmarshal([]byte(in.GetConfig()), &config); err != nil {
+		return nil, status.Error(codes.InvalidArgument, "config must be a JSON object")
+	}
+
+	if err := s.permissions.WriteNamespaceConfig(ctx, caller, in.GetAppId(), config); err != nil {
+		return nil, permissionsError(err)
+	}
+
+	return &permissionsv1.WriteNamespaceConfigResponse{}, nil
+}
+
+// ReadNamespaceConfig handler. Returns namespace config of the application
+func (s *ServerAPI) ReadNamespaceConfig(
+	ctx context.Context,
+	in *permissionsv1.ReadNamespaceConfigRequest,
+) (*permissionsv1.ReadNamespaceConfigResponse, error) {
+	caller, err := readCaller(ctx, in.GetAppId())
+	if err != nil {
+		return nil, err
+	}
+
+	if in.GetAppId() <= 0 {
+		return nil, status.Error(codes.InvalidArgument, "invalid app id")
+	}
+
+	config, err := s.permissions.NamespaceConfig(ctx, caller, in.GetAppId())
+	if err != nil {
+		return nil, permissionsError(err)
+	}
+
+	raw, err := json.Marshal(config)
+	if err != nil {
+		return nil, status.Error(codes.Internal, err.Error())
+	}
+
+	return &permissionsv1.ReadNamespaceConfigResponse{
+		Config: string(raw),
+	}, nil
+}
+
+// WriteTuples handler. Writes and deletes relation tuples atomically
+func (s *ServerAPI) WriteTuples(
+	ctx context.Context,
+	in *permissionsv1.WriteTuplesRequest,
+) (*permissionsv1.WriteTuplesResponse, error) {
+	caller, err := interceptors.MustAdmin(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	if in.GetAppId() <= 0 {
+		return nil, status.Error(codes.InvalidArgument, "invalid app id")
+	}
+
+	if len(in.GetWrites()) == 0 && len(in.GetDeletes()) == 0 {
+		return nil, status.Error(codes.InvalidArgument, "writes or deletes are required")
+	}
+
+	writes, err := parseTuples(in.GetWrites())
+	if err != nil {
+		return nil, err
+	}
+
+	deletes, err := parseTuples(in.GetDeletes())
+	if err != nil {
+		return nil, err
+	}
+
+	revision, err := s.permissions.WriteTuples(ctx, caller, in.GetAppId(), writes, deletes)
+	if err != nil {
+		return nil, permissionsError(err)
+	}
+
+	return &permissionsv1.WriteTuplesResponse{
+		Revision: revision,
+	}, nil
+}
+
+// Check handler. Reports whether the subject has the relation with the object
+func (s *ServerAPI) Check(ctx context.Context, in *permissionsv1.CheckRequest) (*permissionsv1.CheckResponse, error) {
+	caller, err := readCaller(ctx, in.GetAppId())
+	if err != nil {
+		return nil, err
+	}
+
+	if in.GetAppId() <= 0 {
+		return nil, status.Error(codes.InvalidArgument, "invalid app id")
+	}
+
+	object, err := models.ParseObject(in.GetObject())
+	if err != nil {
+		return nil, status.Error(codes.InvalidArgument, "invalid object")
+	}
+
+	if in.GetRelation() == "" {
+		return nil, status.Error(codes.InvalidArgument, "relation is required")
+	}
+
+	subject, err := models.ParseSubject(in.GetSubject())
+	if err != nil {
+		return nil, status.Error(codes.InvalidArgument, "invalid subject")
+	}
+
+	allowed, revision, err := s.permissions.Check(
+		ctx, caller, in.GetAppId(), object, in.GetRelation(), subject, in.GetAtLeastRevision(),
+	)
+	if err != nil {
+		return nil, permissionsError(err)
+	}
+
+	return &permissionsv1.CheckResponse{
+		Allowed:  allowed,
+		Revision: revision,
+	}, nil
+}
+
+// BatchCheck handler. Answers many checks in one request using the decision cache
+func (s *ServerAPI) BatchCheck(
+	ctx context.Context,
+	in *permissionsv1.BatchCheckRequest,
+) (*permissionsv1.BatchCheckResponse, error) {
+	caller, err := readCaller(ctx, in.GetAppId())
+	if err != nil {
+		return nil, err
+	}
+
+	if in.GetAppId() <= 0 {
+		return nil, status.Error(codes.InvalidArgument, "invalid app id")
+	}
+
+	if len(in.GetItems()) == 0 || len(in.GetItems()) > maxBatchChecks {
+		return nil, status.Errorf(codes.InvalidArgument, "from 1 to %d items are required", maxBatchChecks)
+	}
+
+	checks := make([]models.RelationTuple, 0, len(in.GetItems()))
+	for i, item := range in.GetItems() {
+		object, err := models.ParseObject(item.GetObject())
+		if err != nil {
+			return nil, status.Errorf(codes.InvalidArgument, "invalid object of item %d", i)
+		}
+
+		if item.GetRelation() == "" {
+			return nil, status.Errorf(codes.InvalidArgument, "relation of item %d is required", i)
+		}
+
+		subject, err := models.ParseSubject(item.GetSubject())
+		if err != nil {
+			return nil, status.Errorf(codes.InvalidArgument, "invalid subject of item %d", i)
+		}
+
+		checks = append(checks, models.RelationTuple{Object: object, Relation: item.GetRelation(), Subject: subject})
+	}
+
+	results, revision, err := s.permissions.BatchCheck(ctx, caller, in.GetAppId(), checks, in.GetAtLeastRevision())
+	if err != nil {
+		return nil, permissionsError(err)
+	}
+
+	return &permissionsv1.BatchCheckResponse{
+		Allowed:  results,
+		Revision: revision,
+	}, nil
+}
+
+// CacheStats handler. Returns usage of the decision cache of the application
+func (s *ServerAPI) CacheStats(
+	ctx context.Context,
+	in *permissionsv1.CacheStatsRequest,
+) (*permissionsv1.CacheStatsResponse, error) {
+	caller, err := readCaller(ctx, in.GetAppId())
+	if err != nil {
+		return nil, err
+	}
+
+	if in.GetAppId() <= 0 {
+		return nil, status.Error(codes.InvalidArgument, "invalid app id")
+	}
+
+	stats, err := s.permissions.CacheStats(ctx, caller, in.GetAppId())
+	if err != nil {
+		return nil, permissionsError(err)
+	}
+
+	return &permissionsv1.CacheStatsResponse{
+		Hits:          stats.Hits,
+		Misses:        stats.Misses,
+		Invalidations: stats.Invalidations,
+		Size:          int32(stats.Size),
+	}, nil
+}
+
+// Expand handler. Returns tree of the subjects which have the relation with the object
+func (s *ServerAPI) Expand(ctx context.Context, in *permissionsv1.ExpandRequest) (*permissionsv1.ExpandResponse, error) {
+	caller, err := readCaller(ctx, in.GetAppId())
+	if err != nil {
+		return nil, err
+	}
+
+	if in.GetAppId() <= 0 {
+		return nil, status.Error(codes.InvalidArgument, "invalid app id")
+	}
+
+	object, err := models.ParseObject(in.GetObject())
+	if err != nil {
+		return nil, status.Error(codes.InvalidArgument, "invalid object")
+	}
+
+	if in.GetRelation() == "" {
+		return nil, status.Error(codes.InvalidArgument, "relation is required")
+	}
+
+	tree, revision, err := s.permissions.Expand(
+		ctx, caller, in.GetAppId(), object, in.GetRelation(), in.GetAtLeastRevision(),
+	)
+	if err != nil {
+		return nil, permissionsError(err)
+	}
+
+	return &permissionsv1.ExpandResponse{
+		Tree:     treeToProto(tree),
+		Revision: revision,
+	}, nil
+}
+
+// ListObjects handler. Returns IDs of the objects of the type with which the subject has the relation
+func (s *ServerAPI) ListObjects(
+	ctx context.Context,
+	in *permissionsv1.ListObjectsRequest,
+) (*permissionsv1.ListObjectsResponse, error) {
+	caller, err := readCaller(ctx, in.GetAppId())
+	if err != nil {
+		return nil, err
+	}
+
+	if in.GetAppId() <= 0 {
+		return nil, status.Error(codes.InvalidArgument, "invalid app id")
+	}
+
+	if in.GetObjectType() == "" || in.GetRelation() == "" {
+		return nil, status.Error(codes.InvalidArgument, "object type and relation are required")
+	}
+
+	subject, err := models.ParseSubject(in.GetSubject())
+	if err != nil {
+		return nil, status.Error(codes.InvalidArgument, "invalid subject")
+	}
+
+	objectIDs, revision, err := s.permissions.ListObjects(
+		ctx, caller, in.GetAppId(), in.GetObjectType(), in.GetRelation(), subject, in.GetAtLeastRevision(),
+	)
+	if err != nil {
+		return nil, permissionsError(err)
+	}
+
+	return &permissionsv1.ListObjectsResponse{
+		ObjectIds: objectIDs,
+		Revision:  revision,
+	}, nil
+}
+
+// WritePolicy handler. Replaces ABAC policy of the application
+func (s *ServerAPI) WritePolicy(
+	ctx context.Context,
+	in *permissionsv1.WritePolicyRequest,
+) (*permissionsv1.WritePolicyResponse, error) {
+	caller, err := interceptors.MustAdmin(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	if in.GetAppId() <= 0 {
+		return nil, status.Error(codes.InvalidArgument, "invalid app id")
+	}
+
+	var policy models.Policy
+	if err := json.Unmarshal([]byte(in.GetPolicy()), &policy); err != nil {
+		return nil, status.Error(codes.InvalidArgument, "policy must be a JSON object")
+	}
+
+	version, err := s.permissions.WritePolicy(ctx, caller, in.GetAppId(), policy)
+	if err != nil {
+		return nil, permissionsError(err)
+	}
+
+	return &permissionsv1.WritePolicyResponse{
+		Version: version,
+	}, nil
+}
+
+// ReadPolicy handler. Returns ABAC policy of the application
+func (s *ServerAPI) ReadPolicy(
+	ctx context.Context,
+	in *permissionsv1.ReadPolicyRequest,
+) (*permissionsv1.ReadPolicyResponse, error) {
+	caller, err := readCaller(ctx, in.GetAppId())
+	if err != nil {
+		return nil, err
+	}
+
+	if in.GetAppId() <= 0 {
+		return nil, status.Error(codes.InvalidArgument, "invalid app id")
+	}
+
+	policy, version, err := s.permissions.Policy(ctx, caller, in.GetAppId())
+	if err != nil {
+		return nil, permissionsError(err)
+	}
+
+	raw, err := json.Marshal(policy)
+	if err != nil {
+		return nil, status.Error(codes.Internal, err.Error())
+	}
+
+	return &permissionsv1.ReadPolicyResponse{
+		Policy:  string(raw),
+		Version: version,
+	}, nil
+}
+
+// Evaluate handler. Evaluates ABAC policy of the application and explains which rule matched
+func (s *ServerAPI) Evaluate(ctx context.Context, in *permissionsv1.EvaluateRequest) (*permissionsv1.EvaluateResponse, error) {
+	caller, err := readCaller(ctx, in.GetAppId())
+	if err != nil {
+		return nil, err
+	}
+
+	if in.GetAppId() <= 0 {
+		return nil, status.Error(codes.InvalidArgument, "invalid app id")
+	}
+
+	input := models.PolicyInput{
+		User:    models.PolicyUser{ID: in.GetUserId()},
+		Request: models.PolicyRequest{IP: in.GetIp()},
+	}
+
+	if err := unmarshalObject("user_attributes", in.GetUserAttributes(), &input.User.Attributes); err != nil {
+		return nil, err
+	}
+
+	if err := unmarshalObject("resource", in.GetResource(), &input.Resource); err != nil {
+		return nil, err
+	}
+
+	if err := unmarshalObject("request_attributes", in.GetRequestAttributes(), &input.Request.Attributes); err != nil {
+		return nil, err
+	}
+
+	if in.GetTime() != "" {
+		input.Request.Time, err = time.Parse(time.RFC3339, in.GetTime())
+		if err != nil {
+			return nil, status.Error(codes.InvalidArgument, "time must be in RFC 3339")
+		}
+	}
+
+	evaluation, err := s.permissions.Evaluate(ctx, caller, in.GetAppId(), input)
+	if err != nil {
+		return nil, permissionsError(err)
+	}
+
+	resp := &permissionsv1.EvaluateResponse{
+		Allowed:       evaluation.Effect == models.PolicyEffectAllow,
+		Effect:        evaluation.Effect,
+		MatchedRule:   evaluation.Rule,
+		DecisionId:    evaluation.DecisionID,
+		PolicyVersion: evaluation.PolicyVersion,
+	}
+	for _, rule := range evaluation.Rules {
+		resp.Rules = append(resp.Rules, &permissionsv1.RuleResult{
+			Name:    rule.Name,
+			Effect:  rule.Effect,
+			Matched: rule.Matched,
+			Error:   rule.Error,
+		})
+	}
+
+	return resp, nil
+}
+
+// DryRunPolicy handler. Replays the latest recorded decisions of the application with the policy without saving it
+func (s *ServerAPI) DryRunPolicy(
+	ctx context.Context,
+	in *permissionsv1.DryRunPolicyRequest,
+) (*permissionsv1.DryRunPolicyResponse, error) {
+	caller, err := interceptors.MustAdmin(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	if in.GetAppId() <= 0 {
+		return nil, status.Error(codes.InvalidArgument, "invalid app id")
+	}
+
+	var policy models.Policy
+	if err := json.Unmarshal([]byte(in.GetPolicy()), &policy); err != nil {
+		return nil, status.Error(codes.InvalidArgument, "policy must be a JSON object")
+	}
+
+	replays, err := s.permissions.DryRunPolicy(ctx, caller, in.GetAppId(), policy, int(in.GetLimit()))
+	if err != nil {
+		return nil, permissionsError(err)
+	}
+
+	resp := &permissionsv1.DryRunPolicyResponse{
+		Results: make([]*permissionsv1.ReplayResult, 0, len(replays)),
+	}
+	for _, replay := range replays {
+		if replay.Changed() {
+			resp.Changed++
+		}
+
+		resp.Results = append(resp.Results, &permissionsv1.ReplayResult{
+			DecisionId:     replay.Decision.ID,
+			PolicyVersion:  replay.Decision.PolicyVersion,
+			RecordedEffect: replay.Decision.Effect,
+			RecordedRule:   replay.Decision.Rule,
+			Effect:         replay.Effect,
+			Rule:           replay.Rule,
+			Changed:        replay.Changed(),
+		})
+	}
+
+	return resp, nil
+}
+
+// unmarshalObject decodes optional JSON object of the request field
+func unmarshalObject(field string, raw string, dest *map[string]any) error {
+	if raw == "" {
+		return nil
+	}
+
+	if err := json.Unmarshal([]byte(raw), dest); err != nil {
+		return status.Errorf(codes.InvalidArgument, "%s must be a JSON object", field)
+	}
+
+	return nil
+}
+
+func parseTuples(raw []string) ([]models.RelationTuple, error) {
+	tuples := make([]models.RelationTuple, 0, len(raw))
+	for _, s := range raw {
+		tuple, err := models.ParseRelationTuple(s)
+		if err != nil {
+			return nil, status.Errorf(codes.InvalidArgument, "invalid relation tuple %q", s)
+		}
+
+		tuples = append(tuples, tuple)
+	}
+
+	return tuples, nil
+}
+
+func treeToProto(tree models.UsersetTree) *permissionsv1.UsersetTree {
+	resp := &permissionsv1.UsersetTree{
+		Operation: tree.Operation,
+		Object:    tree.Object.String(),
+		Relation:  tree.Relation,
+	}
+
+	for _, subject := range tree.Subjects {
+		resp.Subjects = append(resp.Subjects, subject.String())
+	}
+
+	for _, child := range tree.Children {
+		resp.Children = append(resp.Children, treeToProto(child))
+	}
+
+	return resp
+}
+
+// permissionsError converts errors of the Permissions service to gRPC status errors
+func permissionsError(err error) error {
+	switch {
+	case errors.Is(err, permissions.ErrPermissionDenied):
+		return status.Error(codes.PermissionDenied, "access to relations of the app is denied")
+	case errors.Is(err, permissions.ErrInvalidAppID):
+		return status.Error(codes.NotFound, "app not found")
+	case errors.Is(err, permissions.ErrInvalidUserID):
+		return status.Error(codes.NotFound, "user not found")
+	case errors.Is(err, models.ErrInvalidNamespaceConfig), errors.Is(err, models.ErrInvalidPolicy):
+		return status.Error(codes.InvalidArgument, err.Error())
+	case errors.Is(err, permissions.ErrUnknownRelation):
+		return status.Error(codes.InvalidArgument, "relation is not defined in namespace config")
+	case errors.Is(err, permissions.ErrStaleRevision):
+		return status.Error(codes.FailedPrecondition, "requested revision is not reached yet")
+	case errors.Is(err, permissions.ErrDepthExceeded):
+		return status.Error(codes.ResourceExhausted, "max depth of relations exceeded")
+	}
+
+	return status.Error(codes.Internal, err.Error())
+}
+
+// readCaller returns the caller who may read relations of the application.
+// Tokens of the application itself read them, others have to be admin tokens of the console
+func readCaller(ctx context.Context, appID int32) (models.TokenInfo, error) {
+	caller, err := interceptors.MustCaller(ctx)
+	if err != nil {
+		return models.TokenInfo{}, err
+	}
+
+	if caller.AppID == appID {
+		return caller, nil
+	}
+
+	return interceptors.MustAdmin(ctx)
+}
@@ -0,0 +1,145 @@
+package sessions
+
+import (
+	"context"
+	"errors"
+
+	sessionsv1 "github.com/nhassl3/sso-app/contracts/generated/go/sessions"
+	"github.com/nhassl3/sso-app/internals/domain/models"
+	"github.com/nhassl3/sso-app/internals/domain/services/sessions"
+	"github.com/nhassl3/sso-app/internals/grpc/interceptors"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+type Sessions interface {
+	Logout(
+		ctx context.Context,
+		userID int64,
+		appID int32,
+	) (notified int, err error)
+	RevokeSessions(
+		ctx context.Context,
+		actorID int64,
+		userID int64,
+		appID int32,
+	) (notified int, err error)
+	LogoutDeliveries(
+		ctx context.Context,
+		actorID int64,
+		appID int32,
+		limit int,
+	) (deliveries []models.LogoutDelivery, err error)
+}
+
+type ServerAPI struct {
+	sessionsv1.UnimplementedSessionsServer
+	sessions Sessions
+}
+
+func Register(gRPC *grpc.Server, sessions Sessions) {
+	sessionsv1.RegisterSessionsServer(gRPC, &ServerAPI{sessions: sessions})
+}
+
+// Logout handler. Ends sessions of the caller in all applications by the console token and only in the application
+// of the token otherwise, owners of the applications sign their tokens for any user
+func (s *ServerAPI) Logout(ctx context.Context, _ *sessionsv1.LogoutRequest) (*sessionsv1.LogoutResponse, error) {
+	caller, err := interceptors.MustCaller(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	if caller.UserID == 0 {
+		return nil, status.Error(codes.PermissionDenied, "token of the user is required")
+	}
+
+	appID := caller.AppID
+	if interceptors.IsConsole(ctx) {
+		appID = 0
+	}
+
+	notified, err := s.sessions.Logout(ctx, caller.UserID, appID)
+	if err != nil {
+		return nil, status.Error(codes.Internal, err.Error())
+	}
+
+	return &sessionsv1.LogoutResponse{NotifiedApps: int32(notified)}, nil
+}
+
+// RevokeSessions handler. Ends sessions of the user in the application on behalf of the admin
+func (s *ServerAPI) RevokeSessions(ctx context.Context, in *sessionsv1.RevokeSessionsRequest) (*sessionsv1.RevokeSessionsResponse, error) {
+	caller, err := interceptors.MustAdmin(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	if in.GetUserId() <= 0 {
+		return nil, status.Error(codes.InvalidArgument, "user_id is required")
+	}
+
+	if in.GetAppId() < 0 {
+		return nil, status.Error(codes.InvalidArgument, "invalid app id")
+	}
+
+	notified, err := s.sessions.RevokeSessions(ctx, caller.UserID, in.GetUserId(), in.GetAppId())
+	if err != nil {
+		return nil, sessionsError(err)
+	}
+
+	return &sessionsv1.RevokeSessionsResponse{NotifiedApps: int32(notified)}, nil
+}
+
+// ListLogoutDeliveries handler. Returns the latest logout deliveries to the application with their status
+func (s *ServerAPI) ListLogoutDeliveries(
+	ctx context.Context,
+	in *sessionsv1.ListLogoutDeliveriesRequest,
+) (*sessionsv1.ListLogoutDeliveriesResponse, error) {
+	caller, err := interceptors.MustAdmin(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	if in.GetAppId() < 0 {
+		return nil, status.Error(codes.InvalidArgument, "invalid app id")
+	}
+
+	deliveries, err := s.sessions.LogoutDeliveries(ctx, caller.UserID, in.GetAppId(), int(in.GetLimit()))
+	if err != nil {
+		return nil, sessionsError(err)
+	}
+
+	resp := &sessionsv1.ListLogoutDeliveriesResponse{
+		Deliveries: make([]*sessionsv1.LogoutDelivery, 0, len(deliveries)),
+	}
+	for _, d := range deliveries {
+		delivery := &sessionsv1.LogoutDelivery{
+			Id:        d.ID,
+			AppId:     d.AppID,
+			UserId:    d.UserID,
+			Status:    d.Status,
+			Attempts:  int32(d.Attempts),
+			LastError: d.LastError,
+			CreatedAt: d.CreatedAt.Unix(),
+		}
+		if !d.DeliveredAt.IsZero() {
+			delivery.DeliveredAt = d.DeliveredAt.Unix()
+		}
+
+		resp.Deliveries = append(resp.Deliveries, delivery)
+	}
+
+	return resp, nil
+}
+
+// sessionsError converts errors of the Sessions service to gRPC status errors
+func sessionsError(err error) error {
+	switch {
+	case errors.Is(err, sessions.ErrPermissionDenied):
+		return status.Error(codes.PermissionDenied, "admin rights are required")
+	case errors.Is(err, sessions.ErrInvalidAppID):
+		return status.Error(codes.NotFound, "app not found")
+	}
+
+	return status.Error(codes.Internal, err.Error())
+}
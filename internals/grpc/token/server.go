@@ -0,0 +1,217 @@
+package token
+
+import (
+	"context"
+	"errors"
+
+	tokenv1 "github.com/nhassl3/sso-app/contracts/generated/go/token"
+	"github.com/nhassl3/sso-app/internals/domain/models"
+	"github.com/nhassl3/sso-app/internals/domain/services/auth"
+	"github.com/nhassl3/sso-app/internals/grpc/interceptors"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+type Token interface {
+	Introspect(
+		ctx context.Context,
+		token string,
+	) (info models.TokenInfo, err error)
+	ApproveDevice(
+		ctx context.Context,
+		userID int64,
+		userCode string,
+	) (code models.DeviceCode, err error)
+	DenyDevice(
+		ctx context.Context,
+		userID int64,
+		userCode string,
+	) (code models.DeviceCode, err error)
+	Consents(
+		ctx context.Context,
+		userID int64,
+	) (consents []models.Consent, err error)
+	RevokeConsent(
+		ctx context.Context,
+		userID int64,
+		appID int32,
+	) error
+	DiscoverRealm(
+		ctx context.Context,
+		email string,
+		appID int32,
+	) (realm models.Realm, err error)
+}
+
+type ServerAPI struct {
+	tokenv1.UnimplementedTokenServer
+	token Token
+}
+
+func Register(gRPC *grpc.Server, token Token) {
+	tokenv1.RegisterTokenServer(gRPC, &ServerAPI{token: token})
+}
+
+// Introspect handler. Returns information about the token with actual roles and scopes of the user.
+// Caller authenticates by a token of the application, tokens of other applications are reported inactive (RFC 7662)
+func (s *ServerAPI) Introspect(ctx context.Context, in *tokenv1.IntrospectRequest) (*tokenv1.IntrospectResponse, error) {
+	caller, err := interceptors.MustCaller(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	if in.GetToken() == "" {
+		return nil, status.Error(codes.InvalidArgument, "token is required")
+	}
+
+	info, err := s.token.Introspect(ctx, in.GetToken())
+	if err != nil {
+		return nil, status.Error(codes.Internal, err.Error())
+	}
+
+	if !info.Active || info.AppID != caller.AppID {
+		return &tokenv1.IntrospectResponse{Active: false}, nil
+	}
+
+	return &tokenv1.IntrospectResponse{
+		Active: true,
+		UserId: info.UserID,
+		Email:  info.Email,
+		AppId:  info.AppID,
+		Exp:    info.ExpiresAt.Unix(),
+		Roles:  info.Roles,
+		Scopes: info.Scopes,
+
+		ActorUserId: info.ActorID,
+	}, nil
+}
+
+// ApproveDevice handler. Approves device code of the OAuth client on behalf of the caller
+func (s *ServerAPI) ApproveDevice(ctx context.Context, in *tokenv1.ApproveDeviceRequest) (*tokenv1.ApproveDeviceResponse, error) {
+	userID, err := deviceCaller(ctx, in.GetUserCode())
+	if err != nil {
+		return nil, err
+	}
+
+	code, err := s.token.ApproveDevice(ctx, userID, in.GetUserCode())
+	if err != nil {
+		return nil, deviceError(err)
+	}
+
+	return &tokenv1.ApproveDeviceResponse{AppId: code.AppID, Scope: code.Scope}, nil
+}
+
+// DenyDevice handler. Denies device code of the OAuth client on behalf of the caller
+func (s *ServerAPI) DenyDevice(ctx context.Context, in *tokenv1.DenyDeviceRequest) (*tokenv1.DenyDeviceResponse, error) {
+	userID, err := deviceCaller(ctx, in.GetUserCode())
+	if err != nil {
+		return nil, err
+	}
+
+	code, err := s.token.DenyDevice(ctx, userID, in.GetUserCode())
+	if err != nil {
+		return nil, deviceError(err)
+	}
+
+	return &tokenv1.DenyDeviceResponse{AppId: code.AppID}, nil
+}
+
+// ListConsents handler. Returns consents the caller gave to the third-party applications
+func (s *ServerAPI) ListConsents(ctx context.Context, _ *tokenv1.ListConsentsRequest) (*tokenv1.ListConsentsResponse, error) {
+	userID, err := userCaller(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	consents, err := s.token.Consents(ctx, userID)
+	if err != nil {
+		return nil, status.Error(codes.Internal, err.Error())
+	}
+
+	resp := &tokenv1.ListConsentsResponse{Consents: make([]*tokenv1.Consent, 0, len(consents))}
+	for _, consent := range consents {
+		resp.Consents = append(resp.Consents, &tokenv1.Consent{
+			AppId:     consent.AppID,
+			AppName:   consent.AppName,
+			Scopes:    consent.Scopes,
+			GrantedAt: consent.GrantedAt.Unix(),
+		})
+	}
+
+	return resp, nil
+}
+
+// RevokeConsent handler. Revokes consent of the caller to the application and its refresh tokens of the caller
+func (s *ServerAPI) RevokeConsent(ctx context.Context, in *tokenv1.RevokeConsentRequest) (*tokenv1.RevokeConsentResponse, error) {
+	userID, err := userCaller(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	if in.GetAppId() <= 0 {
+		return nil, status.Error(codes.InvalidArgument, "app_id is required")
+	}
+
+	if err := s.token.RevokeConsent(ctx, userID, in.GetAppId()); err != nil {
+		if errors.Is(err, auth.ErrConsentNotFound) {
+			return nil, status.Error(codes.NotFound, "consent not found")
+		}
+
+		return nil, status.Error(codes.Internal, err.Error())
+	}
+
+	return &tokenv1.RevokeConsentResponse{}, nil
+}
+
+// DiscoverRealm handler. Returns the identity provider the user with the email must sign in with,
+// front-ends ask it before asking the password
+func (s *ServerAPI) DiscoverRealm(ctx context.Context, in *tokenv1.DiscoverRealmRequest) (*tokenv1.DiscoverRealmResponse, error) {
+	if in.GetEmail() == "" {
+		return nil, status.Error(codes.InvalidArgument, "email is required")
+	}
+
+	if in.GetAppId() < 0 {
+		return nil, status.Error(codes.InvalidArgument, "app_id must not be negative")
+	}
+
+	realm, err := s.token.DiscoverRealm(ctx, in.GetEmail(), in.GetAppId())
+	if err != nil {
+		return nil, status.Error(codes.Internal, err.Error())
+	}
+
+	return &tokenv1.DiscoverRealmResponse{Provider: realm.Provider, LoginUri: realm.LoginURI}, nil
+}
+
+// userCaller returns ID of the user of the console token, owners of other applications sign their tokens
+// for any user, so these tokens never act on own account of the user
+func userCaller(ctx context.Context) (int64, error) {
+	caller, err := interceptors.MustConsole(ctx)
+	if err != nil {
+		return 0, err
+	}
+
+	return caller.UserID, nil
+}
+
+// deviceCaller returns ID of the user who decides on the device code, as userCaller does
+func deviceCaller(ctx context.Context, userCode string) (int64, error) {
+	userID, err := userCaller(ctx)
+	if err != nil {
+		return 0, err
+	}
+
+	if userCode == "" {
+		return 0, status.Error(codes.InvalidArgument, "user code is required")
+	}
+
+	return userID, nil
+}
+
+func deviceError(err error) error {
+	if errors.Is(err, auth.ErrInvalidUserCode) {
+		return status.Error(codes.NotFound, "user code is invalid, expired or already decided")
+	}
+
+	return status.Error(codes.Internal, err.Error())
+}
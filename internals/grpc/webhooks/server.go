@@ -0,0 +1,219 @@
+package webhooks
+
+import (
+	"context"
+	"errors"
+
+	webhooksv1 "github.com/nhassl3/sso-app/contracts/generated/go/webhooks"
+	"github.com/nhassl3/sso-app/internals/domain/models"
+	"github.com/nhassl3/sso-app/internals/domain/services/webhooks"
+	"github.com/nhassl3/sso-app/internals/grpc/interceptors"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+type Webhooks interface {
+	CreateSubscription(
+		ctx context.Context,
+		actorID int64,
+		appID int32,
+		webhookURL string,
+		events []string,
+		allUsers bool,
+	) (subscription models.WebhookSubscription, err error)
+	Subscriptions(
+		ctx context.Context,
+		actorID int64,
+		appID int32,
+	) (subscriptions []models.WebhookSubscription, err error)
+	DeleteSubscription(ctx context.Context, actorID int64, appID int32, subscriptionID int64) error
+	Deliveries(
+		ctx context.Context,
+		actorID int64,
+		appID int32,
+		status string,
+		limit int,
+	) (deliveries []models.WebhookDelivery, err error)
+	Redeliver(ctx context.Context, actorID int64, appID int32, deliveryID int64) error
+}
+
+type ServerAPI struct {
+	webhooksv1.UnimplementedWebhooksServer
+	webhooks Webhooks
+}
+
+func Register(gRPC *grpc.Server, webhooks Webhooks) {
+	webhooksv1.RegisterWebhooksServer(gRPC, &ServerAPI{webhooks: webhooks})
+}
+
+// CreateSubscription handler. Subscribes the URL to the events of the application and returns secret of the payloads
+func (s *ServerAPI) CreateSubscription(
+	ctx context.Context,
+	in *webhooksv1.CreateSubscriptionRequest,
+) (*webhooksv1.CreateSubscriptionResponse, error) {
+	caller, err := interceptors.MustAdmin(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	if in.GetAppId() <= 0 {
+		return nil, status.Error(codes.InvalidArgument, "app_id is required")
+	}
+
+	if in.GetUrl() == "" {
+		return nil, status.Error(codes.InvalidArgument, "url is required")
+	}
+
+	subscription, err := s.webhooks.CreateSubscription(
+		ctx, caller.UserID, in.GetAppId(), in.GetUrl(), in.GetEvents(), in.GetAllUsers(),
+	)
+	if err != nil {
+		return nil, webhooksError(err)
+	}
+
+	return &webhooksv1.CreateSubscriptionResponse{
+		Subscription: subscriptionToProto(subscription),
+		Secret:       subscription.Secret,
+	}, nil
+}
+
+// ListSubscriptions handler. Returns subscriptions of the application without their secrets
+func (s *ServerAPI) ListSubscriptions(
+	ctx context.Context,
+	in *webhooksv1.ListSubscriptionsRequest,
+) (*webhooksv1.ListSubscriptionsResponse, error) {
+	caller, err := interceptors.MustAdmin(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	if in.GetAppId() <= 0 {
+		return nil, status.Error(codes.InvalidArgument, "app_id is required")
+	}
+
+	subscriptions, err := s.webhooks.Subscriptions(ctx, caller.UserID, in.GetAppId())
+	if err != nil {
+		return nil, webhooksError(err)
+	}
+
+	resp := &webhooksv1.ListSubscriptionsResponse{
+		Subscriptions: make([]*webhooksv1.Subscription, 0, len(subscriptions)),
+	}
+	for _, subscription := range subscriptions {
+		resp.Subscriptions = append(resp.Subscriptions, subscriptionToProto(subscription))
+	}
+
+	return resp, nil
+}
+
+// DeleteSubscription handler. Deletes the subscription of the application with its deliveries
+func (s *ServerAPI) DeleteSubscription(
+	ctx context.Context,
+	in *webhooksv1.DeleteSubscriptionRequest,
+) (*webhooksv1.DeleteSubscriptionResponse, error) {
+	caller, err := interceptors.MustAdmin(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	if in.GetAppId() <= 0 || in.GetSubscriptionId() <= 0 {
+		return nil, status.Error(codes.InvalidArgument, "app_id and subscription_id are required")
+	}
+
+	if err := s.webhooks.DeleteSubscription(ctx, caller.UserID, in.GetAppId(), in.GetSubscriptionId()); err != nil {
+		return nil, webhooksError(err)
+	}
+
+	return &webhooksv1.DeleteSubscriptionResponse{}, nil
+}
+
+// ListDeliveries handler. Returns the latest deliveries of the application, dead ones are the dead-letter list
+func (s *ServerAPI) ListDeliveries(
+	ctx context.Context,
+	in *webhooksv1.ListDeliveriesRequest,
+) (*webhooksv1.ListDeliveriesResponse, error) {
+	caller, err := interceptors.MustAdmin(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	if in.GetAppId() <= 0 {
+		return nil, status.Error(codes.InvalidArgument, "app_id is required")
+	}
+
+	deliveries, err := s.webhooks.Deliveries(ctx, caller.UserID, in.GetAppId(), in.GetStatus(), int(in.GetLimit()))
+	if err != nil {
+		return nil, webhooksError(err)
+	}
+
+	resp := &webhooksv1.ListDeliveriesResponse{
+		Deliveries: make([]*webhooksv1.Delivery, 0, len(deliveries)),
+	}
+	for _, d := range deliveries {
+		delivery := &webhooksv1.Delivery{
+			Id:             d.ID,
+			SubscriptionId: d.SubscriptionID,
+			EventId:        d.EventID,
+			EventType:      d.EventType,
+			Status:         d.Status,
+			Attempts:       int32(d.Attempts),
+			LastError:      d.LastError,
+			CreatedAt:      d.CreatedAt.Unix(),
+		}
+		if !d.DeliveredAt.IsZero() {
+			delivery.DeliveredAt = d.DeliveredAt.Unix()
+		}
+
+		resp.Deliveries = append(resp.Deliveries, delivery)
+	}
+
+	return resp, nil
+}
+
+// Redeliver handler. Queues the delivered or dead delivery again
+func (s *ServerAPI) Redeliver(ctx context.Context, in *webhooksv1.RedeliverRequest) (*webhooksv1.RedeliverResponse, error) {
+	caller, err := interceptors.MustAdmin(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	if in.GetAppId() <= 0 || in.GetDeliveryId() <= 0 {
+		return nil, status.Error(codes.InvalidArgument, "app_id and delivery_id are required")
+	}
+
+	if err := s.webhooks.Redeliver(ctx, caller.UserID, in.GetAppId(), in.GetDeliveryId()); err != nil {
+		return nil, webhooksError(err)
+	}
+
+	return &webhooksv1.RedeliverResponse{}, nil
+}
+
+func subscriptionToProto(subscription models.WebhookSubscription) *webhooksv1.Subscription {
+	return &webhooksv1.Subscription{
+		Id:        subscription.ID,
+		AppId:     subscription.AppID,
+		Url:       subscription.URL,
+		Events:    subscription.Events,
+		AllUsers:  subscription.AllUsers,
+		CreatedAt: subscription.CreatedAt.Unix(),
+	}
+}
+
+// webhooksError converts errors of the Webhooks service to gRPC status errors
+func webhooksError(err error) error {
+	switch {
+	case errors.Is(err, webhooks.ErrPermissionDenied):
+		return status.Error(codes.PermissionDenied, "admin rights are required")
+	case errors.Is(err, webhooks.ErrInvalidAppID):
+		return status.Error(codes.NotFound, "app not found")
+	case errors.Is(err, webhooks.ErrSubscriptionNotFound):
+		return status.Error(codes.NotFound, "subscription not found")
+	case errors.Is(err, webhooks.ErrDeliveryNotFound):
+		return status.Error(codes.NotFound, "delivery not found or still pending")
+	case errors.Is(err, webhooks.ErrInvalidURL), errors.Is(err, webhooks.ErrUnknownEvent), errors.Is(err, webhooks.ErrInvalidStatus):
+		return status.Error(codes.InvalidArgument, err.Error())
+	}
+
+	return status.Error(codes.Internal, err.Error())
+}
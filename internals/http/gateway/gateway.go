@@ -0,0 +1,306 @@
+package gateway
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+
+	sessionsv1 "github.com/nhassl3/sso-app/contracts/generated/go/sessions"
+	tokenv1 "github.com/nhassl3/sso-app/contracts/generated/go/token"
+	ssov1 "github.com/nhassl3/sso-contracts/generated/go/sso"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/reflect/protoregistry"
+)
+
+const (
+	openAPIPath         = "/openapi.json"
+	authorizationHeader = "authorization"
+	maxBodySize         = 1 << 20
+)
+
+// route maps HTTP endpoint to the RPC. Wildcards of the path and query parameters of the request
+// set fields of the RPC request with the same names, body of POST, PUT and PATCH requests is the RPC request in JSON
+type route struct {
+	method  string
+	path    string // pattern of http.ServeMux without method, e.g. /v1/users/{user_id}/admin
+	rpc     protoreflect.MethodDescriptor
+	summary string
+}
+
+// routes are the REST endpoints of the gateway, new RPC is exposed by adding its route here
+var routes = []route{
+	{http.MethodPost, "/v1/auth/register", authRPC("Register"), "Registers the user by email and password"},
+	{http.MethodPost, "/v1/auth/login", authRPC("Login"), "Logs in the user to the application and returns token"},
+	{http.MethodGet, "/v1/users/{user_id}/admin", authRPC("IsAdmin"), "Checks whether the user is an admin"},
+	{http.MethodPost, "/v1/sessions/logout", sessionsRPC("Logout"), "Ends sessions of the bearer in all applications"},
+	{http.MethodGet, "/v1/realms", tokenRPC("DiscoverRealm"), "Returns the identity provider the user with the email signs in with"},
+}
+
+var (
+	marshaler   = protojson.MarshalOptions{UseProtoNames: true, EmitUnpopulated: true}
+	unmarshaler = protojson.UnmarshalOptions{}
+
+	wildcard = regexp.MustCompile(`{(\w+)}`)
+)
+
+type Handler struct {
+	conn    grpc.ClientConnInterface
+	timeout time.Duration
+}
+
+// errorResponse is body of the failed requests, it carries status of the RPC
+type errorResponse struct {
+	Code    codes.Code `json:"code"`
+	Error   string     `json:"error"`
+	Message string     `json:"message"`
+}
+
+// Register adds the routes and OpenAPI document of them to the mux. Requests are sent by conn
+// to the gRPC server, every call is limited by timeout
+func Register(mux *http.ServeMux, conn grpc.ClientConnInterface, timeout time.Duration) {
+	h := &Handler{conn: conn, timeout: timeout}
+
+	for _, route := range routes {
+		mux.Handle(route.method+" "+route.path, h.rpc(route))
+	}
+
+	document, err := json.Marshal(openAPI(routes))
+	if err != nil {
+		panic(fmt.Errorf("gateway: marshal OpenAPI document: %w", err))
+	}
+
+	mux.HandleFunc("GET "+openAPIPath, func(w http.ResponseWriter, _ *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write(document)
+	})
+}
+
+// rpc returns handler which builds the RPC request from the HTTP request, calls the RPC and writes its response in JSON
+func (h *Handler) rpc(route route) http.Handler {
+	input := mustMessageType(route.rpc.Input())
+	output := mustMessageType(route.rpc.Output())
+	fullMethod := fmt.Sprintf("/%s/%s", route.rpc.Parent().FullName(), route.rpc.Name())
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		in := input.New()
+
+		if err := decodeRequest(r, route, in); err != nil {
+			writeError(w, status.New(codes.InvalidArgument, err.Error()))
+			return
+		}
+
+		ctx, cancel := context.WithTimeout(r.Context(), h.timeout)
+		defer cancel()
+
+		if header := r.Header.Get(authorizationHeader); header != "" {
+			ctx = metadata.AppendToOutgoingContext(ctx, authorizationHeader, header)
+		}
+
+		out := output.New()
+		if err := h.conn.Invoke(ctx, fullMethod, in.Interface(), out.Interface()); err != nil {
+			writeError(w, status.Convert(err))
+			return
+		}
+
+		body, err := marshaler.Marshal(out.Interface())
+		if err != nil {
+			writeError(w, status.New(codes.Internal, err.Error()))
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write(body)
+	})
+}
+
+// decodeRequest sets fields of the RPC request from body, query and path of the HTTP request.
+// Path wildcards are set last, so they can't be overridden by the body
+func decodeRequest(r *http.Request, route route, in protoreflect.Message) error {
+	if hasBody(route.method) {
+		body, err := io.ReadAll(http.MaxBytesReader(nil, r.Body, maxBodySize))
+		if err != nil {
+			return fmt.Errorf("read body: %w", err)
+		}
+
+		if len(body) > 0 {
+			if err := unmarshaler.Unmarshal(body, in.Interface()); err != nil {
+				return fmt.Errorf("malformed body: %w", err)
+			}
+		}
+	} else {
+		for name, values := range r.URL.Query() {
+			if err := setField(in, name, values[len(values)-1]); err != nil {
+				return err
+			}
+		}
+	}
+
+	for _, name := range pathParams(route.path) {
+		if err := setField(in, name, r.PathValue(name)); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// setField parses value of the scalar field by its kind
+func setField(msg protoreflect.Message, name string, value string) error {
+	field := msg.Descriptor().Fields().ByName(protoreflect.Name(name))
+	if field == nil || field.IsList() || field.IsMap() {
+		return fmt.Errorf("unknown parameter %q", name)
+	}
+
+	var (
+		v   protoreflect.Value
+		err error
+	)
+
+	switch field.Kind() {
+	case protoreflect.StringKind:
+		v = protoreflect.ValueOfString(value)
+	case protoreflect.BoolKind:
+		var b bool
+		b, err = strconv.ParseBool(value)
+		v = protoreflect.ValueOfBool(b)
+	case protoreflect.Int32Kind, protoreflect.Sint32Kind, protoreflect.Sfixed32Kind:
+		var i int64
+		i, err = strconv.ParseInt(value, 10, 32)
+		v = protoreflect.ValueOfInt32(int32(i))
+	case protoreflect.Int64Kind, protoreflect.Sint64Kind, protoreflect.Sfixed64Kind:
+		var i int64
+		i, err = strconv.ParseInt(value, 10, 64)
+		v = protoreflect.ValueOfInt64(i)
+	case protoreflect.Uint32Kind, protoreflect.Fixed32Kind:
+		var u uint64
+		u, err = strconv.ParseUint(value, 10, 32)
+		v = protoreflect.ValueOfUint32(uint32(u))
+	case protoreflect.Uint64Kind, protoreflect.Fixed64Kind:
+		var u uint64
+		u, err = strconv.ParseUint(value, 10, 64)
+		v = protoreflect.ValueOfUint64(u)
+	case protoreflect.EnumKind:
+		enum := field.Enum().Values().ByName(protoreflect.Name(value))
+		if enum == nil {
+			return fmt.Errorf("parameter %q: unknown value %q", name, value)
+		}
+		v = protoreflect.ValueOfEnum(enum.Number())
+	default:
+		return fmt.Errorf("parameter %q can't be set by string", name)
+	}
+
+	if err != nil {
+		return fmt.Errorf("parameter %q: %w", name, errors.Unwrap(err))
+	}
+
+	msg.Set(field, v)
+
+	return nil
+}
+
+// writeError writes status of the RPC with the matching HTTP status
+func writeError(w http.ResponseWriter, st *status.Status) {
+	body, _ := json.Marshal(errorResponse{
+		Code:    st.Code(),
+		Error:   codeName(st.Code()),
+		Message: st.Message(),
+	})
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(httpStatus(st.Code()))
+	_, _ = w.Write(body)
+}
+
+// httpStatus maps gRPC code to HTTP status as google.api.http transcoding does
+func httpStatus(code codes.Code) int {
+	switch code {
+	case codes.OK:
+		return http.StatusOK
+	case codes.Canceled:
+		return 499 // client closed request
+	case codes.InvalidArgument, codes.OutOfRange, codes.FailedPrecondition:
+		return http.StatusBadRequest
+	case codes.DeadlineExceeded:
+		return http.StatusGatewayTimeout
+	case codes.NotFound:
+		return http.StatusNotFound
+	case codes.AlreadyExists, codes.Aborted:
+		return http.StatusConflict
+	case codes.PermissionDenied:
+		return http.StatusForbidden
+	case codes.Unauthenticated:
+		return http.StatusUnauthorized
+	case codes.ResourceExhausted:
+		return http.StatusTooManyRequests
+	case codes.Unimplemented:
+		return http.StatusNotImplemented
+	case codes.Unavailable:
+		return http.StatusServiceUnavailable
+	default:
+		return http.StatusInternalServerError
+	}
+}
+
+// codeName returns name of the code in snake case, e.g. invalid_argument
+func codeName(code codes.Code) string {
+	var b strings.Builder
+
+	for i, r := range code.String() {
+		if unicode.IsUpper(r) {
+			if i > 0 {
+				b.WriteByte('_')
+			}
+			r = unicode.ToLower(r)
+		}
+		b.WriteRune(r)
+	}
+
+	return b.String()
+}
+
+func hasBody(method string) bool {
+	return method == http.MethodPost || method == http.MethodPut || method == http.MethodPatch
+}
+
+func pathParams(path string) []string {
+	var params []string
+	for _, match := range wildcard.FindAllStringSubmatch(path, -1) {
+		params = append(params, match[1])
+	}
+
+	return params
+}
+
+func mustMessageType(desc protoreflect.MessageDescriptor) protoreflect.MessageType {
+	messageType, err := protoregistry.GlobalTypes.FindMessageByName(desc.FullName())
+	if err != nil {
+		panic(fmt.Errorf("gateway: message %s: %w", desc.FullName(), err))
+	}
+
+	return messageType
+}
+
+func authRPC(name protoreflect.Name) protoreflect.MethodDescriptor {
+	return ssov1.File_sso_sso_proto.Services().ByName("Auth").Methods().ByName(name)
+}
+
+func sessionsRPC(name protoreflect.Name) protoreflect.MethodDescriptor {
+	return sessionsv1.File_sessions_sessions_proto.Services().ByName("Sessions").Methods().ByName(name)
+}
+
+func tokenRPC(name protoreflect.Name) protoreflect.MethodDescriptor {
+	return tokenv1.File_token_token_proto.Services().ByName("Token").Methods().ByName(name)
+}
@@ -0,0 +1,193 @@
+package gateway
+
+import (
+	"strings"
+
+	"google.golang.org/protobuf/reflect/protoreflect"
+)
+
+const (
+	openAPIVersion = "3.0.3"
+	schemasRef     = "#/components/schemas/"
+	errorSchema    = "Error"
+)
+
+// openAPI generates OpenAPI document of the routes from descriptors of the RPC messages.
+// Fields are named as in proto files and 64-bit integers are strings, as protojson encodes them
+func openAPI(routes []route) map[string]any {
+	schemas := map[string]any{
+		errorSchema: map[string]any{
+			"type": "object",
+			"properties": map[string]any{
+				"code":    map[string]any{"type": "integer", "format": "int32", "description": "gRPC status code"},
+				"error":   map[string]any{"type": "string", "description": "gRPC status code in snake case"},
+				"message": map[string]any{"type": "string"},
+			},
+		},
+	}
+	paths := map[string]map[string]any{}
+
+	for _, route := range routes {
+		addSchema(schemas, route.rpc.Input())
+		addSchema(schemas, route.rpc.Output())
+
+		operation := map[string]any{
+			"operationId": string(route.rpc.Parent().Name()) + "_" + string(route.rpc.Name()),
+			"summary":     route.summary,
+			"tags":        []string{string(route.rpc.Parent().Name())},
+			"responses": map[string]any{
+				"200": jsonContent("OK", schemaRef(route.rpc.Output())),
+				"default": jsonContent("Status of the failed RPC", map[string]any{
+					"$ref": schemasRef + errorSchema,
+				}),
+			},
+			"security": []map[string][]string{{}, {"bearer": {}}},
+		}
+
+		if parameters := parameters(route); len(parameters) > 0 {
+			operation["parameters"] = parameters
+		}
+
+		if hasBody(route.method) {
+			body := jsonContent("", schemaRef(route.rpc.Input()))
+			body["required"] = true
+			operation["requestBody"] = body
+		}
+
+		if paths[route.path] == nil {
+			paths[route.path] = map[string]any{}
+		}
+		paths[route.path][strings.ToLower(route.method)] = operation
+	}
+
+	return map[string]any{
+		"openapi": openAPIVersion,
+		"info": map[string]any{
+			"title":       "SSO REST gateway",
+			"description": "JSON endpoints of the gRPC services, bearer token is passed to the RPC as is",
+			"version":     "v1",
+		},
+		"paths": paths,
+		"components": map[string]any{
+			"schemas": schemas,
+			"securitySchemes": map[string]any{
+				"bearer": map[string]any{"type": "http", "scheme": "bearer"},
+			},
+		},
+	}
+}
+
+// parameters returns path wildcards of the route and, for routes without body, query parameters
+// of the remaining scalar fields of the RPC request
+func parameters(route route) []map[string]any {
+	var params []map[string]any
+
+	inPath := map[string]bool{}
+	for _, name := range pathParams(route.path) {
+		inPath[name] = true
+	}
+
+	fields := route.rpc.Input().Fields()
+	for i := range fields.Len() {
+		field := fields.Get(i)
+		name := string(field.Name())
+
+		switch {
+		case inPath[name]:
+			params = append(params, map[string]any{
+				"name": name, "in": "path", "required": true, "schema": fieldSchema(field),
+			})
+		case !hasBody(route.method) && !field.IsList() && !field.IsMap() && field.Message() == nil:
+			params = append(params, map[string]any{
+				"name": name, "in": "query", "schema": fieldSchema(field),
+			})
+		}
+	}
+
+	return params
+}
+
+// addSchema adds schema of the message and messages of its fields
+func addSchema(schemas map[string]any, msg protoreflect.MessageDescriptor) {
+	name := string(msg.FullName())
+	if _, ok := schemas[name]; ok {
+		return
+	}
+
+	properties := map[string]any{}
+	schemas[name] = map[string]any{"type": "object", "properties": properties}
+
+	fields := msg.Fields()
+	for i := range fields.Len() {
+		field := fields.Get(i)
+		properties[string(field.Name())] = fieldSchema(field)
+
+		if field.IsMap() {
+			field = field.MapValue()
+		}
+		if field.Message() != nil {
+			addSchema(schemas, field.Message())
+		}
+	}
+}
+
+func fieldSchema(field protoreflect.FieldDescriptor) map[string]any {
+	switch {
+	case field.IsMap():
+		return map[string]any{"type": "object", "additionalProperties": kindSchema(field.MapValue())}
+	case field.IsList():
+		return map[string]any{"type": "array", "items": kindSchema(field)}
+	default:
+		return kindSchema(field)
+	}
+}
+
+// kindSchema returns schema of the single value of the field
+func kindSchema(field protoreflect.FieldDescriptor) map[string]any {
+	switch field.Kind() {
+	case protoreflect.BoolKind:
+		return map[string]any{"type": "boolean"}
+	case protoreflect.Int32Kind, protoreflect.Sint32Kind, protoreflect.Sfixed32Kind:
+		return map[string]any{"type": "integer", "format": "int32"}
+	case protoreflect.Uint32Kind, protoreflect.Fixed32Kind:
+		return map[string]any{"type": "integer", "format": "uint32"}
+	case protoreflect.Int64Kind, protoreflect.Sint64Kind, protoreflect.Sfixed64Kind:
+		return map[string]any{"type": "string", "format": "int64"}
+	case protoreflect.Uint64Kind, protoreflect.Fixed64Kind:
+		return map[string]any{"type": "string", "format": "uint64"}
+	case protoreflect.FloatKind:
+		return map[string]any{"type": "number", "format": "float"}
+	case protoreflect.DoubleKind:
+		return map[string]any{"type": "number", "format": "double"}
+	case protoreflect.BytesKind:
+		return map[string]any{"type": "string", "format": "byte"}
+	case protoreflect.EnumKind:
+		var values []string
+		for i := range field.Enum().Values().Len() {
+			values = append(values, string(field.Enum().Values().Get(i).Name()))
+		}
+
+		return map[string]any{"type": "string", "enum": values}
+	case protoreflect.MessageKind, protoreflect.GroupKind:
+		return schemaRef(field.Message())
+	default:
+		return map[string]any{"type": "string"}
+	}
+}
+
+func schemaRef(msg protoreflect.MessageDescriptor) map[string]any {
+	return map[string]any{"$ref": schemasRef + string(msg.FullName())}
+}
+
+func jsonContent(description string, schema map[string]any) map[string]any {
+	content := map[string]any{
+		"content": map[string]any{
+			"application/json": map[string]any{"schema": schema},
+		},
+	}
+	if description != "" {
+		content["description"] = description
+	}
+
+	return content
+}
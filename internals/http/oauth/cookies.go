@@ -0,0 +1,180 @@
+package oauth
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/nhassl3/sso-app/internals/domain/models"
+)
+
+const (
+	csrfCookie       = "sso_csrf"
+	loginCookie      = "sso_login"
+	federationCookie = "sso_federation"
+	csrfField        = "csrf_token"
+	csrfSize         = 32
+)
+
+var errInvalidCookie = errors.New("cookie is invalid or expired")
+
+// pendingLogin is the user authenticated by the login page, who is asked for consent to the request.
+// It is sealed into the cookie, so consent page authorizes the same user and request without asking the password again
+type pendingLogin struct {
+	UserID    int64                   `json:"user_id"`
+	Request   models.AuthorizeRequest `json:"request"`
+	ExpiresAt int64                   `json:"exp"`
+}
+
+// cookies issues CSRF cookies and seals cookies of the hosted pages by AES-GCM
+type cookies struct {
+	aead     cipher.AEAD
+	secure   bool
+	loginTTL time.Duration
+}
+
+func newCookies(key []byte, secure bool, loginTTL time.Duration) (*cookies, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+
+	aead, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, err
+	}
+
+	return &cookies{aead: aead, secure: secure, loginTTL: loginTTL}, nil
+}
+
+// csrfToken returns CSRF token of the browser, new token is set into the cookie if it has none.
+// Form of the page sends the token back and POST handlers compare it with the cookie
+func (c *cookies) csrfToken(w http.ResponseWriter, r *http.Request) (string, error) {
+	if cookie, err := r.Cookie(csrfCookie); err == nil && cookie.Value != "" {
+		return cookie.Value, nil
+	}
+
+	raw := make([]byte, csrfSize)
+	if _, err := rand.Read(raw); err != nil {
+		return "", err
+	}
+	token := base64.RawURLEncoding.EncodeToString(raw)
+
+	http.SetCookie(w, c.cookie(csrfCookie, token, "/", 0))
+
+	return token, nil
+}
+
+// checkCSRF reports whether the form sends the token of the CSRF cookie
+func (c *cookies) checkCSRF(r *http.Request) bool {
+	cookie, err := r.Cookie(csrfCookie)
+	if err != nil || cookie.Value == "" {
+		return false
+	}
+
+	return subtle.ConstantTimeCompare([]byte(cookie.Value), []byte(r.PostForm.Get(csrfField))) == 1
+}
+
+// setLogin seals the login into the cookie sent only to the consent page
+func (c *cookies) setLogin(w http.ResponseWriter, login pendingLogin) error {
+	login.ExpiresAt = time.Now().Add(c.loginTTL).Unix()
+
+	plain, err := json.Marshal(login)
+	if err != nil {
+		return err
+	}
+
+	nonce := make([]byte, c.aead.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return err
+	}
+
+	sealed := c.aead.Seal(nonce, nonce, plain, []byte(loginCookie))
+	http.SetCookie(w, c.cookie(loginCookie, base64.RawURLEncoding.EncodeToString(sealed), consentPath, c.loginTTL))
+
+	return nil
+}
+
+// login opens the sealed login of the cookie, expired login is invalid
+func (c *cookies) login(r *http.Request) (pendingLogin, error) {
+	cookie, err := r.Cookie(loginCookie)
+	if err != nil {
+		return pendingLogin{}, errInvalidCookie
+	}
+
+	sealed, err := base64.RawURLEncoding.DecodeString(cookie.Value)
+	if err != nil || len(sealed) < c.aead.NonceSize() {
+		return pendingLogin{}, errInvalidCookie
+	}
+
+	nonce, ciphertext := sealed[:c.aead.NonceSize()], sealed[c.aead.NonceSize():]
+
+	plain, err := c.aead.Open(nil, nonce, ciphertext, []byte(loginCookie))
+	if err != nil {
+		return pendingLogin{}, errInvalidCookie
+	}
+
+	var login pendingLogin
+	if err := json.Unmarshal(plain, &login); err != nil || time.Now().Unix() > login.ExpiresAt {
+		return pendingLogin{}, errInvalidCookie
+	}
+
+	return login, nil
+}
+
+// clearLogin removes the sealed login from the browser after the decision
+func (c *cookies) clearLogin(w http.ResponseWriter) {
+	http.SetCookie(w, c.cookie(loginCookie, "", consentPath, -1))
+}
+
+// setFederation binds state of the federated login to the browser. The provider redirects back by the top-level
+// cross-site navigation, so the cookie is sent by lax same-site policy only to the callbacks
+func (c *cookies) setFederation(w http.ResponseWriter, state string) {
+	cookie := c.cookie(federationCookie, state, federationPath, 0)
+	cookie.SameSite = http.SameSiteLaxMode
+
+	http.SetCookie(w, cookie)
+}
+
+// checkFederation reports whether the state of the callback is bound to the browser
+func (c *cookies) checkFederation(r *http.Request, state string) bool {
+	cookie, err := r.Cookie(federationCookie)
+	if err != nil || cookie.Value == "" {
+		return false
+	}
+
+	return subtle.ConstantTimeCompare([]byte(cookie.Value), []byte(state)) == 1
+}
+
+// clearFederation removes state of the federated login from the browser after the callback
+func (c *cookies) clearFederation(w http.ResponseWriter) {
+	http.SetCookie(w, c.cookie(federationCookie, "", federationPath, -1))
+}
+
+// cookie returns HTTP-only cookie which isn't sent by cross-site requests, zero TTL makes the session cookie
+// and negative TTL removes the cookie
+func (c *cookies) cookie(name, value, path string, ttl time.Duration) *http.Cookie {
+	cookie := &http.Cookie{
+		Name:     name,
+		Value:    value,
+		Path:     path,
+		Secure:   c.secure,
+		HttpOnly: true,
+		SameSite: http.SameSiteStrictMode,
+	}
+
+	switch {
+	case ttl < 0:
+		cookie.MaxAge = -1
+	case ttl > 0:
+		cookie.MaxAge = int(ttl.Seconds())
+	}
+
+	return cookie
+}
@@ -0,0 +1,104 @@
+package oauth
+
+import (
+	"net/http"
+	"net/url"
+)
+
+const federationPath = "/federation/"
+
+type providerLink struct {
+	Name string
+	URL  string
+}
+
+// FederatedLogin handler. Starts sign in of the authorization request at the upstream provider
+// and redirects the browser to it. State of the provider is bound to the browser by the cookie
+func (p *Pages) FederatedLogin(w http.ResponseWriter, r *http.Request) {
+	if err := r.ParseForm(); err != nil {
+		p.renderError(w, http.StatusBadRequest, "Malformed request")
+		return
+	}
+
+	req, ok := authorizeRequest(r.Form, "")
+	if !ok {
+		p.renderError(w, http.StatusBadRequest, "Sign in must be started by the application")
+		return
+	}
+
+	clientState := r.Form.Get("state")
+
+	authURL, state, redirectURI, err := p.auth.StartFederatedLogin(r.Context(), r.PathValue("provider"), req, clientState)
+	if err != nil {
+		p.federated(w, r, "", redirectURI, clientState, err)
+		return
+	}
+
+	p.cookies.setFederation(w, state)
+
+	http.Redirect(w, r, authURL, http.StatusFound)
+}
+
+// FederatedCallback handler. The provider redirects the browser back with its code, which is exchanged
+// for the identity of the user, and the browser is redirected to the client with authorization code
+func (p *Pages) FederatedCallback(w http.ResponseWriter, r *http.Request) {
+	state := r.URL.Query().Get("state")
+
+	// State issued to another browser is rejected, so nobody signs the user in as somebody else
+	if !p.cookies.checkFederation(r, state) {
+		p.renderError(w, http.StatusBadRequest, "Your sign in has expired, sign in again")
+		return
+	}
+
+	p.cookies.clearFederation(w)
+
+	code, redirectURI, clientState, err := p.auth.FinishFederatedLogin(
+		r.Context(), r.PathValue("provider"), state, r.URL.Query().Get("code"), r.URL.Query().Get("error"),
+	)
+	p.federated(w, r, code, redirectURI, clientState, err)
+}
+
+// federated sends code or error of the federated login to the client, errors which can't be sent are shown to the user
+func (p *Pages) federated(w http.ResponseWriter, r *http.Request, code, redirectURI, state string, err error) {
+	switch {
+	case err == nil:
+		redirect(w, r, redirectURI, url.Values{"code": {code}, "state": {state}})
+	case redirectURI == "":
+		status, _, description := authorizeError(err)
+		p.renderError(w, status, sentence(description))
+	default:
+		_, errCode, description := authorizeError(err)
+		redirect(w, r, redirectURI, url.Values{
+			"error":             {errCode},
+			"error_description": {description},
+			"state":             {state},
+		})
+	}
+}
+
+// realmLogin redirects the user to the federated login of the provider with the authorization request of the page
+func (p *Pages) realmLogin(w http.ResponseWriter, r *http.Request, data pageData, provider string) {
+	for _, link := range data.Providers {
+		if link.Name == provider {
+			http.Redirect(w, r, link.URL, http.StatusFound)
+			return
+		}
+	}
+
+	p.renderError(w, http.StatusInternalServerError, "Identity provider of your email is unavailable")
+}
+
+// providerLinks returns links of the login page to sign in at the upstream providers with the authorization request
+func (p *Pages) providerLinks(query url.Values) []providerLink {
+	names := p.auth.FederationProviders()
+
+	links := make([]providerLink, 0, len(names))
+	for _, name := range names {
+		links = append(links, providerLink{
+			Name: name,
+			URL:  federationPath + url.PathEscape(name) + "/login?" + query.Encode(),
+		})
+	}
+
+	return links
+}
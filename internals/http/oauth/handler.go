@@ -0,0 +1,573 @@
+package oauth
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/url"
+	"strconv"
+
+	"github.com/nhassl3/sso-app/internals/domain/models"
+	"github.com/nhassl3/sso-app/internals/domain/services/auth"
+	njwt "github.com/nhassl3/sso-app/internals/lib/jwt"
+)
+
+// Error codes of RFC 6749
+const (
+	errInvalidRequest          = "invalid_request"
+	errInvalidClient           = "invalid_client"
+	errInvalidGrant            = "invalid_grant"
+	errInvalidScope            = "invalid_scope"
+	errInvalidTarget           = "invalid_target"
+	errUnauthorizedClient      = "unauthorized_client"
+	errUnsupportedGrantType    = "unsupported_grant_type"
+	errUnsupportedResponseType = "unsupported_response_type"
+	errAccessDenied            = "access_denied"
+	errAuthorizationPending    = "authorization_pending"
+	errSlowDown                = "slow_down"
+	errExpiredToken            = "expired_token"
+	errConsentRequired         = "consent_required"
+	errServerError             = "server_error"
+)
+
+type Auth interface {
+	Authorize(
+		ctx context.Context,
+		req models.AuthorizeRequest,
+		email string,
+		password string,
+	) (code string, userID int64, redirectURI string, err error)
+	AuthorizeUser(
+		ctx context.Context,
+		req models.AuthorizeRequest,
+		userID int64,
+	) (code string, redirectURI string, err error)
+	ValidateAuthorizeRequest(
+		ctx context.Context,
+		req models.AuthorizeRequest,
+	) (redirectURI string, err error)
+	ExchangeCode(
+		ctx context.Context,
+		appID int32,
+		clientSecret string,
+		code string,
+		redirectURI string,
+		codeVerifier string,
+	) (token models.OAuthToken, err error)
+	ClientCredentials(
+		ctx context.Context,
+		appID int32,
+		clientSecret string,
+		scope string,
+	) (token models.OAuthToken, err error)
+	AuthorizeDevice(
+		ctx context.Context,
+		appID int32,
+		clientSecret string,
+		scope string,
+	) (authorization models.DeviceAuthorization, err error)
+	ExchangeDeviceCode(
+		ctx context.Context,
+		appID int32,
+		clientSecret string,
+		deviceCode string,
+	) (token models.OAuthToken, err error)
+	RefreshToken(
+		ctx context.Context,
+		appID int32,
+		clientSecret string,
+		refreshToken string,
+		scope string,
+	) (token models.OAuthToken, err error)
+	ExchangeToken(
+		ctx context.Context,
+		req models.TokenExchangeRequest,
+	) (token models.OAuthToken, err error)
+	UserInfo(
+		ctx context.Context,
+		accessToken string,
+	) (claims map[string]any, err error)
+	Issuer() string
+	PublicKeys() []njwt.JWK
+	ClientApp(
+		ctx context.Context,
+		appID int32,
+	) (app models.App, err error)
+	RegisterNewUser(
+		ctx context.Context,
+		email string,
+		password string,
+	) (userID int64, err error)
+	FederationProviders() []string
+	DiscoverRealm(
+		ctx context.Context,
+		email string,
+		appID int32,
+	) (realm models.Realm, err error)
+	StartFederatedLogin(
+		ctx context.Context,
+		providerName string,
+		req models.AuthorizeRequest,
+		clientState string,
+	) (authURL string, state string, redirectURI string, err error)
+	FinishFederatedLogin(
+		ctx context.Context,
+		providerName string,
+		state string,
+		code string,
+		upstreamError string,
+	) (authCode string, redirectURI string, clientState string, err error)
+}
+
+type Handler struct {
+	auth Auth
+}
+
+func Register(mux *http.ServeMux, auth Auth) {
+	h := &Handler{auth: auth}
+
+	mux.HandleFunc("GET /authorize", h.Authorize)
+	mux.HandleFunc("POST /authorize", h.Authorize)
+	mux.HandleFunc("POST /token", h.Token)
+	mux.HandleFunc("POST /device_authorization", h.DeviceAuthorization)
+	mux.HandleFunc("GET /userinfo", h.UserInfo)
+	mux.HandleFunc("POST /userinfo", h.UserInfo)
+	mux.HandleFunc("GET "+discoveryPath, h.Discovery)
+	mux.HandleFunc("GET "+jwksPath, h.JWKS)
+}
+
+// Authorize handler. Checks the authorization request of the client and sends the browser to the hosted login page,
+// where the user signs in and consents to the scope of the third-party client. The request is sent by the query
+// or by the form, credentials and consent of the user aren't accepted here
+func (h *Handler) Authorize(w http.ResponseWriter, r *http.Request) {
+	if err := r.ParseForm(); err != nil {
+		writeError(w, http.StatusBadRequest, errInvalidRequest, "malformed request")
+		return
+	}
+
+	req, ok := authorizeRequest(r.Form, "")
+	if !ok {
+		writeError(w, http.StatusBadRequest, errInvalidRequest, "invalid client_id")
+		return
+	}
+
+	redirectURI, err := h.auth.ValidateAuthorizeRequest(r.Context(), req)
+	if err != nil {
+		// Without checked redirect URI the error can't be sent to the client, it is shown to the user
+		if redirectURI == "" {
+			status, code, description := authorizeError(err)
+			writeError(w, status, code, description)
+
+			return
+		}
+
+		_, code, description := authorizeError(err)
+		redirect(w, r, redirectURI, url.Values{
+			"error":             {code},
+			"error_description": {description},
+			"state":             {r.Form.Get("state")},
+		})
+
+		return
+	}
+
+	query := url.Values{}
+	for _, name := range authorizeParams {
+		if value := r.Form.Get(name); value != "" {
+			query.Set(name, value)
+		}
+	}
+
+	http.Redirect(w, r, loginPath+"?"+query.Encode(), http.StatusFound)
+}
+
+// Token handler. Exchanges authorization code, client credentials, device code, refresh token
+// or other token for the access token
+func (h *Handler) Token(w http.ResponseWriter, r *http.Request) {
+	if err := r.ParseForm(); err != nil {
+		writeError(w, http.StatusBadRequest, errInvalidRequest, "malformed request")
+		return
+	}
+
+	switch grantType := r.PostForm.Get("grant_type"); grantType {
+	case models.GrantTypeAuthCode:
+		h.exchangeCode(w, r)
+	case models.GrantTypeClient:
+		h.clientCredentials(w, r)
+	case models.GrantTypeDevice:
+		h.exchangeDeviceCode(w, r)
+	case models.GrantTypeExchange:
+		h.exchangeToken(w, r)
+	case models.GrantTypeRefresh:
+		h.refreshToken(w, r)
+	default:
+		writeError(w, http.StatusBadRequest, errUnsupportedGrantType, "grant type "+strconv.Quote(grantType)+" is not supported")
+	}
+}
+
+// exchangeCode exchanges authorization code verified by PKCE, confidential client authenticates too
+func (h *Handler) exchangeCode(w http.ResponseWriter, r *http.Request) {
+	appID, clientSecret, ok := oauthClient(r)
+	if !ok {
+		writeError(w, http.StatusBadRequest, errInvalidRequest, "invalid client_id")
+		return
+	}
+
+	if r.PostForm.Get("code") == "" {
+		writeError(w, http.StatusBadRequest, errInvalidRequest, "code is required")
+		return
+	}
+
+	token, err := h.auth.ExchangeCode(
+		r.Context(),
+		appID,
+		clientSecret,
+		r.PostForm.Get("code"),
+		r.PostForm.Get("redirect_uri"),
+		r.PostForm.Get("code_verifier"),
+	)
+	if err != nil {
+		tokenError(w, err)
+		return
+	}
+
+	writeToken(w, token)
+}
+
+// exchangeDeviceCode exchanges device code of the client polling the token endpoint
+func (h *Handler) exchangeDeviceCode(w http.ResponseWriter, r *http.Request) {
+	appID, clientSecret, ok := oauthClient(r)
+	if !ok {
+		writeError(w, http.StatusBadRequest, errInvalidRequest, "invalid client_id")
+		return
+	}
+
+	if r.PostForm.Get("device_code") == "" {
+		writeError(w, http.StatusBadRequest, errInvalidRequest, "device_code is required")
+		return
+	}
+
+	token, err := h.auth.ExchangeDeviceCode(r.Context(), appID, clientSecret, r.PostForm.Get("device_code"))
+	if err != nil {
+		tokenError(w, err)
+		return
+	}
+
+	writeToken(w, token)
+}
+
+// refreshToken exchanges refresh token for the new access and refresh tokens, confidential client authenticates too
+func (h *Handler) refreshToken(w http.ResponseWriter, r *http.Request) {
+	appID, clientSecret, ok := oauthClient(r)
+	if !ok {
+		writeError(w, http.StatusBadRequest, errInvalidRequest, "invalid client_id")
+		return
+	}
+
+	if r.PostForm.Get("refresh_token") == "" {
+		writeError(w, http.StatusBadRequest, errInvalidRequest, "refresh_token is required")
+		return
+	}
+
+	token, err := h.auth.RefreshToken(
+		r.Context(),
+		appID,
+		clientSecret,
+		r.PostForm.Get("refresh_token"),
+		r.PostForm.Get("scope"),
+	)
+	if err != nil {
+		tokenError(w, err)
+		return
+	}
+
+	writeToken(w, token)
+}
+
+// exchangeToken exchanges token of the user by RFC 8693.
+// Admin impersonates the user of requested_subject by own token of the admin console, other exchanges need authentication of the client
+func (h *Handler) exchangeToken(w http.ResponseWriter, r *http.Request) {
+	if r.PostForm.Get("subject_token") == "" || r.PostForm.Get("subject_token_type") != models.TokenTypeAccessToken {
+		writeError(w, http.StatusBadRequest, errInvalidRequest, "subject_token of access_token type is required")
+		return
+	}
+
+	if tokenType := r.PostForm.Get("requested_token_type"); tokenType != "" && tokenType != models.TokenTypeAccessToken {
+		writeError(w, http.StatusBadRequest, errInvalidRequest, "only access_token can be requested")
+		return
+	}
+
+	req := models.TokenExchangeRequest{
+		SubjectToken: r.PostForm.Get("subject_token"),
+		Scope:        r.PostForm.Get("scope"),
+	}
+
+	if audience := r.PostForm.Get("audience"); audience != "" {
+		appID, err := strconv.ParseInt(audience, 10, 32)
+		if err != nil || appID <= 0 {
+			writeError(w, http.StatusBadRequest, errInvalidTarget, "audience must be client_id of the application")
+			return
+		}
+
+		req.Audience = int32(appID)
+	}
+
+	if subject := r.PostForm.Get("requested_subject"); subject != "" {
+		userID, err := strconv.ParseInt(subject, 10, 64)
+		if err != nil || userID <= 0 {
+			writeError(w, http.StatusBadRequest, errInvalidRequest, "requested_subject must be user ID")
+			return
+		}
+
+		req.RequestedSubject = userID
+	} else {
+		clientID, clientSecret, ok := clientAuth(r)
+		if !ok {
+			w.Header().Set("WWW-Authenticate", `Basic realm="sso"`)
+			writeError(w, http.StatusUnauthorized, errInvalidClient, "client authentication is required")
+
+			return
+		}
+
+		appID, err := strconv.ParseInt(clientID, 10, 32)
+		if err != nil || appID <= 0 {
+			writeError(w, http.StatusUnauthorized, errInvalidClient, "invalid client_id")
+			return
+		}
+
+		req.ClientID, req.ClientSecret = int32(appID), clientSecret
+	}
+
+	token, err := h.auth.ExchangeToken(r.Context(), req)
+	if err != nil {
+		tokenError(w, err)
+		return
+	}
+
+	writeToken(w, token)
+}
+
+// clientCredentials issues token of the confidential client authenticated by HTTP Basic or by the form
+func (h *Handler) clientCredentials(w http.ResponseWriter, r *http.Request) {
+	clientID, clientSecret, ok := clientAuth(r)
+	if !ok {
+		w.Header().Set("WWW-Authenticate", `Basic realm="sso"`)
+		writeError(w, http.StatusUnauthorized, errInvalidClient, "client authentication is required")
+
+		return
+	}
+
+	appID, err := strconv.ParseInt(clientID, 10, 32)
+	if err != nil || appID <= 0 {
+		writeError(w, http.StatusUnauthorized, errInvalidClient, "invalid client_id")
+		return
+	}
+
+	token, err := h.auth.ClientCredentials(r.Context(), int32(appID), clientSecret, r.PostForm.Get("scope"))
+	if err != nil {
+		tokenError(w, err)
+		return
+	}
+
+	writeToken(w, token)
+}
+
+// DeviceAuthorization handler. Issues device code and user code of RFC 8628 for the client
+func (h *Handler) DeviceAuthorization(w http.ResponseWriter, r *http.Request) {
+	if err := r.ParseForm(); err != nil {
+		writeError(w, http.StatusBadRequest, errInvalidRequest, "malformed request")
+		return
+	}
+
+	appID, clientSecret, ok := oauthClient(r)
+	if !ok {
+		writeError(w, http.StatusBadRequest, errInvalidRequest, "invalid client_id")
+		return
+	}
+
+	authorization, err := h.auth.AuthorizeDevice(r.Context(), appID, clientSecret, r.PostForm.Get("scope"))
+	if err != nil {
+		tokenError(w, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, deviceAuthorizationResponse{
+		DeviceCode:              authorization.DeviceCode,
+		UserCode:                authorization.UserCode,
+		VerificationURI:         authorization.VerificationURI,
+		VerificationURIComplete: authorization.VerificationURIComplete,
+		ExpiresIn:               int64(authorization.ExpiresIn.Seconds()),
+		Interval:                int64(authorization.Interval.Seconds()),
+	})
+}
+
+type deviceAuthorizationResponse struct {
+	DeviceCode              string `json:"device_code"`
+	UserCode                string `json:"user_code"`
+	VerificationURI         string `json:"verification_uri"`
+	VerificationURIComplete string `json:"verification_uri_complete"`
+	ExpiresIn               int64  `json:"expires_in"`
+	Interval                int64  `json:"interval"`
+}
+
+type tokenResponse struct {
+	AccessToken string `json:"access_token"`
+	TokenType   string `json:"token_type"`
+	ExpiresIn   int64  `json:"expires_in"`
+	Scope       string `json:"scope,omitempty"`
+	IDToken     string `json:"id_token,omitempty"`
+
+	RefreshToken    string `json:"refresh_token,omitempty"`
+	IssuedTokenType string `json:"issued_token_type,omitempty"`
+}
+
+type errorResponse struct {
+	Error            string `json:"error"`
+	ErrorDescription string `json:"error_description,omitempty"`
+}
+
+// clientAuth returns credentials of the client from HTTP Basic authorization or from the form.
+// Basic credentials are form-encoded as RFC 6749 requires
+func clientAuth(r *http.Request) (clientID string, clientSecret string, ok bool) {
+	if user, password, basic := r.BasicAuth(); basic {
+		clientID, errID := url.QueryUnescape(user)
+		clientSecret, errSecret := url.QueryUnescape(password)
+
+		return clientID, clientSecret, errID == nil && errSecret == nil
+	}
+
+	clientID, clientSecret = r.PostForm.Get("client_id"), r.PostForm.Get("client_secret")
+
+	return clientID, clientSecret, clientID != "" && clientSecret != ""
+}
+
+// oauthClient returns ID of the client which may be public and its secret, empty if the client doesn't send it.
+// Credentials are taken from HTTP Basic authorization or from the form as by clientAuth
+func oauthClient(r *http.Request) (appID int32, clientSecret string, ok bool) {
+	clientID, clientSecret, ok := clientAuth(r)
+	if !ok {
+		clientID, clientSecret = r.PostForm.Get("client_id"), ""
+	}
+
+	id, err := strconv.ParseInt(clientID, 10, 32)
+	if err != nil || id <= 0 {
+		return 0, "", false
+	}
+
+	return int32(id), clientSecret, true
+}
+
+// tokenError writes errors of the token issuance as errors of RFC 6749
+func tokenError(w http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, auth.ErrInvalidGrant):
+		writeError(w, http.StatusBadRequest, errInvalidGrant, "grant is invalid, used or issued to another client")
+	case errors.Is(err, auth.ErrAuthorizationPending):
+		writeError(w, http.StatusBadRequest, errAuthorizationPending, "user hasn't approved the device yet")
+	case errors.Is(err, auth.ErrSlowDown):
+		writeError(w, http.StatusBadRequest, errSlowDown, "polling interval is increased by 5 seconds")
+	case errors.Is(err, auth.ErrExpiredToken):
+		writeError(w, http.StatusBadRequest, errExpiredToken, "device code is expired")
+	case errors.Is(err, auth.ErrAccessDenied):
+		writeError(w, http.StatusBadRequest, errAccessDenied, "user denied the device")
+	case errors.Is(err, auth.ErrInvalidScope):
+		writeError(w, http.StatusBadRequest, errInvalidScope, "scope is not granted to the client")
+	case errors.Is(err, auth.ErrInvalidSubjectToken):
+		writeError(w, http.StatusBadRequest, errInvalidRequest, "subject_token is invalid or can't be exchanged")
+	case errors.Is(err, auth.ErrInvalidUserID):
+		writeError(w, http.StatusBadRequest, errInvalidRequest, "requested_subject not found")
+	case errors.Is(err, auth.ErrInvalidTarget):
+		writeError(w, http.StatusBadRequest, errInvalidTarget, "audience is unknown or disabled")
+	case errors.Is(err, auth.ErrUnauthorizedClient):
+		writeError(w, http.StatusBadRequest, errUnauthorizedClient, "grant type is not allowed to the client")
+	case errors.Is(err, auth.ErrImpersonationDenied):
+		writeError(w, http.StatusBadRequest, errUnauthorizedClient, "admin rights are required to impersonate the user")
+	case errors.Is(err, auth.ErrInvalidAppID), errors.Is(err, auth.ErrAppDisabled), errors.Is(err, auth.ErrInvalidClient):
+		writeError(w, http.StatusUnauthorized, errInvalidClient, "unknown or disabled client or invalid client secret")
+	default:
+		writeError(w, http.StatusInternalServerError, errServerError, "failed to issue token")
+	}
+}
+
+func writeToken(w http.ResponseWriter, token models.OAuthToken) {
+	writeJSON(w, http.StatusOK, tokenResponse{
+		AccessToken: token.AccessToken,
+		TokenType:   token.TokenType,
+		ExpiresIn:   int64(token.ExpiresIn.Seconds()),
+		Scope:       token.Scope,
+		IDToken:     token.IDToken,
+
+		RefreshToken:    token.RefreshToken,
+		IssuedTokenType: token.IssuedTokenType,
+	})
+}
+
+// authorizeError converts errors of the authorization to HTTP status and error code of RFC 6749
+func authorizeError(err error) (status int, code string, description string) {
+	switch {
+	case errors.Is(err, auth.ErrInvalidAppID), errors.Is(err, auth.ErrAppDisabled):
+		return http.StatusBadRequest, errInvalidClient, "unknown or disabled client"
+	case errors.Is(err, auth.ErrInvalidRedirectURI):
+		return http.StatusBadRequest, errInvalidRequest, "redirect_uri is not registered by the client"
+	case errors.Is(err, auth.ErrUnsupportedResponseType):
+		return http.StatusBadRequest, errUnsupportedResponseType, "only code response type is supported"
+	case errors.Is(err, auth.ErrInvalidPKCE):
+		return http.StatusBadRequest, errInvalidRequest, "code_challenge with S256 code_challenge_method is required"
+	case errors.Is(err, auth.ErrUnauthorizedClient):
+		return http.StatusBadRequest, errUnauthorizedClient, "authorization code grant is not allowed to the client"
+	case errors.Is(err, auth.ErrInvalidScope):
+		return http.StatusBadRequest, errInvalidScope, "scope is not allowed to the client"
+	case errors.Is(err, auth.ErrInvalidCredentials):
+		return http.StatusUnauthorized, errAccessDenied, "email or password is invalid"
+	case errors.Is(err, auth.ErrConsentDenied):
+		return http.StatusForbidden, errAccessDenied, "user denied the scope"
+	case errors.Is(err, auth.ErrConsentRequired):
+		return http.StatusForbidden, errConsentRequired, "user must consent to the scope of the client"
+	case errors.Is(err, auth.ErrUnknownProvider):
+		return http.StatusNotFound, errInvalidRequest, "identity provider is unknown"
+	case errors.Is(err, auth.ErrInvalidFederationState):
+		return http.StatusBadRequest, errInvalidRequest, "sign in with the identity provider has expired, sign in again"
+	case errors.Is(err, auth.ErrUpstreamLogin), errors.Is(err, auth.ErrIdentityWithoutEmail):
+		return http.StatusUnauthorized, errAccessDenied, "sign in with the identity provider failed"
+	case errors.Is(err, auth.ErrIdentityConflict):
+		return http.StatusConflict, errAccessDenied, "account with the email of the identity already exists"
+	case errors.Is(err, auth.ErrFederatedRealm):
+		return http.StatusForbidden, errAccessDenied, "user must sign in with the identity provider of the email domain"
+	}
+
+	return http.StatusInternalServerError, errServerError, "failed to authorize"
+}
+
+// redirect redirects to the URI with params added to its query, empty params are skipped
+func redirect(w http.ResponseWriter, r *http.Request, redirectURI string, params url.Values) {
+	u, err := url.Parse(redirectURI)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, errServerError, "invalid redirect_uri")
+		return
+	}
+
+	query := u.Query()
+	for name, values := range params {
+		if len(values) > 0 && values[0] != "" {
+			query.Set(name, values[0])
+		}
+	}
+	u.RawQuery = query.Encode()
+
+	http.Redirect(w, r, u.String(), http.StatusFound)
+}
+
+func writeError(w http.ResponseWriter, status int, code string, description string) {
+	writeJSON(w, status, errorResponse{Error: code, ErrorDescription: description})
+}
+
+// writeJSON writes the response which must not be cached as RFC 6749 requires for tokens
+func writeJSON(w http.ResponseWriter, status int, body any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "no-store")
+	w.Header().Set("Pragma", "no-cache")
+	w.WriteHeader(status)
+
+	_ = json.NewEncoder(w).Encode(body)
+}
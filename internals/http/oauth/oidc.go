@@ -0,0 +1,108 @@
+package oauth
+
+import (
+	"errors"
+	"net/http"
+	"strings"
+
+	"github.com/nhassl3/sso-app/internals/domain/models"
+	"github.com/nhassl3/sso-app/internals/domain/services/auth"
+	njwt "github.com/nhassl3/sso-app/internals/lib/jwt"
+)
+
+const (
+	discoveryPath = "/.well-known/openid-configuration"
+	jwksPath      = "/.well-known/jwks.json"
+)
+
+// Error codes of RFC 6750
+const (
+	errInvalidToken      = "invalid_token"
+	errInsufficientScope = "insufficient_scope"
+)
+
+// providerMetadata is a discovery document of OpenID Connect provider
+type providerMetadata struct {
+	Issuer                            string   `json:"issuer"`
+	AuthorizationEndpoint             string   `json:"authorization_endpoint"`
+	TokenEndpoint                     string   `json:"token_endpoint"`
+	DeviceAuthorizationEndpoint       string   `json:"device_authorization_endpoint"`
+	UserInfoEndpoint                  string   `json:"userinfo_endpoint"`
+	JWKSURI                           string   `json:"jwks_uri"`
+	ScopesSupported                   []string `json:"scopes_supported"`
+	ResponseTypesSupported            []string `json:"response_types_supported"`
+	GrantTypesSupported               []string `json:"grant_types_supported"`
+	SubjectTypesSupported             []string `json:"subject_types_supported"`
+	IDTokenSigningAlgValuesSupported  []string `json:"id_token_signing_alg_values_supported"`
+	TokenEndpointAuthMethodsSupported []string `json:"token_endpoint_auth_methods_supported"`
+	CodeChallengeMethodsSupported     []string `json:"code_challenge_methods_supported"`
+	ClaimsSupported                   []string `json:"claims_supported"`
+	BackchannelLogoutSupported        bool     `json:"backchannel_logout_supported"`
+	BackchannelLogoutSessionSupported bool     `json:"backchannel_logout_session_supported"`
+}
+
+// Discovery handler. Returns metadata of the OpenID Connect provider
+func (h *Handler) Discovery(w http.ResponseWriter, _ *http.Request) {
+	issuer := strings.TrimSuffix(h.auth.Issuer(), "/")
+
+	writeJSON(w, http.StatusOK, providerMetadata{
+		Issuer:                      issuer,
+		AuthorizationEndpoint:       issuer + "/authorize",
+		TokenEndpoint:               issuer + "/token",
+		DeviceAuthorizationEndpoint: issuer + "/device_authorization",
+		UserInfoEndpoint:            issuer + "/userinfo",
+		JWKSURI:                     issuer + jwksPath,
+		ScopesSupported:             []string{models.ScopeOpenID, models.ScopeProfile, models.ScopeEmail},
+		ResponseTypesSupported:      []string{models.ResponseTypeCode},
+		GrantTypesSupported: []string{
+			models.GrantTypeAuthCode, models.GrantTypeClient, models.GrantTypeDevice,
+			models.GrantTypeExchange, models.GrantTypeRefresh,
+		},
+		SubjectTypesSupported:             []string{"public"},
+		IDTokenSigningAlgValuesSupported:  []string{"RS256"},
+		TokenEndpointAuthMethodsSupported: []string{"none", "client_secret_basic", "client_secret_post"},
+		CodeChallengeMethodsSupported:     []string{models.PKCEMethodS256},
+		ClaimsSupported: []string{
+			"sub", "iss", "aud", "exp", "iat", "auth_time", "nonce", "email", "email_verified", "preferred_username",
+		},
+		BackchannelLogoutSupported: true,
+	})
+}
+
+// JWKS handler. Returns public keys which verify ID tokens
+func (h *Handler) JWKS(w http.ResponseWriter, _ *http.Request) {
+	writeJSON(w, http.StatusOK, struct {
+		Keys []njwt.JWK `json:"keys"`
+	}{h.auth.PublicKeys()})
+}
+
+// UserInfo handler. Returns claims about the user of the bearer access token
+func (h *Handler) UserInfo(w http.ResponseWriter, r *http.Request) {
+	header := r.Header.Get("Authorization")
+
+	const prefix = "bearer "
+	if len(header) <= len(prefix) || !strings.EqualFold(header[:len(prefix)], prefix) {
+		w.Header().Set("WWW-Authenticate", `Bearer realm="sso"`)
+		writeError(w, http.StatusUnauthorized, errInvalidRequest, "bearer access token is required")
+
+		return
+	}
+
+	claims, err := h.auth.UserInfo(r.Context(), header[len(prefix):])
+	if err != nil {
+		switch {
+		case errors.Is(err, auth.ErrInvalidToken):
+			w.Header().Set("WWW-Authenticate", `Bearer error="invalid_token"`)
+			writeError(w, http.StatusUnauthorized, errInvalidToken, "access token is invalid or expired")
+		case errors.Is(err, auth.ErrInsufficientScope):
+			w.Header().Set("WWW-Authenticate", `Bearer error="insufficient_scope", scope="openid"`)
+			writeError(w, http.StatusForbidden, errInsufficientScope, "openid scope is required")
+		default:
+			writeError(w, http.StatusInternalServerError, errServerError, "failed to get user info")
+		}
+
+		return
+	}
+
+	writeJSON(w, http.StatusOK, claims)
+}
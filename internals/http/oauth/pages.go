@@ -0,0 +1,423 @@
+package oauth
+
+import (
+	"embed"
+	"errors"
+	"fmt"
+	"html/template"
+	"io/fs"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/nhassl3/sso-app/internals/domain/models"
+	"github.com/nhassl3/sso-app/internals/domain/services/auth"
+	ssov1 "github.com/nhassl3/sso-contracts/generated/go/sso"
+)
+
+const (
+	loginPath    = "/login"
+	registerPath = "/register"
+	consentPath  = "/consent"
+	staticPath   = "/static/"
+
+	defaultTitle = "Single sign-on"
+
+	// Styles of the theme are inline, other content is loaded from the server only
+	contentSecurityPolicy = "default-src 'none'; style-src 'self' 'unsafe-inline'; img-src 'self' http: https:; " +
+		"frame-ancestors 'none'; base-uri 'none'"
+)
+
+//go:embed templates static
+var pagesFS embed.FS
+
+// authorizeParams are parameters of the authorization request, the pages pass them from form to form
+var authorizeParams = []string{
+	"client_id", "redirect_uri", "response_type", "scope", "state", "code_challenge", "code_challenge_method", "nonce",
+}
+
+// Pages serves hosted HTML pages of login, registration and consent of the OAuth authorization requests.
+// Pages of the second factor and of the password reset aren't served: users have no second factor to enter
+// and the service has no channel to deliver reset links by, both need their own support in the auth service first
+type Pages struct {
+	auth      Auth
+	templates map[string]*template.Template
+	cookies   *cookies
+}
+
+type hiddenField struct {
+	Name  string
+	Value string
+}
+
+type pageData struct {
+	Title       string
+	Text        string
+	Theme       models.AppTheme
+	AppName     string
+	CSRFToken   string
+	Hidden      []hiddenField // parameters of the authorization request
+	Email       string
+	Scopes      []string
+	Error       string
+	Notice      string
+	LoginURL    template.URL
+	RegisterURL template.URL
+	Providers   []providerLink // upstream providers the user may sign in at instead of the password
+}
+
+// RegisterPages adds the hosted pages, the federated login and assets of the pages to the mux. Cookies of the pages are sealed by the key
+// of AES-128, AES-192 or AES-256 and are sent only by HTTPS if secureCookies is set.
+// Login waiting for consent of the user lasts loginTTL
+func RegisterPages(mux *http.ServeMux, auth Auth, cookieKey []byte, secureCookies bool, loginTTL time.Duration) error {
+	c, err := newCookies(cookieKey, secureCookies, loginTTL)
+	if err != nil {
+		return fmt.Errorf("cookie key: %w", err)
+	}
+
+	p := &Pages{auth: auth, templates: map[string]*template.Template{}, cookies: c}
+
+	for _, name := range []string{"login", "register", "consent", "error"} {
+		tmpl, err := template.ParseFS(pagesFS, "templates/layout.html", "templates/"+name+".html")
+		if err != nil {
+			return fmt.Errorf("template %s: %w", name, err)
+		}
+
+		p.templates[name] = tmpl
+	}
+
+	static, err := fs.Sub(pagesFS, "static")
+	if err != nil {
+		return err
+	}
+
+	mux.HandleFunc("GET "+loginPath, p.LoginPage)
+	mux.HandleFunc("POST "+loginPath, p.Login)
+	mux.HandleFunc("GET "+registerPath, p.RegisterPage)
+	mux.HandleFunc("POST "+registerPath, p.Register)
+	mux.HandleFunc("POST "+consentPath, p.Consent)
+	mux.HandleFunc("GET "+federationPath+"{provider}/login", p.FederatedLogin)
+	mux.HandleFunc("GET "+federationPath+"{provider}/callback", p.FederatedCallback)
+	mux.Handle("GET "+staticPath, http.StripPrefix(staticPath, http.FileServerFS(static)))
+
+	return nil
+}
+
+// LoginPage handler. Shows login form of the authorization request in the theme of the client
+func (p *Pages) LoginPage(w http.ResponseWriter, r *http.Request) {
+	data, ok := p.clientPage(w, r)
+	if !ok {
+		return
+	}
+
+	if r.Form.Get("registered") != "" {
+		data.Notice = "Your account is created, sign in to continue"
+	}
+
+	p.render(w, http.StatusOK, "login", data)
+}
+
+// Login handler. Authorizes the request by credentials of the form as Authorize endpoint does.
+// If the third-party client needs consent of the user, consent page is shown instead of the redirect
+func (p *Pages) Login(w http.ResponseWriter, r *http.Request) {
+	if !p.checkForm(w, r) {
+		return
+	}
+
+	data, ok := p.clientPage(w, r)
+	if !ok {
+		return
+	}
+
+	email, password := r.PostForm.Get("email"), r.PostForm.Get("password")
+	data.Email = email
+
+	req, _ := authorizeRequest(r.PostForm, "")
+
+	realm, err := p.auth.DiscoverRealm(r.Context(), email, req.AppID)
+	if err != nil {
+		p.renderError(w, http.StatusInternalServerError, "Failed to sign in, try again later")
+		return
+	}
+
+	// Users of the realm sign in with its provider, the password isn't even checked
+	if realm.Provider != "" {
+		p.realmLogin(w, r, data, realm.Provider)
+		return
+	}
+
+	code, userID, redirectURI, err := p.auth.Authorize(r.Context(), req, email, password)
+	if redirectURI != "" && errors.Is(err, auth.ErrConsentRequired) {
+		if err := p.cookies.setLogin(w, pendingLogin{UserID: userID, Request: req}); err != nil {
+			p.renderError(w, http.StatusInternalServerError, "Failed to sign in, try again later")
+			return
+		}
+
+		data.Scopes = strings.Fields(req.Scope)
+		p.render(w, http.StatusOK, "consent", data)
+
+		return
+	}
+
+	p.authorized(w, r, data, code, redirectURI, err)
+}
+
+// Consent handler. Authorizes the request sealed by the login page for its user with the decision of the user,
+// the form must be sent for the same request
+func (p *Pages) Consent(w http.ResponseWriter, r *http.Request) {
+	if !p.checkForm(w, r) {
+		return
+	}
+
+	data, ok := p.clientPage(w, r)
+	if !ok {
+		return
+	}
+
+	decision := r.PostForm.Get("consent")
+	if decision != models.ConsentApprove && decision != models.ConsentDeny {
+		p.renderError(w, http.StatusBadRequest, "Allow or deny the access")
+		return
+	}
+
+	req, _ := authorizeRequest(r.PostForm, "")
+
+	login, err := p.cookies.login(r)
+	if err != nil || login.Request != req {
+		data.Error = "Your sign in has expired, sign in again"
+		p.render(w, http.StatusUnauthorized, "login", data)
+
+		return
+	}
+
+	p.cookies.clearLogin(w)
+
+	req.Consent = decision
+
+	code, redirectURI, err := p.auth.AuthorizeUser(r.Context(), req, login.UserID)
+	p.authorized(w, r, data, code, redirectURI, err)
+}
+
+// RegisterPage handler. Shows registration form, in the theme of the client if the page is opened by its login page
+func (p *Pages) RegisterPage(w http.ResponseWriter, r *http.Request) {
+	data, ok := p.registerPage(w, r)
+	if !ok {
+		return
+	}
+
+	p.render(w, http.StatusOK, "register", data)
+}
+
+// Register handler. Registers the user as Register RPC does and returns to the login page of the client
+func (p *Pages) Register(w http.ResponseWriter, r *http.Request) {
+	if !p.checkForm(w, r) {
+		return
+	}
+
+	data, ok := p.registerPage(w, r)
+	if !ok {
+		return
+	}
+
+	email, password := r.PostForm.Get("email"), r.PostForm.Get("password")
+	data.Email = email
+
+	if err := (&ssov1.RegisterRequest{Email: email, Password: password}).Validate(); err != nil {
+		data.Error = "Enter valid email and password of 6 to 100 characters"
+		p.render(w, http.StatusBadRequest, "register", data)
+
+		return
+	}
+
+	if password != r.PostForm.Get("password_confirm") {
+		data.Error = "Passwords don't match"
+		p.render(w, http.StatusBadRequest, "register", data)
+
+		return
+	}
+
+	if _, err := p.auth.RegisterNewUser(r.Context(), email, password); err != nil {
+		if errors.Is(err, auth.ErrUserExists) {
+			data.Error = "User with this email already exists"
+			p.render(w, http.StatusConflict, "register", data)
+
+			return
+		}
+
+		p.renderError(w, http.StatusInternalServerError, "Failed to create the account, try again later")
+
+		return
+	}
+
+	if data.LoginURL == "" {
+		data.Notice = "Your account is created"
+		p.render(w, http.StatusOK, "register", data)
+
+		return
+	}
+
+	http.Redirect(w, r, string(data.LoginURL)+"&registered=1", http.StatusSeeOther)
+}
+
+// clientPage returns data of the page of the authorization request themed by the client.
+// Unknown or disabled client is an error shown to the user
+func (p *Pages) clientPage(w http.ResponseWriter, r *http.Request) (pageData, bool) {
+	if err := r.ParseForm(); err != nil {
+		p.renderError(w, http.StatusBadRequest, "Malformed request")
+		return pageData{}, false
+	}
+
+	req, ok := authorizeRequest(r.Form, "")
+	if !ok {
+		p.renderError(w, http.StatusBadRequest, "Sign in must be started by the application")
+		return pageData{}, false
+	}
+
+	app, err := p.auth.ClientApp(r.Context(), req.AppID)
+	if err != nil {
+		status, _, _ := authorizeError(err)
+		p.renderError(w, status, "Application is unknown or disabled")
+
+		return pageData{}, false
+	}
+
+	data, ok := p.page(w, r, app)
+	if !ok {
+		return pageData{}, false
+	}
+
+	query := url.Values{}
+	for _, name := range authorizeParams {
+		if value := r.Form.Get(name); value != "" {
+			query.Set(name, value)
+			data.Hidden = append(data.Hidden, hiddenField{Name: name, Value: value})
+		}
+	}
+
+	data.LoginURL = template.URL(loginPath + "?" + query.Encode())
+	data.RegisterURL = template.URL(registerPath + "?" + query.Encode())
+	data.Providers = p.providerLinks(query)
+
+	return data, true
+}
+
+// registerPage returns data of the registration page, which is opened by the login page or by itself
+func (p *Pages) registerPage(w http.ResponseWriter, r *http.Request) (pageData, bool) {
+	if err := r.ParseForm(); err != nil {
+		p.renderError(w, http.StatusBadRequest, "Malformed request")
+		return pageData{}, false
+	}
+
+	if r.Form.Get("client_id") != "" {
+		return p.clientPage(w, r)
+	}
+
+	return p.page(w, r, models.App{})
+}
+
+// page returns data of the page in the theme of the application with CSRF token of the browser
+func (p *Pages) page(w http.ResponseWriter, r *http.Request, app models.App) (pageData, bool) {
+	token, err := p.cookies.csrfToken(w, r)
+	if err != nil {
+		p.renderError(w, http.StatusInternalServerError, "Failed to open the page, try again later")
+		return pageData{}, false
+	}
+
+	data := pageData{
+		Title:     app.Theme.Title,
+		Text:      app.Theme.Text,
+		Theme:     app.Theme,
+		AppName:   app.Name,
+		CSRFToken: token,
+	}
+
+	if data.Title == "" {
+		data.Title = app.Name
+	}
+	if data.Title == "" {
+		data.Title = defaultTitle
+	}
+
+	return data, true
+}
+
+// checkForm parses the form and checks its CSRF token
+func (p *Pages) checkForm(w http.ResponseWriter, r *http.Request) bool {
+	if err := r.ParseForm(); err != nil {
+		p.renderError(w, http.StatusBadRequest, "Malformed request")
+		return false
+	}
+
+	if !p.cookies.checkCSRF(r) {
+		p.renderError(w, http.StatusForbidden, "The form has expired, open the page again")
+		return false
+	}
+
+	return true
+}
+
+// authorized finishes the authorization request. Code or error is sent to the client by the redirect,
+// errors which can't be sent are shown to the user and invalid credentials are asked again
+func (p *Pages) authorized(w http.ResponseWriter, r *http.Request, data pageData, code, redirectURI string, err error) {
+	switch {
+	case err == nil:
+		redirect(w, r, redirectURI, url.Values{"code": {code}, "state": {r.PostForm.Get("state")}})
+	case redirectURI == "":
+		status, _, description := authorizeError(err)
+
+		data.Error = sentence(description)
+		p.render(w, status, "error", data)
+	case errors.Is(err, auth.ErrInvalidCredentials):
+		data.Error = "Email or password is invalid"
+		p.render(w, http.StatusUnauthorized, "login", data)
+	default:
+		_, code, description := authorizeError(err)
+		redirect(w, r, redirectURI, url.Values{
+			"error":             {code},
+			"error_description": {description},
+			"state":             {r.PostForm.Get("state")},
+		})
+	}
+}
+
+func (p *Pages) renderError(w http.ResponseWriter, status int, message string) {
+	p.render(w, status, "error", pageData{Title: defaultTitle, Error: message})
+}
+
+// render writes the page, which must not be cached or framed
+func (p *Pages) render(w http.ResponseWriter, status int, name string, data pageData) {
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.Header().Set("Cache-Control", "no-store")
+	w.Header().Set("Content-Security-Policy", contentSecurityPolicy)
+	w.Header().Set("X-Frame-Options", "DENY")
+	w.Header().Set("Referrer-Policy", "no-referrer")
+	w.WriteHeader(status)
+
+	_ = p.templates[name].ExecuteTemplate(w, "layout", data)
+}
+
+// sentence returns the description starting with the capital letter, so it is shown on the page
+func sentence(description string) string {
+	return strings.ToUpper(description[:1]) + description[1:]
+}
+
+// authorizeRequest returns authorization request of the form, client_id must be valid
+func authorizeRequest(form url.Values, consent string) (models.AuthorizeRequest, bool) {
+	appID, err := strconv.ParseInt(form.Get("client_id"), 10, 32)
+	if err != nil || appID <= 0 {
+		return models.AuthorizeRequest{}, false
+	}
+
+	return models.AuthorizeRequest{
+		AppID:               int32(appID),
+		RedirectURI:         form.Get("redirect_uri"),
+		ResponseType:        form.Get("response_type"),
+		Scope:               form.Get("scope"),
+		CodeChallenge:       form.Get("code_challenge"),
+		CodeChallengeMethod: form.Get("code_challenge_method"),
+		Nonce:               form.Get("nonce"),
+		Consent:             consent,
+	}, true
+}
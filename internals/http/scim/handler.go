@@ -0,0 +1,622 @@
+package scim
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/nhassl3/sso-app/internals/domain/models"
+	"github.com/nhassl3/sso-app/internals/domain/services/scim"
+)
+
+const (
+	basePath    = "/scim/v2"
+	contentType = "application/scim+json"
+	maxBodySize = 1 << 20
+)
+
+// Types of the errors of RFC 7644
+const (
+	errInvalidFilter = "invalidFilter"
+	errInvalidPath   = "invalidPath"
+	errInvalidValue  = "invalidValue"
+	errInvalidSyntax = "invalidSyntax"
+	errUniqueness    = "uniqueness"
+	errMutability    = "mutability"
+)
+
+type Scim interface {
+	CreateUser(ctx context.Context, caller models.TokenInfo, user models.ScimUser) (models.ScimUser, error)
+	User(ctx context.Context, caller models.TokenInfo, userID int64) (models.ScimUser, error)
+	Users(
+		ctx context.Context,
+		caller models.TokenInfo,
+		filter string,
+		startIndex int,
+		count int,
+	) (users []models.ScimUser, total int, err error)
+	ReplaceUser(ctx context.Context, caller models.TokenInfo, user models.ScimUser, version int64) (models.ScimUser, error)
+	PatchUser(
+		ctx context.Context,
+		caller models.TokenInfo,
+		userID int64,
+		ops []models.ScimPatchOp,
+		version int64,
+	) (models.ScimUser, error)
+	DeleteUser(ctx context.Context, caller models.TokenInfo, userID int64, version int64) error
+	CreateGroup(ctx context.Context, caller models.TokenInfo, group models.ScimGroup) (models.ScimGroup, error)
+	Group(ctx context.Context, caller models.TokenInfo, groupID int64) (models.ScimGroup, error)
+	Groups(
+		ctx context.Context,
+		caller models.TokenInfo,
+		filter string,
+		startIndex int,
+		count int,
+	) (groups []models.ScimGroup, total int, err error)
+	ReplaceGroup(ctx context.Context, caller models.TokenInfo, group models.ScimGroup, version int64) (models.ScimGroup, error)
+	PatchGroup(
+		ctx context.Context,
+		caller models.TokenInfo,
+		groupID int64,
+		ops []models.ScimPatchOp,
+		version int64,
+	) (models.ScimGroup, error)
+	DeleteGroup(ctx context.Context, caller models.TokenInfo, groupID int64, version int64) error
+}
+
+// Auth authenticates bearer tokens of the provisioning clients
+type Auth interface {
+	Introspect(ctx context.Context, token string) (info models.TokenInfo, err error)
+	Issuer() string
+}
+
+type Handler struct {
+	scim Scim
+	auth Auth
+}
+
+// Register registers SCIM 2.0 endpoints of the users and the groups. Requests are authenticated by bearer token
+// of the client credentials grant with scim scope, groups belong to the application of the client
+func Register(mux *http.ServeMux, scim Scim, auth Auth) {
+	h := &Handler{scim: scim, auth: auth}
+
+	mux.HandleFunc("GET "+basePath+"/ServiceProviderConfig", h.ServiceProviderConfig)
+
+	mux.HandleFunc("GET "+basePath+"/Users", h.Users)
+	mux.HandleFunc("POST "+basePath+"/Users", h.CreateUser)
+	mux.HandleFunc("GET "+basePath+"/Users/{id}", h.User)
+	mux.HandleFunc("PUT "+basePath+"/Users/{id}", h.ReplaceUser)
+	mux.HandleFunc("PATCH "+basePath+"/Users/{id}", h.PatchUser)
+	mux.HandleFunc("DELETE "+basePath+"/Users/{id}", h.DeleteUser)
+
+	mux.HandleFunc("GET "+basePath+"/Groups", h.Groups)
+	mux.HandleFunc("POST "+basePath+"/Groups", h.CreateGroup)
+	mux.HandleFunc("GET "+basePath+"/Groups/{id}", h.Group)
+	mux.HandleFunc("PUT "+basePath+"/Groups/{id}", h.ReplaceGroup)
+	mux.HandleFunc("PATCH "+basePath+"/Groups/{id}", h.PatchGroup)
+	mux.HandleFunc("DELETE "+basePath+"/Groups/{id}", h.DeleteGroup)
+}
+
+// ServiceProviderConfig handler. Describes the supported features, it doesn't require authentication
+func (h *Handler) ServiceProviderConfig(w http.ResponseWriter, _ *http.Request) {
+	writeJSON(w, http.StatusOK, serviceProviderConfig())
+}
+
+// Users handler. Returns page of the users matching the filter
+func (h *Handler) Users(w http.ResponseWriter, r *http.Request) {
+	caller, ok := h.caller(w, r)
+	if !ok {
+		return
+	}
+
+	startIndex, count, ok := pageParams(w, r)
+	if !ok {
+		return
+	}
+
+	users, total, err := h.scim.Users(r.Context(), caller, r.URL.Query().Get("filter"), startIndex, count)
+	if err != nil {
+		writeScimError(w, err)
+		return
+	}
+
+	resources := make([]any, 0, len(users))
+	for _, user := range users {
+		resources = append(resources, h.userResource(user))
+	}
+
+	writeJSON(w, http.StatusOK, listResponse(resources, total, startIndex))
+}
+
+// CreateUser handler. Provisions the user and returns it with its location
+func (h *Handler) CreateUser(w http.ResponseWriter, r *http.Request) {
+	caller, ok := h.caller(w, r)
+	if !ok {
+		return
+	}
+
+	var resource userResource
+	if !decode(w, r, &resource) {
+		return
+	}
+
+	user, err := h.scim.CreateUser(r.Context(), caller, resource.model())
+	if err != nil {
+		writeScimError(w, err)
+		return
+	}
+
+	created := h.userResource(user)
+
+	w.Header().Set("Location", created.Meta.Location)
+	writeResource(w, http.StatusCreated, created, user.Version)
+}
+
+// User handler. Returns the user, If-None-Match with its current version returns Not Modified
+func (h *Handler) User(w http.ResponseWriter, r *http.Request) {
+	caller, ok := h.caller(w, r)
+	if !ok {
+		return
+	}
+
+	userID, ok := resourceID(w, r)
+	if !ok {
+		return
+	}
+
+	user, err := h.scim.User(r.Context(), caller, userID)
+	if err != nil {
+		writeScimError(w, err)
+		return
+	}
+
+	if notModified(w, r, user.Version) {
+		return
+	}
+
+	writeResource(w, http.StatusOK, h.userResource(user), user.Version)
+}
+
+// ReplaceUser handler. Replaces attributes of the user, If-Match must have its current version if it is sent
+func (h *Handler) ReplaceUser(w http.ResponseWriter, r *http.Request) {
+	caller, ok := h.caller(w, r)
+	if !ok {
+		return
+	}
+
+	userID, ok := resourceID(w, r)
+	if !ok {
+		return
+	}
+
+	version, ok := ifMatch(w, r)
+	if !ok {
+		return
+	}
+
+	var resource userResource
+	if !decode(w, r, &resource) {
+		return
+	}
+
+	user := resource.model()
+	user.ID = userID
+
+	user, err := h.scim.ReplaceUser(r.Context(), caller, user, version)
+	if err != nil {
+		writeScimError(w, err)
+		return
+	}
+
+	writeResource(w, http.StatusOK, h.userResource(user), user.Version)
+}
+
+// PatchUser handler. Applies PATCH operations to the user, If-Match must have its current version if it is sent
+func (h *Handler) PatchUser(w http.ResponseWriter, r *http.Request) {
+	caller, ok := h.caller(w, r)
+	if !ok {
+		return
+	}
+
+	userID, ok := resourceID(w, r)
+	if !ok {
+		return
+	}
+
+	version, ok := ifMatch(w, r)
+	if !ok {
+		return
+	}
+
+	var patch patchRequest
+	if !decode(w, r, &patch) {
+		return
+	}
+
+	user, err := h.scim.PatchUser(r.Context(), caller, userID, patch.ops(), version)
+	if err != nil {
+		writeScimError(w, err)
+		return
+	}
+
+	writeResource(w, http.StatusOK, h.userResource(user), user.Version)
+}
+
+// DeleteUser handler. Deprovisions the user, If-Match must have its current version if it is sent
+func (h *Handler) DeleteUser(w http.ResponseWriter, r *http.Request) {
+	caller, ok := h.caller(w, r)
+	if !ok {
+		return
+	}
+
+	userID, ok := resourceID(w, r)
+	if !ok {
+		return
+	}
+
+	version, ok := ifMatch(w, r)
+	if !ok {
+		return
+	}
+
+	if err := h.scim.DeleteUser(r.Context(), caller, userID, version); err != nil {
+		writeScimError(w, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// Groups handler. Returns page of the groups of the application of the client matching the filter
+func (h *Handler) Groups(w http.ResponseWriter, r *http.Request) {
+	caller, ok := h.caller(w, r)
+	if !ok {
+		return
+	}
+
+	startIndex, count, ok := pageParams(w, r)
+	if !ok {
+		return
+	}
+
+	groups, total, err := h.scim.Groups(r.Context(), caller, r.URL.Query().Get("filter"), startIndex, count)
+	if err != nil {
+		writeScimError(w, err)
+		return
+	}
+
+	resources := make([]any, 0, len(groups))
+	for _, group := range groups {
+		resources = append(resources, h.groupResource(group))
+	}
+
+	writeJSON(w, http.StatusOK, listResponse(resources, total, startIndex))
+}
+
+// CreateGroup handler. Creates the group in the application of the client and returns it with its location
+func (h *Handler) CreateGroup(w http.ResponseWriter, r *http.Request) {
+	caller, ok := h.caller(w, r)
+	if !ok {
+		return
+	}
+
+	var resource groupResource
+	if !decode(w, r, &resource) {
+		return
+	}
+
+	group, ok := resource.model(w)
+	if !ok {
+		return
+	}
+
+	group, err := h.scim.CreateGroup(r.Context(), caller, group)
+	if err != nil {
+		writeScimError(w, err)
+		return
+	}
+
+	created := h.groupResource(group)
+
+	w.Header().Set("Location", created.Meta.Location)
+	writeResource(w, http.StatusCreated, created, group.Version)
+}
+
+// Group handler. Returns the group, If-None-Match with its current version returns Not Modified
+func (h *Handler) Group(w http.ResponseWriter, r *http.Request) {
+	caller, ok := h.caller(w, r)
+	if !ok {
+		return
+	}
+
+	groupID, ok := resourceID(w, r)
+	if !ok {
+		return
+	}
+
+	group, err := h.scim.Group(r.Context(), caller, groupID)
+	if err != nil {
+		writeScimError(w, err)
+		return
+	}
+
+	if notModified(w, r, group.Version) {
+		return
+	}
+
+	writeResource(w, http.StatusOK, h.groupResource(group), group.Version)
+}
+
+// ReplaceGroup handler. Replaces attributes and members of the group, If-Match must have its current version if it is sent
+func (h *Handler) ReplaceGroup(w http.ResponseWriter, r *http.Request) {
+	caller, ok := h.caller(w, r)
+	if !ok {
+		return
+	}
+
+	groupID, ok := resourceID(w, r)
+	if !ok {
+		return
+	}
+
+	version, ok := ifMatch(w, r)
+	if !ok {
+		return
+	}
+
+	var resource groupResource
+	if !decode(w, r, &resource) {
+		return
+	}
+
+	group, ok := resource.model(w)
+	if !ok {
+		return
+	}
+	group.ID = groupID
+
+	group, err := h.scim.ReplaceGroup(r.Context(), caller, group, version)
+	if err != nil {
+		writeScimError(w, err)
+		return
+	}
+
+	writeResource(w, http.StatusOK, h.groupResource(group), group.Version)
+}
+
+// PatchGroup handler. Applies PATCH operations to the group, If-Match must have its current version if it is sent
+func (h *Handler) PatchGroup(w http.ResponseWriter, r *http.Request) {
+	caller, ok := h.caller(w, r)
+	if !ok {
+		return
+	}
+
+	groupID, ok := resourceID(w, r)
+	if !ok {
+		return
+	}
+
+	version, ok := ifMatch(w, r)
+	if !ok {
+		return
+	}
+
+	var patch patchRequest
+	if !decode(w, r, &patch) {
+		return
+	}
+
+	group, err := h.scim.PatchGroup(r.Context(), caller, groupID, patch.ops(), version)
+	if err != nil {
+		writeScimError(w, err)
+		return
+	}
+
+	writeResource(w, http.StatusOK, h.groupResource(group), group.Version)
+}
+
+// DeleteGroup handler. Deletes the group, If-Match must have its current version if it is sent
+func (h *Handler) DeleteGroup(w http.ResponseWriter, r *http.Request) {
+	caller, ok := h.caller(w, r)
+	if !ok {
+		return
+	}
+
+	groupID, ok := resourceID(w, r)
+	if !ok {
+		return
+	}
+
+	version, ok := ifMatch(w, r)
+	if !ok {
+		return
+	}
+
+	if err := h.scim.DeleteGroup(r.Context(), caller, groupID, version); err != nil {
+		writeScimError(w, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// caller returns the client of the bearer token, writes error if the token is missing or inactive
+func (h *Handler) caller(w http.ResponseWriter, r *http.Request) (models.TokenInfo, bool) {
+	header := r.Header.Get("Authorization")
+
+	const prefix = "bearer "
+	if len(header) <= len(prefix) || !strings.EqualFold(header[:len(prefix)], prefix) {
+		w.Header().Set("WWW-Authenticate", `Bearer realm="scim"`)
+		writeError(w, http.StatusUnauthorized, "", "bearer token of the provisioning client is required")
+
+		return models.TokenInfo{}, false
+	}
+
+	info, err := h.auth.Introspect(r.Context(), header[len(prefix):])
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "", "failed to authenticate the client")
+
+		return models.TokenInfo{}, false
+	}
+
+	if !info.Active {
+		w.Header().Set("WWW-Authenticate", `Bearer error="invalid_token"`)
+		writeError(w, http.StatusUnauthorized, "", "token is invalid or expired")
+
+		return models.TokenInfo{}, false
+	}
+
+	return info, true
+}
+
+// resourceID returns ID of the resource of the path, unknown ID is written as not found
+func resourceID(w http.ResponseWriter, r *http.Request) (int64, bool) {
+	id, err := strconv.ParseInt(r.PathValue("id"), 10, 64)
+	if err != nil || id <= 0 {
+		writeError(w, http.StatusNotFound, "", "resource not found")
+
+		return 0, false
+	}
+
+	return id, true
+}
+
+// pageParams returns 1-based start index and count of the page, count defaults to scim.DefaultCount
+func pageParams(w http.ResponseWriter, r *http.Request) (startIndex int, count int, ok bool) {
+	startIndex, count = 1, scim.DefaultCount
+
+	for name, target := range map[string]*int{"startIndex": &startIndex, "count": &count} {
+		value := r.URL.Query().Get(name)
+		if value == "" {
+			continue
+		}
+
+		parsed, err := strconv.Atoi(value)
+		if err != nil {
+			writeError(w, http.StatusBadRequest, errInvalidValue, name+" must be integer")
+
+			return 0, 0, false
+		}
+
+		*target = parsed
+	}
+
+	return max(startIndex, 1), count, true
+}
+
+// decode decodes JSON body of the request, writes error if it is malformed
+func decode(w http.ResponseWriter, r *http.Request, dest any) bool {
+	if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, maxBodySize)).Decode(dest); err != nil {
+		writeError(w, http.StatusBadRequest, errInvalidSyntax, "request body must be JSON resource")
+
+		return false
+	}
+
+	return true
+}
+
+// ifMatch returns version of If-Match header, zero if it is missing or any version matches.
+// Writes Precondition Failed if the header can't match any version
+func ifMatch(w http.ResponseWriter, r *http.Request) (int64, bool) {
+	header := strings.TrimSpace(r.Header.Get("If-Match"))
+	if header == "" || header == "*" {
+		return 0, true
+	}
+
+	version, ok := parseETag(header)
+	if !ok {
+		writeError(w, http.StatusPreconditionFailed, "", "resource version doesn't match If-Match")
+
+		return 0, false
+	}
+
+	return version, true
+}
+
+// notModified writes Not Modified if If-None-Match has the version
+func notModified(w http.ResponseWriter, r *http.Request, version int64) bool {
+	for _, tag := range strings.Split(r.Header.Get("If-None-Match"), ",") {
+		tag = strings.TrimSpace(tag)
+
+		if parsed, ok := parseETag(tag); tag == "*" || ok && parsed == version {
+			w.Header().Set("ETag", etag(version))
+			w.WriteHeader(http.StatusNotModified)
+
+			return true
+		}
+	}
+
+	return false
+}
+
+// etag returns weak entity tag of the version, resources are compared by their attributes, not by bytes
+func etag(version int64) string {
+	return `W/"` + strconv.FormatInt(version, 10) + `"`
+}
+
+func parseETag(tag string) (int64, bool) {
+	tag = strings.TrimPrefix(tag, "W/")
+	if len(tag) < 2 || tag[0] != '"' || tag[len(tag)-1] != '"' {
+		return 0, false
+	}
+
+	version, err := strconv.ParseInt(tag[1:len(tag)-1], 10, 64)
+	if err != nil || version <= 0 {
+		return 0, false
+	}
+
+	return version, true
+}
+
+// writeScimError writes errors of the service as SCIM errors
+func writeScimError(w http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, scim.ErrPermissionDenied):
+		writeError(w, http.StatusForbidden, "", "token of the client with scim scope is required")
+	case errors.Is(err, scim.ErrUserNotFound):
+		writeError(w, http.StatusNotFound, "", "user not found")
+	case errors.Is(err, scim.ErrGroupNotFound):
+		writeError(w, http.StatusNotFound, "", "group not found")
+	case errors.Is(err, scim.ErrUserExists):
+		writeError(w, http.StatusConflict, errUniqueness, "user with the user name already exists")
+	case errors.Is(err, scim.ErrGroupExists):
+		writeError(w, http.StatusConflict, errUniqueness, "group with the display name already exists")
+	case errors.Is(err, scim.ErrInvalidFilter):
+		writeError(w, http.StatusBadRequest, errInvalidFilter, "filter is malformed or uses unsupported attribute")
+	case errors.Is(err, scim.ErrInvalidPath):
+		writeError(w, http.StatusBadRequest, errInvalidPath, "attribute path is malformed or unsupported")
+	case errors.Is(err, scim.ErrInvalidValue):
+		writeError(w, http.StatusBadRequest, errInvalidValue, "attribute value is invalid or refers to unknown user")
+	case errors.Is(err, scim.ErrVersionMismatch):
+		writeError(w, http.StatusPreconditionFailed, "", "resource version doesn't match If-Match")
+	case errors.Is(err, scim.ErrLastSuperAdmin):
+		writeError(w, http.StatusConflict, errMutability, "last super-admin can't be deleted")
+	default:
+		writeError(w, http.StatusInternalServerError, "", "failed to process the request")
+	}
+}
+
+func writeError(w http.ResponseWriter, status int, scimType string, detail string) {
+	writeJSON(w, status, errorResponse{
+		Schemas:  []string{errorSchema},
+		Status:   strconv.Itoa(status),
+		ScimType: scimType,
+		Detail:   detail,
+	})
+}
+
+// writeResource writes the resource with ETag of its version
+func writeResource(w http.ResponseWriter, status int, resource any, version int64) {
+	w.Header().Set("ETag", etag(version))
+	writeJSON(w, status, resource)
+}
+
+func writeJSON(w http.ResponseWriter, status int, body any) {
+	w.Header().Set("Content-Type", contentType)
+	w.WriteHeader(status)
+
+	_ = json.NewEncoder(w).Encode(body)
+}
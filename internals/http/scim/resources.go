@@ -0,0 +1,228 @@
+package scim
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/nhassl3/sso-app/internals/domain/models"
+	"github.com/nhassl3/sso-app/internals/domain/services/scim"
+	scimexpr "github.com/nhassl3/sso-app/internals/lib/scim"
+)
+
+// Schemas of the messages of RFC 7644
+const (
+	errorSchema                 = "urn:ietf:params:scim:api:messages:2.0:Error"
+	listSchema                  = "urn:ietf:params:scim:api:messages:2.0:ListResponse"
+	serviceProviderConfigSchema = "urn:ietf:params:scim:schemas:core:2.0:ServiceProviderConfig"
+)
+
+type userResource struct {
+	Schemas     []string        `json:"schemas"`
+	ID          string          `json:"id,omitempty"`
+	ExternalID  string          `json:"externalId,omitempty"`
+	UserName    string          `json:"userName"`
+	Name        *nameResource   `json:"name,omitempty"`
+	DisplayName string          `json:"displayName,omitempty"`
+	Emails      []emailResource `json:"emails,omitempty"`
+	Active      *bool           `json:"active,omitempty"`
+	Password    string          `json:"password,omitempty"`
+	Meta        *metaResource   `json:"meta,omitempty"`
+}
+
+type nameResource struct {
+	GivenName  string `json:"givenName,omitempty"`
+	FamilyName string `json:"familyName,omitempty"`
+}
+
+type emailResource struct {
+	Value   string `json:"value"`
+	Type    string `json:"type,omitempty"`
+	Primary bool   `json:"primary,omitempty"`
+}
+
+type groupResource struct {
+	Schemas     []string         `json:"schemas"`
+	ID          string           `json:"id,omitempty"`
+	ExternalID  string           `json:"externalId,omitempty"`
+	DisplayName string           `json:"displayName"`
+	Members     []memberResource `json:"members"`
+	Meta        *metaResource    `json:"meta,omitempty"`
+}
+
+type memberResource struct {
+	Value   string `json:"value"`
+	Display string `json:"display,omitempty"`
+	Ref     string `json:"$ref,omitempty"`
+}
+
+type metaResource struct {
+	ResourceType string    `json:"resourceType"`
+	Created      time.Time `json:"created"`
+	LastModified time.Time `json:"lastModified"`
+	Location     string    `json:"location"`
+	Version      string    `json:"version"`
+}
+
+type listResponseResource struct {
+	Schemas      []string `json:"schemas"`
+	TotalResults int      `json:"totalResults"`
+	StartIndex   int      `json:"startIndex"`
+	ItemsPerPage int      `json:"itemsPerPage"`
+	Resources    []any    `json:"Resources"`
+}
+
+type patchRequest struct {
+	Operations []struct {
+		Op    string `json:"op"`
+		Path  string `json:"path"`
+		Value any    `json:"value"`
+	} `json:"Operations"`
+}
+
+type errorResponse struct {
+	Schemas  []string `json:"schemas"`
+	Status   string   `json:"status"`
+	ScimType string   `json:"scimType,omitempty"`
+	Detail   string   `json:"detail"`
+}
+
+// model returns the user of the resource. Absent active attribute means active user,
+// user name defaults to the primary email
+func (u userResource) model() models.ScimUser {
+	user := models.ScimUser{
+		UserName:    u.UserName,
+		ExternalID:  u.ExternalID,
+		DisplayName: u.DisplayName,
+		Active:      u.Active == nil || *u.Active,
+		Password:    u.Password,
+	}
+
+	if u.Name != nil {
+		user.GivenName, user.FamilyName = u.Name.GivenName, u.Name.FamilyName
+	}
+
+	if user.UserName == "" {
+		for _, email := range u.Emails {
+			if email.Primary || user.UserName == "" {
+				user.UserName = email.Value
+			}
+		}
+	}
+
+	return user
+}
+
+// model returns the group of the resource, writes error if the member isn't ID of the user
+func (g groupResource) model(w http.ResponseWriter) (models.ScimGroup, bool) {
+	group := models.ScimGroup{
+		DisplayName: g.DisplayName,
+		ExternalID:  g.ExternalID,
+	}
+
+	for _, member := range g.Members {
+		userID, err := strconv.ParseInt(member.Value, 10, 64)
+		if err != nil {
+			writeError(w, http.StatusBadRequest, errInvalidValue, "member "+strconv.Quote(member.Value)+" is unknown")
+
+			return models.ScimGroup{}, false
+		}
+
+		group.Members = append(group.Members, models.ScimMember{UserID: userID})
+	}
+
+	return group, true
+}
+
+func (p patchRequest) ops() []models.ScimPatchOp {
+	ops := make([]models.ScimPatchOp, 0, len(p.Operations))
+	for _, op := range p.Operations {
+		ops = append(ops, models.ScimPatchOp{Op: op.Op, Path: op.Path, Value: op.Value})
+	}
+
+	return ops
+}
+
+func (h *Handler) userResource(user models.ScimUser) userResource {
+	id := strconv.FormatInt(user.ID, 10)
+	resource := userResource{
+		Schemas:     []string{scimexpr.UserSchema},
+		ID:          id,
+		ExternalID:  user.ExternalID,
+		UserName:    user.UserName,
+		DisplayName: user.DisplayName,
+		Emails:      []emailResource{{Value: user.UserName, Type: "work", Primary: true}},
+		Active:      &user.Active,
+		Meta:        h.meta("User", "/Users/"+id, user.Created, user.LastModified, user.Version),
+	}
+
+	if user.GivenName != "" || user.FamilyName != "" {
+		resource.Name = &nameResource{GivenName: user.GivenName, FamilyName: user.FamilyName}
+	}
+
+	return resource
+}
+
+func (h *Handler) groupResource(group models.ScimGroup) groupResource {
+	id := strconv.FormatInt(group.ID, 10)
+	resource := groupResource{
+		Schemas:     []string{scimexpr.GroupSchema},
+		ID:          id,
+		ExternalID:  group.ExternalID,
+		DisplayName: group.DisplayName,
+		Members:     make([]memberResource, 0, len(group.Members)),
+		Meta:        h.meta("Group", "/Groups/"+id, group.Created, group.LastModified, group.Version),
+	}
+
+	for _, member := range group.Members {
+		userID := strconv.FormatInt(member.UserID, 10)
+		resource.Members = append(resource.Members, memberResource{
+			Value:   userID,
+			Display: member.UserName,
+			Ref:     h.auth.Issuer() + basePath + "/Users/" + userID,
+		})
+	}
+
+	return resource
+}
+
+func (h *Handler) meta(resourceType, path string, created, lastModified time.Time, version int64) *metaResource {
+	return &metaResource{
+		ResourceType: resourceType,
+		Created:      created.UTC(),
+		LastModified: lastModified.UTC(),
+		Location:     h.auth.Issuer() + basePath + path,
+		Version:      etag(version),
+	}
+}
+
+func listResponse(resources []any, total int, startIndex int) listResponseResource {
+	return listResponseResource{
+		Schemas:      []string{listSchema},
+		TotalResults: total,
+		StartIndex:   startIndex,
+		ItemsPerPage: len(resources),
+		Resources:    resources,
+	}
+}
+
+// serviceProviderConfig describes the features of RFC 7644 supported by the endpoints
+func serviceProviderConfig() map[string]any {
+	unsupported := map[string]any{"supported": false}
+
+	return map[string]any{
+		"schemas":        []string{serviceProviderConfigSchema},
+		"patch":          map[string]any{"supported": true},
+		"bulk":           map[string]any{"supported": false, "maxOperations": 0, "maxPayloadSize": 0},
+		"filter":         map[string]any{"supported": true, "maxResults": scim.MaxCount},
+		"changePassword": unsupported,
+		"sort":           unsupported,
+		"etag":           map[string]any{"supported": true},
+		"authenticationSchemes": []map[string]any{{
+			"type":        "oauthbearertoken",
+			"name":        "OAuth Bearer Token",
+			"description": "Access token of the client credentials grant with scim scope",
+			"primary":     true,
+		}},
+	}
+}
@@ -0,0 +1,426 @@
+package expr
+
+import (
+	"cmp"
+	"fmt"
+	"net/netip"
+	"reflect"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+// Eval evaluates the expression with the variables. Numbers of the variables must be float64
+// and times must be time.Time, values decoded from JSON satisfy it
+func (e *Expr) Eval(vars map[string]any) (any, error) {
+	return e.root.eval(vars)
+}
+
+// EvalBool evaluates the expression which must give bool
+func (e *Expr) EvalBool(vars map[string]any) (bool, error) {
+	value, err := e.Eval(vars)
+	if err != nil {
+		return false, err
+	}
+
+	result, ok := value.(bool)
+	if !ok {
+		return false, fmt.Errorf("expression gives %s instead of bool", typeName(value))
+	}
+
+	return result, nil
+}
+
+type node interface {
+	eval(vars map[string]any) (any, error)
+}
+
+type literalNode struct {
+	value any
+}
+
+func (n *literalNode) eval(map[string]any) (any, error) {
+	return n.value, nil
+}
+
+type variableNode struct {
+	name string
+}
+
+func (n *variableNode) eval(vars map[string]any) (any, error) {
+	value, ok := vars[n.name]
+	if !ok {
+		return nil, fmt.Errorf("unknown variable %s", n.name)
+	}
+
+	return value, nil
+}
+
+type fieldNode struct {
+	base node
+	name string
+}
+
+func (n *fieldNode) eval(vars map[string]any) (any, error) {
+	base, err := n.base.eval(vars)
+	if err != nil {
+		return nil, err
+	}
+
+	switch base := base.(type) {
+	case nil:
+		return nil, nil
+	case map[string]any:
+		return base[n.name], nil
+	}
+
+	return nil, fmt.Errorf("%s has no field %s", typeName(base), n.name)
+}
+
+type indexNode struct {
+	base  node
+	index node
+}
+
+func (n *indexNode) eval(vars map[string]any) (any, error) {
+	base, err := n.base.eval(vars)
+	if err != nil {
+		return nil, err
+	}
+
+	index, err := n.index.eval(vars)
+	if err != nil {
+		return nil, err
+	}
+
+	switch base := base.(type) {
+	case nil:
+		return nil, nil
+	case map[string]any:
+		key, ok := index.(string)
+		if !ok {
+			return nil, fmt.Errorf("map can't be indexed by %s", typeName(index))
+		}
+
+		return base[key], nil
+	case []any:
+		i, ok := index.(float64)
+		if !ok || i != float64(int(i)) {
+			return nil, fmt.Errorf("list can't be indexed by %v", index)
+		}
+
+		if i < 0 || int(i) >= len(base) {
+			return nil, nil
+		}
+
+		return base[int(i)], nil
+	}
+
+	return nil, fmt.Errorf("%s can't be indexed", typeName(base))
+}
+
+type listNode struct {
+	items []node
+}
+
+func (n *listNode) eval(vars map[string]any) (any, error) {
+	list := make([]any, 0, len(n.items))
+	for _, item := range n.items {
+		value, err := item.eval(vars)
+		if err != nil {
+			return nil, err
+		}
+
+		list = append(list, value)
+	}
+
+	return list, nil
+}
+
+type unaryNode struct {
+	op      string
+	operand node
+}
+
+func (n *unaryNode) eval(vars map[string]any) (any, error) {
+	value, err := n.operand.eval(vars)
+	if err != nil {
+		return nil, err
+	}
+
+	switch value := value.(type) {
+	case bool:
+		if n.op == "!" {
+			return !value, nil
+		}
+	case float64:
+		if n.op == "-" {
+			return -value, nil
+		}
+	}
+
+	return nil, fmt.Errorf("operator %s can't be applied to %s", n.op, typeName(value))
+}
+
+// logicalNode evaluates right operand only when left one doesn't decide the result
+type logicalNode struct {
+	op          string
+	left, right node
+}
+
+func (n *logicalNode) eval(vars map[string]any) (any, error) {
+	for _, operand := range []node{n.left, n.right} {
+		value, err := operand.eval(vars)
+		if err != nil {
+			return nil, err
+		}
+
+		result, ok := value.(bool)
+		if !ok {
+			return nil, fmt.Errorf("operator %s can't be applied to %s", n.op, typeName(value))
+		}
+
+		if result == (n.op == "||") {
+			return result, nil
+		}
+	}
+
+	return n.op == "&&", nil
+}
+
+type comparisonNode struct {
+	op          string
+	left, right node
+}
+
+func (n *comparisonNode) eval(vars map[string]any) (any, error) {
+	left, err := n.left.eval(vars)
+	if err != nil {
+		return nil, err
+	}
+
+	right, err := n.right.eval(vars)
+	if err != nil {
+		return nil, err
+	}
+
+	switch n.op {
+	case "==":
+		return equal(left, right), nil
+	case "!=":
+		return !equal(left, right), nil
+	case "in":
+		return contains(right, left)
+	}
+
+	result, err := compare(left, right)
+	if err != nil {
+		return nil, err
+	}
+
+	switch n.op {
+	case "<":
+		return result < 0, nil
+	case "<=":
+		return result <= 0, nil
+	case ">":
+		return result > 0, nil
+	}
+
+	return result >= 0, nil
+}
+
+type callNode struct {
+	name string
+	fn   func(args []any) (any, error)
+	args []node
+}
+
+func (n *callNode) eval(vars map[string]any) (any, error) {
+	args := make([]any, 0, len(n.args))
+	for _, arg := range n.args {
+		value, err := arg.eval(vars)
+		if err != nil {
+			return nil, err
+		}
+
+		args = append(args, value)
+	}
+
+	result, err := n.fn(args)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", n.name, err)
+	}
+
+	return result, nil
+}
+
+type function struct {
+	arity int
+	call  func(args []any) (any, error)
+}
+
+// functions are available in expressions, times are taken in UTC
+var functions = map[string]function{
+	"size": {1, func(args []any) (any, error) {
+		switch value := args[0].(type) {
+		case string:
+			return float64(utf8.RuneCountInString(value)), nil
+		case []any:
+			return float64(len(value)), nil
+		case map[string]any:
+			return float64(len(value)), nil
+		}
+
+		return nil, fmt.Errorf("%s has no size", typeName(args[0]))
+	}},
+	"contains": {2, func(args []any) (any, error) {
+		return contains(args[0], args[1])
+	}},
+	"startsWith": {2, stringsFunc(strings.HasPrefix)},
+	"endsWith":   {2, stringsFunc(strings.HasSuffix)},
+	"lower": {1, func(args []any) (any, error) {
+		s, ok := args[0].(string)
+		if !ok {
+			return nil, fmt.Errorf("expected string, got %s", typeName(args[0]))
+		}
+
+		return strings.ToLower(s), nil
+	}},
+	"inCidr": {2, func(args []any) (any, error) {
+		ip, ok := args[0].(string)
+		if !ok {
+			return false, nil
+		}
+
+		cidr, ok := args[1].(string)
+		if !ok {
+			return nil, fmt.Errorf("expected CIDR string, got %s", typeName(args[1]))
+		}
+
+		prefix, err := netip.ParsePrefix(cidr)
+		if err != nil {
+			return nil, err
+		}
+
+		addr, err := netip.ParseAddr(ip)
+		if err != nil {
+			return false, nil
+		}
+
+		return prefix.Contains(addr.Unmap()), nil
+	}},
+	"timestamp": {1, func(args []any) (any, error) {
+		s, ok := args[0].(string)
+		if !ok {
+			return nil, fmt.Errorf("expected RFC 3339 string, got %s", typeName(args[0]))
+		}
+
+		return time.Parse(time.RFC3339, s)
+	}},
+	"hour": {1, timeFunc(func(t time.Time) float64 {
+		return float64(t.Hour())
+	})},
+	"weekday": {1, timeFunc(func(t time.Time) float64 {
+		return float64(t.Weekday())
+	})},
+}
+
+func stringsFunc(fn func(s, arg string) bool) func(args []any) (any, error) {
+	return func(args []any) (any, error) {
+		s, ok1 := args[0].(string)
+		arg, ok2 := args[1].(string)
+		if !ok1 || !ok2 {
+			return nil, fmt.Errorf("expected strings, got %s and %s", typeName(args[0]), typeName(args[1]))
+		}
+
+		return fn(s, arg), nil
+	}
+}
+
+func timeFunc(fn func(t time.Time) float64) func(args []any) (any, error) {
+	return func(args []any) (any, error) {
+		t, ok := args[0].(time.Time)
+		if !ok {
+			return nil, fmt.Errorf("expected time, got %s", typeName(args[0]))
+		}
+
+		return fn(t.UTC()), nil
+	}
+}
+
+func equal(a, b any) bool {
+	if a, ok := a.(time.Time); ok {
+		b, ok := b.(time.Time)
+		return ok && a.Equal(b)
+	}
+
+	return reflect.DeepEqual(a, b)
+}
+
+func compare(a, b any) (int, error) {
+	switch a := a.(type) {
+	case float64:
+		if b, ok := b.(float64); ok {
+			return cmp.Compare(a, b), nil
+		}
+	case string:
+		if b, ok := b.(string); ok {
+			return strings.Compare(a, b), nil
+		}
+	case time.Time:
+		if b, ok := b.(time.Time); ok {
+			return a.Compare(b), nil
+		}
+	}
+
+	return 0, fmt.Errorf("%s and %s can't be compared", typeName(a), typeName(b))
+}
+
+// contains reports whether the list has the item, the map has the key or the string has the substring
+func contains(container, item any) (bool, error) {
+	switch container := container.(type) {
+	case nil:
+		return false, nil
+	case []any:
+		for _, value := range container {
+			if equal(value, item) {
+				return true, nil
+			}
+		}
+
+		return false, nil
+	case map[string]any:
+		if key, ok := item.(string); ok {
+			_, ok = container[key]
+			return ok, nil
+		}
+	case string:
+		if s, ok := item.(string); ok {
+			return strings.Contains(container, s), nil
+		}
+	}
+
+	return false, fmt.Errorf("%s can't contain %s", typeName(container), typeName(item))
+}
+
+func typeName(value any) string {
+	switch value.(type) {
+	case nil:
+		return "null"
+	case bool:
+		return "bool"
+	case float64:
+		return "number"
+	case string:
+		return "string"
+	case []any:
+		return "list"
+	case map[string]any:
+		return "map"
+	case time.Time:
+		return "time"
+	}
+
+	return fmt.Sprintf("%T", value)
+}
@@ -0,0 +1,390 @@
+// Package expr implements small CEL-like expression language of the access policies, for example
+//
+//	"admin" in user.roles || resource.owner == user.id && inCidr(request.ip, "10.0.0.0/8")
+//
+// Values are null, bool, number (float64), string, list, map and time. Field of null
+// or missing field of a map is null, so absent attributes can be compared with null.
+// Functions can be called as f(x, y) or as x.f(y)
+package expr
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+var ErrSyntax = errors.New("syntax error")
+
+// Expr is a compiled expression which can be evaluated many times
+type Expr struct {
+	src  string
+	root node
+}
+
+// Compile parses the expression and checks names and arity of the called functions
+func Compile(src string) (*Expr, error) {
+	tokens, err := tokenize(src)
+	if err != nil {
+		return nil, err
+	}
+
+	p := &parser{tokens: tokens}
+
+	root, err := p.parseOr()
+	if err != nil {
+		return nil, err
+	}
+
+	if tok := p.peek(); tok.kind != tokEOF {
+		return nil, syntaxError(tok.pos, "unexpected %q", tok.text)
+	}
+
+	return &Expr{src: src, root: root}, nil
+}
+
+func (e *Expr) String() string {
+	return e.src
+}
+
+type tokenKind int
+
+const (
+	tokEOF tokenKind = iota
+	tokIdent
+	tokNumber
+	tokString
+	tokPunct
+)
+
+type token struct {
+	kind tokenKind
+	text string
+	num  float64
+	pos  int
+}
+
+var twoCharPuncts = []string{"||", "&&", "==", "!=", "<=", ">="}
+
+func tokenize(src string) (tokens []token, err error) {
+	for i := 0; i < len(src); {
+		c := src[i]
+
+		switch {
+		case c == ' ' || c == '\t' || c == '\n' || c == '\r':
+			i++
+		case isLetter(c):
+			start := i
+			for i < len(src) && (isLetter(src[i]) || isDigit(src[i])) {
+				i++
+			}
+
+			tokens = append(tokens, token{kind: tokIdent, text: src[start:i], pos: start})
+		case isDigit(c):
+			start := i
+			for i < len(src) && (isDigit(src[i]) || src[i] == '.') {
+				i++
+			}
+
+			num, err := strconv.ParseFloat(src[start:i], 64)
+			if err != nil {
+				return nil, syntaxError(start, "invalid number %q", src[start:i])
+			}
+
+			tokens = append(tokens, token{kind: tokNumber, text: src[start:i], num: num, pos: start})
+		case c == '"' || c == '\'':
+			start := i
+
+			var sb strings.Builder
+			for i++; i < len(src) && src[i] != c; i++ {
+				if src[i] == '\\' && i+1 < len(src) {
+					i++
+				}
+
+				sb.WriteByte(src[i])
+			}
+
+			if i >= len(src) {
+				return nil, syntaxError(start, "unterminated string")
+			}
+			i++
+
+			tokens = append(tokens, token{kind: tokString, text: sb.String(), pos: start})
+		default:
+			if i+1 < len(src) && slices.Contains(twoCharPuncts, src[i:i+2]) {
+				tokens = append(tokens, token{kind: tokPunct, text: src[i : i+2], pos: i})
+				i += 2
+
+				continue
+			}
+
+			if strings.IndexByte("<>!-()[].,", c) < 0 {
+				return nil, syntaxError(i, "unexpected character %q", c)
+			}
+
+			tokens = append(tokens, token{kind: tokPunct, text: string(c), pos: i})
+			i++
+		}
+	}
+
+	return append(tokens, token{kind: tokEOF, pos: len(src)}), nil
+}
+
+type parser struct {
+	tokens []token
+	pos    int
+}
+
+func (p *parser) peek() token {
+	return p.tokens[p.pos]
+}
+
+func (p *parser) next() token {
+	tok := p.tokens[p.pos]
+	if tok.kind != tokEOF {
+		p.pos++
+	}
+
+	return tok
+}
+
+// accept skips the next token if it is the punctuation or the keyword
+func (p *parser) accept(text string) bool {
+	tok := p.peek()
+	if (tok.kind == tokPunct || tok.kind == tokIdent) && tok.text == text {
+		p.pos++
+		return true
+	}
+
+	return false
+}
+
+func (p *parser) expect(text string) error {
+	if !p.accept(text) {
+		tok := p.peek()
+		return syntaxError(tok.pos, "expected %q, got %q", text, tok.text)
+	}
+
+	return nil
+}
+
+func (p *parser) parseOr() (node, error) {
+	left, err := p.parseAnd()
+	if err != nil {
+		return nil, err
+	}
+
+	for p.accept("||") {
+		right, err := p.parseAnd()
+		if err != nil {
+			return nil, err
+		}
+
+		left = &logicalNode{op: "||", left: left, right: right}
+	}
+
+	return left, nil
+}
+
+func (p *parser) parseAnd() (node, error) {
+	left, err := p.parseComparison()
+	if err != nil {
+		return nil, err
+	}
+
+	for p.accept("&&") {
+		right, err := p.parseComparison()
+		if err != nil {
+			return nil, err
+		}
+
+		left = &logicalNode{op: "&&", left: left, right: right}
+	}
+
+	return left, nil
+}
+
+var comparisonOps = []string{"==", "!=", "<", "<=", ">", ">=", "in"}
+
+func (p *parser) parseComparison() (node, error) {
+	left, err := p.parseUnary()
+	if err != nil {
+		return nil, err
+	}
+
+	for _, op := range comparisonOps {
+		if p.accept(op) {
+			right, err := p.parseUnary()
+			if err != nil {
+				return nil, err
+			}
+
+			return &comparisonNode{op: op, left: left, right: right}, nil
+		}
+	}
+
+	return left, nil
+}
+
+func (p *parser) parseUnary() (node, error) {
+	for _, op := range []string{"!", "-"} {
+		if p.accept(op) {
+			operand, err := p.parseUnary()
+			if err != nil {
+				return nil, err
+			}
+
+			return &unaryNode{op: op, operand: operand}, nil
+		}
+	}
+
+	return p.parsePostfix()
+}
+
+func (p *parser) parsePostfix() (node, error) {
+	n, err := p.parsePrimary()
+	if err != nil {
+		return nil, err
+	}
+
+	for {
+		switch {
+		case p.accept("."):
+			tok := p.next()
+			if tok.kind != tokIdent {
+				return nil, syntaxError(tok.pos, "expected field name, got %q", tok.text)
+			}
+
+			if !p.accept("(") {
+				n = &fieldNode{base: n, name: tok.text}
+				continue
+			}
+
+			args, err := p.parseList(")")
+			if err != nil {
+				return nil, err
+			}
+
+			if n, err = newCall(tok, append([]node{n}, args...)); err != nil {
+				return nil, err
+			}
+		case p.accept("["):
+			index, err := p.parseOr()
+			if err != nil {
+				return nil, err
+			}
+
+			if err := p.expect("]"); err != nil {
+				return nil, err
+			}
+
+			n = &indexNode{base: n, index: index}
+		default:
+			return n, nil
+		}
+	}
+}
+
+func (p *parser) parsePrimary() (node, error) {
+	tok := p.next()
+
+	switch tok.kind {
+	case tokNumber:
+		return &literalNode{value: tok.num}, nil
+	case tokString:
+		return &literalNode{value: tok.text}, nil
+	case tokIdent:
+		switch tok.text {
+		case "true":
+			return &literalNode{value: true}, nil
+		case "false":
+			return &literalNode{value: false}, nil
+		case "null":
+			return &literalNode{value: nil}, nil
+		}
+
+		if !p.accept("(") {
+			return &variableNode{name: tok.text}, nil
+		}
+
+		args, err := p.parseList(")")
+		if err != nil {
+			return nil, err
+		}
+
+		return newCall(tok, args)
+	case tokPunct:
+		switch tok.text {
+		case "(":
+			n, err := p.parseOr()
+			if err != nil {
+				return nil, err
+			}
+
+			return n, p.expect(")")
+		case "[":
+			items, err := p.parseList("]")
+			if err != nil {
+				return nil, err
+			}
+
+			return &listNode{items: items}, nil
+		}
+	}
+
+	if tok.kind == tokEOF {
+		return nil, syntaxError(tok.pos, "unexpected end of expression")
+	}
+
+	return nil, syntaxError(tok.pos, "unexpected %q", tok.text)
+}
+
+// parseList parses comma separated expressions up to the closing punctuation
+func (p *parser) parseList(closing string) (items []node, err error) {
+	if p.accept(closing) {
+		return nil, nil
+	}
+
+	for {
+		item, err := p.parseOr()
+		if err != nil {
+			return nil, err
+		}
+
+		items = append(items, item)
+
+		if p.accept(closing) {
+			return items, nil
+		}
+
+		if err := p.expect(","); err != nil {
+			return nil, err
+		}
+	}
+}
+
+func newCall(name token, args []node) (node, error) {
+	fn, ok := functions[name.text]
+	if !ok {
+		return nil, syntaxError(name.pos, "unknown function %s", name.text)
+	}
+
+	if len(args) != fn.arity {
+		return nil, syntaxError(name.pos, "function %s takes %d arguments, got %d", name.text, fn.arity, len(args))
+	}
+
+	return &callNode{name: name.text, fn: fn.call, args: args}, nil
+}
+
+func syntaxError(pos int, format string, args ...interface{}) error {
+	return fmt.Errorf("%w at position %d: %s", ErrSyntax, pos, fmt.Sprintf(format, args...))
+}
+
+func isLetter(c byte) bool {
+	return c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c == '_'
+}
+
+func isDigit(c byte) bool {
+	return c >= '0' && c <= '9'
+}
@@ -1,17 +1,247 @@
 package jwt
 
 import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
 	"time"
 
 	"github.com/golang-jwt/jwt/v5"
 	"github.com/nhassl3/sso-app/internals/domain/models"
 )
 
-func NewToken(user models.User, app models.App, duration time.Duration) (string, error) {
-	return jwt.NewWithClaims(jwt.SigningMethodHS512, jwt.MapClaims{
+const (
+	claimRoles      = "roles"
+	claimScope      = "scope"
+	claimIntrospect = "introspect"
+	claimGranted    = "granted_scope"
+	claimActor      = "act"
+	claimScopeLimit = "scope_limit"
+	claimIdentity   = "idp"
+)
+
+var ErrInvalidToken = errors.New("invalid token")
+
+type Claims struct {
+	UID        int64 // zero for tokens of the application itself
+	Email      string
+	AppID      int32
+	ExpiresAt  time.Time
+	Roles      []string
+	Scope      string
+	Introspect bool   // roles and scope did not fit into the token and must be fetched by introspection
+	Granted    string // scope granted by the user to the OAuth client, empty for tokens issued by Login
+	ActorID    int64  // user who acts as the token owner by the token exchange, zero if the owner acts
+	ScopeLimit string // scopes the token is limited to by the token exchange, empty if token isn't limited
+}
+
+// NewToken creates a new token of the user signed by the secret of the app.
+// Roles and scope claims are put only if the app asks for them and they fit into
+// app claims size cap, otherwise token gets introspect claim instead of them
+func NewToken(user models.User, app models.App, roles []models.Role, duration time.Duration) (string, error) {
+	return NewGrantedToken(user, app, roles, duration, "", "")
+}
+
+// NewGrantedToken creates a new token of the user as NewToken with scope granted by the user to the OAuth client
+// and idp claim with the identity provider which authenticated the user, empty values are omitted
+func NewGrantedToken(
+	user models.User,
+	app models.App,
+	roles []models.Role,
+	duration time.Duration,
+	granted string,
+	identity string,
+) (string, error) {
+	claims := userClaims(user, app, duration)
+
+	if granted != "" {
+		claims[claimGranted] = granted
+	}
+
+	if identity != "" {
+		claims[claimIdentity] = identity
+	}
+
+	return signUserToken(claims, app, roles)
+}
+
+// NewExchangedToken creates a new token of the user as NewToken issued by the token exchange.
+// Token of the impersonated user has act claim of RFC 8693 with the actor, limited token has scope_limit claim,
+// roles of the limited token must have only scopes of the limit
+func NewExchangedToken(
+	user models.User,
+	app models.App,
+	roles []models.Role,
+	duration time.Duration,
+	actorID int64,
+	scopeLimit string,
+) (string, error) {
+	claims := userClaims(user, app, duration)
+
+	if actorID != 0 {
+		claims[claimActor] = map[string]any{"sub": strconv.FormatInt(actorID, 10)}
+	}
+
+	if scopeLimit != "" {
+		claims[claimScopeLimit] = scopeLimit
+	}
+
+	return signUserToken(claims, app, roles)
+}
+
+// NewClientToken creates a new token of the application itself signed by its secret.
+// Token has no uid and email claims, scope claim has the scopes granted to the application
+func NewClientToken(app models.App, scopes []string, duration time.Duration) (string, error) {
+	claims := jwt.MapClaims{
+		"exp":      time.Now().Add(duration).Unix(),
+		"app_id":   app.ID,
+		claimScope: strings.Join(scopes, " "),
+	}
+
+	return jwt.NewWithClaims(jwt.SigningMethodHS512, claims).SignedString([]byte(app.Secret))
+}
+
+// Parse validates the token and returns its claims.
+// secret is called with the application ID from the token and must return secret of that app.
+// Returns ErrInvalidToken if token is malformed, expired or has wrong signature, or error of the secret
+func Parse(token string, secret func(appID int32) (string, error)) (claims Claims, err error) {
+	var secretErr error
+
+	parsed, err := jwt.Parse(token, func(t *jwt.Token) (interface{}, error) {
+		mapClaims, ok := t.Claims.(jwt.MapClaims)
+		if !ok {
+			return nil, ErrInvalidToken
+		}
+
+		appID, ok := mapClaims["app_id"].(float64)
+		if !ok {
+			return nil, ErrInvalidToken
+		}
+
+		key, err := secret(int32(appID))
+		if err != nil {
+			secretErr = err
+			return nil, err
+		}
+
+		return []byte(key), nil
+	}, jwt.WithValidMethods([]string{jwt.SigningMethodHS512.Alg()}), jwt.WithExpirationRequired())
+	if err != nil {
+		if secretErr != nil {
+			return Claims{}, secretErr
+		}
+
+		return Claims{}, fmt.Errorf("%w: %w", ErrInvalidToken, err)
+	}
+
+	mapClaims := parsed.Claims.(jwt.MapClaims)
+
+	uid, _ := mapClaims["uid"].(float64)
+	appID, _ := mapClaims["app_id"].(float64)
+	exp, _ := mapClaims.GetExpirationTime()
+
+	claims = Claims{
+		UID:       int64(uid),
+		AppID:     int32(appID),
+		ExpiresAt: exp.Time,
+	}
+	claims.Email, _ = mapClaims["email"].(string)
+	claims.Scope, _ = mapClaims[claimScope].(string)
+	claims.Introspect, _ = mapClaims[claimIntrospect].(bool)
+	claims.Granted, _ = mapClaims[claimGranted].(string)
+	claims.ScopeLimit, _ = mapClaims[claimScopeLimit].(string)
+
+	if actor, ok := mapClaims[claimActor].(map[string]interface{}); ok {
+		sub, _ := actor["sub"].(string)
+		claims.ActorID, _ = strconv.ParseInt(sub, 10, 64)
+	}
+
+	if roles, ok := mapClaims[claimRoles].([]interface{}); ok {
+		for _, role := range roles {
+			if name, ok := role.(string); ok {
+				claims.Roles = append(claims.Roles, name)
+			}
+		}
+	}
+
+	return
+}
+
+// RoleNames returns names of the roles
+func RoleNames(roles []models.Role) []string {
+	names := make([]string, 0, len(roles))
+	for _, role := range roles {
+		names = append(names, role.Name)
+	}
+
+	return names
+}
+
+// RoleScopes returns sorted scopes granted by the roles without duplicates
+func RoleScopes(roles []models.Role) []string {
+	scopes := make([]string, 0, len(roles))
+	for _, role := range roles {
+		scopes = append(scopes, role.Scopes...)
+	}
+
+	slices.Sort(scopes)
+
+	return slices.Compact(scopes)
+}
+
+// userClaims returns claims every token of the user has
+func userClaims(user models.User, app models.App, duration time.Duration) jwt.MapClaims {
+	return jwt.MapClaims{
 		"email":  user.Email,
 		"exp":    time.Now().Add(duration).Unix(),
 		"uid":    user.ID,
 		"app_id": app.ID,
-	}).SignedString([]byte(app.Secret))
+	}
+}
+
+// signUserToken puts roles into the claims of the user and signs them by the secret of the app
+func signUserToken(claims jwt.MapClaims, app models.App, roles []models.Role) (string, error) {
+	if err := putRoles(claims, app, roles); err != nil {
+		return "", err
+	}
+
+	return jwt.NewWithClaims(jwt.SigningMethodHS512, claims).SignedString([]byte(app.Secret))
+}
+
+// putRoles puts roles and scope claims which app wants to see in the token
+func putRoles(claims jwt.MapClaims, app models.App, roles []models.Role) error {
+	extra := make(map[string]interface{}, 2)
+
+	if app.EmbedRoles {
+		extra[claimRoles] = RoleNames(roles)
+	}
+
+	if app.EmbedScope {
+		extra[claimScope] = strings.Join(RoleScopes(roles), " ")
+	}
+
+	if len(extra) == 0 {
+		return nil
+	}
+
+	raw, err := json.Marshal(extra)
+	if err != nil {
+		return err
+	}
+
+	// Too large token is a problem for headers and cookies, so downstream service
+	// should ask roles and scope by introspection
+	if len(raw) > app.MaxClaimsSize {
+		claims[claimIntrospect] = true
+		return nil
+	}
+
+	for name, value := range extra {
+		claims[name] = value
+	}
+
+	return nil
 }
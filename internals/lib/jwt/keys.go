@@ -0,0 +1,139 @@
+package jwt
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"math/big"
+	"os"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+const signingKeyBits = 2048
+
+var ErrInvalidKey = errors.New("invalid signing key")
+
+// SigningKey is RSA key of the server which signs ID tokens, its public part is published as JWK
+type SigningKey struct {
+	ID      string // thumbprint of the public key of RFC 7638
+	private *rsa.PrivateKey
+}
+
+// JWK is a public key in the JSON Web Key format of RFC 7517
+type JWK struct {
+	KeyType   string `json:"kty"`
+	Use       string `json:"use"`
+	Algorithm string `json:"alg"`
+	KeyID     string `json:"kid"`
+	Modulus   string `json:"n"`
+	Exponent  string `json:"e"`
+}
+
+// LoadSigningKey reads RSA private key in PKCS#1 or PKCS#8 PEM file
+func LoadSigningKey(path string) (*SigningKey, error) {
+	raw, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	block, _ := pem.Decode(raw)
+	if block == nil {
+		return nil, fmt.Errorf("%w: no PEM block in %s", ErrInvalidKey, path)
+	}
+
+	if private, err := x509.ParsePKCS1PrivateKey(block.Bytes); err == nil {
+		return newSigningKey(private), nil
+	}
+
+	parsed, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrInvalidKey, err)
+	}
+
+	private, ok := parsed.(*rsa.PrivateKey)
+	if !ok {
+		return nil, fmt.Errorf("%w: key in %s is not RSA key", ErrInvalidKey, path)
+	}
+
+	return newSigningKey(private), nil
+}
+
+// GenerateSigningKey generates new RSA key, tokens signed by it become invalid after restart
+func GenerateSigningKey() (*SigningKey, error) {
+	private, err := rsa.GenerateKey(rand.Reader, signingKeyBits)
+	if err != nil {
+		return nil, err
+	}
+
+	return newSigningKey(private), nil
+}
+
+// JWK returns public part of the key
+func (k *SigningKey) JWK() JWK {
+	return JWK{
+		KeyType:   "RSA",
+		Use:       "sig",
+		Algorithm: jwt.SigningMethodRS256.Alg(),
+		KeyID:     k.ID,
+		Modulus:   base64.RawURLEncoding.EncodeToString(k.private.N.Bytes()),
+		Exponent:  base64.RawURLEncoding.EncodeToString(big.NewInt(int64(k.private.E)).Bytes()),
+	}
+}
+
+// NewIDToken creates ID token of OpenID Connect with the claims signed by the key.
+// Issued at and expiry claims are set by the function
+func (k *SigningKey) NewIDToken(claims map[string]any, duration time.Duration) (string, error) {
+	return k.sign(claims, duration, "")
+}
+
+// NewLogoutToken creates logout token of OpenID Connect Back-Channel Logout with the claims signed by the key.
+// Token has explicit type, so it can't be confused with ID token. Issued at and expiry claims are set by the function
+func (k *SigningKey) NewLogoutToken(claims map[string]any, duration time.Duration) (string, error) {
+	return k.sign(claims, duration, "logout+jwt")
+}
+
+// sign signs the claims with issued at and expiry claims, typ header is set if it is given
+func (k *SigningKey) sign(claims map[string]any, duration time.Duration, typ string) (string, error) {
+	now := time.Now()
+
+	mapClaims := jwt.MapClaims{
+		"iat": now.Unix(),
+		"exp": now.Add(duration).Unix(),
+	}
+	for name, value := range claims {
+		mapClaims[name] = value
+	}
+
+	token := jwt.NewWithClaims(jwt.SigningMethodRS256, mapClaims)
+	token.Header["kid"] = k.ID
+	if typ != "" {
+		token.Header["typ"] = typ
+	}
+
+	return token.SignedString(k.private)
+}
+
+func newSigningKey(private *rsa.PrivateKey) *SigningKey {
+	key := &SigningKey{private: private}
+
+	// Members of the thumbprint are in lexicographic order without spaces as RFC 7638 requires
+	jwk := key.JWK()
+	thumbprint, _ := json.Marshal(struct {
+		E   string `json:"e"`
+		Kty string `json:"kty"`
+		N   string `json:"n"`
+	}{jwk.Exponent, jwk.KeyType, jwk.Modulus})
+
+	sum := sha256.Sum256(thumbprint)
+	key.ID = base64.RawURLEncoding.EncodeToString(sum[:])
+
+	return key
+}
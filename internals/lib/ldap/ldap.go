@@ -0,0 +1,155 @@
+package ldap
+
+import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"net"
+	"net/url"
+	"strings"
+	"time"
+
+	goldap "github.com/go-ldap/ldap/v3"
+	"github.com/nhassl3/sso-app/internals/domain/models"
+)
+
+// LoginPlaceholder is replaced by the escaped login in the user filter
+const LoginPlaceholder = "{login}"
+
+var (
+	ErrUserNotFound       = errors.New("user not found in the directory")
+	ErrInvalidCredentials = errors.New("invalid credentials of the directory user")
+)
+
+// Config is the directory users are searched in by the service account and authenticated by bind
+type Config struct {
+	URL                string // ldap:// or ldaps:// URL of the server
+	StartTLS           bool
+	InsecureSkipVerify bool
+	Timeout            time.Duration
+	BindDN             string // service account searching the users
+	BindPassword       string
+	BaseDN             string
+	UserFilter         string // LDAP filter of the user with LoginPlaceholder
+
+	SubjectAttribute string // stable ID of the user, DN is used if empty
+	EmailAttribute   string
+	GroupsAttribute  string // DNs of the groups of the user
+}
+
+// Directory authenticates the users by LDAP bind. Every authentication uses its own connection
+type Directory struct {
+	cfg Config
+}
+
+// NewDirectory returns the directory of the config
+func NewDirectory(cfg Config) *Directory {
+	return &Directory{cfg: cfg}
+}
+
+// Authenticate finds the only user of the login by the service account and binds as the user with the password.
+// Returns ErrUserNotFound if the filter matches no user and ErrInvalidCredentials if the password is wrong
+func (d *Directory) Authenticate(ctx context.Context, login, password string) (models.DirectoryEntry, error) {
+	// Bind with empty password is anonymous bind, which succeeds for any DN
+	if login == "" || password == "" {
+		return models.DirectoryEntry{}, ErrInvalidCredentials
+	}
+
+	conn, err := d.dial(ctx)
+	if err != nil {
+		return models.DirectoryEntry{}, err
+	}
+	defer conn.Close()
+
+	if err := conn.Bind(d.cfg.BindDN, d.cfg.BindPassword); err != nil {
+		return models.DirectoryEntry{}, fmt.Errorf("bind of the service account: %w", err)
+	}
+
+	attributes := []string{d.cfg.EmailAttribute, d.cfg.GroupsAttribute}
+	if d.cfg.SubjectAttribute != "" {
+		attributes = append(attributes, d.cfg.SubjectAttribute)
+	}
+
+	result, err := conn.Search(goldap.NewSearchRequest(
+		d.cfg.BaseDN,
+		goldap.ScopeWholeSubtree,
+		goldap.NeverDerefAliases,
+		2, // the second entry makes the login ambiguous
+		int(d.cfg.Timeout.Seconds()),
+		false,
+		strings.ReplaceAll(d.cfg.UserFilter, LoginPlaceholder, goldap.EscapeFilter(login)),
+		attributes,
+		nil,
+	))
+	if err != nil && !goldap.IsErrorWithCode(err, goldap.LDAPResultSizeLimitExceeded) {
+		return models.DirectoryEntry{}, fmt.Errorf("search of the user: %w", err)
+	}
+
+	switch len(result.Entries) {
+	case 0:
+		return models.DirectoryEntry{}, ErrUserNotFound
+	case 1:
+	default:
+		return models.DirectoryEntry{}, fmt.Errorf("login %q matches several users", login)
+	}
+
+	entry := result.Entries[0]
+
+	if err := conn.Bind(entry.DN, password); err != nil {
+		if goldap.IsErrorWithCode(err, goldap.LDAPResultInvalidCredentials) {
+			return models.DirectoryEntry{}, ErrInvalidCredentials
+		}
+
+		return models.DirectoryEntry{}, fmt.Errorf("bind of the user: %w", err)
+	}
+
+	user := models.DirectoryEntry{
+		DN:      entry.DN,
+		Subject: entry.DN,
+		Email:   entry.GetAttributeValue(d.cfg.EmailAttribute),
+		Groups:  entry.GetAttributeValues(d.cfg.GroupsAttribute),
+	}
+	if d.cfg.SubjectAttribute != "" {
+		user.Subject = entry.GetAttributeValue(d.cfg.SubjectAttribute)
+	}
+
+	if user.Subject == "" {
+		return models.DirectoryEntry{}, fmt.Errorf("entry %q has no %s attribute", entry.DN, d.cfg.SubjectAttribute)
+	}
+
+	return user, nil
+}
+
+// dial connects to the server, connection is secured by StartTLS if it is configured
+func (d *Directory) dial(ctx context.Context) (*goldap.Conn, error) {
+	dialer := &net.Dialer{Timeout: d.cfg.Timeout}
+	if deadline, ok := ctx.Deadline(); ok {
+		dialer.Deadline = deadline
+	}
+
+	u, err := url.Parse(d.cfg.URL)
+	if err != nil {
+		return nil, fmt.Errorf("url: %w", err)
+	}
+
+	// Certificate of the server is verified against its host name unless the config opts out
+	tlsConfig := &tls.Config{ServerName: u.Hostname(), InsecureSkipVerify: d.cfg.InsecureSkipVerify}
+
+	conn, err := goldap.DialURL(d.cfg.URL, goldap.DialWithDialer(dialer), goldap.DialWithTLSConfig(tlsConfig))
+	if err != nil {
+		return nil, fmt.Errorf("dial: %w", err)
+	}
+
+	conn.SetTimeout(d.cfg.Timeout)
+
+	if d.cfg.StartTLS {
+		if err := conn.StartTLS(tlsConfig); err != nil {
+			_ = conn.Close()
+
+			return nil, fmt.Errorf("start tls: %w", err)
+		}
+	}
+
+	return conn, nil
+}
@@ -0,0 +1,383 @@
+package oidc
+
+import (
+	"context"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math/big"
+	"net/http"
+	"net/url"
+	"slices"
+	"strings"
+	"sync"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/nhassl3/sso-app/internals/domain/models"
+)
+
+const (
+	discoveryPath = "/.well-known/openid-configuration"
+
+	// maxResponseSize limits responses of the provider read into memory
+	maxResponseSize = 1 << 20
+)
+
+var (
+	ErrDiscovery      = errors.New("failed to discover the provider")
+	ErrExchange       = errors.New("failed to exchange the code")
+	ErrInvalidIDToken = errors.New("ID token is invalid")
+	ErrInvalidGrant   = errors.New("provider rejected the grant")
+)
+
+// ClaimMapping names claims of ID token of the provider which hold fields of the identity,
+// empty names are standard claims of OpenID Connect
+type ClaimMapping struct {
+	Subject       string
+	Email         string
+	EmailVerified string
+}
+
+// Provider is a client of the upstream OpenID Connect provider using authorization code flow with PKCE.
+// Metadata of the provider is discovered by the first request and its keys are fetched again by unknown key ID
+type Provider struct {
+	name         string
+	issuer       string
+	clientID     string
+	clientSecret string
+	scopes       []string
+	claims       ClaimMapping
+	client       *http.Client
+
+	mu       sync.Mutex
+	metadata *metadata
+	keys     map[string]*rsa.PublicKey
+}
+
+type metadata struct {
+	Issuer                string `json:"issuer"`
+	AuthorizationEndpoint string `json:"authorization_endpoint"`
+	TokenEndpoint         string `json:"token_endpoint"`
+	JWKSURI               string `json:"jwks_uri"`
+}
+
+type tokenResponse struct {
+	IDToken string `json:"id_token"`
+}
+
+type errorResponse struct {
+	Error string `json:"error"`
+}
+
+type jwks struct {
+	Keys []struct {
+		KeyType  string `json:"kty"`
+		KeyID    string `json:"kid"`
+		Modulus  string `json:"n"`
+		Exponent string `json:"e"`
+	} `json:"keys"`
+}
+
+// NewProvider returns client of the provider. openid scope is always requested
+func NewProvider(
+	name string,
+	issuer string,
+	clientID string,
+	clientSecret string,
+	scopes []string,
+	claims ClaimMapping,
+	client *http.Client,
+) *Provider {
+	if !slices.Contains(scopes, "openid") {
+		scopes = append([]string{"openid"}, scopes...)
+	}
+
+	if claims.Subject == "" {
+		claims.Subject = "sub"
+	}
+	if claims.Email == "" {
+		claims.Email = "email"
+	}
+	if claims.EmailVerified == "" {
+		claims.EmailVerified = "email_verified"
+	}
+
+	return &Provider{
+		name:         name,
+		issuer:       strings.TrimSuffix(issuer, "/"),
+		clientID:     clientID,
+		clientSecret: clientSecret,
+		scopes:       scopes,
+		claims:       claims,
+		client:       client,
+	}
+}
+
+// Name returns name of the provider in the config
+func (p *Provider) Name() string {
+	return p.name
+}
+
+// AuthCodeURL returns URL of the authorization request to the provider, the user is redirected to it.
+// Code challenge of the request is S256 hash of the verifier
+func (p *Provider) AuthCodeURL(ctx context.Context, redirectURI, state, nonce, codeVerifier string) (string, error) {
+	meta, err := p.discover(ctx)
+	if err != nil {
+		return "", err
+	}
+
+	u, err := url.Parse(meta.AuthorizationEndpoint)
+	if err != nil {
+		return "", fmt.Errorf("%w: %w", ErrDiscovery, err)
+	}
+
+	challenge := sha256.Sum256([]byte(codeVerifier))
+
+	query := u.Query()
+	query.Set("response_type", "code")
+	query.Set("client_id", p.clientID)
+	query.Set("redirect_uri", redirectURI)
+	query.Set("scope", strings.Join(p.scopes, " "))
+	query.Set("state", state)
+	query.Set("nonce", nonce)
+	query.Set("code_challenge", base64.RawURLEncoding.EncodeToString(challenge[:]))
+	query.Set("code_challenge_method", "S256")
+	u.RawQuery = query.Encode()
+
+	return u.String(), nil
+}
+
+// Exchange exchanges the code of the provider for ID token and returns the identity asserted by it.
+// ID token must be signed by the key of the provider, issued by it to the client and carry the nonce
+func (p *Provider) Exchange(
+	ctx context.Context,
+	code string,
+	redirectURI string,
+	codeVerifier string,
+	nonce string,
+) (models.ExternalIdentity, error) {
+	meta, err := p.discover(ctx)
+	if err != nil {
+		return models.ExternalIdentity{}, err
+	}
+
+	claims, err := p.idToken(ctx, meta, url.Values{
+		"grant_type":    {"authorization_code"},
+		"code":          {code},
+		"redirect_uri":  {redirectURI},
+		"code_verifier": {codeVerifier},
+	}, nonce)
+	if err != nil {
+		return models.ExternalIdentity{}, err
+	}
+
+	return p.identity(claims)
+}
+
+// PasswordGrant exchanges credentials of the user for ID token by the resource owner password credentials grant
+// and returns the identity asserted by it. Returns ErrInvalidGrant if the provider rejects the credentials
+func (p *Provider) PasswordGrant(ctx context.Context, username, password string) (models.ExternalIdentity, error) {
+	meta, err := p.discover(ctx)
+	if err != nil {
+		return models.ExternalIdentity{}, err
+	}
+
+	claims, err := p.idToken(ctx, meta, url.Values{
+		"grant_type": {"password"},
+		"username":   {username},
+		"password":   {password},
+		"scope":      {strings.Join(p.scopes, " ")},
+	}, "") // no browser to bind the nonce to
+
+	if err != nil {
+		return models.ExternalIdentity{}, err
+	}
+
+	return p.identity(claims)
+}
+
+// idToken requests ID token of the grant from the token endpoint and returns its verified claims
+func (p *Provider) idToken(ctx context.Context, meta *metadata, form url.Values, nonce string) (jwt.MapClaims, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, meta.TokenEndpoint, strings.NewReader(form.Encode()))
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrExchange, err)
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	// Client credentials are form-encoded before Basic encoding as RFC 6749 requires
+	req.SetBasicAuth(url.QueryEscape(p.clientID), url.QueryEscape(p.clientSecret))
+
+	var token tokenResponse
+	if err := p.do(req, &token); err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrExchange, err)
+	}
+
+	if token.IDToken == "" {
+		return nil, fmt.Errorf("%w: no ID token in the response", ErrExchange)
+	}
+
+	return p.verify(ctx, meta, token.IDToken, nonce)
+}
+
+// verify checks signature, issuer, audience, expiry and nonce of ID token
+func (p *Provider) verify(ctx context.Context, meta *metadata, idToken string, nonce string) (jwt.MapClaims, error) {
+	parsed, err := jwt.Parse(idToken, func(t *jwt.Token) (interface{}, error) {
+		kid, _ := t.Header["kid"].(string)
+
+		return p.key(ctx, meta, kid)
+	},
+		jwt.WithValidMethods([]string{jwt.SigningMethodRS256.Alg()}),
+		jwt.WithIssuer(meta.Issuer),
+		jwt.WithAudience(p.clientID),
+		jwt.WithExpirationRequired(),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrInvalidIDToken, err)
+	}
+
+	claims := parsed.Claims.(jwt.MapClaims)
+
+	if claimNonce, _ := claims["nonce"].(string); claimNonce != nonce {
+		return nil, fmt.Errorf("%w: nonce doesn't match", ErrInvalidIDToken)
+	}
+
+	return claims, nil
+}
+
+// identity maps claims of ID token to the identity, subject is required
+func (p *Provider) identity(claims jwt.MapClaims) (models.ExternalIdentity, error) {
+	identity := models.ExternalIdentity{Provider: p.name}
+
+	switch subject := claims[p.claims.Subject].(type) {
+	case string:
+		identity.Subject = subject
+	case float64:
+		identity.Subject = big.NewFloat(subject).Text('f', -1)
+	}
+
+	if identity.Subject == "" {
+		return models.ExternalIdentity{}, fmt.Errorf("%w: no %s claim", ErrInvalidIDToken, p.claims.Subject)
+	}
+
+	identity.Email, _ = claims[p.claims.Email].(string)
+
+	// Some providers send the flag as string
+	switch verified := claims[p.claims.EmailVerified].(type) {
+	case bool:
+		identity.EmailVerified = verified
+	case string:
+		identity.EmailVerified = verified == "true"
+	}
+
+	return identity, nil
+}
+
+// discover returns metadata of the provider, failed discovery is tried again by the next request
+func (p *Provider) discover(ctx context.Context) (*metadata, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if p.metadata != nil {
+		return p.metadata, nil
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, p.issuer+discoveryPath, nil)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrDiscovery, err)
+	}
+
+	var meta metadata
+	if err := p.do(req, &meta); err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrDiscovery, err)
+	}
+
+	// Issuer of the metadata must be the configured one, so tokens of other issuers aren't accepted
+	if meta.Issuer != p.issuer {
+		return nil, fmt.Errorf("%w: issuer %q doesn't match", ErrDiscovery, meta.Issuer)
+	}
+
+	if meta.AuthorizationEndpoint == "" || meta.TokenEndpoint == "" || meta.JWKSURI == "" {
+		return nil, fmt.Errorf("%w: endpoints are missing", ErrDiscovery)
+	}
+
+	p.metadata = &meta
+
+	return p.metadata, nil
+}
+
+// key returns public key of the provider by its ID, keys are fetched again if the ID is unknown
+func (p *Provider) key(ctx context.Context, meta *metadata, kid string) (*rsa.PublicKey, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if key, ok := p.keys[kid]; ok {
+		return key, nil
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, meta.JWKSURI, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	var set jwks
+	if err := p.do(req, &set); err != nil {
+		return nil, err
+	}
+
+	p.keys = make(map[string]*rsa.PublicKey, len(set.Keys))
+	for _, jwk := range set.Keys {
+		if jwk.KeyType != "RSA" {
+			continue
+		}
+
+		n, err := base64.RawURLEncoding.DecodeString(jwk.Modulus)
+		if err != nil {
+			continue
+		}
+
+		e, err := base64.RawURLEncoding.DecodeString(jwk.Exponent)
+		if err != nil {
+			continue
+		}
+
+		p.keys[jwk.KeyID] = &rsa.PublicKey{N: new(big.Int).SetBytes(n), E: int(new(big.Int).SetBytes(e).Int64())}
+	}
+
+	key, ok := p.keys[kid]
+	if !ok {
+		return nil, fmt.Errorf("unknown key %q", kid)
+	}
+
+	return key, nil
+}
+
+// do sends the request and decodes JSON response of successful status.
+// Returns ErrInvalidGrant if the provider answers with invalid_grant error
+func (p *Provider) do(req *http.Request, dest any) error {
+	req.Header.Set("Accept", "application/json")
+
+	resp, err := p.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(io.LimitReader(resp.Body, maxResponseSize))
+	if err != nil {
+		return err
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		var errResp errorResponse
+		if json.Unmarshal(body, &errResp) == nil && errResp.Error == "invalid_grant" {
+			return ErrInvalidGrant
+		}
+
+		return fmt.Errorf("status %d: %s", resp.StatusCode, body)
+	}
+
+	return json.Unmarshal(body, dest)
+}
@@ -0,0 +1,16 @@
+package random
+
+import (
+	"crypto/rand"
+	"encoding/base64"
+)
+
+// String returns URL-safe string made of n cryptographically random bytes
+func String(n int) (string, error) {
+	b := make([]byte, n)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
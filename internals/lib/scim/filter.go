@@ -0,0 +1,414 @@
+// Package scim implements filters and attribute paths of SCIM 2.0 (RFC 7644), for example
+//
+//	userName eq "bjensen@example.com" and (active eq true or not (emails pr))
+//	members[value eq "2819c223"]
+//
+// Attribute names and operators are case-insensitive, names are returned in lower case
+// without the URN of the core schemas
+package scim
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+var ErrSyntax = errors.New("syntax error")
+
+// Schemas of the core resources, attributes may be prefixed by them
+const (
+	UserSchema  = "urn:ietf:params:scim:schemas:core:2.0:User"
+	GroupSchema = "urn:ietf:params:scim:schemas:core:2.0:Group"
+)
+
+// Operators of the comparisons
+const (
+	OpEqual          = "eq"
+	OpNotEqual       = "ne"
+	OpContains       = "co"
+	OpStartsWith     = "sw"
+	OpEndsWith       = "ew"
+	OpPresent        = "pr"
+	OpGreater        = "gt"
+	OpGreaterOrEqual = "ge"
+	OpLess           = "lt"
+	OpLessOrEqual    = "le"
+)
+
+var compareOps = []string{
+	OpEqual, OpNotEqual, OpContains, OpStartsWith, OpEndsWith,
+	OpGreater, OpGreaterOrEqual, OpLess, OpLessOrEqual,
+}
+
+// Expr is the node of the filter: *Comparison, *Logical or *Not
+type Expr interface {
+	expr()
+}
+
+// Comparison compares the attribute with the value, value of OpPresent is nil.
+// Value is string, float64, bool or nil as it is decoded from JSON
+type Comparison struct {
+	Attr  string // dotted path of the attribute, sub-attribute of the value filter is prefixed by its attribute
+	Op    string
+	Value any
+}
+
+// Logical is "and" or "or" of the filters
+type Logical struct {
+	Op    string
+	Left  Expr
+	Right Expr
+}
+
+// Not negates the filter
+type Not struct {
+	Expr Expr
+}
+
+func (*Comparison) expr() {}
+func (*Logical) expr()    {}
+func (*Not) expr()        {}
+
+// Path is the attribute path of PATCH operation: attr[filter].subAttr, filter and sub-attribute are optional
+type Path struct {
+	Attr    string
+	Filter  Expr // attributes of the filter are prefixed by Attr
+	SubAttr string
+}
+
+// Parse parses the filter
+func Parse(filter string) (Expr, error) {
+	p, err := newParser(filter)
+	if err != nil {
+		return nil, err
+	}
+
+	expr, err := p.parseOr("")
+	if err != nil {
+		return nil, err
+	}
+
+	if tok := p.peek(); tok.kind != tokEOF {
+		return nil, syntaxError(tok.pos, "unexpected %q", tok.text)
+	}
+
+	return expr, nil
+}
+
+// ParsePath parses the attribute path of PATCH operation
+func ParsePath(path string) (Path, error) {
+	p, err := newParser(path)
+	if err != nil {
+		return Path{}, err
+	}
+
+	tok := p.next()
+	if tok.kind != tokAttr {
+		return Path{}, syntaxError(tok.pos, "expected attribute, got %q", tok.text)
+	}
+
+	result := Path{Attr: attrName(tok.text)}
+
+	if p.accept("[") {
+		if result.Filter, err = p.parseOr(result.Attr + "."); err != nil {
+			return Path{}, err
+		}
+
+		if err := p.expect("]"); err != nil {
+			return Path{}, err
+		}
+
+		// Sub-attribute follows the closing bracket as ".name" which is tokenized as the attribute
+		if tok := p.peek(); tok.kind == tokAttr && strings.HasPrefix(tok.text, ".") {
+			p.next()
+			result.SubAttr = strings.ToLower(tok.text[1:])
+		}
+	}
+
+	if tok := p.peek(); tok.kind != tokEOF {
+		return Path{}, syntaxError(tok.pos, "unexpected %q", tok.text)
+	}
+
+	return result, nil
+}
+
+// String returns the path in its canonical form, attribute is joined with the sub-attribute
+func (p Path) String() string {
+	if p.SubAttr != "" {
+		return p.Attr + "." + p.SubAttr
+	}
+
+	return p.Attr
+}
+
+// attrName returns lower case name of the attribute without URN of the core schemas
+func attrName(name string) string {
+	name = strings.ToLower(name)
+
+	for _, schema := range []string{UserSchema, GroupSchema} {
+		if prefix := strings.ToLower(schema) + ":"; strings.HasPrefix(name, prefix) {
+			return name[len(prefix):]
+		}
+	}
+
+	return name
+}
+
+type tokenKind int
+
+const (
+	tokEOF tokenKind = iota
+	tokAttr
+	tokValue
+	tokPunct
+)
+
+type token struct {
+	kind  tokenKind
+	text  string
+	value any
+	pos   int
+}
+
+func tokenize(src string) (tokens []token, err error) {
+	for i := 0; i < len(src); {
+		c := src[i]
+
+		switch {
+		case c == ' ' || c == '\t' || c == '\n' || c == '\r':
+			i++
+		case c == '"':
+			start := i
+
+			for i++; i < len(src) && src[i] != '"'; i++ {
+				if src[i] == '\\' {
+					i++
+				}
+			}
+
+			if i >= len(src) {
+				return nil, syntaxError(start, "unterminated string")
+			}
+			i++
+
+			var value string
+			if err := json.Unmarshal([]byte(src[start:i]), &value); err != nil {
+				return nil, syntaxError(start, "invalid string %s", src[start:i])
+			}
+
+			tokens = append(tokens, token{kind: tokValue, text: src[start:i], value: value, pos: start})
+		case c == '-' || isDigit(c):
+			start := i
+			i++
+
+			for i < len(src) && strings.IndexByte("0123456789.eE+-", src[i]) >= 0 {
+				i++
+			}
+
+			num, err := strconv.ParseFloat(src[start:i], 64)
+			if err != nil {
+				return nil, syntaxError(start, "invalid number %q", src[start:i])
+			}
+
+			tokens = append(tokens, token{kind: tokValue, text: src[start:i], value: num, pos: start})
+		case isNameChar(c) || c == '.':
+			start := i
+			for i < len(src) && (isNameChar(src[i]) || src[i] == '.' || src[i] == ':') {
+				i++
+			}
+
+			tokens = append(tokens, token{kind: tokAttr, text: src[start:i], pos: start})
+		case strings.IndexByte("()[]", c) >= 0:
+			tokens = append(tokens, token{kind: tokPunct, text: string(c), pos: i})
+			i++
+		default:
+			return nil, syntaxError(i, "unexpected character %q", c)
+		}
+	}
+
+	return append(tokens, token{kind: tokEOF, pos: len(src)}), nil
+}
+
+type parser struct {
+	tokens []token
+	pos    int
+}
+
+func newParser(src string) (*parser, error) {
+	tokens, err := tokenize(src)
+	if err != nil {
+		return nil, err
+	}
+
+	return &parser{tokens: tokens}, nil
+}
+
+func (p *parser) peek() token {
+	return p.tokens[p.pos]
+}
+
+func (p *parser) next() token {
+	tok := p.tokens[p.pos]
+	if tok.kind != tokEOF {
+		p.pos++
+	}
+
+	return tok
+}
+
+// accept skips the next token if it is the punctuation or the keyword, keywords are case-insensitive
+func (p *parser) accept(text string) bool {
+	tok := p.peek()
+	if (tok.kind == tokPunct || tok.kind == tokAttr) && strings.EqualFold(tok.text, text) {
+		p.pos++
+		return true
+	}
+
+	return false
+}
+
+func (p *parser) expect(text string) error {
+	if !p.accept(text) {
+		tok := p.peek()
+		return syntaxError(tok.pos, "expected %q, got %q", text, tok.text)
+	}
+
+	return nil
+}
+
+// parseOr parses the filter, prefix is added to the attributes inside of the value filter
+func (p *parser) parseOr(prefix string) (Expr, error) {
+	left, err := p.parseAnd(prefix)
+	if err != nil {
+		return nil, err
+	}
+
+	for p.accept("or") {
+		right, err := p.parseAnd(prefix)
+		if err != nil {
+			return nil, err
+		}
+
+		left = &Logical{Op: "or", Left: left, Right: right}
+	}
+
+	return left, nil
+}
+
+func (p *parser) parseAnd(prefix string) (Expr, error) {
+	left, err := p.parseUnary(prefix)
+	if err != nil {
+		return nil, err
+	}
+
+	for p.accept("and") {
+		right, err := p.parseUnary(prefix)
+		if err != nil {
+			return nil, err
+		}
+
+		left = &Logical{Op: "and", Left: left, Right: right}
+	}
+
+	return left, nil
+}
+
+func (p *parser) parseUnary(prefix string) (Expr, error) {
+	if p.accept("not") {
+		if err := p.expect("("); err != nil {
+			return nil, err
+		}
+
+		expr, err := p.parseOr(prefix)
+		if err != nil {
+			return nil, err
+		}
+
+		if err := p.expect(")"); err != nil {
+			return nil, err
+		}
+
+		return &Not{Expr: expr}, nil
+	}
+
+	if p.accept("(") {
+		expr, err := p.parseOr(prefix)
+		if err != nil {
+			return nil, err
+		}
+
+		if err := p.expect(")"); err != nil {
+			return nil, err
+		}
+
+		return expr, nil
+	}
+
+	return p.parseComparison(prefix)
+}
+
+func (p *parser) parseComparison(prefix string) (Expr, error) {
+	tok := p.next()
+	if tok.kind != tokAttr {
+		return nil, syntaxError(tok.pos, "expected attribute, got %q", tok.text)
+	}
+
+	attr := prefix + attrName(tok.text)
+
+	// Value filter of the multi-valued attribute, nested value filters are not allowed
+	if prefix == "" && p.accept("[") {
+		expr, err := p.parseOr(attr + ".")
+		if err != nil {
+			return nil, err
+		}
+
+		if err := p.expect("]"); err != nil {
+			return nil, err
+		}
+
+		return expr, nil
+	}
+
+	if p.accept(OpPresent) {
+		return &Comparison{Attr: attr, Op: OpPresent}, nil
+	}
+
+	opTok := p.next()
+	op := strings.ToLower(opTok.text)
+	if opTok.kind != tokAttr || !slices.Contains(compareOps, op) {
+		return nil, syntaxError(opTok.pos, "expected operator, got %q", opTok.text)
+	}
+
+	valueTok := p.next()
+
+	var value any
+	switch {
+	case valueTok.kind == tokValue:
+		value = valueTok.value
+	case valueTok.kind == tokAttr && strings.EqualFold(valueTok.text, "true"):
+		value = true
+	case valueTok.kind == tokAttr && strings.EqualFold(valueTok.text, "false"):
+		value = false
+	case valueTok.kind == tokAttr && strings.EqualFold(valueTok.text, "null"):
+		value = nil
+	default:
+		return nil, syntaxError(valueTok.pos, "expected value, got %q", valueTok.text)
+	}
+
+	return &Comparison{Attr: attr, Op: op, Value: value}, nil
+}
+
+func isDigit(c byte) bool {
+	return c >= '0' && c <= '9'
+}
+
+func isNameChar(c byte) bool {
+	return c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || isDigit(c) || c == '_' || c == '-' || c == '$'
+}
+
+func syntaxError(pos int, format string, args ...any) error {
+	return fmt.Errorf("%w at %d: %s", ErrSyntax, pos, fmt.Sprintf(format, args...))
+}
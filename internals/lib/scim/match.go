@@ -0,0 +1,69 @@
+package scim
+
+import (
+	"strings"
+)
+
+// Match reports whether the attributes match the filter, attributes are keyed by lower case names as in the filter.
+// Strings are compared case-insensitively, values of other types match only equal values
+func Match(expr Expr, attrs map[string]any) bool {
+	switch expr := expr.(type) {
+	case *Logical:
+		if expr.Op == "and" {
+			return Match(expr.Left, attrs) && Match(expr.Right, attrs)
+		}
+
+		return Match(expr.Left, attrs) || Match(expr.Right, attrs)
+	case *Not:
+		return !Match(expr.Expr, attrs)
+	case *Comparison:
+		return compare(expr, attrs[expr.Attr])
+	}
+
+	return false
+}
+
+func compare(c *Comparison, actual any) bool {
+	if c.Op == OpPresent {
+		return actual != nil && actual != ""
+	}
+
+	actualStr, isStr := actual.(string)
+	valueStr, valueIsStr := c.Value.(string)
+
+	if !isStr || !valueIsStr {
+		switch c.Op {
+		case OpEqual:
+			return actual == c.Value
+		case OpNotEqual:
+			return actual != c.Value
+		}
+
+		return false
+	}
+
+	actualStr, valueStr = strings.ToLower(actualStr), strings.ToLower(valueStr)
+
+	switch c.Op {
+	case OpEqual:
+		return actualStr == valueStr
+	case OpNotEqual:
+		return actualStr != valueStr
+	case OpContains:
+		return strings.Contains(actualStr, valueStr)
+	case OpStartsWith:
+		return strings.HasPrefix(actualStr, valueStr)
+	case OpEndsWith:
+		return strings.HasSuffix(actualStr, valueStr)
+	case OpGreater:
+		return actualStr > valueStr
+	case OpGreaterOrEqual:
+		return actualStr >= valueStr
+	case OpLess:
+		return actualStr < valueStr
+	case OpLessOrEqual:
+		return actualStr <= valueStr
+	}
+
+	return false
+}
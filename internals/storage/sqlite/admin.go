@@ -0,0 +1,319 @@
+package sqlite
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"github.com/nhassl3/sso-app/internals/domain/models"
+	"github.com/nhassl3/sso-app/internals/lib/logger/sl"
+	"github.com/nhassl3/sso-app/internals/storage"
+)
+
+const (
+	opSaveAdmin   = "storage.sqlite.SaveAdmin"
+	opDeleteAdmin = "storage.sqlite.DeleteAdmin"
+	opAdmins      = "storage.sqlite.Admins"
+	opHasAdmin    = "storage.sqlite.HasAdminRights"
+
+	opDeleteExpiredAdmins = "storage.sqlite.DeleteExpiredAdmins"
+
+	// activeAdmin is a condition of the admins rows which rights are not expired yet
+	activeAdmin = "(admins.expires_at IS NULL OR admins.expires_at > unixepoch())"
+)
+
+// HasAdminRights checks if user has admin rights in any application or is super-admin
+func (s *Storage) HasAdminRights(ctx context.Context, userID int64) (hasRights bool, err error) {
+	err = s.newSelect(
+		ctx,
+		`SELECT EXISTS(SELECT 1 FROM admins WHERE admins.user_id = ? AND `+activeAdmin+`)`,
+		[]interface{}{userID},
+		&hasRights,
+	)
+	if err != nil {
+		return false, sl.ErrUpLevel(opHasAdmin, err)
+	}
+
+	return
+}
+
+// SaveAdmin gives admin rights in the application to the user, zero app ID gives super-admin rights.
+// Rights expire after ttl, zero ttl gives permanent rights. Temporary rights are extended or made permanent.
+// Returns false if user already has these rights, otherwise saves audit event of the actor and the outbox event
+func (s *Storage) SaveAdmin(ctx context.Context, actorID, userID int64, appID int32, ttl time.Duration) (saved bool, err error) {
+	err = s.inTx(ctx, func(tx *sql.Tx) error {
+		if err := checkUserAndApp(ctx, tx, userID, appID); err != nil {
+			return err
+		}
+
+		saved, err = saveAdmin(ctx, tx, actorID, userID, appID, ttl)
+
+		return err
+	})
+	if err != nil {
+		return false, sl.ErrUpLevel(opSaveAdmin, err)
+	}
+
+	return
+}
+
+// DeleteAdmin takes away admin rights in the application from the user, zero app ID takes super-admin rights.
+// Returns false if user doesn't have these rights, otherwise saves audit event of the actor and the outbox event.
+// Last permanent super-admin of the system can't be deleted
+func (s *Storage) DeleteAdmin(ctx context.Context, actorID, userID int64, appID int32) (deleted bool, err error) {
+	err = s.inTx(ctx, func(tx *sql.Tx) error {
+		if appID == 0 {
+			last, err := lastSuperAdmin(ctx, tx, userID)
+			if err != nil {
+				return err
+			}
+
+			if last {
+				return storage.ErrLastSuperAdmin
+			}
+		}
+
+		res, err := tx.ExecContext(
+			ctx,
+			"DELETE FROM admins WHERE user_id = ? AND IFNULL(app_id, 0) = ?",
+			userID, appID,
+		)
+		if err != nil {
+			return err
+		}
+
+		affected, err := res.RowsAffected()
+		if err != nil {
+			return err
+		}
+
+		if deleted = affected > 0; !deleted {
+			return nil
+		}
+
+		err = saveAuditEvent(ctx, tx, models.AuditEvent{
+			ActorID:      actorID,
+			Action:       models.AuditActionRevokeAdmin,
+			TargetUserID: userID,
+			AppID:        appID,
+		})
+		if err != nil {
+			return err
+		}
+
+		return saveEvent(ctx, tx, models.Event{
+			Type:    models.EventUserAdminRevoked,
+			ActorID: actorID,
+			UserID:  userID,
+			AppID:   appID,
+		})
+	})
+	if err != nil {
+		return false, sl.ErrUpLevel(opDeleteAdmin, err)
+	}
+
+	return
+}
+
+// Admins returns admins which have rights in the application including super-admins.
+// Zero app ID returns admins of all applications
+func (s *Storage) Admins(ctx context.Context, appID int32) (admins []models.Admin, err error) {
+	rows, err := s.db.QueryContext(
+		ctx,
+		`SELECT admins.user_id, users.email, IFNULL(admins.app_id, 0), admins.expires_at FROM admins
+    JOIN users ON users.id = admins.user_id
+             WHERE (? = 0 OR admins.app_id = ? OR admins.app_id IS NULL) AND `+activeAdmin+`
+             ORDER BY admins.id`,
+		appID, appID,
+	)
+	if err != nil {
+		return nil, sl.ErrUpLevel(opAdmins, err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var (
+			admin     models.Admin
+			expiresAt sql.NullInt64
+		)
+
+		if err := rows.Scan(&admin.UserID, &admin.Email, &admin.AppID, &expiresAt); err != nil {
+			return nil, sl.ErrUpLevel(opAdmins, err)
+		}
+
+		if expiresAt.Valid {
+			admin.ExpiresAt = time.Unix(expiresAt.Int64, 0)
+		}
+
+		admins = append(admins, admin)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, sl.ErrUpLevel(opAdmins, err)
+	}
+
+	return
+}
+
+// DeleteExpiredAdmins deletes expired admin rights and saves audit and outbox events made by the system
+func (s *Storage) DeleteExpiredAdmins(ctx context.Context) (expired []models.Admin, err error) {
+	err = s.inTx(ctx, func(tx *sql.Tx) error {
+		rows, err := tx.QueryContext(
+			ctx,
+			"DELETE FROM admins WHERE expires_at <= unixepoch() RETURNING user_id, IFNULL(app_id, 0), expires_at",
+		)
+		if err != nil {
+			return err
+		}
+
+		for rows.Next() {
+			var (
+				admin     models.Admin
+				expiresAt int64
+			)
+
+			if err := rows.Scan(&admin.UserID, &admin.AppID, &expiresAt); err != nil {
+				rows.Close()
+				return err
+			}
+			admin.ExpiresAt = time.Unix(expiresAt, 0)
+
+			expired = append(expired, admin)
+		}
+		rows.Close()
+
+		if err := rows.Err(); err != nil {
+			return err
+		}
+
+		for _, admin := range expired {
+			err := saveAuditEvent(ctx, tx, models.AuditEvent{
+				Action:       models.AuditActionExpireAdmin,
+				TargetUserID: admin.UserID,
+				AppID:        admin.AppID,
+			})
+			if err != nil {
+				return err
+			}
+
+			err = saveEvent(ctx, tx, models.Event{
+				Type:   models.EventUserAdminRevoked,
+				UserID: admin.UserID,
+				AppID:  admin.AppID,
+			})
+			if err != nil {
+				return err
+			}
+		}
+
+		return nil
+	})
+	if err != nil {
+		return nil, sl.ErrUpLevel(opDeleteExpiredAdmins, err)
+	}
+
+	return
+}
+
+// saveAdmin gives admin rights in the transaction and saves audit event of the actor
+// and the outbox event if rights are changed
+func saveAdmin(ctx context.Context, tx *sql.Tx, actorID, userID int64, appID int32, ttl time.Duration) (saved bool, err error) {
+	var expiresAt sql.NullInt64
+	if ttl > 0 {
+		err := tx.QueryRowContext(ctx, "SELECT unixepoch() + ?", int64(ttl.Seconds())).Scan(&expiresAt)
+		if err != nil {
+			return false, err
+		}
+	}
+
+	// Permanent rights are kept as is, temporary ones are replaced by the longer or permanent rights
+	res, err := tx.ExecContext(
+		ctx,
+		`INSERT INTO admins (user_id, app_id, expires_at) VALUES (?, ?, ?)
+ON CONFLICT (user_id, IFNULL(app_id, 0)) DO UPDATE SET expires_at = excluded.expires_at
+WHERE admins.expires_at IS NOT NULL AND (excluded.expires_at IS NULL OR excluded.expires_at > admins.expires_at)`,
+		userID, nullAppID(appID), expiresAt,
+	)
+	if err != nil {
+		return false, err
+	}
+
+	affected, err := res.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+
+	if affected == 0 {
+		return false, nil
+	}
+
+	event := models.AuditEvent{
+		ActorID:      actorID,
+		Action:       models.AuditActionGrantAdmin,
+		TargetUserID: userID,
+		AppID:        appID,
+	}
+	if ttl > 0 {
+		event.Details = "expires in " + ttl.String()
+	}
+
+	if err := saveAuditEvent(ctx, tx, event); err != nil {
+		return false, err
+	}
+
+	return true, saveEvent(ctx, tx, models.Event{
+		Type:    models.EventUserAdminGranted,
+		ActorID: actorID,
+		UserID:  userID,
+		AppID:   appID,
+	})
+}
+
+// checkUserAndApp returns error if the user or non-zero application doesn't exist
+func checkUserAndApp(ctx context.Context, tx *sql.Tx, userID int64, appID int32) error {
+	var userExists, appExists bool
+
+	err := tx.QueryRowContext(
+		ctx,
+		`SELECT EXISTS(SELECT 1 FROM users WHERE id = ?),
+       ? = 0 OR EXISTS(SELECT 1 FROM apps WHERE id = ?)`,
+		userID, appID, appID,
+	).Scan(&userExists, &appExists)
+	if err != nil {
+		return err
+	}
+
+	if !userExists {
+		return storage.ErrUserNotFound
+	}
+
+	if !appExists {
+		return storage.ErrAppNotFound
+	}
+
+	return nil
+}
+
+// nullAppID converts zero application ID to NULL
+func nullAppID(appID int32) sql.NullInt32 {
+	return sql.NullInt32{Int32: appID, Valid: appID != 0}
+}
+
+// lastSuperAdmin reports whether the user is the last permanent super-admin of the system.
+// Temporary super-admins don't count, they are going to lose their rights anyway
+func lastSuperAdmin(ctx context.Context, tx *sql.Tx, userID int64) (bool, error) {
+	var isSuperAdmin bool
+	var superAdmins int
+
+	err := tx.QueryRowContext(
+		ctx,
+		`SELECT EXISTS(SELECT 1 FROM admins WHERE user_id = ? AND app_id IS NULL AND expires_at IS NULL),
+       (SELECT COUNT(*) FROM admins WHERE app_id IS NULL AND expires_at IS NULL)`,
+		userID,
+	).Scan(&isSuperAdmin, &superAdmins)
+	if err != nil {
+		return false, err
+	}
+
+	return isSuperAdmin && superAdmins <= 1, nil
+}
@@ -0,0 +1,381 @@
+package sqlite
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/nhassl3/sso-app/internals/domain/models"
+	"github.com/nhassl3/sso-app/internals/lib/logger/sl"
+	"github.com/nhassl3/sso-app/internals/storage"
+)
+
+const (
+	opSaveApp        = "storage.sqlite.SaveApp"
+	opUpdateApp      = "storage.sqlite.UpdateApp"
+	opSetAppDisabled = "storage.sqlite.SetAppDisabled"
+	opDeleteApp      = "storage.sqlite.DeleteApp"
+	opApps           = "storage.sqlite.Apps"
+
+	opSetClientSecret = "storage.sqlite.SetClientSecret"
+)
+
+// appScopedTables are tables with rows which belong to some application,
+// they are cleaned up together with the application
+var appScopedTables = []string{
+	"user_roles",
+	"role_scopes",
+	"admins",
+	"relation_tuples",
+	"namespace_configs",
+	"permission_revisions",
+	"abac_policies",
+	"policy_decisions",
+	"elevation_requests",
+	"authorization_codes",
+	"device_codes",
+	"refresh_tokens",
+	"consents",
+	"sessions",
+	"logout_deliveries",
+	"scim_groups",
+	"webhook_subscriptions",
+	"webhook_deliveries",
+}
+
+// SaveApp saves new application in the system, audit event of the actor and the outbox event
+func (s *Storage) SaveApp(ctx context.Context, actorID int64, app models.App) (appID int32, err error) {
+	err = s.inTx(ctx, func(tx *sql.Tx) error {
+		settings, err := settingsValues(app.AppSettings)
+		if err != nil {
+			return err
+		}
+
+		res, err := tx.ExecContext(
+			ctx,
+			"INSERT INTO apps (name, secret, "+settingsSelect+") VALUES (?, ?, "+settingsPlaceholders+")",
+			append([]any{app.Name, app.Secret}, settings...)...,
+		)
+		if err != nil {
+			return appErr(err)
+		}
+
+		id, err := res.LastInsertId()
+		if err != nil {
+			return err
+		}
+		appID = int32(id)
+
+		err = saveAuditEvent(ctx, tx, models.AuditEvent{
+			ActorID: actorID,
+			Action:  models.AuditActionCreateApp,
+			AppID:   appID,
+			Details: app.Name,
+		})
+		if err != nil {
+			return err
+		}
+
+		return saveEvent(ctx, tx, models.Event{Type: models.EventAppCreated, ActorID: actorID, AppID: appID})
+	})
+	if err != nil {
+		return 0, sl.ErrUpLevel(opSaveApp, err)
+	}
+
+	return
+}
+
+// UpdateApp updates name and settings of the application and saves audit event of the actor and the outbox event
+func (s *Storage) UpdateApp(ctx context.Context, actorID int64, app models.App) error {
+	err := s.inTx(ctx, func(tx *sql.Tx) error {
+		settings, err := settingsValues(app.AppSettings)
+		if err != nil {
+			return err
+		}
+
+		res, err := tx.ExecContext(
+			ctx,
+			"UPDATE apps SET name = ?, "+settingsAssignments+" WHERE id = ?",
+			append(append([]any{app.Name}, settings...), app.ID)...,
+		)
+		if err != nil {
+			return appErr(err)
+		}
+
+		if err := mustAffect(res, storage.ErrAppNotFound); err != nil {
+			return err
+		}
+
+		err = saveAuditEvent(ctx, tx, models.AuditEvent{
+			ActorID: actorID,
+			Action:  models.AuditActionUpdateApp,
+			AppID:   int32(app.ID),
+			Details: app.Name,
+		})
+		if err != nil {
+			return err
+		}
+
+		return saveEvent(ctx, tx, models.Event{Type: models.EventAppUpdated, ActorID: actorID, AppID: int32(app.ID)})
+	})
+	if err != nil {
+		return sl.ErrUpLevel(opUpdateApp, err)
+	}
+
+	return nil
+}
+
+// SetAppDisabled disables or enables the application and saves audit event of the actor and the outbox event
+func (s *Storage) SetAppDisabled(ctx context.Context, actorID int64, appID int32, disabled bool) error {
+	err := s.inTx(ctx, func(tx *sql.Tx) error {
+		res, err := tx.ExecContext(ctx, "UPDATE apps SET disabled = ? WHERE id = ?", disabled, appID)
+		if err != nil {
+			return err
+		}
+
+		if err := mustAffect(res, storage.ErrAppNotFound); err != nil {
+			return err
+		}
+
+		action, eventType := models.AuditActionEnableApp, models.EventAppEnabled
+		if disabled {
+			action, eventType = models.AuditActionDisableApp, models.EventAppDisabled
+		}
+
+		err = saveAuditEvent(ctx, tx, models.AuditEvent{
+			ActorID: actorID,
+			Action:  action,
+			AppID:   appID,
+		})
+		if err != nil {
+			return err
+		}
+
+		return saveEvent(ctx, tx, models.Event{Type: eventType, ActorID: actorID, AppID: appID})
+	})
+	if err != nil {
+		return sl.ErrUpLevel(opSetAppDisabled, err)
+	}
+
+	return nil
+}
+
+// DeleteApp deletes the application with all rows which belong to it
+// and saves audit event of the actor and the outbox event
+func (s *Storage) DeleteApp(ctx context.Context, actorID int64, appID int32) error {
+	err := s.inTx(ctx, func(tx *sql.Tx) error {
+		for _, table := range appScopedTables {
+			if _, err := tx.ExecContext(ctx, fmt.Sprintf("DELETE FROM %s WHERE app_id = ?", table), appID); err != nil {
+				return err
+			}
+		}
+
+		res, err := tx.ExecContext(ctx, "DELETE FROM apps WHERE id = ?", appID)
+		if err != nil {
+			return err
+		}
+
+		if err := mustAffect(res, storage.ErrAppNotFound); err != nil {
+			return err
+		}
+
+		err = saveAuditEvent(ctx, tx, models.AuditEvent{
+			ActorID: actorID,
+			Action:  models.AuditActionDeleteApp,
+			AppID:   appID,
+		})
+		if err != nil {
+			return err
+		}
+
+		return saveEvent(ctx, tx, models.Event{Type: models.EventAppDeleted, ActorID: actorID, AppID: appID})
+	})
+	if err != nil {
+		return sl.ErrUpLevel(opDeleteApp, err)
+	}
+
+	return nil
+}
+
+// Apps returns page of applications ordered by ID which go after the given application ID.
+// Secrets of the applications are not returned
+func (s *Storage) Apps(ctx context.Context, afterID int32, limit int) (apps []models.App, err error) {
+	rows, err := s.db.QueryContext(
+		ctx,
+		`SELECT id, name, disabled, `+settingsSelect+` FROM apps
+WHERE id > ?
+ORDER BY id
+LIMIT ?`,
+		afterID, limit,
+	)
+	if err != nil {
+		return nil, sl.ErrUpLevel(opApps, err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var app models.App
+
+		settings := scanSettings(&app.AppSettings)
+
+		if err := rows.Scan(append([]any{&app.ID, &app.Name, &app.Disabled}, settings.dest()...)...); err != nil {
+			return nil, sl.ErrUpLevel(opApps, err)
+		}
+
+		if err := settings.decode(); err != nil {
+			return nil, sl.ErrUpLevel(opApps, err)
+		}
+
+		apps = append(apps, app)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, sl.ErrUpLevel(opApps, err)
+	}
+
+	return
+}
+
+// checkApp returns error if the application doesn't exist
+func checkApp(ctx context.Context, tx *sql.Tx, appID int32) error {
+	var exists bool
+
+	err := tx.QueryRowContext(ctx, "SELECT EXISTS(SELECT 1 FROM apps WHERE id = ?)", appID).Scan(&exists)
+	if err != nil {
+		return err
+	}
+
+	if !exists {
+		return storage.ErrAppNotFound
+	}
+
+	return nil
+}
+
+// appErr converts unique constraint error of the apps table to storage error
+func appErr(err error) error {
+	return uniqueErr(err, storage.ErrAppExists)
+}
+
+// SetClientSecret replaces hash of the OAuth client secret of the application and saves audit event of the actor
+func (s *Storage) SetClientSecret(ctx context.Context, actorID int64, appID int32, secretHash []byte) error {
+	err := s.inTx(ctx, func(tx *sql.Tx) error {
+		res, err := tx.ExecContext(ctx, "UPDATE apps SET client_secret_hash = ? WHERE id = ?", secretHash, appID)
+		if err != nil {
+			return err
+		}
+
+		if err := mustAffect(res, storage.ErrAppNotFound); err != nil {
+			return err
+		}
+
+		return saveAuditEvent(ctx, tx, models.AuditEvent{
+			ActorID: actorID,
+			Action:  models.AuditActionRotateClientSecret,
+			AppID:   appID,
+		})
+	})
+	if err != nil {
+		return sl.ErrUpLevel(opSetClientSecret, err)
+	}
+
+	return nil
+}
+
+// settingsColumns are columns of the apps table with settings of the application
+var settingsColumns = []string{
+	"embed_roles", "embed_scope", "max_claims_size", "redirect_uris", "client_scopes",
+	"grant_types", "allowed_scopes", "access_token_ttl", "refresh_token_ttl", "public_client",
+	"third_party", "backchannel_logout_uri", "theme",
+}
+
+var (
+	settingsSelect       = strings.Join(settingsColumns, ", ")
+	settingsAssignments  = strings.Join(settingsColumns, " = ?, ") + " = ?"
+	settingsPlaceholders = strings.TrimSuffix(strings.Repeat("?, ", len(settingsColumns)), ", ")
+)
+
+// settingsValues returns values of the settings columns.
+// Lists are encoded as JSON arrays, nil lists are kept as empty arrays, theme is encoded as JSON object, TTLs are kept in seconds
+func settingsValues(settings models.AppSettings) ([]any, error) {
+	values := []any{settings.EmbedRoles, settings.EmbedScope, settings.MaxClaimsSize}
+
+	for _, list := range [][]string{settings.RedirectURIs, settings.ClientScopes, settings.GrantTypes, settings.AllowedScopes} {
+		if list == nil {
+			list = []string{}
+		}
+
+		raw, err := json.Marshal(list)
+		if err != nil {
+			return nil, err
+		}
+
+		values = append(values, string(raw))
+	}
+
+	theme, err := json.Marshal(settings.Theme)
+	if err != nil {
+		return nil, err
+	}
+
+	return append(
+		values,
+		int64(settings.AccessTokenTTL.Seconds()), int64(settings.RefreshTokenTTL.Seconds()), settings.PublicClient,
+		settings.ThirdParty, settings.BackchannelLogoutURI, string(theme),
+	), nil
+}
+
+// settingsScanner scans settings columns into the settings of the application
+type settingsScanner struct {
+	settings   *models.AppSettings
+	lists      [4]string
+	accessTTL  int64
+	refreshTTL int64
+	theme      string
+}
+
+func scanSettings(settings *models.AppSettings) *settingsScanner {
+	return &settingsScanner{settings: settings}
+}
+
+// dest returns destinations of the settings columns for Scan
+func (s *settingsScanner) dest() []any {
+	return []any{
+		&s.settings.EmbedRoles, &s.settings.EmbedScope, &s.settings.MaxClaimsSize,
+		&s.lists[0], &s.lists[1], &s.lists[2], &s.lists[3],
+		&s.accessTTL, &s.refreshTTL, &s.settings.PublicClient,
+		&s.settings.ThirdParty, &s.settings.BackchannelLogoutURI, &s.theme,
+	}
+}
+
+// decode decodes scanned lists, TTLs and theme into the settings
+func (s *settingsScanner) decode() error {
+	lists := []*[]string{&s.settings.RedirectURIs, &s.settings.ClientScopes, &s.settings.GrantTypes, &s.settings.AllowedScopes}
+	for i, list := range lists {
+		if err := json.Unmarshal([]byte(s.lists[i]), list); err != nil {
+			return err
+		}
+	}
+
+	s.settings.AccessTokenTTL = time.Duration(s.accessTTL) * time.Second
+	s.settings.RefreshTokenTTL = time.Duration(s.refreshTTL) * time.Second
+
+	return json.Unmarshal([]byte(s.theme), &s.settings.Theme)
+}
+
+// mustAffect returns notFound error if the statement didn't affect any row
+func mustAffect(res sql.Result, notFound error) error {
+	affected, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if affected == 0 {
+		return notFound
+	}
+
+	return nil
+}
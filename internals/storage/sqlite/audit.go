@@ -0,0 +1,75 @@
+package sqlite
+
+import (
+	"context"
+	"database/sql"
+
+	"github.com/nhassl3/sso-app/internals/domain/models"
+	"github.com/nhassl3/sso-app/internals/lib/logger/sl"
+)
+
+const (
+	opAuditEvents    = "storage.sqlite.AuditEvents"
+	opSaveAuditEvent = "storage.sqlite.SaveAuditEvent"
+)
+
+// AuditEvents returns the latest audit events of the application, zero app ID returns events of all applications
+func (s *Storage) AuditEvents(ctx context.Context, appID int32, limit int) (events []models.AuditEvent, err error) {
+	rows, err := s.db.QueryContext(
+		ctx,
+		`SELECT id, actor_id, action, IFNULL(target_user_id, 0), IFNULL(app_id, 0), details, created_at
+FROM audit_events
+WHERE ? = 0 OR app_id = ?
+ORDER BY id DESC
+LIMIT ?`,
+		appID, appID, limit,
+	)
+	if err != nil {
+		return nil, sl.ErrUpLevel(opAuditEvents, err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var event models.AuditEvent
+
+		err := rows.Scan(
+			&event.ID, &event.ActorID, &event.Action, &event.TargetUserID,
+			&event.AppID, &event.Details, &event.CreatedAt,
+		)
+		if err != nil {
+			return nil, sl.ErrUpLevel(opAuditEvents, err)
+		}
+
+		events = append(events, event)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, sl.ErrUpLevel(opAuditEvents, err)
+	}
+
+	return
+}
+
+// SaveAuditEvent saves audit event of the action which changes nothing in the storage
+func (s *Storage) SaveAuditEvent(ctx context.Context, event models.AuditEvent) error {
+	err := s.inTx(ctx, func(tx *sql.Tx) error {
+		return saveAuditEvent(ctx, tx, event)
+	})
+	if err != nil {
+		return sl.ErrUpLevel(opSaveAuditEvent, err)
+	}
+
+	return nil
+}
+
+// saveAuditEvent saves audit event in the transaction of the change it describes
+func saveAuditEvent(ctx context.Context, tx *sql.Tx, event models.AuditEvent) error {
+	_, err := tx.ExecContext(
+		ctx,
+		"INSERT INTO audit_events (actor_id, action, target_user_id, app_id, details) VALUES (?, ?, ?, ?, ?)",
+		event.ActorID, event.Action, sql.NullInt64{Int64: event.TargetUserID, Valid: event.TargetUserID != 0},
+		nullAppID(event.AppID), event.Details,
+	)
+
+	return err
+}
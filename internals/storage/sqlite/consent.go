@@ -0,0 +1,127 @@
+package sqlite
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/nhassl3/sso-app/internals/domain/models"
+	"github.com/nhassl3/sso-app/internals/lib/logger/sl"
+	"github.com/nhassl3/sso-app/internals/storage"
+)
+
+const (
+	opConsent       = "storage.sqlite.Consent"
+	opSaveConsent   = "storage.sqlite.SaveConsent"
+	opConsents      = "storage.sqlite.Consents"
+	opDeleteConsent = "storage.sqlite.DeleteConsent"
+)
+
+// Consent returns consent of the user to the application
+func (s *Storage) Consent(ctx context.Context, userID int64, appID int32) (consent models.Consent, err error) {
+	var (
+		scope     string
+		grantedAt int64
+	)
+
+	err = s.newSelect(
+		ctx,
+		`SELECT consents.user_id, consents.app_id, apps.name, consents.scope, consents.granted_at
+FROM consents JOIN apps ON apps.id = consents.app_id
+WHERE consents.user_id = ? AND consents.app_id = ?`,
+		[]interface{}{userID, appID},
+		&consent.UserID, &consent.AppID, &consent.AppName, &scope, &grantedAt,
+	)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return models.Consent{}, sl.ErrUpLevel(opConsent, storage.ErrConsentNotFound)
+		}
+
+		return models.Consent{}, sl.ErrUpLevel(opConsent, err)
+	}
+
+	consent.Scopes = strings.Fields(scope)
+	consent.GrantedAt = time.Unix(grantedAt, 0)
+
+	return
+}
+
+// SaveConsent saves consent of the user to the application, previous consent is replaced
+func (s *Storage) SaveConsent(ctx context.Context, consent models.Consent) error {
+	_, err := s.db.ExecContext(
+		ctx,
+		`INSERT INTO consents (user_id, app_id, scope, granted_at) VALUES (?, ?, ?, ?)
+ON CONFLICT (user_id, app_id) DO UPDATE SET scope = excluded.scope, granted_at = excluded.granted_at`,
+		consent.UserID, consent.AppID, strings.Join(consent.Scopes, " "), consent.GrantedAt.Unix(),
+	)
+	if err != nil {
+		return sl.ErrUpLevel(opSaveConsent, err)
+	}
+
+	return nil
+}
+
+// Consents returns consents of the user ordered by application ID
+func (s *Storage) Consents(ctx context.Context, userID int64) (consents []models.Consent, err error) {
+	rows, err := s.db.QueryContext(
+		ctx,
+		`SELECT consents.app_id, apps.name, consents.scope, consents.granted_at
+FROM consents JOIN apps ON apps.id = consents.app_id
+WHERE consents.user_id = ?
+ORDER BY consents.app_id`,
+		userID,
+	)
+	if err != nil {
+		return nil, sl.ErrUpLevel(opConsents, err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var (
+			scope     string
+			grantedAt int64
+		)
+
+		consent := models.Consent{UserID: userID}
+		if err := rows.Scan(&consent.AppID, &consent.AppName, &scope, &grantedAt); err != nil {
+			return nil, sl.ErrUpLevel(opConsents, err)
+		}
+
+		consent.Scopes = strings.Fields(scope)
+		consent.GrantedAt = time.Unix(grantedAt, 0)
+
+		consents = append(consents, consent)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, sl.ErrUpLevel(opConsents, err)
+	}
+
+	return
+}
+
+// DeleteConsent deletes consent of the user to the application together with refresh tokens
+// the application got by it
+func (s *Storage) DeleteConsent(ctx context.Context, userID int64, appID int32) error {
+	err := s.inTx(ctx, func(tx *sql.Tx) error {
+		res, err := tx.ExecContext(ctx, "DELETE FROM consents WHERE user_id = ? AND app_id = ?", userID, appID)
+		if err != nil {
+			return err
+		}
+
+		if err := mustAffect(res, storage.ErrConsentNotFound); err != nil {
+			return err
+		}
+
+		_, err = tx.ExecContext(ctx, "DELETE FROM refresh_tokens WHERE user_id = ? AND app_id = ?", userID, appID)
+
+		return err
+	})
+	if err != nil {
+		return sl.ErrUpLevel(opDeleteConsent, err)
+	}
+
+	return nil
+}
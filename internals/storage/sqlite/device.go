@@ -0,0 +1,153 @@
+package sqlite
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+
+	"github.com/mattn/go-sqlite3"
+	"github.com/nhassl3/sso-app/internals/domain/models"
+	"github.com/nhassl3/sso-app/internals/lib/logger/sl"
+	"github.com/nhassl3/sso-app/internals/storage"
+)
+
+const (
+	opSaveDeviceCode     = "storage.sqlite.SaveDeviceCode"
+	opDecideDeviceCode   = "storage.sqlite.DecideDeviceCode"
+	opPollDeviceCode     = "storage.sqlite.PollDeviceCode"
+	opSlowDownDeviceCode = "storage.sqlite.SlowDownDeviceCode"
+)
+
+// SaveDeviceCode saves pending device code by hash of the code and deletes expired codes
+func (s *Storage) SaveDeviceCode(ctx context.Context, codeHash string, code models.DeviceCode) error {
+	err := s.inTx(ctx, func(tx *sql.Tx) error {
+		if _, err := tx.ExecContext(ctx, "DELETE FROM device_codes WHERE expires_at <= unixepoch()"); err != nil {
+			return err
+		}
+
+		_, err := tx.ExecContext(
+			ctx,
+			`INSERT INTO device_codes (device_code_hash, user_code, app_id, scope, status, poll_interval, expires_at)
+VALUES (?, ?, ?, ?, ?, ?, ?)`,
+			codeHash, code.UserCode, code.AppID, code.Scope, models.DevicePending,
+			int64(code.Interval.Seconds()), code.ExpiresAt.Unix(),
+		)
+
+		return err
+	})
+	if err != nil {
+		var sqliteErr sqlite3.Error
+		if errors.As(err, &sqliteErr) && errors.Is(sqliteErr.ExtendedCode, sqlite3.ErrConstraintUnique) {
+			return sl.ErrUpLevel(opSaveDeviceCode, storage.ErrUserCodeExists)
+		}
+
+		return sl.ErrUpLevel(opSaveDeviceCode, err)
+	}
+
+	return nil
+}
+
+// DecideDeviceCode approves or denies pending not expired device code by its user code and returns the code
+func (s *Storage) DecideDeviceCode(
+	ctx context.Context,
+	userCode string,
+	userID int64,
+	status string,
+) (code models.DeviceCode, err error) {
+	var interval, lastPolledAt, expiresAt int64
+
+	err = s.db.QueryRowContext(
+		ctx,
+		`UPDATE device_codes SET status = ?, user_id = ?
+WHERE user_code = ? AND status = ? AND expires_at > unixepoch()
+RETURNING app_id, user_code, scope, status, user_id, poll_interval, last_polled_at, expires_at`,
+		status, userID, userCode, models.DevicePending,
+	).Scan(
+		&code.AppID, &code.UserCode, &code.Scope, &code.Status, &code.UserID,
+		&interval, &lastPolledAt, &expiresAt,
+	)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return models.DeviceCode{}, sl.ErrUpLevel(opDecideDeviceCode, storage.ErrDeviceCodeNotFound)
+		}
+
+		return models.DeviceCode{}, sl.ErrUpLevel(opDecideDeviceCode, err)
+	}
+
+	return deviceCode(code, interval, lastPolledAt, expiresAt), nil
+}
+
+// PollDeviceCode returns device code of the application by hash of the code as it was before the poll
+// and remembers time of the poll. Decided and expired codes are deleted, so every decision is returned once
+func (s *Storage) PollDeviceCode(
+	ctx context.Context,
+	appID int32,
+	codeHash string,
+	polledAt time.Time,
+) (code models.DeviceCode, err error) {
+	var interval, lastPolledAt, expiresAt int64
+
+	err = s.inTx(ctx, func(tx *sql.Tx) error {
+		err := tx.QueryRowContext(
+			ctx,
+			`SELECT app_id, user_code, scope, status, user_id, poll_interval, last_polled_at, expires_at
+FROM device_codes WHERE device_code_hash = ? AND app_id = ?`,
+			codeHash, appID,
+		).Scan(
+			&code.AppID, &code.UserCode, &code.Scope, &code.Status, &code.UserID,
+			&interval, &lastPolledAt, &expiresAt,
+		)
+		if err != nil {
+			if errors.Is(err, sql.ErrNoRows) {
+				return storage.ErrDeviceCodeNotFound
+			}
+
+			return err
+		}
+
+		if code.Status != models.DevicePending || expiresAt <= polledAt.Unix() {
+			_, err = tx.ExecContext(ctx, "DELETE FROM device_codes WHERE device_code_hash = ?", codeHash)
+			return err
+		}
+
+		_, err = tx.ExecContext(
+			ctx,
+			"UPDATE device_codes SET last_polled_at = ? WHERE device_code_hash = ?",
+			polledAt.UnixMilli(), codeHash,
+		)
+
+		return err
+	})
+	if err != nil {
+		return models.DeviceCode{}, sl.ErrUpLevel(opPollDeviceCode, err)
+	}
+
+	return deviceCode(code, interval, lastPolledAt, expiresAt), nil
+}
+
+// SlowDownDeviceCode sets new minimal interval between polls of the device code
+func (s *Storage) SlowDownDeviceCode(ctx context.Context, codeHash string, interval time.Duration) error {
+	_, err := s.db.ExecContext(
+		ctx,
+		"UPDATE device_codes SET poll_interval = ? WHERE device_code_hash = ?",
+		int64(interval.Seconds()), codeHash,
+	)
+	if err != nil {
+		return sl.ErrUpLevel(opSlowDownDeviceCode, err)
+	}
+
+	return nil
+}
+
+// deviceCode fills times of the device code scanned as integers
+func deviceCode(code models.DeviceCode, interval, lastPolledAt, expiresAt int64) models.DeviceCode {
+	code.Interval = time.Duration(interval) * time.Second
+	code.ExpiresAt = time.Unix(expiresAt, 0)
+
+	if lastPolledAt != 0 {
+		code.LastPolledAt = time.UnixMilli(lastPolledAt)
+	}
+
+	return code
+}
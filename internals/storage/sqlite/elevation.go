@@ -0,0 +1,175 @@
+package sqlite
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+
+	"github.com/nhassl3/sso-app/internals/domain/models"
+	"github.com/nhassl3/sso-app/internals/lib/logger/sl"
+	"github.com/nhassl3/sso-app/internals/storage"
+)
+
+const (
+	opSaveElevationRequest = "storage.sqlite.SaveElevationRequest"
+	opElevationRequest     = "storage.sqlite.ElevationRequest"
+	opElevationRequests    = "storage.sqlite.ElevationRequests"
+	opDecideElevation      = "storage.sqlite.DecideElevation"
+
+	elevationColumns = "id, user_id, IFNULL(app_id, 0), ttl, reason, status, IFNULL(decided_by, 0), created_at"
+)
+
+// SaveElevationRequest saves pending request of the user for temporary admin rights and audit event of the user
+func (s *Storage) SaveElevationRequest(ctx context.Context, request models.ElevationRequest) (requestID int64, err error) {
+	err = s.inTx(ctx, func(tx *sql.Tx) error {
+		if err := checkUserAndApp(ctx, tx, request.UserID, request.AppID); err != nil {
+			return err
+		}
+
+		res, err := tx.ExecContext(
+			ctx,
+			"INSERT INTO elevation_requests (user_id, app_id, ttl, reason) VALUES (?, ?, ?, ?)",
+			request.UserID, nullAppID(request.AppID), int64(request.TTL.Seconds()), request.Reason,
+		)
+		if err != nil {
+			return err
+		}
+
+		requestID, err = res.LastInsertId()
+		if err != nil {
+			return err
+		}
+
+		return saveAuditEvent(ctx, tx, models.AuditEvent{
+			ActorID:      request.UserID,
+			Action:       models.AuditActionRequestElevation,
+			TargetUserID: request.UserID,
+			AppID:        request.AppID,
+			Details:      request.Reason,
+		})
+	})
+	if err != nil {
+		return 0, sl.ErrUpLevel(opSaveElevationRequest, err)
+	}
+
+	return
+}
+
+// ElevationRequest returns request for temporary admin rights by its ID
+func (s *Storage) ElevationRequest(ctx context.Context, requestID int64) (request models.ElevationRequest, err error) {
+	request, err = scanElevationRequest(s.db.QueryRowContext(
+		ctx,
+		"SELECT "+elevationColumns+" FROM elevation_requests WHERE id = ?",
+		requestID,
+	))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return models.ElevationRequest{}, sl.ErrUpLevel(opElevationRequest, storage.ErrElevationNotFound)
+		}
+
+		return models.ElevationRequest{}, sl.ErrUpLevel(opElevationRequest, err)
+	}
+
+	return
+}
+
+// ElevationRequests returns requests for temporary admin rights in the application,
+// zero app ID returns requests of all applications
+func (s *Storage) ElevationRequests(ctx context.Context, appID int32, pendingOnly bool) (requests []models.ElevationRequest, err error) {
+	rows, err := s.db.QueryContext(
+		ctx,
+		"SELECT "+elevationColumns+` FROM elevation_requests
+WHERE (? = 0 OR app_id = ?) AND (NOT ? OR status = ?)
+ORDER BY id`,
+		appID, appID, pendingOnly, models.ElevationPending,
+	)
+	if err != nil {
+		return nil, sl.ErrUpLevel(opElevationRequests, err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		request, err := scanElevationRequest(rows)
+		if err != nil {
+			return nil, sl.ErrUpLevel(opElevationRequests, err)
+		}
+
+		requests = append(requests, request)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, sl.ErrUpLevel(opElevationRequests, err)
+	}
+
+	return
+}
+
+// DecideElevation approves or denies pending request for temporary admin rights and saves audit event of the actor.
+// Approved request gives the rights to the user for requested time
+func (s *Storage) DecideElevation(ctx context.Context, actorID, requestID int64, approve bool) (request models.ElevationRequest, err error) {
+	status, action := models.ElevationDenied, models.AuditActionDenyElevation
+	if approve {
+		status, action = models.ElevationApproved, models.AuditActionApproveElevation
+	}
+
+	err = s.inTx(ctx, func(tx *sql.Tx) error {
+		request, err = scanElevationRequest(tx.QueryRowContext(
+			ctx,
+			"UPDATE elevation_requests SET status = ?, decided_by = ? WHERE id = ? AND status = ? RETURNING "+elevationColumns,
+			status, actorID, requestID, models.ElevationPending,
+		))
+		if err != nil {
+			if !errors.Is(err, sql.ErrNoRows) {
+				return err
+			}
+
+			var exists bool
+			err := tx.QueryRowContext(ctx, "SELECT EXISTS(SELECT 1 FROM elevation_requests WHERE id = ?)", requestID).Scan(&exists)
+			if err != nil {
+				return err
+			}
+
+			if exists {
+				return storage.ErrElevationNotPending
+			}
+
+			return storage.ErrElevationNotFound
+		}
+
+		if approve {
+			if _, err := saveAdmin(ctx, tx, actorID, request.UserID, request.AppID, request.TTL); err != nil {
+				return err
+			}
+		}
+
+		return saveAuditEvent(ctx, tx, models.AuditEvent{
+			ActorID:      actorID,
+			Action:       action,
+			TargetUserID: request.UserID,
+			AppID:        request.AppID,
+			Details:      request.Reason,
+		})
+	})
+	if err != nil {
+		return models.ElevationRequest{}, sl.ErrUpLevel(opDecideElevation, err)
+	}
+
+	return
+}
+
+type rowScanner interface {
+	Scan(dest ...interface{}) error
+}
+
+func scanElevationRequest(row rowScanner) (request models.ElevationRequest, err error) {
+	var ttl int64
+
+	err = row.Scan(
+		&request.ID, &request.UserID, &request.AppID, &ttl,
+		&request.Reason, &request.Status, &request.DecidedBy, &request.CreatedAt,
+	)
+	request.TTL = time.Duration(ttl) * time.Second
+
+	return
+}
@@ -0,0 +1,163 @@
+package sqlite
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"time"
+
+	"github.com/mattn/go-sqlite3"
+	"github.com/nhassl3/sso-app/internals/domain/models"
+	"github.com/nhassl3/sso-app/internals/lib/logger/sl"
+	"github.com/nhassl3/sso-app/internals/storage"
+)
+
+const (
+	opSaveFederationState = "storage.sqlite.SaveFederationState"
+	opUseFederationState  = "storage.sqlite.UseFederationState"
+	opFederatedIdentity   = "storage.sqlite.FederatedIdentity"
+	opLinkIdentity        = "storage.sqlite.LinkIdentity"
+	opSaveFederatedUser   = "storage.sqlite.SaveFederatedUser"
+)
+
+// SaveFederationState saves state of the federated login by hash of the state and deletes expired states
+func (s *Storage) SaveFederationState(ctx context.Context, stateHash string, state models.FederationState) error {
+	request, err := json.Marshal(state.Request)
+	if err != nil {
+		return sl.ErrUpLevel(opSaveFederationState, err)
+	}
+
+	err = s.inTx(ctx, func(tx *sql.Tx) error {
+		if _, err := tx.ExecContext(ctx, "DELETE FROM federation_states WHERE expires_at <= unixepoch()"); err != nil {
+			return err
+		}
+
+		_, err := tx.ExecContext(
+			ctx,
+			`INSERT INTO federation_states (state_hash, provider, nonce, code_verifier, request, client_state, expires_at)
+VALUES (?, ?, ?, ?, ?, ?, ?)`,
+			stateHash, state.Provider, state.Nonce, state.CodeVerifier, string(request), state.ClientState,
+			state.ExpiresAt.Unix(),
+		)
+
+		return err
+	})
+	if err != nil {
+		return sl.ErrUpLevel(opSaveFederationState, err)
+	}
+
+	return nil
+}
+
+// UseFederationState deletes state of the federated login by hash of the state and returns it,
+// so every callback is handled once. Expired states are returned too, caller checks expiry time
+func (s *Storage) UseFederationState(ctx context.Context, stateHash string) (state models.FederationState, err error) {
+	var (
+		request   string
+		expiresAt int64
+	)
+
+	err = s.db.QueryRowContext(
+		ctx,
+		`DELETE FROM federation_states WHERE state_hash = ?
+RETURNING provider, nonce, code_verifier, request, client_state, expires_at`,
+		stateHash,
+	).Scan(&state.Provider, &state.Nonce, &state.CodeVerifier, &request, &state.ClientState, &expiresAt)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return models.FederationState{}, sl.ErrUpLevel(opUseFederationState, storage.ErrFederationStateNotFound)
+		}
+
+		return models.FederationState{}, sl.ErrUpLevel(opUseFederationState, err)
+	}
+
+	if err := json.Unmarshal([]byte(request), &state.Request); err != nil {
+		return models.FederationState{}, sl.ErrUpLevel(opUseFederationState, err)
+	}
+	state.ExpiresAt = time.Unix(expiresAt, 0)
+
+	return
+}
+
+// FederatedIdentity returns ID of the user linked to the identity at the provider
+func (s *Storage) FederatedIdentity(ctx context.Context, provider, subject string) (userID int64, err error) {
+	err = s.db.QueryRowContext(
+		ctx,
+		"SELECT user_id FROM federated_identities WHERE provider = ? AND subject = ?",
+		provider, subject,
+	).Scan(&userID)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return 0, sl.ErrUpLevel(opFederatedIdentity, storage.ErrIdentityNotFound)
+		}
+
+		return 0, sl.ErrUpLevel(opFederatedIdentity, err)
+	}
+
+	return
+}
+
+// LinkIdentity links the identity at the provider to the existing user
+func (s *Storage) LinkIdentity(ctx context.Context, identity models.ExternalIdentity, userID int64) error {
+	if err := linkIdentity(ctx, s.db, identity, userID); err != nil {
+		return sl.ErrUpLevel(opLinkIdentity, err)
+	}
+
+	return nil
+}
+
+// SaveFederatedUser saves the new user of the identity at the provider, links the identity to the user
+// and saves registration event of the user in the outbox
+func (s *Storage) SaveFederatedUser(
+	ctx context.Context,
+	identity models.ExternalIdentity,
+	hashPassword []byte,
+) (userID int64, err error) {
+	err = s.inTx(ctx, func(tx *sql.Tx) error {
+		res, err := tx.ExecContext(ctx, "INSERT INTO users (email, pass_hash) VALUES (?, ?)", identity.Email, hashPassword)
+		if err != nil {
+			var sqliteErr sqlite3.Error
+			if errors.As(err, &sqliteErr) && errors.Is(sqliteErr.ExtendedCode, sqlite3.ErrConstraintUnique) {
+				return storage.ErrUserExists
+			}
+
+			return err
+		}
+
+		if userID, err = res.LastInsertId(); err != nil {
+			return err
+		}
+
+		if err := linkIdentity(ctx, tx, identity, userID); err != nil {
+			return err
+		}
+
+		return saveEvent(ctx, tx, models.Event{Type: models.EventUserRegistered, UserID: userID})
+	})
+	if err != nil {
+		return 0, sl.ErrUpLevel(opSaveFederatedUser, err)
+	}
+
+	return
+}
+
+// execer is a database or transaction the statements are executed by
+type execer interface {
+	ExecContext(ctx context.Context, query string, args ...any) (sql.Result, error)
+}
+
+func linkIdentity(ctx context.Context, db execer, identity models.ExternalIdentity, userID int64) error {
+	_, err := db.ExecContext(
+		ctx,
+		"INSERT INTO federated_identities (provider, subject, user_id, email) VALUES (?, ?, ?, ?)",
+		identity.Provider, identity.Subject, userID, identity.Email,
+	)
+
+	var sqliteErr sqlite3.Error
+	if errors.As(err, &sqliteErr) && errors.Is(sqliteErr.ExtendedCode, sqlite3.ErrConstraintPrimaryKey) {
+		return storage.ErrIdentityExists
+	}
+
+	return err
+}
@@ -0,0 +1,116 @@
+package sqlite
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+
+	"github.com/nhassl3/sso-app/internals/domain/models"
+	"github.com/nhassl3/sso-app/internals/lib/logger/sl"
+	"github.com/nhassl3/sso-app/internals/storage"
+)
+
+const (
+	opSaveAuthCode = "storage.sqlite.SaveAuthCode"
+	opUseAuthCode  = "storage.sqlite.UseAuthCode"
+
+	opSaveRefreshToken = "storage.sqlite.SaveRefreshToken"
+	opUseRefreshToken  = "storage.sqlite.UseRefreshToken"
+)
+
+// SaveAuthCode saves authorization code by hash of the code and deletes expired codes
+func (s *Storage) SaveAuthCode(ctx context.Context, codeHash string, code models.AuthCode) error {
+	err := s.inTx(ctx, func(tx *sql.Tx) error {
+		if _, err := tx.ExecContext(ctx, "DELETE FROM authorization_codes WHERE expires_at <= unixepoch()"); err != nil {
+			return err
+		}
+
+		_, err := tx.ExecContext(
+			ctx,
+			`INSERT INTO authorization_codes
+    (code_hash, app_id, user_id, redirect_uri, scope, code_challenge, nonce, auth_time, expires_at)
+VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+			codeHash, code.AppID, code.UserID, code.RedirectURI, code.Scope, code.CodeChallenge,
+			code.Nonce, code.AuthTime.Unix(), code.ExpiresAt.Unix(),
+		)
+
+		return err
+	})
+	if err != nil {
+		return sl.ErrUpLevel(opSaveAuthCode, err)
+	}
+
+	return nil
+}
+
+// UseAuthCode deletes authorization code by hash of the code and returns it, so every code is used once.
+// Expired codes are returned too, caller checks expiry time
+func (s *Storage) UseAuthCode(ctx context.Context, codeHash string) (code models.AuthCode, err error) {
+	var authTime, expiresAt int64
+
+	err = s.db.QueryRowContext(
+		ctx,
+		`DELETE FROM authorization_codes WHERE code_hash = ?
+RETURNING app_id, user_id, redirect_uri, scope, code_challenge, nonce, auth_time, expires_at`,
+		codeHash,
+	).Scan(
+		&code.AppID, &code.UserID, &code.RedirectURI, &code.Scope, &code.CodeChallenge,
+		&code.Nonce, &authTime, &expiresAt,
+	)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return models.AuthCode{}, sl.ErrUpLevel(opUseAuthCode, storage.ErrAuthCodeNotFound)
+		}
+
+		return models.AuthCode{}, sl.ErrUpLevel(opUseAuthCode, err)
+	}
+	code.AuthTime = time.Unix(authTime, 0)
+	code.ExpiresAt = time.Unix(expiresAt, 0)
+
+	return
+}
+
+// SaveRefreshToken saves refresh token by hash of the token and deletes expired tokens
+func (s *Storage) SaveRefreshToken(ctx context.Context, tokenHash string, token models.RefreshToken) error {
+	err := s.inTx(ctx, func(tx *sql.Tx) error {
+		if _, err := tx.ExecContext(ctx, "DELETE FROM refresh_tokens WHERE expires_at <= unixepoch()"); err != nil {
+			return err
+		}
+
+		_, err := tx.ExecContext(
+			ctx,
+			"INSERT INTO refresh_tokens (token_hash, app_id, user_id, scope, expires_at) VALUES (?, ?, ?, ?, ?)",
+			tokenHash, token.AppID, token.UserID, token.Scope, token.ExpiresAt.Unix(),
+		)
+
+		return err
+	})
+	if err != nil {
+		return sl.ErrUpLevel(opSaveRefreshToken, err)
+	}
+
+	return nil
+}
+
+// UseRefreshToken deletes refresh token by hash of the token and returns it, so every token is used once.
+// Expired tokens are returned too, caller checks expiry time
+func (s *Storage) UseRefreshToken(ctx context.Context, tokenHash string) (token models.RefreshToken, err error) {
+	var expiresAt int64
+
+	err = s.db.QueryRowContext(
+		ctx,
+		"DELETE FROM refresh_tokens WHERE token_hash = ? RETURNING app_id, user_id, scope, expires_at",
+		tokenHash,
+	).Scan(&token.AppID, &token.UserID, &token.Scope, &expiresAt)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return models.RefreshToken{}, sl.ErrUpLevel(opUseRefreshToken, storage.ErrRefreshTokenNotFound)
+		}
+
+		return models.RefreshToken{}, sl.ErrUpLevel(opUseRefreshToken, err)
+	}
+	token.ExpiresAt = time.Unix(expiresAt, 0)
+
+	return
+}
@@ -0,0 +1,122 @@
+package sqlite
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"github.com/nhassl3/sso-app/internals/domain/models"
+	"github.com/nhassl3/sso-app/internals/lib/logger/sl"
+	"github.com/nhassl3/sso-app/internals/lib/random"
+)
+
+const (
+	opPendingEvents         = "storage.sqlite.PendingEvents"
+	opUpdateOutboxEvent     = "storage.sqlite.UpdateOutboxEvent"
+	opDeletePublishedEvents = "storage.sqlite.DeletePublishedEvents"
+
+	eventIDSize = 16
+)
+
+// PendingEvents returns unpublished events of the outbox which next attempt is due, in the order they are saved
+func (s *Storage) PendingEvents(ctx context.Context, now time.Time, limit int) (events []models.OutboxEvent, err error) {
+	rows, err := s.db.QueryContext(
+		ctx,
+		`SELECT id, event_id, type, actor_id, user_id, email, app_id, attempts, next_attempt_at, last_error, created_at
+FROM outbox_events
+WHERE published_at = 0 AND next_attempt_at <= ?
+ORDER BY id
+LIMIT ?`,
+		now.UnixMilli(), limit,
+	)
+	if err != nil {
+		return nil, sl.ErrUpLevel(opPendingEvents, err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var (
+			event                    models.OutboxEvent
+			nextAttemptAt, createdAt int64
+		)
+
+		err := rows.Scan(
+			&event.Seq, &event.Event.ID, &event.Event.Type, &event.Event.ActorID, &event.Event.UserID,
+			&event.Event.Email, &event.Event.AppID, &event.Attempts, &nextAttemptAt, &event.LastError, &createdAt,
+		)
+		if err != nil {
+			return nil, sl.ErrUpLevel(opPendingEvents, err)
+		}
+
+		event.NextAttemptAt = time.UnixMilli(nextAttemptAt)
+		event.Event.CreatedAt = time.Unix(createdAt, 0)
+
+		events = append(events, event)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, sl.ErrUpLevel(opPendingEvents, err)
+	}
+
+	return
+}
+
+// UpdateOutboxEvent saves attempts, error and publishing time of the event
+func (s *Storage) UpdateOutboxEvent(ctx context.Context, event models.OutboxEvent) error {
+	var publishedAt int64
+	if !event.PublishedAt.IsZero() {
+		publishedAt = event.PublishedAt.Unix()
+	}
+
+	_, err := s.db.ExecContext(
+		ctx,
+		"UPDATE outbox_events SET attempts = ?, next_attempt_at = ?, last_error = ?, published_at = ? WHERE id = ?",
+		event.Attempts, event.NextAttemptAt.UnixMilli(), event.LastError, publishedAt, event.Seq,
+	)
+	if err != nil {
+		return sl.ErrUpLevel(opUpdateOutboxEvent, err)
+	}
+
+	return nil
+}
+
+// DeletePublishedEvents deletes events published before the time and returns count of them
+func (s *Storage) DeletePublishedEvents(ctx context.Context, before time.Time) (deleted int, err error) {
+	res, err := s.db.ExecContext(
+		ctx,
+		"DELETE FROM outbox_events WHERE published_at != 0 AND published_at < ?",
+		before.Unix(),
+	)
+	if err != nil {
+		return 0, sl.ErrUpLevel(opDeletePublishedEvents, err)
+	}
+
+	affected, err := res.RowsAffected()
+	if err != nil {
+		return 0, sl.ErrUpLevel(opDeletePublishedEvents, err)
+	}
+
+	return int(affected), nil
+}
+
+// saveEvent saves the event in the outbox in the transaction of the change it describes.
+// Email of the event is the current email of its user unless the user doesn't exist
+func saveEvent(ctx context.Context, tx *sql.Tx, event models.Event) error {
+	if event.ID == "" {
+		id, err := random.String(eventIDSize)
+		if err != nil {
+			return err
+		}
+
+		event.ID = "evt_" + id
+	}
+
+	_, err := tx.ExecContext(
+		ctx,
+		`INSERT INTO outbox_events (event_id, type, actor_id, user_id, email, app_id)
+VALUES (?, ?, ?, ?, IFNULL((SELECT email FROM users WHERE id = ?), ?), ?)`,
+		event.ID, event.Type, event.ActorID, event.UserID, event.UserID, event.Email, event.AppID,
+	)
+
+	return err
+}
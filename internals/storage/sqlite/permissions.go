@@ -0,0 +1,199 @@
+package sqlite
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+
+	"github.com/nhassl3/sso-app/internals/domain/models"
+	"github.com/nhassl3/sso-app/internals/lib/logger/sl"
+	"github.com/nhassl3/sso-app/internals/storage"
+)
+
+const (
+	opWriteTuples         = "storage.sqlite.WriteTuples"
+	opReadTuples          = "storage.sqlite.ReadTuples"
+	opSaveNamespaceConfig = "storage.sqlite.SaveNamespaceConfig"
+	opNamespaceConfig     = "storage.sqlite.NamespaceConfig"
+)
+
+// WriteTuples writes and deletes relation tuples of the application atomically.
+// Returns revision of the tuples after the change
+func (s *Storage) WriteTuples(ctx context.Context, appID int32, writes, deletes []models.RelationTuple) (revision int64, err error) {
+	err = s.inTx(ctx, func(tx *sql.Tx) error {
+		if err := checkApp(ctx, tx, appID); err != nil {
+			return err
+		}
+
+		for _, tuple := range writes {
+			_, err := tx.ExecContext(
+				ctx,
+				`INSERT INTO relation_tuples (app_id, object_type, object_id, relation, subject_type, subject_id, subject_relation)
+VALUES (?, ?, ?, ?, ?, ?, ?)
+ON CONFLICT DO NOTHING`,
+				appID, tuple.Object.Type, tuple.Object.ID, tuple.Relation,
+				tuple.Subject.Type, tuple.Subject.ID, tuple.Subject.Relation,
+			)
+			if err != nil {
+				return err
+			}
+		}
+
+		for _, tuple := range deletes {
+			_, err := tx.ExecContext(
+				ctx,
+				`DELETE FROM relation_tuples
+WHERE app_id = ? AND object_type = ? AND object_id = ? AND relation = ?
+  AND subject_type = ? AND subject_id = ? AND subject_relation = ?`,
+				appID, tuple.Object.Type, tuple.Object.ID, tuple.Relation,
+				tuple.Subject.Type, tuple.Subject.ID, tuple.Subject.Relation,
+			)
+			if err != nil {
+				return err
+			}
+		}
+
+		revision, err = bumpRevision(ctx, tx, appID)
+
+		return err
+	})
+	if err != nil {
+		return 0, sl.ErrUpLevel(opWriteTuples, err)
+	}
+
+	return
+}
+
+// ReadTuples calls read with reader of the relation tuples of the application.
+// All reads see one snapshot of the tuples, its revision is returned
+func (s *Storage) ReadTuples(ctx context.Context, appID int32, read func(reader storage.TupleReader) error) (revision int64, err error) {
+	err = s.inTx(ctx, func(tx *sql.Tx) error {
+		err := tx.QueryRowContext(
+			ctx,
+			"SELECT IFNULL((SELECT revision FROM permission_revisions WHERE app_id = ?), 0)",
+			appID,
+		).Scan(&revision)
+		if err != nil {
+			return err
+		}
+
+		return read(&tupleReader{ctx: ctx, tx: tx, appID: appID})
+	})
+	if err != nil {
+		return 0, sl.ErrUpLevel(opReadTuples, err)
+	}
+
+	return
+}
+
+// SaveNamespaceConfig saves namespace configuration of the application replacing the previous one
+func (s *Storage) SaveNamespaceConfig(ctx context.Context, appID int32, config []byte) error {
+	err := s.inTx(ctx, func(tx *sql.Tx) error {
+		if err := checkApp(ctx, tx, appID); err != nil {
+			return err
+		}
+
+		_, err := tx.ExecContext(
+			ctx,
+			`INSERT INTO namespace_configs (app_id, config) VALUES (?, ?)
+ON CONFLICT (app_id) DO UPDATE SET config = excluded.config`,
+			appID, string(config),
+		)
+
+		return err
+	})
+	if err != nil {
+		return sl.ErrUpLevel(opSaveNamespaceConfig, err)
+	}
+
+	return nil
+}
+
+// NamespaceConfig returns namespace configuration of the application
+func (s *Storage) NamespaceConfig(ctx context.Context, appID int32) (config []byte, err error) {
+	err = s.newSelect(
+		ctx,
+		"SELECT config FROM namespace_configs WHERE app_id = ?",
+		[]interface{}{appID},
+		&config,
+	)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, sl.ErrUpLevel(opNamespaceConfig, storage.ErrNamespaceConfigNotFound)
+		}
+
+		return nil, sl.ErrUpLevel(opNamespaceConfig, err)
+	}
+
+	return
+}
+
+// bumpRevision increases revision of the tuples of the application and returns it
+func bumpRevision(ctx context.Context, tx *sql.Tx, appID int32) (revision int64, err error) {
+	err = tx.QueryRowContext(
+		ctx,
+		`INSERT INTO permission_revisions (app_id, revision) VALUES (?, 1)
+ON CONFLICT (app_id) DO UPDATE SET revision = revision + 1
+RETURNING revision`,
+		appID,
+	).Scan(&revision)
+
+	return
+}
+
+// tupleReader reads relation tuples in the transaction of ReadTuples
+type tupleReader struct {
+	ctx   context.Context
+	tx    *sql.Tx
+	appID int32
+}
+
+func (r *tupleReader) Subjects(object models.Object, relation string) (subjects []models.Subject, err error) {
+	rows, err := r.tx.QueryContext(
+		r.ctx,
+		`SELECT subject_type, subject_id, subject_relation FROM relation_tuples
+WHERE app_id = ? AND object_type = ? AND object_id = ? AND relation = ?
+ORDER BY id`,
+		r.appID, object.Type, object.ID, relation,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var subject models.Subject
+
+		if err := rows.Scan(&subject.Type, &subject.ID, &subject.Relation); err != nil {
+			return nil, err
+		}
+
+		subjects = append(subjects, subject)
+	}
+
+	return subjects, rows.Err()
+}
+
+func (r *tupleReader) Objects(objectType string) (objectIDs []string, err error) {
+	rows, err := r.tx.QueryContext(
+		r.ctx,
+		"SELECT DISTINCT object_id FROM relation_tuples WHERE app_id = ? AND object_type = ? ORDER BY object_id",
+		r.appID, objectType,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var objectID string
+
+		if err := rows.Scan(&objectID); err != nil {
+			return nil, err
+		}
+
+		objectIDs = append(objectIDs, objectID)
+	}
+
+	return objectIDs, rows.Err()
+}
@@ -0,0 +1,129 @@
+package sqlite
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+
+	"github.com/nhassl3/sso-app/internals/domain/models"
+	"github.com/nhassl3/sso-app/internals/lib/logger/sl"
+	"github.com/nhassl3/sso-app/internals/storage"
+)
+
+const (
+	opSavePolicy   = "storage.sqlite.SavePolicy"
+	opPolicy       = "storage.sqlite.Policy"
+	opSaveDecision = "storage.sqlite.SaveDecision"
+	opDecisions    = "storage.sqlite.Decisions"
+)
+
+// SavePolicy saves ABAC policy of the application replacing the previous one and returns its version
+func (s *Storage) SavePolicy(ctx context.Context, appID int32, policy []byte) (version int64, err error) {
+	err = s.inTx(ctx, func(tx *sql.Tx) error {
+		if err := checkApp(ctx, tx, appID); err != nil {
+			return err
+		}
+
+		return tx.QueryRowContext(
+			ctx,
+			`INSERT INTO abac_policies (app_id, policy) VALUES (?, ?)
+ON CONFLICT (app_id) DO UPDATE SET policy = excluded.policy, version = version + 1
+RETURNING version`,
+			appID, string(policy),
+		).Scan(&version)
+	})
+	if err != nil {
+		return 0, sl.ErrUpLevel(opSavePolicy, err)
+	}
+
+	return
+}
+
+// Policy returns ABAC policy of the application with its version
+func (s *Storage) Policy(ctx context.Context, appID int32) (policy []byte, version int64, err error) {
+	err = s.newSelect(
+		ctx,
+		"SELECT policy, version FROM abac_policies WHERE app_id = ?",
+		[]interface{}{appID},
+		&policy, &version,
+	)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, 0, sl.ErrUpLevel(opPolicy, storage.ErrPolicyNotFound)
+		}
+
+		return nil, 0, sl.ErrUpLevel(opPolicy, err)
+	}
+
+	return
+}
+
+// SaveDecision records result of the policy evaluation and deletes decisions of the application
+// older than the latest keep ones
+func (s *Storage) SaveDecision(ctx context.Context, decision models.PolicyDecision, keep int) (decisionID int64, err error) {
+	err = s.inTx(ctx, func(tx *sql.Tx) error {
+		res, err := tx.ExecContext(
+			ctx,
+			"INSERT INTO policy_decisions (app_id, policy_version, input, effect, rule) VALUES (?, ?, ?, ?, ?)",
+			decision.AppID, decision.PolicyVersion, string(decision.Input), decision.Effect, decision.Rule,
+		)
+		if err != nil {
+			return err
+		}
+
+		if decisionID, err = res.LastInsertId(); err != nil {
+			return err
+		}
+
+		_, err = tx.ExecContext(
+			ctx,
+			`DELETE FROM policy_decisions WHERE app_id = ? AND id <= (
+    SELECT id FROM policy_decisions WHERE app_id = ? ORDER BY id DESC LIMIT 1 OFFSET ?
+)`,
+			decision.AppID, decision.AppID, keep,
+		)
+
+		return err
+	})
+	if err != nil {
+		return 0, sl.ErrUpLevel(opSaveDecision, err)
+	}
+
+	return
+}
+
+// Decisions returns the latest recorded decisions of the application
+func (s *Storage) Decisions(ctx context.Context, appID int32, limit int) (decisions []models.PolicyDecision, err error) {
+	rows, err := s.db.QueryContext(
+		ctx,
+		`SELECT id, app_id, policy_version, input, effect, rule, created_at FROM policy_decisions
+WHERE app_id = ?
+ORDER BY id DESC
+LIMIT ?`,
+		appID, limit,
+	)
+	if err != nil {
+		return nil, sl.ErrUpLevel(opDecisions, err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var decision models.PolicyDecision
+
+		err := rows.Scan(
+			&decision.ID, &decision.AppID, &decision.PolicyVersion, &decision.Input,
+			&decision.Effect, &decision.Rule, &decision.CreatedAt,
+		)
+		if err != nil {
+			return nil, sl.ErrUpLevel(opDecisions, err)
+		}
+
+		decisions = append(decisions, decision)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, sl.ErrUpLevel(opDecisions, err)
+	}
+
+	return
+}
@@ -0,0 +1,729 @@
+package sqlite
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/nhassl3/sso-app/internals/domain/models"
+	"github.com/nhassl3/sso-app/internals/lib/logger/sl"
+	"github.com/nhassl3/sso-app/internals/lib/scim"
+	"github.com/nhassl3/sso-app/internals/storage"
+)
+
+const (
+	opSaveScimUser    = "storage.sqlite.SaveScimUser"
+	opScimUser        = "storage.sqlite.ScimUser"
+	opScimUsers       = "storage.sqlite.ScimUsers"
+	opUpdateScimUser  = "storage.sqlite.UpdateScimUser"
+	opDeleteUser      = "storage.sqlite.DeleteUser"
+	opSaveScimGroup   = "storage.sqlite.SaveScimGroup"
+	opScimGroup       = "storage.sqlite.ScimGroup"
+	opScimGroups      = "storage.sqlite.ScimGroups"
+	opUpdateScimGroup = "storage.sqlite.UpdateScimGroup"
+	opDeleteScimGroup = "storage.sqlite.DeleteScimGroup"
+
+	scimUserColumns = `users.id, users.email, users.external_id, users.display_name, users.given_name, users.family_name,
+NOT users.disabled, users.version, users.created_at, users.updated_at`
+	scimGroupColumns = `scim_groups.id, scim_groups.app_id, scim_groups.display_name, scim_groups.external_id,
+scim_groups.version, scim_groups.created_at, scim_groups.updated_at`
+
+	// groupMembers is the condition of the relation tuples of the members of scim_groups row
+	groupMembers = `relation_tuples.app_id = scim_groups.app_id AND relation_tuples.object_type = 'group'
+AND relation_tuples.object_id = CAST(scim_groups.id AS TEXT) AND relation_tuples.relation = 'member'
+AND relation_tuples.subject_type = 'user' AND relation_tuples.subject_relation = ''`
+)
+
+// userScopedTables are tables with rows which belong to some user, they are cleaned up together with the user.
+// Audit events and logout deliveries outlive the user
+var userScopedTables = []string{
+	"user_roles",
+	"admins",
+	"elevation_requests",
+	"authorization_codes",
+	"device_codes",
+	"refresh_tokens",
+	"consents",
+	"sessions",
+	"federated_identities",
+}
+
+// SaveScimUser saves the user provisioned by SCIM client and its registration event in the outbox
+func (s *Storage) SaveScimUser(ctx context.Context, user models.ScimUser, hashPassword []byte) (userID int64, err error) {
+	err = s.inTx(ctx, func(tx *sql.Tx) error {
+		res, err := tx.ExecContext(
+			ctx,
+			`INSERT INTO users (email, pass_hash, external_id, display_name, given_name, family_name, disabled)
+VALUES (?, ?, ?, ?, ?, ?, ?)`,
+			user.UserName, hashPassword, user.ExternalID, user.DisplayName, user.GivenName, user.FamilyName, !user.Active,
+		)
+		if err != nil {
+			return uniqueErr(err, storage.ErrUserExists)
+		}
+
+		if userID, err = res.LastInsertId(); err != nil {
+			return err
+		}
+
+		return saveEvent(ctx, tx, models.Event{Type: models.EventUserRegistered, UserID: userID})
+	})
+	if err != nil {
+		return 0, sl.ErrUpLevel(opSaveScimUser, err)
+	}
+
+	return
+}
+
+// ScimUser returns the user with SCIM attributes
+func (s *Storage) ScimUser(ctx context.Context, userID int64) (user models.ScimUser, err error) {
+	user, err = scanScimUser(s.db.QueryRowContext(ctx, "SELECT "+scimUserColumns+" FROM users WHERE id = ?", userID))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return models.ScimUser{}, sl.ErrUpLevel(opScimUser, storage.ErrUserNotFound)
+		}
+
+		return models.ScimUser{}, sl.ErrUpLevel(opScimUser, err)
+	}
+
+	return
+}
+
+// ScimUsers returns page of the users matching the filter ordered by ID and count of all matching users.
+// Nil filter matches all users
+func (s *Storage) ScimUsers(
+	ctx context.Context,
+	filter scim.Expr,
+	offset int,
+	limit int,
+) (users []models.ScimUser, total int, err error) {
+	where, args, err := scimWhere(filter, scimUserAttrs)
+	if err != nil {
+		return nil, 0, sl.ErrUpLevel(opScimUsers, err)
+	}
+
+	err = s.inTx(ctx, func(tx *sql.Tx) error {
+		if err := tx.QueryRowContext(ctx, "SELECT COUNT(*) FROM users WHERE "+where, args...).Scan(&total); err != nil {
+			return err
+		}
+
+		rows, err := tx.QueryContext(
+			ctx,
+			"SELECT "+scimUserColumns+" FROM users WHERE "+where+" ORDER BY users.id LIMIT ? OFFSET ?",
+			append(args, limit, offset)...,
+		)
+		if err != nil {
+			return err
+		}
+		defer rows.Close()
+
+		for rows.Next() {
+			user, err := scanScimUser(rows)
+			if err != nil {
+				return err
+			}
+
+			users = append(users, user)
+		}
+
+		return rows.Err()
+	})
+	if err != nil {
+		return nil, 0, sl.ErrUpLevel(opScimUsers, err)
+	}
+
+	return
+}
+
+// UpdateScimUser saves SCIM attributes of the user if its version is still the version of the model.
+// Nil password hash keeps the password, the new one is announced by the event in the outbox
+func (s *Storage) UpdateScimUser(ctx context.Context, user models.ScimUser, hashPassword []byte) error {
+	// Nil slice is bound as empty blob, not NULL
+	var hash any
+	if hashPassword != nil {
+		hash = hashPassword
+	}
+
+	err := s.inTx(ctx, func(tx *sql.Tx) error {
+		res, err := tx.ExecContext(
+			ctx,
+			`UPDATE users SET email = ?, external_id = ?, display_name = ?, given_name = ?, family_name = ?, disabled = ?,
+pass_hash = IFNULL(?, pass_hash), version = version + 1, updated_at = unixepoch()
+WHERE id = ? AND version = ?`,
+			user.UserName, user.ExternalID, user.DisplayName, user.GivenName, user.FamilyName, !user.Active,
+			hash, user.ID, user.Version,
+		)
+		if err != nil {
+			return uniqueErr(err, storage.ErrUserExists)
+		}
+
+		if err := versionErr(ctx, tx, res, "users", user.ID, storage.ErrUserNotFound); err != nil {
+			return err
+		}
+
+		if hashPassword == nil {
+			return nil
+		}
+
+		return saveEvent(ctx, tx, models.Event{Type: models.EventUserPasswordChanged, UserID: user.ID})
+	})
+	if err != nil {
+		return sl.ErrUpLevel(opUpdateScimUser, err)
+	}
+
+	return nil
+}
+
+// DeleteUser deletes the user with its rows in other tables and relation tuples of the user, sessions of the user
+// are ended as by EndSessions, deletion event is saved in the outbox. Returns applications which tuples are changed.
+// Last permanent super-admin of the system can't be deleted
+func (s *Storage) DeleteUser(ctx context.Context, userID int64) (appIDs []int32, err error) {
+	err = s.inTx(ctx, func(tx *sql.Tx) error {
+		last, err := lastSuperAdmin(ctx, tx, userID)
+		if err != nil {
+			return err
+		}
+
+		if last {
+			return storage.ErrLastSuperAdmin
+		}
+
+		// Event takes the email of the user before the user is deleted
+		if err := saveEvent(ctx, tx, models.Event{Type: models.EventUserDeleted, UserID: userID}); err != nil {
+			return err
+		}
+
+		if _, err := endSessions(ctx, tx, userID, 0); err != nil {
+			return err
+		}
+
+		subjectID := strconv.FormatInt(userID, 10)
+
+		rows, err := tx.QueryContext(
+			ctx,
+			`SELECT DISTINCT app_id FROM relation_tuples
+WHERE subject_type = 'user' AND subject_id = ? AND subject_relation = '' ORDER BY app_id`,
+			subjectID,
+		)
+		if err != nil {
+			return err
+		}
+
+		for rows.Next() {
+			var appID int32
+			if err := rows.Scan(&appID); err != nil {
+				_ = rows.Close()
+				return err
+			}
+
+			appIDs = append(appIDs, appID)
+		}
+
+		if err := errors.Join(rows.Err(), rows.Close()); err != nil {
+			return err
+		}
+
+		_, err = tx.ExecContext(
+			ctx,
+			"DELETE FROM relation_tuples WHERE subject_type = 'user' AND subject_id = ? AND subject_relation = ''",
+			subjectID,
+		)
+		if err != nil {
+			return err
+		}
+
+		for _, appID := range appIDs {
+			if _, err := bumpRevision(ctx, tx, appID); err != nil {
+				return err
+			}
+		}
+
+		for _, table := range userScopedTables {
+			if _, err := tx.ExecContext(ctx, fmt.Sprintf("DELETE FROM %s WHERE user_id = ?", table), userID); err != nil {
+				return err
+			}
+		}
+
+		res, err := tx.ExecContext(ctx, "DELETE FROM users WHERE id = ?", userID)
+		if err != nil {
+			return err
+		}
+
+		return mustAffect(res, storage.ErrUserNotFound)
+	})
+	if err != nil {
+		return nil, sl.ErrUpLevel(opDeleteUser, err)
+	}
+
+	return
+}
+
+// SaveScimGroup saves the group of the application with its members, members must exist
+func (s *Storage) SaveScimGroup(ctx context.Context, group models.ScimGroup) (groupID int64, err error) {
+	err = s.inTx(ctx, func(tx *sql.Tx) error {
+		if err := checkApp(ctx, tx, group.AppID); err != nil {
+			return err
+		}
+
+		res, err := tx.ExecContext(
+			ctx,
+			"INSERT INTO scim_groups (app_id, display_name, external_id) VALUES (?, ?, ?)",
+			group.AppID, group.DisplayName, group.ExternalID,
+		)
+		if err != nil {
+			return uniqueErr(err, storage.ErrGroupExists)
+		}
+
+		if groupID, err = res.LastInsertId(); err != nil {
+			return err
+		}
+
+		return writeMembers(ctx, tx, group.AppID, groupID, group.Members)
+	})
+	if err != nil {
+		return 0, sl.ErrUpLevel(opSaveScimGroup, err)
+	}
+
+	return
+}
+
+// ScimGroup returns the group of the application with its members
+func (s *Storage) ScimGroup(ctx context.Context, appID int32, groupID int64) (group models.ScimGroup, err error) {
+	err = s.inTx(ctx, func(tx *sql.Tx) error {
+		group, err = scanScimGroup(tx.QueryRowContext(
+			ctx,
+			"SELECT "+scimGroupColumns+" FROM scim_groups WHERE app_id = ? AND id = ?",
+			appID, groupID,
+		))
+		if err != nil {
+			if errors.Is(err, sql.ErrNoRows) {
+				return storage.ErrGroupNotFound
+			}
+
+			return err
+		}
+
+		group.Members, err = members(ctx, tx, appID, groupID)
+
+		return err
+	})
+	if err != nil {
+		return models.ScimGroup{}, sl.ErrUpLevel(opScimGroup, err)
+	}
+
+	return
+}
+
+// ScimGroups returns page of the groups of the application matching the filter ordered by ID
+// and count of all matching groups. Nil filter matches all groups
+func (s *Storage) ScimGroups(
+	ctx context.Context,
+	appID int32,
+	filter scim.Expr,
+	offset int,
+	limit int,
+) (groups []models.ScimGroup, total int, err error) {
+	where, args, err := scimWhere(filter, scimGroupAttrs)
+	if err != nil {
+		return nil, 0, sl.ErrUpLevel(opScimGroups, err)
+	}
+
+	args = append([]any{appID}, args...)
+
+	err = s.inTx(ctx, func(tx *sql.Tx) error {
+		err := tx.QueryRowContext(
+			ctx,
+			"SELECT COUNT(*) FROM scim_groups WHERE scim_groups.app_id = ? AND "+where,
+			args...,
+		).Scan(&total)
+		if err != nil {
+			return err
+		}
+
+		rows, err := tx.QueryContext(
+			ctx,
+			"SELECT "+scimGroupColumns+" FROM scim_groups WHERE scim_groups.app_id = ? AND "+where+
+				" ORDER BY scim_groups.id LIMIT ? OFFSET ?",
+			append(args, limit, offset)...,
+		)
+		if err != nil {
+			return err
+		}
+
+		for rows.Next() {
+			group, err := scanScimGroup(rows)
+			if err != nil {
+				_ = rows.Close()
+				return err
+			}
+
+			groups = append(groups, group)
+		}
+
+		if err := errors.Join(rows.Err(), rows.Close()); err != nil {
+			return err
+		}
+
+		for i := range groups {
+			if groups[i].Members, err = members(ctx, tx, appID, groups[i].ID); err != nil {
+				return err
+			}
+		}
+
+		return nil
+	})
+	if err != nil {
+		return nil, 0, sl.ErrUpLevel(opScimGroups, err)
+	}
+
+	return
+}
+
+// UpdateScimGroup saves attributes and members of the group if its version is still the version of the model
+func (s *Storage) UpdateScimGroup(ctx context.Context, group models.ScimGroup) error {
+	err := s.inTx(ctx, func(tx *sql.Tx) error {
+		res, err := tx.ExecContext(
+			ctx,
+			`UPDATE scim_groups SET display_name = ?, external_id = ?, version = version + 1, updated_at = unixepoch()
+WHERE app_id = ? AND id = ? AND version = ?`,
+			group.DisplayName, group.ExternalID, group.AppID, group.ID, group.Version,
+		)
+		if err != nil {
+			return uniqueErr(err, storage.ErrGroupExists)
+		}
+
+		if err := versionErr(ctx, tx, res, "scim_groups", group.ID, storage.ErrGroupNotFound); err != nil {
+			return err
+		}
+
+		return writeMembers(ctx, tx, group.AppID, group.ID, group.Members)
+	})
+	if err != nil {
+		return sl.ErrUpLevel(opUpdateScimGroup, err)
+	}
+
+	return nil
+}
+
+// DeleteScimGroup deletes the group of the application with relation tuples of the group as object and as subject
+func (s *Storage) DeleteScimGroup(ctx context.Context, appID int32, groupID int64) error {
+	err := s.inTx(ctx, func(tx *sql.Tx) error {
+		res, err := tx.ExecContext(ctx, "DELETE FROM scim_groups WHERE app_id = ? AND id = ?", appID, groupID)
+		if err != nil {
+			return err
+		}
+
+		if err := mustAffect(res, storage.ErrGroupNotFound); err != nil {
+			return err
+		}
+
+		_, err = tx.ExecContext(
+			ctx,
+			`DELETE FROM relation_tuples WHERE app_id = ?
+AND (object_type = 'group' AND object_id = ? OR subject_type = 'group' AND subject_id = ?)`,
+			appID, strconv.FormatInt(groupID, 10), strconv.FormatInt(groupID, 10),
+		)
+		if err != nil {
+			return err
+		}
+
+		_, err = bumpRevision(ctx, tx, appID)
+
+		return err
+	})
+	if err != nil {
+		return sl.ErrUpLevel(opDeleteScimGroup, err)
+	}
+
+	return nil
+}
+
+// writeMembers replaces users of the group with the members, other subjects of the group are kept
+func writeMembers(ctx context.Context, tx *sql.Tx, appID int32, groupID int64, members []models.ScimMember) error {
+	objectID := strconv.FormatInt(groupID, 10)
+
+	_, err := tx.ExecContext(
+		ctx,
+		`DELETE FROM relation_tuples WHERE app_id = ? AND object_type = 'group' AND object_id = ? AND relation = 'member'
+AND subject_type = 'user' AND subject_relation = ''`,
+		appID, objectID,
+	)
+	if err != nil {
+		return err
+	}
+
+	for _, member := range members {
+		res, err := tx.ExecContext(
+			ctx,
+			`INSERT INTO relation_tuples (app_id, object_type, object_id, relation, subject_type, subject_id, subject_relation)
+SELECT ?, 'group', ?, 'member', 'user', CAST(id AS TEXT), '' FROM users WHERE id = ?
+ON CONFLICT DO NOTHING`,
+			appID, objectID, member.UserID,
+		)
+		if err != nil {
+			return err
+		}
+
+		if err := mustAffect(res, storage.ErrUserNotFound); err != nil {
+			return err
+		}
+	}
+
+	_, err = bumpRevision(ctx, tx, appID)
+
+	return err
+}
+
+// members returns members of the group ordered by user ID
+func members(ctx context.Context, tx *sql.Tx, appID int32, groupID int64) (members []models.ScimMember, err error) {
+	rows, err := tx.QueryContext(
+		ctx,
+		`SELECT users.id, users.email FROM scim_groups
+JOIN relation_tuples ON `+groupMembers+`
+JOIN users ON users.id = CAST(relation_tuples.subject_id AS INTEGER)
+WHERE scim_groups.app_id = ? AND scim_groups.id = ?
+ORDER BY users.id`,
+		appID, groupID,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var member models.ScimMember
+
+		if err := rows.Scan(&member.UserID, &member.UserName); err != nil {
+			return nil, err
+		}
+
+		members = append(members, member)
+	}
+
+	return members, rows.Err()
+}
+
+// versionErr returns nil if the versioned update affected the row, otherwise tells whether the row
+// doesn't exist or has another version
+func versionErr(ctx context.Context, tx *sql.Tx, res sql.Result, table string, id int64, notFound error) error {
+	affected, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if affected > 0 {
+		return nil
+	}
+
+	var exists bool
+
+	err = tx.QueryRowContext(ctx, fmt.Sprintf("SELECT EXISTS(SELECT 1 FROM %s WHERE id = ?)", table), id).Scan(&exists)
+	if err != nil {
+		return err
+	}
+
+	if !exists {
+		return notFound
+	}
+
+	return storage.ErrVersionConflict
+}
+
+func scanScimUser(row rowScanner) (user models.ScimUser, err error) {
+	var created, lastModified int64
+
+	err = row.Scan(
+		&user.ID, &user.UserName, &user.ExternalID, &user.DisplayName, &user.GivenName, &user.FamilyName,
+		&user.Active, &user.Version, &created, &lastModified,
+	)
+	user.Created, user.LastModified = time.Unix(created, 0), time.Unix(lastModified, 0)
+
+	return
+}
+
+func scanScimGroup(row rowScanner) (group models.ScimGroup, err error) {
+	var created, lastModified int64
+
+	err = row.Scan(
+		&group.ID, &group.AppID, &group.DisplayName, &group.ExternalID, &group.Version, &created, &lastModified,
+	)
+	group.Created, group.LastModified = time.Unix(created, 0), time.Unix(lastModified, 0)
+
+	return
+}
+
+// scimAttr is the column of SCIM attribute in the filters
+type scimAttr struct {
+	column string
+	kind   scimKind
+}
+
+type scimKind int
+
+const (
+	scimString scimKind = iota
+	scimBool
+	scimTime
+	scimID
+	scimMember // user ID of the member of the group
+)
+
+var scimUserAttrs = map[string]scimAttr{
+	"id":                {"users.id", scimID},
+	"username":          {"users.email", scimString},
+	"emails":            {"users.email", scimString},
+	"emails.value":      {"users.email", scimString},
+	"externalid":        {"users.external_id", scimString},
+	"displayname":       {"users.display_name", scimString},
+	"name.givenname":    {"users.given_name", scimString},
+	"name.familyname":   {"users.family_name", scimString},
+	"active":            {"NOT users.disabled", scimBool},
+	"meta.created":      {"users.created_at", scimTime},
+	"meta.lastmodified": {"users.updated_at", scimTime},
+}
+
+var scimGroupAttrs = map[string]scimAttr{
+	"id":                {"scim_groups.id", scimID},
+	"displayname":       {"scim_groups.display_name", scimString},
+	"externalid":        {"scim_groups.external_id", scimString},
+	"members":           {"relation_tuples.subject_id", scimMember},
+	"members.value":     {"relation_tuples.subject_id", scimMember},
+	"meta.created":      {"scim_groups.created_at", scimTime},
+	"meta.lastmodified": {"scim_groups.updated_at", scimTime},
+}
+
+var scimOperators = map[string]string{
+	scim.OpEqual:          "=",
+	scim.OpNotEqual:       "!=",
+	scim.OpGreater:        ">",
+	scim.OpGreaterOrEqual: ">=",
+	scim.OpLess:           "<",
+	scim.OpLessOrEqual:    "<=",
+}
+
+// likeEscaper escapes wildcards of LIKE pattern, escape character is backslash
+var likeEscaper = strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`)
+
+// scimWhere compiles the filter to SQL condition over the columns of the attributes.
+// Strings are compared case-insensitively as SCIM requires for the attributes which are not case exact
+func scimWhere(filter scim.Expr, attrs map[string]scimAttr) (where string, args []any, err error) {
+	switch filter := filter.(type) {
+	case nil:
+		return "TRUE", nil, nil
+	case *scim.Logical:
+		left, leftArgs, err := scimWhere(filter.Left, attrs)
+		if err != nil {
+			return "", nil, err
+		}
+
+		right, rightArgs, err := scimWhere(filter.Right, attrs)
+		if err != nil {
+			return "", nil, err
+		}
+
+		return "(" + left + " " + strings.ToUpper(filter.Op) + " " + right + ")", append(leftArgs, rightArgs...), nil
+	case *scim.Not:
+		where, args, err := scimWhere(filter.Expr, attrs)
+		if err != nil {
+			return "", nil, err
+		}
+
+		return "NOT (" + where + ")", args, nil
+	case *scim.Comparison:
+		attr, ok := attrs[filter.Attr]
+		if !ok {
+			return "", nil, fmt.Errorf("%w: attribute %s can't be filtered", storage.ErrInvalidFilter, filter.Attr)
+		}
+
+		return scimComparison(attr, filter)
+	}
+
+	return "", nil, fmt.Errorf("%w: unknown expression %T", storage.ErrInvalidFilter, filter)
+}
+
+func scimComparison(attr scimAttr, c *scim.Comparison) (where string, args []any, err error) {
+	invalid := fmt.Errorf("%w: %s %s %v isn't supported", storage.ErrInvalidFilter, c.Attr, c.Op, c.Value)
+
+	if c.Op == scim.OpPresent {
+		switch attr.kind {
+		case scimString:
+			return attr.column + " != ''", nil, nil
+		case scimMember:
+			return "EXISTS(SELECT 1 FROM relation_tuples WHERE " + groupMembers + ")", nil, nil
+		}
+
+		return "TRUE", nil, nil
+	}
+
+	operator, ordered := scimOperators[c.Op]
+
+	switch attr.kind {
+	case scimString:
+		value, ok := c.Value.(string)
+		if !ok {
+			return "", nil, invalid
+		}
+
+		switch c.Op {
+		case scim.OpContains:
+			return attr.column + ` LIKE ? ESCAPE '\'`, []any{"%" + likeEscaper.Replace(value) + "%"}, nil
+		case scim.OpStartsWith:
+			return attr.column + ` LIKE ? ESCAPE '\'`, []any{likeEscaper.Replace(value) + "%"}, nil
+		case scim.OpEndsWith:
+			return attr.column + ` LIKE ? ESCAPE '\'`, []any{"%" + likeEscaper.Replace(value)}, nil
+		}
+
+		return attr.column + " " + operator + " ? COLLATE NOCASE", []any{value}, nil
+	case scimBool:
+		value, ok := c.Value.(bool)
+		if !ok || c.Op != scim.OpEqual && c.Op != scim.OpNotEqual {
+			return "", nil, invalid
+		}
+
+		return "(" + attr.column + ") " + operator + " ?", []any{value}, nil
+	case scimTime:
+		value, ok := c.Value.(string)
+		if !ok || !ordered {
+			return "", nil, invalid
+		}
+
+		t, err := time.Parse(time.RFC3339, value)
+		if err != nil {
+			return "", nil, invalid
+		}
+
+		return attr.column + " " + operator + " ?", []any{t.Unix()}, nil
+	case scimID:
+		value, ok := c.Value.(string)
+		if !ok || !ordered {
+			return "", nil, invalid
+		}
+
+		id, err := strconv.ParseInt(value, 10, 64)
+		if err != nil {
+			// Resource with such ID can't exist
+			switch c.Op {
+			case scim.OpEqual:
+				return "FALSE", nil, nil
+			case scim.OpNotEqual:
+				return "TRUE", nil, nil
+			}
+
+			return "", nil, invalid
+		}
+
+		return attr.column + " " + operator + " ?", []any{id}, nil
+	case scimMember:
+		value, ok := c.Value.(string)
+		if !ok || c.Op != scim.OpEqual {
+			return "", nil, invalid
+		}
+
+		return "EXISTS(SELECT 1 FROM relation_tuples WHERE " + groupMembers + " AND " + attr.column + " = ?)", []any{value}, nil
+	}
+
+	return "", nil, invalid
+}
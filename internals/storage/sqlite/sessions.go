@@ -0,0 +1,173 @@
+package sqlite
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"github.com/nhassl3/sso-app/internals/domain/models"
+	"github.com/nhassl3/sso-app/internals/lib/logger/sl"
+)
+
+const (
+	opSaveSession          = "storage.sqlite.SaveSession"
+	opEndSessions          = "storage.sqlite.EndSessions"
+	opDueLogoutDeliveries  = "storage.sqlite.DueLogoutDeliveries"
+	opUpdateLogoutDelivery = "storage.sqlite.UpdateLogoutDelivery"
+	opLogoutDeliveries     = "storage.sqlite.LogoutDeliveries"
+)
+
+// logoutDeliveriesColumns are columns of the deliveries joined with their applications scanned by logoutDeliveries
+const logoutDeliveriesColumns = `logout_deliveries.id, logout_deliveries.app_id, logout_deliveries.user_id,
+apps.backchannel_logout_uri, logout_deliveries.status, logout_deliveries.attempts, logout_deliveries.next_attempt_at,
+logout_deliveries.last_error, logout_deliveries.created_at, logout_deliveries.delivered_at`
+
+// SaveSession saves that the user got token for the application, expiry of the session is extended to the token
+func (s *Storage) SaveSession(ctx context.Context, userID int64, appID int32, expiresAt time.Time) error {
+	_, err := s.db.ExecContext(
+		ctx,
+		`INSERT INTO sessions (user_id, app_id, expires_at) VALUES (?, ?, ?)
+ON CONFLICT (user_id, app_id) DO UPDATE SET expires_at = MAX(expires_at, excluded.expires_at)`,
+		userID, appID, expiresAt.Unix(),
+	)
+	if err != nil {
+		return sl.ErrUpLevel(opSaveSession, err)
+	}
+
+	return nil
+}
+
+// EndSessions ends sessions of the user in the application, zero app ID ends sessions in all applications.
+// Refresh tokens of the sessions are deleted, applications of the active sessions with back-channel logout URI
+// get logout deliveries. Returns count of the created deliveries
+func (s *Storage) EndSessions(ctx context.Context, userID int64, appID int32) (notified int, err error) {
+	err = s.inTx(ctx, func(tx *sql.Tx) error {
+		notified, err = endSessions(ctx, tx, userID, appID)
+
+		return err
+	})
+	if err != nil {
+		return 0, sl.ErrUpLevel(opEndSessions, err)
+	}
+
+	return
+}
+
+// endSessions ends sessions of the user in the transaction as EndSessions does
+func endSessions(ctx context.Context, tx *sql.Tx, userID int64, appID int32) (notified int, err error) {
+	res, err := tx.ExecContext(
+		ctx,
+		`INSERT INTO logout_deliveries (app_id, user_id, next_attempt_at)
+SELECT sessions.app_id, sessions.user_id, ? FROM sessions JOIN apps ON apps.id = sessions.app_id
+WHERE sessions.user_id = ? AND (? = 0 OR sessions.app_id = ?)
+AND sessions.expires_at > unixepoch() AND apps.backchannel_logout_uri != ''`,
+		time.Now().UnixMilli(), userID, appID, appID,
+	)
+	if err != nil {
+		return 0, err
+	}
+
+	affected, err := res.RowsAffected()
+	if err != nil {
+		return 0, err
+	}
+
+	for _, table := range []string{"sessions", "refresh_tokens"} {
+		_, err := tx.ExecContext(ctx, "DELETE FROM "+table+" WHERE user_id = ? AND (? = 0 OR app_id = ?)", userID, appID, appID)
+		if err != nil {
+			return 0, err
+		}
+	}
+
+	return int(affected), nil
+}
+
+// DueLogoutDeliveries returns pending deliveries which next attempt is due, the oldest first
+func (s *Storage) DueLogoutDeliveries(ctx context.Context, now time.Time, limit int) (deliveries []models.LogoutDelivery, err error) {
+	deliveries, err = s.logoutDeliveries(
+		ctx,
+		`SELECT `+logoutDeliveriesColumns+` FROM logout_deliveries JOIN apps ON apps.id = logout_deliveries.app_id
+WHERE logout_deliveries.status = ? AND logout_deliveries.next_attempt_at <= ?
+ORDER BY logout_deliveries.id
+LIMIT ?`,
+		models.LogoutPending, now.UnixMilli(), limit,
+	)
+	if err != nil {
+		return nil, sl.ErrUpLevel(opDueLogoutDeliveries, err)
+	}
+
+	return
+}
+
+// UpdateLogoutDelivery saves status, attempts and error of the delivery
+func (s *Storage) UpdateLogoutDelivery(ctx context.Context, delivery models.LogoutDelivery) error {
+	var deliveredAt int64
+	if !delivery.DeliveredAt.IsZero() {
+		deliveredAt = delivery.DeliveredAt.Unix()
+	}
+
+	_, err := s.db.ExecContext(
+		ctx,
+		`UPDATE logout_deliveries
+SET status = ?, attempts = ?, next_attempt_at = ?, last_error = ?, delivered_at = ?
+WHERE id = ?`,
+		delivery.Status, delivery.Attempts, delivery.NextAttemptAt.UnixMilli(), delivery.LastError, deliveredAt, delivery.ID,
+	)
+	if err != nil {
+		return sl.ErrUpLevel(opUpdateLogoutDelivery, err)
+	}
+
+	return nil
+}
+
+// LogoutDeliveries returns the latest deliveries to the application, zero app ID returns deliveries to all applications
+func (s *Storage) LogoutDeliveries(ctx context.Context, appID int32, limit int) (deliveries []models.LogoutDelivery, err error) {
+	deliveries, err = s.logoutDeliveries(
+		ctx,
+		`SELECT `+logoutDeliveriesColumns+` FROM logout_deliveries JOIN apps ON apps.id = logout_deliveries.app_id
+WHERE ? = 0 OR logout_deliveries.app_id = ?
+ORDER BY logout_deliveries.id DESC
+LIMIT ?`,
+		appID, appID, limit,
+	)
+	if err != nil {
+		return nil, sl.ErrUpLevel(opLogoutDeliveries, err)
+	}
+
+	return
+}
+
+// logoutDeliveries returns deliveries selected by the query of logoutDeliveriesColumns
+func (s *Storage) logoutDeliveries(ctx context.Context, query string, args ...any) ([]models.LogoutDelivery, error) {
+	rows, err := s.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var deliveries []models.LogoutDelivery
+	for rows.Next() {
+		var (
+			delivery                              models.LogoutDelivery
+			nextAttemptAt, createdAt, deliveredAt int64
+		)
+
+		err := rows.Scan(
+			&delivery.ID, &delivery.AppID, &delivery.UserID, &delivery.LogoutURI, &delivery.Status, &delivery.Attempts,
+			&nextAttemptAt, &delivery.LastError, &createdAt, &deliveredAt,
+		)
+		if err != nil {
+			return nil, err
+		}
+
+		delivery.NextAttemptAt = time.UnixMilli(nextAttemptAt)
+		delivery.CreatedAt = time.Unix(createdAt, 0)
+		if deliveredAt != 0 {
+			delivery.DeliveredAt = time.Unix(deliveredAt, 0)
+		}
+
+		deliveries = append(deliveries, delivery)
+	}
+
+	return deliveries, rows.Err()
+}
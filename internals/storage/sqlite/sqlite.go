@@ -4,6 +4,8 @@ import (
 	"context"
 	"database/sql"
 	"errors"
+	"slices"
+	"strings"
 
 	"github.com/mattn/go-sqlite3"
 	"github.com/nhassl3/sso-app/internals/domain/models"
@@ -15,8 +17,12 @@ const (
 	opNewStorage = "storage.sqlite.NewStorage"
 	opSaveUser   = "storage.sqlite.SaveUser"
 	opUser       = "storage.sqlite.User"
+	opUserByID   = "storage.sqlite.UserByID"
 	opIsAdmin    = "storage.sqlite.IsAdmin"
+	opIsAppAdmin = "storage.sqlite.IsAppAdmin"
 	opApp        = "storage.sqlite.App"
+	opUserRoles  = "storage.sqlite.UserRoles"
+	opSyncRoles  = "storage.sqlite.SyncUserRoles"
 )
 
 type Storage struct {
@@ -25,36 +31,32 @@ type Storage struct {
 
 // NewStorage creates a new instance of the SQLite storage.
 func NewStorage(storagePath string) (*Storage, error) {
-	db, err := sql.Open("sqlite3", storagePath)
+	// Transactions take write lock at the beginning, otherwise concurrent
+	// read-then-write transactions fail with busy error instead of waiting
+	db, err := sql.Open("sqlite3", withParam(storagePath, "_txlock=immediate"))
 	if err != nil {
-		return nil, sl.ErrUpLevel(opNewStorage, err.Error())
+		return nil, sl.ErrUpLevel(opNewStorage, err)
 	}
 
 	return &Storage{db: db}, nil
 }
 
-// SaveUser save user in the system
+// SaveUser save user in the system and its registration event in the outbox
 func (s *Storage) SaveUser(ctx context.Context, email string, hashPassword []byte) (userID int64, err error) {
-	stmt, err := s.db.PrepareContext(ctx, "INSERT INTO users (email, pass_hash) VALUES (?, ?)")
-	if err != nil {
-		return 0, sl.ErrUpLevel(opSaveUser, err.Error())
-	}
-	defer stmt.Close()
-
-	var sqliteErr sqlite3.Error
-
-	res, err := stmt.ExecContext(ctx, email, hashPassword)
-	if err != nil {
-		if errors.As(err, &sqliteErr) && errors.Is(sqliteErr.ExtendedCode, sqlite3.ErrConstraintUnique) {
-			return 0, sl.ErrUpLevel(opSaveUser, storage.ErrUserExists.Error())
+	err = s.inTx(ctx, func(tx *sql.Tx) error {
+		res, err := tx.ExecContext(ctx, "INSERT INTO users (email, pass_hash) VALUES (?, ?)", email, hashPassword)
+		if err != nil {
+			return uniqueErr(err, storage.ErrUserExists)
 		}
 
-		return 0, sl.ErrUpLevel(opSaveUser, err.Error())
-	}
+		if userID, err = res.LastInsertId(); err != nil {
+			return err
+		}
 
-	userID, err = res.LastInsertId()
+		return saveEvent(ctx, tx, models.Event{Type: models.EventUserRegistered, UserID: userID})
+	})
 	if err != nil {
-		return 0, sl.ErrUpLevel(opSaveUser, err.Error())
+		return 0, sl.ErrUpLevel(opSaveUser, err)
 	}
 
 	return
@@ -64,29 +66,51 @@ func (s *Storage) SaveUser(ctx context.Context, email string, hashPassword []byt
 func (s *Storage) User(ctx context.Context, email string) (user models.User, err error) {
 	err = s.newSelect(
 		ctx,
-		"SELECT id, email, pass_hash FROM users WHERE email=?",
+		"SELECT id, email, pass_hash, disabled FROM users WHERE email=?",
 		[]interface{}{email},
-		&user.ID, &user.Email, &user.HashPassword,
+		&user.ID, &user.Email, &user.HashPassword, &user.Disabled,
 	)
 
 	if err != nil {
 		if errors.Is(err, sql.ErrNoRows) {
-			return models.User{}, sl.ErrUpLevel(opUser, storage.ErrUserNotFound.Error())
+			return models.User{}, sl.ErrUpLevel(opUser, storage.ErrUserNotFound)
 		}
 
-		return models.User{}, sl.ErrUpLevel(opUser, err.Error())
+		return models.User{}, sl.ErrUpLevel(opUser, err)
 	}
 
 	return
 }
 
-// IsAdmin checks by UID if user is admin returns true else false
+// UserByID returns user model by UID
+func (s *Storage) UserByID(ctx context.Context, userID int64) (user models.User, err error) {
+	err = s.newSelect(
+		ctx,
+		"SELECT id, email, pass_hash, disabled FROM users WHERE id=?",
+		[]interface{}{userID},
+		&user.ID, &user.Email, &user.HashPassword, &user.Disabled,
+	)
+
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return models.User{}, sl.ErrUpLevel(opUserByID, storage.ErrUserNotFound)
+		}
+
+		return models.User{}, sl.ErrUpLevel(opUserByID, err)
+	}
+
+	return
+}
+
+// IsAdmin checks by UID if user is super-admin returns true else false
 func (s *Storage) IsAdmin(ctx context.Context, userID int64) (isAdmin bool, err error) {
 	err = s.newSelect(
 		ctx,
 		`SELECT EXISTS(
     SELECT 1 FROM admins
              WHERE admins.user_id = ?
+             AND admins.app_id IS NULL
+             AND `+activeAdmin+`
              AND EXISTS(SELECT 1 FROM users WHERE users.id = ?)
 )`,
 		[]interface{}{userID, userID}, // Two user IDs need to be transferred
@@ -95,35 +119,202 @@ func (s *Storage) IsAdmin(ctx context.Context, userID int64) (isAdmin bool, err
 
 	if err != nil {
 		if errors.Is(err, sql.ErrNoRows) {
-			return false, sl.ErrUpLevel(opIsAdmin, storage.ErrUserNotFound.Error())
+			return false, sl.ErrUpLevel(opIsAdmin, storage.ErrUserNotFound)
 		}
 
-		return false, sl.ErrUpLevel(opIsAdmin, err.Error())
+		return false, sl.ErrUpLevel(opIsAdmin, err)
 	}
 
 	return
 }
 
+// IsAppAdmin checks by UID if user is admin of the application or super-admin.
+// Returns error if application doesn't exist
+func (s *Storage) IsAppAdmin(ctx context.Context, userID int64, appID int32) (isAdmin bool, isSuperAdmin bool, err error) {
+	var appExists bool
+
+	err = s.newSelect(
+		ctx,
+		`SELECT EXISTS(SELECT 1 FROM apps WHERE apps.id = ?),
+       EXISTS(SELECT 1 FROM admins WHERE admins.user_id = ? AND admins.app_id = ? AND `+activeAdmin+`),
+       EXISTS(SELECT 1 FROM admins WHERE admins.user_id = ? AND admins.app_id IS NULL AND `+activeAdmin+`)`,
+		[]interface{}{appID, userID, appID, userID},
+		&appExists, &isAdmin, &isSuperAdmin,
+	)
+	if err != nil {
+		return false, false, sl.ErrUpLevel(opIsAppAdmin, err)
+	}
+
+	if !appExists {
+		return false, false, sl.ErrUpLevel(opIsAppAdmin, storage.ErrAppNotFound)
+	}
+
+	// Super-admin has admin rights in every application
+	return isAdmin || isSuperAdmin, isSuperAdmin, nil
+}
+
 // App returns model of an application
 func (s *Storage) App(ctx context.Context, appID int32) (app models.App, err error) {
+	settings := scanSettings(&app.AppSettings)
+
 	err = s.newSelect(
 		ctx,
-		"SELECT id, name, secret FROM apps WHERE id = ?",
+		"SELECT id, name, secret, client_secret_hash, disabled, "+settingsSelect+" FROM apps WHERE id = ?",
 		[]interface{}{appID},
-		&app.ID, &app.Name, &app.Secret,
+		append([]any{&app.ID, &app.Name, &app.Secret, &app.ClientSecretHash, &app.Disabled}, settings.dest()...)...,
 	)
 
 	if err != nil {
 		if errors.Is(err, sql.ErrNoRows) {
-			return models.App{}, sl.ErrUpLevel(opApp, storage.ErrAppNotFound.Error())
+			return models.App{}, sl.ErrUpLevel(opApp, storage.ErrAppNotFound)
 		}
 
-		return models.App{}, sl.ErrUpLevel(opApp, err.Error())
+		return models.App{}, sl.ErrUpLevel(opApp, err)
+	}
+
+	if err := settings.decode(); err != nil {
+		return models.App{}, sl.ErrUpLevel(opApp, err)
 	}
 
 	return
 }
 
+// UserRoles returns roles of the user in the application with scopes granted by every role
+func (s *Storage) UserRoles(ctx context.Context, userID int64, appID int32) (roles []models.Role, err error) {
+	rows, err := s.db.QueryContext(
+		ctx,
+		`SELECT user_roles.role, role_scopes.scope FROM user_roles
+    LEFT JOIN role_scopes ON role_scopes.app_id = user_roles.app_id AND role_scopes.role = user_roles.role
+             WHERE user_roles.user_id = ? AND user_roles.app_id = ?
+             ORDER BY user_roles.role, role_scopes.scope`,
+		userID, appID,
+	)
+	if err != nil {
+		return nil, sl.ErrUpLevel(opUserRoles, err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var (
+			role  string
+			scope sql.NullString
+		)
+
+		if err := rows.Scan(&role, &scope); err != nil {
+			return nil, sl.ErrUpLevel(opUserRoles, err)
+		}
+
+		// Rows are ordered by role, so scopes of the same role go one after another
+		if len(roles) == 0 || roles[len(roles)-1].Name != role {
+			roles = append(roles, models.Role{Name: role})
+		}
+
+		if scope.Valid {
+			roles[len(roles)-1].Scopes = append(roles[len(roles)-1].Scopes, scope.String)
+		}
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, sl.ErrUpLevel(opUserRoles, err)
+	}
+
+	return
+}
+
+// SyncUserRoles makes the user have the granted roles and none of the other managed roles, roles of the user
+// outside of the managed ones are kept. Roles of unknown applications are skipped.
+// Every application which roles of the user are changed gets the event in the outbox
+func (s *Storage) SyncUserRoles(ctx context.Context, userID int64, managed []models.AppRole, granted []models.AppRole) error {
+	err := s.inTx(ctx, func(tx *sql.Tx) error {
+		// Granted roles are deleted and inserted back, so the roles are compared with the roles before the sync
+		before, err := userAppRoles(ctx, tx, userID)
+		if err != nil {
+			return err
+		}
+
+		for _, role := range managed {
+			_, err := tx.ExecContext(
+				ctx,
+				"DELETE FROM user_roles WHERE user_id = ? AND app_id = ? AND role = ?",
+				userID, role.AppID, role.Role,
+			)
+			if err != nil {
+				return err
+			}
+		}
+
+		for _, role := range granted {
+			_, err := tx.ExecContext(
+				ctx,
+				"INSERT OR IGNORE INTO user_roles (user_id, app_id, role) SELECT ?, id, ? FROM apps WHERE id = ?",
+				userID, role.Role, role.AppID,
+			)
+			if err != nil {
+				return err
+			}
+		}
+
+		after, err := userAppRoles(ctx, tx, userID)
+		if err != nil {
+			return err
+		}
+
+		for _, appID := range changedApps(before, after) {
+			err := saveEvent(ctx, tx, models.Event{Type: models.EventUserRolesChanged, UserID: userID, AppID: appID})
+			if err != nil {
+				return err
+			}
+		}
+
+		return nil
+	})
+	if err != nil {
+		return sl.ErrUpLevel(opSyncRoles, err)
+	}
+
+	return nil
+}
+
+// userAppRoles returns roles of the user in all applications
+func userAppRoles(ctx context.Context, tx *sql.Tx, userID int64) (map[models.AppRole]bool, error) {
+	rows, err := tx.QueryContext(ctx, "SELECT app_id, role FROM user_roles WHERE user_id = ?", userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	roles := make(map[models.AppRole]bool)
+	for rows.Next() {
+		var role models.AppRole
+		if err := rows.Scan(&role.AppID, &role.Role); err != nil {
+			return nil, err
+		}
+
+		roles[role] = true
+	}
+
+	return roles, rows.Err()
+}
+
+// changedApps returns sorted applications which roles differ in the sets
+func changedApps(before, after map[models.AppRole]bool) []int32 {
+	var appIDs []int32
+	for role := range before {
+		if !after[role] && !slices.Contains(appIDs, role.AppID) {
+			appIDs = append(appIDs, role.AppID)
+		}
+	}
+
+	for role := range after {
+		if !before[role] && !slices.Contains(appIDs, role.AppID) {
+			appIDs = append(appIDs, role.AppID)
+		}
+	}
+	slices.Sort(appIDs)
+
+	return appIDs
+}
+
 // newSelect cleaning code deletes duplicates
 func (s *Storage) newSelect(ctx context.Context, query string, args []interface{}, dest ...interface{}) error {
 	stmt, err := s.db.PrepareContext(ctx, query)
@@ -141,3 +332,37 @@ func (s *Storage) newSelect(ctx context.Context, query string, args []interface{
 
 	return nil
 }
+
+// inTx runs fn in the transaction, commits it if fn succeeds else rollbacks
+func (s *Storage) inTx(ctx context.Context, fn func(tx *sql.Tx) error) error {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+
+	if err := fn(tx); err != nil {
+		_ = tx.Rollback()
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// uniqueErr converts unique constraint error to the storage error
+func uniqueErr(err error, exists error) error {
+	var sqliteErr sqlite3.Error
+	if errors.As(err, &sqliteErr) && errors.Is(sqliteErr.ExtendedCode, sqlite3.ErrConstraintUnique) {
+		return exists
+	}
+
+	return err
+}
+
+// withParam adds connection parameter to the storage path
+func withParam(storagePath, param string) string {
+	if strings.Contains(storagePath, "?") {
+		return storagePath + "&" + param
+	}
+
+	return storagePath + "?" + param
+}
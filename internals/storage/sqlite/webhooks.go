@@ -0,0 +1,315 @@
+package sqlite
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/nhassl3/sso-app/internals/domain/models"
+	"github.com/nhassl3/sso-app/internals/lib/logger/sl"
+	"github.com/nhassl3/sso-app/internals/storage"
+)
+
+const (
+	opSaveWebhookSubscription   = "storage.sqlite.SaveWebhookSubscription"
+	opWebhookSubscriptions      = "storage.sqlite.WebhookSubscriptions"
+	opDeleteWebhookSubscription = "storage.sqlite.DeleteWebhookSubscription"
+	opSaveWebhookEvent          = "storage.sqlite.SaveWebhookEvent"
+	opDueWebhookDeliveries      = "storage.sqlite.DueWebhookDeliveries"
+	opUpdateWebhookDelivery     = "storage.sqlite.UpdateWebhookDelivery"
+	opWebhookDeliveries         = "storage.sqlite.WebhookDeliveries"
+	opRedeliverWebhook          = "storage.sqlite.RedeliverWebhook"
+)
+
+// webhookDeliveriesColumns are columns of the deliveries joined with their subscriptions scanned by webhookDeliveries
+const webhookDeliveriesColumns = `webhook_deliveries.id, webhook_deliveries.app_id, webhook_deliveries.subscription_id,
+webhook_subscriptions.url, webhook_subscriptions.secret, webhook_deliveries.event_id, webhook_deliveries.event_type,
+webhook_deliveries.payload, webhook_deliveries.status, webhook_deliveries.attempts, webhook_deliveries.next_attempt_at,
+webhook_deliveries.last_error, webhook_deliveries.created_at, webhook_deliveries.delivered_at`
+
+// SaveWebhookSubscription saves the subscription of the application and audit event of the actor
+func (s *Storage) SaveWebhookSubscription(
+	ctx context.Context,
+	actorID int64,
+	subscription models.WebhookSubscription,
+) (subscriptionID int64, err error) {
+	events := subscription.Events
+	if events == nil {
+		events = []string{}
+	}
+
+	rawEvents, err := json.Marshal(events)
+	if err != nil {
+		return 0, sl.ErrUpLevel(opSaveWebhookSubscription, err)
+	}
+
+	err = s.inTx(ctx, func(tx *sql.Tx) error {
+		if err := checkApp(ctx, tx, subscription.AppID); err != nil {
+			return err
+		}
+
+		res, err := tx.ExecContext(
+			ctx,
+			"INSERT INTO webhook_subscriptions (app_id, url, secret, events, all_users) VALUES (?, ?, ?, ?, ?)",
+			subscription.AppID, subscription.URL, subscription.Secret, string(rawEvents), subscription.AllUsers,
+		)
+		if err != nil {
+			return err
+		}
+
+		subscriptionID, err = res.LastInsertId()
+		if err != nil {
+			return err
+		}
+
+		return saveAuditEvent(ctx, tx, models.AuditEvent{
+			ActorID: actorID,
+			Action:  models.AuditActionCreateWebhook,
+			AppID:   subscription.AppID,
+			Details: subscription.URL,
+		})
+	})
+	if err != nil {
+		return 0, sl.ErrUpLevel(opSaveWebhookSubscription, err)
+	}
+
+	return
+}
+
+// WebhookSubscriptions returns subscriptions of the application
+func (s *Storage) WebhookSubscriptions(ctx context.Context, appID int32) (subscriptions []models.WebhookSubscription, err error) {
+	rows, err := s.db.QueryContext(
+		ctx,
+		"SELECT id, app_id, url, secret, events, all_users, created_at FROM webhook_subscriptions WHERE app_id = ? ORDER BY id",
+		appID,
+	)
+	if err != nil {
+		return nil, sl.ErrUpLevel(opWebhookSubscriptions, err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var (
+			subscription models.WebhookSubscription
+			events       string
+			createdAt    int64
+		)
+
+		err := rows.Scan(
+			&subscription.ID, &subscription.AppID, &subscription.URL, &subscription.Secret, &events, &subscription.AllUsers,
+			&createdAt,
+		)
+		if err != nil {
+			return nil, sl.ErrUpLevel(opWebhookSubscriptions, err)
+		}
+
+		if err := json.Unmarshal([]byte(events), &subscription.Events); err != nil {
+			return nil, sl.ErrUpLevel(opWebhookSubscriptions, err)
+		}
+		subscription.CreatedAt = time.Unix(createdAt, 0)
+
+		subscriptions = append(subscriptions, subscription)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, sl.ErrUpLevel(opWebhookSubscriptions, err)
+	}
+
+	return
+}
+
+// DeleteWebhookSubscription deletes the subscription of the application with its deliveries
+// and saves audit event of the actor
+func (s *Storage) DeleteWebhookSubscription(ctx context.Context, actorID int64, appID int32, subscriptionID int64) error {
+	err := s.inTx(ctx, func(tx *sql.Tx) error {
+		res, err := tx.ExecContext(
+			ctx,
+			"DELETE FROM webhook_subscriptions WHERE id = ? AND app_id = ?",
+			subscriptionID, appID,
+		)
+		if err != nil {
+			return err
+		}
+
+		if err := mustAffect(res, storage.ErrWebhookNotFound); err != nil {
+			return err
+		}
+
+		_, err = tx.ExecContext(ctx, "DELETE FROM webhook_deliveries WHERE subscription_id = ?", subscriptionID)
+		if err != nil {
+			return err
+		}
+
+		return saveAuditEvent(ctx, tx, models.AuditEvent{
+			ActorID: actorID,
+			Action:  models.AuditActionDeleteWebhook,
+			AppID:   appID,
+			Details: fmt.Sprintf("subscription %d", subscriptionID),
+		})
+	})
+	if err != nil {
+		return sl.ErrUpLevel(opDeleteWebhookSubscription, err)
+	}
+
+	return nil
+}
+
+// SaveWebhookEvent queues delivery of the payload of the event to every subscription of the event type.
+// Event of the application is queued only for its subscriptions, event with zero app ID
+// is queued only for subscriptions of all users, so data of the users isn't sent to every application.
+// Event already queued for the subscription is skipped. Returns count of the queued deliveries
+func (s *Storage) SaveWebhookEvent(ctx context.Context, event models.Event, payload []byte) (queued int, err error) {
+	res, err := s.db.ExecContext(
+		ctx,
+		`INSERT OR IGNORE INTO webhook_deliveries (app_id, subscription_id, event_id, event_type, payload, next_attempt_at)
+SELECT app_id, id, ?, ?, ?, ? FROM webhook_subscriptions
+WHERE (app_id = ? OR (? = 0 AND all_users))
+AND (json_array_length(events) = 0 OR EXISTS (SELECT 1 FROM json_each(events) WHERE json_each.value = ?))`,
+		event.ID, event.Type, string(payload), event.CreatedAt.UnixMilli(), event.AppID, event.AppID, event.Type,
+	)
+	if err != nil {
+		return 0, sl.ErrUpLevel(opSaveWebhookEvent, err)
+	}
+
+	affected, err := res.RowsAffected()
+	if err != nil {
+		return 0, sl.ErrUpLevel(opSaveWebhookEvent, err)
+	}
+
+	return int(affected), nil
+}
+
+// DueWebhookDeliveries returns pending deliveries which next attempt is due, the oldest first
+func (s *Storage) DueWebhookDeliveries(ctx context.Context, now time.Time, limit int) (deliveries []models.WebhookDelivery, err error) {
+	deliveries, err = s.webhookDeliveries(
+		ctx,
+		`SELECT `+webhookDeliveriesColumns+` FROM webhook_deliveries
+JOIN webhook_subscriptions ON webhook_subscriptions.id = webhook_deliveries.subscription_id
+WHERE webhook_deliveries.status = ? AND webhook_deliveries.next_attempt_at <= ?
+ORDER BY webhook_deliveries.id
+LIMIT ?`,
+		models.WebhookPending, now.UnixMilli(), limit,
+	)
+	if err != nil {
+		return nil, sl.ErrUpLevel(opDueWebhookDeliveries, err)
+	}
+
+	return
+}
+
+// UpdateWebhookDelivery saves status, attempts and error of the delivery
+func (s *Storage) UpdateWebhookDelivery(ctx context.Context, delivery models.WebhookDelivery) error {
+	var deliveredAt int64
+	if !delivery.DeliveredAt.IsZero() {
+		deliveredAt = delivery.DeliveredAt.Unix()
+	}
+
+	_, err := s.db.ExecContext(
+		ctx,
+		`UPDATE webhook_deliveries
+SET status = ?, attempts = ?, next_attempt_at = ?, last_error = ?, delivered_at = ?
+WHERE id = ?`,
+		delivery.Status, delivery.Attempts, delivery.NextAttemptAt.UnixMilli(), delivery.LastError, deliveredAt, delivery.ID,
+	)
+	if err != nil {
+		return sl.ErrUpLevel(opUpdateWebhookDelivery, err)
+	}
+
+	return nil
+}
+
+// WebhookDeliveries returns the latest deliveries of the application with the status, empty status returns all of them
+func (s *Storage) WebhookDeliveries(
+	ctx context.Context,
+	appID int32,
+	status string,
+	limit int,
+) (deliveries []models.WebhookDelivery, err error) {
+	deliveries, err = s.webhookDeliveries(
+		ctx,
+		`SELECT `+webhookDeliveriesColumns+` FROM webhook_deliveries
+JOIN webhook_subscriptions ON webhook_subscriptions.id = webhook_deliveries.subscription_id
+WHERE webhook_deliveries.app_id = ? AND (? = '' OR webhook_deliveries.status = ?)
+ORDER BY webhook_deliveries.id DESC
+LIMIT ?`,
+		appID, status, status, limit,
+	)
+	if err != nil {
+		return nil, sl.ErrUpLevel(opWebhookDeliveries, err)
+	}
+
+	return
+}
+
+// RedeliverWebhook returns the delivery of the application to the queue with fresh attempts
+// and saves audit event of the actor, pending delivery is not found
+func (s *Storage) RedeliverWebhook(ctx context.Context, actorID int64, appID int32, deliveryID int64, now time.Time) error {
+	err := s.inTx(ctx, func(tx *sql.Tx) error {
+		res, err := tx.ExecContext(
+			ctx,
+			`UPDATE webhook_deliveries
+SET status = ?, attempts = 0, next_attempt_at = ?, last_error = '', delivered_at = 0
+WHERE id = ? AND app_id = ? AND status != ?`,
+			models.WebhookPending, now.UnixMilli(), deliveryID, appID, models.WebhookPending,
+		)
+		if err != nil {
+			return err
+		}
+
+		if err := mustAffect(res, storage.ErrDeliveryNotFound); err != nil {
+			return err
+		}
+
+		return saveAuditEvent(ctx, tx, models.AuditEvent{
+			ActorID: actorID,
+			Action:  models.AuditActionRedeliverWebhook,
+			AppID:   appID,
+			Details: fmt.Sprintf("delivery %d", deliveryID),
+		})
+	})
+	if err != nil {
+		return sl.ErrUpLevel(opRedeliverWebhook, err)
+	}
+
+	return nil
+}
+
+// webhookDeliveries returns deliveries selected by the query of webhookDeliveriesColumns
+func (s *Storage) webhookDeliveries(ctx context.Context, query string, args ...any) ([]models.WebhookDelivery, error) {
+	rows, err := s.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var deliveries []models.WebhookDelivery
+	for rows.Next() {
+		var (
+			delivery                              models.WebhookDelivery
+			payload                               string
+			nextAttemptAt, createdAt, deliveredAt int64
+		)
+
+		err := rows.Scan(
+			&delivery.ID, &delivery.AppID, &delivery.SubscriptionID, &delivery.URL, &delivery.Secret,
+			&delivery.EventID, &delivery.EventType, &payload, &delivery.Status, &delivery.Attempts,
+			&nextAttemptAt, &delivery.LastError, &createdAt, &deliveredAt,
+		)
+		if err != nil {
+			return nil, err
+		}
+
+		delivery.Payload = []byte(payload)
+		delivery.NextAttemptAt = time.UnixMilli(nextAttemptAt)
+		delivery.CreatedAt = time.Unix(createdAt, 0)
+		if deliveredAt != 0 {
+			delivery.DeliveredAt = time.Unix(deliveredAt, 0)
+		}
+
+		deliveries = append(deliveries, delivery)
+	}
+
+	return deliveries, rows.Err()
+}
@@ -1,9 +1,48 @@
 package storage
 
-import "errors"
+import (
+	"errors"
+
+	"github.com/nhassl3/sso-app/internals/domain/models"
+)
 
 var (
 	ErrUserNotFound = errors.New("user not found")
 	ErrAppNotFound  = errors.New("application not found")
 	ErrUserExists   = errors.New("user already exists")
+	ErrAppExists    = errors.New("application already exists")
+
+	ErrLastSuperAdmin = errors.New("last super-admin can't be revoked")
+
+	ErrElevationNotFound   = errors.New("elevation request not found")
+	ErrElevationNotPending = errors.New("elevation request is already decided")
+
+	ErrNamespaceConfigNotFound = errors.New("namespace config not found")
+	ErrPolicyNotFound          = errors.New("policy not found")
+
+	ErrAuthCodeNotFound     = errors.New("authorization code not found")
+	ErrDeviceCodeNotFound   = errors.New("device code not found")
+	ErrRefreshTokenNotFound = errors.New("refresh token not found")
+	ErrConsentNotFound      = errors.New("consent not found")
+	ErrUserCodeExists       = errors.New("user code already exists")
+
+	ErrFederationStateNotFound = errors.New("federation state not found")
+	ErrIdentityNotFound        = errors.New("federated identity not found")
+	ErrIdentityExists          = errors.New("federated identity is already linked")
+
+	ErrGroupNotFound   = errors.New("group not found")
+	ErrGroupExists     = errors.New("group already exists")
+	ErrVersionConflict = errors.New("resource is changed concurrently")
+	ErrInvalidFilter   = errors.New("filter is invalid")
+
+	ErrWebhookNotFound  = errors.New("webhook subscription not found")
+	ErrDeliveryNotFound = errors.New("webhook delivery not found")
 )
+
+// TupleReader reads relation tuples of the application from one consistent snapshot
+type TupleReader interface {
+	// Subjects returns subjects which have the relation with the object
+	Subjects(object models.Object, relation string) (subjects []models.Subject, err error)
+	// Objects returns IDs of the objects of the type which have any relation
+	Objects(objectType string) (objectIDs []string, err error)
+}
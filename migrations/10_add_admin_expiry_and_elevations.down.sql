@@ -0,0 +1,3 @@
+DROP TABLE IF EXISTS elevation_requests;
+DROP INDEX IF EXISTS idx_admins_expires_at;
+ALTER TABLE admins DROP COLUMN expires_at;
@@ -0,0 +1,19 @@
+-- Admin rights with expiry time (unix seconds) are temporary, the sweeper removes them after expiry
+ALTER TABLE admins ADD COLUMN expires_at INTEGER;
+
+CREATE INDEX IF NOT EXISTS idx_admins_expires_at ON admins (expires_at) WHERE expires_at IS NOT NULL;
+
+-- Requests of the users for temporary admin rights, another admin approves or denies them
+CREATE TABLE IF NOT EXISTS elevation_requests
+(
+    id INTEGER PRIMARY KEY,
+    user_id INTEGER NOT NULL REFERENCES users(id),
+    app_id INTEGER REFERENCES apps(id),
+    ttl INTEGER NOT NULL,
+    reason TEXT NOT NULL DEFAULT '',
+    status TEXT NOT NULL DEFAULT 'pending',
+    decided_by INTEGER REFERENCES users(id),
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_elevation_requests_app_status ON elevation_requests (app_id, status);
@@ -0,0 +1,16 @@
+-- Redirect URIs of the OAuth client of the application as JSON array
+ALTER TABLE apps ADD COLUMN redirect_uris TEXT NOT NULL DEFAULT '[]';
+
+-- Codes are kept as SHA-256 hashes and deleted by the exchange, so every code is used once
+CREATE TABLE IF NOT EXISTS authorization_codes
+(
+    code_hash TEXT PRIMARY KEY,
+    app_id INTEGER NOT NULL REFERENCES apps(id),
+    user_id INTEGER NOT NULL REFERENCES users(id),
+    redirect_uri TEXT NOT NULL,
+    scope TEXT NOT NULL DEFAULT '',
+    code_challenge TEXT NOT NULL,
+    expires_at INTEGER NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS idx_authorization_codes_expires_at ON authorization_codes (expires_at);
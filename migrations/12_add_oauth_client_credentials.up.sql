@@ -0,0 +1,5 @@
+-- Client secret of the OAuth client is kept as bcrypt hash, unlike the secret which signs tokens of the application
+ALTER TABLE apps ADD COLUMN client_secret_hash BLOB;
+
+-- Scopes the client may get by the client credentials grant as JSON array
+ALTER TABLE apps ADD COLUMN client_scopes TEXT NOT NULL DEFAULT '[]';
@@ -0,0 +1,3 @@
+-- Nonce and authentication time of the authorization request are put into ID token
+ALTER TABLE authorization_codes ADD COLUMN nonce TEXT NOT NULL DEFAULT '';
+ALTER TABLE authorization_codes ADD COLUMN auth_time INTEGER NOT NULL DEFAULT 0;
@@ -0,0 +1,15 @@
+-- Device codes are kept as SHA-256 hashes, user codes are kept normalized: upper case without dashes
+CREATE TABLE IF NOT EXISTS device_codes
+(
+    device_code_hash TEXT PRIMARY KEY,
+    user_code TEXT NOT NULL UNIQUE,
+    app_id INTEGER NOT NULL REFERENCES apps(id),
+    scope TEXT NOT NULL DEFAULT '',
+    status TEXT NOT NULL DEFAULT 'pending',
+    user_id INTEGER NOT NULL DEFAULT 0,
+    poll_interval INTEGER NOT NULL, -- seconds
+    last_polled_at INTEGER NOT NULL DEFAULT 0, -- unix milliseconds, polls are compared with the interval
+    expires_at INTEGER NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS idx_device_codes_expires_at ON device_codes (expires_at);
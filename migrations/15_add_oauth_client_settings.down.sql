@@ -0,0 +1,6 @@
+DROP TABLE IF EXISTS refresh_tokens;
+ALTER TABLE apps DROP COLUMN public_client;
+ALTER TABLE apps DROP COLUMN refresh_token_ttl;
+ALTER TABLE apps DROP COLUMN access_token_ttl;
+ALTER TABLE apps DROP COLUMN allowed_scopes;
+ALTER TABLE apps DROP COLUMN grant_types;
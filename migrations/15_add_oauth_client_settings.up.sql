@@ -0,0 +1,21 @@
+-- Grant types and scopes are JSON arrays, empty arrays allow everything; TTLs are in seconds
+ALTER TABLE apps ADD COLUMN grant_types TEXT NOT NULL DEFAULT '[]';
+ALTER TABLE apps ADD COLUMN allowed_scopes TEXT NOT NULL DEFAULT '[]';
+ALTER TABLE apps ADD COLUMN access_token_ttl INTEGER NOT NULL DEFAULT 0;
+ALTER TABLE apps ADD COLUMN refresh_token_ttl INTEGER NOT NULL DEFAULT 0;
+ALTER TABLE apps ADD COLUMN public_client BOOLEAN NOT NULL DEFAULT FALSE;
+
+-- Clients which already use the authorization code flow have no secret
+UPDATE apps SET public_client = TRUE WHERE redirect_uris != '[]';
+
+-- Refresh tokens are kept as SHA-256 hashes and replaced by every refresh
+CREATE TABLE IF NOT EXISTS refresh_tokens
+(
+    token_hash TEXT PRIMARY KEY,
+    app_id INTEGER NOT NULL REFERENCES apps(id),
+    user_id INTEGER NOT NULL REFERENCES users(id),
+    scope TEXT NOT NULL DEFAULT '',
+    expires_at INTEGER NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS idx_refresh_tokens_expires_at ON refresh_tokens (expires_at);
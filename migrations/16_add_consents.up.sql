@@ -0,0 +1,11 @@
+ALTER TABLE apps ADD COLUMN third_party BOOLEAN NOT NULL DEFAULT FALSE;
+
+-- Scopes are space-separated, the user has one consent per application which grows by every new consent
+CREATE TABLE IF NOT EXISTS consents
+(
+    user_id INTEGER NOT NULL REFERENCES users(id),
+    app_id INTEGER NOT NULL REFERENCES apps(id),
+    scope TEXT NOT NULL DEFAULT '',
+    granted_at INTEGER NOT NULL,
+    PRIMARY KEY (user_id, app_id)
+);
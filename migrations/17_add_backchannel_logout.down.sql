@@ -0,0 +1,3 @@
+DROP TABLE IF EXISTS logout_deliveries;
+DROP TABLE IF EXISTS sessions;
+ALTER TABLE apps DROP COLUMN backchannel_logout_uri;
@@ -0,0 +1,26 @@
+ALTER TABLE apps ADD COLUMN backchannel_logout_uri TEXT NOT NULL DEFAULT '';
+
+-- Applications the user got tokens for, expires_at is the latest expiry of the tokens of the user
+CREATE TABLE IF NOT EXISTS sessions
+(
+    user_id INTEGER NOT NULL REFERENCES users(id),
+    app_id INTEGER NOT NULL REFERENCES apps(id),
+    expires_at INTEGER NOT NULL,
+    PRIMARY KEY (user_id, app_id)
+);
+
+-- Logout tokens are signed on every attempt, so only the subject of the notification is kept
+CREATE TABLE IF NOT EXISTS logout_deliveries
+(
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    app_id INTEGER NOT NULL REFERENCES apps(id),
+    user_id INTEGER NOT NULL,
+    status TEXT NOT NULL DEFAULT 'pending',
+    attempts INTEGER NOT NULL DEFAULT 0,
+    next_attempt_at INTEGER NOT NULL, -- unix milliseconds
+    last_error TEXT NOT NULL DEFAULT '',
+    created_at INTEGER NOT NULL DEFAULT (unixepoch()),
+    delivered_at INTEGER NOT NULL DEFAULT 0
+);
+
+CREATE INDEX IF NOT EXISTS idx_logout_deliveries_pending ON logout_deliveries (status, next_attempt_at);
@@ -0,0 +1,2 @@
+-- Theme of the hosted login pages as JSON object, empty object keeps the default look
+ALTER TABLE apps ADD COLUMN theme TEXT NOT NULL DEFAULT '{}';
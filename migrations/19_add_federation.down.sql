@@ -0,0 +1,2 @@
+DROP TABLE IF EXISTS federation_states;
+DROP TABLE IF EXISTS federated_identities;
@@ -0,0 +1,24 @@
+-- Identities of the users at the upstream OpenID Connect providers, provider is its name in the config
+CREATE TABLE IF NOT EXISTS federated_identities
+(
+    provider TEXT NOT NULL,
+    subject TEXT NOT NULL,
+    user_id INTEGER NOT NULL REFERENCES users(id),
+    email TEXT NOT NULL DEFAULT '',
+    created_at INTEGER NOT NULL DEFAULT (unixepoch()),
+    PRIMARY KEY (provider, subject)
+);
+
+CREATE INDEX IF NOT EXISTS idx_federated_identities_user_id ON federated_identities (user_id);
+
+-- Authorization requests waiting for the callback of the provider, request is JSON of the original request
+CREATE TABLE IF NOT EXISTS federation_states
+(
+    state_hash TEXT PRIMARY KEY,
+    provider TEXT NOT NULL,
+    nonce TEXT NOT NULL,
+    code_verifier TEXT NOT NULL,
+    request TEXT NOT NULL,
+    client_state TEXT NOT NULL DEFAULT '',
+    expires_at INTEGER NOT NULL
+);
@@ -0,0 +1,11 @@
+DROP TABLE IF EXISTS scim_groups;
+DROP TRIGGER IF EXISTS users_created_at;
+
+ALTER TABLE users DROP COLUMN updated_at;
+ALTER TABLE users DROP COLUMN created_at;
+ALTER TABLE users DROP COLUMN version;
+ALTER TABLE users DROP COLUMN family_name;
+ALTER TABLE users DROP COLUMN given_name;
+ALTER TABLE users DROP COLUMN display_name;
+ALTER TABLE users DROP COLUMN external_id;
+ALTER TABLE users DROP COLUMN disabled;
@@ -0,0 +1,30 @@
+-- Attributes of the users provisioned by SCIM, version grows on every change and is sent as ETag
+ALTER TABLE users ADD COLUMN disabled BOOLEAN NOT NULL DEFAULT FALSE;
+ALTER TABLE users ADD COLUMN external_id TEXT NOT NULL DEFAULT '';
+ALTER TABLE users ADD COLUMN display_name TEXT NOT NULL DEFAULT '';
+ALTER TABLE users ADD COLUMN given_name TEXT NOT NULL DEFAULT '';
+ALTER TABLE users ADD COLUMN family_name TEXT NOT NULL DEFAULT '';
+ALTER TABLE users ADD COLUMN version INTEGER NOT NULL DEFAULT 1;
+ALTER TABLE users ADD COLUMN created_at INTEGER NOT NULL DEFAULT 0;
+ALTER TABLE users ADD COLUMN updated_at INTEGER NOT NULL DEFAULT 0;
+
+UPDATE users SET created_at = unixepoch(), updated_at = unixepoch();
+
+-- Added column can't have expression as default, so time of creation is set after insert
+CREATE TRIGGER IF NOT EXISTS users_created_at AFTER INSERT ON users WHEN NEW.created_at = 0
+BEGIN
+    UPDATE users SET created_at = unixepoch(), updated_at = unixepoch() WHERE id = NEW.id;
+END;
+
+-- Groups of the application provisioned by SCIM, members are relation tuples group:<id>#member@user:<user id>
+CREATE TABLE IF NOT EXISTS scim_groups
+(
+    id INTEGER PRIMARY KEY,
+    app_id INTEGER NOT NULL REFERENCES apps(id),
+    display_name TEXT NOT NULL,
+    external_id TEXT NOT NULL DEFAULT '',
+    version INTEGER NOT NULL DEFAULT 1,
+    created_at INTEGER NOT NULL DEFAULT (unixepoch()),
+    updated_at INTEGER NOT NULL DEFAULT (unixepoch()),
+    UNIQUE (app_id, display_name)
+);
@@ -0,0 +1,32 @@
+-- Secret signs the payloads by HMAC, so it is kept as is
+CREATE TABLE IF NOT EXISTS webhook_subscriptions
+(
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    app_id INTEGER NOT NULL REFERENCES apps(id),
+    url TEXT NOT NULL,
+    secret TEXT NOT NULL,
+    events TEXT NOT NULL DEFAULT '[]', -- JSON array of the event types, empty array subscribes to all events
+    created_at INTEGER NOT NULL DEFAULT (unixepoch())
+);
+
+CREATE INDEX IF NOT EXISTS idx_webhook_subscriptions_app ON webhook_subscriptions (app_id);
+
+-- Payload is built once for the event, so every attempt and redelivery sends the same body
+CREATE TABLE IF NOT EXISTS webhook_deliveries
+(
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    app_id INTEGER NOT NULL REFERENCES apps(id),
+    subscription_id INTEGER NOT NULL REFERENCES webhook_subscriptions(id),
+    event_id TEXT NOT NULL,
+    event_type TEXT NOT NULL,
+    payload TEXT NOT NULL,
+    status TEXT NOT NULL DEFAULT 'pending',
+    attempts INTEGER NOT NULL DEFAULT 0,
+    next_attempt_at INTEGER NOT NULL, -- unix milliseconds
+    last_error TEXT NOT NULL DEFAULT '',
+    created_at INTEGER NOT NULL DEFAULT (unixepoch()),
+    delivered_at INTEGER NOT NULL DEFAULT 0
+);
+
+CREATE INDEX IF NOT EXISTS idx_webhook_deliveries_pending ON webhook_deliveries (status, next_attempt_at);
+CREATE INDEX IF NOT EXISTS idx_webhook_deliveries_app ON webhook_deliveries (app_id, status);
@@ -0,0 +1,21 @@
+-- Events are saved in the transaction of the change they describe and published by the relay afterwards
+CREATE TABLE IF NOT EXISTS outbox_events
+(
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    event_id TEXT NOT NULL UNIQUE,
+    type TEXT NOT NULL,
+    actor_id INTEGER NOT NULL DEFAULT 0,
+    user_id INTEGER NOT NULL DEFAULT 0,
+    email TEXT NOT NULL DEFAULT '',
+    app_id INTEGER NOT NULL DEFAULT 0, -- not a reference, events of the deleted applications are still published
+    attempts INTEGER NOT NULL DEFAULT 0,
+    next_attempt_at INTEGER NOT NULL DEFAULT 0, -- unix milliseconds
+    last_error TEXT NOT NULL DEFAULT '',
+    created_at INTEGER NOT NULL DEFAULT (unixepoch()),
+    published_at INTEGER NOT NULL DEFAULT 0
+);
+
+CREATE INDEX IF NOT EXISTS idx_outbox_events_pending ON outbox_events (published_at, next_attempt_at);
+
+-- Relay publishes the event again after a failure, the same event is queued once for the subscription
+CREATE UNIQUE INDEX IF NOT EXISTS idx_webhook_deliveries_event ON webhook_deliveries (subscription_id, event_id);
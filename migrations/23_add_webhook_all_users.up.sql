@@ -0,0 +1,2 @@
+-- Subscriptions of all users get events which are not bound to an application, e.g. registration of the user
+ALTER TABLE webhook_subscriptions ADD COLUMN all_users BOOLEAN NOT NULL DEFAULT FALSE;
@@ -0,0 +1,6 @@
+ALTER TABLE apps DROP COLUMN max_claims_size;
+ALTER TABLE apps DROP COLUMN embed_scope;
+ALTER TABLE apps DROP COLUMN embed_roles;
+
+DROP TABLE IF EXISTS role_scopes;
+DROP TABLE IF EXISTS user_roles;
@@ -0,0 +1,23 @@
+CREATE TABLE IF NOT EXISTS user_roles
+(
+    id INTEGER PRIMARY KEY,
+    user_id INTEGER NOT NULL REFERENCES users(id),
+    app_id INTEGER NOT NULL REFERENCES apps(id),
+    role TEXT NOT NULL,
+    UNIQUE (user_id, app_id, role)
+);
+
+CREATE INDEX IF NOT EXISTS idx_user_roles_user_app ON user_roles (user_id, app_id);
+
+CREATE TABLE IF NOT EXISTS role_scopes
+(
+    id INTEGER PRIMARY KEY,
+    app_id INTEGER NOT NULL REFERENCES apps(id),
+    role TEXT NOT NULL,
+    scope TEXT NOT NULL,
+    UNIQUE (app_id, role, scope)
+);
+
+ALTER TABLE apps ADD COLUMN embed_roles BOOLEAN NOT NULL DEFAULT FALSE;
+ALTER TABLE apps ADD COLUMN embed_scope BOOLEAN NOT NULL DEFAULT FALSE;
+ALTER TABLE apps ADD COLUMN max_claims_size INTEGER NOT NULL DEFAULT 2048;
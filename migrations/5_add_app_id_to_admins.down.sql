@@ -0,0 +1,14 @@
+DROP INDEX IF EXISTS idx_admins_user_app;
+
+-- SQLite can't drop column used in foreign key, so the table is recreated with super-admins only
+CREATE TABLE IF NOT EXISTS admins_global
+(
+    id INTEGER PRIMARY KEY,
+    user_id INTEGER REFERENCES users(id)
+);
+
+INSERT INTO admins_global(id, user_id) SELECT id, user_id FROM admins WHERE app_id IS NULL;
+
+DROP TABLE admins;
+
+ALTER TABLE admins_global RENAME TO admins;
@@ -0,0 +1,4 @@
+-- Admins without application are super-admins of the whole system
+ALTER TABLE admins ADD COLUMN app_id INTEGER REFERENCES apps(id);
+
+CREATE INDEX IF NOT EXISTS idx_admins_user_app ON admins (user_id, app_id);
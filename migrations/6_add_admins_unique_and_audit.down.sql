@@ -0,0 +1,3 @@
+DROP TABLE IF EXISTS audit_events;
+
+DROP INDEX IF EXISTS idx_admins_unique_user_app;
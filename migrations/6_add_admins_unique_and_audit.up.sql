@@ -0,0 +1,19 @@
+-- Remove duplicates which were possible before the unique index
+DELETE FROM admins WHERE id NOT IN (
+    SELECT MIN(id) FROM admins GROUP BY user_id, IFNULL(app_id, 0)
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_admins_unique_user_app ON admins (user_id, IFNULL(app_id, 0));
+
+CREATE TABLE IF NOT EXISTS audit_events
+(
+    id INTEGER PRIMARY KEY,
+    actor_id INTEGER NOT NULL REFERENCES users(id),
+    action TEXT NOT NULL,
+    target_user_id INTEGER,
+    app_id INTEGER,
+    details TEXT NOT NULL DEFAULT '',
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_audit_events_app ON audit_events (app_id);
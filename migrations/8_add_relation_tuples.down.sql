@@ -0,0 +1,3 @@
+DROP TABLE IF EXISTS permission_revisions;
+DROP TABLE IF EXISTS namespace_configs;
+DROP TABLE IF EXISTS relation_tuples;
@@ -0,0 +1,27 @@
+CREATE TABLE IF NOT EXISTS relation_tuples
+(
+    id INTEGER PRIMARY KEY,
+    app_id INTEGER NOT NULL REFERENCES apps(id),
+    object_type TEXT NOT NULL,
+    object_id TEXT NOT NULL,
+    relation TEXT NOT NULL,
+    subject_type TEXT NOT NULL,
+    subject_id TEXT NOT NULL,
+    subject_relation TEXT NOT NULL DEFAULT '',
+    UNIQUE (app_id, object_type, object_id, relation, subject_type, subject_id, subject_relation)
+);
+
+CREATE INDEX IF NOT EXISTS idx_relation_tuples_object ON relation_tuples (app_id, object_type, object_id, relation);
+
+CREATE TABLE IF NOT EXISTS namespace_configs
+(
+    app_id INTEGER PRIMARY KEY REFERENCES apps(id),
+    config TEXT NOT NULL
+);
+
+-- Revision grows on every change of the tuples, reads may require to see at least some revision
+CREATE TABLE IF NOT EXISTS permission_revisions
+(
+    app_id INTEGER PRIMARY KEY REFERENCES apps(id),
+    revision INTEGER NOT NULL DEFAULT 0
+);
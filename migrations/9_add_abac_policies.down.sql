@@ -0,0 +1,2 @@
+DROP TABLE IF EXISTS policy_decisions;
+DROP TABLE IF EXISTS abac_policies;
@@ -0,0 +1,20 @@
+CREATE TABLE IF NOT EXISTS abac_policies
+(
+    app_id INTEGER PRIMARY KEY REFERENCES apps(id),
+    policy TEXT NOT NULL,
+    version INTEGER NOT NULL DEFAULT 1
+);
+
+-- Decisions keep input of the evaluation, so changed policy can be dry-run against them
+CREATE TABLE IF NOT EXISTS policy_decisions
+(
+    id INTEGER PRIMARY KEY,
+    app_id INTEGER NOT NULL REFERENCES apps(id),
+    policy_version INTEGER NOT NULL,
+    input TEXT NOT NULL,
+    effect TEXT NOT NULL,
+    rule TEXT NOT NULL DEFAULT '',
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_policy_decisions_app_id ON policy_decisions (app_id, id);
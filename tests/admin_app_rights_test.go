@@ -0,0 +1,98 @@
+package tests
+
+import (
+	"testing"
+
+	adminv1 "github.com/nhassl3/sso-app/contracts/generated/go/admin"
+	"github.com/nhassl3/sso-app/tests/suite"
+	ssov1 "github.com/nhassl3/sso-contracts/generated/go/sso"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func TestIsAppAdmin(t *testing.T) {
+	ctx, st := suite.NewSuite(t)
+
+	_, superAdminID := st.Login(ctx, suite.SuperAdminEmail, suite.AdminPassword, suite.AppID)
+	_, appAdminID := st.Login(ctx, suite.AppAdminEmail, suite.AdminPassword, suite.AppID)
+
+	respReg, err := st.AuthClient.Register(ctx, &ssov1.RegisterRequest{
+		Email:    st.NewEmail(),
+		Password: st.NewPassword(),
+	})
+	require.NoError(t, err)
+
+	tests := []struct {
+		Name         string
+		UserID       int64
+		AppID        int32
+		IsAdmin      bool
+		IsSuperAdmin bool
+	}{
+		{
+			Name:         "Super-admin in any app",
+			UserID:       superAdminID,
+			AppID:        suite.SmallClaimsAppID,
+			IsAdmin:      true,
+			IsSuperAdmin: true,
+		},
+		{
+			Name:    "App admin in own app",
+			UserID:  appAdminID,
+			AppID:   suite.ClaimsAppID,
+			IsAdmin: true,
+		},
+		{
+			Name:   "App admin in other app",
+			UserID: appAdminID,
+			AppID:  suite.SmallClaimsAppID,
+		},
+		{
+			Name:   "Regular user",
+			UserID: respReg.GetUserId(),
+			AppID:  suite.ClaimsAppID,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.Name, func(t *testing.T) {
+			resp, err := st.AdminClient.IsAppAdmin(ctx, &adminv1.IsAppAdminRequest{
+				UserId: tt.UserID,
+				AppId:  tt.AppID,
+			})
+			require.NoError(t, err)
+			assert.Equal(t, tt.IsAdmin, resp.GetIsAdmin())
+			assert.Equal(t, tt.IsSuperAdmin, resp.GetIsSuperAdmin())
+		})
+	}
+}
+
+func TestIsAppAdmin_UnknownApp(t *testing.T) {
+	ctx, st := suite.NewSuite(t)
+
+	_, superAdminID := st.Login(ctx, suite.SuperAdminEmail, suite.AdminPassword, suite.AppID)
+
+	_, err := st.AdminClient.IsAppAdmin(ctx, &adminv1.IsAppAdminRequest{
+		UserId: superAdminID,
+		AppId:  100500,
+	})
+	require.Error(t, err)
+	assert.Equal(t, codes.NotFound, status.Code(err))
+}
+
+func TestIsAdmin_OnlySuperAdmin(t *testing.T) {
+	ctx, st := suite.NewSuite(t)
+
+	_, superAdminID := st.Login(ctx, suite.SuperAdminEmail, suite.AdminPassword, suite.AppID)
+	_, appAdminID := st.Login(ctx, suite.AppAdminEmail, suite.AdminPassword, suite.AppID)
+
+	respSuper, err := st.AuthClient.IsAdmin(ctx, &ssov1.IsAdminRequest{UserId: superAdminID})
+	require.NoError(t, err)
+	assert.True(t, respSuper.GetIsAdmin())
+
+	respApp, err := st.AuthClient.IsAdmin(ctx, &ssov1.IsAdminRequest{UserId: appAdminID})
+	require.NoError(t, err)
+	assert.False(t, respApp.GetIsAdmin())
+}
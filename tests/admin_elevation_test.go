@@ -0,0 +1,261 @@
+package tests
+
+import (
+	"context"
+	"net/http"
+	"testing"
+	"time"
+
+	adminv1 "github.com/nhassl3/sso-app/contracts/generated/go/admin"
+	"github.com/nhassl3/sso-app/tests/suite"
+	ssov1 "github.com/nhassl3/sso-contracts/generated/go/sso"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func TestAdminElevation_ApproveExpire(t *testing.T) {
+	ctx, st := suite.NewSuite(t)
+
+	appAdminToken, _ := st.Login(ctx, suite.AppAdminEmail, suite.AdminPassword, suite.AppID)
+	adminCtx := st.WithToken(ctx, appAdminToken)
+
+	userCtx, userID := registerAndLogin(ctx, t, st)
+
+	respRequest, err := st.AdminClient.RequestElevation(userCtx, &adminv1.RequestElevationRequest{
+		AppId:      suite.ClaimsAppID,
+		TtlSeconds: 1,
+		Reason:     "incident",
+	})
+	require.NoError(t, err)
+	requestID := respRequest.GetRequestId()
+
+	respList, err := st.AdminClient.ListElevationRequests(adminCtx, &adminv1.ListElevationRequestsRequest{
+		AppId:       suite.ClaimsAppID,
+		PendingOnly: true,
+	})
+	require.NoError(t, err)
+	request := findElevationRequest(respList.GetRequests(), requestID)
+	require.NotNil(t, request)
+	assert.Equal(t, userID, request.GetUserId())
+	assert.Equal(t, "pending", request.GetStatus())
+	assert.EqualValues(t, 1, request.GetTtlSeconds())
+
+	_, err = st.AdminClient.ApproveElevation(adminCtx, &adminv1.ApproveElevationRequest{RequestId: requestID})
+	require.NoError(t, err)
+
+	respIsAdmin, err := st.AdminClient.IsAppAdmin(ctx, &adminv1.IsAppAdminRequest{UserId: userID, AppId: suite.ClaimsAppID})
+	require.NoError(t, err)
+	assert.True(t, respIsAdmin.GetIsAdmin())
+
+	// Request is decided only once
+	_, err = st.AdminClient.ApproveElevation(adminCtx, &adminv1.ApproveElevationRequest{RequestId: requestID})
+	require.Error(t, err)
+	assert.Equal(t, codes.FailedPrecondition, status.Code(err))
+
+	// Sweeper of the test config runs every second
+	require.Eventually(t, func() bool {
+		return len(targetActions(adminCtx, t, st, userID)) == 4
+	}, 4*time.Second, 100*time.Millisecond)
+
+	assert.Equal(
+		t,
+		[]string{"admin.expire", "elevation.approve", "admin.grant", "elevation.request"},
+		targetActions(adminCtx, t, st, userID),
+	)
+
+	respIsAdmin, err = st.AdminClient.IsAppAdmin(ctx, &adminv1.IsAppAdminRequest{UserId: userID, AppId: suite.ClaimsAppID})
+	require.NoError(t, err)
+	assert.False(t, respIsAdmin.GetIsAdmin())
+}
+
+func TestAdminElevation_Deny(t *testing.T) {
+	ctx, st := suite.NewSuite(t)
+
+	appAdminToken, _ := st.Login(ctx, suite.AppAdminEmail, suite.AdminPassword, suite.AppID)
+	adminCtx := st.WithToken(ctx, appAdminToken)
+
+	userCtx, userID := registerAndLogin(ctx, t, st)
+
+	respRequest, err := st.AdminClient.RequestElevation(userCtx, &adminv1.RequestElevationRequest{AppId: suite.ClaimsAppID})
+	require.NoError(t, err)
+
+	// Requests can't be listed or decided without admin rights
+	_, err = st.AdminClient.ListElevationRequests(userCtx, &adminv1.ListElevationRequestsRequest{AppId: suite.ClaimsAppID})
+	require.Error(t, err)
+	assert.Equal(t, codes.PermissionDenied, status.Code(err))
+
+	_, err = st.AdminClient.DenyElevation(adminCtx, &adminv1.DenyElevationRequest{RequestId: respRequest.GetRequestId()})
+	require.NoError(t, err)
+
+	respList, err := st.AdminClient.ListElevationRequests(adminCtx, &adminv1.ListElevationRequestsRequest{AppId: suite.ClaimsAppID})
+	require.NoError(t, err)
+	request := findElevationRequest(respList.GetRequests(), respRequest.GetRequestId())
+	require.NotNil(t, request)
+	assert.Equal(t, "denied", request.GetStatus())
+	assert.EqualValues(t, time.Hour.Seconds(), request.GetTtlSeconds())
+
+	respIsAdmin, err := st.AdminClient.IsAppAdmin(ctx, &adminv1.IsAppAdminRequest{UserId: userID, AppId: suite.ClaimsAppID})
+	require.NoError(t, err)
+	assert.False(t, respIsAdmin.GetIsAdmin())
+}
+
+func TestAdminElevation_InvalidRequests(t *testing.T) {
+	ctx, st := suite.NewSuite(t)
+
+	superToken, _ := st.Login(ctx, suite.SuperAdminEmail, suite.AdminPassword, suite.AppID)
+	superCtx := st.WithToken(ctx, superToken)
+
+	// Admins can't approve own requests even with rights in every application
+	respRequest, err := st.AdminClient.RequestElevation(superCtx, &adminv1.RequestElevationRequest{AppId: suite.SmallClaimsAppID})
+	require.NoError(t, err)
+
+	_, err = st.AdminClient.ApproveElevation(superCtx, &adminv1.ApproveElevationRequest{RequestId: respRequest.GetRequestId()})
+	require.Error(t, err)
+	assert.Equal(t, codes.PermissionDenied, status.Code(err))
+
+	// Admin of another application can't decide the request
+	appAdminToken, _ := st.Login(ctx, suite.AppAdminEmail, suite.AdminPassword, suite.AppID)
+
+	_, err = st.AdminClient.DenyElevation(
+		st.WithToken(ctx, appAdminToken),
+		&adminv1.DenyElevationRequest{RequestId: respRequest.GetRequestId()},
+	)
+	require.Error(t, err)
+	assert.Equal(t, codes.PermissionDenied, status.Code(err))
+
+	_, err = st.AdminClient.ApproveElevation(superCtx, &adminv1.ApproveElevationRequest{RequestId: 100500})
+	require.Error(t, err)
+	assert.Equal(t, codes.NotFound, status.Code(err))
+
+	_, err = st.AdminClient.RequestElevation(superCtx, &adminv1.RequestElevationRequest{
+		AppId:      suite.SmallClaimsAppID,
+		TtlSeconds: int64((24 * time.Hour).Seconds()),
+	})
+	require.Error(t, err)
+	assert.Equal(t, codes.InvalidArgument, status.Code(err))
+
+	_, err = st.AdminClient.RequestElevation(ctx, &adminv1.RequestElevationRequest{AppId: suite.SmallClaimsAppID})
+	require.Error(t, err)
+	assert.Equal(t, codes.Unauthenticated, status.Code(err))
+}
+
+func TestAdminElevation_GrantWithTTL(t *testing.T) {
+	ctx, st := suite.NewSuite(t)
+
+	superToken, _ := st.Login(ctx, suite.SuperAdminEmail, suite.AdminPassword, suite.AppID)
+	adminCtx := st.WithToken(ctx, superToken)
+
+	_, userID := registerAndLogin(ctx, t, st)
+
+	before := time.Now()
+
+	_, err := st.AdminClient.GrantAdmin(adminCtx, &adminv1.GrantAdminRequest{
+		UserId:     userID,
+		AppId:      suite.SmallClaimsAppID,
+		TtlSeconds: int64(time.Hour.Seconds()),
+	})
+	require.NoError(t, err)
+
+	respList, err := st.AdminClient.ListAdmins(adminCtx, &adminv1.ListAdminsRequest{AppId: suite.SmallClaimsAppID})
+	require.NoError(t, err)
+
+	var expiresAt int64
+	for _, a := range respList.GetAdmins() {
+		if a.GetUserId() == userID {
+			expiresAt = a.GetExpiresAt()
+		}
+	}
+	assert.InDelta(t, before.Add(time.Hour).Unix(), expiresAt, 2)
+
+	// Permanent grant replaces temporary one
+	respGrant, err := st.AdminClient.GrantAdmin(adminCtx, &adminv1.GrantAdminRequest{UserId: userID, AppId: suite.SmallClaimsAppID})
+	require.NoError(t, err)
+	assert.True(t, respGrant.GetGranted())
+
+	respRevoke, err := st.AdminClient.RevokeAdmin(adminCtx, &adminv1.RevokeAdminRequest{UserId: userID, AppId: suite.SmallClaimsAppID})
+	require.NoError(t, err)
+	assert.True(t, respRevoke.GetRevoked())
+}
+
+func TestAdmin_OnlyConsoleTokens(t *testing.T) {
+	ctx, st := suite.NewSuite(t)
+
+	superToken, _ := st.Login(ctx, suite.SuperAdminEmail, suite.AdminPassword, suite.AppID)
+
+	email, password := st.NewEmail(), st.NewPassword()
+	_, err := st.AuthClient.Register(ctx, &ssov1.RegisterRequest{Email: email, Password: password})
+	require.NoError(t, err)
+
+	otherToken, userID := st.Login(ctx, email, password, suite.ClaimsAppID)
+
+	resp, body := postForm(t, st, "/token", impersonation(superToken, userID, suite.AppID))
+	require.Equal(t, http.StatusOK, resp.StatusCode, body)
+
+	tests := []struct {
+		Name  string
+		Token string
+	}{
+		{Name: "Token of other application", Token: otherToken},
+		{Name: "Impersonation token of the console", Token: body["access_token"].(string)},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.Name, func(t *testing.T) {
+			_, err := st.AdminClient.RequestElevation(st.WithToken(ctx, tt.Token), &adminv1.RequestElevationRequest{
+				AppId: suite.ClaimsAppID,
+			})
+			require.Error(t, err)
+			assert.Equal(t, codes.PermissionDenied, status.Code(err))
+		})
+	}
+
+	// Super-admin acting through token of other application is not an admin as well
+	superOtherToken, _ := st.Login(ctx, suite.SuperAdminEmail, suite.AdminPassword, suite.ClaimsAppID)
+
+	_, err = st.AdminClient.ListAdmins(st.WithToken(ctx, superOtherToken), &adminv1.ListAdminsRequest{AppId: suite.ClaimsAppID})
+	require.Error(t, err)
+	assert.Equal(t, codes.PermissionDenied, status.Code(err))
+}
+
+// registerAndLogin registers new user and returns context with its token for the console application
+func registerAndLogin(ctx context.Context, t *testing.T, st *suite.Suite) (context.Context, int64) {
+	t.Helper()
+
+	email, password := st.NewEmail(), st.NewPassword()
+
+	_, err := st.AuthClient.Register(ctx, &ssov1.RegisterRequest{Email: email, Password: password})
+	require.NoError(t, err)
+
+	token, userID := st.Login(ctx, email, password, suite.AppID)
+
+	return st.WithToken(ctx, token), userID
+}
+
+func findElevationRequest(requests []*adminv1.ElevationRequest, requestID int64) *adminv1.ElevationRequest {
+	for _, request := range requests {
+		if request.GetId() == requestID {
+			return request
+		}
+	}
+
+	return nil
+}
+
+// targetActions returns actions of the audit events of the ClaimsAppID which affected the user, from the latest
+func targetActions(ctx context.Context, t *testing.T, st *suite.Suite, userID int64) []string {
+	t.Helper()
+
+	respEvents, err := st.AdminClient.ListAuditEvents(ctx, &adminv1.ListAuditEventsRequest{AppId: suite.ClaimsAppID})
+	require.NoError(t, err)
+
+	var actions []string
+	for _, event := range respEvents.GetEvents() {
+		if event.GetTargetUserId() == userID {
+			actions = append(actions, event.GetAction())
+		}
+	}
+
+	return actions
+}
@@ -0,0 +1,157 @@
+package tests
+
+import (
+	"testing"
+
+	adminv1 "github.com/nhassl3/sso-app/contracts/generated/go/admin"
+	"github.com/nhassl3/sso-app/tests/suite"
+	ssov1 "github.com/nhassl3/sso-contracts/generated/go/sso"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func TestAdminManagement_GrantRevoke(t *testing.T) {
+	ctx, st := suite.NewSuite(t)
+
+	superToken, _ := st.Login(ctx, suite.SuperAdminEmail, suite.AdminPassword, suite.AppID)
+	adminCtx := st.WithToken(ctx, superToken)
+
+	respReg, err := st.AuthClient.Register(ctx, &ssov1.RegisterRequest{
+		Email:    st.NewEmail(),
+		Password: st.NewPassword(),
+	})
+	require.NoError(t, err)
+	userID := respReg.GetUserId()
+
+	respGrant, err := st.AdminClient.GrantAdmin(adminCtx, &adminv1.GrantAdminRequest{UserId: userID, AppId: suite.SmallClaimsAppID})
+	require.NoError(t, err)
+	assert.True(t, respGrant.GetGranted())
+
+	// Second grant is idempotent
+	respGrant, err = st.AdminClient.GrantAdmin(adminCtx, &adminv1.GrantAdminRequest{UserId: userID, AppId: suite.SmallClaimsAppID})
+	require.NoError(t, err)
+	assert.False(t, respGrant.GetGranted())
+
+	respIsAdmin, err := st.AdminClient.IsAppAdmin(ctx, &adminv1.IsAppAdminRequest{UserId: userID, AppId: suite.SmallClaimsAppID})
+	require.NoError(t, err)
+	assert.True(t, respIsAdmin.GetIsAdmin())
+
+	respList, err := st.AdminClient.ListAdmins(adminCtx, &adminv1.ListAdminsRequest{AppId: suite.SmallClaimsAppID})
+	require.NoError(t, err)
+	assert.Contains(t, adminIDs(respList.GetAdmins()), userID)
+
+	respRevoke, err := st.AdminClient.RevokeAdmin(adminCtx, &adminv1.RevokeAdminRequest{UserId: userID, AppId: suite.SmallClaimsAppID})
+	require.NoError(t, err)
+	assert.True(t, respRevoke.GetRevoked())
+
+	// Second revoke is idempotent
+	respRevoke, err = st.AdminClient.RevokeAdmin(adminCtx, &adminv1.RevokeAdminRequest{UserId: userID, AppId: suite.SmallClaimsAppID})
+	require.NoError(t, err)
+	assert.False(t, respRevoke.GetRevoked())
+
+	respIsAdmin, err = st.AdminClient.IsAppAdmin(ctx, &adminv1.IsAppAdminRequest{UserId: userID, AppId: suite.SmallClaimsAppID})
+	require.NoError(t, err)
+	assert.False(t, respIsAdmin.GetIsAdmin())
+
+	respEvents, err := st.AdminClient.ListAuditEvents(adminCtx, &adminv1.ListAuditEventsRequest{AppId: suite.SmallClaimsAppID})
+	require.NoError(t, err)
+
+	var actions []string
+	for _, event := range respEvents.GetEvents() {
+		if event.GetTargetUserId() == userID {
+			actions = append(actions, event.GetAction())
+		}
+	}
+	assert.Equal(t, []string{"admin.revoke", "admin.grant"}, actions)
+}
+
+func TestAdminManagement_PermissionDenied(t *testing.T) {
+	ctx, st := suite.NewSuite(t)
+
+	appAdminToken, _ := st.Login(ctx, suite.AppAdminEmail, suite.AdminPassword, suite.AppID)
+
+	email, password := st.NewEmail(), st.NewPassword()
+	respReg, err := st.AuthClient.Register(ctx, &ssov1.RegisterRequest{
+		Email:    email,
+		Password: password,
+	})
+	require.NoError(t, err)
+	userToken, _ := st.Login(ctx, email, password, suite.AppID)
+
+	tests := []struct {
+		Name   string
+		Token  string
+		AppID  int32
+		Expect codes.Code
+	}{
+		{
+			Name:   "Without token",
+			AppID:  suite.ClaimsAppID,
+			Expect: codes.Unauthenticated,
+		},
+		{
+			Name:   "With invalid token",
+			Token:  "not-a-token",
+			AppID:  suite.ClaimsAppID,
+			Expect: codes.Unauthenticated,
+		},
+		{
+			Name:   "Regular user",
+			Token:  userToken,
+			AppID:  suite.ClaimsAppID,
+			Expect: codes.PermissionDenied,
+		},
+		{
+			Name:   "App admin in other app",
+			Token:  appAdminToken,
+			AppID:  suite.SmallClaimsAppID,
+			Expect: codes.PermissionDenied,
+		},
+		{
+			Name:   "App admin grants super-admin rights",
+			Token:  appAdminToken,
+			AppID:  0,
+			Expect: codes.PermissionDenied,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.Name, func(t *testing.T) {
+			callCtx := ctx
+			if tt.Token != "" {
+				callCtx = st.WithToken(ctx, tt.Token)
+			}
+
+			_, err := st.AdminClient.GrantAdmin(callCtx, &adminv1.GrantAdminRequest{
+				UserId: respReg.GetUserId(),
+				AppId:  tt.AppID,
+			})
+			require.Error(t, err)
+			assert.Equal(t, tt.Expect, status.Code(err))
+		})
+	}
+}
+
+func TestAdminManagement_LastSuperAdmin(t *testing.T) {
+	ctx, st := suite.NewSuite(t)
+
+	superToken, superAdminID := st.Login(ctx, suite.SuperAdminEmail, suite.AdminPassword, suite.AppID)
+
+	_, err := st.AdminClient.RevokeAdmin(st.WithToken(ctx, superToken), &adminv1.RevokeAdminRequest{
+		UserId: superAdminID,
+		AppId:  0,
+	})
+	require.Error(t, err)
+	assert.Equal(t, codes.FailedPrecondition, status.Code(err))
+}
+
+func adminIDs(admins []*adminv1.AdminInfo) []int64 {
+	ids := make([]int64, 0, len(admins))
+	for _, admin := range admins {
+		ids = append(ids, admin.GetUserId())
+	}
+
+	return ids
+}
@@ -0,0 +1,251 @@
+package tests
+
+import (
+	"context"
+	"testing"
+
+	"github.com/brianvoe/gofakeit/v6"
+	adminv1 "github.com/nhassl3/sso-app/contracts/generated/go/admin"
+	appsv1 "github.com/nhassl3/sso-app/contracts/generated/go/apps"
+	tokenv1 "github.com/nhassl3/sso-app/contracts/generated/go/token"
+	"github.com/nhassl3/sso-app/tests/suite"
+	ssov1 "github.com/nhassl3/sso-contracts/generated/go/sso"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/fieldmaskpb"
+)
+
+func TestAppsRegistry_Lifecycle(t *testing.T) {
+	ctx, st := suite.NewSuite(t)
+
+	superToken, _ := st.Login(ctx, suite.SuperAdminEmail, suite.AdminPassword, suite.AppID)
+	adminCtx := st.WithToken(ctx, superToken)
+
+	email, password := st.NewEmail(), st.NewPassword()
+	respReg, err := st.AuthClient.Register(ctx, &ssov1.RegisterRequest{Email: email, Password: password})
+	require.NoError(t, err)
+
+	respCreate, err := st.AppsClient.CreateApp(adminCtx, &appsv1.CreateAppRequest{
+		Name:     "app-" + gofakeit.UUID(),
+		Settings: &appsv1.AppSettings{EmbedRoles: true},
+	})
+	require.NoError(t, err)
+	require.NotEmpty(t, respCreate.GetSecret())
+
+	app := respCreate.GetApp()
+	assert.True(t, app.GetSettings().GetEmbedRoles())
+	assert.EqualValues(t, 2048, app.GetSettings().GetMaxClaimsSize())
+
+	respLogin, err := st.AuthClient.Login(ctx, &ssov1.LoginRequest{Email: email, Password: password, AppId: app.GetId()})
+	require.NoError(t, err)
+
+	claims := parseClaims(t, respLogin.GetToken(), respCreate.GetSecret())
+	assert.Equal(t, []interface{}{}, claims["roles"])
+
+	assert.Contains(t, listAllApps(adminCtx, t, st), app.GetId())
+
+	respUpdate, err := st.AppsClient.UpdateApp(adminCtx, &appsv1.UpdateAppRequest{
+		AppId:    app.GetId(),
+		Settings: &appsv1.AppSettings{EmbedScope: true, MaxClaimsSize: 512},
+	})
+	require.NoError(t, err)
+	assert.Equal(t, app.GetName(), respUpdate.GetApp().GetName())
+	assert.False(t, respUpdate.GetApp().GetSettings().GetEmbedRoles())
+	assert.True(t, respUpdate.GetApp().GetSettings().GetEmbedScope())
+	assert.EqualValues(t, 512, respUpdate.GetApp().GetSettings().GetMaxClaimsSize())
+
+	_, err = st.AppsClient.DisableApp(adminCtx, &appsv1.DisableAppRequest{AppId: app.GetId()})
+	require.NoError(t, err)
+
+	_, err = st.AuthClient.Login(ctx, &ssov1.LoginRequest{Email: email, Password: password, AppId: app.GetId()})
+	require.Error(t, err)
+	assert.Equal(t, codes.PermissionDenied, status.Code(err))
+
+	// Tokens of the disabled application are not accepted
+	_, err = st.TokenClient.Introspect(st.WithToken(ctx, respLogin.GetToken()), &tokenv1.IntrospectRequest{Token: respLogin.GetToken()})
+	require.Error(t, err)
+	assert.Equal(t, codes.Unauthenticated, status.Code(err))
+
+	_, err = st.AppsClient.EnableApp(adminCtx, &appsv1.EnableAppRequest{AppId: app.GetId()})
+	require.NoError(t, err)
+
+	_, err = st.AdminClient.GrantAdmin(adminCtx, &adminv1.GrantAdminRequest{UserId: respReg.GetUserId(), AppId: app.GetId()})
+	require.NoError(t, err)
+
+	_, err = st.AppsClient.DeleteApp(adminCtx, &appsv1.DeleteAppRequest{AppId: app.GetId()})
+	require.NoError(t, err)
+
+	_, err = st.AdminClient.IsAppAdmin(ctx, &adminv1.IsAppAdminRequest{UserId: respReg.GetUserId(), AppId: app.GetId()})
+	require.Error(t, err)
+	assert.Equal(t, codes.NotFound, status.Code(err))
+
+	respAdmins, err := st.AdminClient.ListAdmins(adminCtx, &adminv1.ListAdminsRequest{})
+	require.NoError(t, err)
+	assert.NotContains(t, adminIDs(respAdmins.GetAdmins()), respReg.GetUserId())
+
+	assert.NotContains(t, listAllApps(adminCtx, t, st), app.GetId())
+}
+
+func TestAppsRegistry_DuplicateName(t *testing.T) {
+	ctx, st := suite.NewSuite(t)
+
+	superToken, _ := st.Login(ctx, suite.SuperAdminEmail, suite.AdminPassword, suite.AppID)
+	adminCtx := st.WithToken(ctx, superToken)
+
+	name := "app-" + gofakeit.UUID()
+
+	_, err := st.AppsClient.CreateApp(adminCtx, &appsv1.CreateAppRequest{Name: name})
+	require.NoError(t, err)
+
+	_, err = st.AppsClient.CreateApp(adminCtx, &appsv1.CreateAppRequest{Name: name})
+	require.Error(t, err)
+	assert.Equal(t, codes.AlreadyExists, status.Code(err))
+}
+
+func TestAppsRegistry_PermissionDenied(t *testing.T) {
+	ctx, st := suite.NewSuite(t)
+
+	appAdminToken, _ := st.Login(ctx, suite.AppAdminEmail, suite.AdminPassword, suite.AppID)
+	appAdminCtx := st.WithToken(ctx, appAdminToken)
+
+	_, err := st.AppsClient.CreateApp(appAdminCtx, &appsv1.CreateAppRequest{Name: "app-" + gofakeit.UUID()})
+	require.Error(t, err)
+	assert.Equal(t, codes.PermissionDenied, status.Code(err))
+
+	_, err = st.AppsClient.DeleteApp(appAdminCtx, &appsv1.DeleteAppRequest{AppId: suite.ClaimsAppID})
+	require.Error(t, err)
+	assert.Equal(t, codes.PermissionDenied, status.Code(err))
+
+	_, err = st.AppsClient.DisableApp(appAdminCtx, &appsv1.DisableAppRequest{AppId: suite.SmallClaimsAppID})
+	require.Error(t, err)
+	assert.Equal(t, codes.PermissionDenied, status.Code(err))
+
+	// App admin can change own application
+	respUpdate, err := st.AppsClient.UpdateApp(appAdminCtx, &appsv1.UpdateAppRequest{AppId: suite.ClaimsAppID})
+	require.NoError(t, err)
+	assert.Equal(t, suite.ClaimsAppID, respUpdate.GetApp().GetId())
+}
+
+func TestAppsRegistry_UpdateMask(t *testing.T) {
+	ctx, st := suite.NewSuite(t)
+
+	adminCtx := superAdminCtx(ctx, st)
+
+	app := createApp(ctx, t, st, &appsv1.AppSettings{
+		EmbedRoles:   true,
+		RedirectUris: []string{"https://app.test/callback"},
+		Theme:        &appsv1.AppTheme{Title: "Before"},
+	})
+
+	respUpdate, err := st.AppsClient.UpdateApp(adminCtx, &appsv1.UpdateAppRequest{
+		AppId:      app.GetId(),
+		Settings:   &appsv1.AppSettings{Theme: &appsv1.AppTheme{Title: "After"}},
+		UpdateMask: &fieldmaskpb.FieldMask{Paths: []string{"theme"}},
+	})
+	require.NoError(t, err)
+
+	settings := respUpdate.GetApp().GetSettings()
+	assert.Equal(t, "After", settings.GetTheme().GetTitle())
+	assert.True(t, settings.GetEmbedRoles(), "fields out of the mask are kept")
+	assert.Equal(t, []string{"https://app.test/callback"}, settings.GetRedirectUris())
+
+	// Field of the mask without settings is reset
+	respUpdate, err = st.AppsClient.UpdateApp(adminCtx, &appsv1.UpdateAppRequest{
+		AppId:      app.GetId(),
+		UpdateMask: &fieldmaskpb.FieldMask{Paths: []string{"redirect_uris"}},
+	})
+	require.NoError(t, err)
+	assert.Empty(t, respUpdate.GetApp().GetSettings().GetRedirectUris())
+	assert.Equal(t, "After", respUpdate.GetApp().GetSettings().GetTheme().GetTitle())
+
+	_, err = st.AppsClient.UpdateApp(adminCtx, &appsv1.UpdateAppRequest{
+		AppId:      app.GetId(),
+		Settings:   &appsv1.AppSettings{},
+		UpdateMask: &fieldmaskpb.FieldMask{Paths: []string{"secret"}},
+	})
+	require.Error(t, err)
+	assert.Equal(t, codes.InvalidArgument, status.Code(err))
+}
+
+func TestAppsRegistry_ClientKindBySuperAdmin(t *testing.T) {
+	ctx, st := suite.NewSuite(t)
+
+	app := createApp(ctx, t, st, &appsv1.AppSettings{GrantTypes: []string{"authorization_code"}})
+
+	appAdminCtx, appAdminID := registerAndLogin(ctx, t, st)
+	_, err := st.AdminClient.GrantAdmin(superAdminCtx(ctx, st), &adminv1.GrantAdminRequest{UserId: appAdminID, AppId: app.GetId()})
+	require.NoError(t, err)
+
+	tests := []struct {
+		Name     string
+		Settings *appsv1.AppSettings
+		Field    string
+	}{
+		{Name: "Public client", Settings: &appsv1.AppSettings{PublicClient: true}, Field: "public_client"},
+		{Name: "Third party", Settings: &appsv1.AppSettings{ThirdParty: true}, Field: "third_party"},
+		{Name: "Grant types", Settings: &appsv1.AppSettings{GrantTypes: []string{"client_credentials"}}, Field: "grant_types"},
+		{Name: "All grant types", Settings: &appsv1.AppSettings{}, Field: "grant_types"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.Name, func(t *testing.T) {
+			_, err := st.AppsClient.UpdateApp(appAdminCtx, &appsv1.UpdateAppRequest{
+				AppId:      app.GetId(),
+				Settings:   tt.Settings,
+				UpdateMask: &fieldmaskpb.FieldMask{Paths: []string{tt.Field}},
+			})
+			require.Error(t, err)
+			assert.Equal(t, codes.PermissionDenied, status.Code(err))
+		})
+	}
+
+	// Replacement of all settings can't change them as well
+	_, err = st.AppsClient.UpdateApp(appAdminCtx, &appsv1.UpdateAppRequest{
+		AppId:    app.GetId(),
+		Settings: &appsv1.AppSettings{EmbedRoles: true},
+	})
+	require.Error(t, err)
+	assert.Equal(t, codes.PermissionDenied, status.Code(err))
+
+	// App admin changes other settings, super-admin changes the kind of the client
+	_, err = st.AppsClient.UpdateApp(appAdminCtx, &appsv1.UpdateAppRequest{
+		AppId:      app.GetId(),
+		Settings:   &appsv1.AppSettings{EmbedRoles: true},
+		UpdateMask: &fieldmaskpb.FieldMask{Paths: []string{"embed_roles"}},
+	})
+	require.NoError(t, err)
+
+	respUpdate, err := st.AppsClient.UpdateApp(superAdminCtx(ctx, st), &appsv1.UpdateAppRequest{
+		AppId:      app.GetId(),
+		Settings:   &appsv1.AppSettings{ThirdParty: true},
+		UpdateMask: &fieldmaskpb.FieldMask{Paths: []string{"third_party"}},
+	})
+	require.NoError(t, err)
+	assert.True(t, respUpdate.GetApp().GetSettings().GetThirdParty())
+	assert.True(t, respUpdate.GetApp().GetSettings().GetEmbedRoles())
+}
+
+func listAllApps(ctx context.Context, t *testing.T, st *suite.Suite) []int32 {
+	t.Helper()
+
+	var (
+		ids       []int32
+		pageToken string
+	)
+
+	for {
+		resp, err := st.AppsClient.ListApps(ctx, &appsv1.ListAppsRequest{PageSize: 2, PageToken: pageToken})
+		require.NoError(t, err)
+		require.LessOrEqual(t, len(resp.GetApps()), 2)
+
+		for _, app := range resp.GetApps() {
+			ids = append(ids, app.GetId())
+		}
+
+		if pageToken = resp.GetNextPageToken(); pageToken == "" {
+			return ids
+		}
+	}
+}
@@ -0,0 +1,207 @@
+package tests
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/brianvoe/gofakeit/v6"
+	"github.com/golang-jwt/jwt/v5"
+	appsv1 "github.com/nhassl3/sso-app/contracts/generated/go/apps"
+	sessionsv1 "github.com/nhassl3/sso-app/contracts/generated/go/sessions"
+	"github.com/nhassl3/sso-app/tests/suite"
+	ssov1 "github.com/nhassl3/sso-contracts/generated/go/sso"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+const backchannelLogoutEvent = "http://schemas.openid.net/event/backchannel-logout"
+
+// logoutReceiver is back-channel logout URI of the application, it fails the first failures requests
+type logoutReceiver struct {
+	*httptest.Server
+	failures int32
+	requests atomic.Int32
+
+	mu     sync.Mutex
+	tokens []string
+}
+
+func newLogoutReceiver(t *testing.T, failures int32) *logoutReceiver {
+	t.Helper()
+
+	receiver := &logoutReceiver{failures: failures}
+	receiver.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if receiver.requests.Add(1) <= receiver.failures {
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+
+		receiver.mu.Lock()
+		receiver.tokens = append(receiver.tokens, r.PostFormValue("logout_token"))
+		receiver.mu.Unlock()
+	}))
+	t.Cleanup(receiver.Close)
+
+	return receiver
+}
+
+func (r *logoutReceiver) Tokens() []string {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	return append([]string(nil), r.tokens...)
+}
+
+func TestSessions_BackchannelLogout(t *testing.T) {
+	ctx, st := suite.NewSuite(t)
+
+	receiver := newLogoutReceiver(t, 1)
+	app := createApp(ctx, t, st, &appsv1.AppSettings{BackchannelLogoutUri: receiver.URL + "/logout"})
+	assert.Equal(t, receiver.URL+"/logout", app.GetSettings().GetBackchannelLogoutUri())
+
+	email, password := st.NewEmail(), st.NewPassword()
+	_, err := st.AuthClient.Register(ctx, &ssov1.RegisterRequest{Email: email, Password: password})
+	require.NoError(t, err)
+
+	st.Login(ctx, email, password, app.GetId())
+	userToken, userID := st.Login(ctx, email, password, suite.AppID)
+
+	// Token of other application ends sessions only in that application
+	appToken, _ := st.Login(ctx, email, password, suite.ClaimsAppID)
+
+	respLogout, err := st.SessionsClient.Logout(st.WithToken(ctx, appToken), &sessionsv1.LogoutRequest{})
+	require.NoError(t, err)
+	assert.Zero(t, respLogout.GetNotifiedApps())
+
+	respLogout, err = st.SessionsClient.Logout(st.WithToken(ctx, userToken), &sessionsv1.LogoutRequest{})
+	require.NoError(t, err)
+	assert.EqualValues(t, 1, respLogout.GetNotifiedApps())
+
+	superToken, _ := st.Login(ctx, suite.SuperAdminEmail, suite.AdminPassword, suite.AppID)
+	adminCtx := st.WithToken(ctx, superToken)
+
+	// Receiver fails the first attempt, so the token is delivered by the retry
+	delivery := waitLogoutDelivery(adminCtx, t, st, app.GetId(), "delivered")
+	assert.Equal(t, userID, delivery.GetUserId())
+	assert.EqualValues(t, 2, delivery.GetAttempts())
+	assert.Empty(t, delivery.GetLastError())
+	assert.NotZero(t, delivery.GetDeliveredAt())
+
+	tokens := receiver.Tokens()
+	require.Len(t, tokens, 1)
+
+	parsed, err := jwt.Parse(tokens[0], publicKeyFunc(t, st), jwt.WithValidMethods([]string{"RS256"}))
+	require.NoError(t, err)
+	assert.Equal(t, "logout+jwt", parsed.Header["typ"])
+
+	claims := parsed.Claims.(jwt.MapClaims)
+	assert.Equal(t, st.Cfg.OAuth.Issuer, claims["iss"])
+	assert.Equal(t, strconv.Itoa(int(app.GetId())), claims["aud"])
+	assert.Equal(t, strconv.FormatInt(userID, 10), claims["sub"])
+	assert.NotEmpty(t, claims["jti"])
+	assert.Contains(t, claims["events"], backchannelLogoutEvent)
+	assert.NotContains(t, claims, "nonce")
+
+	// Sessions are ended, so the next logout notifies nobody
+	respLogout, err = st.SessionsClient.Logout(st.WithToken(ctx, userToken), &sessionsv1.LogoutRequest{})
+	require.NoError(t, err)
+	assert.Zero(t, respLogout.GetNotifiedApps())
+}
+
+func TestSessions_RevokeSessionsRetries(t *testing.T) {
+	ctx, st := suite.NewSuite(t)
+
+	receiver := newLogoutReceiver(t, 100)
+	app := createApp(ctx, t, st, &appsv1.AppSettings{BackchannelLogoutUri: receiver.URL})
+
+	email, password := st.NewEmail(), st.NewPassword()
+	_, err := st.AuthClient.Register(ctx, &ssov1.RegisterRequest{Email: email, Password: password})
+	require.NoError(t, err)
+
+	userToken, userID := st.Login(ctx, email, password, app.GetId())
+
+	_, err = st.SessionsClient.RevokeSessions(st.WithToken(ctx, userToken), &sessionsv1.RevokeSessionsRequest{
+		UserId: userID,
+		AppId:  app.GetId(),
+	})
+	require.Error(t, err)
+	assert.Equal(t, codes.PermissionDenied, status.Code(err))
+
+	superToken, _ := st.Login(ctx, suite.SuperAdminEmail, suite.AdminPassword, suite.AppID)
+	adminCtx := st.WithToken(ctx, superToken)
+
+	respRevoke, err := st.SessionsClient.RevokeSessions(adminCtx, &sessionsv1.RevokeSessionsRequest{
+		UserId: userID,
+		AppId:  app.GetId(),
+	})
+	require.NoError(t, err)
+	assert.EqualValues(t, 1, respRevoke.GetNotifiedApps())
+
+	// Test config allows 3 attempts
+	delivery := waitLogoutDelivery(adminCtx, t, st, app.GetId(), "failed")
+	assert.EqualValues(t, 3, delivery.GetAttempts())
+	assert.Contains(t, delivery.GetLastError(), "500")
+	assert.Zero(t, delivery.GetDeliveredAt())
+	assert.EqualValues(t, 3, receiver.requests.Load())
+	assert.Empty(t, receiver.Tokens())
+
+	_, err = st.SessionsClient.ListLogoutDeliveries(st.WithToken(ctx, userToken), &sessionsv1.ListLogoutDeliveriesRequest{
+		AppId: app.GetId(),
+	})
+	require.Error(t, err)
+	assert.Equal(t, codes.PermissionDenied, status.Code(err))
+
+	_, err = st.SessionsClient.Logout(ctx, &sessionsv1.LogoutRequest{})
+	require.Error(t, err)
+	assert.Equal(t, codes.Unauthenticated, status.Code(err))
+}
+
+func TestApps_InvalidBackchannelLogoutURI(t *testing.T) {
+	ctx, st := suite.NewSuite(t)
+
+	superToken, _ := st.Login(ctx, suite.SuperAdminEmail, suite.AdminPassword, suite.AppID)
+	adminCtx := st.WithToken(ctx, superToken)
+
+	for _, logoutURI := range []string{"/logout", "ftp://app.test/logout", "https://app.test/logout#fragment"} {
+		_, err := st.AppsClient.CreateApp(adminCtx, &appsv1.CreateAppRequest{
+			Name:     "logout-" + gofakeit.UUID(),
+			Settings: &appsv1.AppSettings{BackchannelLogoutUri: logoutURI},
+		})
+		require.Error(t, err, logoutURI)
+		assert.Equal(t, codes.InvalidArgument, status.Code(err), logoutURI)
+	}
+}
+
+// waitLogoutDelivery waits until the only logout delivery to the application gets the status
+func waitLogoutDelivery(
+	ctx context.Context,
+	t *testing.T,
+	st *suite.Suite,
+	appID int32,
+	deliveryStatus string,
+) *sessionsv1.LogoutDelivery {
+	t.Helper()
+
+	var delivery *sessionsv1.LogoutDelivery
+
+	require.Eventually(t, func() bool {
+		resp, err := st.SessionsClient.ListLogoutDeliveries(ctx, &sessionsv1.ListLogoutDeliveriesRequest{AppId: appID})
+		if err != nil || len(resp.GetDeliveries()) != 1 {
+			return false
+		}
+
+		delivery = resp.GetDeliveries()[0]
+
+		return delivery.GetStatus() == deliveryStatus
+	}, 4*time.Second, 100*time.Millisecond)
+
+	return delivery
+}
@@ -0,0 +1,541 @@
+package tests
+
+import (
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"html"
+	"net"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/brianvoe/gofakeit/v6"
+	njwt "github.com/nhassl3/sso-app/internals/lib/jwt"
+	"github.com/nhassl3/sso-app/tests/suite"
+	ssov1 "github.com/nhassl3/sso-contracts/generated/go/sso"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// Mock providers are configured in config/local_tests.yaml
+const mockProvidersAddress = "localhost:44090"
+
+var (
+	// mockProviders are served by TestMain by their names
+	mockProviders = map[string]*mockProvider{}
+
+	providerLink = regexp.MustCompile(`href="(/federation/[^"]+)"`)
+)
+
+// mockProvider is a minimal OpenID Connect provider. The test chooses the user by claims added
+// to the query of the authorization request, claim_ prefix is removed from their names.
+// Users added by the test sign in by the password grant
+type mockProvider struct {
+	issuer       string
+	clientID     string
+	clientSecret string
+	key          *njwt.SigningKey
+
+	mu    sync.Mutex
+	codes map[string]mockCode
+	users map[string]mockUser
+}
+
+type mockCode struct {
+	redirectURI string
+	challenge   string
+	nonce       string
+	claims      map[string]any
+}
+
+type mockUser struct {
+	password string
+	claims   map[string]any
+}
+
+// serveMockProviders serves mock and corp providers of the config
+func serveMockProviders() error {
+	key, err := njwt.GenerateSigningKey()
+	if err != nil {
+		return err
+	}
+
+	mux := http.NewServeMux()
+	for name, client := range map[string][2]string{
+		"mock": {"sso-client", "mock-secret"},
+		"corp": {"sso-corp-client", "corp-secret"},
+	} {
+		provider := &mockProvider{
+			issuer:       "http://" + mockProvidersAddress + "/" + name,
+			clientID:     client[0],
+			clientSecret: client[1],
+			key:          key,
+			codes:        map[string]mockCode{},
+			users:        map[string]mockUser{},
+		}
+		mockProviders[name] = provider
+		mux.Handle("/"+name+"/", http.StripPrefix("/"+name, provider.handler()))
+	}
+
+	listener, err := net.Listen("tcp", mockProvidersAddress)
+	if err != nil {
+		return err
+	}
+
+	go func() { _ = http.Serve(listener, mux) }()
+
+	return nil
+}
+
+// addUser adds the user signing in by the password grant with the claims of ID token
+func (p *mockProvider) addUser(username, password string, claims map[string]any) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	p.users[username] = mockUser{password: password, claims: claims}
+}
+
+func (p *mockProvider) handler() http.Handler {
+	mux := http.NewServeMux()
+
+	mux.HandleFunc("GET /.well-known/openid-configuration", func(w http.ResponseWriter, r *http.Request) {
+		writeJSON(w, http.StatusOK, map[string]any{
+			"issuer":                 p.issuer,
+			"authorization_endpoint": p.issuer + "/authorize",
+			"token_endpoint":         p.issuer + "/token",
+			"jwks_uri":               p.issuer + "/jwks",
+		})
+	})
+
+	mux.HandleFunc("GET /jwks", func(w http.ResponseWriter, r *http.Request) {
+		writeJSON(w, http.StatusOK, map[string]any{"keys": []njwt.JWK{p.key.JWK()}})
+	})
+
+	mux.HandleFunc("GET /authorize", p.authorize)
+	mux.HandleFunc("POST /token", p.token)
+
+	return mux
+}
+
+func (p *mockProvider) authorize(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+
+	if query.Get("client_id") != p.clientID || query.Get("response_type") != "code" ||
+		query.Get("code_challenge_method") != "S256" || query.Get("scope") == "" {
+		http.Error(w, "invalid request", http.StatusBadRequest)
+		return
+	}
+
+	callback, err := url.Parse(query.Get("redirect_uri"))
+	if err != nil {
+		http.Error(w, "invalid redirect_uri", http.StatusBadRequest)
+		return
+	}
+
+	params := url.Values{"state": {query.Get("state")}}
+
+	if query.Get("deny") != "" {
+		params.Set("error", "access_denied")
+	} else {
+		code := gofakeit.UUID()
+		issued := mockCode{
+			redirectURI: query.Get("redirect_uri"),
+			challenge:   query.Get("code_challenge"),
+			nonce:       query.Get("nonce"),
+			claims:      map[string]any{},
+		}
+
+		for name, values := range query {
+			if claim, ok := strings.CutPrefix(name, "claim_"); ok {
+				issued.claims[claim] = claimValue(values[0])
+			}
+		}
+
+		if query.Get("bad_nonce") != "" {
+			issued.nonce = "another-nonce"
+		}
+
+		p.mu.Lock()
+		p.codes[code] = issued
+		p.mu.Unlock()
+
+		params.Set("code", code)
+	}
+
+	callback.RawQuery = params.Encode()
+	http.Redirect(w, r, callback.String(), http.StatusFound)
+}
+
+func (p *mockProvider) token(w http.ResponseWriter, r *http.Request) {
+	clientID, clientSecret, ok := r.BasicAuth()
+	if !ok || clientID != p.clientID || clientSecret != p.clientSecret {
+		writeJSON(w, http.StatusUnauthorized, map[string]any{"error": "invalid_client"})
+		return
+	}
+
+	if err := r.ParseForm(); err != nil {
+		writeJSON(w, http.StatusBadRequest, map[string]any{"error": "invalid_request"})
+		return
+	}
+
+	var claims map[string]any
+	if r.PostForm.Get("grant_type") == "password" {
+		claims, ok = p.passwordClaims(r.PostForm)
+	} else {
+		claims, ok = p.codeClaims(r.PostForm)
+	}
+
+	if !ok {
+		writeJSON(w, http.StatusBadRequest, map[string]any{"error": "invalid_grant"})
+		return
+	}
+
+	claims["iss"], claims["aud"] = p.issuer, p.clientID
+
+	idToken, err := p.key.NewIDToken(claims, time.Minute)
+	if err != nil {
+		writeJSON(w, http.StatusInternalServerError, map[string]any{"error": "server_error"})
+		return
+	}
+
+	writeJSON(w, http.StatusOK, map[string]any{
+		"access_token": gofakeit.UUID(),
+		"token_type":   "Bearer",
+		"id_token":     idToken,
+	})
+}
+
+// codeClaims uses the code of the form and returns claims of the user signed in by it
+func (p *mockProvider) codeClaims(form url.Values) (map[string]any, bool) {
+	p.mu.Lock()
+	issued, ok := p.codes[form.Get("code")]
+	delete(p.codes, form.Get("code"))
+	p.mu.Unlock()
+
+	challenge := sha256.Sum256([]byte(form.Get("code_verifier")))
+
+	if !ok || issued.redirectURI != form.Get("redirect_uri") ||
+		issued.challenge != base64.RawURLEncoding.EncodeToString(challenge[:]) {
+		return nil, false
+	}
+
+	claims := map[string]any{"nonce": issued.nonce}
+	for name, value := range issued.claims {
+		claims[name] = value
+	}
+
+	return claims, true
+}
+
+// passwordClaims returns claims of the user with the username and password of the form
+func (p *mockProvider) passwordClaims(form url.Values) (map[string]any, bool) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	user, ok := p.users[form.Get("username")]
+	if !ok || user.password != form.Get("password") {
+		return nil, false
+	}
+
+	claims := make(map[string]any, len(user.claims))
+	for name, value := range user.claims {
+		claims[name] = value
+	}
+
+	return claims, true
+}
+
+func TestFederation_NewUser(t *testing.T) {
+	ctx, st := suite.NewSuite(t)
+
+	appID := createOAuthApp(ctx, t, st, oauthRedirectURI)
+	subject, email := gofakeit.UUID(), st.NewEmail()
+
+	b := newBrowser(t, st)
+	query, verifier := federationQuery(appID)
+
+	resp, page := b.get("/login?" + query.Encode())
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.Contains(t, page, "Sign in with mock")
+	assert.Contains(t, page, "Sign in with corp")
+
+	resp = federatedLogin(t, b, "mock", query, url.Values{
+		"claim_sub":            {subject},
+		"claim_email":          {email},
+		"claim_email_verified": {"true"},
+	})
+	userID := federatedUserID(t, st, resp, appID, verifier)
+
+	// Same identity signs in as the same user
+	query, verifier = federationQuery(appID)
+	resp = federatedLogin(t, b, "mock", query, url.Values{"claim_sub": {subject}, "claim_email": {st.NewEmail()}})
+	assert.Equal(t, userID, federatedUserID(t, st, resp, appID, verifier))
+
+	// Same subject at another provider is another identity, its email is taken by the user
+	resp = federatedLogin(t, b, "corp", loginQuery(appID), url.Values{
+		"claim_employee_id":   {subject},
+		"claim_mail":          {email},
+		"claim_mail_verified": {"true"},
+	})
+	assertFederationError(t, resp, "access_denied")
+}
+
+func TestFederation_LinkExistingUser(t *testing.T) {
+	ctx, st := suite.NewSuite(t)
+
+	appID := createOAuthApp(ctx, t, st, oauthRedirectURI)
+
+	email, password := st.NewEmail(), st.NewPassword()
+	registered, err := st.AuthClient.Register(ctx, &ssov1.RegisterRequest{Email: email, Password: password})
+	require.NoError(t, err)
+
+	b := newBrowser(t, st)
+
+	// Unverified email doesn't prove the identity owns the account
+	resp := federatedLogin(t, b, "mock", loginQuery(appID), url.Values{
+		"claim_sub":            {gofakeit.UUID()},
+		"claim_email":          {email},
+		"claim_email_verified": {"false"},
+	})
+	assertFederationError(t, resp, "access_denied")
+
+	// Corp provider isn't trusted to verify emails
+	resp = federatedLogin(t, b, "corp", loginQuery(appID), url.Values{
+		"claim_employee_id":   {gofakeit.UUID()},
+		"claim_mail":          {email},
+		"claim_mail_verified": {"true"},
+	})
+	assertFederationError(t, resp, "access_denied")
+
+	subject := gofakeit.UUID()
+
+	query, verifier := federationQuery(appID)
+	resp = federatedLogin(t, b, "mock", query, url.Values{
+		"claim_sub":            {subject},
+		"claim_email":          {email},
+		"claim_email_verified": {"true"},
+	})
+	assert.Equal(t, registered.GetUserId(), federatedUserID(t, st, resp, appID, verifier))
+
+	// Linked identity doesn't need verified email anymore, password of the user still works
+	query, verifier = federationQuery(appID)
+	resp = federatedLogin(t, b, "mock", query, url.Values{"claim_sub": {subject}})
+	assert.Equal(t, registered.GetUserId(), federatedUserID(t, st, resp, appID, verifier))
+
+	st.Login(ctx, email, password, suite.AppID)
+}
+
+func TestFederation_ClaimMapping(t *testing.T) {
+	ctx, st := suite.NewSuite(t)
+
+	appID := createOAuthApp(ctx, t, st, oauthRedirectURI)
+	email := st.NewEmail()
+
+	b := newBrowser(t, st)
+
+	query, verifier := federationQuery(appID)
+	resp := federatedLogin(t, b, "corp", query, url.Values{
+		"claim_employee_id": {strconv.Itoa(gofakeit.Number(1, 1<<30))},
+		"claim_mail":        {email},
+	})
+	userID := federatedUserID(t, st, resp, appID, verifier)
+	assert.Positive(t, userID)
+
+	// Identity without email can't create the user
+	resp = federatedLogin(t, b, "corp", loginQuery(appID), url.Values{"claim_employee_id": {gofakeit.UUID()}, "claim_email": {email}})
+	assertFederationError(t, resp, "access_denied")
+}
+
+func TestFederation_InvalidCallback(t *testing.T) {
+	ctx, st := suite.NewSuite(t)
+
+	appID := createOAuthApp(ctx, t, st, oauthRedirectURI)
+	claims := url.Values{"claim_sub": {gofakeit.UUID()}, "claim_email": {st.NewEmail()}}
+
+	b := newBrowser(t, st)
+
+	resp, _ := b.get("/federation/unknown/login?" + loginQuery(appID).Encode())
+	assert.Equal(t, http.StatusNotFound, resp.StatusCode)
+
+	// Unregistered redirect URI isn't trusted with the error
+	query := loginQuery(appID)
+	query.Set("redirect_uri", "https://evil.test/callback")
+	resp, _ = b.get("/federation/mock/login?" + query.Encode())
+	assert.Equal(t, http.StatusBadRequest, resp.StatusCode)
+
+	// Nonce of ID token must be the nonce of the request
+	badNonce := url.Values{"bad_nonce": {"1"}}
+	for name, values := range claims {
+		badNonce[name] = values
+	}
+	resp = federatedLogin(t, b, "mock", loginQuery(appID), badNonce)
+	assertFederationError(t, resp, "access_denied")
+
+	resp = federatedLogin(t, b, "mock", loginQuery(appID), url.Values{"deny": {"1"}})
+	assertFederationError(t, resp, "access_denied")
+
+	// Callback is bound to the browser which started the login and is handled once
+	resp, _ = b.get("/federation/mock/login?" + loginQuery(appID).Encode())
+	require.Equal(t, http.StatusFound, resp.StatusCode)
+	callback := upstreamCallback(t, st, resp.Header.Get("Location"), claims)
+
+	resp, _ = newBrowser(t, st).get(callback)
+	assert.Equal(t, http.StatusBadRequest, resp.StatusCode)
+
+	resp, _ = b.get(callback)
+	require.Equal(t, http.StatusFound, resp.StatusCode)
+	assert.NotEmpty(t, redirectQuery(t, resp).Get("code"))
+
+	used, err := url.Parse(callback)
+	require.NoError(t, err)
+
+	b.cookies["sso_federation"] = &http.Cookie{Name: "sso_federation", Value: used.Query().Get("state")}
+	resp, _ = b.get(callback)
+	assert.Equal(t, http.StatusBadRequest, resp.StatusCode)
+}
+
+// federationQuery returns authorization request of the client asking for ID token and its code verifier
+func federationQuery(appID int32) (url.Values, string) {
+	verifier := gofakeit.LetterN(64)
+
+	query := authorizeQuery(appID, verifier)
+	query.Set("scope", "openid email")
+	query.Set("nonce", gofakeit.LetterN(16))
+
+	return query, verifier
+}
+
+// loginQuery returns authorization request of the client which isn't finished by the test
+func loginQuery(appID int32) url.Values {
+	query, _ := federationQuery(appID)
+
+	return query
+}
+
+// federatedLogin signs in at the provider by the link of the login page and returns response of the callback
+func federatedLogin(t *testing.T, b *browser, provider string, query url.Values, claims url.Values) *http.Response {
+	t.Helper()
+
+	resp, page := b.get("/login?" + query.Encode())
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+
+	var link string
+	for _, match := range providerLink.FindAllStringSubmatch(page, -1) {
+		if path := html.UnescapeString(match[1]); strings.HasPrefix(path, "/federation/"+provider+"/") {
+			link = path
+		}
+	}
+	require.NotEmpty(t, link, "login page has no link of %s", provider)
+
+	resp, _ = b.get(link)
+	require.Equal(t, http.StatusFound, resp.StatusCode)
+
+	state := b.cookies["sso_federation"]
+	require.NotNil(t, state)
+	assert.Equal(t, http.SameSiteLaxMode, state.SameSite)
+	assert.Equal(t, "/federation/", state.Path)
+	assert.True(t, state.HttpOnly)
+
+	resp, _ = b.get(upstreamCallback(t, b.st, resp.Header.Get("Location"), claims))
+
+	return resp
+}
+
+// upstreamCallback signs in at the provider with the claims and returns path of the callback the provider redirects to
+func upstreamCallback(t *testing.T, st *suite.Suite, authURL string, claims url.Values) string {
+	t.Helper()
+
+	u, err := url.Parse(authURL)
+	require.NoError(t, err)
+	assert.Equal(t, mockProvidersAddress, u.Host)
+
+	query := u.Query()
+	for name, values := range claims {
+		query[name] = values
+	}
+	u.RawQuery = query.Encode()
+
+	resp, err := st.HTTPClient.Get(u.String())
+	require.NoError(t, err)
+	_ = resp.Body.Close()
+	require.Equal(t, http.StatusFound, resp.StatusCode)
+
+	callback, err := url.Parse(resp.Header.Get("Location"))
+	require.NoError(t, err)
+
+	return callback.RequestURI()
+}
+
+// federatedUserID exchanges the code of the callback and returns ID of the user of the ID token
+func federatedUserID(t *testing.T, st *suite.Suite, resp *http.Response, appID int32, verifier string) int64 {
+	t.Helper()
+
+	require.Equal(t, http.StatusFound, resp.StatusCode)
+
+	location := redirectQuery(t, resp)
+	require.Empty(t, location.Get("error"), location.Get("error_description"))
+	assert.Equal(t, "page-state", location.Get("state"))
+
+	resp, body := postForm(t, st, "/token", url.Values{
+		"grant_type":    {"authorization_code"},
+		"client_id":     {strconv.Itoa(int(appID))},
+		"code":          {location.Get("code")},
+		"redirect_uri":  {oauthRedirectURI},
+		"code_verifier": {verifier},
+	})
+	require.Equal(t, http.StatusOK, resp.StatusCode, body)
+
+	_, info := getJSON(t, st, "/userinfo", body["access_token"].(string))
+
+	userID, err := strconv.ParseInt(info["sub"].(string), 10, 64)
+	require.NoError(t, err)
+
+	return userID
+}
+
+// assertFederationError checks that the callback sends the error to the client
+func assertFederationError(t *testing.T, resp *http.Response, code string) {
+	t.Helper()
+
+	require.Equal(t, http.StatusFound, resp.StatusCode)
+
+	location := redirectQuery(t, resp)
+	assert.Equal(t, code, location.Get("error"))
+	assert.Equal(t, "page-state", location.Get("state"))
+	assert.Empty(t, location.Get("code"))
+}
+
+// redirectQuery returns query of the URI the response redirects to
+func redirectQuery(t *testing.T, resp *http.Response) url.Values {
+	t.Helper()
+
+	u, err := url.Parse(resp.Header.Get("Location"))
+	require.NoError(t, err)
+
+	return u.Query()
+}
+
+// claimValue converts flags of the query to boolean claims
+func claimValue(value string) any {
+	switch value {
+	case "true":
+		return true
+	case "false":
+		return false
+	}
+
+	return value
+}
+
+func writeJSON(w http.ResponseWriter, status int, body any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+
+	_ = json.NewEncoder(w).Encode(body)
+}
@@ -0,0 +1,162 @@
+package tests
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"testing"
+
+	"github.com/nhassl3/sso-app/tests/suite"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGateway_RegisterLogin(t *testing.T) {
+	_, st := suite.NewSuite(t)
+
+	email, password := st.NewEmail(), st.NewPassword()
+
+	resp, body := gatewayRequest(t, st, http.MethodPost, "/v1/auth/register", "", map[string]any{
+		"email":    email,
+		"password": password,
+	})
+	require.Equal(t, http.StatusOK, resp.StatusCode, body)
+
+	// protojson encodes 64-bit integers as strings
+	userID, err := strconv.ParseInt(body["user_id"].(string), 10, 64)
+	require.NoError(t, err)
+	assert.Positive(t, userID)
+
+	resp, body = gatewayRequest(t, st, http.MethodPost, "/v1/auth/login", "", map[string]any{
+		"email":    email,
+		"password": password,
+		"app_id":   suite.AppID,
+	})
+	require.Equal(t, http.StatusOK, resp.StatusCode, body)
+
+	token := body["token"].(string)
+	claims := parseClaims(t, token, suite.AppSecret)
+	assert.Equal(t, email, claims["email"])
+
+	resp, body = gatewayRequest(t, st, http.MethodPost, "/v1/auth/login", "", map[string]any{
+		"email":    email,
+		"password": password + "x",
+		"app_id":   suite.AppID,
+	})
+	assert.Equal(t, http.StatusBadRequest, resp.StatusCode)
+	assert.Equal(t, "invalid_argument", body["error"])
+	assert.InDelta(t, 3, body["code"], 0)
+	assert.Equal(t, "email or password is invalid", body["message"])
+
+	// Bearer token is passed to the RPC
+	resp, body = gatewayRequest(t, st, http.MethodPost, "/v1/sessions/logout", token, nil)
+	require.Equal(t, http.StatusOK, resp.StatusCode, body)
+	assert.InDelta(t, 0, body["notified_apps"], 0)
+
+	resp, body = gatewayRequest(t, st, http.MethodPost, "/v1/sessions/logout", "", nil)
+	assert.Equal(t, http.StatusUnauthorized, resp.StatusCode)
+	assert.Equal(t, "unauthenticated", body["error"])
+}
+
+func TestGateway_IsAdmin(t *testing.T) {
+	ctx, st := suite.NewSuite(t)
+
+	_, superAdminID := st.Login(ctx, suite.SuperAdminEmail, suite.AdminPassword, suite.AppID)
+	_, userID := st.Login(ctx, suite.RolesUserEmail, suite.RolesUserPassword, suite.AppID)
+
+	resp, body := gatewayRequest(t, st, http.MethodGet, "/v1/users/"+strconv.FormatInt(superAdminID, 10)+"/admin", "", nil)
+	require.Equal(t, http.StatusOK, resp.StatusCode, body)
+	assert.Equal(t, true, body["is_admin"])
+
+	// Unpopulated fields are written too
+	resp, body = gatewayRequest(t, st, http.MethodGet, "/v1/users/"+strconv.FormatInt(userID, 10)+"/admin", "", nil)
+	require.Equal(t, http.StatusOK, resp.StatusCode, body)
+	assert.Equal(t, false, body["is_admin"])
+
+	resp, body = gatewayRequest(t, st, http.MethodGet, "/v1/users/0/admin", "", nil)
+	assert.Equal(t, http.StatusBadRequest, resp.StatusCode)
+	assert.Equal(t, "invalid user id", body["message"])
+
+	resp, body = gatewayRequest(t, st, http.MethodGet, "/v1/users/abc/admin", "", nil)
+	assert.Equal(t, http.StatusBadRequest, resp.StatusCode)
+	assert.Equal(t, "invalid_argument", body["error"])
+
+	resp, body = gatewayRequest(t, st, http.MethodGet, "/v1/users/1/admin?role=admin", "", nil)
+	assert.Equal(t, http.StatusBadRequest, resp.StatusCode)
+	assert.Contains(t, body["message"], "role")
+}
+
+func TestGateway_MalformedBody(t *testing.T) {
+	_, st := suite.NewSuite(t)
+
+	req, err := http.NewRequest(http.MethodPost, st.GatewayURL("/v1/auth/login"), bytes.NewBufferString("{"))
+	require.NoError(t, err)
+
+	resp, body := doJSON(t, st, req)
+	assert.Equal(t, http.StatusBadRequest, resp.StatusCode)
+	assert.Equal(t, "invalid_argument", body["error"])
+
+	resp, body = gatewayRequest(t, st, http.MethodPost, "/v1/auth/login", "", map[string]any{"login": "user"})
+	assert.Equal(t, http.StatusBadRequest, resp.StatusCode)
+	assert.Equal(t, "invalid_argument", body["error"])
+}
+
+func TestGateway_OpenAPI(t *testing.T) {
+	_, st := suite.NewSuite(t)
+
+	resp, body := gatewayRequest(t, st, http.MethodGet, "/openapi.json", "", nil)
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.Equal(t, "3.0.3", body["openapi"])
+
+	paths := body["paths"].(map[string]any)
+	require.Contains(t, paths, "/v1/auth/login")
+	require.Contains(t, paths, "/v1/users/{user_id}/admin")
+
+	login := paths["/v1/auth/login"].(map[string]any)["post"].(map[string]any)
+	assert.Equal(t, "Auth_Login", login["operationId"])
+
+	isAdmin := paths["/v1/users/{user_id}/admin"].(map[string]any)["get"].(map[string]any)
+	parameters := isAdmin["parameters"].([]any)
+	require.Len(t, parameters, 1)
+	assert.Equal(t, "user_id", parameters[0].(map[string]any)["name"])
+	assert.Equal(t, "path", parameters[0].(map[string]any)["in"])
+
+	schemas := body["components"].(map[string]any)["schemas"].(map[string]any)
+	require.Contains(t, schemas, "auth.LoginRequest")
+	require.Contains(t, schemas, "Error")
+
+	properties := schemas["auth.LoginRequest"].(map[string]any)["properties"].(map[string]any)
+	assert.Equal(t, map[string]any{"type": "integer", "format": "int32"}, properties["app_id"])
+	assert.Equal(t, map[string]any{"type": "string"}, properties["email"])
+
+	properties = schemas["auth.RegisterResponse"].(map[string]any)["properties"].(map[string]any)
+	assert.Equal(t, map[string]any{"type": "string", "format": "int64"}, properties["user_id"])
+}
+
+// gatewayRequest sends the request with JSON body to the REST gateway
+func gatewayRequest(
+	t *testing.T,
+	st *suite.Suite,
+	method string,
+	path string,
+	bearer string,
+	payload map[string]any,
+) (*http.Response, map[string]any) {
+	t.Helper()
+
+	var body bytes.Buffer
+	if payload != nil {
+		require.NoError(t, json.NewEncoder(&body).Encode(payload))
+	}
+
+	req, err := http.NewRequest(method, st.GatewayURL(path), &body)
+	require.NoError(t, err)
+	req.Header.Set("Content-Type", "application/json")
+
+	if bearer != "" {
+		req.Header.Set("Authorization", "Bearer "+bearer)
+	}
+
+	return doJSON(t, st, req)
+}
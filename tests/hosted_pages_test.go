@@ -0,0 +1,374 @@
+package tests
+
+import (
+	"io"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/brianvoe/gofakeit/v6"
+	appsv1 "github.com/nhassl3/sso-app/contracts/generated/go/apps"
+	"github.com/nhassl3/sso-app/tests/suite"
+	ssov1 "github.com/nhassl3/sso-contracts/generated/go/sso"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+var csrfInput = regexp.MustCompile(`name="csrf_token" value="([^"]+)"`)
+
+// browser keeps cookies of the hosted pages, the cookies are secure, so cookie jar wouldn't send them to plain HTTP server
+type browser struct {
+	t       *testing.T
+	st      *suite.Suite
+	cookies map[string]*http.Cookie
+}
+
+func newBrowser(t *testing.T, st *suite.Suite) *browser {
+	return &browser{t: t, st: st, cookies: map[string]*http.Cookie{}}
+}
+
+func (b *browser) do(req *http.Request) (*http.Response, string) {
+	b.t.Helper()
+
+	for _, cookie := range b.cookies {
+		req.AddCookie(cookie)
+	}
+
+	resp, err := b.st.HTTPClient.Do(req)
+	require.NoError(b.t, err)
+	defer resp.Body.Close()
+
+	for _, cookie := range resp.Cookies() {
+		if cookie.MaxAge < 0 {
+			delete(b.cookies, cookie.Name)
+			continue
+		}
+
+		b.cookies[cookie.Name] = cookie
+	}
+
+	body, err := io.ReadAll(resp.Body)
+	require.NoError(b.t, err)
+
+	return resp, string(body)
+}
+
+func (b *browser) get(path string) (*http.Response, string) {
+	b.t.Helper()
+
+	req, err := http.NewRequest(http.MethodGet, b.st.HTTPURL(path), nil)
+	require.NoError(b.t, err)
+
+	return b.do(req)
+}
+
+func (b *browser) post(path string, form url.Values) (*http.Response, string) {
+	b.t.Helper()
+
+	req, err := http.NewRequest(http.MethodPost, b.st.HTTPURL(path), strings.NewReader(form.Encode()))
+	require.NoError(b.t, err)
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+
+	return b.do(req)
+}
+
+// submit posts the form of the page with its CSRF token
+func (b *browser) submit(page string, path string, form url.Values) (*http.Response, string) {
+	b.t.Helper()
+
+	match := csrfInput.FindStringSubmatch(page)
+	require.NotNil(b.t, match, "page has no CSRF token")
+
+	form.Set("csrf_token", match[1])
+
+	return b.post(path, form)
+}
+
+// signIn submits credentials of the user on the login page of the authorization request
+func (b *browser) signIn(query url.Values, email, password string) (*http.Response, string) {
+	b.t.Helper()
+
+	resp, page := b.get("/login?" + query.Encode())
+	require.Equal(b.t, http.StatusOK, resp.StatusCode)
+
+	form := url.Values{"email": {email}, "password": {password}}
+	for name, values := range query {
+		form[name] = values
+	}
+
+	return b.submit(page, "/login", form)
+}
+
+func authorizeQuery(appID int32, verifier string) url.Values {
+	return url.Values{
+		"response_type":         {"code"},
+		"client_id":             {strconv.Itoa(int(appID))},
+		"redirect_uri":          {oauthRedirectURI},
+		"state":                 {"page-state"},
+		"scope":                 {"docs:read"},
+		"code_challenge":        {pkceChallenge(verifier)},
+		"code_challenge_method": {"S256"},
+	}
+}
+
+func TestPages_Login(t *testing.T) {
+	ctx, st := suite.NewSuite(t)
+
+	app := createApp(ctx, t, st, &appsv1.AppSettings{
+		RedirectUris: []string{oauthRedirectURI},
+		PublicClient: true,
+		Theme: &appsv1.AppTheme{
+			LogoUri:         "https://client.test/logo.png",
+			PrimaryColor:    "#ff5500",
+			BackgroundColor: "#101010",
+			Title:           "Welcome to <Docs>",
+			Text:            "Sign in to edit your documents",
+		},
+	})
+	assert.Equal(t, "#ff5500", app.GetSettings().GetTheme().GetPrimaryColor())
+
+	email, password := st.NewEmail(), st.NewPassword()
+	_, err := st.AuthClient.Register(ctx, &ssov1.RegisterRequest{Email: email, Password: password})
+	require.NoError(t, err)
+
+	verifier := gofakeit.LetterN(64)
+	query := authorizeQuery(app.GetId(), verifier)
+	b := newBrowser(t, st)
+
+	// Browser without credentials is sent to the login page
+	resp, _ := b.get("/authorize?" + query.Encode())
+	require.Equal(t, http.StatusFound, resp.StatusCode)
+
+	location := resp.Header.Get("Location")
+	assert.True(t, strings.HasPrefix(location, "/login?"), location)
+
+	resp, page := b.get(location)
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.Equal(t, "DENY", resp.Header.Get("X-Frame-Options"))
+	assert.Contains(t, resp.Header.Get("Content-Security-Policy"), "frame-ancestors 'none'")
+	assert.Equal(t, "no-store", resp.Header.Get("Cache-Control"))
+
+	// Theme of the application is escaped
+	assert.Contains(t, page, "Welcome to &lt;Docs&gt;")
+	assert.Contains(t, page, "Sign in to edit your documents")
+	assert.Contains(t, page, `src="https://client.test/logo.png"`)
+	assert.Contains(t, page, "--primary: #ff5500")
+	assert.Contains(t, page, "background-color: #101010")
+
+	csrf := b.cookies["sso_csrf"]
+	require.NotNil(t, csrf)
+	assert.True(t, csrf.Secure)
+	assert.True(t, csrf.HttpOnly)
+	assert.Equal(t, http.SameSiteStrictMode, csrf.SameSite)
+
+	form := url.Values{"email": {email}, "password": {password}}
+	for name, values := range query {
+		form[name] = values
+	}
+
+	// Form without CSRF token is rejected
+	resp, _ = b.post("/login", form)
+	assert.Equal(t, http.StatusForbidden, resp.StatusCode)
+
+	form.Set("password", password+"x")
+	resp, page = b.submit(page, "/login", form)
+	assert.Equal(t, http.StatusUnauthorized, resp.StatusCode)
+	assert.Contains(t, page, "Email or password is invalid")
+
+	form.Set("password", password)
+	resp, _ = b.submit(page, "/login", form)
+	require.Equal(t, http.StatusFound, resp.StatusCode)
+
+	redirected, err := url.Parse(resp.Header.Get("Location"))
+	require.NoError(t, err)
+	assert.Equal(t, "page-state", redirected.Query().Get("state"))
+
+	resp, body := postForm(t, st, "/token", url.Values{
+		"grant_type":    {"authorization_code"},
+		"client_id":     {strconv.Itoa(int(app.GetId()))},
+		"code":          {redirected.Query().Get("code")},
+		"redirect_uri":  {oauthRedirectURI},
+		"code_verifier": {verifier},
+	})
+	require.Equal(t, http.StatusOK, resp.StatusCode, body)
+	assert.NotEmpty(t, body["access_token"])
+
+	resp, _ = b.get("/static/style.css")
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+}
+
+func TestPages_Register(t *testing.T) {
+	ctx, st := suite.NewSuite(t)
+
+	appID := createOAuthApp(ctx, t, st, oauthRedirectURI)
+	query := authorizeQuery(appID, gofakeit.LetterN(64))
+	b := newBrowser(t, st)
+
+	resp, page := b.get("/register?" + query.Encode())
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+
+	email, password := st.NewEmail(), st.NewPassword()
+
+	form := url.Values{"email": {email}, "password": {password}, "password_confirm": {password + "x"}}
+	for name, values := range query {
+		form[name] = values
+	}
+
+	resp, page = b.submit(page, "/register", form)
+	assert.Equal(t, http.StatusBadRequest, resp.StatusCode)
+	assert.Contains(t, page, "Passwords don&#39;t match")
+
+	form.Set("password_confirm", password)
+	resp, page = b.submit(page, "/register", form)
+	require.Equal(t, http.StatusSeeOther, resp.StatusCode)
+
+	location := resp.Header.Get("Location")
+	assert.True(t, strings.HasPrefix(location, "/login?"), location)
+	assert.Contains(t, location, "registered=1")
+
+	resp, page = b.get(location)
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.Contains(t, page, "Your account is created")
+
+	// Registered user signs in by gRPC too
+	st.Login(ctx, email, password, suite.AppID)
+
+	resp, page = b.get("/register?" + query.Encode())
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+
+	resp, page = b.submit(page, "/register", form)
+	assert.Equal(t, http.StatusConflict, resp.StatusCode)
+	assert.Contains(t, page, "User with this email already exists")
+
+	form.Set("email", "not-an-email")
+	resp, _ = b.submit(page, "/register", form)
+	assert.Equal(t, http.StatusBadRequest, resp.StatusCode)
+}
+
+func TestPages_Consent(t *testing.T) {
+	ctx, st := suite.NewSuite(t)
+
+	app := createApp(ctx, t, st, &appsv1.AppSettings{
+		RedirectUris: []string{oauthRedirectURI},
+		PublicClient: true,
+		ThirdParty:   true,
+	})
+
+	email, password := st.NewEmail(), st.NewPassword()
+	_, err := st.AuthClient.Register(ctx, &ssov1.RegisterRequest{Email: email, Password: password})
+	require.NoError(t, err)
+
+	query := authorizeQuery(app.GetId(), gofakeit.LetterN(64))
+
+	login := func(b *browser) string {
+		resp, page := b.get("/login?" + query.Encode())
+		require.Equal(t, http.StatusOK, resp.StatusCode)
+
+		form := url.Values{"email": {email}, "password": {password}}
+		for name, values := range query {
+			form[name] = values
+		}
+
+		resp, page = b.submit(page, "/login", form)
+		require.Equal(t, http.StatusOK, resp.StatusCode)
+		assert.Contains(t, page, app.GetName())
+		assert.Contains(t, page, "<li>docs:read</li>")
+
+		require.Contains(t, b.cookies, "sso_login")
+		assert.Equal(t, "/consent", b.cookies["sso_login"].Path)
+
+		return page
+	}
+
+	decide := func(b *browser, page, decision string) *http.Response {
+		form := url.Values{"consent": {decision}}
+		for name, values := range query {
+			form[name] = values
+		}
+
+		resp, _ := b.submit(page, "/consent", form)
+
+		return resp
+	}
+
+	b := newBrowser(t, st)
+	page := login(b)
+
+	resp := decide(b, page, "deny")
+	require.Equal(t, http.StatusFound, resp.StatusCode)
+
+	location, err := url.Parse(resp.Header.Get("Location"))
+	require.NoError(t, err)
+	assert.Equal(t, "access_denied", location.Query().Get("error"))
+	assert.NotContains(t, b.cookies, "sso_login")
+
+	// Login is sealed for one decision
+	resp = decide(b, page, "approve")
+	assert.Equal(t, http.StatusUnauthorized, resp.StatusCode)
+
+	page = login(b)
+
+	// Sealed login isn't accepted by another browser
+	other := newBrowser(t, st)
+	_, otherPage := other.get("/login?" + query.Encode())
+	resp = decide(other, otherPage, "approve")
+	assert.Equal(t, http.StatusUnauthorized, resp.StatusCode)
+
+	// Consent is given only to the request the user signed in for
+	changed := url.Values{"consent": {"approve"}}
+	for name, values := range query {
+		changed[name] = values
+	}
+	changed.Set("scope", "docs:read docs:write")
+
+	resp, _ = b.submit(page, "/consent", changed)
+	assert.Equal(t, http.StatusUnauthorized, resp.StatusCode)
+
+	resp = decide(b, page, "approve")
+	require.Equal(t, http.StatusFound, resp.StatusCode)
+
+	location, err = url.Parse(resp.Header.Get("Location"))
+	require.NoError(t, err)
+	assert.NotEmpty(t, location.Query().Get("code"))
+}
+
+func TestPages_UnknownClient(t *testing.T) {
+	_, st := suite.NewSuite(t)
+
+	b := newBrowser(t, st)
+
+	resp, page := b.get("/login?client_id=999999")
+	assert.Equal(t, http.StatusBadRequest, resp.StatusCode)
+	assert.Contains(t, page, "Application is unknown or disabled")
+
+	resp, _ = b.get("/login")
+	assert.Equal(t, http.StatusBadRequest, resp.StatusCode)
+}
+
+func TestApps_InvalidTheme(t *testing.T) {
+	ctx, st := suite.NewSuite(t)
+
+	superToken, _ := st.Login(ctx, suite.SuperAdminEmail, suite.AdminPassword, suite.AppID)
+	adminCtx := st.WithToken(ctx, superToken)
+
+	invalid := []*appsv1.AppTheme{
+		{PrimaryColor: "red; background: url(https://evil.test)"},
+		{BackgroundColor: "#12345"},
+		{LogoUri: "javascript:alert(1)"},
+		{Title: strings.Repeat("a", 101)},
+	}
+
+	for _, theme := range invalid {
+		_, err := st.AppsClient.CreateApp(adminCtx, &appsv1.CreateAppRequest{
+			Name:     "theme-" + gofakeit.UUID(),
+			Settings: &appsv1.AppSettings{Theme: theme},
+		})
+		require.Error(t, err, theme)
+		assert.Equal(t, codes.InvalidArgument, status.Code(err), theme)
+	}
+}
@@ -0,0 +1,73 @@
+package tests
+
+import (
+	"testing"
+
+	"github.com/brianvoe/gofakeit/v6"
+	"github.com/nhassl3/sso-app/tests/suite"
+	ssov1 "github.com/nhassl3/sso-contracts/generated/go/sso"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// Identity chain of config/local_tests.yaml is ldap, local with fallthrough and then oidc of corp provider
+
+func TestIdentityChain_RecordsProvider(t *testing.T) {
+	ctx, st := suite.NewSuite(t)
+
+	email, password := st.NewEmail(), st.NewPassword()
+	_, err := st.AuthClient.Register(ctx, &ssov1.RegisterRequest{Email: email, Password: password})
+	require.NoError(t, err)
+
+	token, _ := st.Login(ctx, email, password, suite.AppID)
+	assert.Equal(t, "local", parseClaims(t, token, suite.AppSecret)["idp"])
+
+	email, password = st.NewEmail(), st.NewPassword()
+	mockDirectory.addUser(gofakeit.UUID(), email, password)
+
+	token, _ = st.Login(ctx, email, password, suite.AppID)
+	assert.Equal(t, "ldap", parseClaims(t, token, suite.AppSecret)["idp"])
+
+	email, password = st.NewEmail(), st.NewPassword()
+	mockProviders["corp"].addUser(email, password, map[string]any{"employee_id": gofakeit.UUID(), "mail": email})
+
+	token, userID := st.Login(ctx, email, password, suite.AppID)
+	assert.Equal(t, "corp", parseClaims(t, token, suite.AppSecret)["idp"])
+	assert.Equal(t, email, parseClaims(t, token, suite.AppSecret)["email"])
+
+	// User of the provider is created once
+	_, sameUserID := st.Login(ctx, email, password, suite.AppID)
+	assert.Equal(t, userID, sameUserID)
+
+	_, err = st.AuthClient.Login(ctx, &ssov1.LoginRequest{Email: email, Password: password + "x", AppId: suite.AppID})
+	require.Error(t, err)
+	assert.Equal(t, codes.InvalidArgument, status.Code(err))
+}
+
+func TestIdentityChain_RejectStopsChain(t *testing.T) {
+	ctx, st := suite.NewSuite(t)
+
+	// Directory rejects the password without passing the user to the provider which accepts it
+	email, password, providerPassword := st.NewEmail(), st.NewPassword(), st.NewPassword()
+	mockDirectory.addUser(gofakeit.UUID(), email, password)
+	mockProviders["corp"].addUser(email, providerPassword, map[string]any{"employee_id": gofakeit.UUID(), "mail": email})
+
+	_, err := st.AuthClient.Login(ctx, &ssov1.LoginRequest{Email: email, Password: providerPassword, AppId: suite.AppID})
+	require.Error(t, err)
+	assert.Equal(t, codes.InvalidArgument, status.Code(err))
+
+	// Invalid local password falls through to the provider, which can't take over the local user
+	email, password, providerPassword = st.NewEmail(), st.NewPassword(), st.NewPassword()
+	_, err = st.AuthClient.Register(ctx, &ssov1.RegisterRequest{Email: email, Password: password})
+	require.NoError(t, err)
+	mockProviders["corp"].addUser(email, providerPassword, map[string]any{"employee_id": gofakeit.UUID(), "mail": email})
+
+	_, err = st.AuthClient.Login(ctx, &ssov1.LoginRequest{Email: email, Password: providerPassword, AppId: suite.AppID})
+	require.Error(t, err)
+	assert.Equal(t, codes.InvalidArgument, status.Code(err))
+
+	token, _ := st.Login(ctx, email, password, suite.AppID)
+	assert.Equal(t, "local", parseClaims(t, token, suite.AppSecret)["idp"])
+}
@@ -0,0 +1,333 @@
+package tests
+
+import (
+	"net"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/brianvoe/gofakeit/v6"
+	ber "github.com/go-asn1-ber/asn1-ber"
+	"github.com/nhassl3/sso-app/tests/suite"
+	ssov1 "github.com/nhassl3/sso-contracts/generated/go/sso"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// Mock directory is configured in config/local_tests.yaml
+const (
+	mockDirectoryAddress = "localhost:44389"
+	directoryBindDN      = "cn=sso,dc=sso,dc=test"
+	directoryBindPass    = "service-password"
+	directoryPeopleDN    = "ou=people,dc=sso,dc=test"
+	directoryEditorsDN   = "cn=editors,ou=groups,dc=sso,dc=test"
+	directoryViewersDN   = "cn=viewers,ou=groups,dc=sso,dc=test"
+)
+
+// Operations and result codes of RFC 4511
+const (
+	ldapBindRequest        ber.Tag = 0
+	ldapBindResponse       ber.Tag = 1
+	ldapUnbindRequest      ber.Tag = 2
+	ldapSearchRequest      ber.Tag = 3
+	ldapSearchEntry        ber.Tag = 4
+	ldapSearchDone         ber.Tag = 5
+	ldapFilterAnd          ber.Tag = 0
+	ldapFilterEquality     ber.Tag = 3
+	ldapFilterPresent      ber.Tag = 7
+	ldapSuccess                    = 0
+	ldapInvalidCreds               = 49
+	ldapInsufficientAccess         = 50
+	ldapUnwillingToPerform         = 53
+)
+
+// mockDirectory is the directory every login of the tests is authenticated by before the database.
+// It serves simple bind and search by AND of equality and presence filters
+var mockDirectory = &directory{entries: map[string]*directoryEntry{}}
+
+type directory struct {
+	mu      sync.Mutex
+	entries map[string]*directoryEntry // by lowercase DN
+}
+
+type directoryEntry struct {
+	dn         string
+	password   string
+	attributes map[string][]string
+}
+
+// serveMockDirectory serves the mock directory of the config
+func serveMockDirectory() error {
+	listener, err := net.Listen("tcp", mockDirectoryAddress)
+	if err != nil {
+		return err
+	}
+
+	go mockDirectory.serve(listener)
+
+	return nil
+}
+
+// addUser adds the person to the directory and returns its DN
+func (d *directory) addUser(uid, mail, password string, groups ...string) string {
+	entry := &directoryEntry{
+		dn:       "uid=" + uid + "," + directoryPeopleDN,
+		password: password,
+		attributes: map[string][]string{
+			"objectClass": {"person"},
+			"uid":         {uid},
+			"memberOf":    groups,
+		},
+	}
+	if mail != "" {
+		entry.attributes["mail"] = []string{mail}
+	}
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	d.entries[strings.ToLower(entry.dn)] = entry
+
+	return entry.dn
+}
+
+// setGroups replaces groups of the entry
+func (d *directory) setGroups(dn string, groups ...string) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	d.entries[strings.ToLower(dn)].attributes["memberOf"] = groups
+}
+
+func (d *directory) serve(listener net.Listener) {
+	for {
+		conn, err := listener.Accept()
+		if err != nil {
+			return
+		}
+
+		go d.handle(conn)
+	}
+}
+
+// handle serves requests of the connection one by one, search is allowed only to the service account
+func (d *directory) handle(conn net.Conn) {
+	defer conn.Close()
+
+	var boundDN string
+
+	for {
+		packet, err := ber.ReadPacket(conn)
+		if err != nil {
+			return
+		}
+
+		if len(packet.Children) < 2 {
+			return
+		}
+
+		messageID, _ := packet.Children[0].Value.(int64)
+		op := packet.Children[1]
+
+		var responses []*ber.Packet
+
+		switch op.Tag {
+		case ldapBindRequest:
+			dn, _ := op.Children[1].Value.(string)
+			password := op.Children[2].Data.String()
+
+			code := d.bind(dn, password)
+			if code == ldapSuccess {
+				boundDN = dn
+			} else {
+				boundDN = ""
+			}
+
+			responses = append(responses, ldapResult(ldapBindResponse, code))
+		case ldapSearchRequest:
+			if !strings.EqualFold(boundDN, directoryBindDN) {
+				responses = append(responses, ldapResult(ldapSearchDone, ldapInsufficientAccess))
+				break
+			}
+
+			baseDN, _ := op.Children[0].Value.(string)
+			responses = append(responses, d.search(baseDN, op.Children[6])...)
+
+			responses = append(responses, ldapResult(ldapSearchDone, ldapSuccess))
+		case ldapUnbindRequest:
+			return
+		default:
+			responses = append(responses, ldapResult(op.Tag+1, ldapUnwillingToPerform))
+		}
+
+		for _, response := range responses {
+			message := ber.NewSequence("LDAP Response")
+			message.AppendChild(ber.NewInteger(ber.ClassUniversal, ber.TypePrimitive, ber.TagInteger, messageID, "Message ID"))
+			message.AppendChild(response)
+
+			if _, err := conn.Write(message.Bytes()); err != nil {
+				return
+			}
+		}
+	}
+}
+
+func (d *directory) bind(dn, password string) int64 {
+	if strings.EqualFold(dn, directoryBindDN) {
+		if password == directoryBindPass {
+			return ldapSuccess
+		}
+
+		return ldapInvalidCreds
+	}
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	entry, ok := d.entries[strings.ToLower(dn)]
+	if !ok || password == "" || entry.password != password {
+		return ldapInvalidCreds
+	}
+
+	return ldapSuccess
+}
+
+// search returns entries under the base DN matching the filter
+func (d *directory) search(baseDN string, filter *ber.Packet) []*ber.Packet {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	var results []*ber.Packet
+
+	for dn, entry := range d.entries {
+		if !strings.HasSuffix(dn, ","+strings.ToLower(baseDN)) || !entry.matches(filter) {
+			continue
+		}
+
+		attributes := ber.NewSequence("Attributes")
+		for name, values := range entry.attributes {
+			attribute := ber.NewSequence("Attribute")
+			attribute.AppendChild(ber.NewString(ber.ClassUniversal, ber.TypePrimitive, ber.TagOctetString, name, "Type"))
+
+			set := ber.Encode(ber.ClassUniversal, ber.TypeConstructed, ber.TagSet, nil, "Values")
+			for _, value := range values {
+				set.AppendChild(ber.NewString(ber.ClassUniversal, ber.TypePrimitive, ber.TagOctetString, value, "Value"))
+			}
+			attribute.AppendChild(set)
+
+			attributes.AppendChild(attribute)
+		}
+
+		result := ber.Encode(ber.ClassApplication, ber.TypeConstructed, ldapSearchEntry, nil, "Search Result Entry")
+		result.AppendChild(ber.NewString(ber.ClassUniversal, ber.TypePrimitive, ber.TagOctetString, entry.dn, "DN"))
+		result.AppendChild(attributes)
+
+		results = append(results, result)
+	}
+
+	return results
+}
+
+// matches reports whether the entry matches AND of equality and presence filters, values are case-insensitive
+func (e *directoryEntry) matches(filter *ber.Packet) bool {
+	switch filter.Tag {
+	case ldapFilterAnd:
+		for _, child := range filter.Children {
+			if !e.matches(child) {
+				return false
+			}
+		}
+
+		return true
+	case ldapFilterEquality:
+		name, _ := filter.Children[0].Value.(string)
+		value, _ := filter.Children[1].Value.(string)
+
+		for _, actual := range e.attribute(name) {
+			if strings.EqualFold(actual, value) {
+				return true
+			}
+		}
+	case ldapFilterPresent:
+		return len(e.attribute(filter.Data.String())) > 0
+	}
+
+	return false
+}
+
+func (e *directoryEntry) attribute(name string) []string {
+	for attribute, values := range e.attributes {
+		if strings.EqualFold(attribute, name) {
+			return values
+		}
+	}
+
+	return nil
+}
+
+func ldapResult(tag ber.Tag, code int64) *ber.Packet {
+	result := ber.Encode(ber.ClassApplication, ber.TypeConstructed, tag, nil, "Result")
+	result.AppendChild(ber.NewInteger(ber.ClassUniversal, ber.TypePrimitive, ber.TagEnumerated, code, "Result Code"))
+	result.AppendChild(ber.NewString(ber.ClassUniversal, ber.TypePrimitive, ber.TagOctetString, "", "Matched DN"))
+	result.AppendChild(ber.NewString(ber.ClassUniversal, ber.TypePrimitive, ber.TagOctetString, "", "Diagnostic Message"))
+
+	return result
+}
+
+func TestLDAP_LoginCreatesUser(t *testing.T) {
+	ctx, st := suite.NewSuite(t)
+
+	uid, email, password := gofakeit.Username()+gofakeit.UUID(), st.NewEmail(), st.NewPassword()
+	dn := mockDirectory.addUser(uid, email, password, directoryEditorsDN, "cn=others,ou=groups,dc=sso,dc=test")
+
+	token, userID := st.Login(ctx, email, password, suite.ClaimsAppID)
+
+	claims := parseClaims(t, token, suite.ClaimsAppSecret)
+	assert.Equal(t, email, claims["email"])
+	assert.Equal(t, []any{"editor"}, claims["roles"])
+	assert.Equal(t, "docs:read docs:write", claims["scope"])
+
+	// The entry signs in as the same user, roles follow the groups
+	mockDirectory.setGroups(dn, directoryViewersDN)
+
+	token, sameUserID := st.Login(ctx, email, password, suite.ClaimsAppID)
+	assert.Equal(t, userID, sameUserID)
+
+	claims = parseClaims(t, token, suite.ClaimsAppSecret)
+	assert.Equal(t, []any{"viewer"}, claims["roles"])
+	assert.Equal(t, "docs:read", claims["scope"])
+
+	mockDirectory.setGroups(dn)
+
+	token, _ = st.Login(ctx, email, password, suite.ClaimsAppID)
+	assert.Empty(t, parseClaims(t, token, suite.ClaimsAppSecret)["roles"])
+
+	_, err := st.AuthClient.Login(ctx, &ssov1.LoginRequest{Email: email, Password: password + "x", AppId: suite.AppID})
+	require.Error(t, err)
+	assert.Equal(t, codes.InvalidArgument, status.Code(err))
+}
+
+func TestLDAP_LinksExistingUser(t *testing.T) {
+	ctx, st := suite.NewSuite(t)
+
+	email, localPassword, directoryPassword := st.NewEmail(), st.NewPassword(), st.NewPassword()
+
+	registered, err := st.AuthClient.Register(ctx, &ssov1.RegisterRequest{Email: email, Password: localPassword})
+	require.NoError(t, err)
+
+	// User outside of the directory signs in by the database
+	_, userID := st.Login(ctx, email, localPassword, suite.AppID)
+	assert.Equal(t, registered.GetUserId(), userID)
+
+	mockDirectory.addUser(gofakeit.UUID(), email, directoryPassword)
+
+	// Directory is the authority on its users, password of the database isn't accepted anymore
+	_, err = st.AuthClient.Login(ctx, &ssov1.LoginRequest{Email: email, Password: localPassword, AppId: suite.AppID})
+	require.Error(t, err)
+	assert.Equal(t, codes.InvalidArgument, status.Code(err))
+
+	_, userID = st.Login(ctx, email, directoryPassword, suite.AppID)
+	assert.Equal(t, registered.GetUserId(), userID)
+}
@@ -0,0 +1,20 @@
+package tests
+
+import (
+	"os"
+	"testing"
+)
+
+// TestMain serves mock directory and providers of the identity chain while the tests run,
+// logins of all tests pass through them
+func TestMain(m *testing.M) {
+	if err := serveMockDirectory(); err != nil {
+		panic(err)
+	}
+
+	if err := serveMockProviders(); err != nil {
+		panic(err)
+	}
+
+	os.Exit(m.Run())
+}
@@ -0,0 +1,4 @@
+DELETE FROM role_scopes WHERE app_id IN (10, 11);
+DELETE FROM user_roles WHERE app_id IN (10, 11);
+DELETE FROM users WHERE email = 'roles@sso.test';
+DELETE FROM apps WHERE id IN (10, 11);
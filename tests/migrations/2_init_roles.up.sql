@@ -0,0 +1,24 @@
+INSERT INTO apps(id, name, secret, embed_roles, embed_scope, max_claims_size)
+VALUES (10, 'test-claims', 'test-claims-secret', TRUE, TRUE, 2048),
+       (11, 'test-claims-small', 'test-claims-small-secret', TRUE, TRUE, 16)
+ON CONFLICT DO NOTHING;
+
+-- Password of the user is roles-password
+INSERT INTO users(email, pass_hash)
+VALUES ('roles@sso.test', '$2a$10$QJk6UcUfNQHGPD/yR2qJWu0nyjB.FcfeYfLGT3aJshl3Zdn9iw04q')
+ON CONFLICT DO NOTHING;
+
+INSERT INTO user_roles(user_id, app_id, role)
+SELECT users.id, apps.id, roles.role
+FROM users, apps, (SELECT 'editor' AS role UNION SELECT 'viewer') AS roles
+WHERE users.email = 'roles@sso.test' AND apps.id IN (10, 11)
+ON CONFLICT DO NOTHING;
+
+INSERT INTO role_scopes(app_id, role, scope)
+VALUES (10, 'editor', 'docs:read'),
+       (10, 'editor', 'docs:write'),
+       (10, 'viewer', 'docs:read'),
+       (11, 'editor', 'docs:read'),
+       (11, 'editor', 'docs:write'),
+       (11, 'viewer', 'docs:read')
+ON CONFLICT DO NOTHING;
@@ -0,0 +1,4 @@
+DELETE FROM admins WHERE user_id IN (
+    SELECT id FROM users WHERE email IN ('super-admin@sso.test', 'app-admin@sso.test')
+);
+DELETE FROM users WHERE email IN ('super-admin@sso.test', 'app-admin@sso.test');
@@ -0,0 +1,11 @@
+-- Password of the users is admin-password
+INSERT INTO users(email, pass_hash)
+VALUES ('super-admin@sso.test', '$2a$10$hCKE/5WdUcR89ZxGBbBTSeKX3HvWve54HI6NEdEbG3HVlZjE5OrSy'),
+       ('app-admin@sso.test', '$2a$10$hCKE/5WdUcR89ZxGBbBTSeKX3HvWve54HI6NEdEbG3HVlZjE5OrSy')
+ON CONFLICT DO NOTHING;
+
+INSERT INTO admins(user_id, app_id)
+SELECT id, NULL FROM users WHERE email = 'super-admin@sso.test';
+
+INSERT INTO admins(user_id, app_id)
+SELECT id, 10 FROM users WHERE email = 'app-admin@sso.test';
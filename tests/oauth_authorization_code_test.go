@@ -0,0 +1,309 @@
+package tests
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/brianvoe/gofakeit/v6"
+	appsv1 "github.com/nhassl3/sso-app/contracts/generated/go/apps"
+	tokenv1 "github.com/nhassl3/sso-app/contracts/generated/go/token"
+	"github.com/nhassl3/sso-app/tests/suite"
+	ssov1 "github.com/nhassl3/sso-contracts/generated/go/sso"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+const oauthRedirectURI = "https://client.test/callback"
+
+func TestOAuth_AuthorizationCodePKCE(t *testing.T) {
+	ctx, st := suite.NewSuite(t)
+
+	appID := createOAuthApp(ctx, t, st, oauthRedirectURI)
+
+	email, password := st.NewEmail(), st.NewPassword()
+	respReg, err := st.AuthClient.Register(ctx, &ssov1.RegisterRequest{Email: email, Password: password})
+	require.NoError(t, err)
+
+	verifier := gofakeit.LetterN(64)
+
+	query := url.Values{
+		"response_type":         {"code"},
+		"client_id":             {strconv.Itoa(int(appID))},
+		"redirect_uri":          {oauthRedirectURI},
+		"state":                 {"xyz"},
+		"scope":                 {"docs:read"},
+		"code_challenge":        {pkceChallenge(verifier)},
+		"code_challenge_method": {"S256"},
+	}
+
+	// Credentials sent to the authorization endpoint are ignored, the user signs in on the login page
+	form := url.Values{"email": {email}, "password": {password}, "consent": {"approve"}}
+	for name, values := range query {
+		form[name] = values
+	}
+
+	resp, _ := postForm(t, st, "/authorize", form)
+	require.Equal(t, http.StatusFound, resp.StatusCode)
+	assert.True(t, strings.HasPrefix(resp.Header.Get("Location"), "/login?"), resp.Header.Get("Location"))
+
+	resp, _ = newBrowser(t, st).signIn(query, email, password)
+	require.Equal(t, http.StatusFound, resp.StatusCode)
+
+	location, err := url.Parse(resp.Header.Get("Location"))
+	require.NoError(t, err)
+	assert.Equal(t, oauthRedirectURI, location.Scheme+"://"+location.Host+location.Path)
+	assert.Equal(t, "xyz", location.Query().Get("state"))
+
+	code := location.Query().Get("code")
+	require.NotEmpty(t, code)
+
+	exchange := url.Values{
+		"grant_type":    {"authorization_code"},
+		"client_id":     {strconv.Itoa(int(appID))},
+		"code":          {code},
+		"redirect_uri":  {oauthRedirectURI},
+		"code_verifier": {verifier},
+	}
+
+	resp, body := postForm(t, st, "/token", exchange)
+	require.Equal(t, http.StatusOK, resp.StatusCode, body)
+	assert.Equal(t, "no-store", resp.Header.Get("Cache-Control"))
+	assert.Equal(t, "Bearer", body["token_type"])
+	assert.Equal(t, "docs:read", body["scope"])
+	assert.InDelta(t, st.Cfg.TokenTTL.Seconds(), body["expires_in"], 1)
+
+	respIntrospect, err := st.TokenClient.Introspect(st.WithToken(ctx, body["access_token"].(string)), &tokenv1.IntrospectRequest{Token: body["access_token"].(string)})
+	require.NoError(t, err)
+	assert.True(t, respIntrospect.GetActive())
+	assert.Equal(t, respReg.GetUserId(), respIntrospect.GetUserId())
+	assert.Equal(t, appID, respIntrospect.GetAppId())
+
+	// Code is used once
+	resp, body = postForm(t, st, "/token", exchange)
+	assert.Equal(t, http.StatusBadRequest, resp.StatusCode)
+	assert.Equal(t, "invalid_grant", body["error"])
+}
+
+func TestOAuth_AuthorizationCodeInvalidExchange(t *testing.T) {
+	ctx, st := suite.NewSuite(t)
+
+	appID := createOAuthApp(ctx, t, st, oauthRedirectURI)
+	otherAppID := createOAuthApp(ctx, t, st, oauthRedirectURI)
+
+	tests := []struct {
+		Name   string
+		Change func(exchange url.Values)
+		Error  string
+	}{
+		{Name: "Wrong verifier", Change: func(v url.Values) { v.Set("code_verifier", gofakeit.LetterN(64)) }, Error: "invalid_grant"},
+		{Name: "No verifier", Change: func(v url.Values) { v.Del("code_verifier") }, Error: "invalid_grant"},
+		{Name: "Other redirect URI", Change: func(v url.Values) { v.Set("redirect_uri", oauthRedirectURI+"/other") }, Error: "invalid_grant"},
+		{Name: "Other client", Change: func(v url.Values) { v.Set("client_id", strconv.Itoa(int(otherAppID))) }, Error: "invalid_grant"},
+		{Name: "Unknown grant type", Change: func(v url.Values) { v.Set("grant_type", "password") }, Error: "unsupported_grant_type"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.Name, func(t *testing.T) {
+			verifier := gofakeit.LetterN(64)
+
+			code := authorizeCode(t, st, appID, verifier)
+
+			exchange := url.Values{
+				"grant_type":    {"authorization_code"},
+				"client_id":     {strconv.Itoa(int(appID))},
+				"code":          {code},
+				"redirect_uri":  {oauthRedirectURI},
+				"code_verifier": {verifier},
+			}
+			tt.Change(exchange)
+
+			resp, body := postForm(t, st, "/token", exchange)
+			assert.Equal(t, http.StatusBadRequest, resp.StatusCode)
+			assert.Equal(t, tt.Error, body["error"])
+		})
+	}
+}
+
+func TestOAuth_AuthorizeInvalidRequests(t *testing.T) {
+	ctx, st := suite.NewSuite(t)
+
+	appID := createOAuthApp(ctx, t, st, oauthRedirectURI)
+
+	valid := func() url.Values {
+		return url.Values{
+			"response_type":         {"code"},
+			"client_id":             {strconv.Itoa(int(appID))},
+			"redirect_uri":          {oauthRedirectURI},
+			"state":                 {"xyz"},
+			"code_challenge":        {pkceChallenge(gofakeit.LetterN(64))},
+			"code_challenge_method": {"S256"},
+		}
+	}
+
+	// Errors before redirect URI is checked are shown to the user
+	shown := []struct {
+		Name   string
+		Change func(v url.Values)
+		Status int
+		Error  string
+	}{
+		{Name: "Unregistered redirect URI", Change: func(v url.Values) { v.Set("redirect_uri", "https://evil.test/") }, Status: 400, Error: "invalid_request"},
+		{Name: "Unknown client", Change: func(v url.Values) { v.Set("client_id", "100500") }, Status: 400, Error: "invalid_client"},
+	}
+
+	for _, tt := range shown {
+		t.Run(tt.Name, func(t *testing.T) {
+			form := valid()
+			tt.Change(form)
+
+			resp, body := postForm(t, st, "/authorize", form)
+			assert.Equal(t, tt.Status, resp.StatusCode)
+			assert.Empty(t, resp.Header.Get("Location"))
+			assert.Equal(t, tt.Error, body["error"])
+		})
+	}
+
+	// Other errors are sent to the client
+	redirected := []struct {
+		Name   string
+		Change func(v url.Values)
+		Error  string
+	}{
+		{Name: "Plain PKCE method", Change: func(v url.Values) { v.Set("code_challenge_method", "plain") }, Error: "invalid_request"},
+		{Name: "No code challenge", Change: func(v url.Values) { v.Del("code_challenge") }, Error: "invalid_request"},
+		{Name: "Token response type", Change: func(v url.Values) { v.Set("response_type", "token") }, Error: "unsupported_response_type"},
+	}
+
+	for _, tt := range redirected {
+		t.Run(tt.Name, func(t *testing.T) {
+			form := valid()
+			tt.Change(form)
+
+			resp, _ := postForm(t, st, "/authorize", form)
+			require.Equal(t, http.StatusFound, resp.StatusCode)
+
+			location, err := url.Parse(resp.Header.Get("Location"))
+			require.NoError(t, err)
+			assert.True(t, strings.HasPrefix(location.String(), oauthRedirectURI+"?"))
+			assert.Equal(t, tt.Error, location.Query().Get("error"))
+			assert.Equal(t, "xyz", location.Query().Get("state"))
+			assert.Empty(t, location.Query().Get("code"))
+		})
+	}
+
+	// Invalid password is asked again by the login page
+	resp, page := newBrowser(t, st).signIn(valid(), suite.RolesUserEmail, "wrong-password")
+	assert.Equal(t, http.StatusUnauthorized, resp.StatusCode)
+	assert.Contains(t, page, "Email or password is invalid")
+}
+
+func TestOAuth_InvalidRedirectURISetting(t *testing.T) {
+	ctx, st := suite.NewSuite(t)
+
+	superToken, _ := st.Login(ctx, suite.SuperAdminEmail, suite.AdminPassword, suite.AppID)
+
+	for _, redirectURI := range []string{"/callback", "https://client.test/callback#fragment", "client.test"} {
+		_, err := st.AppsClient.CreateApp(st.WithToken(ctx, superToken), &appsv1.CreateAppRequest{
+			Name:     "oauth-" + gofakeit.UUID(),
+			Settings: &appsv1.AppSettings{RedirectUris: []string{redirectURI}},
+		})
+		require.Error(t, err, redirectURI)
+		assert.Equal(t, codes.InvalidArgument, status.Code(err), redirectURI)
+	}
+}
+
+// createOAuthApp creates public client with the redirect URIs which is deleted after the test
+func createOAuthApp(ctx context.Context, t *testing.T, st *suite.Suite, redirectURIs ...string) int32 {
+	t.Helper()
+
+	app := createApp(ctx, t, st, &appsv1.AppSettings{RedirectUris: redirectURIs, PublicClient: true})
+	assert.Equal(t, redirectURIs, app.GetSettings().GetRedirectUris())
+
+	return app.GetId()
+}
+
+// createApp creates application with the settings which is deleted after the test
+func createApp(ctx context.Context, t *testing.T, st *suite.Suite, settings *appsv1.AppSettings) *appsv1.AppInfo {
+	t.Helper()
+
+	superToken, _ := st.Login(ctx, suite.SuperAdminEmail, suite.AdminPassword, suite.AppID)
+	adminCtx := st.WithToken(ctx, superToken)
+
+	resp, err := st.AppsClient.CreateApp(adminCtx, &appsv1.CreateAppRequest{
+		Name:     "oauth-" + gofakeit.UUID(),
+		Settings: settings,
+	})
+	require.NoError(t, err)
+
+	appID := resp.GetApp().GetId()
+
+	t.Cleanup(func() {
+		_, _ = st.AppsClient.DeleteApp(adminCtx, &appsv1.DeleteAppRequest{AppId: appID})
+	})
+
+	return resp.GetApp()
+}
+
+// authorizeCode returns authorization code of the roles user for the application with S256 challenge of the verifier
+func authorizeCode(t *testing.T, st *suite.Suite, appID int32, verifier string) string {
+	t.Helper()
+
+	resp, page := newBrowser(t, st).signIn(url.Values{
+		"response_type":         {"code"},
+		"client_id":             {strconv.Itoa(int(appID))},
+		"redirect_uri":          {oauthRedirectURI},
+		"code_challenge":        {pkceChallenge(verifier)},
+		"code_challenge_method": {"S256"},
+	}, suite.RolesUserEmail, suite.RolesUserPassword)
+	require.Equal(t, http.StatusFound, resp.StatusCode, page)
+
+	location, err := url.Parse(resp.Header.Get("Location"))
+	require.NoError(t, err)
+
+	code := location.Query().Get("code")
+	require.NotEmpty(t, code)
+
+	return code
+}
+
+// postForm posts the form to the HTTP server and returns response with decoded JSON body, if it has one
+func postForm(t *testing.T, st *suite.Suite, path string, form url.Values) (*http.Response, map[string]any) {
+	t.Helper()
+
+	req, err := http.NewRequest(http.MethodPost, st.HTTPURL(path), strings.NewReader(form.Encode()))
+	require.NoError(t, err)
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+
+	return doJSON(t, st, req)
+}
+
+// doJSON sends the request to the HTTP server and returns response with decoded JSON body, if it has one
+func doJSON(t *testing.T, st *suite.Suite, req *http.Request) (*http.Response, map[string]any) {
+	t.Helper()
+
+	resp, err := st.HTTPClient.Do(req)
+	require.NoError(t, err)
+	defer resp.Body.Close()
+
+	var body map[string]any
+	if strings.HasPrefix(resp.Header.Get("Content-Type"), "application/json") {
+		require.NoError(t, json.NewDecoder(resp.Body).Decode(&body))
+	}
+
+	return resp, body
+}
+
+func pkceChallenge(verifier string) string {
+	sum := sha256.Sum256([]byte(verifier))
+
+	return base64.RawURLEncoding.EncodeToString(sum[:])
+}
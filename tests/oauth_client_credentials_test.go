@@ -0,0 +1,97 @@
+package tests
+
+import (
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"testing"
+
+	appsv1 "github.com/nhassl3/sso-app/contracts/generated/go/apps"
+	tokenv1 "github.com/nhassl3/sso-app/contracts/generated/go/token"
+	"github.com/nhassl3/sso-app/tests/suite"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestOAuth_ClientCredentials(t *testing.T) {
+	ctx, st := suite.NewSuite(t)
+
+	superToken, _ := st.Login(ctx, suite.SuperAdminEmail, suite.AdminPassword, suite.AppID)
+	adminCtx := st.WithToken(ctx, superToken)
+
+	app := createApp(ctx, t, st, &appsv1.AppSettings{ClientScopes: []string{"reports:read", "reports:write"}})
+	clientID := strconv.Itoa(int(app.GetId()))
+	assert.Equal(t, []string{"reports:read", "reports:write"}, app.GetSettings().GetClientScopes())
+
+	// Client without secret can't get tokens
+	resp, body := clientTokenRequest(t, st, clientID, "any-secret", url.Values{})
+	assert.Equal(t, http.StatusUnauthorized, resp.StatusCode)
+	assert.Equal(t, "invalid_client", body["error"])
+
+	respRotate, err := st.AppsClient.RotateClientSecret(adminCtx, &appsv1.RotateClientSecretRequest{AppId: app.GetId()})
+	require.NoError(t, err)
+	secret := respRotate.GetClientSecret()
+	require.NotEmpty(t, secret)
+
+	resp, body = clientTokenRequest(t, st, clientID, secret, url.Values{})
+	require.Equal(t, http.StatusOK, resp.StatusCode, body)
+	assert.Equal(t, "reports:read reports:write", body["scope"])
+
+	respIntrospect, err := st.TokenClient.Introspect(st.WithToken(ctx, body["access_token"].(string)), &tokenv1.IntrospectRequest{Token: body["access_token"].(string)})
+	require.NoError(t, err)
+	assert.True(t, respIntrospect.GetActive())
+	assert.Zero(t, respIntrospect.GetUserId())
+	assert.Empty(t, respIntrospect.GetEmail())
+	assert.Equal(t, app.GetId(), respIntrospect.GetAppId())
+	assert.Equal(t, []string{"reports:read", "reports:write"}, respIntrospect.GetScopes())
+
+	// Credentials in the form instead of HTTP Basic
+	resp, body = postForm(t, st, "/token", url.Values{
+		"grant_type":    {"client_credentials"},
+		"client_id":     {clientID},
+		"client_secret": {secret},
+		"scope":         {"reports:read"},
+	})
+	require.Equal(t, http.StatusOK, resp.StatusCode, body)
+	assert.Equal(t, "reports:read", body["scope"])
+
+	resp, body = clientTokenRequest(t, st, clientID, secret, url.Values{"scope": {"reports:read admin"}})
+	assert.Equal(t, http.StatusBadRequest, resp.StatusCode)
+	assert.Equal(t, "invalid_scope", body["error"])
+
+	resp, body = clientTokenRequest(t, st, clientID, secret+"x", url.Values{})
+	assert.Equal(t, http.StatusUnauthorized, resp.StatusCode)
+	assert.Equal(t, "invalid_client", body["error"])
+
+	// Rotated secret replaces the previous one
+	_, err = st.AppsClient.RotateClientSecret(adminCtx, &appsv1.RotateClientSecretRequest{AppId: app.GetId()})
+	require.NoError(t, err)
+
+	resp, body = clientTokenRequest(t, st, clientID, secret, url.Values{})
+	assert.Equal(t, http.StatusUnauthorized, resp.StatusCode)
+	assert.Equal(t, "invalid_client", body["error"])
+}
+
+// clientTokenRequest requests token with HTTP Basic authentication of the client,
+// by the client credentials grant unless the form has another grant type
+func clientTokenRequest(
+	t *testing.T,
+	st *suite.Suite,
+	clientID string,
+	secret string,
+	form url.Values,
+) (*http.Response, map[string]any) {
+	t.Helper()
+
+	if !form.Has("grant_type") {
+		form.Set("grant_type", "client_credentials")
+	}
+
+	req, err := http.NewRequest(http.MethodPost, st.HTTPURL("/token"), strings.NewReader(form.Encode()))
+	require.NoError(t, err)
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.SetBasicAuth(url.QueryEscape(clientID), url.QueryEscape(secret))
+
+	return doJSON(t, st, req)
+}
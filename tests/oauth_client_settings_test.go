@@ -0,0 +1,191 @@
+package tests
+
+import (
+	"net/http"
+	"net/url"
+	"strconv"
+	"testing"
+
+	"github.com/brianvoe/gofakeit/v6"
+	appsv1 "github.com/nhassl3/sso-app/contracts/generated/go/apps"
+	tokenv1 "github.com/nhassl3/sso-app/contracts/generated/go/token"
+	"github.com/nhassl3/sso-app/tests/suite"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func TestApps_OAuthClientSettings(t *testing.T) {
+	ctx, st := suite.NewSuite(t)
+
+	settings := &appsv1.AppSettings{
+		MaxClaimsSize:          2048,
+		RedirectUris:           []string{oauthRedirectURI},
+		GrantTypes:             []string{"authorization_code", "refresh_token"},
+		AllowedScopes:          []string{"openid", "docs:read"},
+		AccessTokenTtlSeconds:  120,
+		RefreshTokenTtlSeconds: 3600,
+		PublicClient:           true,
+	}
+
+	app := createApp(ctx, t, st, settings)
+	assert.Equal(t, settings.GetGrantTypes(), app.GetSettings().GetGrantTypes())
+	assert.Equal(t, settings.GetAllowedScopes(), app.GetSettings().GetAllowedScopes())
+	assert.Equal(t, settings.GetAccessTokenTtlSeconds(), app.GetSettings().GetAccessTokenTtlSeconds())
+	assert.Equal(t, settings.GetRefreshTokenTtlSeconds(), app.GetSettings().GetRefreshTokenTtlSeconds())
+	assert.True(t, app.GetSettings().GetPublicClient())
+
+	superToken, _ := st.Login(ctx, suite.SuperAdminEmail, suite.AdminPassword, suite.AppID)
+	adminCtx := st.WithToken(ctx, superToken)
+
+	invalid := []*appsv1.AppSettings{
+		{GrantTypes: []string{"password"}},
+		{AllowedScopes: []string{"docs read"}},
+		{AccessTokenTtlSeconds: -1},
+		{RefreshTokenTtlSeconds: -1},
+	}
+
+	for _, settings := range invalid {
+		_, err := st.AppsClient.CreateApp(adminCtx, &appsv1.CreateAppRequest{
+			Name:     "oauth-" + gofakeit.UUID(),
+			Settings: settings,
+		})
+		require.Error(t, err, settings)
+		assert.Equal(t, codes.InvalidArgument, status.Code(err), settings)
+	}
+}
+
+func TestOAuth_RefreshTokenRotation(t *testing.T) {
+	ctx, st := suite.NewSuite(t)
+
+	app := createApp(ctx, t, st, &appsv1.AppSettings{
+		RedirectUris:           []string{oauthRedirectURI},
+		AccessTokenTtlSeconds:  120,
+		RefreshTokenTtlSeconds: 3600,
+		PublicClient:           true,
+	})
+	clientID := strconv.Itoa(int(app.GetId()))
+
+	tokens := oidcTokens(t, st, app.GetId(), "docs:read docs:write", "")
+	assert.InDelta(t, 120, tokens["expires_in"], 0)
+
+	refreshToken, ok := tokens["refresh_token"].(string)
+	require.True(t, ok)
+
+	refresh := func(clientID, refreshToken, scope string) (*http.Response, map[string]any) {
+		return postForm(t, st, "/token", url.Values{
+			"grant_type":    {"refresh_token"},
+			"client_id":     {clientID},
+			"refresh_token": {refreshToken},
+			"scope":         {scope},
+		})
+	}
+
+	resp, body := refresh(clientID, refreshToken, "docs:read")
+	require.Equal(t, http.StatusOK, resp.StatusCode, body)
+	assert.Equal(t, "docs:read", body["scope"])
+	assert.InDelta(t, 120, body["expires_in"], 0)
+
+	rotated := body["refresh_token"].(string)
+	assert.NotEqual(t, refreshToken, rotated)
+
+	respIntrospect, err := st.TokenClient.Introspect(st.WithToken(ctx, body["access_token"].(string)), &tokenv1.IntrospectRequest{Token: body["access_token"].(string)})
+	require.NoError(t, err)
+	assert.True(t, respIntrospect.GetActive())
+	assert.Equal(t, app.GetId(), respIntrospect.GetAppId())
+
+	// Refresh token is used once
+	resp, body = refresh(clientID, refreshToken, "")
+	assert.Equal(t, http.StatusBadRequest, resp.StatusCode)
+	assert.Equal(t, "invalid_grant", body["error"])
+
+	// Rotated token keeps the original scope
+	resp, body = refresh(clientID, rotated, "")
+	require.Equal(t, http.StatusOK, resp.StatusCode, body)
+	assert.Equal(t, "docs:read docs:write", body["scope"])
+
+	resp, body = refresh(clientID, body["refresh_token"].(string), "docs:delete")
+	assert.Equal(t, http.StatusBadRequest, resp.StatusCode)
+	assert.Equal(t, "invalid_scope", body["error"])
+
+	// Refresh token is issued to the client
+	tokens = oidcTokens(t, st, app.GetId(), "docs:read", "")
+	otherClientID := strconv.Itoa(int(createOAuthApp(ctx, t, st)))
+
+	_, body = refresh(otherClientID, tokens["refresh_token"].(string), "")
+	assert.Equal(t, "invalid_grant", body["error"])
+}
+
+func TestOAuth_ClientRestrictions(t *testing.T) {
+	ctx, st := suite.NewSuite(t)
+
+	app := createApp(ctx, t, st, &appsv1.AppSettings{
+		RedirectUris:           []string{oauthRedirectURI},
+		GrantTypes:             []string{"authorization_code"},
+		AllowedScopes:          []string{"docs:read"},
+		RefreshTokenTtlSeconds: 3600,
+		PublicClient:           true,
+	})
+	clientID := strconv.Itoa(int(app.GetId()))
+
+	// Refresh tokens aren't issued without the refresh token grant
+	tokens := oidcTokens(t, st, app.GetId(), "docs:read", "")
+	assert.NotContains(t, tokens, "refresh_token")
+	assert.InDelta(t, st.Cfg.TokenTTL.Seconds(), tokens["expires_in"], 1)
+
+	resp, _ := postForm(t, st, "/authorize", url.Values{
+		"response_type":         {"code"},
+		"client_id":             {clientID},
+		"redirect_uri":          {oauthRedirectURI},
+		"scope":                 {"docs:read docs:write"},
+		"code_challenge":        {pkceChallenge(gofakeit.LetterN(64))},
+		"code_challenge_method": {"S256"},
+	})
+	require.Equal(t, http.StatusFound, resp.StatusCode)
+
+	location, err := url.Parse(resp.Header.Get("Location"))
+	require.NoError(t, err)
+	assert.Equal(t, "invalid_scope", location.Query().Get("error"))
+	assert.Empty(t, location.Query().Get("code"))
+
+	resp, body := postForm(t, st, "/device_authorization", url.Values{"client_id": {clientID}})
+	assert.Equal(t, http.StatusBadRequest, resp.StatusCode)
+	assert.Equal(t, "unauthorized_client", body["error"])
+}
+
+func TestOAuth_ConfidentialClient(t *testing.T) {
+	ctx, st := suite.NewSuite(t)
+
+	superToken, _ := st.Login(ctx, suite.SuperAdminEmail, suite.AdminPassword, suite.AppID)
+
+	app := createApp(ctx, t, st, &appsv1.AppSettings{RedirectUris: []string{oauthRedirectURI}})
+	respRotate, err := st.AppsClient.RotateClientSecret(st.WithToken(ctx, superToken), &appsv1.RotateClientSecretRequest{
+		AppId: app.GetId(),
+	})
+	require.NoError(t, err)
+
+	clientID := strconv.Itoa(int(app.GetId()))
+	verifier := gofakeit.LetterN(64)
+
+	exchange := url.Values{
+		"grant_type":    {"authorization_code"},
+		"client_id":     {clientID},
+		"code":          {authorizeCode(t, st, app.GetId(), verifier)},
+		"redirect_uri":  {oauthRedirectURI},
+		"code_verifier": {verifier},
+	}
+
+	// Client is authenticated before the code is used
+	resp, body := postForm(t, st, "/token", exchange)
+	assert.Equal(t, http.StatusUnauthorized, resp.StatusCode)
+	assert.Equal(t, "invalid_client", body["error"])
+
+	resp, body = clientTokenRequest(t, st, clientID, respRotate.GetClientSecret()+"x", exchange)
+	assert.Equal(t, http.StatusUnauthorized, resp.StatusCode)
+	assert.Equal(t, "invalid_client", body["error"])
+
+	resp, body = clientTokenRequest(t, st, clientID, respRotate.GetClientSecret(), exchange)
+	require.Equal(t, http.StatusOK, resp.StatusCode, body)
+	assert.NotEmpty(t, body["access_token"])
+}
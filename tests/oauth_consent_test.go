@@ -0,0 +1,173 @@
+package tests
+
+import (
+	"net/http"
+	"net/url"
+	"strconv"
+	"testing"
+
+	"github.com/brianvoe/gofakeit/v6"
+	appsv1 "github.com/nhassl3/sso-app/contracts/generated/go/apps"
+	tokenv1 "github.com/nhassl3/sso-app/contracts/generated/go/token"
+	"github.com/nhassl3/sso-app/tests/suite"
+	ssov1 "github.com/nhassl3/sso-contracts/generated/go/sso"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func TestOAuth_Consent(t *testing.T) {
+	ctx, st := suite.NewSuite(t)
+
+	app := createApp(ctx, t, st, &appsv1.AppSettings{
+		RedirectUris:           []string{oauthRedirectURI},
+		RefreshTokenTtlSeconds: 3600,
+		PublicClient:           true,
+		ThirdParty:             true,
+	})
+	clientID := strconv.Itoa(int(app.GetId()))
+
+	// Consents of the shared users may be changed by the parallel tests
+	email, password := st.NewEmail(), st.NewPassword()
+	_, err := st.AuthClient.Register(ctx, &ssov1.RegisterRequest{Email: email, Password: password})
+	require.NoError(t, err)
+
+	userToken, _ := st.Login(ctx, email, password, suite.AppID)
+	userCtx := st.WithToken(ctx, userToken)
+
+	verifier := gofakeit.LetterN(64)
+
+	// User signs in on the login page and decides on the consent page if it is shown
+	authorize := func(scope, consent string) (*http.Response, url.Values) {
+		query := authorizeQuery(app.GetId(), verifier)
+		query.Set("scope", scope)
+
+		b := newBrowser(t, st)
+		resp, page := b.signIn(query, email, password)
+		if resp.StatusCode == http.StatusOK && consent != "" {
+			form := url.Values{"consent": {consent}}
+			for name, values := range query {
+				form[name] = values
+			}
+
+			resp, _ = b.submit(page, "/consent", form)
+		}
+
+		if resp.StatusCode != http.StatusFound {
+			return resp, url.Values{}
+		}
+
+		location, err := url.Parse(resp.Header.Get("Location"))
+		require.NoError(t, err)
+
+		return resp, location.Query()
+	}
+
+	// Consent page is shown to the user
+	resp, _ := authorize("docs:read", "")
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+
+	resp, query := authorize("docs:read", "deny")
+	require.Equal(t, http.StatusFound, resp.StatusCode)
+	assert.Equal(t, "access_denied", query.Get("error"))
+
+	resp, query = authorize("docs:read", "approve")
+	require.Equal(t, http.StatusFound, resp.StatusCode)
+	require.NotEmpty(t, query.Get("code"))
+
+	resp, body := postForm(t, st, "/token", url.Values{
+		"grant_type":    {"authorization_code"},
+		"client_id":     {clientID},
+		"code":          {query.Get("code")},
+		"redirect_uri":  {oauthRedirectURI},
+		"code_verifier": {verifier},
+	})
+	require.Equal(t, http.StatusOK, resp.StatusCode, body)
+
+	refreshToken := body["refresh_token"].(string)
+
+	// Consent covers the scope, so the user isn't asked again
+	_, query = authorize("docs:read", "")
+	assert.NotEmpty(t, query.Get("code"))
+
+	resp, _ = authorize("docs:read docs:write", "")
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+
+	_, query = authorize("docs:write", "approve")
+	assert.NotEmpty(t, query.Get("code"))
+
+	respList, err := st.TokenClient.ListConsents(userCtx, &tokenv1.ListConsentsRequest{})
+	require.NoError(t, err)
+	require.Len(t, respList.GetConsents(), 1)
+
+	consent := respList.GetConsents()[0]
+	assert.Equal(t, app.GetId(), consent.GetAppId())
+	assert.Equal(t, app.GetName(), consent.GetAppName())
+	assert.Equal(t, []string{"docs:read", "docs:write"}, consent.GetScopes())
+	assert.NotZero(t, consent.GetGrantedAt())
+
+	// Token of other application can't list or revoke consents of the user
+	appToken, _ := st.Login(ctx, email, password, suite.ClaimsAppID)
+	appCtx := st.WithToken(ctx, appToken)
+
+	_, err = st.TokenClient.ListConsents(appCtx, &tokenv1.ListConsentsRequest{})
+	require.Error(t, err)
+	assert.Equal(t, codes.PermissionDenied, status.Code(err))
+
+	_, err = st.TokenClient.RevokeConsent(appCtx, &tokenv1.RevokeConsentRequest{AppId: app.GetId()})
+	require.Error(t, err)
+	assert.Equal(t, codes.PermissionDenied, status.Code(err))
+
+	_, err = st.TokenClient.RevokeConsent(userCtx, &tokenv1.RevokeConsentRequest{AppId: app.GetId()})
+	require.NoError(t, err)
+
+	// Refresh tokens of the application are revoked with the consent
+	_, body = postForm(t, st, "/token", url.Values{
+		"grant_type":    {"refresh_token"},
+		"client_id":     {clientID},
+		"refresh_token": {refreshToken},
+	})
+	assert.Equal(t, "invalid_grant", body["error"])
+
+	respList, err = st.TokenClient.ListConsents(userCtx, &tokenv1.ListConsentsRequest{})
+	require.NoError(t, err)
+	assert.Empty(t, respList.GetConsents())
+
+	resp, _ = authorize("docs:read", "")
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+
+	_, err = st.TokenClient.RevokeConsent(userCtx, &tokenv1.RevokeConsentRequest{AppId: app.GetId()})
+	require.Error(t, err)
+	assert.Equal(t, codes.NotFound, status.Code(err))
+}
+
+func TestOAuth_ConsentByDeviceApproval(t *testing.T) {
+	ctx, st := suite.NewSuite(t)
+
+	app := createApp(ctx, t, st, &appsv1.AppSettings{PublicClient: true, ThirdParty: true})
+
+	email, password := st.NewEmail(), st.NewPassword()
+	_, err := st.AuthClient.Register(ctx, &ssov1.RegisterRequest{Email: email, Password: password})
+	require.NoError(t, err)
+
+	userToken, _ := st.Login(ctx, email, password, suite.AppID)
+	userCtx := st.WithToken(ctx, userToken)
+
+	_, device := postForm(t, st, "/device_authorization", url.Values{
+		"client_id": {strconv.Itoa(int(app.GetId()))},
+		"scope":     {"docs:read"},
+	})
+
+	_, err = st.TokenClient.ApproveDevice(userCtx, &tokenv1.ApproveDeviceRequest{UserCode: device["user_code"].(string)})
+	require.NoError(t, err)
+
+	respList, err := st.TokenClient.ListConsents(userCtx, &tokenv1.ListConsentsRequest{})
+	require.NoError(t, err)
+	require.Len(t, respList.GetConsents(), 1)
+	assert.Equal(t, []string{"docs:read"}, respList.GetConsents()[0].GetScopes())
+
+	_, err = st.TokenClient.ListConsents(ctx, &tokenv1.ListConsentsRequest{})
+	require.Error(t, err)
+	assert.Equal(t, codes.Unauthenticated, status.Code(err))
+}
@@ -0,0 +1,135 @@
+package tests
+
+import (
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	tokenv1 "github.com/nhassl3/sso-app/contracts/generated/go/token"
+	"github.com/nhassl3/sso-app/tests/suite"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func TestOAuth_DeviceAuthorization(t *testing.T) {
+	ctx, st := suite.NewSuite(t)
+
+	appID := createOAuthApp(ctx, t, st)
+	clientID := strconv.Itoa(int(appID))
+
+	resp, device := postForm(t, st, "/device_authorization", url.Values{"client_id": {clientID}, "scope": {"cli"}})
+	require.Equal(t, http.StatusOK, resp.StatusCode, device)
+	assert.Equal(t, st.Cfg.OAuth.DeviceVerificationURI, device["verification_uri"])
+	assert.Equal(t, st.Cfg.OAuth.DeviceVerificationURI+"?user_code="+device["user_code"].(string), device["verification_uri_complete"])
+	assert.InDelta(t, st.Cfg.OAuth.DeviceInterval.Seconds(), device["interval"], 0)
+	assert.InDelta(t, st.Cfg.OAuth.DeviceCodeTTL.Seconds(), device["expires_in"], 0)
+
+	userCode := device["user_code"].(string)
+	assert.Regexp(t, `^[A-Z]{4}-[A-Z]{4}$`, userCode)
+
+	poll := url.Values{
+		"grant_type":  {"urn:ietf:params:oauth:grant-type:device_code"},
+		"client_id":   {clientID},
+		"device_code": {device["device_code"].(string)},
+	}
+
+	resp, body := postForm(t, st, "/token", poll)
+	assert.Equal(t, http.StatusBadRequest, resp.StatusCode)
+	assert.Equal(t, "authorization_pending", body["error"])
+
+	// Device polls again without waiting for the interval
+	_, body = postForm(t, st, "/token", poll)
+	assert.Equal(t, "slow_down", body["error"])
+
+	token, userID := st.Login(ctx, suite.RolesUserEmail, suite.RolesUserPassword, suite.AppID)
+
+	// User code is typed by the user, so case and dashes don't matter
+	respApprove, err := st.TokenClient.ApproveDevice(st.WithToken(ctx, token), &tokenv1.ApproveDeviceRequest{
+		UserCode: strings.ToLower(strings.ReplaceAll(userCode, "-", "")),
+	})
+	require.NoError(t, err)
+	assert.Equal(t, appID, respApprove.GetAppId())
+	assert.Equal(t, "cli", respApprove.GetScope())
+
+	resp, body = postForm(t, st, "/token", poll)
+	require.Equal(t, http.StatusOK, resp.StatusCode, body)
+	assert.Equal(t, "cli", body["scope"])
+
+	respIntrospect, err := st.TokenClient.Introspect(st.WithToken(ctx, body["access_token"].(string)), &tokenv1.IntrospectRequest{Token: body["access_token"].(string)})
+	require.NoError(t, err)
+	assert.True(t, respIntrospect.GetActive())
+	assert.Equal(t, userID, respIntrospect.GetUserId())
+	assert.Equal(t, appID, respIntrospect.GetAppId())
+
+	// Device code is used once
+	_, body = postForm(t, st, "/token", poll)
+	assert.Equal(t, "invalid_grant", body["error"])
+
+	_, err = st.TokenClient.ApproveDevice(st.WithToken(ctx, token), &tokenv1.ApproveDeviceRequest{UserCode: userCode})
+	require.Error(t, err)
+	assert.Equal(t, codes.NotFound, status.Code(err))
+}
+
+func TestOAuth_DeviceDeniedAndExpired(t *testing.T) {
+	ctx, st := suite.NewSuite(t)
+
+	appID := createOAuthApp(ctx, t, st)
+	clientID := strconv.Itoa(int(appID))
+
+	token, _ := st.Login(ctx, suite.RolesUserEmail, suite.RolesUserPassword, suite.AppID)
+
+	_, denied := postForm(t, st, "/device_authorization", url.Values{"client_id": {clientID}})
+	_, expired := postForm(t, st, "/device_authorization", url.Values{"client_id": {clientID}})
+
+	_, err := st.TokenClient.DenyDevice(ctx, &tokenv1.DenyDeviceRequest{UserCode: denied["user_code"].(string)})
+	require.Error(t, err)
+	assert.Equal(t, codes.Unauthenticated, status.Code(err))
+
+	// Token of other application can't decide on the device of the user
+	appToken, _ := st.Login(ctx, suite.RolesUserEmail, suite.RolesUserPassword, suite.ClaimsAppID)
+
+	_, err = st.TokenClient.DenyDevice(st.WithToken(ctx, appToken), &tokenv1.DenyDeviceRequest{UserCode: denied["user_code"].(string)})
+	require.Error(t, err)
+	assert.Equal(t, codes.PermissionDenied, status.Code(err))
+
+	_, err = st.TokenClient.ApproveDevice(st.WithToken(ctx, appToken), &tokenv1.ApproveDeviceRequest{UserCode: expired["user_code"].(string)})
+	require.Error(t, err)
+	assert.Equal(t, codes.PermissionDenied, status.Code(err))
+
+	respDeny, err := st.TokenClient.DenyDevice(st.WithToken(ctx, token), &tokenv1.DenyDeviceRequest{
+		UserCode: denied["user_code"].(string),
+	})
+	require.NoError(t, err)
+	assert.Equal(t, appID, respDeny.GetAppId())
+
+	poll := func(device map[string]any, clientID string) (*http.Response, map[string]any) {
+		return postForm(t, st, "/token", url.Values{
+			"grant_type":  {"urn:ietf:params:oauth:grant-type:device_code"},
+			"client_id":   {clientID},
+			"device_code": {device["device_code"].(string)},
+		})
+	}
+
+	_, body := poll(denied, clientID)
+	assert.Equal(t, "access_denied", body["error"])
+
+	otherClientID := strconv.Itoa(int(createOAuthApp(ctx, t, st)))
+
+	_, body = poll(expired, otherClientID)
+	assert.Equal(t, "invalid_grant", body["error"])
+
+	time.Sleep(st.Cfg.OAuth.DeviceCodeTTL)
+
+	resp, body := poll(expired, clientID)
+	assert.Equal(t, http.StatusBadRequest, resp.StatusCode)
+	assert.Equal(t, "expired_token", body["error"])
+
+	resp, body = postForm(t, st, "/device_authorization", url.Values{"client_id": {"100500"}})
+	assert.Equal(t, http.StatusUnauthorized, resp.StatusCode)
+	assert.Equal(t, "invalid_client", body["error"])
+}
@@ -0,0 +1,194 @@
+package tests
+
+import (
+	"net/http"
+	"net/url"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	adminv1 "github.com/nhassl3/sso-app/contracts/generated/go/admin"
+	appsv1 "github.com/nhassl3/sso-app/contracts/generated/go/apps"
+	permissionsv1 "github.com/nhassl3/sso-app/contracts/generated/go/permissions"
+	tokenv1 "github.com/nhassl3/sso-app/contracts/generated/go/token"
+	"github.com/nhassl3/sso-app/tests/suite"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+const (
+	grantTypeTokenExchange = "urn:ietf:params:oauth:grant-type:token-exchange"
+	tokenTypeAccessToken   = "urn:ietf:params:oauth:token-type:access_token"
+)
+
+func TestTokenExchange_Impersonation(t *testing.T) {
+	ctx, st := suite.NewSuite(t)
+
+	adminToken, adminID := st.Login(ctx, suite.AppAdminEmail, suite.AdminPassword, suite.AppID)
+	_, rolesUserID := st.Login(ctx, suite.RolesUserEmail, suite.RolesUserPassword, suite.ClaimsAppID)
+
+	resp, body := postForm(t, st, "/token", impersonation(adminToken, rolesUserID, suite.ClaimsAppID))
+	require.Equal(t, http.StatusOK, resp.StatusCode, body)
+	assert.Equal(t, tokenTypeAccessToken, body["issued_token_type"])
+	assert.Equal(t, "Bearer", body["token_type"])
+	assert.InDelta(t, min(st.Cfg.OAuth.ImpersonationTTL, st.Cfg.TokenTTL).Seconds(), body["expires_in"], 1)
+
+	token := body["access_token"].(string)
+
+	claims := parseClaims(t, token, suite.ClaimsAppSecret)
+	assert.Equal(t, map[string]any{"sub": strconv.FormatInt(adminID, 10)}, claims["act"])
+
+	respIntrospect, err := st.TokenClient.Introspect(st.WithToken(ctx, token), &tokenv1.IntrospectRequest{Token: token})
+	require.NoError(t, err)
+	assert.True(t, respIntrospect.GetActive())
+	assert.Equal(t, rolesUserID, respIntrospect.GetUserId())
+	assert.Equal(t, adminID, respIntrospect.GetActorUserId())
+	assert.Equal(t, suite.ClaimsAppID, respIntrospect.GetAppId())
+	assert.Equal(t, []string{"editor", "viewer"}, respIntrospect.GetRoles())
+
+	respAudit, err := st.AdminClient.ListAuditEvents(st.WithToken(ctx, adminToken), &adminv1.ListAuditEventsRequest{
+		AppId: suite.ClaimsAppID,
+	})
+	require.NoError(t, err)
+
+	var recorded bool
+	for _, event := range respAudit.GetEvents() {
+		if event.GetAction() == "token.impersonate" && event.GetActorId() == adminID && event.GetTargetUserId() == rolesUserID {
+			recorded = true
+			break
+		}
+	}
+	assert.True(t, recorded)
+
+	// Impersonated user doesn't act in the permissions of the application
+	_, err = st.PermsClient.Check(st.WithToken(ctx, token), &permissionsv1.CheckRequest{
+		AppId:    suite.ClaimsAppID,
+		Object:   "doc:readme",
+		Relation: "viewer",
+		Subject:  "user:1",
+	})
+	require.Error(t, err)
+	assert.Equal(t, codes.PermissionDenied, status.Code(err))
+
+	// Impersonation token can't impersonate somebody else
+	resp, body = postForm(t, st, "/token", impersonation(token, adminID, suite.ClaimsAppID))
+	assert.Equal(t, http.StatusBadRequest, resp.StatusCode)
+	assert.Equal(t, "invalid_request", body["error"])
+}
+
+func TestTokenExchange_ImpersonationDenied(t *testing.T) {
+	ctx, st := suite.NewSuite(t)
+
+	adminToken, _ := st.Login(ctx, suite.AppAdminEmail, suite.AdminPassword, suite.AppID)
+	appAdminToken, _ := st.Login(ctx, suite.AppAdminEmail, suite.AdminPassword, suite.ClaimsAppID)
+	rolesToken, rolesUserID := st.Login(ctx, suite.RolesUserEmail, suite.RolesUserPassword, suite.AppID)
+	superToken, superAdminID := st.Login(ctx, suite.SuperAdminEmail, suite.AdminPassword, suite.AppID)
+
+	// Owner of the application signs tokens of any user for it by the secret of the application
+	forgedToken := signToken(t, jwt.MapClaims{
+		"uid": superAdminID, "app_id": suite.ClaimsAppID, "exp": time.Now().Add(time.Hour).Unix(),
+	}, suite.ClaimsAppSecret)
+
+	_, otherAdminID := registerAndLogin(ctx, t, st)
+	_, err := st.AdminClient.GrantAdmin(st.WithToken(ctx, superToken), &adminv1.GrantAdminRequest{
+		UserId: otherAdminID,
+		AppId:  suite.SmallClaimsAppID,
+	})
+	require.NoError(t, err)
+
+	tests := []struct {
+		Name  string
+		Form  url.Values
+		Error string
+	}{
+		{Name: "Not admin", Form: impersonation(rolesToken, superAdminID, suite.ClaimsAppID), Error: "unauthorized_client"},
+		{Name: "Admin of other app", Form: impersonation(adminToken, rolesUserID, suite.SmallClaimsAppID), Error: "unauthorized_client"},
+		{Name: "Super-admin", Form: impersonation(adminToken, superAdminID, suite.ClaimsAppID), Error: "unauthorized_client"},
+		{Name: "Admin of other app as target", Form: impersonation(adminToken, otherAdminID, suite.ClaimsAppID), Error: "unauthorized_client"},
+		{Name: "Token of other app", Form: impersonation(appAdminToken, rolesUserID, suite.ClaimsAppID), Error: "unauthorized_client"},
+		{Name: "Forged token of other app", Form: impersonation(forgedToken, rolesUserID, suite.SmallClaimsAppID), Error: "unauthorized_client"},
+		{Name: "Unknown user", Form: impersonation(adminToken, 100500100, suite.ClaimsAppID), Error: "invalid_request"},
+		{Name: "Unknown audience", Form: impersonation(adminToken, rolesUserID, 100500), Error: "invalid_target"},
+		{Name: "Invalid subject token", Form: impersonation("not-a-token", rolesUserID, suite.ClaimsAppID), Error: "invalid_request"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.Name, func(t *testing.T) {
+			resp, body := postForm(t, st, "/token", tt.Form)
+			assert.Equal(t, http.StatusBadRequest, resp.StatusCode)
+			assert.Equal(t, tt.Error, body["error"])
+		})
+	}
+}
+
+func TestTokenExchange_Downscoping(t *testing.T) {
+	ctx, st := suite.NewSuite(t)
+
+	superToken, _ := st.Login(ctx, suite.SuperAdminEmail, suite.AdminPassword, suite.AppID)
+	adminCtx := st.WithToken(ctx, superToken)
+
+	service := createApp(ctx, t, st, &appsv1.AppSettings{})
+	respRotate, err := st.AppsClient.RotateClientSecret(adminCtx, &appsv1.RotateClientSecretRequest{AppId: service.GetId()})
+	require.NoError(t, err)
+
+	other := createApp(ctx, t, st, &appsv1.AppSettings{})
+	respOther, err := st.AppsClient.RotateClientSecret(adminCtx, &appsv1.RotateClientSecretRequest{AppId: other.GetId()})
+	require.NoError(t, err)
+
+	userToken, rolesUserID := st.Login(ctx, suite.RolesUserEmail, suite.RolesUserPassword, service.GetId())
+	serviceID := strconv.Itoa(int(service.GetId()))
+
+	exchange := func(scope string) url.Values {
+		return url.Values{
+			"grant_type":         {grantTypeTokenExchange},
+			"subject_token":      {userToken},
+			"subject_token_type": {tokenTypeAccessToken},
+			"audience":           {strconv.Itoa(int(suite.ClaimsAppID))},
+			"scope":              {scope},
+		}
+	}
+
+	resp, body := clientTokenRequest(t, st, serviceID, respRotate.GetClientSecret(), exchange("docs:read"))
+	require.Equal(t, http.StatusOK, resp.StatusCode, body)
+	assert.Equal(t, "docs:read", body["scope"])
+
+	token := body["access_token"].(string)
+
+	claims := parseClaims(t, token, suite.ClaimsAppSecret)
+	assert.Equal(t, "docs:read", claims["scope"])
+	assert.NotContains(t, claims, "act")
+
+	respIntrospect, err := st.TokenClient.Introspect(st.WithToken(ctx, token), &tokenv1.IntrospectRequest{Token: token})
+	require.NoError(t, err)
+	assert.Equal(t, rolesUserID, respIntrospect.GetUserId())
+	assert.Equal(t, suite.ClaimsAppID, respIntrospect.GetAppId())
+	assert.Equal(t, []string{"docs:read"}, respIntrospect.GetScopes())
+	assert.Zero(t, respIntrospect.GetActorUserId())
+
+	resp, body = clientTokenRequest(t, st, serviceID, respRotate.GetClientSecret(), exchange("docs:delete"))
+	assert.Equal(t, http.StatusBadRequest, resp.StatusCode)
+	assert.Equal(t, "invalid_scope", body["error"])
+
+	// Token of the user is exchanged only by the client it was issued to
+	resp, body = clientTokenRequest(t, st, strconv.Itoa(int(other.GetId())), respOther.GetClientSecret(), exchange("docs:read"))
+	assert.Equal(t, http.StatusBadRequest, resp.StatusCode)
+	assert.Equal(t, "invalid_request", body["error"])
+
+	resp, body = postForm(t, st, "/token", exchange("docs:read"))
+	assert.Equal(t, http.StatusUnauthorized, resp.StatusCode)
+	assert.Equal(t, "invalid_client", body["error"])
+}
+
+// impersonation returns form of the token exchange which impersonates the user by the subject token of the admin
+func impersonation(subjectToken string, userID int64, audience int32) url.Values {
+	return url.Values{
+		"grant_type":         {grantTypeTokenExchange},
+		"subject_token":      {subjectToken},
+		"subject_token_type": {tokenTypeAccessToken},
+		"requested_subject":  {strconv.FormatInt(userID, 10)},
+		"audience":           {strconv.Itoa(int(audience))},
+	}
+}
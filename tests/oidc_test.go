@@ -0,0 +1,191 @@
+package tests
+
+import (
+	"crypto/rsa"
+	"encoding/base64"
+	"math/big"
+	"net/http"
+	"net/url"
+	"strconv"
+	"testing"
+
+	"github.com/brianvoe/gofakeit/v6"
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/nhassl3/sso-app/tests/suite"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestOIDC_Discovery(t *testing.T) {
+	_, st := suite.NewSuite(t)
+
+	resp, body := getJSON(t, st, "/.well-known/openid-configuration", "")
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+
+	issuer := st.Cfg.OAuth.Issuer
+	assert.Equal(t, issuer, body["issuer"])
+	assert.Equal(t, issuer+"/authorize", body["authorization_endpoint"])
+	assert.Equal(t, issuer+"/token", body["token_endpoint"])
+	assert.Equal(t, issuer+"/userinfo", body["userinfo_endpoint"])
+	assert.Equal(t, issuer+"/.well-known/jwks.json", body["jwks_uri"])
+	assert.Contains(t, body["scopes_supported"], "openid")
+	assert.Equal(t, []any{"RS256"}, body["id_token_signing_alg_values_supported"])
+	assert.Equal(t, []any{"S256"}, body["code_challenge_methods_supported"])
+
+	resp, body = getJSON(t, st, "/.well-known/jwks.json", "")
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+
+	keys, ok := body["keys"].([]any)
+	require.True(t, ok)
+	require.Len(t, keys, 1)
+
+	key := keys[0].(map[string]any)
+	assert.Equal(t, "RSA", key["kty"])
+	assert.Equal(t, "sig", key["use"])
+	assert.Equal(t, "RS256", key["alg"])
+	assert.NotEmpty(t, key["kid"])
+}
+
+func TestOIDC_IDTokenAndUserInfo(t *testing.T) {
+	ctx, st := suite.NewSuite(t)
+
+	appID := createOAuthApp(ctx, t, st, oauthRedirectURI)
+	nonce := gofakeit.UUID()
+
+	tokens := oidcTokens(t, st, appID, "openid email", nonce)
+
+	rawIDToken, ok := tokens["id_token"].(string)
+	require.True(t, ok)
+
+	claims := jwt.MapClaims{}
+	idToken, err := jwt.ParseWithClaims(rawIDToken, claims, publicKeyFunc(t, st),
+		jwt.WithValidMethods([]string{"RS256"}),
+		jwt.WithIssuer(st.Cfg.OAuth.Issuer),
+		jwt.WithAudience(strconv.Itoa(int(appID))),
+	)
+	require.NoError(t, err)
+	require.True(t, idToken.Valid)
+
+	_, rolesUserID := st.Login(ctx, suite.RolesUserEmail, suite.RolesUserPassword, suite.AppID)
+	assert.Equal(t, strconv.FormatInt(rolesUserID, 10), claims["sub"])
+	assert.Equal(t, nonce, claims["nonce"])
+	assert.Equal(t, suite.RolesUserEmail, claims["email"])
+	assert.NotZero(t, claims["auth_time"])
+	assert.NotContains(t, claims, "preferred_username")
+
+	resp, body := getJSON(t, st, "/userinfo", tokens["access_token"].(string))
+	require.Equal(t, http.StatusOK, resp.StatusCode, body)
+	assert.Equal(t, claims["sub"], body["sub"])
+	assert.Equal(t, suite.RolesUserEmail, body["email"])
+
+	// Without email scope the claims tell only the subject
+	tokens = oidcTokens(t, st, appID, "openid", "")
+
+	claims = jwt.MapClaims{}
+	_, err = jwt.ParseWithClaims(tokens["id_token"].(string), claims, publicKeyFunc(t, st))
+	require.NoError(t, err)
+	assert.NotContains(t, claims, "email")
+	assert.NotContains(t, claims, "nonce")
+
+	resp, body = getJSON(t, st, "/userinfo", tokens["access_token"].(string))
+	require.Equal(t, http.StatusOK, resp.StatusCode, body)
+	assert.NotContains(t, body, "email")
+}
+
+func TestOIDC_UserInfoInvalidTokens(t *testing.T) {
+	ctx, st := suite.NewSuite(t)
+
+	appID := createOAuthApp(ctx, t, st, oauthRedirectURI)
+
+	// OAuth token without openid scope has no ID token and can't read user info
+	tokens := oidcTokens(t, st, appID, "docs:read", "")
+	assert.NotContains(t, tokens, "id_token")
+
+	resp, body := getJSON(t, st, "/userinfo", tokens["access_token"].(string))
+	assert.Equal(t, http.StatusForbidden, resp.StatusCode)
+	assert.Equal(t, "insufficient_scope", body["error"])
+
+	resp, body = getJSON(t, st, "/userinfo", "not-a-token")
+	assert.Equal(t, http.StatusUnauthorized, resp.StatusCode)
+	assert.Equal(t, "invalid_token", body["error"])
+	assert.Contains(t, resp.Header.Get("WWW-Authenticate"), `error="invalid_token"`)
+
+	resp, _ = getJSON(t, st, "/userinfo", "")
+	assert.Equal(t, http.StatusUnauthorized, resp.StatusCode)
+}
+
+// oidcTokens runs the authorization code flow of the roles user with the scope and nonce and returns token response
+func oidcTokens(t *testing.T, st *suite.Suite, appID int32, scope, nonce string) map[string]any {
+	t.Helper()
+
+	verifier := gofakeit.LetterN(64)
+
+	resp, page := newBrowser(t, st).signIn(url.Values{
+		"response_type":         {"code"},
+		"client_id":             {strconv.Itoa(int(appID))},
+		"redirect_uri":          {oauthRedirectURI},
+		"scope":                 {scope},
+		"nonce":                 {nonce},
+		"code_challenge":        {pkceChallenge(verifier)},
+		"code_challenge_method": {"S256"},
+	}, suite.RolesUserEmail, suite.RolesUserPassword)
+	require.Equal(t, http.StatusFound, resp.StatusCode, page)
+
+	location, err := url.Parse(resp.Header.Get("Location"))
+	require.NoError(t, err)
+
+	resp, body := postForm(t, st, "/token", url.Values{
+		"grant_type":    {"authorization_code"},
+		"client_id":     {strconv.Itoa(int(appID))},
+		"code":          {location.Query().Get("code")},
+		"redirect_uri":  {oauthRedirectURI},
+		"code_verifier": {verifier},
+	})
+	require.Equal(t, http.StatusOK, resp.StatusCode, body)
+
+	return body
+}
+
+// publicKeyFunc returns key function which finds public key of the token in JWKS of the server
+func publicKeyFunc(t *testing.T, st *suite.Suite) jwt.Keyfunc {
+	t.Helper()
+
+	_, body := getJSON(t, st, "/.well-known/jwks.json", "")
+
+	return func(token *jwt.Token) (any, error) {
+		for _, raw := range body["keys"].([]any) {
+			key := raw.(map[string]any)
+			if key["kid"] != token.Header["kid"] {
+				continue
+			}
+
+			n, err := base64.RawURLEncoding.DecodeString(key["n"].(string))
+			if err != nil {
+				return nil, err
+			}
+
+			e, err := base64.RawURLEncoding.DecodeString(key["e"].(string))
+			if err != nil {
+				return nil, err
+			}
+
+			return &rsa.PublicKey{N: new(big.Int).SetBytes(n), E: int(new(big.Int).SetBytes(e).Int64())}, nil
+		}
+
+		return nil, jwt.ErrTokenUnverifiable
+	}
+}
+
+// getJSON gets the path of the HTTP server with the bearer token, if it is given
+func getJSON(t *testing.T, st *suite.Suite, path, bearer string) (*http.Response, map[string]any) {
+	t.Helper()
+
+	req, err := http.NewRequest(http.MethodGet, st.HTTPURL(path), nil)
+	require.NoError(t, err)
+
+	if bearer != "" {
+		req.Header.Set("Authorization", "Bearer "+bearer)
+	}
+
+	return doJSON(t, st, req)
+}
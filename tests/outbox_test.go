@@ -0,0 +1,76 @@
+package tests
+
+import (
+	"net/http"
+	"strconv"
+	"testing"
+
+	webhooksv1 "github.com/nhassl3/sso-app/contracts/generated/go/webhooks"
+	"github.com/nhassl3/sso-app/tests/suite"
+	ssov1 "github.com/nhassl3/sso-contracts/generated/go/sso"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestOutbox_OnlyCommittedChangesPublished(t *testing.T) {
+	ctx, st := suite.NewSuite(t)
+
+	receiver := newWebhookReceiver(t, 0)
+	appID, token := scimClient(ctx, t, st)
+
+	_, err := st.WebhooksClient.CreateSubscription(superAdminCtx(ctx, st), &webhooksv1.CreateSubscriptionRequest{
+		AppId:    appID,
+		Url:      receiver.URL,
+		AllUsers: true,
+	})
+	require.NoError(t, err)
+
+	email := st.NewEmail()
+
+	resp, body := scimRequest(t, st, token, http.MethodPost, "/Users", map[string]any{
+		"userName": email,
+		"password": st.NewPassword(),
+	}, nil)
+	require.Equal(t, http.StatusCreated, resp.StatusCode, body)
+	userID, err := strconv.ParseInt(body["id"].(string), 10, 64)
+	require.NoError(t, err)
+
+	// Failed writes leave nothing in the outbox
+	resp, _ = scimRequest(t, st, token, http.MethodPost, "/Users", map[string]any{"userName": email}, nil)
+	assert.Equal(t, http.StatusConflict, resp.StatusCode)
+
+	_, err = st.AuthClient.Register(ctx, &ssov1.RegisterRequest{Email: email, Password: st.NewPassword()})
+	require.Error(t, err)
+
+	changePassword := map[string]any{
+		"Operations": []map[string]any{{"op": "replace", "path": "password", "value": st.NewPassword()}},
+	}
+
+	resp, _ = scimRequest(t, st, token, http.MethodPatch, "/Users/"+body["id"].(string), changePassword, map[string]string{
+		"If-Match": `W/"7"`,
+	})
+	assert.Equal(t, http.StatusPreconditionFailed, resp.StatusCode)
+
+	resp, _ = scimRequest(t, st, token, http.MethodPatch, "/Users/"+body["id"].(string), changePassword, map[string]string{
+		"If-Match": `W/"1"`,
+	})
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+
+	resp, _ = scimRequest(t, st, token, http.MethodDelete, "/Users/"+body["id"].(string), nil, nil)
+	require.Equal(t, http.StatusNoContent, resp.StatusCode)
+
+	// Events are relayed in the order of the changes, so the earlier ones are already received
+	deleted := receiver.Event(t, "user.deleted", userID, 1)
+	require.Len(t, deleted, 1)
+	assert.Equal(t, email, deleted[0].event.Data.Email, "deleted user keeps the email in the event")
+
+	registered := receiver.Event(t, "user.registered", userID, 1)
+	require.Len(t, registered, 1)
+	assert.Equal(t, email, registered[0].event.Data.Email)
+
+	changed := receiver.Event(t, "user.password_changed", userID, 1)
+	require.Len(t, changed, 1)
+
+	assert.NotEqual(t, registered[0].id, changed[0].id)
+	assert.NotEqual(t, changed[0].id, deleted[0].id)
+}
@@ -0,0 +1,259 @@
+package tests
+
+import (
+	"encoding/json"
+	"strconv"
+	"testing"
+
+	appsv1 "github.com/nhassl3/sso-app/contracts/generated/go/apps"
+	permissionsv1 "github.com/nhassl3/sso-app/contracts/generated/go/permissions"
+	"github.com/nhassl3/sso-app/tests/suite"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+type policyRule struct {
+	Name      string `json:"name"`
+	Effect    string `json:"effect"`
+	Condition string `json:"condition"`
+}
+
+var abacRules = []policyRule{
+	{Name: "blocked", Effect: "deny", Condition: "user.attributes.blocked == true"},
+	{Name: "owner", Effect: "allow", Condition: "resource.owner == user.id"},
+	{Name: "clearance", Effect: "allow", Condition: "user.attributes.clearance >= resource.level"},
+	{
+		Name:   "editors-office-hours",
+		Effect: "allow",
+		Condition: `"editor" in user.roles && hour(request.time) >= 9 && hour(request.time) < 18 &&
+			inCidr(request.ip, "10.0.0.0/8")`,
+	},
+}
+
+// ABAC policies are written to the SmallClaimsAppID only by this test, so recorded decisions of the app are known
+func TestPermissions_ABAC(t *testing.T) {
+	ctx, st := suite.NewSuite(t)
+
+	superToken, _ := st.Login(ctx, suite.SuperAdminEmail, suite.AdminPassword, suite.AppID)
+	adminCtx := st.WithToken(ctx, superToken)
+
+	_, rolesUserID := st.Login(ctx, suite.RolesUserEmail, suite.RolesUserPassword, suite.SmallClaimsAppID)
+
+	respWrite, err := st.PermsClient.WritePolicy(adminCtx, &permissionsv1.WritePolicyRequest{
+		AppId:  suite.SmallClaimsAppID,
+		Policy: policyJSON(t, abacRules),
+	})
+	require.NoError(t, err)
+
+	respRead, err := st.PermsClient.ReadPolicy(adminCtx, &permissionsv1.ReadPolicyRequest{AppId: suite.SmallClaimsAppID})
+	require.NoError(t, err)
+	assert.Equal(t, respWrite.GetVersion(), respRead.GetVersion())
+	assert.Contains(t, respRead.GetPolicy(), "editors-office-hours")
+
+	tests := []struct {
+		Name           string
+		UserAttributes string
+		Resource       string
+		Time           string
+		Effect         string
+		Rule           string
+		RulesEvaluated int
+	}{
+		{
+			Name:           "Editor in office hours",
+			Resource:       `{"owner": 0}`,
+			Time:           "2026-03-02T10:00:00Z",
+			Effect:         "allow",
+			Rule:           "editors-office-hours",
+			RulesEvaluated: 4,
+		},
+		{
+			Name:           "Editor after office hours",
+			Time:           "2026-03-02T20:00:00Z",
+			Effect:         "deny",
+			RulesEvaluated: 4,
+		},
+		{
+			Name:           "Blocked user",
+			UserAttributes: `{"blocked": true}`,
+			Resource:       `{"owner": ` + strconv.FormatInt(rolesUserID, 10) + `}`,
+			Time:           "2026-03-02T10:00:00Z",
+			Effect:         "deny",
+			Rule:           "blocked",
+			RulesEvaluated: 1,
+		},
+		{
+			Name:           "Owner",
+			Resource:       `{"owner": ` + strconv.FormatInt(rolesUserID, 10) + `}`,
+			Time:           "2026-03-02T20:00:00Z",
+			Effect:         "allow",
+			Rule:           "owner",
+			RulesEvaluated: 2,
+		},
+	}
+
+	decisionIDs := make([]int64, 0, len(tests))
+	for _, tt := range tests {
+		resp, err := st.PermsClient.Evaluate(adminCtx, &permissionsv1.EvaluateRequest{
+			AppId:          suite.SmallClaimsAppID,
+			UserId:         rolesUserID,
+			UserAttributes: tt.UserAttributes,
+			Resource:       tt.Resource,
+			Ip:             "10.1.2.3",
+			Time:           tt.Time,
+		})
+		require.NoError(t, err, tt.Name)
+		assert.Equal(t, tt.Effect, resp.GetEffect(), tt.Name)
+		assert.Equal(t, tt.Effect == "allow", resp.GetAllowed(), tt.Name)
+		assert.Equal(t, tt.Rule, resp.GetMatchedRule(), tt.Name)
+		assert.Len(t, resp.GetRules(), tt.RulesEvaluated, tt.Name)
+		assert.Equal(t, respWrite.GetVersion(), resp.GetPolicyVersion(), tt.Name)
+
+		if tt.RulesEvaluated > 2 {
+			// Absent clearance and level can't be compared, the rule doesn't match and explains why
+			assert.False(t, resp.GetRules()[2].GetMatched(), tt.Name)
+			assert.NotEmpty(t, resp.GetRules()[2].GetError(), tt.Name)
+		}
+
+		decisionIDs = append(decisionIDs, resp.GetDecisionId())
+	}
+
+	// Without office hours the evening request of the editor becomes allowed, other decisions stay the same
+	relaxed := append([]policyRule{}, abacRules[:3]...)
+	relaxed = append(relaxed, policyRule{Name: "editors", Effect: "allow", Condition: `"editor" in user.roles`})
+
+	respDryRun, err := st.PermsClient.DryRunPolicy(adminCtx, &permissionsv1.DryRunPolicyRequest{
+		AppId:  suite.SmallClaimsAppID,
+		Policy: policyJSON(t, relaxed),
+		Limit:  int32(len(tests)),
+	})
+	require.NoError(t, err)
+	require.Len(t, respDryRun.GetResults(), len(tests))
+	assert.EqualValues(t, 1, respDryRun.GetChanged())
+
+	for _, result := range respDryRun.GetResults() {
+		assert.Contains(t, decisionIDs, result.GetDecisionId())
+
+		if result.GetChanged() {
+			assert.Equal(t, decisionIDs[1], result.GetDecisionId())
+			assert.Equal(t, "deny", result.GetRecordedEffect())
+			assert.Equal(t, "allow", result.GetEffect())
+			assert.Equal(t, "editors", result.GetRule())
+		}
+	}
+
+	// Dry-run doesn't change the policy
+	respRead, err = st.PermsClient.ReadPolicy(adminCtx, &permissionsv1.ReadPolicyRequest{AppId: suite.SmallClaimsAppID})
+	require.NoError(t, err)
+	assert.Equal(t, respWrite.GetVersion(), respRead.GetVersion())
+}
+
+func TestPermissions_ABACDecisionsKept(t *testing.T) {
+	ctx, st := suite.NewSuite(t)
+
+	superToken, _ := st.Login(ctx, suite.SuperAdminEmail, suite.AdminPassword, suite.AppID)
+	adminCtx := st.WithToken(ctx, superToken)
+
+	_, rolesUserID := st.Login(ctx, suite.RolesUserEmail, suite.RolesUserPassword, suite.SmallClaimsAppID)
+
+	app := createApp(ctx, t, st, &appsv1.AppSettings{})
+
+	_, err := st.PermsClient.WritePolicy(adminCtx, &permissionsv1.WritePolicyRequest{
+		AppId:  app.GetId(),
+		Policy: policyJSON(t, abacRules),
+	})
+	require.NoError(t, err)
+
+	kept := st.Cfg.Permissions.DecisionsKept
+
+	var decisionIDs []int64
+	for i := 0; i < kept+2; i++ {
+		resp, err := st.PermsClient.Evaluate(adminCtx, &permissionsv1.EvaluateRequest{
+			AppId:  app.GetId(),
+			UserId: rolesUserID,
+			Time:   "2026-03-02T10:00:00Z",
+		})
+		require.NoError(t, err)
+
+		decisionIDs = append(decisionIDs, resp.GetDecisionId())
+	}
+
+	// Only the latest decisions are kept
+	respDryRun, err := st.PermsClient.DryRunPolicy(adminCtx, &permissionsv1.DryRunPolicyRequest{
+		AppId:  app.GetId(),
+		Policy: policyJSON(t, abacRules),
+		Limit:  int32(len(decisionIDs)),
+	})
+	require.NoError(t, err)
+	require.Len(t, respDryRun.GetResults(), kept)
+
+	for _, result := range respDryRun.GetResults() {
+		assert.Contains(t, decisionIDs[len(decisionIDs)-kept:], result.GetDecisionId())
+	}
+}
+
+func TestPermissions_ABACInvalidRequests(t *testing.T) {
+	ctx, st := suite.NewSuite(t)
+
+	superToken, _ := st.Login(ctx, suite.SuperAdminEmail, suite.AdminPassword, suite.AppID)
+	adminCtx := st.WithToken(ctx, superToken)
+
+	tests := []struct {
+		Name  string
+		Rules []policyRule
+	}{
+		{Name: "Syntax error", Rules: []policyRule{{Name: "r", Effect: "allow", Condition: "user.id == "}}},
+		{Name: "Unknown function", Rules: []policyRule{{Name: "r", Effect: "allow", Condition: "now() > 1"}}},
+		{Name: "Unknown effect", Rules: []policyRule{{Name: "r", Effect: "maybe", Condition: "true"}}},
+		{Name: "Duplicate name", Rules: []policyRule{
+			{Name: "r", Effect: "allow", Condition: "true"},
+			{Name: "r", Effect: "deny", Condition: "false"},
+		}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.Name, func(t *testing.T) {
+			_, err := st.PermsClient.DryRunPolicy(adminCtx, &permissionsv1.DryRunPolicyRequest{
+				AppId:  suite.SmallClaimsAppID,
+				Policy: policyJSON(t, tt.Rules),
+			})
+			require.Error(t, err)
+			assert.Equal(t, codes.InvalidArgument, status.Code(err))
+		})
+	}
+
+	_, err := st.PermsClient.Evaluate(adminCtx, &permissionsv1.EvaluateRequest{
+		AppId:  suite.SmallClaimsAppID,
+		UserId: 100500,
+	})
+	require.Error(t, err)
+	assert.Equal(t, codes.NotFound, status.Code(err))
+
+	_, err = st.PermsClient.Evaluate(adminCtx, &permissionsv1.EvaluateRequest{
+		AppId:    suite.SmallClaimsAppID,
+		Resource: "[1, 2]",
+	})
+	require.Error(t, err)
+	assert.Equal(t, codes.InvalidArgument, status.Code(err))
+
+	// Admin of other application can't change the policy
+	appAdminToken, _ := st.Login(ctx, suite.AppAdminEmail, suite.AdminPassword, suite.AppID)
+
+	_, err = st.PermsClient.WritePolicy(st.WithToken(ctx, appAdminToken), &permissionsv1.WritePolicyRequest{
+		AppId:  suite.SmallClaimsAppID,
+		Policy: policyJSON(t, nil),
+	})
+	require.Error(t, err)
+	assert.Equal(t, codes.PermissionDenied, status.Code(err))
+}
+
+func policyJSON(t *testing.T, rules []policyRule) string {
+	t.Helper()
+
+	raw, err := json.Marshal(map[string]interface{}{"rules": rules})
+	require.NoError(t, err)
+
+	return string(raw)
+}
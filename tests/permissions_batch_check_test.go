@@ -0,0 +1,123 @@
+package tests
+
+import (
+	"testing"
+
+	"github.com/brianvoe/gofakeit/v6"
+	appsv1 "github.com/nhassl3/sso-app/contracts/generated/go/apps"
+	permissionsv1 "github.com/nhassl3/sso-app/contracts/generated/go/permissions"
+	"github.com/nhassl3/sso-app/tests/suite"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func TestPermissions_BatchCheckCache(t *testing.T) {
+	ctx, st := suite.NewSuite(t)
+
+	superToken, _ := st.Login(ctx, suite.SuperAdminEmail, suite.AdminPassword, suite.AppID)
+	adminCtx := st.WithToken(ctx, superToken)
+
+	// Own application keeps cache stats of the test apart from the parallel ones
+	respCreate, err := st.AppsClient.CreateApp(adminCtx, &appsv1.CreateAppRequest{Name: "cache-" + gofakeit.UUID()})
+	require.NoError(t, err)
+	appID := respCreate.GetApp().GetId()
+
+	t.Cleanup(func() {
+		_, _ = st.AppsClient.DeleteApp(adminCtx, &appsv1.DeleteAppRequest{AppId: appID})
+	})
+
+	_, err = st.PermsClient.WriteTuples(adminCtx, &permissionsv1.WriteTuplesRequest{
+		AppId:  appID,
+		Writes: []string{"doc:a#viewer@user:1", "doc:b#viewer@user:1"},
+	})
+	require.NoError(t, err)
+
+	items := []*permissionsv1.CheckItem{
+		{Object: "doc:a", Relation: "viewer", Subject: "user:1"},
+		{Object: "doc:b", Relation: "viewer", Subject: "user:1"},
+		{Object: "doc:a", Relation: "viewer", Subject: "user:2"},
+	}
+
+	for range 2 {
+		resp, err := st.PermsClient.BatchCheck(adminCtx, &permissionsv1.BatchCheckRequest{AppId: appID, Items: items})
+		require.NoError(t, err)
+		assert.Equal(t, []bool{true, true, false}, resp.GetAllowed())
+	}
+
+	respCheck, err := st.PermsClient.Check(adminCtx, &permissionsv1.CheckRequest{
+		AppId:    appID,
+		Object:   "doc:a",
+		Relation: "viewer",
+		Subject:  "user:1",
+	})
+	require.NoError(t, err)
+	assert.True(t, respCheck.GetAllowed())
+
+	stats, err := st.PermsClient.CacheStats(adminCtx, &permissionsv1.CacheStatsRequest{AppId: appID})
+	require.NoError(t, err)
+	assert.EqualValues(t, 3, stats.GetMisses())
+	assert.EqualValues(t, 4, stats.GetHits())
+	assert.EqualValues(t, 3, stats.GetSize())
+
+	respWrite, err := st.PermsClient.WriteTuples(adminCtx, &permissionsv1.WriteTuplesRequest{
+		AppId:   appID,
+		Deletes: []string{"doc:a#viewer@user:1"},
+	})
+	require.NoError(t, err)
+
+	stats, err = st.PermsClient.CacheStats(adminCtx, &permissionsv1.CacheStatsRequest{AppId: appID})
+	require.NoError(t, err)
+	assert.EqualValues(t, 2, stats.GetInvalidations())
+	assert.Zero(t, stats.GetSize())
+
+	respBatch, err := st.PermsClient.BatchCheck(adminCtx, &permissionsv1.BatchCheckRequest{
+		AppId:           appID,
+		Items:           items,
+		AtLeastRevision: respWrite.GetRevision(),
+	})
+	require.NoError(t, err)
+	assert.Equal(t, []bool{false, true, false}, respBatch.GetAllowed())
+	assert.Equal(t, respWrite.GetRevision(), respBatch.GetRevision())
+
+	_, err = st.PermsClient.BatchCheck(adminCtx, &permissionsv1.BatchCheckRequest{
+		AppId:           appID,
+		Items:           items,
+		AtLeastRevision: respWrite.GetRevision() + 1,
+	})
+	require.Error(t, err)
+	assert.Equal(t, codes.FailedPrecondition, status.Code(err))
+}
+
+func TestPermissions_BatchCheckInvalidItems(t *testing.T) {
+	ctx, st := suite.NewSuite(t)
+
+	appToken, _ := st.Login(ctx, suite.RolesUserEmail, suite.RolesUserPassword, suite.ClaimsAppID)
+	appCtx := st.WithToken(ctx, appToken)
+
+	tooMany := make([]*permissionsv1.CheckItem, 101)
+	for i := range tooMany {
+		tooMany[i] = &permissionsv1.CheckItem{Object: "doc:a", Relation: "viewer", Subject: "user:1"}
+	}
+
+	tests := []struct {
+		Name  string
+		Items []*permissionsv1.CheckItem
+	}{
+		{Name: "No items"},
+		{Name: "Too many items", Items: tooMany},
+		{Name: "Invalid subject", Items: []*permissionsv1.CheckItem{{Object: "doc:a", Relation: "viewer", Subject: "1"}}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.Name, func(t *testing.T) {
+			_, err := st.PermsClient.BatchCheck(appCtx, &permissionsv1.BatchCheckRequest{
+				AppId: suite.ClaimsAppID,
+				Items: tt.Items,
+			})
+			require.Error(t, err)
+			assert.Equal(t, codes.InvalidArgument, status.Code(err))
+		})
+	}
+}
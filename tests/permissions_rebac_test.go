@@ -0,0 +1,294 @@
+package tests
+
+import (
+	"context"
+	"testing"
+
+	"github.com/brianvoe/gofakeit/v6"
+	appsv1 "github.com/nhassl3/sso-app/contracts/generated/go/apps"
+	permissionsv1 "github.com/nhassl3/sso-app/contracts/generated/go/permissions"
+	"github.com/nhassl3/sso-app/tests/suite"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// docsNamespaceConfig gives viewers of the folder access to its documents and lets groups be viewers
+const docsNamespaceConfig = `{"namespaces": {
+  "doc": {"relations": {
+    "parent": {},
+    "owner": {},
+    "editor": {"union": [{"this": true}, {"computed": "owner"}]},
+    "viewer": {"union": [{"this": true}, {"computed": "editor"},
+      {"tuple_to_userset": {"tupleset": "parent", "computed": "viewer"}}]},
+    "banned": {},
+    "reader": {"exclusion": {"base": {"computed": "viewer"}, "subtract": {"computed": "banned"}}}
+  }},
+  "folder": {"relations": {"viewer": {}}},
+  "group": {"relations": {"member": {}}}
+}}`
+
+func TestPermissions_CheckExpandListObjects(t *testing.T) {
+	ctx, st := suite.NewSuite(t)
+
+	adminToken, _ := st.Login(ctx, suite.AppAdminEmail, suite.AdminPassword, suite.AppID)
+	adminCtx := st.WithToken(ctx, adminToken)
+	writeDocsConfig(adminCtx, t, st)
+
+	// Unique IDs keep tuples of the parallel tests apart
+	id := gofakeit.UUID()
+	folder, readme, notes, group := "folder:f-"+id, "doc:readme-"+id, "doc:notes-"+id, "group:eng-"+id
+	alice, bob, carol := "user:alice-"+id, "user:bob-"+id, "user:carol-"+id
+
+	respWrite, err := st.PermsClient.WriteTuples(adminCtx, &permissionsv1.WriteTuplesRequest{
+		AppId: suite.ClaimsAppID,
+		Writes: []string{
+			readme + "#owner@" + alice,
+			readme + "#parent@" + folder,
+			notes + "#parent@" + folder,
+			folder + "#viewer@" + group + "#member",
+			group + "#member@" + bob,
+			group + "#member@" + carol,
+			notes + "#banned@" + carol,
+		},
+	})
+	require.NoError(t, err)
+	require.Positive(t, respWrite.GetRevision())
+
+	// Token of the application is enough to read its relations
+	appToken, _ := st.Login(ctx, suite.RolesUserEmail, suite.RolesUserPassword, suite.ClaimsAppID)
+	appCtx := st.WithToken(ctx, appToken)
+
+	tests := []struct {
+		Name     string
+		Object   string
+		Relation string
+		Subject  string
+		Allowed  bool
+	}{
+		{Name: "Direct owner", Object: readme, Relation: "owner", Subject: alice, Allowed: true},
+		{Name: "Owner is editor", Object: readme, Relation: "editor", Subject: alice, Allowed: true},
+		{Name: "Owner is viewer", Object: readme, Relation: "viewer", Subject: alice, Allowed: true},
+		{Name: "Viewer through folder and group", Object: readme, Relation: "viewer", Subject: bob, Allowed: true},
+		{Name: "Viewer is not editor", Object: readme, Relation: "editor", Subject: bob},
+		{Name: "Owner of other doc", Object: notes, Relation: "viewer", Subject: alice},
+		{Name: "Group userset as subject", Object: notes, Relation: "viewer", Subject: group + "#member", Allowed: true},
+		{Name: "Not banned reader", Object: notes, Relation: "reader", Subject: bob, Allowed: true},
+		{Name: "Banned reader", Object: notes, Relation: "reader", Subject: carol},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.Name, func(t *testing.T) {
+			resp, err := st.PermsClient.Check(appCtx, &permissionsv1.CheckRequest{
+				AppId:           suite.ClaimsAppID,
+				Object:          tt.Object,
+				Relation:        tt.Relation,
+				Subject:         tt.Subject,
+				AtLeastRevision: respWrite.GetRevision(),
+			})
+			require.NoError(t, err)
+			assert.Equal(t, tt.Allowed, resp.GetAllowed())
+			assert.GreaterOrEqual(t, resp.GetRevision(), respWrite.GetRevision())
+		})
+	}
+
+	respList, err := st.PermsClient.ListObjects(appCtx, &permissionsv1.ListObjectsRequest{
+		AppId:      suite.ClaimsAppID,
+		ObjectType: "doc",
+		Relation:   "viewer",
+		Subject:    bob,
+	})
+	require.NoError(t, err)
+	assert.ElementsMatch(t, []string{"notes-" + id, "readme-" + id}, respList.GetObjectIds())
+
+	respExpand, err := st.PermsClient.Expand(appCtx, &permissionsv1.ExpandRequest{
+		AppId:    suite.ClaimsAppID,
+		Object:   readme,
+		Relation: "viewer",
+	})
+	require.NoError(t, err)
+
+	tree := respExpand.GetTree()
+	assert.Equal(t, "union", tree.GetOperation())
+	require.Len(t, tree.GetChildren(), 3)
+	assert.Empty(t, tree.GetChildren()[0].GetSubjects())
+	assert.Equal(t, []string{alice}, tree.GetChildren()[1].GetChildren()[1].GetSubjects())
+
+	folderTree := tree.GetChildren()[2].GetChildren()
+	require.Len(t, folderTree, 1)
+	assert.Equal(t, folder, folderTree[0].GetObject())
+	assert.Equal(t, []string{group + "#member"}, folderTree[0].GetSubjects())
+
+	_, err = st.PermsClient.WriteTuples(adminCtx, &permissionsv1.WriteTuplesRequest{
+		AppId:   suite.ClaimsAppID,
+		Deletes: []string{group + "#member@" + bob},
+	})
+	require.NoError(t, err)
+
+	respCheck, err := st.PermsClient.Check(appCtx, &permissionsv1.CheckRequest{
+		AppId:    suite.ClaimsAppID,
+		Object:   readme,
+		Relation: "viewer",
+		Subject:  bob,
+	})
+	require.NoError(t, err)
+	assert.False(t, respCheck.GetAllowed())
+}
+
+func TestPermissions_StaleRevision(t *testing.T) {
+	ctx, st := suite.NewSuite(t)
+
+	appToken, _ := st.Login(ctx, suite.RolesUserEmail, suite.RolesUserPassword, suite.ClaimsAppID)
+
+	_, err := st.PermsClient.Check(st.WithToken(ctx, appToken), &permissionsv1.CheckRequest{
+		AppId:           suite.ClaimsAppID,
+		Object:          "doc:any",
+		Relation:        "viewer",
+		Subject:         "user:any",
+		AtLeastRevision: 1 << 40,
+	})
+	require.Error(t, err)
+	assert.Equal(t, codes.FailedPrecondition, status.Code(err))
+}
+
+func TestPermissions_InvalidWrites(t *testing.T) {
+	ctx, st := suite.NewSuite(t)
+
+	adminToken, _ := st.Login(ctx, suite.AppAdminEmail, suite.AdminPassword, suite.AppID)
+	adminCtx := st.WithToken(ctx, adminToken)
+	writeDocsConfig(adminCtx, t, st)
+
+	tests := []struct {
+		Name   string
+		Writes []string
+		Code   codes.Code
+	}{
+		{Name: "Malformed tuple", Writes: []string{"doc:readme#viewer"}, Code: codes.InvalidArgument},
+		{Name: "Unknown relation", Writes: []string{"doc:readme#commenter@user:1"}, Code: codes.InvalidArgument},
+		{Name: "Unknown type", Writes: []string{"repo:sso#viewer@user:1"}, Code: codes.InvalidArgument},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.Name, func(t *testing.T) {
+			_, err := st.PermsClient.WriteTuples(adminCtx, &permissionsv1.WriteTuplesRequest{
+				AppId:  suite.ClaimsAppID,
+				Writes: tt.Writes,
+			})
+			require.Error(t, err)
+			assert.Equal(t, tt.Code, status.Code(err))
+		})
+	}
+
+	_, err := st.PermsClient.WriteNamespaceConfig(adminCtx, &permissionsv1.WriteNamespaceConfigRequest{
+		AppId:  suite.ClaimsAppID,
+		Config: `{"namespaces": {"doc": {"relations": {"viewer": {"computed": "owner"}}}}}`,
+	})
+	require.Error(t, err)
+	assert.Equal(t, codes.InvalidArgument, status.Code(err))
+}
+
+func TestPermissions_PermissionDenied(t *testing.T) {
+	ctx, st := suite.NewSuite(t)
+
+	// Token of other application can't read relations and regular user can't write them
+	appToken, _ := st.Login(ctx, suite.RolesUserEmail, suite.RolesUserPassword, suite.ClaimsAppID)
+	appCtx := st.WithToken(ctx, appToken)
+
+	_, err := st.PermsClient.WriteTuples(appCtx, &permissionsv1.WriteTuplesRequest{
+		AppId:  suite.ClaimsAppID,
+		Writes: []string{"doc:readme#owner@user:1"},
+	})
+	require.Error(t, err)
+	assert.Equal(t, codes.PermissionDenied, status.Code(err))
+
+	_, err = st.PermsClient.Check(appCtx, &permissionsv1.CheckRequest{
+		AppId:    suite.SmallClaimsAppID,
+		Object:   "doc:readme",
+		Relation: "owner",
+		Subject:  "user:1",
+	})
+	require.Error(t, err)
+	assert.Equal(t, codes.PermissionDenied, status.Code(err))
+
+	_, err = st.PermsClient.Check(ctx, &permissionsv1.CheckRequest{
+		AppId:    suite.ClaimsAppID,
+		Object:   "doc:readme",
+		Relation: "owner",
+		Subject:  "user:1",
+	})
+	require.Error(t, err)
+	assert.Equal(t, codes.Unauthenticated, status.Code(err))
+}
+
+func TestPermissions_Cycles(t *testing.T) {
+	ctx, st := suite.NewSuite(t)
+
+	adminCtx := superAdminCtx(ctx, st)
+	app := createApp(ctx, t, st, &appsv1.AppSettings{})
+
+	_, err := st.PermsClient.WriteNamespaceConfig(adminCtx, &permissionsv1.WriteNamespaceConfigRequest{
+		AppId: app.GetId(),
+		Config: `{"namespaces": {
+  "doc": {"relations": {
+    "first": {},
+    "second": {},
+    "both": {"intersection": [{"computed": "first"}, {"computed": "second"}]},
+    "blocked": {},
+    "reader": {"exclusion": {"base": {"computed": "first"}, "subtract": {"computed": "blocked"}}}
+  }},
+  "group": {"relations": {"member": {}}}
+}}`,
+	})
+	require.NoError(t, err)
+
+	// Groups are members of each other, the document blocks its own readers
+	respWrite, err := st.PermsClient.WriteTuples(adminCtx, &permissionsv1.WriteTuplesRequest{
+		AppId: app.GetId(),
+		Writes: []string{
+			"group:p#member@group:q#member",
+			"group:p#member@user:alice",
+			"group:q#member@group:p#member",
+			"doc:cyclic#first@group:p#member",
+			"doc:cyclic#second@group:q#member",
+			"doc:cyclic#blocked@doc:cyclic#reader",
+			"doc:plain#first@user:alice",
+		},
+	})
+	require.NoError(t, err)
+
+	tests := []struct {
+		Name     string
+		Object   string
+		Relation string
+		Allowed  bool
+	}{
+		{Name: "Member through cycle of groups", Object: "doc:cyclic", Relation: "both", Allowed: true},
+		{Name: "Cycle in subtracted relation", Object: "doc:cyclic", Relation: "reader"},
+		{Name: "Nothing subtracted", Object: "doc:plain", Relation: "reader", Allowed: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.Name, func(t *testing.T) {
+			resp, err := st.PermsClient.Check(adminCtx, &permissionsv1.CheckRequest{
+				AppId:           app.GetId(),
+				Object:          tt.Object,
+				Relation:        tt.Relation,
+				Subject:         "user:alice",
+				AtLeastRevision: respWrite.GetRevision(),
+			})
+			require.NoError(t, err)
+			assert.Equal(t, tt.Allowed, resp.GetAllowed())
+		})
+	}
+}
+
+func writeDocsConfig(ctx context.Context, t *testing.T, st *suite.Suite) {
+	t.Helper()
+
+	_, err := st.PermsClient.WriteNamespaceConfig(ctx, &permissionsv1.WriteNamespaceConfigRequest{
+		AppId:  suite.ClaimsAppID,
+		Config: docsNamespaceConfig,
+	})
+	require.NoError(t, err)
+}
@@ -0,0 +1,113 @@
+package tests
+
+import (
+	"net/http"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/brianvoe/gofakeit/v6"
+	tokenv1 "github.com/nhassl3/sso-app/contracts/generated/go/token"
+	"github.com/nhassl3/sso-app/tests/suite"
+	ssov1 "github.com/nhassl3/sso-contracts/generated/go/sso"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// Realms of config/local_tests.yaml route partner.test to mock provider for all applications
+// and corp-partner.test to corp provider for the claims application
+
+func TestRealm_Discover(t *testing.T) {
+	ctx, st := suite.NewSuite(t)
+
+	resp, err := st.TokenClient.DiscoverRealm(ctx, &tokenv1.DiscoverRealmRequest{
+		Email: gofakeit.Username() + "@Partner.Test",
+		AppId: suite.AppID,
+	})
+	require.NoError(t, err)
+	assert.Equal(t, "mock", resp.GetProvider())
+	assert.Equal(t, "http://localhost:44080/federation/mock/login", resp.GetLoginUri())
+
+	resp, err = st.TokenClient.DiscoverRealm(ctx, &tokenv1.DiscoverRealmRequest{Email: st.NewEmail(), AppId: suite.AppID})
+	require.NoError(t, err)
+	assert.Empty(t, resp.GetProvider())
+	assert.Empty(t, resp.GetLoginUri())
+
+	// Rule of the application doesn't apply to other applications
+	email := gofakeit.Username() + "@corp-partner.test"
+
+	resp, err = st.TokenClient.DiscoverRealm(ctx, &tokenv1.DiscoverRealmRequest{Email: email, AppId: suite.ClaimsAppID})
+	require.NoError(t, err)
+	assert.Equal(t, "corp", resp.GetProvider())
+
+	for _, appID := range []int32{suite.AppID, 0} {
+		resp, err = st.TokenClient.DiscoverRealm(ctx, &tokenv1.DiscoverRealmRequest{Email: email, AppId: appID})
+		require.NoError(t, err)
+		assert.Empty(t, resp.GetProvider())
+	}
+
+	_, err = st.TokenClient.DiscoverRealm(ctx, &tokenv1.DiscoverRealmRequest{AppId: suite.AppID})
+	require.Error(t, err)
+	assert.Equal(t, codes.InvalidArgument, status.Code(err))
+
+	httpResp, body := gatewayRequest(t, st, http.MethodGet, "/v1/realms?email="+url.QueryEscape(email)+"&app_id=10", "", nil)
+	require.Equal(t, http.StatusOK, httpResp.StatusCode)
+	assert.Equal(t, "corp", body["provider"])
+}
+
+func TestRealm_LoginRejectsPassword(t *testing.T) {
+	ctx, st := suite.NewSuite(t)
+
+	email, password := gofakeit.Username()+gofakeit.DigitN(6)+"@partner.test", st.NewPassword()
+	_, err := st.AuthClient.Register(ctx, &ssov1.RegisterRequest{Email: email, Password: password})
+	require.NoError(t, err)
+
+	_, err = st.AuthClient.Login(ctx, &ssov1.LoginRequest{Email: email, Password: password, AppId: suite.AppID})
+	require.Error(t, err)
+	assert.Equal(t, codes.FailedPrecondition, status.Code(err))
+
+	// Password of the application without the rule is still accepted
+	email = gofakeit.Username() + gofakeit.DigitN(6) + "@corp-partner.test"
+	_, err = st.AuthClient.Register(ctx, &ssov1.RegisterRequest{Email: email, Password: password})
+	require.NoError(t, err)
+
+	st.Login(ctx, email, password, suite.AppID)
+
+	_, err = st.AuthClient.Login(ctx, &ssov1.LoginRequest{Email: email, Password: password, AppId: suite.ClaimsAppID})
+	require.Error(t, err)
+	assert.Equal(t, codes.FailedPrecondition, status.Code(err))
+}
+
+func TestRealm_HostedLoginRedirectsToProvider(t *testing.T) {
+	ctx, st := suite.NewSuite(t)
+
+	appID := createOAuthApp(ctx, t, st, oauthRedirectURI)
+	query := loginQuery(appID)
+
+	b := newBrowser(t, st)
+
+	resp, page := b.get("/login?" + query.Encode())
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+
+	form := url.Values{"email": {gofakeit.Username() + "@partner.test"}, "password": {st.NewPassword()}}
+	for name, values := range query {
+		form[name] = values
+	}
+
+	resp, _ = b.submit(page, "/login", form)
+	require.Equal(t, http.StatusFound, resp.StatusCode)
+
+	location := resp.Header.Get("Location")
+	require.True(t, strings.HasPrefix(location, "/federation/mock/login?"), location)
+
+	redirected, err := url.Parse(location)
+	require.NoError(t, err)
+	assert.Equal(t, query.Get("code_challenge"), redirected.Query().Get("code_challenge"))
+
+	// Federated login of the realm is started as by the link of the page
+	resp, _ = b.get(location)
+	require.Equal(t, http.StatusFound, resp.StatusCode)
+	assert.True(t, strings.HasPrefix(resp.Header.Get("Location"), "http://"+mockProvidersAddress+"/mock/"))
+}
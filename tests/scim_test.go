@@ -0,0 +1,335 @@
+package tests
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/url"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	adminv1 "github.com/nhassl3/sso-app/contracts/generated/go/admin"
+	appsv1 "github.com/nhassl3/sso-app/contracts/generated/go/apps"
+	permissionsv1 "github.com/nhassl3/sso-app/contracts/generated/go/permissions"
+	"github.com/nhassl3/sso-app/tests/suite"
+	ssov1 "github.com/nhassl3/sso-contracts/generated/go/sso"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/fieldmaskpb"
+)
+
+func TestScim_Users(t *testing.T) {
+	ctx, st := suite.NewSuite(t)
+
+	_, token := scimClient(ctx, t, st)
+
+	resp, body := scimRequest(t, st, "", http.MethodGet, "/ServiceProviderConfig", nil, nil)
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.Equal(t, true, body["patch"].(map[string]any)["supported"])
+
+	resp, _ = scimRequest(t, st, "", http.MethodGet, "/Users", nil, nil)
+	assert.Equal(t, http.StatusUnauthorized, resp.StatusCode)
+
+	// Tokens of the users can't provision
+	userToken, _ := st.Login(ctx, suite.RolesUserEmail, suite.RolesUserPassword, suite.AppID)
+	resp, _ = scimRequest(t, st, userToken, http.MethodGet, "/Users", nil, nil)
+	assert.Equal(t, http.StatusForbidden, resp.StatusCode)
+
+	email, password, externalID := st.NewEmail(), st.NewPassword(), "ext-"+st.NewPassword()
+
+	resp, body = scimRequest(t, st, token, http.MethodPost, "/Users", map[string]any{
+		"schemas":    []string{"urn:ietf:params:scim:schemas:core:2.0:User"},
+		"userName":   email,
+		"externalId": externalID,
+		"name":       map[string]any{"givenName": "Ada", "familyName": "Lovelace"},
+		"password":   password,
+	}, nil)
+	require.Equal(t, http.StatusCreated, resp.StatusCode, body)
+	assert.Equal(t, `W/"1"`, resp.Header.Get("ETag"))
+	assert.Equal(t, true, body["active"])
+	assert.NotContains(t, body, "password")
+	userID := body["id"].(string)
+	assert.Equal(t, st.HTTPURL("/scim/v2/Users/"+userID), resp.Header.Get("Location"))
+
+	_, loginID := st.Login(ctx, email, password, suite.AppID)
+	assert.Equal(t, userID, strconv.FormatInt(loginID, 10))
+
+	resp, body = scimRequest(t, st, token, http.MethodPost, "/Users", map[string]any{"userName": email}, nil)
+	assert.Equal(t, http.StatusConflict, resp.StatusCode)
+	assert.Equal(t, "uniqueness", body["scimType"])
+
+	tests := []struct {
+		Name   string
+		Filter string
+		Total  float64
+	}{
+		{Name: "User name", Filter: `userName eq "` + email + `"`, Total: 1},
+		{Name: "Case-insensitive", Filter: `USERNAME Eq "` + email + `"`, Total: 1},
+		{Name: "External ID and active", Filter: `externalId eq "` + externalID + `" and active eq true`, Total: 1},
+		{Name: "Inactive", Filter: `externalId eq "` + externalID + `" and not (active eq true)`, Total: 0},
+		{Name: "Given name", Filter: `name.givenName sw "ad" and userName eq "` + email + `"`, Total: 1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.Name, func(t *testing.T) {
+			resp, body := scimRequest(t, st, token, http.MethodGet, "/Users?"+url.Values{"filter": {tt.Filter}}.Encode(), nil, nil)
+			require.Equal(t, http.StatusOK, resp.StatusCode, body)
+			assert.Equal(t, tt.Total, body["totalResults"])
+		})
+	}
+
+	resp, body = scimRequest(t, st, token, http.MethodGet, "/Users?filter="+url.QueryEscape(`userName eq`), nil, nil)
+	assert.Equal(t, http.StatusBadRequest, resp.StatusCode)
+	assert.Equal(t, "invalidFilter", body["scimType"])
+
+	resp, body = scimRequest(t, st, token, http.MethodGet, "/Users?startIndex=1&count=1", nil, nil)
+	require.Equal(t, http.StatusOK, resp.StatusCode, body)
+	assert.Len(t, body["Resources"], 1)
+	assert.Greater(t, body["totalResults"], float64(1))
+
+	resp, _ = scimRequest(t, st, token, http.MethodGet, "/Users/"+userID, nil, map[string]string{"If-None-Match": `W/"1"`})
+	assert.Equal(t, http.StatusNotModified, resp.StatusCode)
+
+	deactivate := map[string]any{"Operations": []map[string]any{{"op": "Replace", "path": "active", "value": false}}}
+
+	resp, _ = scimRequest(t, st, token, http.MethodPatch, "/Users/"+userID, deactivate, map[string]string{"If-Match": `W/"7"`})
+	assert.Equal(t, http.StatusPreconditionFailed, resp.StatusCode)
+
+	resp, body = scimRequest(t, st, token, http.MethodPatch, "/Users/"+userID, deactivate, map[string]string{"If-Match": `W/"1"`})
+	require.Equal(t, http.StatusOK, resp.StatusCode, body)
+	assert.Equal(t, false, body["active"])
+	assert.Equal(t, `W/"2"`, resp.Header.Get("ETag"))
+
+	// Deactivated users can't sign in
+	_, err := st.AuthClient.Login(ctx, &ssov1.LoginRequest{Email: email, Password: password, AppId: suite.AppID})
+	require.Error(t, err)
+
+	// Some clients send booleans as strings in the operation without path
+	resp, body = scimRequest(t, st, token, http.MethodPatch, "/Users/"+userID, map[string]any{
+		"Operations": []map[string]any{{"op": "replace", "value": map[string]any{"active": "True", "displayName": "Ada"}}},
+	}, nil)
+	require.Equal(t, http.StatusOK, resp.StatusCode, body)
+	assert.Equal(t, true, body["active"])
+	assert.Equal(t, "Ada", body["displayName"])
+
+	st.Login(ctx, email, password, suite.AppID)
+
+	resp, _ = scimRequest(t, st, token, http.MethodDelete, "/Users/"+userID, nil, nil)
+	assert.Equal(t, http.StatusNoContent, resp.StatusCode)
+
+	resp, _ = scimRequest(t, st, token, http.MethodGet, "/Users/"+userID, nil, nil)
+	assert.Equal(t, http.StatusNotFound, resp.StatusCode)
+
+	_, err = st.AuthClient.Login(ctx, &ssov1.LoginRequest{Email: email, Password: password, AppId: suite.AppID})
+	require.Error(t, err)
+}
+
+func TestScim_Groups(t *testing.T) {
+	ctx, st := suite.NewSuite(t)
+
+	appID, token := scimClient(ctx, t, st)
+
+	superToken, _ := st.Login(ctx, suite.SuperAdminEmail, suite.AdminPassword, suite.AppID)
+	adminCtx := st.WithToken(ctx, superToken)
+
+	_, err := st.PermsClient.WriteNamespaceConfig(adminCtx, &permissionsv1.WriteNamespaceConfigRequest{
+		AppId:  appID,
+		Config: `{"namespaces": {"group": {"relations": {"member": {}}}}}`,
+	})
+	require.NoError(t, err)
+
+	userIDs := make([]string, 2)
+	for i := range userIDs {
+		resp, body := scimRequest(t, st, token, http.MethodPost, "/Users", map[string]any{
+			"emails": []map[string]any{{"value": st.NewEmail(), "primary": true}},
+		}, nil)
+		require.Equal(t, http.StatusCreated, resp.StatusCode, body)
+		userIDs[i] = body["id"].(string)
+	}
+
+	name := "engineers-" + st.NewPassword()
+
+	resp, body := scimRequest(t, st, token, http.MethodPost, "/Groups", map[string]any{
+		"displayName": name,
+		"members":     []map[string]any{{"value": userIDs[0]}},
+	}, nil)
+	require.Equal(t, http.StatusCreated, resp.StatusCode, body)
+	groupID := body["id"].(string)
+	require.Len(t, body["members"], 1)
+
+	isMember := func(userID string) bool {
+		t.Helper()
+
+		resp, err := st.PermsClient.Check(adminCtx, &permissionsv1.CheckRequest{
+			AppId:    appID,
+			Object:   "group:" + groupID,
+			Relation: "member",
+			Subject:  "user:" + userID,
+		})
+		require.NoError(t, err)
+
+		return resp.GetAllowed()
+	}
+
+	assert.True(t, isMember(userIDs[0]))
+	assert.False(t, isMember(userIDs[1]))
+
+	resp, body = scimRequest(t, st, token, http.MethodPatch, "/Groups/"+groupID, map[string]any{
+		"Operations": []map[string]any{
+			{"op": "add", "path": "members", "value": []map[string]any{{"value": userIDs[1]}}},
+			{"op": "remove", "path": `members[value eq "` + userIDs[0] + `"]`},
+		},
+	}, map[string]string{"If-Match": `W/"1"`})
+	require.Equal(t, http.StatusOK, resp.StatusCode, body)
+	require.Len(t, body["members"], 1)
+	assert.Equal(t, userIDs[1], body["members"].([]any)[0].(map[string]any)["value"])
+
+	assert.False(t, isMember(userIDs[0]))
+	assert.True(t, isMember(userIDs[1]))
+
+	resp, body = scimRequest(t, st, token, http.MethodPatch, "/Groups/"+groupID, map[string]any{
+		"Operations": []map[string]any{{"op": "add", "path": "members", "value": []map[string]any{{"value": "0"}}}},
+	}, nil)
+	assert.Equal(t, http.StatusBadRequest, resp.StatusCode)
+	assert.Equal(t, "invalidValue", body["scimType"])
+
+	resp, body = scimRequest(t, st, token, http.MethodGet,
+		"/Groups?"+url.Values{"filter": {`displayName eq "` + name + `"`}}.Encode(), nil, nil)
+	require.Equal(t, http.StatusOK, resp.StatusCode, body)
+	assert.Equal(t, float64(1), body["totalResults"])
+
+	resp, body = scimRequest(t, st, token, http.MethodPost, "/Groups", map[string]any{"displayName": name}, nil)
+	assert.Equal(t, http.StatusConflict, resp.StatusCode)
+	assert.Equal(t, "uniqueness", body["scimType"])
+
+	// Groups belong to the application of the client
+	_, otherToken := scimClient(ctx, t, st)
+	resp, _ = scimRequest(t, st, otherToken, http.MethodGet, "/Groups/"+groupID, nil, nil)
+	assert.Equal(t, http.StatusNotFound, resp.StatusCode)
+
+	resp, _ = scimRequest(t, st, token, http.MethodDelete, "/Groups/"+groupID, nil, nil)
+	assert.Equal(t, http.StatusNoContent, resp.StatusCode)
+
+	assert.False(t, isMember(userIDs[1]))
+
+	resp, _ = scimRequest(t, st, token, http.MethodGet, "/Groups/"+groupID, nil, nil)
+	assert.Equal(t, http.StatusNotFound, resp.StatusCode)
+}
+
+func TestScim_ScopeGrantedBySuperAdminOnly(t *testing.T) {
+	ctx, st := suite.NewSuite(t)
+
+	app := createApp(ctx, t, st, &appsv1.AppSettings{ClientScopes: []string{"reports:read"}})
+
+	appAdminCtx, appAdminID := registerAndLogin(ctx, t, st)
+	_, err := st.AdminClient.GrantAdmin(superAdminCtx(ctx, st), &adminv1.GrantAdminRequest{UserId: appAdminID, AppId: app.GetId()})
+	require.NoError(t, err)
+
+	// App admin can't give own application the scope of the provisioning client
+	_, err = st.AppsClient.UpdateApp(appAdminCtx, &appsv1.UpdateAppRequest{
+		AppId:      app.GetId(),
+		Settings:   &appsv1.AppSettings{ClientScopes: []string{"reports:read", "scim"}},
+		UpdateMask: &fieldmaskpb.FieldMask{Paths: []string{"client_scopes"}},
+	})
+	require.Error(t, err)
+	assert.Equal(t, codes.PermissionDenied, status.Code(err))
+
+	_, err = st.AppsClient.UpdateApp(appAdminCtx, &appsv1.UpdateAppRequest{
+		AppId:    app.GetId(),
+		Settings: &appsv1.AppSettings{ClientScopes: []string{"scim"}},
+	})
+	require.Error(t, err)
+	assert.Equal(t, codes.PermissionDenied, status.Code(err))
+
+	respRotate, err := st.AppsClient.RotateClientSecret(appAdminCtx, &appsv1.RotateClientSecretRequest{AppId: app.GetId()})
+	require.NoError(t, err)
+
+	resp, body := clientTokenRequest(t, st, strconv.Itoa(int(app.GetId())), respRotate.GetClientSecret(), url.Values{})
+	require.Equal(t, http.StatusOK, resp.StatusCode, body)
+	assert.Equal(t, "reports:read", body["scope"])
+
+	resp, _ = scimRequest(t, st, body["access_token"].(string), http.MethodGet, "/Users", nil, nil)
+	assert.Equal(t, http.StatusForbidden, resp.StatusCode)
+
+	// Other client scopes stay with the app admin
+	_, err = st.AppsClient.UpdateApp(appAdminCtx, &appsv1.UpdateAppRequest{
+		AppId:      app.GetId(),
+		Settings:   &appsv1.AppSettings{ClientScopes: []string{"reports:write"}},
+		UpdateMask: &fieldmaskpb.FieldMask{Paths: []string{"client_scopes"}},
+	})
+	require.NoError(t, err)
+
+	// Owner of the application can't sign the scope into the token of the application by its secret
+	forgedToken := signToken(t, jwt.MapClaims{
+		"app_id": suite.SmallClaimsAppID, "scope": "scim", "exp": time.Now().Add(time.Hour).Unix(),
+	}, suite.SmallClaimsAppSecret)
+
+	resp, _ = scimRequest(t, st, forgedToken, http.MethodGet, "/Users", nil, nil)
+	assert.Equal(t, http.StatusForbidden, resp.StatusCode)
+}
+
+// scimClient creates application with scim client scope and returns it with token of its client
+func scimClient(ctx context.Context, t *testing.T, st *suite.Suite) (int32, string) {
+	t.Helper()
+
+	superToken, _ := st.Login(ctx, suite.SuperAdminEmail, suite.AdminPassword, suite.AppID)
+
+	app := createApp(ctx, t, st, &appsv1.AppSettings{ClientScopes: []string{"scim"}})
+
+	respRotate, err := st.AppsClient.RotateClientSecret(st.WithToken(ctx, superToken), &appsv1.RotateClientSecretRequest{
+		AppId: app.GetId(),
+	})
+	require.NoError(t, err)
+
+	resp, body := clientTokenRequest(t, st, strconv.Itoa(int(app.GetId())), respRotate.GetClientSecret(), url.Values{})
+	require.Equal(t, http.StatusOK, resp.StatusCode, body)
+
+	return app.GetId(), body["access_token"].(string)
+}
+
+// scimRequest sends the request to SCIM endpoints with the bearer token, if it is set,
+// and returns response with decoded JSON body, if it has one
+func scimRequest(
+	t *testing.T,
+	st *suite.Suite,
+	token string,
+	method string,
+	path string,
+	resource any,
+	headers map[string]string,
+) (*http.Response, map[string]any) {
+	t.Helper()
+
+	var reqBody bytes.Buffer
+	if resource != nil {
+		require.NoError(t, json.NewEncoder(&reqBody).Encode(resource))
+	}
+
+	req, err := http.NewRequest(method, st.HTTPURL("/scim/v2"+path), &reqBody)
+	require.NoError(t, err)
+	req.Header.Set("Content-Type", "application/scim+json")
+
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+
+	for name, value := range headers {
+		req.Header.Set(name, value)
+	}
+
+	resp, err := st.HTTPClient.Do(req)
+	require.NoError(t, err)
+	defer resp.Body.Close()
+
+	var body map[string]any
+	if resp.Header.Get("Content-Type") == "application/scim+json" {
+		require.NoError(t, json.NewDecoder(resp.Body).Decode(&body))
+	}
+
+	return resp, body
+}
@@ -3,31 +3,57 @@ package suite
 import (
 	"context"
 	"net"
+	"net/http"
 	"strconv"
 	"testing"
 
 	"github.com/brianvoe/gofakeit/v6"
+	adminv1 "github.com/nhassl3/sso-app/contracts/generated/go/admin"
+	appsv1 "github.com/nhassl3/sso-app/contracts/generated/go/apps"
+	permissionsv1 "github.com/nhassl3/sso-app/contracts/generated/go/permissions"
+	sessionsv1 "github.com/nhassl3/sso-app/contracts/generated/go/sessions"
+	tokenv1 "github.com/nhassl3/sso-app/contracts/generated/go/token"
+	webhooksv1 "github.com/nhassl3/sso-app/contracts/generated/go/webhooks"
 	"github.com/nhassl3/sso-app/internals/config"
 	ssov1 "github.com/nhassl3/sso-contracts/generated/go/sso"
 	"google.golang.org/grpc"
 	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/metadata"
 )
 
 const (
 	gRPCHost = "localhost"
+	httpHost = "localhost"
 
 	EmptyAppID int32 = 0
 	AppID      int32 = 2
 	AppSecret        = "test-secret"
 
+	ClaimsAppID          int32 = 10
+	ClaimsAppSecret            = "test-claims-secret"
+	SmallClaimsAppID     int32 = 11
+	SmallClaimsAppSecret       = "test-claims-small-secret"
+	RolesUserEmail             = "roles@sso.test"
+	RolesUserPassword          = "roles-password"
+	SuperAdminEmail            = "super-admin@sso.test"
+	AppAdminEmail              = "app-admin@sso.test"
+	AdminPassword              = "admin-password"
+
 	passDefaultLen = 10
 	DeltaSecond    = 1
 )
 
 type Suite struct {
 	*testing.T
-	Cfg        *config.Config
-	AuthClient ssov1.AuthClient
+	Cfg            *config.Config
+	AuthClient     ssov1.AuthClient
+	TokenClient    tokenv1.TokenClient
+	AdminClient    adminv1.AdminClient
+	AppsClient     appsv1.AppServiceClient
+	PermsClient    permissionsv1.PermissionsClient
+	SessionsClient sessionsv1.SessionsClient
+	WebhooksClient webhooksv1.WebhooksClient
+	HTTPClient     *http.Client // doesn't follow redirects, so tests see redirects of the OAuth endpoints
 }
 
 func NewSuite(t *testing.T) (context.Context, *Suite) {
@@ -55,9 +81,36 @@ func NewSuite(t *testing.T) (context.Context, *Suite) {
 		t,
 		cfg,
 		ssov1.NewAuthClient(cc),
+		tokenv1.NewTokenClient(cc),
+		adminv1.NewAdminClient(cc),
+		appsv1.NewAppServiceClient(cc),
+		permissionsv1.NewPermissionsClient(cc),
+		sessionsv1.NewSessionsClient(cc),
+		webhooksv1.NewWebhooksClient(cc),
+		&http.Client{
+			Timeout: cfg.HTTP.Timeout,
+			CheckRedirect: func(*http.Request, []*http.Request) error {
+				return http.ErrUseLastResponse
+			},
+		},
 	}
 }
 
+// HTTPURL returns URL of the path on the HTTP server
+func (s *Suite) HTTPURL(path string) string {
+	return "http://" + net.JoinHostPort(httpHost, strconv.Itoa(s.Cfg.HTTP.Port)) + path
+}
+
+// GatewayURL returns URL of the path on the REST gateway
+func (s *Suite) GatewayURL(path string) string {
+	_, port, err := net.SplitHostPort(s.Cfg.Gateway.Address)
+	if err != nil {
+		s.Fatalf("invalid gateway address %v", err)
+	}
+
+	return "http://" + net.JoinHostPort(httpHost, port) + path
+}
+
 func (s *Suite) NewPassword() string {
 	return gofakeit.Password(true, false, true, false, false, passDefaultLen)
 }
@@ -65,3 +118,31 @@ func (s *Suite) NewPassword() string {
 func (s *Suite) NewEmail() string {
 	return gofakeit.Email()
 }
+
+// Login logs in the user to the application and returns token with user ID from it
+func (s *Suite) Login(ctx context.Context, email, password string, appID int32) (token string, userID int64) {
+	s.Helper()
+
+	respLogin, err := s.AuthClient.Login(ctx, &ssov1.LoginRequest{
+		Email:    email,
+		Password: password,
+		AppId:    appID,
+	})
+	if err != nil {
+		s.Fatalf("login of %s failed %v", email, err)
+	}
+
+	respIntrospect, err := s.TokenClient.Introspect(s.WithToken(ctx, respLogin.GetToken()), &tokenv1.IntrospectRequest{
+		Token: respLogin.GetToken(),
+	})
+	if err != nil {
+		s.Fatalf("introspection of %s token failed %v", email, err)
+	}
+
+	return respLogin.GetToken(), respIntrospect.GetUserId()
+}
+
+// WithToken returns context which passes token of the user as bearer token of the requests
+func (s *Suite) WithToken(ctx context.Context, token string) context.Context {
+	return metadata.AppendToOutgoingContext(ctx, "authorization", "Bearer "+token)
+}
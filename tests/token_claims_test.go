@@ -0,0 +1,155 @@
+package tests
+
+import (
+	"testing"
+
+	"github.com/golang-jwt/jwt/v5"
+	tokenv1 "github.com/nhassl3/sso-app/contracts/generated/go/token"
+	"github.com/nhassl3/sso-app/tests/suite"
+	ssov1 "github.com/nhassl3/sso-contracts/generated/go/sso"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func TestTokenClaims_RolesAndScope(t *testing.T) {
+	ctx, st := suite.NewSuite(t)
+
+	respLogin, err := st.AuthClient.Login(ctx, &ssov1.LoginRequest{
+		Email:    suite.RolesUserEmail,
+		Password: suite.RolesUserPassword,
+		AppId:    suite.ClaimsAppID,
+	})
+	require.NoError(t, err)
+
+	claims := parseClaims(t, respLogin.GetToken(), suite.ClaimsAppSecret)
+
+	assert.Equal(t, []interface{}{"editor", "viewer"}, claims["roles"])
+	assert.Equal(t, "docs:read docs:write", claims["scope"])
+	assert.NotContains(t, claims, "introspect")
+}
+
+func TestTokenClaims_NotRequestedByApp(t *testing.T) {
+	ctx, st := suite.NewSuite(t)
+
+	email, password := st.NewEmail(), st.NewPassword()
+
+	_, err := st.AuthClient.Register(ctx, &ssov1.RegisterRequest{
+		Email:    email,
+		Password: password,
+	})
+	require.NoError(t, err)
+
+	respLogin, err := st.AuthClient.Login(ctx, &ssov1.LoginRequest{
+		Email:    email,
+		Password: password,
+		AppId:    suite.AppID,
+	})
+	require.NoError(t, err)
+
+	claims := parseClaims(t, respLogin.GetToken(), suite.AppSecret)
+
+	assert.NotContains(t, claims, "roles")
+	assert.NotContains(t, claims, "scope")
+	assert.NotContains(t, claims, "introspect")
+}
+
+func TestTokenClaims_FallbackToIntrospection(t *testing.T) {
+	ctx, st := suite.NewSuite(t)
+
+	respLogin, err := st.AuthClient.Login(ctx, &ssov1.LoginRequest{
+		Email:    suite.RolesUserEmail,
+		Password: suite.RolesUserPassword,
+		AppId:    suite.SmallClaimsAppID,
+	})
+	require.NoError(t, err)
+
+	claims := parseClaims(t, respLogin.GetToken(), suite.SmallClaimsAppSecret)
+
+	assert.NotContains(t, claims, "roles")
+	assert.NotContains(t, claims, "scope")
+	assert.Equal(t, true, claims["introspect"])
+
+	respIntrospect, err := st.TokenClient.Introspect(st.WithToken(ctx, respLogin.GetToken()), &tokenv1.IntrospectRequest{
+		Token: respLogin.GetToken(),
+	})
+	require.NoError(t, err)
+
+	assert.True(t, respIntrospect.GetActive())
+	assert.Equal(t, suite.RolesUserEmail, respIntrospect.GetEmail())
+	assert.Equal(t, suite.SmallClaimsAppID, respIntrospect.GetAppId())
+	assert.Equal(t, []string{"editor", "viewer"}, respIntrospect.GetRoles())
+	assert.Equal(t, []string{"docs:read", "docs:write"}, respIntrospect.GetScopes())
+}
+
+func TestIntrospect_InvalidToken(t *testing.T) {
+	ctx, st := suite.NewSuite(t)
+
+	callerToken, _ := st.Login(ctx, suite.RolesUserEmail, suite.RolesUserPassword, suite.AppID)
+	otherToken, _ := st.Login(ctx, suite.RolesUserEmail, suite.RolesUserPassword, suite.ClaimsAppID)
+
+	tests := []struct {
+		Name  string
+		Token string
+	}{
+		{
+			Name:  "With malformed token",
+			Token: "not-a-token",
+		},
+		{
+			Name:  "With token signed by other secret",
+			Token: signToken(t, jwt.MapClaims{"uid": 1, "app_id": suite.AppID, "exp": 4102444800}, "other-secret"),
+		},
+		{
+			Name:  "With expired token",
+			Token: signToken(t, jwt.MapClaims{"uid": 1, "app_id": suite.AppID, "exp": 1}, suite.AppSecret),
+		},
+		{
+			Name:  "With token of unknown app",
+			Token: signToken(t, jwt.MapClaims{"uid": 1, "app_id": 100500, "exp": 4102444800}, suite.AppSecret),
+		},
+		{
+			Name:  "With token of other app",
+			Token: otherToken,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.Name, func(t *testing.T) {
+			resp, err := st.TokenClient.Introspect(st.WithToken(ctx, callerToken), &tokenv1.IntrospectRequest{
+				Token: tt.Token,
+			})
+			require.NoError(t, err)
+			assert.False(t, resp.GetActive())
+		})
+	}
+
+	// Introspection is available only to the applications
+	_, err := st.TokenClient.Introspect(ctx, &tokenv1.IntrospectRequest{Token: callerToken})
+	require.Error(t, err)
+	assert.Equal(t, codes.Unauthenticated, status.Code(err))
+}
+
+func parseClaims(t *testing.T, token string, secret string) jwt.MapClaims {
+	t.Helper()
+
+	tokenParsed, err := jwt.Parse(token, func(token *jwt.Token) (interface{}, error) {
+		return []byte(secret), nil
+	})
+	require.NoError(t, err)
+
+	claims, ok := tokenParsed.Claims.(jwt.MapClaims)
+	require.True(t, ok)
+
+	return claims
+}
+
+func signToken(t *testing.T, claims jwt.MapClaims, secret string) string {
+	t.Helper()
+
+	token, err := jwt.NewWithClaims(jwt.SigningMethodHS512, claims).SignedString([]byte(secret))
+	require.NoError(t, err)
+
+	return token
+}
@@ -0,0 +1,404 @@
+package tests
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	adminv1 "github.com/nhassl3/sso-app/contracts/generated/go/admin"
+	webhooksv1 "github.com/nhassl3/sso-app/contracts/generated/go/webhooks"
+	"github.com/nhassl3/sso-app/tests/suite"
+	ssov1 "github.com/nhassl3/sso-contracts/generated/go/sso"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// webhookRequest is the event received by the webhook receiver
+type webhookRequest struct {
+	id        string
+	timestamp string
+	signature string
+	body      []byte
+	event     webhookEvent
+}
+
+type webhookEvent struct {
+	ID    string `json:"id"`
+	Type  string `json:"type"`
+	AppID int32  `json:"app_id"`
+	Data  struct {
+		UserID int64  `json:"user_id"`
+		Email  string `json:"email"`
+	} `json:"data"`
+}
+
+// webhookReceiver is the endpoint of the subscription, it fails the first failures requests
+type webhookReceiver struct {
+	*httptest.Server
+	failures int32
+	requests atomic.Int32
+
+	mu       sync.Mutex
+	received []webhookRequest
+}
+
+func newWebhookReceiver(t *testing.T, failures int32) *webhookReceiver {
+	t.Helper()
+
+	receiver := &webhookReceiver{failures: failures}
+	receiver.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+
+		request := webhookRequest{
+			id:        r.Header.Get("Webhook-Id"),
+			timestamp: r.Header.Get("Webhook-Timestamp"),
+			signature: r.Header.Get("Webhook-Signature"),
+			body:      body,
+		}
+		_ = json.Unmarshal(body, &request.event)
+
+		receiver.mu.Lock()
+		receiver.received = append(receiver.received, request)
+		receiver.mu.Unlock()
+
+		if receiver.requests.Add(1) <= receiver.failures {
+			w.WriteHeader(http.StatusInternalServerError)
+		}
+	}))
+	t.Cleanup(receiver.Close)
+
+	return receiver
+}
+
+// Event waits for the event of the type about the user and returns all its requests
+func (r *webhookReceiver) Event(t *testing.T, eventType string, userID int64, requests int) []webhookRequest {
+	t.Helper()
+
+	var found []webhookRequest
+
+	require.Eventually(t, func() bool {
+		r.mu.Lock()
+		defer r.mu.Unlock()
+
+		found = found[:0]
+		for _, request := range r.received {
+			if request.event.Type == eventType && request.event.Data.UserID == userID {
+				found = append(found, request)
+			}
+		}
+
+		return len(found) >= requests
+	}, 4*time.Second, 50*time.Millisecond)
+
+	return found
+}
+
+func TestWebhooks_UserRegistered(t *testing.T) {
+	ctx, st := suite.NewSuite(t)
+
+	receiver := newWebhookReceiver(t, 0)
+	app := createApp(ctx, t, st, nil)
+	adminCtx := superAdminCtx(ctx, st)
+
+	// Registration isn't bound to an application, so only subscription of all users gets it
+	respCreate, err := st.WebhooksClient.CreateSubscription(adminCtx, &webhooksv1.CreateSubscriptionRequest{
+		AppId:    app.GetId(),
+		Url:      receiver.URL + "/hooks",
+		Events:   []string{"user.registered"},
+		AllUsers: true,
+	})
+	require.NoError(t, err)
+	require.NotEmpty(t, respCreate.GetSecret())
+	assert.Equal(t, app.GetId(), respCreate.GetSubscription().GetAppId())
+	assert.Equal(t, []string{"user.registered"}, respCreate.GetSubscription().GetEvents())
+	assert.True(t, respCreate.GetSubscription().GetAllUsers())
+
+	respList, err := st.WebhooksClient.ListSubscriptions(adminCtx, &webhooksv1.ListSubscriptionsRequest{AppId: app.GetId()})
+	require.NoError(t, err)
+	require.Len(t, respList.GetSubscriptions(), 1)
+	assert.Equal(t, respCreate.GetSubscription().GetId(), respList.GetSubscriptions()[0].GetId())
+
+	email := st.NewEmail()
+	respReg, err := st.AuthClient.Register(ctx, &ssov1.RegisterRequest{Email: email, Password: st.NewPassword()})
+	require.NoError(t, err)
+
+	request := receiver.Event(t, "user.registered", respReg.GetUserId(), 1)[0]
+	assert.Equal(t, email, request.event.Data.Email)
+	assert.Equal(t, request.id, request.event.ID)
+	assertWebhookSignature(t, respCreate.GetSecret(), request)
+
+	timestamp, err := strconv.ParseInt(request.timestamp, 10, 64)
+	require.NoError(t, err)
+	assert.InDelta(t, time.Now().Unix(), timestamp, 5)
+
+	delivery := waitWebhookDelivery(ctx, t, st, app.GetId(), request.id, "delivered")
+	assert.Equal(t, "user.registered", delivery.GetEventType())
+	assert.Equal(t, int32(1), delivery.GetAttempts())
+	assert.NotZero(t, delivery.GetDeliveredAt())
+
+	_, err = st.WebhooksClient.DeleteSubscription(adminCtx, &webhooksv1.DeleteSubscriptionRequest{
+		AppId:          app.GetId(),
+		SubscriptionId: respCreate.GetSubscription().GetId(),
+	})
+	require.NoError(t, err)
+
+	_, err = st.WebhooksClient.DeleteSubscription(adminCtx, &webhooksv1.DeleteSubscriptionRequest{
+		AppId:          app.GetId(),
+		SubscriptionId: respCreate.GetSubscription().GetId(),
+	})
+	assert.Equal(t, codes.NotFound, status.Code(err))
+}
+
+func TestWebhooks_AdminGrantedOnlyToApp(t *testing.T) {
+	ctx, st := suite.NewSuite(t)
+
+	receiver := newWebhookReceiver(t, 0)
+	other := newWebhookReceiver(t, 0)
+	app := createApp(ctx, t, st, nil)
+	otherApp := createApp(ctx, t, st, nil)
+	adminCtx := superAdminCtx(ctx, st)
+
+	respCreate, err := st.WebhooksClient.CreateSubscription(adminCtx, &webhooksv1.CreateSubscriptionRequest{
+		AppId: app.GetId(),
+		Url:   receiver.URL,
+	})
+	require.NoError(t, err)
+
+	_, err = st.WebhooksClient.CreateSubscription(adminCtx, &webhooksv1.CreateSubscriptionRequest{
+		AppId:  otherApp.GetId(),
+		Url:    other.URL,
+		Events: []string{"user.admin_granted"},
+	})
+	require.NoError(t, err)
+
+	respReg, err := st.AuthClient.Register(ctx, &ssov1.RegisterRequest{Email: st.NewEmail(), Password: st.NewPassword()})
+	require.NoError(t, err)
+
+	_, err = st.AdminClient.GrantAdmin(adminCtx, &adminv1.GrantAdminRequest{UserId: respReg.GetUserId(), AppId: app.GetId()})
+	require.NoError(t, err)
+
+	request := receiver.Event(t, "user.admin_granted", respReg.GetUserId(), 1)[0]
+	assert.Equal(t, app.GetId(), request.event.AppID)
+	assertWebhookSignature(t, respCreate.GetSecret(), request)
+
+	time.Sleep(300 * time.Millisecond)
+
+	other.mu.Lock()
+	defer other.mu.Unlock()
+
+	for _, request := range other.received {
+		assert.NotEqual(t, respReg.GetUserId(), request.event.Data.UserID)
+	}
+
+	// Subscriptions of the applications don't get events of the users which aren't bound to them
+	receiver.mu.Lock()
+	defer receiver.mu.Unlock()
+
+	for _, request := range receiver.received {
+		assert.NotEqual(t, "user.registered", request.event.Type)
+	}
+}
+
+func TestWebhooks_DeadLetterAndRedeliver(t *testing.T) {
+	ctx, st := suite.NewSuite(t)
+
+	// Test config allows 3 attempts
+	receiver := newWebhookReceiver(t, 3)
+	app := createApp(ctx, t, st, nil)
+	adminCtx := superAdminCtx(ctx, st)
+
+	_, err := st.WebhooksClient.CreateSubscription(adminCtx, &webhooksv1.CreateSubscriptionRequest{
+		AppId:    app.GetId(),
+		Url:      receiver.URL,
+		Events:   []string{"user.registered"},
+		AllUsers: true,
+	})
+	require.NoError(t, err)
+
+	respReg, err := st.AuthClient.Register(ctx, &ssov1.RegisterRequest{Email: st.NewEmail(), Password: st.NewPassword()})
+	require.NoError(t, err)
+
+	requests := receiver.Event(t, "user.registered", respReg.GetUserId(), 3)
+	eventID := requests[0].id
+	for _, request := range requests {
+		assert.Equal(t, eventID, request.id)
+	}
+
+	dead := waitWebhookDelivery(ctx, t, st, app.GetId(), eventID, "dead")
+	assert.Equal(t, int32(3), dead.GetAttempts())
+	assert.NotEmpty(t, dead.GetLastError())
+
+	respDead, err := st.WebhooksClient.ListDeliveries(adminCtx, &webhooksv1.ListDeliveriesRequest{
+		AppId:  app.GetId(),
+		Status: "dead",
+	})
+	require.NoError(t, err)
+	require.NotEmpty(t, respDead.GetDeliveries())
+	for _, delivery := range respDead.GetDeliveries() {
+		assert.Equal(t, "dead", delivery.GetStatus())
+	}
+
+	_, err = st.WebhooksClient.Redeliver(adminCtx, &webhooksv1.RedeliverRequest{AppId: app.GetId(), DeliveryId: dead.GetId()})
+	require.NoError(t, err)
+
+	redelivered := receiver.Event(t, "user.registered", respReg.GetUserId(), 4)
+	assert.Equal(t, eventID, redelivered[3].id)
+
+	delivered := waitWebhookDelivery(ctx, t, st, app.GetId(), eventID, "delivered")
+	assert.Equal(t, dead.GetId(), delivered.GetId())
+	assert.Equal(t, int32(1), delivered.GetAttempts())
+
+	// Delivered event may be sent again, pending one is still in the queue
+	_, err = st.WebhooksClient.Redeliver(adminCtx, &webhooksv1.RedeliverRequest{AppId: app.GetId(), DeliveryId: dead.GetId()})
+	require.NoError(t, err)
+
+	_, err = st.WebhooksClient.Redeliver(adminCtx, &webhooksv1.RedeliverRequest{AppId: app.GetId(), DeliveryId: dead.GetId() + 1000000})
+	assert.Equal(t, codes.NotFound, status.Code(err))
+}
+
+func TestWebhooks_Validation(t *testing.T) {
+	ctx, st := suite.NewSuite(t)
+
+	app := createApp(ctx, t, st, nil)
+	adminCtx := superAdminCtx(ctx, st)
+
+	tests := []struct {
+		name   string
+		appID  int32
+		url    string
+		events []string
+		code   codes.Code
+	}{
+		{name: "no app", url: "https://hooks.example.com", code: codes.InvalidArgument},
+		{name: "unknown app", appID: 1000000, url: "https://hooks.example.com", code: codes.NotFound},
+		{name: "no url", appID: app.GetId(), code: codes.InvalidArgument},
+		{name: "relative url", appID: app.GetId(), url: "/hooks", code: codes.InvalidArgument},
+		{name: "not http url", appID: app.GetId(), url: "ftp://hooks.example.com", code: codes.InvalidArgument},
+		{
+			name:   "unknown event",
+			appID:  app.GetId(),
+			url:    "https://hooks.example.com",
+			events: []string{"user.registered", "user.unknown"},
+			code:   codes.InvalidArgument,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := st.WebhooksClient.CreateSubscription(adminCtx, &webhooksv1.CreateSubscriptionRequest{
+				AppId:  tt.appID,
+				Url:    tt.url,
+				Events: tt.events,
+			})
+			assert.Equal(t, tt.code, status.Code(err))
+		})
+	}
+
+	_, err := st.WebhooksClient.ListDeliveries(adminCtx, &webhooksv1.ListDeliveriesRequest{AppId: app.GetId(), Status: "lost"})
+	assert.Equal(t, codes.InvalidArgument, status.Code(err))
+}
+
+func TestWebhooks_PermissionDenied(t *testing.T) {
+	ctx, st := suite.NewSuite(t)
+
+	app := createApp(ctx, t, st, nil)
+
+	email, password := st.NewEmail(), st.NewPassword()
+	_, err := st.AuthClient.Register(ctx, &ssov1.RegisterRequest{Email: email, Password: password})
+	require.NoError(t, err)
+
+	userToken, _ := st.Login(ctx, email, password, suite.AppID)
+	userCtx := st.WithToken(ctx, userToken)
+
+	_, err = st.WebhooksClient.CreateSubscription(userCtx, &webhooksv1.CreateSubscriptionRequest{
+		AppId: app.GetId(),
+		Url:   "https://hooks.example.com",
+	})
+	assert.Equal(t, codes.PermissionDenied, status.Code(err))
+
+	_, err = st.WebhooksClient.ListSubscriptions(userCtx, &webhooksv1.ListSubscriptionsRequest{AppId: app.GetId()})
+	assert.Equal(t, codes.PermissionDenied, status.Code(err))
+
+	_, err = st.WebhooksClient.ListDeliveries(userCtx, &webhooksv1.ListDeliveriesRequest{AppId: app.GetId()})
+	assert.Equal(t, codes.PermissionDenied, status.Code(err))
+
+	_, err = st.WebhooksClient.ListSubscriptions(ctx, &webhooksv1.ListSubscriptionsRequest{AppId: app.GetId()})
+	assert.Equal(t, codes.Unauthenticated, status.Code(err))
+
+	// App admin subscribes only to the events of own application
+	appAdminCtx, appAdminID := registerAndLogin(ctx, t, st)
+	_, err = st.AdminClient.GrantAdmin(superAdminCtx(ctx, st), &adminv1.GrantAdminRequest{UserId: appAdminID, AppId: app.GetId()})
+	require.NoError(t, err)
+
+	_, err = st.WebhooksClient.CreateSubscription(appAdminCtx, &webhooksv1.CreateSubscriptionRequest{
+		AppId:    app.GetId(),
+		Url:      "https://hooks.example.com",
+		AllUsers: true,
+	})
+	assert.Equal(t, codes.PermissionDenied, status.Code(err))
+}
+
+// superAdminCtx returns context with the token of the super-admin
+func superAdminCtx(ctx context.Context, st *suite.Suite) context.Context {
+	superToken, _ := st.Login(ctx, suite.SuperAdminEmail, suite.AdminPassword, suite.AppID)
+
+	return st.WithToken(ctx, superToken)
+}
+
+// assertWebhookSignature checks the signature of the request made by the secret of the subscription
+func assertWebhookSignature(t *testing.T, secret string, request webhookRequest) {
+	t.Helper()
+
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(request.id + "." + request.timestamp + "."))
+	mac.Write(request.body)
+
+	assert.Equal(t, "v1,"+base64.StdEncoding.EncodeToString(mac.Sum(nil)), request.signature)
+}
+
+// waitWebhookDelivery waits for the delivery of the event to the application to get the status
+func waitWebhookDelivery(
+	ctx context.Context,
+	t *testing.T,
+	st *suite.Suite,
+	appID int32,
+	eventID string,
+	deliveryStatus string,
+) *webhooksv1.Delivery {
+	t.Helper()
+
+	adminCtx := superAdminCtx(ctx, st)
+
+	var delivery *webhooksv1.Delivery
+
+	require.Eventually(t, func() bool {
+		resp, err := st.WebhooksClient.ListDeliveries(adminCtx, &webhooksv1.ListDeliveriesRequest{AppId: appID})
+		if err != nil {
+			return false
+		}
+
+		for _, d := range resp.GetDeliveries() {
+			if d.GetEventId() == eventID {
+				delivery = d
+
+				return d.GetStatus() == deliveryStatus
+			}
+		}
+
+		return false
+	}, 4*time.Second, 100*time.Millisecond)
+
+	return delivery
+}